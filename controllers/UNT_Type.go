@@ -3,327 +3,147 @@ package controllers
 import (
 	"database/sql"
 	"encoding/json"
+	"fmt"
 	"log"
 	"net/http"
 	"ranking-school/models"
 	"ranking-school/utils"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gorilla/mux"
 )
 
 type UNTTypeController struct{}
 
-
+// CreateUNTType — старый эндпоинт создания результата ЕНТ. Результаты
+// хранятся в UNT_Exams, школа берётся из карточки ученика.
 func (sc UNTTypeController) CreateUNTType(db *sql.DB) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		var untType models.UNTType
-		if err := json.NewDecoder(r.Body).Decode(&untType); err != nil {
-			utils.RespondWithError(w, http.StatusBadRequest, models.Error{Message: "Invalid request"})
+		userID, err := utils.VerifyToken(r)
+		if err != nil {
+			utils.RespondWithError(w, http.StatusUnauthorized, models.Error{Message: err.Error()})
 			return
 		}
 
-		// Проверяем, что предоставлен только один тип (либо First_Type, либо Second_Type)
-		if (untType.FirstTypeID == nil && untType.SecondTypeID == nil) || (untType.FirstTypeID != nil && untType.SecondTypeID != nil) {
-			utils.RespondWithError(w, http.StatusBadRequest, models.Error{Message: "You must provide either First_Type or Second_Type, but not both"})
+		var exam models.UNTExam
+		if err := json.NewDecoder(r.Body).Decode(&exam); err != nil {
+			utils.RespondWithError(w, http.StatusBadRequest, models.Error{Message: "Invalid request"})
 			return
 		}
-
-		// Устанавливаем тип (type-1 или type-2) в зависимости от того, какой тип был передан
-		if untType.FirstTypeID != nil {
-			untType.Type = "type-1" // Первый тип
-		} else if untType.SecondTypeID != nil {
-			untType.Type = "type-2" // Второй тип
+		if exam.StudentID <= 0 {
+			utils.RespondWithError(w, http.StatusBadRequest, models.Error{Message: "student_id is required"})
+			return
 		}
 
-		// Проверка существования First_Type, если передан first_type_id
-		if untType.FirstTypeID != nil {
-			var firstTypeExists bool
-			err := db.QueryRow("SELECT EXISTS(SELECT 1 FROM First_Type WHERE first_type_id = ?)", *untType.FirstTypeID).Scan(&firstTypeExists)
-			if err != nil || !firstTypeExists {
-				utils.RespondWithError(w, http.StatusBadRequest, models.Error{Message: "First Type ID does not exist"})
-				return
-			}
-
-			// Рассчитываем total_score для первого типа
-			totalScore := 0
-			if untType.FirstSubjectScore != nil {
-				totalScore += *untType.FirstSubjectScore
-			}
-			if untType.SecondSubjectScore != nil {
-				totalScore += *untType.SecondSubjectScore
-			}
-			if untType.HistoryKazakhstan != nil {
-				totalScore += *untType.HistoryKazakhstan
-			}
-			if untType.MathematicalLiteracy != nil {
-				totalScore += *untType.MathematicalLiteracy
-			}
-			if untType.ReadingLiteracy != nil {
-				totalScore += *untType.ReadingLiteracy
-			}
-
-			untType.TotalScore = new(int)
-			*untType.TotalScore = totalScore
+		err = db.QueryRow("SELECT COALESCE(school_id, 0) FROM student WHERE student_id = ?", exam.StudentID).Scan(&exam.SchoolID)
+		if err == sql.ErrNoRows {
+			utils.RespondWithError(w, http.StatusNotFound, models.Error{Message: "Student not found"})
+			return
+		} else if err != nil {
+			log.Println("SQL Error:", err)
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Failed to get student"})
+			return
 		}
 
-		// Проверка существования Second_Type, если передан second_type_id
-		if untType.SecondTypeID != nil {
-			var secondTypeExists bool
-			err := db.QueryRow("SELECT EXISTS(SELECT 1 FROM Second_Type WHERE second_type_id = ?)", *untType.SecondTypeID).Scan(&secondTypeExists)
-			if err != nil || !secondTypeExists {
-				utils.RespondWithError(w, http.StatusBadRequest, models.Error{Message: "Second Type ID does not exist"})
+		var userRole string
+		if err := db.QueryRow("SELECT role FROM users WHERE id = ?", userID).Scan(&userRole); err != nil {
+			utils.RespondWithError(w, http.StatusForbidden, models.Error{Message: "Не удалось получить роль пользователя"})
+			return
+		}
+		if userRole != "superadmin" {
+			allowed, err := hasSchoolPermission(db, userID, exam.SchoolID, PermissionManageUNT)
+			if err != nil {
+				log.Printf("Ошибка при проверке прав участника школы: %v", err)
+				utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Не удалось проверить права пользователя"})
 				return
 			}
-
-			// Рассчитываем total_score_creative для второго типа
-			totalScoreCreative := 0
-			if untType.SecondTypeHistoryKazakhstan != nil {
-				totalScoreCreative += *untType.SecondTypeHistoryKazakhstan
-			}
-			if untType.SecondTypeReadingLiteracy != nil {
-				totalScoreCreative += *untType.SecondTypeReadingLiteracy
-			}
-			if untType.CreativeExam1 != nil {
-				totalScoreCreative += *untType.CreativeExam1
-			}
-			if untType.CreativeExam2 != nil {
-				totalScoreCreative += *untType.CreativeExam2
+			if !allowed {
+				utils.RespondWithError(w, http.StatusForbidden, models.Error{Message: "У вас нет прав на создание UNT экзамена для этой школы"})
+				return
 			}
-
-			untType.TotalScoreCreative = new(int)
-			*untType.TotalScoreCreative = totalScoreCreative
 		}
 
-		// Вставка в UNT_Type таблицу
-		query := `INSERT INTO UNT_Type (first_type_id, second_type_id, second_type_history_kazakhstan, second_type_reading_literacy, creative_exam1, creative_exam2, total_score, total_score_creative, type) 
-				  VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`
-		_, err := db.Exec(query, utils.NullableValue(untType.FirstTypeID), utils.NullableValue(untType.SecondTypeID),
-			utils.NullableValue(untType.SecondTypeHistoryKazakhstan), utils.NullableValue(untType.SecondTypeReadingLiteracy),
-			utils.NullableValue(untType.CreativeExam1), utils.NullableValue(untType.CreativeExam2),
-			utils.NullableValue(untType.TotalScore), utils.NullableValue(untType.TotalScoreCreative), untType.Type) // Добавляем тип в запрос
-		if err != nil {
-			log.Println("SQL Error:", err)
-			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Failed to create UNT Type"})
+		exam.ExamType = strings.ToLower(exam.ExamType)
+		if exam.ExamType == "" {
+			exam.ExamType = "regular"
+		}
+		if exam.ExamType != "regular" && exam.ExamType != "creative" {
+			utils.RespondWithError(w, http.StatusBadRequest, models.Error{Message: "Некорректный тип экзамена. Допустимые значения: regular, creative"})
 			return
 		}
-
-		utils.ResponseJSON(w, "UNT Type created successfully")
-	}
-}
-func (c *UNTTypeController) GetTop3StudentsByUNT(db *sql.DB) http.HandlerFunc {
-	return func(w http.ResponseWriter, r *http.Request) {
-		// Define response structure
-		type TopStudent struct {
-			FullName string `json:"full_name"`
-			IIN      string `json:"iin"`
-			Grade    int    `json:"grade"`
-			Letter   string `json:"letter"`
-			UNTScore int    `json:"unt_score"`
+		exam.AttemptKind = strings.ToLower(exam.AttemptKind)
+		if exam.AttemptKind == "" {
+			exam.AttemptKind = "official"
 		}
-
-		// Query to get top 3 students by UNT score, handling both type-1 and type-2
-		query := `
-			SELECT 
-				TRIM(CONCAT(s.first_name, ' ', s.last_name, ' ', COALESCE(s.patronymic, ''))) AS full_name,
-				s.iin,
-				s.grade,
-				s.letter,
-				COALESCE(ut.total_score, ut.total_score_creative, 0) AS unt_score
-			FROM student s
-			INNER JOIN UNT_Type ut ON s.student_type_id = ut.unt_type_id
-			WHERE s.role = 'student'
-			ORDER BY COALESCE(ut.total_score, ut.total_score_creative, 0) DESC
-			LIMIT 3
-		`
-
-		// Execute query
-		rows, err := db.Query(query)
-		if err != nil {
-			log.Printf("Error querying top students: %v", err)
-			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Failed to retrieve top students"})
+		if exam.AttemptKind != "official" && exam.AttemptKind != "trial" {
+			utils.RespondWithError(w, http.StatusBadRequest, models.Error{Message: "Некорректный вид попытки. Допустимые значения: official, trial"})
 			return
 		}
-		defer rows.Close()
-
-		// Process results
-		var topStudents []TopStudent
-		for rows.Next() {
-			var student TopStudent
-			var iin sql.NullString    // Handle nullable IIN
-			var letter sql.NullString // Handle nullable Letter
-			if err := rows.Scan(
-				&student.FullName,
-				&iin,
-				&student.Grade,
-				&letter,
-				&student.UNTScore,
-			); err != nil {
-				log.Printf("Error scanning row: %v", err)
-				utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Failed to parse student data"})
-				return
-			}
-
-			// Assign values, handling NULLs
-			student.IIN = utils.NullStringToString(iin)
-			student.Letter = utils.NullStringToString(letter)
-
-			topStudents = append(topStudents, student)
-		}
-
-		if err = rows.Err(); err != nil {
-			log.Printf("Error iterating rows: %v", err)
-			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Database error"})
+		if _, err := time.Parse("2006-01-02", exam.Date); err != nil {
+			utils.RespondWithError(w, http.StatusBadRequest, models.Error{Message: "Некорректный формат даты. Используйте формат ГГГГ-ММ-ДД"})
 			return
 		}
 
-		// Debugging: Log if no results are found
-		if len(topStudents) == 0 {
-			log.Println("No students found with valid UNT scores")
-			// Optionally, check if students or UNT_Type records exist
-			var studentCount int
-			db.QueryRow("SELECT COUNT(*) FROM student WHERE role = 'student'").Scan(&studentCount)
-			var untCount int
-			db.QueryRow("SELECT COUNT(*) FROM UNT_Type").Scan(&untCount)
-			log.Printf("Students with role='student': %d, UNT_Type records: %d", studentCount, untCount)
+		if !validateUNTExamWithCatalog(w, db, &exam, exam.TotalScore) {
+			return
 		}
 
-		// Return the top 3 students
-		w.Header().Set("Content-Type", "application/json")
-		if err := json.NewEncoder(w).Encode(topStudents); err != nil {
-			log.Printf("Error encoding response: %v", err)
-			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Failed to encode response"})
+		if _, err := insertUNTExam(db, &exam); err != nil {
+			log.Println("SQL Error:", err)
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Failed to create UNT result"})
 			return
 		}
+
+		utils.ResponseJSON(w, map[string]interface{}{
+			"message":   fmt.Sprintf("UNT экзамен типа %s создан успешно", exam.ExamType),
+			"id":        exam.ID,
+			"exam_data": exam,
+		})
 	}
 }
 func (c *TypeController) GetUNTTypesBySchool(db *sql.DB) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		// Извлекаем school_id из параметров URL
 		vars := mux.Vars(r)
-		schoolID, err := strconv.Atoi(vars["school_id"]) // Извлекаем school_id из URL
+		schoolID, err := strconv.Atoi(vars["school_id"])
 		if err != nil {
 			utils.RespondWithError(w, http.StatusBadRequest, models.Error{Message: "Invalid school ID"})
 			return
 		}
 
-		// Запрос для получения всех типов экзаменов для конкретной школы
-		query := `
-            SELECT 
-                ut.unt_type_id, 
-                ut.type AS unt_type, 
-                ft.first_type_id, 
-                ft.first_subject_id, 
-                ft.subject AS first_subject_name, 
-                COALESCE(ft.score, 0) AS first_subject_score,
-                st.second_type_id,
-                st.history_of_kazakhstan_creative,
-                st.reading_literacy_creative,
-                st.creative_exam1,
-                st.creative_exam2,
-                (COALESCE(ft.score, 0) + COALESCE(ft.history_of_kazakhstan, 0) + COALESCE(ft.mathematical_literacy, 0) + COALESCE(ft.reading_literacy, 0)) AS total_score,
-                (COALESCE(st.history_of_kazakhstan_creative, 0) + COALESCE(st.reading_literacy_creative, 0) + COALESCE(st.creative_exam1, 0) + COALESCE(st.creative_exam2, 0)) AS total_score_creative
-            FROM UNT_Type ut
-            LEFT JOIN First_Type ft ON ut.first_type_id = ft.first_type_id
-            LEFT JOIN Second_Type st ON ut.second_type_id = st.second_type_id
-            WHERE ft.school_id = ? 
-               OR st.school_id = ?`
-
-		// Передаем два параметра schoolID для обоих условий
-		rows, err := db.Query(query, schoolID, schoolID)
+		// Итоговые результаты ЕНТ школы; пробные попытки видны только администрации
+		rows, err := db.Query(`
+			SELECT id, exam_type, attempt_kind, COALESCE(first_subject, ''), COALESCE(first_subject_score, 0),
+			       COALESCE(second_subject, ''), COALESCE(second_subject_score, 0), COALESCE(history_of_kazakhstan, 0),
+			       COALESCE(mathematical_literacy, 0), COALESCE(reading_literacy, 0), COALESCE(total_score, 0),
+			       student_id, school_id, COALESCE(CAST(date AS CHAR), '')
+			FROM UNT_Exams
+			WHERE school_id = ? AND attempt_kind = 'official'
+			ORDER BY date DESC, id DESC`, schoolID)
 		if err != nil {
 			log.Println("SQL Error:", err)
-			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Failed to get UNT Types by School"})
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Failed to get UNT results by school"})
 			return
 		}
 		defer rows.Close()
 
-		var types []models.UNTType
+		exams := []models.UNTExam{}
 		for rows.Next() {
-			var untType models.UNTType
-			var firstSubjectID, secondSubjectID, historyKazakhstan, mathematicalLiteracy, readingLiteracy sql.NullInt64
-			var firstSubjectName, secondSubjectName sql.NullString
-			var firstSubjectScore, secondSubjectScore sql.NullInt64
-			var secondTypeID sql.NullInt64
-			var secondTypeHistoryOfKazakhstan, secondTypeReadingLiteracy, creativeExam1, creativeExam2 sql.NullInt64
-
-			if err := rows.Scan(
-				&untType.UNTTypeID,
-				&untType.Type,
-				&untType.FirstTypeID,
-				&firstSubjectID, &firstSubjectName, &firstSubjectScore,
-				&secondSubjectID, &secondSubjectName, &secondSubjectScore,
-				&historyKazakhstan, &mathematicalLiteracy, &readingLiteracy,
-				&secondTypeID,
-				&secondTypeHistoryOfKazakhstan, &secondTypeReadingLiteracy,
-				&creativeExam1, &creativeExam2,
-				&untType.TotalScore,
-				&untType.TotalScoreCreative,
-			); err != nil {
+			var exam models.UNTExam
+			if err := rows.Scan(&exam.ID, &exam.ExamType, &exam.AttemptKind, &exam.FirstSubject, &exam.FirstSubjectScore,
+				&exam.SecondSubject, &exam.SecondSubjectScore, &exam.HistoryOfKazakhstan,
+				&exam.MathematicalLiteracy, &exam.ReadingLiteracy, &exam.TotalScore,
+				&exam.StudentID, &exam.SchoolID, &exam.Date); err != nil {
 				log.Println("Scan Error:", err)
-				utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Failed to parse UNT Types"})
+				utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Failed to parse UNT results"})
 				return
 			}
-
-			// Преобразуем значения, если они присутствуют
-			if firstSubjectID.Valid {
-				untType.FirstSubjectID = new(int)
-				*untType.FirstSubjectID = int(firstSubjectID.Int64)
-			}
-			if firstSubjectName.Valid {
-				untType.FirstSubjectName = new(string)
-				*untType.FirstSubjectName = firstSubjectName.String
-			}
-			if firstSubjectScore.Valid {
-				untType.FirstSubjectScore = new(int)
-				*untType.FirstSubjectScore = int(firstSubjectScore.Int64)
-			}
-			if secondSubjectName.Valid {
-				untType.SecondSubjectName = new(string)
-				*untType.SecondSubjectName = secondSubjectName.String
-			}
-			if secondSubjectScore.Valid {
-				untType.SecondSubjectScore = new(int)
-				*untType.SecondSubjectScore = int(secondSubjectScore.Int64)
-			}
-			if historyKazakhstan.Valid {
-				untType.HistoryKazakhstan = new(int)
-				*untType.HistoryKazakhstan = int(historyKazakhstan.Int64)
-			}
-			if mathematicalLiteracy.Valid {
-				untType.MathematicalLiteracy = new(int)
-				*untType.MathematicalLiteracy = int(mathematicalLiteracy.Int64)
-			}
-			if readingLiteracy.Valid {
-				untType.ReadingLiteracy = new(int)
-				*untType.ReadingLiteracy = int(readingLiteracy.Int64)
-			}
-
-			if secondTypeID.Valid {
-				untType.SecondTypeID = new(int)
-				*untType.SecondTypeID = int(secondTypeID.Int64)
-			}
-			if secondTypeHistoryOfKazakhstan.Valid {
-				untType.SecondTypeHistoryKazakhstan = new(int)
-				*untType.SecondTypeHistoryKazakhstan = int(secondTypeHistoryOfKazakhstan.Int64)
-			}
-			if secondTypeReadingLiteracy.Valid {
-				untType.SecondTypeReadingLiteracy = new(int)
-				*untType.SecondTypeReadingLiteracy = int(secondTypeReadingLiteracy.Int64)
-			}
-			if creativeExam1.Valid {
-				untType.CreativeExam1 = new(int)
-				*untType.CreativeExam1 = int(creativeExam1.Int64)
-			}
-			if creativeExam2.Valid {
-				untType.CreativeExam2 = new(int)
-				*untType.CreativeExam2 = int(creativeExam2.Int64)
-			}
-
-			// Добавляем тип в результирующий список
-			types = append(types, untType)
+			exam.Date = firstN(exam.Date, 10)
+			exams = append(exams, exam)
 		}
 
-		utils.ResponseJSON(w, types)
+		utils.ResponseJSON(w, exams)
 	}
 }
@@ -0,0 +1,591 @@
+package controllers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"ranking-school/models"
+	"ranking-school/utils"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+type AcademicYearController struct{}
+
+// Последний класс школы — после него ученик становится выпускником
+const finalGrade = 11
+
+type rolloverRequest struct {
+	AcademicYear     string `json:"academic_year"`
+	DryRun           bool   `json:"dry_run"`
+	RepeatStudentIDs []int  `json:"repeat_student_ids"`
+}
+
+type rolloverStudent struct {
+	StudentID int    `json:"student_id"`
+	FirstName string `json:"first_name"`
+	LastName  string `json:"last_name"`
+	Grade     int    `json:"grade"`
+	Letter    string `json:"letter"`
+	classID   sql.NullInt64
+}
+
+type rolloverSummary struct {
+	SchoolID        int               `json:"school_id"`
+	AcademicYear    string            `json:"academic_year"`
+	AlreadyRolled   bool              `json:"already_rolled_over"`
+	Promoted        int               `json:"promoted"`
+	Repeated        int               `json:"repeated"`
+	Graduated       int               `json:"graduated"`
+	Graduates       []rolloverStudent `json:"graduates"`
+	Repeaters       []rolloverStudent `json:"repeaters"`
+	matchedRepeater map[int]bool
+}
+
+// currentAcademicYear returns the academic year in progress. From July on the
+// new year is considered started, so rollover in summer lands in it. Class
+// listings, syncStudentClass and rollover all use this boundary.
+func currentAcademicYear() string {
+	now := time.Now()
+	y := now.Year()
+	if now.Month() >= time.July {
+		return fmt.Sprintf("%d-%d", y, y+1)
+	}
+	return fmt.Sprintf("%d-%d", y-1, y)
+}
+
+// lastCompletedAcademicYear returns the year before currentAcademicYear,
+// the default for rollover: its students move into current-year classes.
+func lastCompletedAcademicYear() string {
+	end, _ := parseAcademicYear(currentAcademicYear())
+	return fmt.Sprintf("%d-%d", end-2, end-1)
+}
+
+// parseAcademicYear validates "YYYY-YYYY" and returns the year it ends in.
+func parseAcademicYear(academicYear string) (int, bool) {
+	parts := strings.Split(academicYear, "-")
+	if len(parts) != 2 {
+		return 0, false
+	}
+	start, err1 := strconv.Atoi(parts[0])
+	end, err2 := strconv.Atoi(parts[1])
+	if err1 != nil || err2 != nil || end != start+1 || start < 2000 {
+		return 0, false
+	}
+	return end, true
+}
+
+func decodeRolloverRequest(r *http.Request) (rolloverRequest, error) {
+	var req rolloverRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			return req, fmt.Errorf("invalid request body")
+		}
+	}
+	if req.AcademicYear == "" {
+		req.AcademicYear = lastCompletedAcademicYear()
+	}
+	end, ok := parseAcademicYear(req.AcademicYear)
+	if !ok {
+		return req, fmt.Errorf("academic_year must look like 2024-2025")
+	}
+	// Students are moved into classes of the following year, which must
+	// already be the current one; a dry run may preview it earlier
+	current, _ := parseAcademicYear(currentAcademicYear())
+	if end >= current && !req.DryRun {
+		return req, fmt.Errorf("academic year %s is still in progress, rollover is available from July 1", req.AcademicYear)
+	}
+	return req, nil
+}
+
+// rolloverSchool promotes every active student of a school by one grade,
+// keeps repeaters in place and archives final-grade students as alumni.
+// A grade/letter snapshot is written to student_grade_history first.
+// With dryRun nothing is written and only the summary is returned.
+func rolloverSchool(tx *sql.Tx, schoolID int, academicYear string, repeaters map[int]bool, performedBy int, dryRun bool) (rolloverSummary, error) {
+	summary := rolloverSummary{
+		SchoolID:        schoolID,
+		AcademicYear:    academicYear,
+		Graduates:       []rolloverStudent{},
+		Repeaters:       []rolloverStudent{},
+		matchedRepeater: map[int]bool{},
+	}
+	endYear, _ := parseAcademicYear(academicYear)
+	nextYear := fmt.Sprintf("%d-%d", endYear, endYear+1)
+
+	err := tx.QueryRow("SELECT EXISTS(SELECT 1 FROM academic_year_rollovers WHERE school_id = ? AND academic_year = ?)",
+		schoolID, academicYear).Scan(&summary.AlreadyRolled)
+	if err != nil || summary.AlreadyRolled {
+		return summary, err
+	}
+
+	rows, err := tx.Query(`
+		SELECT student_id, first_name, last_name, COALESCE(grade, 0), COALESCE(letter, ''), class_id
+		FROM student
+		WHERE school_id = ? AND status = 'active'
+		FOR UPDATE`, schoolID)
+	if err != nil {
+		return summary, err
+	}
+	var students []rolloverStudent
+	for rows.Next() {
+		var s rolloverStudent
+		if err := rows.Scan(&s.StudentID, &s.FirstName, &s.LastName, &s.Grade, &s.Letter, &s.classID); err != nil {
+			rows.Close()
+			return summary, err
+		}
+		students = append(students, s)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return summary, err
+	}
+
+	var historyStmt, promoteStmt, graduateStmt *sql.Stmt
+	if !dryRun {
+		if historyStmt, err = tx.Prepare(`INSERT INTO student_grade_history (student_id, school_id, academic_year, grade, letter, outcome, class_id)
+			VALUES (?, ?, ?, ?, ?, ?, ?)`); err != nil {
+			return summary, err
+		}
+		defer historyStmt.Close()
+		if promoteStmt, err = tx.Prepare("UPDATE student SET grade = ?, class_id = ? WHERE student_id = ?"); err != nil {
+			return summary, err
+		}
+		defer promoteStmt.Close()
+		if graduateStmt, err = tx.Prepare("UPDATE student SET status = 'alumni', graduation_year = ? WHERE student_id = ?"); err != nil {
+			return summary, err
+		}
+		defer graduateStmt.Close()
+	}
+
+	for _, s := range students {
+		var outcome string
+		switch {
+		case repeaters[s.StudentID]:
+			outcome = "repeated"
+			summary.Repeated++
+			summary.Repeaters = append(summary.Repeaters, s)
+			summary.matchedRepeater[s.StudentID] = true
+		case s.Grade >= finalGrade:
+			outcome = "graduated"
+			summary.Graduated++
+			summary.Graduates = append(summary.Graduates, s)
+		default:
+			outcome = "promoted"
+			summary.Promoted++
+		}
+
+		if dryRun {
+			continue
+		}
+		if _, err := historyStmt.Exec(s.StudentID, schoolID, academicYear, s.Grade, s.Letter, outcome, s.classID); err != nil {
+			return summary, err
+		}
+		switch outcome {
+		case "promoted", "repeated":
+			// Ученик попадает в класс нового учебного года
+			newGrade := s.Grade
+			if outcome == "promoted" {
+				newGrade++
+			}
+			var classID int
+			classID, err = ensureClass(tx, schoolID, nextYear, newGrade, s.Letter)
+			if err == nil {
+				_, err = promoteStmt.Exec(newGrade, classID, s.StudentID)
+			}
+		case "graduated":
+			_, err = graduateStmt.Exec(endYear, s.StudentID)
+		}
+		if err != nil {
+			return summary, err
+		}
+	}
+
+	if !dryRun {
+		_, err = tx.Exec(`INSERT INTO academic_year_rollovers (school_id, academic_year, promoted, repeated, graduated, performed_by)
+			VALUES (?, ?, ?, ?, ?, ?)`, schoolID, academicYear, summary.Promoted, summary.Repeated, summary.Graduated, performedBy)
+		if err != nil {
+			return summary, err
+		}
+	}
+	return summary, nil
+}
+
+func toIDSet(ids []int) map[int]bool {
+	set := make(map[int]bool, len(ids))
+	for _, id := range ids {
+		set[id] = true
+	}
+	return set
+}
+
+// unmatchedIDs returns ids from the request that no rollover matched.
+func unmatchedIDs(ids []int, matched map[int]bool) []int {
+	var missing []int
+	for _, id := range ids {
+		if !matched[id] {
+			missing = append(missing, id)
+		}
+	}
+	return missing
+}
+
+// RolloverSchool переводит учеников одной школы в следующий класс.
+func (ac *AcademicYearController) RolloverSchool(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := utils.VerifyToken(r)
+		if err != nil {
+			utils.RespondWithError(w, http.StatusUnauthorized, models.Error{Message: err.Error()})
+			return
+		}
+
+		var userRole string
+		err = db.QueryRow("SELECT role FROM users WHERE id = ?", userID).Scan(&userRole)
+		if err != nil {
+			log.Println("Error fetching user role:", err)
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Error fetching user details"})
+			return
+		}
+
+		schoolID, err := strconv.Atoi(mux.Vars(r)["school_id"])
+		if err != nil {
+			utils.RespondWithError(w, http.StatusBadRequest, models.Error{Message: "Invalid school ID"})
+			return
+		}
+
+		if userRole == "schooladmin" {
+			allowed, err := hasSchoolPermission(db, userID, schoolID, PermissionManageStudents)
+			if err != nil {
+				log.Println("Error checking school membership:", err)
+				utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Error checking permissions"})
+				return
+			}
+			if !allowed {
+				utils.RespondWithError(w, http.StatusForbidden, models.Error{Message: "You do not have permission to manage students of this school"})
+				return
+			}
+		} else if userRole != "superadmin" {
+			utils.RespondWithError(w, http.StatusForbidden, models.Error{Message: "You do not have permission to roll over the academic year"})
+			return
+		}
+
+		req, err := decodeRolloverRequest(r)
+		if err != nil {
+			utils.RespondWithError(w, http.StatusBadRequest, models.Error{Message: err.Error()})
+			return
+		}
+
+		tx, err := db.Begin()
+		if err != nil {
+			log.Println("Error starting transaction:", err)
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Failed to start transaction"})
+			return
+		}
+		defer tx.Rollback()
+
+		summary, err := rolloverSchool(tx, schoolID, req.AcademicYear, toIDSet(req.RepeatStudentIDs), userID, req.DryRun)
+		if err != nil {
+			log.Printf("Error rolling over school %d: %v", schoolID, err)
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Failed to roll over academic year"})
+			return
+		}
+		if summary.AlreadyRolled {
+			utils.RespondWithError(w, http.StatusConflict, models.Error{Message: "Academic year " + req.AcademicYear + " is already rolled over for this school"})
+			return
+		}
+		if missing := unmatchedIDs(req.RepeatStudentIDs, summary.matchedRepeater); len(missing) > 0 {
+			utils.RespondWithError(w, http.StatusBadRequest, models.Error{Message: fmt.Sprintf("Students %v are not active students of this school", missing)})
+			return
+		}
+
+		if !req.DryRun {
+			if err := tx.Commit(); err != nil {
+				log.Println("Error committing rollover:", err)
+				utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Failed to roll over academic year"})
+				return
+			}
+			log.Printf("Academic year %s rolled over for school %d by user %d", req.AcademicYear, schoolID, userID)
+		}
+
+		utils.ResponseJSON(w, map[string]interface{}{
+			"dry_run": req.DryRun,
+			"summary": summary,
+		})
+	}
+}
+
+// RolloverAllSchools переводит учеников всех школ (только superadmin).
+// Школы, для которых год уже переведён, пропускаются.
+func (ac *AcademicYearController) RolloverAllSchools(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := utils.VerifyToken(r)
+		if err != nil {
+			utils.RespondWithError(w, http.StatusUnauthorized, models.Error{Message: err.Error()})
+			return
+		}
+
+		var userRole string
+		err = db.QueryRow("SELECT role FROM users WHERE id = ?", userID).Scan(&userRole)
+		if err != nil {
+			log.Println("Error fetching user role:", err)
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Error fetching user details"})
+			return
+		}
+		if userRole != "superadmin" {
+			utils.RespondWithError(w, http.StatusForbidden, models.Error{Message: "Only superadmin can roll over all schools"})
+			return
+		}
+
+		req, err := decodeRolloverRequest(r)
+		if err != nil {
+			utils.RespondWithError(w, http.StatusBadRequest, models.Error{Message: err.Error()})
+			return
+		}
+
+		var schoolIDs []int
+		rows, err := db.Query("SELECT DISTINCT school_id FROM student WHERE status = 'active' AND school_id IS NOT NULL ORDER BY school_id")
+		if err != nil {
+			log.Println("Error fetching schools:", err)
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Failed to fetch schools"})
+			return
+		}
+		for rows.Next() {
+			var id int
+			if err := rows.Scan(&id); err != nil {
+				rows.Close()
+				log.Println("Error scanning school ID:", err)
+				utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Failed to fetch schools"})
+				return
+			}
+			schoolIDs = append(schoolIDs, id)
+		}
+		rows.Close()
+
+		tx, err := db.Begin()
+		if err != nil {
+			log.Println("Error starting transaction:", err)
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Failed to start transaction"})
+			return
+		}
+		defer tx.Rollback()
+
+		repeaters := toIDSet(req.RepeatStudentIDs)
+		matched := map[int]bool{}
+		summaries := []rolloverSummary{}
+		var skipped []int
+		for _, schoolID := range schoolIDs {
+			summary, err := rolloverSchool(tx, schoolID, req.AcademicYear, repeaters, userID, req.DryRun)
+			if err != nil {
+				log.Printf("Error rolling over school %d: %v", schoolID, err)
+				utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: fmt.Sprintf("Failed to roll over school %d", schoolID)})
+				return
+			}
+			if summary.AlreadyRolled {
+				skipped = append(skipped, schoolID)
+				continue
+			}
+			for id := range summary.matchedRepeater {
+				matched[id] = true
+			}
+			summaries = append(summaries, summary)
+		}
+
+		if missing := unmatchedIDs(req.RepeatStudentIDs, matched); len(missing) > 0 {
+			utils.RespondWithError(w, http.StatusBadRequest, models.Error{Message: fmt.Sprintf("Students %v are not active students of any school being rolled over", missing)})
+			return
+		}
+
+		if !req.DryRun {
+			if err := tx.Commit(); err != nil {
+				log.Println("Error committing rollover:", err)
+				utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Failed to roll over academic year"})
+				return
+			}
+			log.Printf("Academic year %s rolled over for %d schools by user %d", req.AcademicYear, len(summaries), userID)
+		}
+
+		utils.ResponseJSON(w, map[string]interface{}{
+			"dry_run":         req.DryRun,
+			"academic_year":   req.AcademicYear,
+			"schools":         summaries,
+			"skipped_schools": skipped,
+		})
+	}
+}
+
+// GetStudentGradeHistory возвращает классы ученика по учебным годам.
+func (ac *AcademicYearController) GetStudentGradeHistory(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := utils.VerifyToken(r)
+		if err != nil {
+			utils.RespondWithError(w, http.StatusUnauthorized, models.Error{Message: err.Error()})
+			return
+		}
+
+		studentID, err := strconv.Atoi(mux.Vars(r)["student_id"])
+		if err != nil {
+			utils.RespondWithError(w, http.StatusBadRequest, models.Error{Message: "Invalid student ID"})
+			return
+		}
+
+		var studentSchoolID sql.NullInt64
+		err = db.QueryRow("SELECT school_id FROM student WHERE student_id = ?", studentID).Scan(&studentSchoolID)
+		if err == sql.ErrNoRows {
+			utils.RespondWithError(w, http.StatusNotFound, models.Error{Message: "Student not found"})
+			return
+		} else if err != nil {
+			log.Println("Error fetching student:", err)
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Error fetching student"})
+			return
+		}
+
+		var userRole string
+		err = db.QueryRow("SELECT role FROM users WHERE id = ?", userID).Scan(&userRole)
+		if err != nil && err != sql.ErrNoRows {
+			log.Println("Error fetching user role:", err)
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Error fetching user details"})
+			return
+		}
+
+		// Ученик видит свою историю, админ школы — историю своих учеников
+		allowed := userRole == "superadmin" || (err == sql.ErrNoRows && userID == studentID)
+		if userRole == "schooladmin" && studentSchoolID.Valid {
+			allowed, err = hasSchoolPermission(db, userID, int(studentSchoolID.Int64), "")
+			if err != nil {
+				log.Println("Error checking school membership:", err)
+				utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Error checking permissions"})
+				return
+			}
+		}
+		if !allowed {
+			utils.RespondWithError(w, http.StatusForbidden, models.Error{Message: "You do not have permission to view this student's history"})
+			return
+		}
+
+		rows, err := db.Query(`
+			SELECT academic_year, school_id, grade, COALESCE(letter, ''), outcome
+			FROM student_grade_history
+			WHERE student_id = ?
+			ORDER BY academic_year`, studentID)
+		if err != nil {
+			log.Println("Error fetching grade history:", err)
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Failed to fetch grade history"})
+			return
+		}
+		defer rows.Close()
+
+		type historyEntry struct {
+			AcademicYear string `json:"academic_year"`
+			SchoolID     int    `json:"school_id"`
+			Grade        int    `json:"grade"`
+			Letter       string `json:"letter"`
+			Outcome      string `json:"outcome"`
+		}
+		history := []historyEntry{}
+		for rows.Next() {
+			var h historyEntry
+			if err := rows.Scan(&h.AcademicYear, &h.SchoolID, &h.Grade, &h.Letter, &h.Outcome); err != nil {
+				log.Println("Error scanning grade history:", err)
+				utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Failed to fetch grade history"})
+				return
+			}
+			history = append(history, h)
+		}
+
+		utils.ResponseJSON(w, history)
+	}
+}
+
+// GetAlumniBySchool возвращает выпускников школы, опционально за один год.
+func (ac *AcademicYearController) GetAlumniBySchool(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := utils.VerifyToken(r)
+		if err != nil {
+			utils.RespondWithError(w, http.StatusUnauthorized, models.Error{Message: err.Error()})
+			return
+		}
+
+		schoolID, err := strconv.Atoi(mux.Vars(r)["school_id"])
+		if err != nil {
+			utils.RespondWithError(w, http.StatusBadRequest, models.Error{Message: "Invalid school ID"})
+			return
+		}
+
+		var userRole string
+		err = db.QueryRow("SELECT role FROM users WHERE id = ?", userID).Scan(&userRole)
+		if err != nil {
+			log.Println("Error fetching user role:", err)
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Error fetching user details"})
+			return
+		}
+		if userRole == "schooladmin" {
+			isMember, err := hasSchoolPermission(db, userID, schoolID, "")
+			if err != nil {
+				log.Println("Error checking school membership:", err)
+				utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Error checking permissions"})
+				return
+			}
+			if !isMember {
+				utils.RespondWithError(w, http.StatusForbidden, models.Error{Message: "You do not have permission to view this school's data"})
+				return
+			}
+		} else if userRole != "superadmin" {
+			utils.RespondWithError(w, http.StatusForbidden, models.Error{Message: "You do not have permission to view alumni"})
+			return
+		}
+
+		query := `SELECT student_id, first_name, last_name, COALESCE(patronymic, ''), COALESCE(letter, ''), graduation_year
+			FROM student
+			WHERE school_id = ? AND status = 'alumni'`
+		args := []interface{}{schoolID}
+		if year := r.URL.Query().Get("graduation_year"); year != "" {
+			y, err := strconv.Atoi(year)
+			if err != nil {
+				utils.RespondWithError(w, http.StatusBadRequest, models.Error{Message: "Invalid graduation_year"})
+				return
+			}
+			query += " AND graduation_year = ?"
+			args = append(args, y)
+		}
+		query += " ORDER BY graduation_year DESC, last_name, first_name"
+
+		rows, err := db.Query(query, args...)
+		if err != nil {
+			log.Println("Error fetching alumni:", err)
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Failed to fetch alumni"})
+			return
+		}
+		defer rows.Close()
+
+		type alumnus struct {
+			StudentID      int    `json:"student_id"`
+			FirstName      string `json:"first_name"`
+			LastName       string `json:"last_name"`
+			Patronymic     string `json:"patronymic"`
+			Letter         string `json:"letter"`
+			GraduationYear *int   `json:"graduation_year"`
+		}
+		alumni := []alumnus{}
+		for rows.Next() {
+			var a alumnus
+			var gradYear sql.NullInt64
+			if err := rows.Scan(&a.StudentID, &a.FirstName, &a.LastName, &a.Patronymic, &a.Letter, &gradYear); err != nil {
+				log.Println("Error scanning alumni:", err)
+				utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Failed to fetch alumni"})
+				return
+			}
+			if gradYear.Valid {
+				y := int(gradYear.Int64)
+				a.GraduationYear = &y
+			}
+			alumni = append(alumni, a)
+		}
+
+		utils.ResponseJSON(w, alumni)
+	}
+}
@@ -183,6 +183,13 @@ func (ec *EventController) AddEvent(db *sql.DB) http.HandlerFunc {
 			return
 		}
 
+		// Окно регистрации (необязательно)
+		windowFields, err := parseRegistrationWindowForm(r, registrationWindow{})
+		if err != nil {
+			utils.RespondWithError(w, http.StatusBadRequest, models.Error{Message: err.Error()})
+			return
+		}
+
 		// Handle file upload for photo
 		file, handler, err := r.FormFile("photo")
 		if err != nil && err != http.ErrMissingFile {
@@ -221,9 +228,7 @@ func (ec *EventController) AddEvent(db *sql.DB) http.HandlerFunc {
 			}
 
 			// Check if user is associated with the school
-			var isUserAssociatedWithSchool bool
-			err := db.QueryRow("SELECT EXISTS(SELECT 1 FROM users WHERE id = ? AND school_id = ? AND role = 'schooladmin')",
-				userID, event.SchoolID).Scan(&isUserAssociatedWithSchool)
+			isUserAssociatedWithSchool, err := hasSchoolPermission(db, userID, event.SchoolID, PermissionManageEvents)
 
 			if err != nil {
 				log.Println("Error checking school association:", err)
@@ -281,16 +286,29 @@ func (ec *EventController) AddEvent(db *sql.DB) http.HandlerFunc {
 		event.CreatedAt = now
 		event.UpdatedAt = now
 
+		// Школа создаёт черновик; submit_for_review=true — сразу на модерацию
+		event.Status, err = initialPublicationStatus(db, userID, r.FormValue("submit_for_review") == "true")
+		if err != nil {
+			log.Println("Error checking user role:", err)
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Error retrieving user information"})
+			return
+		}
+
 		// Insert event with proper error handling
 		result, err := db.Exec(
 			`INSERT INTO Events (
                 school_id, user_id, event_name, description, photo, 
                 start_date, end_date, location, 
-                grade, limit_count, participants, limit_participants, created_at, updated_at, created_by, category
-            ) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+                grade, limit_count, participants, limit_participants, created_at, updated_at, created_by, category,
+                registration_opens_at, registration_closes_at, late_registration_until, cancellation_deadline,
+                status, submitted_at
+            ) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
 			event.SchoolID, event.UserID, event.EventName, event.Description, event.Photo,
 			event.StartDate, event.EndDate, event.Location,
 			event.Grade, event.Limit, event.Participants, event.LimitParticipants, now, now, event.CreatedBy, event.Category,
+			windowFields["registration_opens_at"], windowFields["registration_closes_at"],
+			windowFields["late_registration_until"], windowFields["cancellation_deadline"],
+			event.Status, submittedAtFor(event.Status),
 		)
 
 		if err != nil {
@@ -311,10 +329,15 @@ func (ec *EventController) AddEvent(db *sql.DB) http.HandlerFunc {
 			return
 		}
 
+		if err := recordPublicationCreated(db, "event", eventID, event.Status, userID); err != nil {
+			log.Println("Error writing publication log:", err)
+		}
+
 		// Create response with the new event ID
 		response := map[string]interface{}{
 			"message":  "Event created successfully",
 			"event_id": eventID,
+			"status":   event.Status,
 		}
 
 		utils.ResponseJSON(w, response)
@@ -632,6 +655,17 @@ func (ec *EventController) GetEvents(db *sql.DB) http.HandlerFunc {
 				return
 			}
 
+			// Неопубликованное мероприятие видят только организаторы
+			visible, err := canSeePublication(db, optionalUserID(r), event.SchoolID, event.Status)
+			if err != nil {
+				utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Error fetching event"})
+				return
+			}
+			if !visible {
+				utils.RespondWithError(w, http.StatusNotFound, models.Error{Message: "Event not found"})
+				return
+			}
+
 			// Fetch participants count directly from EventRegistrations table
 			var participantsCount int
 			err = db.QueryRow("SELECT COUNT(*) FROM EventRegistrations WHERE event_id = ?", event.ID).Scan(&participantsCount)
@@ -656,11 +690,13 @@ func (ec *EventController) GetEvents(db *sql.DB) http.HandlerFunc {
             SELECT e.id, e.school_id, e.user_id, e.event_name, e.description, 
             e.photo, e.start_date, e.end_date, e.location, 
             e.grade, e.limit_count as ` + "`limit`" + `, e.participants, e.limit_participants, e.created_at, e.updated_at, 
-            u.email AS created_by, e.category, s.school_name
+            u.email AS created_by, e.category, s.school_name,
+            COALESCE(CAST(e.registration_opens_at AS CHAR), ''), COALESCE(CAST(e.registration_closes_at AS CHAR), ''),
+            COALESCE(CAST(e.late_registration_until AS CHAR), ''), COALESCE(CAST(e.cancellation_deadline AS CHAR), '')
             FROM Events e
             LEFT JOIN users u ON e.user_id = u.id
             LEFT JOIN Schools s ON e.school_id = s.school_id
-            WHERE 1=1
+            WHERE e.status = 'published'
         `)
 
 		var args []interface{}
@@ -779,6 +815,7 @@ func (ec *EventController) GetEvents(db *sql.DB) http.HandlerFunc {
 				&event.Photo, &event.StartDate, &event.EndDate, &event.Location,
 				&event.Grade, &event.Limit, &event.Participants, &event.LimitParticipants,
 				&event.CreatedAt, &event.UpdatedAt, &event.CreatedBy, &event.Category, &event.SchoolName,
+				&event.RegistrationOpensAt, &event.RegistrationClosesAt, &event.LateRegistrationUntil, &event.CancellationDeadline,
 			)
 			if err != nil {
 				log.Println("Error scanning event row:", err)
@@ -819,8 +856,7 @@ func (ec *EventController) UpdateEvent(db *sql.DB) http.HandlerFunc {
 
 		// Получаем роль пользователя
 		var userRole string
-		var userSchoolID sql.NullInt64
-		err = db.QueryRow("SELECT role, school_id FROM users WHERE id = ?", userID).Scan(&userRole, &userSchoolID)
+		err = db.QueryRow("SELECT role FROM users WHERE id = ?", userID).Scan(&userRole)
 		if err != nil {
 			log.Println("Error fetching user role:", err)
 			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Error fetching user information"})
@@ -868,7 +904,13 @@ func (ec *EventController) UpdateEvent(db *sql.DB) http.HandlerFunc {
 
 		// Проверяем права доступа для schooladmin
 		if userRole == "schooladmin" {
-			if !userSchoolID.Valid || int(userSchoolID.Int64) != eventSchoolID {
+			allowed, err := hasSchoolPermission(db, userID, eventSchoolID, PermissionManageEvents)
+			if err != nil {
+				log.Println("Error checking school membership:", err)
+				utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Error fetching user information"})
+				return
+			}
+			if !allowed {
 				utils.RespondWithError(w, http.StatusForbidden, models.Error{Message: "You don't have permission to update this event"})
 				return
 			}
@@ -982,6 +1024,22 @@ func (ec *EventController) UpdateEvent(db *sql.DB) http.HandlerFunc {
 			updatedEvent.Category = eventCategory
 		}
 
+		// Окно регистрации: проверяем порядок с учётом текущих значений
+		currentWindow, err := loadEventWindow(db, eventID)
+		if err != nil {
+			log.Println("Error fetching registration window:", err)
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Error checking occasion existence"})
+			return
+		}
+		windowFields, err := parseRegistrationWindowForm(r, currentWindow)
+		if err != nil {
+			utils.RespondWithError(w, http.StatusBadRequest, models.Error{Message: err.Error()})
+			return
+		}
+		for field, value := range windowFields {
+			updateFields[field] = value
+		}
+
 		// Обработка фото
 		file, handler, err := r.FormFile("photo")
 		if err == nil {
@@ -1018,6 +1076,15 @@ func (ec *EventController) UpdateEvent(db *sql.DB) http.HandlerFunc {
 			return
 		}
 
+		// Изменение проверенных полей опубликованного мероприятия
+		// возвращает его на модерацию
+		changedReviewed, err := changedReviewedColumns(db, publicationKinds["event"], eventID, updateFields)
+		if err != nil {
+			log.Println("Error comparing event fields:", err)
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Failed to update event"})
+			return
+		}
+
 		// Строим SQL запрос
 		query := "UPDATE Events SET "
 		args := make([]interface{}, 0, len(updateFields)+1)
@@ -1049,6 +1116,21 @@ func (ec *EventController) UpdateEvent(db *sql.DB) http.HandlerFunc {
 			return
 		}
 
+		returnedToReview, err := returnToReview(db, publicationKinds["event"], eventID, userID, changedReviewed)
+		if err != nil {
+			log.Println("Error returning event to review:", err)
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Event updated but failed to return it to review"})
+			return
+		}
+
+		// Участия ссылаются на мероприятие по event_id, название хранится
+		// для отображения — обновляем его вслед за мероприятием
+		if eventName, ok := updateFields["event_name"]; ok {
+			if _, err := db.Exec("UPDATE events_participants SET events_name = ? WHERE event_id = ?", eventName, eventID); err != nil {
+				log.Println("Error syncing participant event names:", err)
+			}
+		}
+
 		// Получаем обновленное событие
 		var updated models.Event
 		err = db.QueryRow(`
@@ -1067,16 +1149,18 @@ func (ec *EventController) UpdateEvent(db *sql.DB) http.HandlerFunc {
 		if err != nil {
 			log.Println("Error fetching updated event:", err)
 			utils.ResponseJSON(w, map[string]interface{}{
-				"message":  "Event updated successfully",
-				"event_id": eventID,
+				"message":            "Event updated successfully",
+				"event_id":           eventID,
+				"returned_to_review": returnedToReview,
 			})
 			return
 		}
 
 		// Отправляем ответ
 		utils.ResponseJSON(w, map[string]interface{}{
-			"message": "Event updated successfully",
-			"event":   updated,
+			"message":            "Event updated successfully",
+			"event":              updated,
+			"returned_to_review": returnedToReview,
 		})
 	}
 }
@@ -1121,6 +1205,10 @@ func (c *EventController) DeleteEvent(db *sql.DB) http.HandlerFunc {
 			return
 		}
 
+		if _, err := db.Exec("DELETE FROM eligibility_rules WHERE target_type = 'event' AND target_id = ?", eventID); err != nil {
+			log.Printf("Error deleting eligibility rules for event %d: %v", eventID, err)
+		}
+
 		log.Printf("Event with ID %d deleted successfully", eventID)
 		utils.ResponseJSON(w, map[string]string{"message": "Event deleted successfully"})
 	}
@@ -1167,9 +1255,7 @@ func (ec *EventController) GetEventsBySchoolID(db *sql.DB) http.HandlerFunc {
 
 		// Authorization checks
 		if user.Role == "schooladmin" {
-			var isAssociated bool
-			err = db.QueryRow("SELECT EXISTS(SELECT 1 FROM users WHERE id = ? AND school_id = ? AND role = 'schooladmin')",
-				userID, schoolID).Scan(&isAssociated)
+			isAssociated, err := hasSchoolPermission(db, userID, int(schoolID), "")
 			if err != nil {
 				log.Println("Error checking school association:", err)
 				utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Error validating school association"})
@@ -1193,6 +1279,7 @@ func (ec *EventController) GetEventsBySchoolID(db *sql.DB) http.HandlerFunc {
 			"limit":     query.Get("limit"),
 			"offset":    query.Get("offset"),
 			"category":  query.Get("category"),
+			"status":    query.Get("status"),
 		}
 
 		// Build SQL query
@@ -1201,7 +1288,7 @@ func (ec *EventController) GetEventsBySchoolID(db *sql.DB) http.HandlerFunc {
 			SELECT e.id, e.school_id, e.user_id, e.event_name, e.description,
 			e.photo, e.start_date, e.end_date, e.location,
 			e.grade, e.limit_count as ` + "`limit`" + `, e.participants, e.limit_participants,
-			e.created_at, e.updated_at, e.created_by, e.category, s.school_name
+			e.created_at, e.updated_at, e.created_by, e.category, s.school_name, e.status
 			FROM Events e
 			LEFT JOIN users u ON e.user_id = u.id
 			LEFT JOIN Schools s ON e.school_id = s.school_id
@@ -1243,6 +1330,17 @@ func (ec *EventController) GetEventsBySchoolID(db *sql.DB) http.HandlerFunc {
 			args = append(args, params["date_to"])
 		}
 
+		// Фильтр по статусу модерации
+		switch params["status"] {
+		case "":
+		case PublicationDraft, PublicationPendingReview, PublicationPublished, PublicationRejected:
+			queryBuilder.WriteString(" AND e.status = ?")
+			args = append(args, params["status"])
+		default:
+			utils.RespondWithError(w, http.StatusBadRequest, models.Error{Message: "Invalid status. Allowed values are: draft, pending_review, published, rejected"})
+			return
+		}
+
 		// Handle category filter
 		if params["category"] != "" {
 			allowedCategories := []string{"Science", "Humanities", "Sport", "Creative"}
@@ -1307,7 +1405,7 @@ func (ec *EventController) GetEventsBySchoolID(db *sql.DB) http.HandlerFunc {
 				&event.ID, &event.SchoolID, &event.UserID, &event.EventName, &event.Description,
 				&event.Photo, &event.StartDate, &event.EndDate, &event.Location,
 				&event.Grade, &event.Limit, &event.Participants, &event.LimitParticipants,
-				&event.CreatedAt, &event.UpdatedAt, &event.CreatedBy, &event.Category, &event.SchoolName,
+				&event.CreatedAt, &event.UpdatedAt, &event.CreatedBy, &event.Category, &event.SchoolName, &event.Status,
 			)
 			if err != nil {
 				log.Println("Error scanning event row:", err)
@@ -1362,7 +1460,7 @@ func (ec *EventController) CountEvents(db *sql.DB) http.HandlerFunc {
 
 		// Build query for counting events
 		queryBuilder := strings.Builder{}
-		queryBuilder.WriteString("SELECT COUNT(*) FROM Events WHERE 1=1")
+		queryBuilder.WriteString("SELECT COUNT(*) FROM Events WHERE status = 'published'")
 
 		var args []interface{}
 
@@ -1483,9 +1581,7 @@ func (ec *EventController) GetEventsBySchoolAndType(db *sql.DB) http.HandlerFunc
 		// Check permissions based on role
 		if user.Role == "schooladmin" {
 			// Verify user is associated with the school
-			var isAssociated bool
-			err = db.QueryRow("SELECT EXISTS(SELECT 1 FROM users WHERE id = ? AND school_id = ? AND role = 'schooladmin')",
-				userID, schoolID).Scan(&isAssociated)
+			isAssociated, err := hasSchoolPermission(db, userID, int(schoolID), "")
 			if err != nil {
 				log.Println("Error checking school association:", err)
 				utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Error validating school association"})
@@ -1652,7 +1748,10 @@ func getEventByID(db *sql.DB, id int) (models.Event, error) {
         SELECT id, school_id, user_id, event_name, description, 
                photo, start_date, end_date, location, 
                grade, limit_count as limit, participants, limit_participants, created_at, updated_at, 
-               u.email AS created_by, category, s.school_name
+               u.email AS created_by, category, s.school_name,
+               COALESCE(CAST(e.registration_opens_at AS CHAR), ''), COALESCE(CAST(e.registration_closes_at AS CHAR), ''),
+               COALESCE(CAST(e.late_registration_until AS CHAR), ''), COALESCE(CAST(e.cancellation_deadline AS CHAR), ''),
+               e.status
         FROM Events e
         LEFT JOIN users u ON e.user_id = u.id
         LEFT JOIN Schools s ON e.school_id = s.school_id
@@ -1662,6 +1761,8 @@ func getEventByID(db *sql.DB, id int) (models.Event, error) {
 		&event.Photo, &event.StartDate, &event.EndDate, &event.Location,
 		&event.Grade, &event.Limit, &event.Participants, &event.LimitParticipants,
 		&event.CreatedAt, &event.UpdatedAt, &event.CreatedBy, &event.Category, &event.SchoolName,
+		&event.RegistrationOpensAt, &event.RegistrationClosesAt, &event.LateRegistrationUntil, &event.CancellationDeadline,
+		&event.Status,
 	)
 	if err != nil {
 		return event, err
@@ -1720,7 +1821,7 @@ func (ec *EventController) GetEventsByCategory(db *sql.DB) http.HandlerFunc {
                    e.start_date, e.end_date, e.location,     e.grade
             FROM Events e
             LEFT JOIN Schools s ON e.school_id = s.school_id
-            WHERE e.category = ?
+            WHERE e.category = ? AND e.status = 'published'
         `)
 
 		var args []interface{}
@@ -1897,7 +1998,12 @@ func (ec *EventController) GetEventByID(db *sql.DB) http.HandlerFunc {
                    COALESCE(e.grade, 0) as grade,
                    COALESCE(e.created_at, '') as created_at, 
                    COALESCE(e.updated_at, '') as updated_at, 
-                   COALESCE(e.category, '') as category
+                   COALESCE(e.category, '') as category,
+                   COALESCE(CAST(e.registration_opens_at AS CHAR), '') as registration_opens_at,
+                   COALESCE(CAST(e.registration_closes_at AS CHAR), '') as registration_closes_at,
+                   COALESCE(CAST(e.late_registration_until AS CHAR), '') as late_registration_until,
+                   COALESCE(CAST(e.cancellation_deadline AS CHAR), '') as cancellation_deadline,
+                   e.status
             FROM Events e
             LEFT JOIN Schools s ON e.school_id = s.school_id
             WHERE e.id = ?
@@ -1924,6 +2030,12 @@ func (ec *EventController) GetEventByID(db *sql.DB) http.HandlerFunc {
 			CreatedAt    string `json:"created_at"`
 			UpdatedAt    string `json:"updated_at"`
 			Category     string `json:"category"`
+
+			RegistrationOpensAt   string `json:"registration_opens_at,omitempty"`
+			RegistrationClosesAt  string `json:"registration_closes_at,omitempty"`
+			LateRegistrationUntil string `json:"late_registration_until,omitempty"`
+			CancellationDeadline  string `json:"cancellation_deadline,omitempty"`
+			Status                string `json:"status"`
 		}
 
 		// Scan the result
@@ -1933,6 +2045,8 @@ func (ec *EventController) GetEventByID(db *sql.DB) http.HandlerFunc {
 			&event.PhotoURL, &event.Participants, &event.Limit,
 			&event.StartDate, &event.EndDate, &event.Location, &event.Grade,
 			&event.CreatedAt, &event.UpdatedAt, &event.Category,
+			&event.RegistrationOpensAt, &event.RegistrationClosesAt, &event.LateRegistrationUntil, &event.CancellationDeadline,
+			&event.Status,
 		)
 		if err == sql.ErrNoRows {
 			log.Printf("Event with ID %d not found", eventID)
@@ -1945,6 +2059,17 @@ func (ec *EventController) GetEventByID(db *sql.DB) http.HandlerFunc {
 			return
 		}
 
+		// Черновики и мероприятия на модерации видят только организаторы
+		visible, err := canSeePublication(db, optionalUserID(r), event.SchoolID, event.Status)
+		if err != nil {
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Error fetching event"})
+			return
+		}
+		if !visible {
+			utils.RespondWithError(w, http.StatusNotFound, models.Error{Message: "Event not found"})
+			return
+		}
+
 		// Prepare response
 		utils.ResponseJSON(w, event)
 	}
@@ -1961,7 +2086,7 @@ func (ec *EventController) GetEventCountsBySchool(db *sql.DB) http.HandlerFunc {
 		query := `
             SELECT s.school_id, s.school_name, COUNT(e.id) as event_count
             FROM Schools s
-            LEFT JOIN Events e ON s.school_id = e.school_id
+            LEFT JOIN Events e ON s.school_id = e.school_id AND e.status = 'published'
             GROUP BY s.school_id, s.school_name
             ORDER BY s.school_id ASC
         `
@@ -2025,7 +2150,7 @@ func (ec *EventController) GetEventCountsAndScoresBySchool(db *sql.DB) http.Hand
 		query := `
             SELECT s.school_id, s.school_name, COUNT(e.id) as event_count
             FROM Schools s
-            LEFT JOIN Events e ON s.school_id = e.school_id
+            LEFT JOIN Events e ON s.school_id = e.school_id AND e.status = 'published'
             GROUP BY s.school_id, s.school_name
             ORDER BY s.school_id ASC
         `
@@ -2299,7 +2424,7 @@ func (ec *EventController) GetEventCountAndScoreBySchoolID(db *sql.DB) http.Hand
 		err = db.QueryRow(`
 			SELECT s.school_name, COUNT(e.id) as event_count
 			FROM Schools s
-			LEFT JOIN Events e ON s.school_id = e.school_id AND YEAR(e.end_date) = ?
+			LEFT JOIN Events e ON s.school_id = e.school_id AND YEAR(e.end_date) = ? AND e.status = 'published'
 			WHERE s.school_id = ?
 			GROUP BY s.school_id, s.school_name
 		`, year, schoolID).Scan(&schoolName, &eventCount)
@@ -2315,7 +2440,7 @@ func (ec *EventController) GetEventCountAndScoreBySchoolID(db *sql.DB) http.Hand
 			FROM (
 				SELECT COUNT(id) as event_count
 				FROM Events
-				WHERE YEAR(end_date) = ?
+				WHERE YEAR(end_date) = ? AND status = 'published'
 				GROUP BY school_id
 			) as counts
 		`, year).Scan(&maxEventCount)
@@ -2350,7 +2475,7 @@ func (ec *EventController) GetEventCountAndScoreBySchoolID(db *sql.DB) http.Hand
 				END as month_name,
 				COUNT(*) as count
 			FROM Events 
-			WHERE school_id = ? AND end_date IS NOT NULL AND end_date != '' AND YEAR(end_date) = ?
+			WHERE school_id = ? AND status = 'published' AND end_date IS NOT NULL AND end_date != '' AND YEAR(end_date) = ?
 			GROUP BY MONTH(end_date)
 		`
 
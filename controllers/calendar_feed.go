@@ -0,0 +1,619 @@
+package controllers
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"net/http"
+	"ranking-school/models"
+	"ranking-school/utils"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+	"github.com/gorilla/mux"
+)
+
+// Календарные ленты (iCalendar, RFC 5545) для телефонов и почтовых
+// клиентов: личная лента ученика (его регистрации), лента школы и общая
+// лента с фильтрами. Календарные приложения не передают JWT, поэтому
+// ленты открываются по ссылке с отзываемым токеном (?token=...).
+type CalendarFeedController struct{}
+
+// В ленты попадают мероприятия, закончившиеся не раньше чем
+// calendarFeedHistoryDays дней назад.
+const calendarFeedHistoryDays = 90
+
+const calendarProdID = "-//Ranking School//Calendar Feed//RU"
+
+type calendarFeedOwner struct {
+	Type string // student / user
+	ID   int
+}
+
+func hashFeedToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func newFeedToken() (string, error) {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// feedOwnerFromRequest определяет владельца по JWT: ученики и сотрудники
+// хранятся в разных таблицах, их различает claim role.
+func feedOwnerFromRequest(r *http.Request) (calendarFeedOwner, error) {
+	userID, err := utils.VerifyToken(r)
+	if err != nil {
+		return calendarFeedOwner{}, err
+	}
+	owner := calendarFeedOwner{Type: "user", ID: userID}
+	token, err := utils.ParseToken(strings.Replace(r.Header.Get("Authorization"), "Bearer ", "", 1))
+	if err == nil {
+		if claims, ok := token.Claims.(jwt.MapClaims); ok {
+			if role, _ := claims["role"].(string); role == "student" {
+				owner.Type = "student"
+			}
+		}
+	}
+	return owner, nil
+}
+
+// feedOwnerFromToken проверяет токен ленты и отмечает его использование.
+func feedOwnerFromToken(db *sql.DB, token string) (calendarFeedOwner, error) {
+	var owner calendarFeedOwner
+	var tokenID int
+	err := db.QueryRow(`
+		SELECT id, owner_type, owner_id FROM calendar_feed_tokens
+		WHERE token_hash = ? AND revoked_at IS NULL`, hashFeedToken(token)).Scan(&tokenID, &owner.Type, &owner.ID)
+	if err != nil {
+		return owner, err
+	}
+	if _, err := db.Exec("UPDATE calendar_feed_tokens SET last_used_at = NOW() WHERE id = ?", tokenID); err != nil {
+		log.Printf("Failed to update calendar token %d usage: %v", tokenID, err)
+	}
+	return owner, nil
+}
+
+// requireFeedToken — проверка ?token= для ICS-лент.
+func requireFeedToken(w http.ResponseWriter, r *http.Request, db *sql.DB) (calendarFeedOwner, bool) {
+	token := strings.TrimSpace(r.URL.Query().Get("token"))
+	if token == "" {
+		utils.RespondWithError(w, http.StatusUnauthorized, models.Error{Message: "Feed token is required"})
+		return calendarFeedOwner{}, false
+	}
+	owner, err := feedOwnerFromToken(db, token)
+	if err == sql.ErrNoRows {
+		utils.RespondWithError(w, http.StatusUnauthorized, models.Error{Message: "Invalid or revoked feed token"})
+		return owner, false
+	} else if err != nil {
+		log.Printf("Error checking calendar token: %v", err)
+		utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Error checking feed token"})
+		return owner, false
+	}
+	return owner, true
+}
+
+func feedBaseURL(r *http.Request) string {
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	if proto := r.Header.Get("X-Forwarded-Proto"); proto != "" {
+		scheme = proto
+	}
+	return scheme + "://" + r.Host
+}
+
+func feedURLs(r *http.Request, owner calendarFeedOwner, token string) map[string]string {
+	base := feedBaseURL(r)
+	urls := map[string]string{
+		"public": base + "/api/calendar/public.ics?token=" + token,
+		"school": base + "/api/calendar/schools/{school_id}.ics?token=" + token,
+	}
+	if owner.Type == "student" {
+		urls["personal"] = base + "/api/calendar/my.ics?token=" + token
+	}
+	return urls
+}
+
+// CreateCalendarFeedToken выпускает новый токен лент. Прежний токен
+// владельца отзывается, подписки по старым ссылкам перестают работать.
+// Токен показывается только в этом ответе.
+func (cc *CalendarFeedController) CreateCalendarFeedToken(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		owner, err := feedOwnerFromRequest(r)
+		if err != nil {
+			utils.RespondWithError(w, http.StatusUnauthorized, models.Error{Message: "Unauthorized"})
+			return
+		}
+
+		token, err := newFeedToken()
+		if err != nil {
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Failed to generate token"})
+			return
+		}
+
+		tx, err := db.Begin()
+		if err != nil {
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Database error"})
+			return
+		}
+		defer tx.Rollback()
+
+		if _, err := tx.Exec("UPDATE calendar_feed_tokens SET revoked_at = NOW() WHERE owner_type = ? AND owner_id = ? AND revoked_at IS NULL", owner.Type, owner.ID); err != nil {
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Failed to create feed token"})
+			return
+		}
+		if _, err := tx.Exec("INSERT INTO calendar_feed_tokens (owner_type, owner_id, token_hash) VALUES (?, ?, ?)", owner.Type, owner.ID, hashFeedToken(token)); err != nil {
+			log.Printf("Error creating calendar token for %s %d: %v", owner.Type, owner.ID, err)
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Failed to create feed token"})
+			return
+		}
+		if err := tx.Commit(); err != nil {
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Failed to create feed token"})
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		utils.ResponseJSON(w, map[string]interface{}{
+			"token": token,
+			"feeds": feedURLs(r, owner, token),
+		})
+	}
+}
+
+// GetCalendarFeedToken — есть ли у пользователя действующий токен.
+func (cc *CalendarFeedController) GetCalendarFeedToken(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		owner, err := feedOwnerFromRequest(r)
+		if err != nil {
+			utils.RespondWithError(w, http.StatusUnauthorized, models.Error{Message: "Unauthorized"})
+			return
+		}
+
+		var createdAt string
+		var lastUsedAt sql.NullString
+		err = db.QueryRow(`
+			SELECT CAST(created_at AS CHAR), CAST(last_used_at AS CHAR) FROM calendar_feed_tokens
+			WHERE owner_type = ? AND owner_id = ? AND revoked_at IS NULL
+			ORDER BY id DESC LIMIT 1`, owner.Type, owner.ID).Scan(&createdAt, &lastUsedAt)
+		if err == sql.ErrNoRows {
+			utils.ResponseJSON(w, map[string]interface{}{"active": false})
+			return
+		} else if err != nil {
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Database error"})
+			return
+		}
+
+		utils.ResponseJSON(w, map[string]interface{}{
+			"active":       true,
+			"created_at":   createdAt,
+			"last_used_at": lastUsedAt.String,
+		})
+	}
+}
+
+// RevokeCalendarFeedToken отзывает токен лент пользователя.
+func (cc *CalendarFeedController) RevokeCalendarFeedToken(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		owner, err := feedOwnerFromRequest(r)
+		if err != nil {
+			utils.RespondWithError(w, http.StatusUnauthorized, models.Error{Message: "Unauthorized"})
+			return
+		}
+		res, err := db.Exec("UPDATE calendar_feed_tokens SET revoked_at = NOW() WHERE owner_type = ? AND owner_id = ? AND revoked_at IS NULL", owner.Type, owner.ID)
+		if err != nil {
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Failed to revoke feed token"})
+			return
+		}
+		if affected, _ := res.RowsAffected(); affected == 0 {
+			utils.RespondWithError(w, http.StatusNotFound, models.Error{Message: "No active feed token"})
+			return
+		}
+		utils.ResponseJSON(w, map[string]string{"message": "Feed token revoked"})
+	}
+}
+
+// calendarItem — одно событие VEVENT.
+type calendarItem struct {
+	UID         string
+	Summary     string
+	Description string
+	Location    string
+	Start       string
+	End         string
+	Tentative   bool
+}
+
+func feedHistoryCutoff() string {
+	return time.Now().AddDate(0, 0, -calendarFeedHistoryDays).Format("2006-01-02")
+}
+
+func collectCalendarItems(rows *sql.Rows, build func(scan func(dest ...interface{}) error) (calendarItem, error)) ([]calendarItem, error) {
+	defer rows.Close()
+	var items []calendarItem
+	for rows.Next() {
+		item, err := build(rows.Scan)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	return items, rows.Err()
+}
+
+func scanEventItem(scan func(dest ...interface{}) error) (calendarItem, error) {
+	var item calendarItem
+	var id int
+	var schoolName string
+	err := scan(&id, &item.Summary, &item.Description, &item.Location, &item.Start, &item.End, &schoolName)
+	item.UID = fmt.Sprintf("event-%d@ranking-school", id)
+	if schoolName != "" {
+		item.Description = strings.TrimSpace(schoolName + "\n" + item.Description)
+	}
+	return item, err
+}
+
+func scanOlympiadItem(scan func(dest ...interface{}) error) (calendarItem, error) {
+	var item calendarItem
+	var id int
+	var level string
+	err := scan(&id, &item.Summary, &item.Description, &level, &item.Location, &item.Start, &item.End)
+	item.UID = fmt.Sprintf("olympiad-%d@ranking-school", id)
+	item.Summary = "Олимпиада: " + item.Summary
+	if level != "" {
+		item.Description = strings.TrimSpace("Уровень: " + level + "\n" + item.Description)
+	}
+	return item, err
+}
+
+// tentativeScan дочитывает последний столбец — заявка в листе ожидания.
+func tentativeScan(build func(scan func(dest ...interface{}) error) (calendarItem, error)) func(scan func(dest ...interface{}) error) (calendarItem, error) {
+	return func(scan func(dest ...interface{}) error) (calendarItem, error) {
+		var tentative bool
+		item, err := build(func(dest ...interface{}) error {
+			return scan(append(dest, &tentative)...)
+		})
+		item.Tentative = tentative
+		return item, err
+	}
+}
+
+const calendarEventColumns = `
+	e.id, COALESCE(e.event_name, ''), COALESCE(e.description, ''), COALESCE(e.location, ''),
+	COALESCE(CAST(e.start_date AS CHAR), ''), COALESCE(CAST(e.end_date AS CHAR), ''), COALESCE(s.school_name, '')`
+
+const calendarOlympiadColumns = `
+	so.subject_olympiad_id, COALESCE(so.subject_name, ''), COALESCE(so.description, ''), COALESCE(so.level, ''),
+	COALESCE(s.school_name, ''), COALESCE(CAST(so.date AS CHAR), ''), COALESCE(CAST(so.end_date AS CHAR), CAST(so.date AS CHAR), '')`
+
+// GetMyCalendarFeed — лента ученика: мероприятия и олимпиады, на которые
+// он зарегистрирован (лист ожидания — как предварительные), и сессии
+// мероприятий, которые он посещает.
+func (cc *CalendarFeedController) GetMyCalendarFeed(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		owner, ok := requireFeedToken(w, r, db)
+		if !ok {
+			return
+		}
+		if owner.Type != "student" {
+			utils.RespondWithError(w, http.StatusForbidden, models.Error{Message: "Personal feed is available to students only, use the school or public feed"})
+			return
+		}
+		cutoff := feedHistoryCutoff()
+
+		rows, err := db.Query(`
+			SELECT`+calendarEventColumns+`, r.status = 'waitlisted'
+			FROM EventRegistrations r
+			JOIN Events e ON e.id = r.event_id
+			LEFT JOIN Schools s ON s.school_id = e.school_id
+			WHERE r.student_id = ? AND r.status IN (`+eventQueue.seatStatuses+`, 'waitlisted')
+			  AND e.status = 'published' AND e.end_date >= ?`, owner.ID, cutoff)
+		if err != nil {
+			log.Printf("Error building calendar feed for student %d: %v", owner.ID, err)
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Failed to build calendar feed"})
+			return
+		}
+		items, err := collectCalendarItems(rows, tentativeScan(scanEventItem))
+		if err != nil {
+			log.Printf("Error building calendar feed for student %d: %v", owner.ID, err)
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Failed to build calendar feed"})
+			return
+		}
+
+		rows, err = db.Query(`
+			SELECT s.id, s.title, COALESCE(s.description, ''), COALESCE(s.location, e.location, ''), COALESCE(s.room, ''),
+			       CAST(s.start_time AS CHAR), CAST(s.end_time AS CHAR), COALESCE(e.event_name, '')
+			FROM event_sessions s
+			JOIN Events e ON e.id = s.event_id
+			JOIN EventRegistrations r ON r.event_id = s.event_id AND r.student_id = ? AND r.status IN (`+eventQueue.seatStatuses+`)
+			LEFT JOIN event_session_registrations sr ON sr.session_id = s.id AND sr.student_id = r.student_id
+			WHERE (s.requires_registration = 0 OR sr.status IN ('registered', 'waitlisted'))
+			  AND e.status = 'published' AND s.end_time >= ?`, owner.ID, cutoff)
+		if err != nil {
+			log.Printf("Error building calendar feed for student %d: %v", owner.ID, err)
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Failed to build calendar feed"})
+			return
+		}
+		sessions, err := collectCalendarItems(rows, func(scan func(dest ...interface{}) error) (calendarItem, error) {
+			var item calendarItem
+			var id int
+			var room, eventName string
+			err := scan(&id, &item.Summary, &item.Description, &item.Location, &room, &item.Start, &item.End, &eventName)
+			item.UID = fmt.Sprintf("session-%d@ranking-school", id)
+			item.Summary = eventName + ": " + item.Summary
+			if room != "" && item.Location != "" {
+				item.Location += ", " + room
+			} else if room != "" {
+				item.Location = room
+			}
+			return item, err
+		})
+		if err != nil {
+			log.Printf("Error building calendar feed for student %d: %v", owner.ID, err)
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Failed to build calendar feed"})
+			return
+		}
+		items = append(items, sessions...)
+
+		rows, err = db.Query(`
+			SELECT`+calendarOlympiadColumns+`, r.status = 'waitlisted'
+			FROM olympiad_registrations r
+			JOIN subject_olympiads so ON so.subject_olympiad_id = r.subject_olympiad_id
+			LEFT JOIN Schools s ON s.school_id = so.school_id
+			WHERE r.student_id = ? AND r.status IN (`+olympiadQueue.seatStatuses+`, 'waitlisted')
+			  AND so.status = 'published' AND COALESCE(so.end_date, so.date) >= ?`, owner.ID, cutoff)
+		if err != nil {
+			log.Printf("Error building calendar feed for student %d: %v", owner.ID, err)
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Failed to build calendar feed"})
+			return
+		}
+		olympiads, err := collectCalendarItems(rows, tentativeScan(scanOlympiadItem))
+		if err != nil {
+			log.Printf("Error building calendar feed for student %d: %v", owner.ID, err)
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Failed to build calendar feed"})
+			return
+		}
+		items = append(items, olympiads...)
+
+		writeCalendar(w, "Мои мероприятия", "my.ics", items)
+	}
+}
+
+// GetSchoolCalendarFeed — опубликованные мероприятия и олимпиады школы.
+func (cc *CalendarFeedController) GetSchoolCalendarFeed(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := requireFeedToken(w, r, db); !ok {
+			return
+		}
+		schoolID, err := strconv.Atoi(mux.Vars(r)["school_id"])
+		if err != nil || schoolID <= 0 {
+			utils.RespondWithError(w, http.StatusBadRequest, models.Error{Message: "Invalid school ID"})
+			return
+		}
+		var schoolName string
+		err = db.QueryRow("SELECT COALESCE(school_name, '') FROM Schools WHERE school_id = ?", schoolID).Scan(&schoolName)
+		if err == sql.ErrNoRows {
+			utils.RespondWithError(w, http.StatusNotFound, models.Error{Message: "School not found"})
+			return
+		} else if err != nil {
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Database error"})
+			return
+		}
+
+		items, err := publishedCalendarItems(db, calendarFilter{SchoolID: schoolID})
+		if err != nil {
+			log.Printf("Error building calendar feed for school %d: %v", schoolID, err)
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Failed to build calendar feed"})
+			return
+		}
+		writeCalendar(w, schoolName, fmt.Sprintf("school-%d.ics", schoolID), items)
+	}
+}
+
+// GetPublicCalendarFeed — все опубликованные мероприятия и олимпиады.
+// Фильтры: category (только мероприятия), grade, type=event|olympiad.
+func (cc *CalendarFeedController) GetPublicCalendarFeed(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := requireFeedToken(w, r, db); !ok {
+			return
+		}
+		query := r.URL.Query()
+		filter := calendarFilter{Category: query.Get("category"), Type: query.Get("type")}
+		if filter.Category != "" {
+			valid := false
+			for _, c := range []string{"Science", "Humanities", "Sport", "Creative"} {
+				if filter.Category == c {
+					valid = true
+					break
+				}
+			}
+			if !valid {
+				utils.RespondWithError(w, http.StatusBadRequest, models.Error{Message: "Invalid category. Allowed values are: Science, Humanities, Sport, Creative"})
+				return
+			}
+		}
+		if gradeStr := query.Get("grade"); gradeStr != "" {
+			grade, err := strconv.Atoi(gradeStr)
+			if err != nil || grade <= 0 {
+				utils.RespondWithError(w, http.StatusBadRequest, models.Error{Message: "Invalid grade"})
+				return
+			}
+			filter.Grade = grade
+		}
+		if filter.Type != "" && filter.Type != "event" && filter.Type != "olympiad" {
+			utils.RespondWithError(w, http.StatusBadRequest, models.Error{Message: "type must be event or olympiad"})
+			return
+		}
+
+		items, err := publishedCalendarItems(db, filter)
+		if err != nil {
+			log.Printf("Error building public calendar feed: %v", err)
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Failed to build calendar feed"})
+			return
+		}
+		writeCalendar(w, "Мероприятия и олимпиады", "public.ics", items)
+	}
+}
+
+type calendarFilter struct {
+	SchoolID int
+	Category string
+	Grade    int
+	Type     string
+}
+
+// publishedCalendarItems — опубликованные мероприятия и олимпиады по
+// фильтру. Мероприятие без класса (grade = 0) подходит любому классу;
+// при фильтре по категории олимпиады не выводятся.
+func publishedCalendarItems(db *sql.DB, f calendarFilter) ([]calendarItem, error) {
+	cutoff := feedHistoryCutoff()
+	var items []calendarItem
+
+	if f.Type != "olympiad" {
+		query := `SELECT` + calendarEventColumns + `
+			FROM Events e
+			LEFT JOIN Schools s ON s.school_id = e.school_id
+			WHERE e.status = 'published' AND e.end_date >= ?`
+		args := []interface{}{cutoff}
+		if f.SchoolID > 0 {
+			query += " AND e.school_id = ?"
+			args = append(args, f.SchoolID)
+		}
+		if f.Category != "" {
+			query += " AND e.category = ?"
+			args = append(args, f.Category)
+		}
+		if f.Grade > 0 {
+			query += " AND (e.grade = ? OR COALESCE(e.grade, 0) = 0)"
+			args = append(args, f.Grade)
+		}
+		rows, err := db.Query(query+" ORDER BY e.start_date", args...)
+		if err != nil {
+			return nil, err
+		}
+		events, err := collectCalendarItems(rows, scanEventItem)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, events...)
+	}
+
+	if f.Type != "event" && f.Category == "" {
+		query := `SELECT` + calendarOlympiadColumns + `
+			FROM subject_olympiads so
+			LEFT JOIN Schools s ON s.school_id = so.school_id
+			WHERE so.status = 'published' AND COALESCE(so.end_date, so.date) >= ?`
+		args := []interface{}{cutoff}
+		if f.SchoolID > 0 {
+			query += " AND so.school_id = ?"
+			args = append(args, f.SchoolID)
+		}
+		if f.Grade > 0 {
+			query += " AND so.grade = ?"
+			args = append(args, f.Grade)
+		}
+		rows, err := db.Query(query+" ORDER BY so.date", args...)
+		if err != nil {
+			return nil, err
+		}
+		olympiads, err := collectCalendarItems(rows, scanOlympiadItem)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, olympiads...)
+	}
+	return items, nil
+}
+
+// writeCalendar отдаёт VCALENDAR. Мероприятия, у которых начало и конец
+// приходятся на полночь, выводятся как события на весь день.
+func writeCalendar(w http.ResponseWriter, name, filename string, items []calendarItem) {
+	var b strings.Builder
+	writeICSLine(&b, "BEGIN:VCALENDAR")
+	writeICSLine(&b, "VERSION:2.0")
+	writeICSLine(&b, "PRODID:"+calendarProdID)
+	writeICSLine(&b, "CALSCALE:GREGORIAN")
+	writeICSLine(&b, "METHOD:PUBLISH")
+	writeICSLine(&b, "X-WR-CALNAME:"+icsEscape(name))
+	writeICSLine(&b, "X-PUBLISHED-TTL:PT6H")
+
+	stamp := time.Now().UTC().Format("20060102T150405Z")
+	for _, item := range items {
+		start, end, ok := eventSpan(item.Start, item.End)
+		if !ok {
+			continue
+		}
+		writeICSLine(&b, "BEGIN:VEVENT")
+		writeICSLine(&b, "UID:"+item.UID)
+		writeICSLine(&b, "DTSTAMP:"+stamp)
+		if isMidnight(start) && isMidnight(end) {
+			writeICSLine(&b, "DTSTART;VALUE=DATE:"+start.Format("20060102"))
+			writeICSLine(&b, "DTEND;VALUE=DATE:"+end.Format("20060102"))
+		} else {
+			writeICSLine(&b, "DTSTART:"+start.Format("20060102T150405"))
+			writeICSLine(&b, "DTEND:"+end.Format("20060102T150405"))
+		}
+		writeICSLine(&b, "SUMMARY:"+icsEscape(item.Summary))
+		if item.Description != "" {
+			writeICSLine(&b, "DESCRIPTION:"+icsEscape(item.Description))
+		}
+		if item.Location != "" {
+			writeICSLine(&b, "LOCATION:"+icsEscape(item.Location))
+		}
+		if item.Tentative {
+			writeICSLine(&b, "STATUS:TENTATIVE")
+		} else {
+			writeICSLine(&b, "STATUS:CONFIRMED")
+		}
+		writeICSLine(&b, "END:VEVENT")
+	}
+	writeICSLine(&b, "END:VCALENDAR")
+
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("inline; filename=%q", filename))
+	w.Write([]byte(b.String()))
+}
+
+func isMidnight(t time.Time) bool {
+	return t.Hour() == 0 && t.Minute() == 0 && t.Second() == 0
+}
+
+var icsEscaper = strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`, "\r", `\n`)
+
+func icsEscape(s string) string {
+	return icsEscaper.Replace(s)
+}
+
+// writeICSLine пишет строку с переносом по 75 байт (RFC 5545, 3.1), не
+// разрывая многобайтовые символы. Пробел в начале строки продолжения
+// входит в эти 75 байт.
+func writeICSLine(b *strings.Builder, line string) {
+	limit := 75
+	for len(line) > limit {
+		cut := limit
+		for cut > 0 && !utf8RuneStart(line[cut]) {
+			cut--
+		}
+		b.WriteString(line[:cut])
+		b.WriteString("\r\n ")
+		line = line[cut:]
+		limit = 74
+	}
+	b.WriteString(line)
+	b.WriteString("\r\n")
+}
+
+func utf8RuneStart(c byte) bool {
+	return c&0xC0 != 0x80
+}
@@ -0,0 +1,74 @@
+package controllers
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestICSEscape(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"Олимпиада по физике", "Олимпиада по физике"},
+		{"Актовый зал; 2 этаж", `Актовый зал\; 2 этаж`},
+		{"Алматы, ул. Абая", `Алматы\, ул. Абая`},
+		{`C:\docs`, `C:\\docs`},
+		{"первая строка\r\nвторая\nтретья\rчетвёртая", `первая строка\nвторая\nтретья\nчетвёртая`},
+		{`\;`, `\\\;`},
+	}
+	for _, tt := range tests {
+		if got := icsEscape(tt.in); got != tt.want {
+			t.Errorf("icsEscape(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestWriteICSLine(t *testing.T) {
+	tests := []struct {
+		name      string
+		line      string
+		wantLines int
+	}{
+		{"short line", "SUMMARY:Олимпиада", 1},
+		{"exactly 75 bytes", "DESCRIPTION:" + strings.Repeat("a", 63), 1},
+		{"76 bytes", "DESCRIPTION:" + strings.Repeat("a", 64), 2},
+		{"long ascii", "DESCRIPTION:" + strings.Repeat("a", 300), 5},
+		{"long cyrillic", "DESCRIPTION:" + strings.Repeat("ж", 200), 6},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var b strings.Builder
+			writeICSLine(&b, tt.line)
+			out := b.String()
+			if !strings.HasSuffix(out, "\r\n") {
+				t.Fatalf("output %q does not end with CRLF", out)
+			}
+
+			lines := strings.Split(strings.TrimSuffix(out, "\r\n"), "\r\n")
+			if len(lines) != tt.wantLines {
+				t.Errorf("got %d lines, want %d: %q", len(lines), tt.wantLines, lines)
+			}
+			var unfolded strings.Builder
+			for i, l := range lines {
+				if len(l) > 75 {
+					t.Errorf("line %d is %d bytes, want at most 75", i, len(l))
+				}
+				if !utf8.ValidString(l) {
+					t.Errorf("line %d splits a multibyte character: %q", i, l)
+				}
+				if i > 0 {
+					if !strings.HasPrefix(l, " ") {
+						t.Fatalf("continuation line %d does not start with a space: %q", i, l)
+					}
+					l = l[1:]
+				}
+				unfolded.WriteString(l)
+			}
+			if unfolded.String() != tt.line {
+				t.Errorf("unfolded line = %q, want %q", unfolded.String(), tt.line)
+			}
+		})
+	}
+}
@@ -0,0 +1,651 @@
+package controllers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"ranking-school/models"
+	"ranking-school/utils"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+type ClassController struct{}
+
+// execQuerier is satisfied by both *sql.DB and *sql.Tx.
+type execQuerier interface {
+	QueryRow(query string, args ...interface{}) *sql.Row
+	Exec(query string, args ...interface{}) (sql.Result, error)
+}
+
+func normalizeClassLetter(letter string) string {
+	return strings.ToUpper(strings.TrimSpace(letter))
+}
+
+// ensureClass returns the id of the class, creating it if needed.
+func ensureClass(db execQuerier, schoolID int, academicYear string, grade int, letter string) (int, error) {
+	result, err := db.Exec(`
+		INSERT INTO classes (school_id, academic_year, grade, letter)
+		VALUES (?, ?, ?, ?)
+		ON DUPLICATE KEY UPDATE id = LAST_INSERT_ID(id)`,
+		schoolID, academicYear, grade, normalizeClassLetter(letter))
+	if err != nil {
+		return 0, err
+	}
+	id, err := result.LastInsertId()
+	return int(id), err
+}
+
+// syncStudentClass points an active student at the current-year class that
+// matches their school, grade and letter.
+func syncStudentClass(db execQuerier, studentID int) error {
+	var schoolID, grade sql.NullInt64
+	var letter sql.NullString
+	var status string
+	err := db.QueryRow("SELECT school_id, grade, letter, status FROM student WHERE student_id = ?", studentID).
+		Scan(&schoolID, &grade, &letter, &status)
+	if err != nil {
+		return err
+	}
+	if status != "active" || !schoolID.Valid || !grade.Valid || grade.Int64 <= 0 {
+		return nil
+	}
+
+	classID, err := ensureClass(db, int(schoolID.Int64), currentAcademicYear(), int(grade.Int64), letter.String)
+	if err != nil {
+		return err
+	}
+	_, err = db.Exec("UPDATE student SET class_id = ? WHERE student_id = ?", classID, studentID)
+	return err
+}
+
+type SchoolClass struct {
+	ID              int     `json:"id"`
+	SchoolID        int     `json:"school_id"`
+	AcademicYear    string  `json:"academic_year"`
+	Grade           int     `json:"grade"`
+	Letter          string  `json:"letter"`
+	HomeroomTeacher *string `json:"homeroom_teacher"`
+	Capacity        *int    `json:"capacity"`
+	StudentCount    int     `json:"student_count"`
+}
+
+type classRequest struct {
+	AcademicYear    string  `json:"academic_year"`
+	Grade           int     `json:"grade"`
+	Letter          string  `json:"letter"`
+	HomeroomTeacher *string `json:"homeroom_teacher"`
+	Capacity        *int    `json:"capacity"`
+}
+
+// loadClassForManage fetches a class and checks that the user may manage
+// students of its school. It writes the HTTP error itself.
+func loadClassForManage(w http.ResponseWriter, db *sql.DB, userID, classID int, permission string) (SchoolClass, bool) {
+	var c SchoolClass
+	var teacher sql.NullString
+	var capacity sql.NullInt64
+	err := db.QueryRow(`
+		SELECT c.id, c.school_id, c.academic_year, c.grade, c.letter, c.homeroom_teacher, c.capacity,
+		       (SELECT COUNT(*) FROM student s WHERE s.class_id = c.id AND s.status = 'active')
+		FROM classes c WHERE c.id = ?`, classID).
+		Scan(&c.ID, &c.SchoolID, &c.AcademicYear, &c.Grade, &c.Letter, &teacher, &capacity, &c.StudentCount)
+	if err == sql.ErrNoRows {
+		utils.RespondWithError(w, http.StatusNotFound, models.Error{Message: "Class not found"})
+		return c, false
+	} else if err != nil {
+		log.Println("Error fetching class:", err)
+		utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Error fetching class"})
+		return c, false
+	}
+	if teacher.Valid {
+		c.HomeroomTeacher = &teacher.String
+	}
+	if capacity.Valid {
+		v := int(capacity.Int64)
+		c.Capacity = &v
+	}
+
+	var allowed bool
+	if permission == PermissionManageStudents {
+		allowed, err = canManageStudentsOf(db, userID, c.SchoolID)
+	} else {
+		allowed, err = canViewSchool(db, userID, c.SchoolID)
+	}
+	if err != nil {
+		log.Println("Error checking permissions:", err)
+		utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Error checking permissions"})
+		return c, false
+	}
+	if !allowed {
+		utils.RespondWithError(w, http.StatusForbidden, models.Error{Message: "You do not have permission to access this class"})
+		return c, false
+	}
+	return c, true
+}
+
+// canViewSchool reports whether the user is a superadmin or any member of the school.
+func canViewSchool(db rowQuerier, userID, schoolID int) (bool, error) {
+	var role string
+	err := db.QueryRow("SELECT role FROM users WHERE id = ?", userID).Scan(&role)
+	if err == sql.ErrNoRows {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+	if role == "superadmin" {
+		return true, nil
+	}
+	if role != "schooladmin" {
+		return false, nil
+	}
+	return hasSchoolPermission(db, userID, schoolID, "")
+}
+
+// GetClassesBySchool возвращает классы школы за учебный год (по умолчанию текущий).
+func (cc *ClassController) GetClassesBySchool(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := utils.VerifyToken(r)
+		if err != nil {
+			utils.RespondWithError(w, http.StatusUnauthorized, models.Error{Message: err.Error()})
+			return
+		}
+
+		schoolID, err := strconv.Atoi(mux.Vars(r)["school_id"])
+		if err != nil {
+			utils.RespondWithError(w, http.StatusBadRequest, models.Error{Message: "Invalid school ID"})
+			return
+		}
+
+		allowed, err := canViewSchool(db, userID, schoolID)
+		if err != nil {
+			log.Println("Error checking permissions:", err)
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Error checking permissions"})
+			return
+		}
+		if !allowed {
+			utils.RespondWithError(w, http.StatusForbidden, models.Error{Message: "You do not have permission to view this school's classes"})
+			return
+		}
+
+		academicYear := r.URL.Query().Get("academic_year")
+		if academicYear == "" {
+			academicYear = currentAcademicYear()
+		}
+
+		rows, err := db.Query(`
+			SELECT c.id, c.school_id, c.academic_year, c.grade, c.letter, c.homeroom_teacher, c.capacity,
+			       COUNT(s.student_id)
+			FROM classes c
+			LEFT JOIN student s ON s.class_id = c.id AND s.status = 'active'
+			WHERE c.school_id = ? AND c.academic_year = ?
+			GROUP BY c.id
+			ORDER BY c.grade, c.letter`, schoolID, academicYear)
+		if err != nil {
+			log.Println("Error fetching classes:", err)
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Failed to fetch classes"})
+			return
+		}
+		defer rows.Close()
+
+		classes := []SchoolClass{}
+		for rows.Next() {
+			var c SchoolClass
+			var teacher sql.NullString
+			var capacity sql.NullInt64
+			if err := rows.Scan(&c.ID, &c.SchoolID, &c.AcademicYear, &c.Grade, &c.Letter, &teacher, &capacity, &c.StudentCount); err != nil {
+				log.Println("Error scanning class:", err)
+				utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Failed to fetch classes"})
+				return
+			}
+			if teacher.Valid {
+				c.HomeroomTeacher = &teacher.String
+			}
+			if capacity.Valid {
+				v := int(capacity.Int64)
+				c.Capacity = &v
+			}
+			classes = append(classes, c)
+		}
+
+		utils.ResponseJSON(w, classes)
+	}
+}
+
+// CreateClass создаёт класс в школе.
+func (cc *ClassController) CreateClass(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := utils.VerifyToken(r)
+		if err != nil {
+			utils.RespondWithError(w, http.StatusUnauthorized, models.Error{Message: err.Error()})
+			return
+		}
+
+		schoolID, err := strconv.Atoi(mux.Vars(r)["school_id"])
+		if err != nil {
+			utils.RespondWithError(w, http.StatusBadRequest, models.Error{Message: "Invalid school ID"})
+			return
+		}
+
+		allowed, err := canManageStudentsOf(db, userID, schoolID)
+		if err != nil {
+			log.Println("Error checking permissions:", err)
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Error checking permissions"})
+			return
+		}
+		if !allowed {
+			utils.RespondWithError(w, http.StatusForbidden, models.Error{Message: "You do not have permission to manage classes of this school"})
+			return
+		}
+
+		var req classRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			utils.RespondWithError(w, http.StatusBadRequest, models.Error{Message: "Invalid request body"})
+			return
+		}
+		if req.AcademicYear == "" {
+			req.AcademicYear = currentAcademicYear()
+		}
+		if _, ok := parseAcademicYear(req.AcademicYear); !ok {
+			utils.RespondWithError(w, http.StatusBadRequest, models.Error{Message: "academic_year must look like 2024-2025"})
+			return
+		}
+		if req.Grade < 1 || req.Grade > finalGrade {
+			utils.RespondWithError(w, http.StatusBadRequest, models.Error{Message: fmt.Sprintf("Grade must be from 1 to %d", finalGrade)})
+			return
+		}
+		if req.Capacity != nil && *req.Capacity <= 0 {
+			utils.RespondWithError(w, http.StatusBadRequest, models.Error{Message: "Capacity must be positive"})
+			return
+		}
+
+		result, err := db.Exec(`
+			INSERT INTO classes (school_id, academic_year, grade, letter, homeroom_teacher, capacity)
+			VALUES (?, ?, ?, ?, ?, ?)`,
+			schoolID, req.AcademicYear, req.Grade, normalizeClassLetter(req.Letter), req.HomeroomTeacher, req.Capacity)
+		if err != nil {
+			if strings.Contains(err.Error(), "Duplicate entry") {
+				utils.RespondWithError(w, http.StatusConflict, models.Error{Message: "Class already exists"})
+				return
+			}
+			log.Println("Error creating class:", err)
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Failed to create class"})
+			return
+		}
+		classID, _ := result.LastInsertId()
+
+		utils.ResponseJSON(w, map[string]interface{}{
+			"message":  "Class created",
+			"class_id": classID,
+		})
+	}
+}
+
+// UpdateClass меняет классного руководителя, вместимость или литеру.
+func (cc *ClassController) UpdateClass(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := utils.VerifyToken(r)
+		if err != nil {
+			utils.RespondWithError(w, http.StatusUnauthorized, models.Error{Message: err.Error()})
+			return
+		}
+
+		classID, err := strconv.Atoi(mux.Vars(r)["class_id"])
+		if err != nil {
+			utils.RespondWithError(w, http.StatusBadRequest, models.Error{Message: "Invalid class ID"})
+			return
+		}
+
+		class, ok := loadClassForManage(w, db, userID, classID, PermissionManageStudents)
+		if !ok {
+			return
+		}
+
+		var req classRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			utils.RespondWithError(w, http.StatusBadRequest, models.Error{Message: "Invalid request body"})
+			return
+		}
+		if req.HomeroomTeacher != nil {
+			class.HomeroomTeacher = req.HomeroomTeacher
+		}
+		if req.Capacity != nil {
+			if *req.Capacity < class.StudentCount {
+				utils.RespondWithError(w, http.StatusBadRequest, models.Error{Message: "Capacity cannot be less than the current number of students"})
+				return
+			}
+			class.Capacity = req.Capacity
+		}
+		if req.Letter != "" {
+			class.Letter = normalizeClassLetter(req.Letter)
+		}
+
+		tx, err := db.Begin()
+		if err != nil {
+			log.Println("Error starting transaction:", err)
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Failed to start transaction"})
+			return
+		}
+		defer tx.Rollback()
+
+		_, err = tx.Exec("UPDATE classes SET letter = ?, homeroom_teacher = ?, capacity = ? WHERE id = ?",
+			class.Letter, class.HomeroomTeacher, class.Capacity, classID)
+		if err != nil {
+			if strings.Contains(err.Error(), "Duplicate entry") {
+				utils.RespondWithError(w, http.StatusConflict, models.Error{Message: "Class with this letter already exists"})
+				return
+			}
+			log.Println("Error updating class:", err)
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Failed to update class"})
+			return
+		}
+		// Литера в карточках учеников остаётся синхронной с классом
+		if _, err := tx.Exec("UPDATE student SET letter = ? WHERE class_id = ?", class.Letter, classID); err != nil {
+			log.Println("Error updating student letters:", err)
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Failed to update class"})
+			return
+		}
+		if err := tx.Commit(); err != nil {
+			log.Println("Error committing class update:", err)
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Failed to update class"})
+			return
+		}
+
+		utils.ResponseJSON(w, class)
+	}
+}
+
+// DeleteClass удаляет пустой класс.
+func (cc *ClassController) DeleteClass(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := utils.VerifyToken(r)
+		if err != nil {
+			utils.RespondWithError(w, http.StatusUnauthorized, models.Error{Message: err.Error()})
+			return
+		}
+
+		classID, err := strconv.Atoi(mux.Vars(r)["class_id"])
+		if err != nil {
+			utils.RespondWithError(w, http.StatusBadRequest, models.Error{Message: "Invalid class ID"})
+			return
+		}
+
+		class, ok := loadClassForManage(w, db, userID, classID, PermissionManageStudents)
+		if !ok {
+			return
+		}
+		if class.StudentCount > 0 {
+			utils.RespondWithError(w, http.StatusConflict, models.Error{Message: "Class has students, move them first"})
+			return
+		}
+
+		if _, err := db.Exec("DELETE FROM classes WHERE id = ?", classID); err != nil {
+			log.Println("Error deleting class:", err)
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Failed to delete class"})
+			return
+		}
+
+		utils.ResponseJSON(w, map[string]string{"message": "Class deleted successfully"})
+	}
+}
+
+// GetClassRoster возвращает список учеников класса.
+func (cc *ClassController) GetClassRoster(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := utils.VerifyToken(r)
+		if err != nil {
+			utils.RespondWithError(w, http.StatusUnauthorized, models.Error{Message: err.Error()})
+			return
+		}
+
+		classID, err := strconv.Atoi(mux.Vars(r)["class_id"])
+		if err != nil {
+			utils.RespondWithError(w, http.StatusBadRequest, models.Error{Message: "Invalid class ID"})
+			return
+		}
+
+		class, ok := loadClassForManage(w, db, userID, classID, "")
+		if !ok {
+			return
+		}
+
+		// Для прошлых лет состав берётся из истории классов
+		rows, err := db.Query(`
+			SELECT s.student_id, s.first_name, s.last_name, COALESCE(s.patronymic, ''), COALESCE(s.iin, ''),
+			       COALESCE(s.gender, ''), COALESCE(s.phone, ''), COALESCE(s.email, ''), COALESCE(s.login, '')
+			FROM student s
+			WHERE (s.class_id = ? AND s.status = 'active')
+			   OR s.student_id IN (SELECT h.student_id FROM student_grade_history h WHERE h.class_id = ?)
+			ORDER BY s.last_name, s.first_name`, classID, classID)
+		if err != nil {
+			log.Println("Error fetching roster:", err)
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Failed to fetch class roster"})
+			return
+		}
+		defer rows.Close()
+
+		students := []models.Student{}
+		for rows.Next() {
+			var s models.Student
+			if err := rows.Scan(&s.ID, &s.FirstName, &s.LastName, &s.Patronymic, &s.IIN, &s.Gender, &s.Phone, &s.Email, &s.Login); err != nil {
+				log.Println("Error scanning roster:", err)
+				utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Failed to fetch class roster"})
+				return
+			}
+			s.SchoolID = class.SchoolID
+			s.Grade = class.Grade
+			s.Letter = class.Letter
+			students = append(students, s)
+		}
+
+		utils.ResponseJSON(w, map[string]interface{}{
+			"class":    class,
+			"students": students,
+		})
+	}
+}
+
+// AssignStudentsToClass переводит учеников школы в класс с учётом вместимости.
+func (cc *ClassController) AssignStudentsToClass(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := utils.VerifyToken(r)
+		if err != nil {
+			utils.RespondWithError(w, http.StatusUnauthorized, models.Error{Message: err.Error()})
+			return
+		}
+
+		classID, err := strconv.Atoi(mux.Vars(r)["class_id"])
+		if err != nil {
+			utils.RespondWithError(w, http.StatusBadRequest, models.Error{Message: "Invalid class ID"})
+			return
+		}
+
+		class, ok := loadClassForManage(w, db, userID, classID, PermissionManageStudents)
+		if !ok {
+			return
+		}
+		// Ученики учатся в классах текущего учебного года: назначение в
+		// класс прошлого или будущего года разошлось бы с syncStudentClass
+		if class.AcademicYear != currentAcademicYear() {
+			utils.RespondWithError(w, http.StatusConflict, models.Error{Message: fmt.Sprintf("Class belongs to academic year %s, students can only be assigned to classes of %s", class.AcademicYear, currentAcademicYear())})
+			return
+		}
+
+		var req struct {
+			StudentIDs []int `json:"student_ids"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || len(req.StudentIDs) == 0 {
+			utils.RespondWithError(w, http.StatusBadRequest, models.Error{Message: "student_ids is required"})
+			return
+		}
+
+		tx, err := db.Begin()
+		if err != nil {
+			log.Println("Error starting transaction:", err)
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Failed to start transaction"})
+			return
+		}
+		defer tx.Rollback()
+
+		// Блокируем класс, чтобы параллельные назначения не превысили вместимость
+		var count int
+		err = tx.QueryRow(`SELECT (SELECT COUNT(*) FROM student WHERE class_id = c.id AND status = 'active')
+			FROM classes c WHERE c.id = ? FOR UPDATE`, classID).Scan(&count)
+		if err != nil {
+			log.Println("Error locking class:", err)
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Failed to assign students"})
+			return
+		}
+
+		added := 0
+		for _, studentID := range req.StudentIDs {
+			var schoolID int
+			var currentClass sql.NullInt64
+			err := tx.QueryRow("SELECT school_id, class_id FROM student WHERE student_id = ? AND status = 'active'", studentID).
+				Scan(&schoolID, &currentClass)
+			if err == sql.ErrNoRows || (err == nil && schoolID != class.SchoolID) {
+				utils.RespondWithError(w, http.StatusBadRequest, models.Error{Message: fmt.Sprintf("Student %d is not an active student of this school", studentID)})
+				return
+			} else if err != nil {
+				log.Println("Error fetching student:", err)
+				utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Failed to assign students"})
+				return
+			}
+			if currentClass.Valid && int(currentClass.Int64) == classID {
+				continue
+			}
+			added++
+
+			_, err = tx.Exec("UPDATE student SET class_id = ?, grade = ?, letter = ? WHERE student_id = ?",
+				classID, class.Grade, class.Letter, studentID)
+			if err != nil {
+				log.Println("Error assigning student:", err)
+				utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Failed to assign students"})
+				return
+			}
+		}
+
+		if class.Capacity != nil && count+added > *class.Capacity {
+			utils.RespondWithError(w, http.StatusConflict, models.Error{Message: fmt.Sprintf("Class capacity is %d, it would have %d students", *class.Capacity, count+added)})
+			return
+		}
+
+		if err := tx.Commit(); err != nil {
+			log.Println("Error committing class assignment:", err)
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Failed to assign students"})
+			return
+		}
+
+		utils.ResponseJSON(w, map[string]interface{}{
+			"message":       "Students assigned",
+			"class_id":      classID,
+			"assigned":      added,
+			"student_count": count + added,
+		})
+	}
+}
+
+// GetClassAnalytics возвращает средние баллы ЕНТ по классам школы.
+func (cc *ClassController) GetClassAnalytics(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := utils.VerifyToken(r)
+		if err != nil {
+			utils.RespondWithError(w, http.StatusUnauthorized, models.Error{Message: err.Error()})
+			return
+		}
+
+		schoolID, err := strconv.Atoi(mux.Vars(r)["school_id"])
+		if err != nil {
+			utils.RespondWithError(w, http.StatusBadRequest, models.Error{Message: "Invalid school ID"})
+			return
+		}
+
+		allowed, err := canViewSchool(db, userID, schoolID)
+		if err != nil {
+			log.Println("Error checking permissions:", err)
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Error checking permissions"})
+			return
+		}
+		if !allowed {
+			utils.RespondWithError(w, http.StatusForbidden, models.Error{Message: "You do not have permission to view this school's data"})
+			return
+		}
+
+		academicYear := r.URL.Query().Get("academic_year")
+		if academicYear == "" {
+			academicYear = currentAcademicYear()
+		}
+
+		// Состав класса: текущие ученики плюс те, кто был в нём по истории
+		rows, err := db.Query(`
+			SELECT c.id, c.grade, c.letter, c.homeroom_teacher,
+			       COUNT(DISTINCT m.student_id) AS students,
+			       COUNT(DISTINCT CASE WHEN e.exam_type = 'regular' THEN e.student_id END) AS unt_takers,
+			       AVG(CASE WHEN e.exam_type = 'regular' THEN e.total_score END) AS avg_unt,
+			       MAX(CASE WHEN e.exam_type = 'regular' THEN e.total_score END) AS max_unt,
+			       AVG(CASE WHEN e.exam_type = 'creative' THEN e.total_score END) AS avg_creative_unt
+			FROM classes c
+			LEFT JOIN (
+				SELECT student_id, class_id FROM student WHERE class_id IS NOT NULL
+				UNION
+				SELECT student_id, class_id FROM student_grade_history WHERE class_id IS NOT NULL
+			) m ON m.class_id = c.id
+			LEFT JOIN UNT_Exams e ON e.student_id = m.student_id AND e.school_id = c.school_id AND e.attempt_kind = 'official'
+			WHERE c.school_id = ? AND c.academic_year = ?
+			GROUP BY c.id, c.grade, c.letter, c.homeroom_teacher
+			ORDER BY c.grade, c.letter`, schoolID, academicYear)
+		if err != nil {
+			log.Println("Error fetching class analytics:", err)
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Failed to fetch class analytics"})
+			return
+		}
+		defer rows.Close()
+
+		type classStats struct {
+			ClassID         int      `json:"class_id"`
+			Grade           int      `json:"grade"`
+			Letter          string   `json:"letter"`
+			HomeroomTeacher *string  `json:"homeroom_teacher"`
+			Students        int      `json:"students"`
+			UNTTakers       int      `json:"unt_takers"`
+			AverageUNT      *float64 `json:"average_unt"`
+			MaxUNT          *float64 `json:"max_unt"`
+			AverageCreative *float64 `json:"average_creative_unt"`
+		}
+		stats := []classStats{}
+		for rows.Next() {
+			var s classStats
+			var teacher sql.NullString
+			var avg, max, creative sql.NullFloat64
+			if err := rows.Scan(&s.ClassID, &s.Grade, &s.Letter, &teacher, &s.Students, &s.UNTTakers, &avg, &max, &creative); err != nil {
+				log.Println("Error scanning class analytics:", err)
+				utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Failed to fetch class analytics"})
+				return
+			}
+			if teacher.Valid {
+				s.HomeroomTeacher = &teacher.String
+			}
+			if avg.Valid {
+				v := float64(int(avg.Float64*100+0.5)) / 100
+				s.AverageUNT = &v
+			}
+			if max.Valid {
+				s.MaxUNT = &max.Float64
+			}
+			if creative.Valid {
+				v := float64(int(creative.Float64*100+0.5)) / 100
+				s.AverageCreative = &v
+			}
+			stats = append(stats, s)
+		}
+
+		utils.ResponseJSON(w, map[string]interface{}{
+			"school_id":     schoolID,
+			"academic_year": academicYear,
+			"classes":       stats,
+		})
+	}
+}
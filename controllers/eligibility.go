@@ -0,0 +1,587 @@
+package controllers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"ranking-school/models"
+	"ranking-school/utils"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+type EligibilityController struct{}
+
+// eligibilityTarget связывает тип правил с таблицами мероприятия и заявок.
+type eligibilityTarget struct {
+	kind        string
+	queue       registrationQueue
+	startColumn string
+	label       string
+}
+
+var eventEligibility = eligibilityTarget{kind: "event", queue: eventQueue, startColumn: "start_date", label: "Event"}
+var olympiadEligibility = eligibilityTarget{kind: "olympiad", queue: olympiadQueue, startColumn: "date", label: "Olympiad"}
+
+// Прежние жёсткие ограничения, которые действуют, пока правила не заданы.
+const defaultCreatorSchoolQuota = 2
+
+type eligibilityParent struct {
+	SchoolID  int
+	Grade     int
+	StartDate string
+}
+
+func loadEligibilityParent(db rowQuerier, t eligibilityTarget, targetID int) (eligibilityParent, error) {
+	var p eligibilityParent
+	err := db.QueryRow(fmt.Sprintf("SELECT COALESCE(school_id, 0), COALESCE(grade, 0), COALESCE(CAST(%s AS CHAR), '') FROM %s WHERE %s = ?",
+		t.startColumn, t.queue.parentTable, t.queue.parentKey), targetID).Scan(&p.SchoolID, &p.Grade, &p.StartDate)
+	return p, err
+}
+
+func intPtr(v sql.NullInt64) *int {
+	if !v.Valid {
+		return nil
+	}
+	n := int(v.Int64)
+	return &n
+}
+
+func nullableInt(v *int) interface{} {
+	if v == nil {
+		return nil
+	}
+	return *v
+}
+
+func nullableJSON(v interface{}, empty bool) (interface{}, error) {
+	if empty {
+		return nil, nil
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return string(b), nil
+}
+
+// loadEligibilityRule возвращает правила события. Если они не заданы,
+// возвращается правило по умолчанию: класс события и квота школы-организатора.
+func loadEligibilityRule(db rowQuerier, t eligibilityTarget, targetID int, parent eligibilityParent) (models.EligibilityRule, error) {
+	rule := models.EligibilityRule{TargetType: t.kind, TargetID: targetID}
+
+	var minGrade, maxGrade, perSchool, creatorSchool, minAge, maxAge, prereqID, prereqPlace sql.NullInt64
+	var allowedSchools, excludedSchools, allowedCities, excludedCities []byte
+	var gender, prereqLevel sql.NullString
+	err := db.QueryRow(`
+		SELECT id, min_grade, max_grade, allowed_school_ids, excluded_school_ids, allowed_cities, excluded_cities,
+		       per_school_quota, creator_school_quota, gender, min_age, max_age,
+		       prerequisite_olympiad_id, prerequisite_level, prerequisite_max_place
+		FROM eligibility_rules WHERE target_type = ? AND target_id = ?`, t.kind, targetID).
+		Scan(&rule.ID, &minGrade, &maxGrade, &allowedSchools, &excludedSchools, &allowedCities, &excludedCities,
+			&perSchool, &creatorSchool, &gender, &minAge, &maxAge, &prereqID, &prereqLevel, &prereqPlace)
+	if err == sql.ErrNoRows {
+		rule.IsDefault = true
+		if parent.Grade > 0 {
+			grade := parent.Grade
+			rule.MinGrade = &grade
+			rule.MaxGrade = &grade
+		}
+		quota := defaultCreatorSchoolQuota
+		rule.CreatorSchoolQuota = &quota
+		return rule, nil
+	}
+	if err != nil {
+		return rule, err
+	}
+
+	rule.MinGrade = intPtr(minGrade)
+	rule.MaxGrade = intPtr(maxGrade)
+	rule.PerSchoolQuota = intPtr(perSchool)
+	rule.CreatorSchoolQuota = intPtr(creatorSchool)
+	rule.MinAge = intPtr(minAge)
+	rule.MaxAge = intPtr(maxAge)
+	rule.PrerequisiteOlympiadID = intPtr(prereqID)
+	rule.PrerequisiteMaxPlace = intPtr(prereqPlace)
+	rule.Gender = utils.NullStringToString(gender)
+	rule.PrerequisiteLevel = utils.NullStringToString(prereqLevel)
+
+	for _, list := range []struct {
+		raw  []byte
+		dest interface{}
+	}{
+		{allowedSchools, &rule.AllowedSchoolIDs},
+		{excludedSchools, &rule.ExcludedSchoolIDs},
+		{allowedCities, &rule.AllowedCities},
+		{excludedCities, &rule.ExcludedCities},
+	} {
+		if len(list.raw) == 0 {
+			continue
+		}
+		if err := json.Unmarshal(list.raw, list.dest); err != nil {
+			return rule, err
+		}
+	}
+	return rule, nil
+}
+
+func containsInt(list []int, v int) bool {
+	for _, item := range list {
+		if item == v {
+			return true
+		}
+	}
+	return false
+}
+
+func containsCity(list []string, city string) bool {
+	for _, item := range list {
+		if strings.EqualFold(strings.TrimSpace(item), strings.TrimSpace(city)) {
+			return true
+		}
+	}
+	return false
+}
+
+// evaluateEligibility проверяет ученика по правилам события. Результат
+// содержит все проверки, Reasons — сообщения непройденных.
+func evaluateEligibility(db rowQuerier, t eligibilityTarget, targetID, studentID int) (models.EligibilityResult, error) {
+	result := models.EligibilityResult{Checks: []models.EligibilityCheck{}}
+
+	parent, err := loadEligibilityParent(db, t, targetID)
+	if err != nil {
+		return result, err
+	}
+	rule, err := loadEligibilityRule(db, t, targetID, parent)
+	if err != nil {
+		return result, err
+	}
+
+	var grade int
+	var schoolID sql.NullInt64
+	var gender, dateOfBirth, city string
+	err = db.QueryRow(`
+		SELECT COALESCE(s.grade, 0), s.school_id, COALESCE(s.gender, ''),
+		       COALESCE(CAST(s.date_of_birth AS CHAR), ''), COALESCE(sc.city, '')
+		FROM student s
+		LEFT JOIN Schools sc ON sc.school_id = s.school_id
+		WHERE s.student_id = ?`, studentID).Scan(&grade, &schoolID, &gender, &dateOfBirth, &city)
+	if err != nil {
+		return result, err
+	}
+
+	check := func(rule string, passed bool, message string) {
+		result.Checks = append(result.Checks, models.EligibilityCheck{Rule: rule, Passed: passed, Message: message})
+		if !passed {
+			result.Reasons = append(result.Reasons, message)
+		}
+	}
+
+	if rule.MinGrade != nil && rule.MaxGrade != nil && *rule.MinGrade == *rule.MaxGrade {
+		check("grade", grade == *rule.MinGrade, fmt.Sprintf("Grade %d is required", *rule.MinGrade))
+	} else {
+		if rule.MinGrade != nil {
+			check("min_grade", grade >= *rule.MinGrade, fmt.Sprintf("Grade %d or higher is required", *rule.MinGrade))
+		}
+		if rule.MaxGrade != nil {
+			check("max_grade", grade <= *rule.MaxGrade, fmt.Sprintf("Grade %d or lower is required", *rule.MaxGrade))
+		}
+	}
+
+	if len(rule.AllowedSchoolIDs) > 0 {
+		check("allowed_schools", schoolID.Valid && containsInt(rule.AllowedSchoolIDs, int(schoolID.Int64)), "Your school is not on the list of allowed schools")
+	}
+	if len(rule.ExcludedSchoolIDs) > 0 {
+		check("excluded_schools", !schoolID.Valid || !containsInt(rule.ExcludedSchoolIDs, int(schoolID.Int64)), "Students of your school cannot take part")
+	}
+	if len(rule.AllowedCities) > 0 {
+		check("allowed_cities", city != "" && containsCity(rule.AllowedCities, city), "Your city is not on the list of allowed cities")
+	}
+	if len(rule.ExcludedCities) > 0 {
+		check("excluded_cities", !containsCity(rule.ExcludedCities, city), "Students from your city cannot take part")
+	}
+
+	if rule.Gender != "" {
+		// В базе встречаются старые значения ("М", "жен"), поэтому
+		// обе стороны приводятся к male/female
+		studentGender, studentOK := normalizeGender(gender)
+		ruleGender, _ := normalizeGender(rule.Gender)
+		check("gender", studentOK && studentGender == ruleGender, fmt.Sprintf("Only %s students can take part", rule.Gender))
+	}
+
+	if rule.MinAge != nil || rule.MaxAge != nil {
+		refDate := time.Now()
+		if start, err := parseWindowTime(parent.StartDate); err == nil {
+			refDate = start
+		}
+		age := calculateAge(firstN(dateOfBirth, 10), refDate)
+		if dateOfBirth == "" {
+			check("age", false, "Date of birth is not set in your profile")
+		} else {
+			if rule.MinAge != nil {
+				check("min_age", age >= *rule.MinAge, fmt.Sprintf("Minimum age is %d", *rule.MinAge))
+			}
+			if rule.MaxAge != nil {
+				check("max_age", age <= *rule.MaxAge, fmt.Sprintf("Maximum age is %d", *rule.MaxAge))
+			}
+		}
+	}
+
+	if rule.PerSchoolQuota != nil || (rule.CreatorSchoolQuota != nil && schoolID.Valid && int(schoolID.Int64) == parent.SchoolID) {
+		var fromSchool int
+		if schoolID.Valid {
+			err = db.QueryRow(fmt.Sprintf(`
+				SELECT COUNT(*) FROM %s
+				WHERE %s = ? AND school_id = ? AND student_id <> ? AND status IN (%s, 'waitlisted')`,
+				t.queue.regTable, t.queue.regParentKey, t.queue.seatStatuses), targetID, schoolID.Int64, studentID).Scan(&fromSchool)
+			if err != nil {
+				return result, err
+			}
+		}
+		if rule.PerSchoolQuota != nil {
+			check("per_school_quota", fromSchool < *rule.PerSchoolQuota,
+				fmt.Sprintf("Only %d participants allowed from one school", *rule.PerSchoolQuota))
+		}
+		if rule.CreatorSchoolQuota != nil && schoolID.Valid && int(schoolID.Int64) == parent.SchoolID {
+			check("creator_school_quota", fromSchool < *rule.CreatorSchoolQuota,
+				fmt.Sprintf("Only %d participants allowed from the creator's school", *rule.CreatorSchoolQuota))
+		}
+	}
+
+	if rule.PrerequisiteOlympiadID != nil || rule.PrerequisiteLevel != "" || rule.PrerequisiteMaxPlace != nil {
+		maxPlace := 3
+		if rule.PrerequisiteMaxPlace != nil {
+			maxPlace = *rule.PrerequisiteMaxPlace
+		}
+		query := "SELECT EXISTS(SELECT 1 FROM Olympiads WHERE student_id = ? AND olympiad_place IS NOT NULL AND olympiad_place <= ?"
+		args := []interface{}{studentID, maxPlace}
+		message := fmt.Sprintf("A prize place (1-%d)", maxPlace)
+		if rule.PrerequisiteOlympiadID != nil {
+			query += " AND subject_olympiad_id = ?"
+			args = append(args, *rule.PrerequisiteOlympiadID)
+			message += fmt.Sprintf(" in olympiad #%d", *rule.PrerequisiteOlympiadID)
+		}
+		if rule.PrerequisiteLevel != "" {
+			query += " AND level = ?"
+			args = append(args, rule.PrerequisiteLevel)
+			message += fmt.Sprintf(" at %s level", rule.PrerequisiteLevel)
+		}
+		var placed bool
+		if err := db.QueryRow(query+")", args...).Scan(&placed); err != nil {
+			return result, err
+		}
+		check("prerequisite", placed, message+" is required")
+	}
+
+	result.Eligible = len(result.Reasons) == 0
+	return result, nil
+}
+
+func validateEligibilityRule(db *sql.DB, rule *models.EligibilityRule) string {
+	if rule.MinGrade != nil && (*rule.MinGrade < 1 || *rule.MinGrade > 12) ||
+		rule.MaxGrade != nil && (*rule.MaxGrade < 1 || *rule.MaxGrade > 12) {
+		return "Grades must be between 1 and 12"
+	}
+	if rule.MinGrade != nil && rule.MaxGrade != nil && *rule.MinGrade > *rule.MaxGrade {
+		return "min_grade cannot be greater than max_grade"
+	}
+	if rule.MinAge != nil && *rule.MinAge <= 0 || rule.MaxAge != nil && *rule.MaxAge <= 0 {
+		return "Ages must be positive"
+	}
+	if rule.MinAge != nil && rule.MaxAge != nil && *rule.MinAge > *rule.MaxAge {
+		return "min_age cannot be greater than max_age"
+	}
+	if rule.PerSchoolQuota != nil && *rule.PerSchoolQuota < 0 || rule.CreatorSchoolQuota != nil && *rule.CreatorSchoolQuota < 0 {
+		return "Quotas cannot be negative"
+	}
+	if rule.Gender != "" && rule.Gender != "male" && rule.Gender != "female" {
+		return "gender must be 'male' or 'female'"
+	}
+	if rule.PrerequisiteMaxPlace != nil && *rule.PrerequisiteMaxPlace < 1 {
+		return "prerequisite_max_place must be at least 1"
+	}
+	if rule.PrerequisiteOlympiadID != nil {
+		var exists bool
+		db.QueryRow("SELECT EXISTS(SELECT 1 FROM subject_olympiads WHERE subject_olympiad_id = ?)", *rule.PrerequisiteOlympiadID).Scan(&exists)
+		if !exists {
+			return "Prerequisite olympiad not found"
+		}
+	}
+	return ""
+}
+
+func eligibilityTargetID(w http.ResponseWriter, r *http.Request) (int, bool) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil || id <= 0 {
+		utils.RespondWithError(w, http.StatusBadRequest, models.Error{Message: "Invalid ID"})
+		return 0, false
+	}
+	return id, true
+}
+
+// loadParentForManage проверяет, что пользователь может менять правила
+// события: суперадмин или член школы-организатора с manage_events.
+func loadParentForManage(w http.ResponseWriter, db *sql.DB, t eligibilityTarget, targetID, userID int) (eligibilityParent, bool) {
+	parent, err := loadEligibilityParent(db, t, targetID)
+	if err == sql.ErrNoRows {
+		utils.RespondWithError(w, http.StatusNotFound, models.Error{Message: t.label + " not found"})
+		return parent, false
+	} else if err != nil {
+		log.Println("Error fetching eligibility target:", err)
+		utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Error fetching " + t.kind})
+		return parent, false
+	}
+
+	var role string
+	if err := db.QueryRow("SELECT role FROM users WHERE id = ?", userID).Scan(&role); err != nil && err != sql.ErrNoRows {
+		utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Error fetching user role"})
+		return parent, false
+	}
+	if role == "superadmin" {
+		return parent, true
+	}
+	allowed, err := hasSchoolPermission(db, userID, parent.SchoolID, PermissionManageEvents)
+	if err != nil {
+		utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Error checking permissions"})
+		return parent, false
+	}
+	if !allowed {
+		utils.RespondWithError(w, http.StatusForbidden, models.Error{Message: "You do not have permission to manage eligibility rules"})
+		return parent, false
+	}
+	return parent, true
+}
+
+func getEligibilityRules(db *sql.DB, t eligibilityTarget) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if _, err := utils.VerifyToken(r); err != nil {
+			utils.RespondWithError(w, http.StatusUnauthorized, models.Error{Message: "Unauthorized"})
+			return
+		}
+		targetID, ok := eligibilityTargetID(w, r)
+		if !ok {
+			return
+		}
+
+		parent, err := loadEligibilityParent(db, t, targetID)
+		if err == sql.ErrNoRows {
+			utils.RespondWithError(w, http.StatusNotFound, models.Error{Message: t.label + " not found"})
+			return
+		} else if err != nil {
+			log.Println("Error fetching eligibility target:", err)
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Error fetching " + t.kind})
+			return
+		}
+		rule, err := loadEligibilityRule(db, t, targetID, parent)
+		if err != nil {
+			log.Println("Error fetching eligibility rules:", err)
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Error fetching eligibility rules"})
+			return
+		}
+		utils.ResponseJSON(w, rule)
+	}
+}
+
+func updateEligibilityRules(db *sql.DB, t eligibilityTarget) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := utils.VerifyToken(r)
+		if err != nil {
+			utils.RespondWithError(w, http.StatusUnauthorized, models.Error{Message: "Unauthorized"})
+			return
+		}
+		targetID, ok := eligibilityTargetID(w, r)
+		if !ok {
+			return
+		}
+		parent, ok := loadParentForManage(w, db, t, targetID, userID)
+		if !ok {
+			return
+		}
+
+		var rule models.EligibilityRule
+		if err := json.NewDecoder(r.Body).Decode(&rule); err != nil {
+			utils.RespondWithError(w, http.StatusBadRequest, models.Error{Message: "Invalid request body"})
+			return
+		}
+		rule.Gender = strings.ToLower(strings.TrimSpace(rule.Gender))
+		if gender, ok := normalizeGender(rule.Gender); ok {
+			rule.Gender = gender
+		}
+		rule.PrerequisiteLevel = strings.TrimSpace(rule.PrerequisiteLevel)
+		if msg := validateEligibilityRule(db, &rule); msg != "" {
+			utils.RespondWithError(w, http.StatusBadRequest, models.Error{Message: msg})
+			return
+		}
+
+		lists := make([]interface{}, 0, 4)
+		for _, list := range []struct {
+			value interface{}
+			empty bool
+		}{
+			{rule.AllowedSchoolIDs, len(rule.AllowedSchoolIDs) == 0},
+			{rule.ExcludedSchoolIDs, len(rule.ExcludedSchoolIDs) == 0},
+			{rule.AllowedCities, len(rule.AllowedCities) == 0},
+			{rule.ExcludedCities, len(rule.ExcludedCities) == 0},
+		} {
+			v, err := nullableJSON(list.value, list.empty)
+			if err != nil {
+				utils.RespondWithError(w, http.StatusBadRequest, models.Error{Message: "Invalid list value"})
+				return
+			}
+			lists = append(lists, v)
+		}
+
+		_, err = db.Exec(`
+			INSERT INTO eligibility_rules (
+				target_type, target_id, min_grade, max_grade,
+				allowed_school_ids, excluded_school_ids, allowed_cities, excluded_cities,
+				per_school_quota, creator_school_quota, gender, min_age, max_age,
+				prerequisite_olympiad_id, prerequisite_level, prerequisite_max_place, updated_by
+			) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+			ON DUPLICATE KEY UPDATE
+				min_grade = VALUES(min_grade), max_grade = VALUES(max_grade),
+				allowed_school_ids = VALUES(allowed_school_ids), excluded_school_ids = VALUES(excluded_school_ids),
+				allowed_cities = VALUES(allowed_cities), excluded_cities = VALUES(excluded_cities),
+				per_school_quota = VALUES(per_school_quota), creator_school_quota = VALUES(creator_school_quota),
+				gender = VALUES(gender), min_age = VALUES(min_age), max_age = VALUES(max_age),
+				prerequisite_olympiad_id = VALUES(prerequisite_olympiad_id),
+				prerequisite_level = VALUES(prerequisite_level),
+				prerequisite_max_place = VALUES(prerequisite_max_place),
+				updated_by = VALUES(updated_by)`,
+			t.kind, targetID, nullableInt(rule.MinGrade), nullableInt(rule.MaxGrade),
+			lists[0], lists[1], lists[2], lists[3],
+			nullableInt(rule.PerSchoolQuota), nullableInt(rule.CreatorSchoolQuota), toNullString(rule.Gender),
+			nullableInt(rule.MinAge), nullableInt(rule.MaxAge),
+			nullableInt(rule.PrerequisiteOlympiadID), toNullString(rule.PrerequisiteLevel), nullableInt(rule.PrerequisiteMaxPlace),
+			userID)
+		if err != nil {
+			log.Println("Error saving eligibility rules:", err)
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Failed to save eligibility rules"})
+			return
+		}
+
+		saved, err := loadEligibilityRule(db, t, targetID, parent)
+		if err != nil {
+			log.Println("Error fetching eligibility rules:", err)
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Error fetching eligibility rules"})
+			return
+		}
+		utils.ResponseJSON(w, saved)
+	}
+}
+
+// deleteEligibilityRules возвращает событие к правилам по умолчанию.
+func deleteEligibilityRules(db *sql.DB, t eligibilityTarget) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := utils.VerifyToken(r)
+		if err != nil {
+			utils.RespondWithError(w, http.StatusUnauthorized, models.Error{Message: "Unauthorized"})
+			return
+		}
+		targetID, ok := eligibilityTargetID(w, r)
+		if !ok {
+			return
+		}
+		if _, ok := loadParentForManage(w, db, t, targetID, userID); !ok {
+			return
+		}
+
+		if _, err := db.Exec("DELETE FROM eligibility_rules WHERE target_type = ? AND target_id = ?", t.kind, targetID); err != nil {
+			log.Println("Error deleting eligibility rules:", err)
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Failed to delete eligibility rules"})
+			return
+		}
+		utils.ResponseJSON(w, map[string]string{"message": "Eligibility rules reset to default"})
+	}
+}
+
+// checkEligibility объясняет ученику, допущен ли он. Сотрудники школы
+// могут проверить ученика через ?student_id=.
+func checkEligibility(db *sql.DB, t eligibilityTarget) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := utils.VerifyToken(r)
+		if err != nil {
+			utils.RespondWithError(w, http.StatusUnauthorized, models.Error{Message: "Unauthorized"})
+			return
+		}
+		targetID, ok := eligibilityTargetID(w, r)
+		if !ok {
+			return
+		}
+
+		studentID := userID
+		if s := r.URL.Query().Get("student_id"); s != "" {
+			studentID, err = strconv.Atoi(s)
+			if err != nil || studentID <= 0 {
+				utils.RespondWithError(w, http.StatusBadRequest, models.Error{Message: "Invalid student_id"})
+				return
+			}
+			var studentSchoolID sql.NullInt64
+			err = db.QueryRow("SELECT school_id FROM student WHERE student_id = ?", studentID).Scan(&studentSchoolID)
+			if err == sql.ErrNoRows {
+				utils.RespondWithError(w, http.StatusNotFound, models.Error{Message: "Student not found"})
+				return
+			} else if err != nil {
+				utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Error fetching student"})
+				return
+			}
+			allowed, err := canViewSchool(db, userID, int(studentSchoolID.Int64))
+			if err != nil {
+				utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Error checking permissions"})
+				return
+			}
+			if !allowed {
+				utils.RespondWithError(w, http.StatusForbidden, models.Error{Message: "You do not have permission to view this student"})
+				return
+			}
+		}
+
+		result, err := evaluateEligibility(db, t, targetID, studentID)
+		if err == sql.ErrNoRows {
+			utils.RespondWithError(w, http.StatusNotFound, models.Error{Message: t.label + " or student not found"})
+			return
+		} else if err != nil {
+			log.Println("Error evaluating eligibility:", err)
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Error checking eligibility"})
+			return
+		}
+		utils.ResponseJSON(w, result)
+	}
+}
+
+func (ec *EligibilityController) GetEventEligibilityRules(db *sql.DB) http.HandlerFunc {
+	return getEligibilityRules(db, eventEligibility)
+}
+
+func (ec *EligibilityController) UpdateEventEligibilityRules(db *sql.DB) http.HandlerFunc {
+	return updateEligibilityRules(db, eventEligibility)
+}
+
+func (ec *EligibilityController) DeleteEventEligibilityRules(db *sql.DB) http.HandlerFunc {
+	return deleteEligibilityRules(db, eventEligibility)
+}
+
+func (ec *EligibilityController) CheckEventEligibility(db *sql.DB) http.HandlerFunc {
+	return checkEligibility(db, eventEligibility)
+}
+
+func (ec *EligibilityController) GetOlympiadEligibilityRules(db *sql.DB) http.HandlerFunc {
+	return getEligibilityRules(db, olympiadEligibility)
+}
+
+func (ec *EligibilityController) UpdateOlympiadEligibilityRules(db *sql.DB) http.HandlerFunc {
+	return updateEligibilityRules(db, olympiadEligibility)
+}
+
+func (ec *EligibilityController) DeleteOlympiadEligibilityRules(db *sql.DB) http.HandlerFunc {
+	return deleteEligibilityRules(db, olympiadEligibility)
+}
+
+func (ec *EligibilityController) CheckOlympiadEligibility(db *sql.DB) http.HandlerFunc {
+	return checkEligibility(db, olympiadEligibility)
+}
@@ -0,0 +1,562 @@
+package controllers
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"log"
+	"net/http"
+	"ranking-school/models"
+	"ranking-school/utils"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// Посещаемость мероприятий. У каждой заявки есть код билета, который
+// приложение ученика показывает в виде QR. Организатор сканирует его
+// телефоном: онлайн — по одному (check-in), без сети — копит сканы и
+// отправляет пачкой (check-in/batch). После мероприятия неотмеченные
+// заявки закрываются как no_show.
+
+// ticketQRPrefix — префикс содержимого QR-кода, чтобы сканер отличал наши
+// билеты от посторонних кодов. Check-in принимает и код, и весь payload.
+const ticketQRPrefix = "RSEVENT:"
+
+// maxCheckInBatch — ограничение на размер одной офлайн-пачки.
+const maxCheckInBatch = 1000
+
+func newTicketCode() (string, error) {
+	b := make([]byte, 10)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return strings.ToUpper(hex.EncodeToString(b)), nil
+}
+
+func ticketQRPayload(code string) string {
+	return ticketQRPrefix + code
+}
+
+func normalizeTicketCode(raw string) string {
+	code := strings.TrimSpace(raw)
+	if len(code) >= len(ticketQRPrefix) && strings.EqualFold(code[:len(ticketQRPrefix)], ticketQRPrefix) {
+		code = code[len(ticketQRPrefix):]
+	}
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+// Результаты отметки одного билета
+const (
+	checkInOK          = "checked_in"
+	checkInDuplicate   = "already_checked_in"
+	checkInNotFound    = "not_found"
+	checkInNotAdmitted = "not_admitted"
+)
+
+type checkInResult struct {
+	TicketCode          string `json:"ticket_code"`
+	Result              string `json:"result"`
+	EventRegistrationID int    `json:"event_registration_id,omitempty"`
+	StudentID           int    `json:"student_id,omitempty"`
+	StudentName         string `json:"student_name,omitempty"`
+	CheckedInAt         string `json:"checked_in_at,omitempty"`
+}
+
+// checkInTicket отмечает приход по билету. Повторный скан не меняет время
+// первой отметки, поэтому офлайн-пачку можно безопасно отправить ещё раз.
+func checkInTicket(tx *sql.Tx, eventID int, code string, at time.Time, checkedInBy int) (checkInResult, error) {
+	res := checkInResult{TicketCode: code, Result: checkInNotFound}
+	if code == "" {
+		return res, nil
+	}
+
+	var status string
+	var attendance, checkedInAt sql.NullString
+	err := tx.QueryRow(`
+		SELECT r.event_registration_id, r.student_id, COALESCE(r.status, ''), r.attendance, CAST(r.checked_in_at AS CHAR),
+		       TRIM(CONCAT(COALESCE(s.last_name, ''), ' ', COALESCE(s.first_name, '')))
+		FROM EventRegistrations r
+		LEFT JOIN student s ON s.student_id = r.student_id
+		WHERE r.ticket_code = ? AND r.event_id = ?
+		FOR UPDATE`, code, eventID).
+		Scan(&res.EventRegistrationID, &res.StudentID, &status, &attendance, &checkedInAt, &res.StudentName)
+	if err == sql.ErrNoRows {
+		return res, nil
+	} else if err != nil {
+		return res, err
+	}
+
+	if !strings.Contains(eventQueue.seatStatuses, "'"+status+"'") {
+		res.Result = checkInNotAdmitted
+		return res, nil
+	}
+	if attendance.String == "attended" {
+		res.Result = checkInDuplicate
+		res.CheckedInAt = checkedInAt.String
+		return res, nil
+	}
+
+	res.CheckedInAt = at.Format("2006-01-02 15:04:05")
+	_, err = tx.Exec(`
+		UPDATE EventRegistrations
+		SET attendance = 'attended', checked_in_at = ?, checked_in_by = ?
+		WHERE event_registration_id = ?`, res.CheckedInAt, checkedInBy, res.EventRegistrationID)
+	if err != nil {
+		return res, err
+	}
+	res.Result = checkInOK
+	return res, nil
+}
+
+type attendanceSummary struct {
+	Registered int `json:"registered"`
+	Attended   int `json:"attended"`
+	NoShow     int `json:"no_show"`
+	Unmarked   int `json:"unmarked"`
+}
+
+func loadAttendanceSummary(db rowQuerier, eventID int) (attendanceSummary, error) {
+	var s attendanceSummary
+	err := db.QueryRow(`
+		SELECT COUNT(*),
+		       COALESCE(SUM(attendance = 'attended'), 0),
+		       COALESCE(SUM(attendance = 'no_show'), 0),
+		       COALESCE(SUM(attendance IS NULL), 0)
+		FROM EventRegistrations
+		WHERE event_id = ? AND status IN (`+eventQueue.seatStatuses+`)`, eventID).
+		Scan(&s.Registered, &s.Attended, &s.NoShow, &s.Unmarked)
+	return s, err
+}
+
+// requireEventOrganizer — суперадмин или сотрудник школы-организатора
+// с правом manage_events.
+func requireEventOrganizer(w http.ResponseWriter, db *sql.DB, eventID, userID int) (eligibilityParent, bool) {
+	event, err := loadEligibilityParent(db, eventEligibility, eventID)
+	if err == sql.ErrNoRows {
+		utils.RespondWithError(w, http.StatusNotFound, models.Error{Message: "Event not found"})
+		return event, false
+	} else if err != nil {
+		log.Printf("Error fetching event %d: %v", eventID, err)
+		utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Error fetching event"})
+		return event, false
+	}
+
+	allowed, err := canManageEvents(db, userID, event.SchoolID)
+	if err != nil {
+		utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Error checking permissions"})
+		return event, false
+	}
+	if !allowed {
+		utils.RespondWithError(w, http.StatusForbidden, models.Error{Message: "Only event organisers can do this"})
+		return event, false
+	}
+	return event, true
+}
+
+func eventIDFromPath(w http.ResponseWriter, r *http.Request) (int, bool) {
+	eventID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil || eventID <= 0 {
+		utils.RespondWithError(w, http.StatusBadRequest, models.Error{Message: "Invalid event ID"})
+		return 0, false
+	}
+	return eventID, true
+}
+
+// GetMyEventTicket — билет ученика на мероприятие (код и содержимое QR).
+// Картинку QR рисует клиент. Для старых заявок без кода билет выпускается
+// при первом запросе.
+func (ec *EventsRegistrationController) GetMyEventTicket(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := utils.VerifyToken(r)
+		if err != nil {
+			utils.RespondWithError(w, http.StatusUnauthorized, models.Error{Message: "Unauthorized"})
+			return
+		}
+
+		registrationID, err := strconv.Atoi(mux.Vars(r)["id"])
+		if err != nil || registrationID <= 0 {
+			utils.RespondWithError(w, http.StatusBadRequest, models.Error{Message: "Invalid registration ID"})
+			return
+		}
+
+		var reg models.EventRegistration
+		var ticketCode, attendance, checkedInAt sql.NullString
+		err = db.QueryRow(`
+			SELECT r.event_registration_id, r.student_id, r.event_id, COALESCE(r.status, ''),
+			       r.ticket_code, r.attendance, CAST(r.checked_in_at AS CHAR),
+			       COALESCE(e.event_name, ''), COALESCE(CAST(e.start_date AS CHAR), ''), COALESCE(CAST(e.end_date AS CHAR), '')
+			FROM EventRegistrations r
+			JOIN Events e ON e.id = r.event_id
+			WHERE r.event_registration_id = ? AND r.student_id = ?`, registrationID, userID).
+			Scan(&reg.EventRegistrationID, &reg.StudentID, &reg.EventID, &reg.Status,
+				&ticketCode, &attendance, &checkedInAt,
+				&reg.EventName, &reg.EventStartDate, &reg.EventEndDate)
+		if err == sql.ErrNoRows {
+			utils.RespondWithError(w, http.StatusNotFound, models.Error{Message: "Registration not found"})
+			return
+		} else if err != nil {
+			log.Printf("Error fetching ticket for registration %d: %v", registrationID, err)
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Error fetching ticket"})
+			return
+		}
+
+		if !strings.Contains(eventQueue.seatStatuses, "'"+reg.Status+"'") {
+			utils.RespondWithError(w, http.StatusConflict, models.Error{Message: "Ticket is available only for confirmed registrations"})
+			return
+		}
+
+		if !ticketCode.Valid || ticketCode.String == "" {
+			code, err := newTicketCode()
+			if err != nil {
+				utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Failed to generate ticket"})
+				return
+			}
+			_, err = db.Exec("UPDATE EventRegistrations SET ticket_code = ? WHERE event_registration_id = ? AND ticket_code IS NULL", code, registrationID)
+			if err != nil {
+				log.Printf("Error issuing ticket for registration %d: %v", registrationID, err)
+				utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Failed to generate ticket"})
+				return
+			}
+			db.QueryRow("SELECT ticket_code FROM EventRegistrations WHERE event_registration_id = ?", registrationID).Scan(&ticketCode)
+		}
+
+		utils.ResponseJSON(w, map[string]interface{}{
+			"event_registration_id": reg.EventRegistrationID,
+			"event_id":              reg.EventID,
+			"event_name":            reg.EventName,
+			"start_date":            reg.EventStartDate,
+			"end_date":              reg.EventEndDate,
+			"status":                reg.Status,
+			"ticket_code":           ticketCode.String,
+			"qr_payload":            ticketQRPayload(ticketCode.String),
+			"attendance":            attendance.String,
+			"checked_in_at":         checkedInAt.String,
+		})
+	}
+}
+
+// CheckInEventTicket — онлайн-отметка одного билета организатором.
+func (ec *EventsRegistrationController) CheckInEventTicket(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := utils.VerifyToken(r)
+		if err != nil {
+			utils.RespondWithError(w, http.StatusUnauthorized, models.Error{Message: "Unauthorized"})
+			return
+		}
+		eventID, ok := eventIDFromPath(w, r)
+		if !ok {
+			return
+		}
+		if _, ok := requireEventOrganizer(w, db, eventID, userID); !ok {
+			return
+		}
+
+		var req struct {
+			TicketCode string `json:"ticket_code"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || strings.TrimSpace(req.TicketCode) == "" {
+			utils.RespondWithError(w, http.StatusBadRequest, models.Error{Message: "ticket_code is required"})
+			return
+		}
+
+		tx, err := db.Begin()
+		if err != nil {
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Database error"})
+			return
+		}
+		defer tx.Rollback()
+
+		result, err := checkInTicket(tx, eventID, normalizeTicketCode(req.TicketCode), time.Now(), userID)
+		if err != nil {
+			log.Printf("Error checking in ticket for event %d: %v", eventID, err)
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Failed to check in"})
+			return
+		}
+		if err := tx.Commit(); err != nil {
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Failed to check in"})
+			return
+		}
+
+		switch result.Result {
+		case checkInNotFound:
+			utils.RespondWithError(w, http.StatusNotFound, models.Error{Message: "Ticket not found for this event"})
+			return
+		case checkInNotAdmitted:
+			utils.RespondWithError(w, http.StatusConflict, models.Error{Message: "Registration is not confirmed (waitlisted or canceled)"})
+			return
+		}
+
+		summary, err := loadAttendanceSummary(db, eventID)
+		if err != nil {
+			log.Printf("Error loading attendance summary for event %d: %v", eventID, err)
+		}
+		utils.ResponseJSON(w, map[string]interface{}{
+			"check_in": result,
+			"summary":  summary,
+		})
+	}
+}
+
+// BatchCheckInEvent принимает сканы, накопленные телефоном без сети.
+// Время отметки берётся из скана (если оно не в будущем), ответ содержит
+// результат по каждому билету. Повторная отправка пачки безопасна.
+func (ec *EventsRegistrationController) BatchCheckInEvent(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := utils.VerifyToken(r)
+		if err != nil {
+			utils.RespondWithError(w, http.StatusUnauthorized, models.Error{Message: "Unauthorized"})
+			return
+		}
+		eventID, ok := eventIDFromPath(w, r)
+		if !ok {
+			return
+		}
+		if _, ok := requireEventOrganizer(w, db, eventID, userID); !ok {
+			return
+		}
+
+		var req struct {
+			Scans []struct {
+				TicketCode string `json:"ticket_code"`
+				ScannedAt  string `json:"scanned_at"`
+			} `json:"scans"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || len(req.Scans) == 0 {
+			utils.RespondWithError(w, http.StatusBadRequest, models.Error{Message: "scans must be a non-empty array"})
+			return
+		}
+		if len(req.Scans) > maxCheckInBatch {
+			utils.RespondWithError(w, http.StatusBadRequest, models.Error{Message: "Too many scans in one batch, max " + strconv.Itoa(maxCheckInBatch)})
+			return
+		}
+
+		tx, err := db.Begin()
+		if err != nil {
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Database error"})
+			return
+		}
+		defer tx.Rollback()
+
+		now := time.Now()
+		results := make([]checkInResult, 0, len(req.Scans))
+		counts := map[string]int{}
+		for _, scan := range req.Scans {
+			at := now
+			if scan.ScannedAt != "" {
+				if t, err := time.Parse(time.RFC3339, scan.ScannedAt); err == nil {
+					at = t.Local()
+				} else if t, err := parseWindowTime(scan.ScannedAt); err == nil {
+					at = t
+				}
+				if at.After(now) {
+					at = now
+				}
+			}
+
+			result, err := checkInTicket(tx, eventID, normalizeTicketCode(scan.TicketCode), at, userID)
+			if err != nil {
+				log.Printf("Error checking in batch for event %d: %v", eventID, err)
+				utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Failed to process scans"})
+				return
+			}
+			counts[result.Result]++
+			results = append(results, result)
+		}
+
+		if err := tx.Commit(); err != nil {
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Failed to process scans"})
+			return
+		}
+
+		summary, err := loadAttendanceSummary(db, eventID)
+		if err != nil {
+			log.Printf("Error loading attendance summary for event %d: %v", eventID, err)
+		}
+		utils.ResponseJSON(w, map[string]interface{}{
+			"results": results,
+			"counts":  counts,
+			"summary": summary,
+		})
+	}
+}
+
+// GetEventAttendance — список подтверждённых заявок с билетами и отметками.
+// Приложение организатора загружает его заранее, чтобы проверять билеты
+// без сети.
+func (ec *EventsRegistrationController) GetEventAttendance(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := utils.VerifyToken(r)
+		if err != nil {
+			utils.RespondWithError(w, http.StatusUnauthorized, models.Error{Message: "Unauthorized"})
+			return
+		}
+		eventID, ok := eventIDFromPath(w, r)
+		if !ok {
+			return
+		}
+		if _, ok := requireEventOrganizer(w, db, eventID, userID); !ok {
+			return
+		}
+
+		rows, err := db.Query(`
+			SELECT r.event_registration_id, r.student_id, r.event_id, COALESCE(r.status, ''), COALESCE(r.school_id, 0),
+			       COALESCE(s.first_name, ''), COALESCE(s.last_name, ''), COALESCE(s.patronymic, ''),
+			       COALESCE(s.grade, 0), COALESCE(s.letter, ''),
+			       COALESCE(r.ticket_code, ''), COALESCE(r.attendance, ''), COALESCE(CAST(r.checked_in_at AS CHAR), '')
+			FROM EventRegistrations r
+			LEFT JOIN student s ON s.student_id = r.student_id
+			WHERE r.event_id = ? AND r.status IN (`+eventQueue.seatStatuses+`)
+			ORDER BY s.last_name, s.first_name`, eventID)
+		if err != nil {
+			log.Printf("Error fetching attendance for event %d: %v", eventID, err)
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Error fetching attendance"})
+			return
+		}
+		defer rows.Close()
+
+		registrations := []models.EventRegistration{}
+		for rows.Next() {
+			var reg models.EventRegistration
+			if err := rows.Scan(&reg.EventRegistrationID, &reg.StudentID, &reg.EventID, &reg.Status, &reg.SchoolID,
+				&reg.StudentFirstName, &reg.StudentLastName, &reg.StudentPatronymic,
+				&reg.StudentGrade, &reg.StudentLetter,
+				&reg.TicketCode, &reg.Attendance, &reg.CheckedInAt); err != nil {
+				log.Printf("Error scanning attendance row: %v", err)
+				utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Error fetching attendance"})
+				return
+			}
+			registrations = append(registrations, reg)
+		}
+
+		summary, err := loadAttendanceSummary(db, eventID)
+		if err != nil {
+			log.Printf("Error loading attendance summary for event %d: %v", eventID, err)
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Error fetching attendance"})
+			return
+		}
+
+		utils.ResponseJSON(w, map[string]interface{}{
+			"event_id":      eventID,
+			"summary":       summary,
+			"registrations": registrations,
+		})
+	}
+}
+
+// SetEventRegistrationAttendance — ручная правка отметки организатором
+// (attended / no_show, пустое значение сбрасывает отметку).
+func (ec *EventsRegistrationController) SetEventRegistrationAttendance(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := utils.VerifyToken(r)
+		if err != nil {
+			utils.RespondWithError(w, http.StatusUnauthorized, models.Error{Message: "Unauthorized"})
+			return
+		}
+
+		registrationID, err := strconv.Atoi(mux.Vars(r)["id"])
+		if err != nil || registrationID <= 0 {
+			utils.RespondWithError(w, http.StatusBadRequest, models.Error{Message: "Invalid registration ID"})
+			return
+		}
+
+		var req struct {
+			Attendance string `json:"attendance"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			utils.RespondWithError(w, http.StatusBadRequest, models.Error{Message: "Invalid request body"})
+			return
+		}
+		if req.Attendance != "" && req.Attendance != "attended" && req.Attendance != "no_show" {
+			utils.RespondWithError(w, http.StatusBadRequest, models.Error{Message: "attendance must be attended, no_show or empty"})
+			return
+		}
+
+		var eventID int
+		var status string
+		err = db.QueryRow("SELECT event_id, COALESCE(status, '') FROM EventRegistrations WHERE event_registration_id = ?", registrationID).Scan(&eventID, &status)
+		if err == sql.ErrNoRows {
+			utils.RespondWithError(w, http.StatusNotFound, models.Error{Message: "Registration not found"})
+			return
+		} else if err != nil {
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Error fetching registration"})
+			return
+		}
+		if _, ok := requireEventOrganizer(w, db, eventID, userID); !ok {
+			return
+		}
+		if req.Attendance != "" && !strings.Contains(eventQueue.seatStatuses, "'"+status+"'") {
+			utils.RespondWithError(w, http.StatusConflict, models.Error{Message: "Registration is not confirmed (waitlisted or canceled)"})
+			return
+		}
+
+		switch req.Attendance {
+		case "attended":
+			_, err = db.Exec(`
+				UPDATE EventRegistrations
+				SET attendance = 'attended', checked_in_at = COALESCE(checked_in_at, NOW()), checked_in_by = COALESCE(checked_in_by, ?)
+				WHERE event_registration_id = ?`, userID, registrationID)
+		case "no_show":
+			_, err = db.Exec("UPDATE EventRegistrations SET attendance = 'no_show', checked_in_at = NULL, checked_in_by = ? WHERE event_registration_id = ?", userID, registrationID)
+		default:
+			_, err = db.Exec("UPDATE EventRegistrations SET attendance = NULL, checked_in_at = NULL, checked_in_by = NULL WHERE event_registration_id = ?", registrationID)
+		}
+		if err != nil {
+			log.Printf("Error updating attendance for registration %d: %v", registrationID, err)
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Failed to update attendance"})
+			return
+		}
+
+		utils.ResponseJSON(w, map[string]interface{}{
+			"event_registration_id": registrationID,
+			"attendance":            req.Attendance,
+		})
+	}
+}
+
+// FinalizeEventAttendance отмечает всех неотмеченных участников как no_show.
+// Доступно после начала мероприятия.
+func (ec *EventsRegistrationController) FinalizeEventAttendance(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := utils.VerifyToken(r)
+		if err != nil {
+			utils.RespondWithError(w, http.StatusUnauthorized, models.Error{Message: "Unauthorized"})
+			return
+		}
+		eventID, ok := eventIDFromPath(w, r)
+		if !ok {
+			return
+		}
+		event, ok := requireEventOrganizer(w, db, eventID, userID)
+		if !ok {
+			return
+		}
+
+		if start, err := parseWindowTime(event.StartDate); err == nil && time.Now().Before(start) {
+			utils.RespondWithError(w, http.StatusConflict, models.Error{Message: "Event has not started yet"})
+			return
+		}
+
+		res, err := db.Exec(`
+			UPDATE EventRegistrations SET attendance = 'no_show', checked_in_by = ?
+			WHERE event_id = ? AND attendance IS NULL AND status IN (`+eventQueue.seatStatuses+`)`, userID, eventID)
+		if err != nil {
+			log.Printf("Error finalizing attendance for event %d: %v", eventID, err)
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Failed to finalize attendance"})
+			return
+		}
+		marked, _ := res.RowsAffected()
+
+		summary, err := loadAttendanceSummary(db, eventID)
+		if err != nil {
+			log.Printf("Error loading attendance summary for event %d: %v", eventID, err)
+		}
+		utils.ResponseJSON(w, map[string]interface{}{
+			"marked_no_show": marked,
+			"summary":        summary,
+		})
+	}
+}
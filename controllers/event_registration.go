@@ -127,15 +127,15 @@ func (ec *EventsRegistrationController) RegisterForEvent(db *sql.DB) http.Handle
 
 		var role string
 		var studentSchoolID sql.NullInt64
-		var studentGrade int
-		err = db.QueryRow("SELECT role, school_id, grade FROM student WHERE student_id = ?", userID).Scan(&role, &studentSchoolID, &studentGrade)
+		err = db.QueryRow("SELECT role, school_id FROM student WHERE student_id = ?", userID).Scan(&role, &studentSchoolID)
 		if err != nil || role != "student" {
 			utils.RespondWithError(w, http.StatusForbidden, models.Error{Message: "Only students can register for events"})
 			return
 		}
 
 		var body struct {
-			EventID int `json:"event_id"`
+			EventID        int  `json:"event_id"`
+			AllowConflicts bool `json:"allow_conflicts"`
 		}
 		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
 			utils.RespondWithError(w, http.StatusBadRequest, models.Error{Message: "Invalid body"})
@@ -156,40 +156,48 @@ func (ec *EventsRegistrationController) RegisterForEvent(db *sql.DB) http.Handle
 		}
 		defer tx.Rollback()
 
-		var existingRegistration int
-		err = tx.QueryRow("SELECT COUNT(*) FROM EventRegistrations WHERE student_id = ? AND event_id = ? AND status = 'registered'", userID, eventID).Scan(&existingRegistration)
-		if err != nil {
-			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Database error"})
+		// Строка события блокируется до коммита: параллельные заявки
+		// проверяют лимит мест по очереди.
+		limitCount, taken, err := eventQueue.lock(tx, eventID)
+		if err == sql.ErrNoRows {
+			utils.RespondWithError(w, http.StatusNotFound, models.Error{Message: "Event not found"})
 			return
 		}
-		if existingRegistration > 0 {
-			utils.RespondWithError(w, http.StatusBadRequest, models.Error{Message: "Already registered for this event"})
+		if err != nil {
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Error checking event"})
 			return
 		}
 
-		var eventGrade, limitCount, participants int
-		var eventSchoolID int
-		var eventName, eventStartDate string
-
-		err = tx.QueryRow("SELECT school_id, grade, limit_count, event_name, participants, start_date FROM Events WHERE id = ?", eventID).
-			Scan(&eventSchoolID, &eventGrade, &limitCount, &eventName, &participants, &eventStartDate)
-		if err == sql.ErrNoRows {
+		// Регистрация открыта только на опубликованные мероприятия
+		var eventStatus string
+		if err := tx.QueryRow("SELECT status FROM Events WHERE id = ?", eventID).Scan(&eventStatus); err != nil {
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Error checking event"})
+			return
+		}
+		if eventStatus != PublicationPublished {
 			utils.RespondWithError(w, http.StatusNotFound, models.Error{Message: "Event not found"})
 			return
 		}
+
+		var existingRegistration int
+		err = tx.QueryRow("SELECT COUNT(*) FROM EventRegistrations WHERE student_id = ? AND event_id = ? AND status IN ("+eventQueue.seatStatuses+", 'waitlisted')", userID, eventID).Scan(&existingRegistration)
 		if err != nil {
-			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Error checking event"})
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Database error"})
 			return
 		}
-
-		parsedStartDate, err := time.Parse("2006-01-02", eventStartDate)
-		if err == nil && time.Now().After(parsedStartDate) {
-			utils.RespondWithError(w, http.StatusBadRequest, models.Error{Message: "Registration has already closed"})
+		if existingRegistration > 0 {
+			utils.RespondWithError(w, http.StatusBadRequest, models.Error{Message: "Already registered for this event"})
 			return
 		}
 
-		if studentGrade != eventGrade {
-			utils.RespondWithError(w, http.StatusForbidden, models.Error{Message: "Your grade does not match the required grade for this event"})
+		window, err := loadEventWindow(tx, eventID)
+		if err != nil {
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Error checking event"})
+			return
+		}
+		isLate, closedMessage := window.check(time.Now())
+		if closedMessage != "" {
+			utils.RespondWithError(w, http.StatusBadRequest, models.Error{Message: closedMessage})
 			return
 		}
 
@@ -202,46 +210,74 @@ func (ec *EventsRegistrationController) RegisterForEvent(db *sql.DB) http.Handle
 			return
 		}
 
-		// 🔒 Ограничение 2 участника только от школы-организатора
-		if schoolID == int64(eventSchoolID) {
-			var countFromCreatorSchool int
-			err = tx.QueryRow(`
-		SELECT COUNT(*) FROM EventRegistrations
-		WHERE event_id = ? AND school_id = ?`,
-				eventID, schoolID).Scan(&countFromCreatorSchool)
-			if err != nil {
-				utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Error checking school participant count"})
-				return
-			}
-			if countFromCreatorSchool >= 2 {
-				utils.RespondWithError(w, http.StatusBadRequest, models.Error{Message: "Only 2 participants allowed from the creator's school"})
-				return
-			}
+		// Правила допуска (класс, школы, квоты и т.д.)
+		eligibility, err := evaluateEligibility(tx, eventEligibility, eventID, userID)
+		if err != nil {
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Error checking eligibility"})
+			return
+		}
+		if !eligibility.Eligible {
+			utils.RespondWithError(w, http.StatusForbidden, models.Error{Message: strings.Join(eligibility.Reasons, "; ")})
+			return
 		}
 
-		if limitCount > 0 && participants >= limitCount {
-			utils.RespondWithError(w, http.StatusBadRequest, models.Error{Message: "Registration limit for this event has been reached"})
+		// Пересечения с мероприятиями и сессиями, на которые ученик уже записан
+		conflicts, err := eventScheduleConflicts(tx, eventID, userID)
+		if err != nil {
+			log.Printf("Error checking schedule conflicts for student %d: %v", userID, err)
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Error checking schedule conflicts"})
+			return
+		}
+		if len(conflicts) > 0 && !body.AllowConflicts {
+			utils.RespondWithError(w, http.StatusConflict, models.Error{Message: "Schedule conflict with: " + strings.Join(conflicts, "; ")})
 			return
 		}
 
+		// Мест нет — заявка попадает в лист ожидания
+		newStatus := "registered"
+		message := "Successfully registered for event"
+		if !hasFreeSeat(limitCount, taken) {
+			newStatus = "waitlisted"
+			message = "Event is full, you have been added to the waitlist"
+		}
+
 		currentTime := time.Now().Format("2006-01-02 15:04:05")
 
+		// Код билета кодируется в QR, по нему организатор отмечает приход
+		ticketCode, err := newTicketCode()
+		if err != nil {
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Failed to generate ticket"})
+			return
+		}
+
 		result, err := tx.Exec(`
-			INSERT INTO EventRegistrations (student_id, event_id, registration_date, status, school_id)
-			VALUES (?, ?, ?, 'registered', ?)`,
-			userID, eventID, currentTime, schoolID)
+			INSERT INTO EventRegistrations (student_id, event_id, registration_date, status, school_id, is_late, ticket_code)
+			VALUES (?, ?, ?, ?, ?, ?, ?)`,
+			userID, eventID, currentTime, newStatus, schoolID, isLate, ticketCode)
 		if err != nil {
 			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Failed to register for event"})
 			return
 		}
 
-		_, err = tx.Exec("UPDATE Events SET participants = participants + 1 WHERE id = ?", eventID)
+		registrationID, _ := result.LastInsertId()
+
+		if newStatus == "registered" {
+			taken++
+		}
+		_, err = tx.Exec("UPDATE Events SET participants = ? WHERE id = ?", taken, eventID)
 		if err != nil {
 			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Failed to update participants count"})
 			return
 		}
 
-		registrationID, _ := result.LastInsertId()
+		var waitlistPosition int
+		if newStatus == "waitlisted" {
+			waitlistPosition, err = eventQueue.waitlistPosition(tx, eventID, int(registrationID))
+			if err != nil {
+				utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Failed to compute waitlist position"})
+				return
+			}
+		}
 
 		var registration models.EventRegistration
 		var regDateStr string
@@ -289,8 +325,8 @@ func (ec *EventsRegistrationController) RegisterForEvent(db *sql.DB) http.Handle
 				EventRegistrationID: int(registrationID),
 				StudentID:           userID,
 				EventID:             eventID,
-				Status:              "registered",
-				Message:             "Successfully registered for event",
+				Status:              newStatus,
+				Message:             message,
 			}
 		} else {
 			registration.RegistrationDate, _ = time.Parse("2006-01-02 15:04:05", regDateStr)
@@ -303,8 +339,11 @@ func (ec *EventsRegistrationController) RegisterForEvent(db *sql.DB) http.Handle
 			if eventEnd.Valid {
 				registration.EventEndDate = eventEnd.String
 			}
-			registration.Message = "Successfully registered for event"
+			registration.Message = message
 		}
+		registration.WaitlistPosition = waitlistPosition
+		registration.IsLate = isLate
+		registration.TicketCode = ticketCode
 
 		if err := tx.Commit(); err != nil {
 			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Failed to complete registration"})
@@ -325,8 +364,7 @@ func (ec *EventsRegistrationController) UpdateEventRegistrationStatus(db *sql.DB
 		}
 
 		var role string
-		var userSchoolID sql.NullInt64
-		err = db.QueryRow("SELECT role, school_id FROM users WHERE id = ?", userID).Scan(&role, &userSchoolID)
+		err = db.QueryRow("SELECT role FROM users WHERE id = ?", userID).Scan(&role)
 		if err != nil || (role != "schooladmin" && role != "superadmin") {
 			log.Printf("Access denied for user %d: invalid role or error %v", userID, err)
 			utils.RespondWithError(w, http.StatusForbidden, models.Error{Message: "Access denied"})
@@ -384,6 +422,38 @@ func (ec *EventsRegistrationController) UpdateEventRegistrationStatus(db *sql.DB
 		// 	}
 		// }
 
+		var eventID int
+		err = tx.QueryRow("SELECT event_id FROM EventRegistrations WHERE event_registration_id = ?", regID).Scan(&eventID)
+		if err == sql.ErrNoRows {
+			log.Printf("Registration %d not found", regID)
+			utils.RespondWithError(w, http.StatusNotFound, models.Error{Message: "Registration not found"})
+			return
+		}
+		if err != nil {
+			log.Printf("Error fetching registration %d: %v", regID, err)
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Error checking registration"})
+			return
+		}
+
+		// Заявка из листа ожидания может занять место только если оно свободно
+		limitCount, taken, err := eventQueue.lock(tx, eventID)
+		if err != nil {
+			log.Printf("Error locking event %d: %v", eventID, err)
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Error checking event"})
+			return
+		}
+		var currentStatus string
+		err = tx.QueryRow("SELECT status FROM EventRegistrations WHERE event_registration_id = ? FOR UPDATE", regID).Scan(&currentStatus)
+		if err != nil {
+			log.Printf("Error fetching status for registration %d: %v", regID, err)
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Error checking registration"})
+			return
+		}
+		if currentStatus == "waitlisted" && body.Status == "completed" && !hasFreeSeat(limitCount, taken) {
+			utils.RespondWithError(w, http.StatusConflict, models.Error{Message: "Event is full, the registration stays on the waitlist"})
+			return
+		}
+
 		_, err = tx.Exec("UPDATE EventRegistrations SET status = ? WHERE event_registration_id = ?", body.Status, regID)
 		if err != nil {
 			log.Printf("Error updating status for registration %d: %v", regID, err)
@@ -391,6 +461,21 @@ func (ec *EventsRegistrationController) UpdateEventRegistrationStatus(db *sql.DB
 			return
 		}
 
+		// Отмена освобождает место — переводим следующего из листа ожидания
+		promotions, err := eventQueue.promote(tx, eventID)
+		if err != nil {
+			log.Printf("Error promoting waitlist for event %d: %v", eventID, err)
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Failed to update waitlist"})
+			return
+		}
+		sessionPromotions, err := releaseSessionSeats(tx, eventID)
+		if err != nil {
+			log.Printf("Error releasing session seats for event %d: %v", eventID, err)
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Failed to update waitlist"})
+			return
+		}
+		promotions = append(promotions, sessionPromotions...)
+
 		var registration models.EventRegistration
 		var regDateStr string
 		var eventEnd sql.NullString
@@ -435,6 +520,7 @@ func (ec *EventsRegistrationController) UpdateEventRegistrationStatus(db *sql.DB
 			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Failed to update registration"})
 			return
 		}
+		emailWaitlistPromotions(db, promotions)
 
 		registration.RegistrationDate, _ = time.Parse("2006-01-02 15:04:05", regDateStr)
 		if status.Valid {
@@ -461,8 +547,7 @@ func (ec *EventsRegistrationController) GetEventRegistrations(db *sql.DB) http.H
 		}
 
 		var role string
-		var schoolID sql.NullInt64
-		err = db.QueryRow("SELECT role, school_id FROM users WHERE id = ?", userID).Scan(&role, &schoolID)
+		err = db.QueryRow("SELECT role FROM users WHERE id = ?", userID).Scan(&role)
 		if err != nil || (role != "schooladmin" && role != "superadmin") {
 			log.Printf("Access denied for user %d: invalid role or error %v", userID, err)
 			utils.RespondWithError(w, http.StatusForbidden, models.Error{Message: "Access denied"})
@@ -471,6 +556,12 @@ func (ec *EventsRegistrationController) GetEventRegistrations(db *sql.DB) http.H
 
 		var rows *sql.Rows
 		if role == "schooladmin" {
+			schoolID, err := getMemberSchoolID(db, userID, "")
+			if err != nil {
+				log.Printf("Error fetching memberships for schooladmin %d: %v", userID, err)
+				utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Database error"})
+				return
+			}
 			if !schoolID.Valid {
 				log.Printf("No school assigned to schooladmin %d", userID)
 				utils.RespondWithError(w, http.StatusForbidden, models.Error{Message: "No school assigned"})
@@ -487,7 +578,7 @@ func (ec *EventsRegistrationController) GetEventRegistrations(db *sql.DB) http.H
                 JOIN student s ON r.student_id = s.student_id
                 JOIN Schools sc ON r.school_id = sc.school_id
                 JOIN Events e ON r.event_id = e.id
-                WHERE r.school_id = ?`, schoolID.Int64) // УБРАЛ фильтр по статусу
+                WHERE r.school_id IN (SELECT school_id FROM school_members WHERE user_id = ?)`, userID) // УБРАЛ фильтр по статусу
 		} else {
 			rows, err = db.Query(`
                 SELECT r.event_registration_id, r.student_id, r.event_id, r.registration_date, r.status,
@@ -595,8 +686,7 @@ func (ec *EventsRegistrationController) DeleteEventRegistrationByID(db *sql.DB)
 		defer tx.Rollback()
 
 		var role string
-		var userSchoolID sql.NullInt64
-		err = tx.QueryRow("SELECT role, school_id FROM users WHERE id = ?", userID).Scan(&role, &userSchoolID)
+		err = tx.QueryRow("SELECT role FROM users WHERE id = ?", userID).Scan(&role)
 		if err != nil {
 			log.Printf("Error fetching user role for user %d: %v", userID, err)
 			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Error fetching user details"})
@@ -609,21 +699,36 @@ func (ec *EventsRegistrationController) DeleteEventRegistrationByID(db *sql.DB)
 			return
 		}
 
+		var regSchoolID, eventID int
+		err = tx.QueryRow("SELECT school_id, event_id FROM EventRegistrations WHERE event_registration_id = ?", regID).Scan(&regSchoolID, &eventID)
+		if err == sql.ErrNoRows {
+			log.Printf("Registration %d not found", regID)
+			utils.RespondWithError(w, http.StatusNotFound, models.Error{Message: "Registration not found"})
+			return
+		}
+		if err != nil {
+			log.Printf("Error fetching registration %d: %v", regID, err)
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Error checking registration"})
+			return
+		}
+
+		// Строку мероприятия блокируем раньше заявки — в том же порядке,
+		// что и eventQueue.promote, иначе параллельные отмены взаимоблокируются
+		if _, _, err := eventQueue.lock(tx, eventID); err != nil {
+			log.Printf("Error locking event %d: %v", eventID, err)
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Error checking event"})
+			return
+		}
+
 		if role == "schooladmin" {
-			var regSchoolID int
-			err = tx.QueryRow("SELECT school_id FROM EventRegistrations WHERE event_registration_id = ?", regID).Scan(&regSchoolID)
-			if err == sql.ErrNoRows {
-				log.Printf("Registration %d not found", regID)
-				utils.RespondWithError(w, http.StatusNotFound, models.Error{Message: "Registration not found"})
-				return
-			}
+			allowed, err := hasSchoolPermission(tx, userID, regSchoolID, PermissionManageEvents)
 			if err != nil {
-				log.Printf("Error fetching registration %d: %v", regID, err)
+				log.Printf("Error checking membership for schooladmin %d: %v", userID, err)
 				utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Error checking registration"})
 				return
 			}
-			if !userSchoolID.Valid || regSchoolID != int(userSchoolID.Int64) {
-				log.Printf("School mismatch for schooladmin %d, registration %d: user school %v, reg school %d", userID, regID, userSchoolID, regSchoolID)
+			if !allowed {
+				log.Printf("School mismatch for schooladmin %d, registration %d: reg school %d", userID, regID, regSchoolID)
 				utils.RespondWithError(w, http.StatusForbidden, models.Error{Message: "You can only delete registrations for your school"})
 				return
 			}
@@ -643,11 +748,26 @@ func (ec *EventsRegistrationController) DeleteEventRegistrationByID(db *sql.DB)
 			return
 		}
 
+		promotions, err := eventQueue.promote(tx, eventID)
+		if err != nil {
+			log.Printf("Error promoting waitlist for event %d: %v", eventID, err)
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Failed to update waitlist"})
+			return
+		}
+		sessionPromotions, err := releaseSessionSeats(tx, eventID)
+		if err != nil {
+			log.Printf("Error releasing session seats for event %d: %v", eventID, err)
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Failed to update waitlist"})
+			return
+		}
+		promotions = append(promotions, sessionPromotions...)
+
 		if err := tx.Commit(); err != nil {
 			log.Printf("Error committing transaction for registration %d: %v", regID, err)
 			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Failed to delete registration"})
 			return
 		}
+		emailWaitlistPromotions(db, promotions)
 
 		utils.ResponseJSON(w, map[string]string{"message": "Registration deleted successfully"})
 	}
@@ -690,8 +810,8 @@ func (ec *EventsRegistrationController) DeleteMyEventRegistration(db *sql.DB) ht
 			return
 		}
 
-		var regStudentID int
-		err = tx.QueryRow("SELECT student_id FROM EventRegistrations WHERE event_registration_id = ?", regID).Scan(&regStudentID)
+		var regStudentID, eventID int
+		err = tx.QueryRow("SELECT student_id, event_id FROM EventRegistrations WHERE event_registration_id = ?", regID).Scan(&regStudentID, &eventID)
 		if err == sql.ErrNoRows {
 			log.Printf("Registration %d not found", regID)
 			utils.RespondWithError(w, http.StatusNotFound, models.Error{Message: "Registration not found"})
@@ -702,12 +822,31 @@ func (ec *EventsRegistrationController) DeleteMyEventRegistration(db *sql.DB) ht
 			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Error checking registration"})
 			return
 		}
+		// Строку мероприятия блокируем раньше заявки — в том же порядке,
+		// что и eventQueue.promote, иначе параллельные отмены взаимоблокируются
+		if _, _, err := eventQueue.lock(tx, eventID); err != nil {
+			log.Printf("Error locking event %d: %v", eventID, err)
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Error checking event"})
+			return
+		}
+
 		if regStudentID != userID {
 			log.Printf("Student %d attempted to delete registration %d belonging to student %d", userID, regID, regStudentID)
 			utils.RespondWithError(w, http.StatusForbidden, models.Error{Message: "You can only delete your own registration"})
 			return
 		}
 
+		window, err := loadEventWindow(tx, eventID)
+		if err != nil {
+			log.Printf("Error fetching registration window for event %d: %v", eventID, err)
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Error checking registration"})
+			return
+		}
+		if !window.canCancel(time.Now()) {
+			utils.RespondWithError(w, http.StatusForbidden, models.Error{Message: "Cancellation deadline has passed"})
+			return
+		}
+
 		res, err := tx.Exec("DELETE FROM EventRegistrations WHERE event_registration_id = ?", regID)
 		if err != nil {
 			log.Printf("Error deleting registration %d: %v", regID, err)
@@ -722,11 +861,26 @@ func (ec *EventsRegistrationController) DeleteMyEventRegistration(db *sql.DB) ht
 			return
 		}
 
+		promotions, err := eventQueue.promote(tx, eventID)
+		if err != nil {
+			log.Printf("Error promoting waitlist for event %d: %v", eventID, err)
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Failed to update waitlist"})
+			return
+		}
+		sessionPromotions, err := releaseSessionSeats(tx, eventID)
+		if err != nil {
+			log.Printf("Error releasing session seats for event %d: %v", eventID, err)
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Failed to update waitlist"})
+			return
+		}
+		promotions = append(promotions, sessionPromotions...)
+
 		if err := tx.Commit(); err != nil {
 			log.Printf("Error committing transaction for registration %d: %v", regID, err)
 			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Failed to delete registration"})
 			return
 		}
+		emailWaitlistPromotions(db, promotions)
 
 		utils.ResponseJSON(w, map[string]string{"message": "Registration deleted successfully"})
 	}
@@ -741,8 +895,7 @@ func (ec *EventsRegistrationController) ApproveOrCancelEventRegistration(db *sql
 		}
 
 		var role string
-		var userSchoolID sql.NullInt64
-		err = db.QueryRow("SELECT role, school_id FROM users WHERE id = ?", userID).Scan(&role, &userSchoolID)
+		err = db.QueryRow("SELECT role FROM users WHERE id = ?", userID).Scan(&role)
 		if err != nil || (role != "schooladmin" && role != "superadmin") {
 			log.Printf("Access denied for user %d: invalid role or error %v", userID, err)
 			utils.RespondWithError(w, http.StatusForbidden, models.Error{Message: "Access denied"})
@@ -804,18 +957,40 @@ func (ec *EventsRegistrationController) ApproveOrCancelEventRegistration(db *sql
 		}
 
 		if role == "schooladmin" {
-			if !userSchoolID.Valid {
-				log.Printf("No school assigned to schooladmin %d", userID)
-				utils.RespondWithError(w, http.StatusForbidden, models.Error{Message: "No school assigned"})
+			allowed, err := hasSchoolPermission(tx, userID, eventSchoolID, PermissionManageEvents)
+			if err != nil {
+				log.Printf("Error checking membership for schooladmin %d: %v", userID, err)
+				utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Error checking registration"})
 				return
 			}
-			if eventSchoolID != int(userSchoolID.Int64) {
-				log.Printf("School mismatch for schooladmin %d, event %d: user school %v, event school %d", userID, eventID, userSchoolID, eventSchoolID)
+			if !allowed {
+				log.Printf("School mismatch for schooladmin %d, event %d: event school %d", userID, eventID, eventSchoolID)
 				utils.RespondWithError(w, http.StatusForbidden, models.Error{Message: "You can only approve/cancel registrations for your school's events"})
 				return
 			}
 		}
 
+		// Строку мероприятия блокируем раньше заявки — в том же порядке,
+		// что и eventQueue.promote
+		limitCount, taken, err := eventQueue.lock(tx, eventID)
+		if err != nil {
+			log.Printf("Error locking event %d: %v", eventID, err)
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Error checking event"})
+			return
+		}
+
+		var currentStatus string
+		err = tx.QueryRow("SELECT status FROM EventRegistrations WHERE event_registration_id = ? FOR UPDATE", regID).Scan(&currentStatus)
+		if err != nil {
+			log.Printf("Error fetching status for registration %d: %v", regID, err)
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Error checking registration"})
+			return
+		}
+		if body.Status == "accepted" && currentStatus == "waitlisted" && !hasFreeSeat(limitCount, taken) {
+			utils.RespondWithError(w, http.StatusConflict, models.Error{Message: "Event is full, the registration stays on the waitlist"})
+			return
+		}
+
 		_, err = tx.Exec("UPDATE EventRegistrations SET status = ? WHERE event_registration_id = ?", body.Status, regID)
 		if err != nil {
 			log.Printf("Error updating status for registration %d: %v", regID, err)
@@ -823,6 +998,20 @@ func (ec *EventsRegistrationController) ApproveOrCancelEventRegistration(db *sql
 			return
 		}
 
+		promotions, err := eventQueue.promote(tx, eventID)
+		if err != nil {
+			log.Printf("Error promoting waitlist for event %d: %v", eventID, err)
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Failed to update waitlist"})
+			return
+		}
+		sessionPromotions, err := releaseSessionSeats(tx, eventID)
+		if err != nil {
+			log.Printf("Error releasing session seats for event %d: %v", eventID, err)
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Failed to update waitlist"})
+			return
+		}
+		promotions = append(promotions, sessionPromotions...)
+
 		var registration models.EventRegistration
 		var regDateStr string
 		var eventEnd sql.NullString
@@ -867,6 +1056,7 @@ func (ec *EventsRegistrationController) ApproveOrCancelEventRegistration(db *sql
 			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Failed to update registration"})
 			return
 		}
+		emailWaitlistPromotions(db, promotions)
 
 		registration.RegistrationDate, _ = time.Parse("2006-01-02 15:04:05", regDateStr)
 		if status.Valid {
@@ -951,8 +1141,7 @@ func (ec *EventsRegistrationController) GetParticipantsBySchoolID(db *sql.DB) ht
 
 		// Check user role
 		var role string
-		var userSchoolID sql.NullInt64
-		err = db.QueryRow("SELECT role, school_id FROM users WHERE id = ?", userID).Scan(&role, &userSchoolID)
+		err = db.QueryRow("SELECT role FROM users WHERE id = ?", userID).Scan(&role)
 		if err != nil || (role != "schooladmin" && role != "superadmin") {
 			log.Printf("Access denied for user %d: invalid role or error %v", userID, err)
 			utils.RespondWithError(w, http.StatusForbidden, models.Error{Message: "Access denied"})
@@ -971,8 +1160,14 @@ func (ec *EventsRegistrationController) GetParticipantsBySchoolID(db *sql.DB) ht
 
 		// For schooladmin, ensure they can only access their own school's data
 		if role == "schooladmin" {
-			if !userSchoolID.Valid || int(userSchoolID.Int64) != schoolID {
-				log.Printf("School mismatch for user %d: user school %v, requested school %d", userID, userSchoolID, schoolID)
+			isMember, err := hasSchoolPermission(db, userID, schoolID, "")
+			if err != nil {
+				log.Printf("Error checking membership for user %d: %v", userID, err)
+				utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Database error"})
+				return
+			}
+			if !isMember {
+				log.Printf("School mismatch for user %d: requested school %d", userID, schoolID)
 				utils.RespondWithError(w, http.StatusForbidden, models.Error{Message: "You can only access your school's participants"})
 				return
 			}
@@ -0,0 +1,941 @@
+package controllers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"ranking-school/models"
+	"ranking-school/utils"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// Сессии многодневных мероприятий. Сессии без requires_registration
+// посещают все участники мероприятия; на сессии с записью ученик,
+// зарегистрированный на мероприятие, записывается отдельно. Лимит мест
+// и лист ожидания сессий работают через sessionQueue.
+type EventSessionController struct{}
+
+type rowsQuerier interface {
+	rowQuerier
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+}
+
+const sessionSelect = `
+	SELECT s.id, s.event_id, s.title, COALESCE(s.description, ''),
+	       CAST(s.start_time AS CHAR), CAST(s.end_time AS CHAR),
+	       COALESCE(s.room, ''), COALESCE(s.location, e.location, ''), s.speakers,
+	       s.capacity, s.requires_registration, s.participants
+	FROM event_sessions s
+	JOIN Events e ON e.id = s.event_id`
+
+func scanEventSession(scan func(dest ...interface{}) error) (models.EventSession, error) {
+	var s models.EventSession
+	var speakers sql.NullString
+	var capacity sql.NullInt64
+	err := scan(&s.ID, &s.EventID, &s.Title, &s.Description, &s.StartTime, &s.EndTime,
+		&s.Room, &s.Location, &speakers, &capacity, &s.RequiresRegistration, &s.Participants)
+	if err != nil {
+		return s, err
+	}
+	s.Speakers = []string{}
+	if speakers.Valid && speakers.String != "" {
+		if err := json.Unmarshal([]byte(speakers.String), &s.Speakers); err != nil {
+			return s, err
+		}
+	}
+	if capacity.Valid {
+		c := int(capacity.Int64)
+		left := c - s.Participants
+		if left < 0 {
+			left = 0
+		}
+		s.Capacity = &c
+		s.SeatsLeft = &left
+	}
+	return s, nil
+}
+
+func loadEventSession(db rowQuerier, sessionID int) (models.EventSession, error) {
+	return scanEventSession(db.QueryRow(sessionSelect+" WHERE s.id = ?", sessionID).Scan)
+}
+
+// eventSpan — время проведения мероприятия. Дата без времени в end_date
+// означает конец этого дня.
+func eventSpan(startDate, endDate string) (time.Time, time.Time, bool) {
+	start, err := parseWindowTime(startDate)
+	if err != nil {
+		return time.Time{}, time.Time{}, false
+	}
+	end, err := parseWindowTime(endDate)
+	if err != nil {
+		end = start
+	}
+	if end.Hour() == 0 && end.Minute() == 0 && end.Second() == 0 {
+		end = end.Add(24 * time.Hour)
+	}
+	return start, end, true
+}
+
+type sessionRequest struct {
+	Title                string   `json:"title"`
+	Description          string   `json:"description"`
+	StartTime            string   `json:"start_time"`
+	EndTime              string   `json:"end_time"`
+	Room                 string   `json:"room"`
+	Location             string   `json:"location"`
+	Speakers             []string `json:"speakers"`
+	Capacity             *int     `json:"capacity"`
+	RequiresRegistration bool     `json:"requires_registration"`
+}
+
+// validate проверяет сессию и возвращает время начала/окончания в формате БД.
+// Сессия должна укладываться в даты мероприятия.
+func (req *sessionRequest) validate(eventStart, eventEnd string) (string, string, error) {
+	req.Title = strings.TrimSpace(req.Title)
+	if req.Title == "" || len(req.Title) > 255 {
+		return "", "", fmt.Errorf("title is required and must be at most 255 characters")
+	}
+	start, err := parseWindowTime(req.StartTime)
+	if err != nil {
+		return "", "", fmt.Errorf("invalid start_time format, use YYYY-MM-DD HH:MM:SS")
+	}
+	end, err := parseWindowTime(req.EndTime)
+	if err != nil {
+		return "", "", fmt.Errorf("invalid end_time format, use YYYY-MM-DD HH:MM:SS")
+	}
+	if !end.After(start) {
+		return "", "", fmt.Errorf("end_time must be after start_time")
+	}
+	if spanStart, spanEnd, ok := eventSpan(eventStart, eventEnd); ok {
+		if start.Before(spanStart) || end.After(spanEnd) {
+			return "", "", fmt.Errorf("session must be within the event dates (%s – %s)", eventStart, eventEnd)
+		}
+	}
+	if req.Capacity != nil && *req.Capacity <= 0 {
+		return "", "", fmt.Errorf("capacity must be positive or null")
+	}
+	if req.Capacity != nil && !req.RequiresRegistration {
+		return "", "", fmt.Errorf("capacity requires requires_registration = true")
+	}
+	speakers := make([]string, 0, len(req.Speakers))
+	for _, speaker := range req.Speakers {
+		if speaker = strings.TrimSpace(speaker); speaker != "" {
+			speakers = append(speakers, speaker)
+		}
+	}
+	req.Speakers = speakers
+	return start.Format("2006-01-02 15:04:05"), end.Format("2006-01-02 15:04:05"), nil
+}
+
+func sessionIDFromPath(w http.ResponseWriter, r *http.Request) (int, bool) {
+	sessionID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil || sessionID <= 0 {
+		utils.RespondWithError(w, http.StatusBadRequest, models.Error{Message: "Invalid session ID"})
+		return 0, false
+	}
+	return sessionID, true
+}
+
+// requireSessionOrganizer загружает сессию и проверяет права на её мероприятие.
+func requireSessionOrganizer(w http.ResponseWriter, db *sql.DB, sessionID, userID int) (models.EventSession, bool) {
+	session, err := loadEventSession(db, sessionID)
+	if err == sql.ErrNoRows {
+		utils.RespondWithError(w, http.StatusNotFound, models.Error{Message: "Session not found"})
+		return session, false
+	} else if err != nil {
+		log.Printf("Error fetching session %d: %v", sessionID, err)
+		utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Error fetching session"})
+		return session, false
+	}
+	_, ok := requireEventOrganizer(w, db, session.EventID, userID)
+	return session, ok
+}
+
+func decodeSessionRequest(w http.ResponseWriter, r *http.Request, db *sql.DB, eventID int) (sessionRequest, string, string, bool) {
+	var req sessionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, models.Error{Message: "Invalid request body"})
+		return req, "", "", false
+	}
+	var eventStart, eventEnd string
+	err := db.QueryRow("SELECT COALESCE(CAST(start_date AS CHAR), ''), COALESCE(CAST(end_date AS CHAR), '') FROM Events WHERE id = ?", eventID).Scan(&eventStart, &eventEnd)
+	if err != nil {
+		utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Error fetching event"})
+		return req, "", "", false
+	}
+	start, end, err := req.validate(eventStart, eventEnd)
+	if err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, models.Error{Message: err.Error()})
+		return req, "", "", false
+	}
+	return req, start, end, true
+}
+
+// CreateEventSession добавляет сессию в расписание мероприятия.
+func (sc *EventSessionController) CreateEventSession(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := utils.VerifyToken(r)
+		if err != nil {
+			utils.RespondWithError(w, http.StatusUnauthorized, models.Error{Message: "Unauthorized"})
+			return
+		}
+		eventID, ok := eventIDFromPath(w, r)
+		if !ok {
+			return
+		}
+		if _, ok := requireEventOrganizer(w, db, eventID, userID); !ok {
+			return
+		}
+		req, start, end, ok := decodeSessionRequest(w, r, db, eventID)
+		if !ok {
+			return
+		}
+
+		speakers, _ := json.Marshal(req.Speakers)
+		res, err := db.Exec(`
+			INSERT INTO event_sessions (event_id, title, description, start_time, end_time, room, location, speakers, capacity, requires_registration)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			eventID, req.Title, toNullString(req.Description), start, end,
+			toNullString(req.Room), toNullString(req.Location), string(speakers), req.Capacity, req.RequiresRegistration)
+		if err != nil {
+			log.Printf("Error creating session for event %d: %v", eventID, err)
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Failed to create session"})
+			return
+		}
+		sessionID, _ := res.LastInsertId()
+
+		session, err := loadEventSession(db, int(sessionID))
+		if err != nil {
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Session created, but failed to load it"})
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		utils.ResponseJSON(w, session)
+	}
+}
+
+// UpdateEventSession заменяет поля сессии. При увеличении лимита места
+// отдаются листу ожидания. Если запись на сессию отключается, записи
+// учеников отменяются.
+func (sc *EventSessionController) UpdateEventSession(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := utils.VerifyToken(r)
+		if err != nil {
+			utils.RespondWithError(w, http.StatusUnauthorized, models.Error{Message: "Unauthorized"})
+			return
+		}
+		sessionID, ok := sessionIDFromPath(w, r)
+		if !ok {
+			return
+		}
+		current, ok := requireSessionOrganizer(w, db, sessionID, userID)
+		if !ok {
+			return
+		}
+		req, start, end, ok := decodeSessionRequest(w, r, db, current.EventID)
+		if !ok {
+			return
+		}
+
+		tx, err := db.Begin()
+		if err != nil {
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Database error"})
+			return
+		}
+		defer tx.Rollback()
+
+		speakers, _ := json.Marshal(req.Speakers)
+		_, err = tx.Exec(`
+			UPDATE event_sessions
+			SET title = ?, description = ?, start_time = ?, end_time = ?, room = ?, location = ?,
+			    speakers = ?, capacity = ?, requires_registration = ?
+			WHERE id = ?`,
+			req.Title, toNullString(req.Description), start, end, toNullString(req.Room), toNullString(req.Location),
+			string(speakers), req.Capacity, req.RequiresRegistration, sessionID)
+		if err != nil {
+			log.Printf("Error updating session %d: %v", sessionID, err)
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Failed to update session"})
+			return
+		}
+
+		if !req.RequiresRegistration {
+			if _, err := tx.Exec("UPDATE event_session_registrations SET status = 'canceled' WHERE session_id = ? AND status <> 'canceled'", sessionID); err != nil {
+				utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Failed to update session"})
+				return
+			}
+		}
+		promotions, err := sessionQueue.promote(tx, sessionID)
+		if err != nil {
+			log.Printf("Error promoting session %d waitlist: %v", sessionID, err)
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Failed to update waitlist"})
+			return
+		}
+		if err := tx.Commit(); err != nil {
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Failed to update session"})
+			return
+		}
+		emailWaitlistPromotions(db, promotions)
+
+		session, err := loadEventSession(db, sessionID)
+		if err != nil {
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Session updated, but failed to load it"})
+			return
+		}
+		utils.ResponseJSON(w, session)
+	}
+}
+
+// DeleteEventSession удаляет сессию и уведомляет записанных учеников.
+func (sc *EventSessionController) DeleteEventSession(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := utils.VerifyToken(r)
+		if err != nil {
+			utils.RespondWithError(w, http.StatusUnauthorized, models.Error{Message: "Unauthorized"})
+			return
+		}
+		sessionID, ok := sessionIDFromPath(w, r)
+		if !ok {
+			return
+		}
+		session, ok := requireSessionOrganizer(w, db, sessionID, userID)
+		if !ok {
+			return
+		}
+
+		tx, err := db.Begin()
+		if err != nil {
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Database error"})
+			return
+		}
+		defer tx.Rollback()
+
+		rows, err := tx.Query("SELECT student_id FROM event_session_registrations WHERE session_id = ? AND status IN ('registered', 'waitlisted')", sessionID)
+		if err != nil {
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Failed to delete session"})
+			return
+		}
+		var studentIDs []int
+		for rows.Next() {
+			var id int
+			if err := rows.Scan(&id); err == nil {
+				studentIDs = append(studentIDs, id)
+			}
+		}
+		rows.Close()
+
+		message := fmt.Sprintf("Сессия «%s» (%s) отменена организатором", session.Title, session.StartTime)
+		for _, studentID := range studentIDs {
+			if err := createNotification(tx, studentID, "session_canceled", message); err != nil {
+				utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Failed to delete session"})
+				return
+			}
+		}
+		if _, err := tx.Exec("DELETE FROM event_sessions WHERE id = ?", sessionID); err != nil {
+			log.Printf("Error deleting session %d: %v", sessionID, err)
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Failed to delete session"})
+			return
+		}
+		if err := tx.Commit(); err != nil {
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Failed to delete session"})
+			return
+		}
+		utils.ResponseJSON(w, map[string]interface{}{"message": "Session deleted", "notified_students": len(studentIDs)})
+	}
+}
+
+// GetEventSchedule — расписание мероприятия по дням. Для ученика
+// дополнительно указывается статус его записи на каждую сессию.
+func (sc *EventSessionController) GetEventSchedule(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := utils.VerifyToken(r)
+		if err != nil {
+			utils.RespondWithError(w, http.StatusUnauthorized, models.Error{Message: "Unauthorized"})
+			return
+		}
+		eventID, ok := eventIDFromPath(w, r)
+		if !ok {
+			return
+		}
+
+		schedule := models.EventSchedule{EventID: eventID, Days: []models.ScheduleDay{}}
+		err = db.QueryRow(`
+			SELECT COALESCE(event_name, ''), COALESCE(CAST(start_date AS CHAR), ''), COALESCE(CAST(end_date AS CHAR), ''), COALESCE(location, '')
+			FROM Events WHERE id = ?`, eventID).Scan(&schedule.EventName, &schedule.StartDate, &schedule.EndDate, &schedule.Location)
+		if err == sql.ErrNoRows {
+			utils.RespondWithError(w, http.StatusNotFound, models.Error{Message: "Event not found"})
+			return
+		} else if err != nil {
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Error fetching event"})
+			return
+		}
+
+		rows, err := db.Query(sessionSelect+" WHERE s.event_id = ? ORDER BY s.start_time, s.id", eventID)
+		if err != nil {
+			log.Printf("Error fetching sessions for event %d: %v", eventID, err)
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Failed to fetch schedule"})
+			return
+		}
+		var sessions []models.EventSession
+		for rows.Next() {
+			session, err := scanEventSession(rows.Scan)
+			if err != nil {
+				rows.Close()
+				log.Printf("Error scanning session: %v", err)
+				utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Failed to fetch schedule"})
+				return
+			}
+			sessions = append(sessions, session)
+		}
+		rows.Close()
+
+		for i := range sessions {
+			s := &sessions[i]
+			var regID int
+			err := db.QueryRow("SELECT id, status FROM event_session_registrations WHERE session_id = ? AND student_id = ? AND status <> 'canceled'", s.ID, userID).
+				Scan(&regID, &s.RegistrationStatus)
+			if err == nil && s.RegistrationStatus == "waitlisted" {
+				s.WaitlistPosition, _ = sessionQueue.waitlistPosition(db, s.ID, regID)
+			}
+
+			day := firstN(s.StartTime, 10)
+			if n := len(schedule.Days); n == 0 || schedule.Days[n-1].Date != day {
+				schedule.Days = append(schedule.Days, models.ScheduleDay{Date: day})
+			}
+			last := &schedule.Days[len(schedule.Days)-1]
+			last.Sessions = append(last.Sessions, *s)
+		}
+
+		utils.ResponseJSON(w, schedule)
+	}
+}
+
+// GetSessionRegistrations — записи на сессию для организатора.
+func (sc *EventSessionController) GetSessionRegistrations(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := utils.VerifyToken(r)
+		if err != nil {
+			utils.RespondWithError(w, http.StatusUnauthorized, models.Error{Message: "Unauthorized"})
+			return
+		}
+		sessionID, ok := sessionIDFromPath(w, r)
+		if !ok {
+			return
+		}
+		if _, ok := requireSessionOrganizer(w, db, sessionID, userID); !ok {
+			return
+		}
+
+		rows, err := db.Query(`
+			SELECT sr.id, sr.session_id, sr.event_registration_id, sr.student_id,
+			       COALESCE(s.first_name, ''), COALESCE(s.last_name, ''), COALESCE(s.grade, 0), COALESCE(s.letter, ''),
+			       sr.status, CAST(sr.registration_date AS CHAR)
+			FROM event_session_registrations sr
+			LEFT JOIN student s ON s.student_id = sr.student_id
+			WHERE sr.session_id = ? AND sr.status <> 'canceled'
+			ORDER BY sr.status = 'waitlisted', sr.registration_date, sr.id`, sessionID)
+		if err != nil {
+			log.Printf("Error fetching registrations for session %d: %v", sessionID, err)
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Failed to fetch registrations"})
+			return
+		}
+		defer rows.Close()
+
+		registrations := []models.EventSessionRegistration{}
+		position := 0
+		for rows.Next() {
+			var reg models.EventSessionRegistration
+			if err := rows.Scan(&reg.ID, &reg.SessionID, &reg.EventRegistrationID, &reg.StudentID,
+				&reg.StudentFirstName, &reg.StudentLastName, &reg.StudentGrade, &reg.StudentLetter,
+				&reg.Status, &reg.RegistrationDate); err != nil {
+				utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Failed to fetch registrations"})
+				return
+			}
+			if reg.Status == "waitlisted" {
+				position++
+				reg.WaitlistPosition = position
+			}
+			registrations = append(registrations, reg)
+		}
+		utils.ResponseJSON(w, registrations)
+	}
+}
+
+// RegisterForSession записывает ученика на сессию. Нужна действующая
+// регистрация на само мероприятие; при пересечении с другими сессиями и
+// мероприятиями ученика возвращается 409 (allow_conflicts = true — записать
+// всё равно).
+func (sc *EventSessionController) RegisterForSession(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := utils.VerifyToken(r)
+		if err != nil {
+			utils.RespondWithError(w, http.StatusUnauthorized, models.Error{Message: "Unauthorized"})
+			return
+		}
+		sessionID, ok := sessionIDFromPath(w, r)
+		if !ok {
+			return
+		}
+
+		var body struct {
+			AllowConflicts bool `json:"allow_conflicts"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil && err != io.EOF {
+			utils.RespondWithError(w, http.StatusBadRequest, models.Error{Message: "Invalid body"})
+			return
+		}
+
+		tx, err := db.Begin()
+		if err != nil {
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Database error"})
+			return
+		}
+		defer tx.Rollback()
+
+		limit, taken, err := sessionQueue.lock(tx, sessionID)
+		if err == sql.ErrNoRows {
+			utils.RespondWithError(w, http.StatusNotFound, models.Error{Message: "Session not found"})
+			return
+		} else if err != nil {
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Error checking session"})
+			return
+		}
+		session, err := loadEventSession(tx, sessionID)
+		if err != nil {
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Error checking session"})
+			return
+		}
+		if !session.RequiresRegistration {
+			utils.RespondWithError(w, http.StatusBadRequest, models.Error{Message: "This session is open to all event participants, no registration needed"})
+			return
+		}
+		if start, err := parseWindowTime(session.StartTime); err == nil && !time.Now().Before(start) {
+			utils.RespondWithError(w, http.StatusBadRequest, models.Error{Message: "Session has already started"})
+			return
+		}
+
+		var eventRegistrationID int
+		err = tx.QueryRow("SELECT event_registration_id FROM EventRegistrations WHERE event_id = ? AND student_id = ? AND status IN ("+eventQueue.seatStatuses+") LIMIT 1",
+			session.EventID, userID).Scan(&eventRegistrationID)
+		if err == sql.ErrNoRows {
+			utils.RespondWithError(w, http.StatusForbidden, models.Error{Message: "Register for the event first"})
+			return
+		} else if err != nil {
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Database error"})
+			return
+		}
+
+		var existing string
+		err = tx.QueryRow("SELECT status FROM event_session_registrations WHERE session_id = ? AND student_id = ?", sessionID, userID).Scan(&existing)
+		if err != nil && err != sql.ErrNoRows {
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Database error"})
+			return
+		}
+		if existing == "registered" || existing == "waitlisted" {
+			utils.RespondWithError(w, http.StatusBadRequest, models.Error{Message: "Already registered for this session"})
+			return
+		}
+
+		var conflicts []string
+		if start, end, err := sessionTimes(session); err == nil {
+			conflicts, err = findScheduleConflicts(tx, userID, []scheduleInterval{{
+				Kind: "session", EventID: session.EventID, SessionID: session.ID, Title: session.Title, Start: start, End: end,
+			}})
+			if err != nil {
+				log.Printf("Error checking schedule conflicts for student %d: %v", userID, err)
+				utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Error checking schedule conflicts"})
+				return
+			}
+		}
+		if len(conflicts) > 0 && !body.AllowConflicts {
+			utils.RespondWithError(w, http.StatusConflict, models.Error{Message: "Schedule conflict with: " + strings.Join(conflicts, "; ")})
+			return
+		}
+
+		status := "registered"
+		if !hasFreeSeat(limit, taken) {
+			status = "waitlisted"
+		}
+		now := time.Now().Format("2006-01-02 15:04:05")
+		res, err := tx.Exec(`
+			INSERT INTO event_session_registrations (session_id, event_registration_id, student_id, status, registration_date)
+			VALUES (?, ?, ?, ?, ?)
+			ON DUPLICATE KEY UPDATE id = LAST_INSERT_ID(id), event_registration_id = VALUES(event_registration_id),
+			    status = VALUES(status), registration_date = VALUES(registration_date)`,
+			sessionID, eventRegistrationID, userID, status, now)
+		if err != nil {
+			log.Printf("Error registering student %d for session %d: %v", userID, sessionID, err)
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Failed to register for session"})
+			return
+		}
+		regID, _ := res.LastInsertId()
+		if status == "registered" {
+			taken++
+		}
+		if _, err := tx.Exec("UPDATE event_sessions SET participants = ? WHERE id = ?", taken, sessionID); err != nil {
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Failed to register for session"})
+			return
+		}
+
+		reg := models.EventSessionRegistration{
+			ID:                  int(regID),
+			SessionID:           sessionID,
+			EventRegistrationID: eventRegistrationID,
+			StudentID:           userID,
+			Status:              status,
+			RegistrationDate:    now,
+		}
+		if status == "waitlisted" {
+			reg.WaitlistPosition, _ = sessionQueue.waitlistPosition(tx, sessionID, reg.ID)
+		}
+		if err := tx.Commit(); err != nil {
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Failed to register for session"})
+			return
+		}
+
+		utils.ResponseJSON(w, map[string]interface{}{
+			"registration": reg,
+			"conflicts":    conflicts,
+		})
+	}
+}
+
+// CancelSessionRegistration отменяет запись ученика на сессию.
+func (sc *EventSessionController) CancelSessionRegistration(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := utils.VerifyToken(r)
+		if err != nil {
+			utils.RespondWithError(w, http.StatusUnauthorized, models.Error{Message: "Unauthorized"})
+			return
+		}
+		sessionID, ok := sessionIDFromPath(w, r)
+		if !ok {
+			return
+		}
+
+		tx, err := db.Begin()
+		if err != nil {
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Database error"})
+			return
+		}
+		defer tx.Rollback()
+
+		if _, _, err := sessionQueue.lock(tx, sessionID); err == sql.ErrNoRows {
+			utils.RespondWithError(w, http.StatusNotFound, models.Error{Message: "Session not found"})
+			return
+		} else if err != nil {
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Database error"})
+			return
+		}
+
+		res, err := tx.Exec("UPDATE event_session_registrations SET status = 'canceled' WHERE session_id = ? AND student_id = ? AND status IN ('registered', 'waitlisted')", sessionID, userID)
+		if err != nil {
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Failed to cancel session registration"})
+			return
+		}
+		if affected, _ := res.RowsAffected(); affected == 0 {
+			utils.RespondWithError(w, http.StatusNotFound, models.Error{Message: "You are not registered for this session"})
+			return
+		}
+
+		promotions, err := sessionQueue.promote(tx, sessionID)
+		if err != nil {
+			log.Printf("Error promoting session %d waitlist: %v", sessionID, err)
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Failed to update waitlist"})
+			return
+		}
+		if err := tx.Commit(); err != nil {
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Failed to cancel session registration"})
+			return
+		}
+		emailWaitlistPromotions(db, promotions)
+
+		utils.ResponseJSON(w, map[string]string{"message": "Session registration canceled"})
+	}
+}
+
+// releaseSessionSeats отменяет записи на сессии мероприятия, у которых
+// больше нет действующей регистрации на само мероприятие, и отдаёт
+// освободившиеся места листам ожидания. Вызывается вместе с
+// eventQueue.promote при отмене или удалении регистрации.
+func releaseSessionSeats(tx *sql.Tx, eventID int) ([]waitlistPromotion, error) {
+	// Строки сессий блокируются раньше записей на них, как в sessionQueue.lock
+	rows, err := tx.Query("SELECT id, requires_registration FROM event_sessions WHERE event_id = ? ORDER BY id FOR UPDATE", eventID)
+	if err != nil {
+		return nil, err
+	}
+	var sessionIDs []int
+	for rows.Next() {
+		var id int
+		var requiresRegistration bool
+		if err := rows.Scan(&id, &requiresRegistration); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		if requiresRegistration {
+			sessionIDs = append(sessionIDs, id)
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	_, err = tx.Exec(`
+		UPDATE event_session_registrations sr
+		JOIN event_sessions s ON s.id = sr.session_id
+		JOIN EventRegistrations r ON r.event_registration_id = sr.event_registration_id
+		SET sr.status = 'canceled'
+		WHERE s.event_id = ? AND sr.status IN ('registered', 'waitlisted')
+		  AND r.status NOT IN (`+eventQueue.seatStatuses+`)`, eventID)
+	if err != nil {
+		return nil, err
+	}
+
+	var promotions []waitlistPromotion
+	for _, sessionID := range sessionIDs {
+		p, err := sessionQueue.promote(tx, sessionID)
+		if err != nil {
+			return nil, err
+		}
+		promotions = append(promotions, p...)
+	}
+	return promotions, nil
+}
+
+// scheduleInterval — занятый промежуток времени ученика.
+type scheduleInterval struct {
+	Kind      string // event / session
+	EventID   int
+	EventName string
+	SessionID int
+	Title     string
+	Location  string
+	Room      string
+	Start     time.Time
+	End       time.Time
+}
+
+func (si scheduleInterval) describe() string {
+	return fmt.Sprintf("«%s» (%s – %s)", si.Title, si.Start.Format("2006-01-02 15:04"), si.End.Format("2006-01-02 15:04"))
+}
+
+func (si scheduleInterval) overlaps(other scheduleInterval) bool {
+	return si.Start.Before(other.End) && other.Start.Before(si.End)
+}
+
+func sessionTimes(s models.EventSession) (time.Time, time.Time, error) {
+	start, err := parseWindowTime(s.StartTime)
+	if err != nil {
+		return start, start, err
+	}
+	end, err := parseWindowTime(s.EndTime)
+	return start, end, err
+}
+
+// studentScheduleIntervals — расписание ученика: мероприятия без сессий
+// целиком, а у мероприятий с сессиями — общие сессии и сессии, на которые
+// он записан. Учитываются только регистрации с местом.
+func studentScheduleIntervals(db rowsQuerier, studentID int) ([]scheduleInterval, error) {
+	var intervals []scheduleInterval
+
+	rows, err := db.Query(`
+		SELECT e.id, COALESCE(e.event_name, ''), COALESCE(CAST(e.start_date AS CHAR), ''), COALESCE(CAST(e.end_date AS CHAR), ''),
+		       COALESCE(e.location, '')
+		FROM EventRegistrations r
+		JOIN Events e ON e.id = r.event_id
+		WHERE r.student_id = ? AND r.status IN (`+eventQueue.seatStatuses+`)
+		  AND NOT EXISTS (SELECT 1 FROM event_sessions s WHERE s.event_id = e.id)`, studentID)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var si scheduleInterval
+		var startDate, endDate string
+		if err := rows.Scan(&si.EventID, &si.EventName, &startDate, &endDate, &si.Location); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		start, end, ok := eventSpan(startDate, endDate)
+		if !ok {
+			continue
+		}
+		si.Kind, si.Title, si.Start, si.End = "event", si.EventName, start, end
+		intervals = append(intervals, si)
+	}
+	rows.Close()
+
+	rows, err = db.Query(`
+		SELECT s.id, s.event_id, COALESCE(e.event_name, ''), s.title,
+		       CAST(s.start_time AS CHAR), CAST(s.end_time AS CHAR),
+		       COALESCE(s.location, e.location, ''), COALESCE(s.room, '')
+		FROM event_sessions s
+		JOIN Events e ON e.id = s.event_id
+		JOIN EventRegistrations r ON r.event_id = s.event_id AND r.student_id = ? AND r.status IN (`+eventQueue.seatStatuses+`)
+		LEFT JOIN event_session_registrations sr ON sr.session_id = s.id AND sr.student_id = r.student_id
+		WHERE s.requires_registration = 0 OR sr.status = 'registered'`, studentID)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var si scheduleInterval
+		var start, end string
+		if err := rows.Scan(&si.SessionID, &si.EventID, &si.EventName, &si.Title, &start, &end, &si.Location, &si.Room); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		if si.Start, err = parseWindowTime(start); err != nil {
+			continue
+		}
+		if si.End, err = parseWindowTime(end); err != nil {
+			continue
+		}
+		si.Kind = "session"
+		intervals = append(intervals, si)
+	}
+	rows.Close()
+
+	sort.Slice(intervals, func(i, j int) bool { return intervals[i].Start.Before(intervals[j].Start) })
+	return intervals, nil
+}
+
+// eventPlannedIntervals — время, которое займёт регистрация на мероприятие:
+// общие сессии, а если сессий нет — всё мероприятие.
+func eventPlannedIntervals(db rowsQuerier, eventID int) ([]scheduleInterval, error) {
+	var name, startDate, endDate string
+	err := db.QueryRow("SELECT COALESCE(event_name, ''), COALESCE(CAST(start_date AS CHAR), ''), COALESCE(CAST(end_date AS CHAR), '') FROM Events WHERE id = ?", eventID).
+		Scan(&name, &startDate, &endDate)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := db.Query(sessionSelect+" WHERE s.event_id = ?", eventID)
+	if err != nil {
+		return nil, err
+	}
+	var intervals []scheduleInterval
+	hasSessions := false
+	for rows.Next() {
+		session, err := scanEventSession(rows.Scan)
+		if err != nil {
+			rows.Close()
+			return nil, err
+		}
+		hasSessions = true
+		if session.RequiresRegistration {
+			continue
+		}
+		start, end, err := sessionTimes(session)
+		if err != nil {
+			continue
+		}
+		intervals = append(intervals, scheduleInterval{Kind: "session", EventID: eventID, EventName: name, SessionID: session.ID, Title: session.Title, Start: start, End: end})
+	}
+	rows.Close()
+
+	if !hasSessions {
+		if start, end, ok := eventSpan(startDate, endDate); ok {
+			intervals = append(intervals, scheduleInterval{Kind: "event", EventID: eventID, EventName: name, Title: name, Start: start, End: end})
+		}
+	}
+	return intervals, nil
+}
+
+// findScheduleConflicts сравнивает новые промежутки с расписанием ученика.
+// Сам новый пункт (та же сессия, то же мероприятие без сессий) не считается.
+func findScheduleConflicts(db rowsQuerier, studentID int, planned []scheduleInterval) ([]string, error) {
+	busy, err := studentScheduleIntervals(db, studentID)
+	if err != nil {
+		return nil, err
+	}
+	var conflicts []string
+	seen := make(map[string]bool)
+	for _, p := range planned {
+		for _, b := range busy {
+			if p.Kind == "session" && b.SessionID == p.SessionID {
+				continue
+			}
+			if p.Kind == "event" && b.Kind == "event" && b.EventID == p.EventID {
+				continue
+			}
+			if p.overlaps(b) {
+				desc := b.describe()
+				if !seen[desc] {
+					seen[desc] = true
+					conflicts = append(conflicts, desc)
+				}
+			}
+		}
+	}
+	return conflicts, nil
+}
+
+// eventScheduleConflicts — пересечения регистрации на мероприятие с
+// расписанием ученика.
+func eventScheduleConflicts(db rowsQuerier, eventID, studentID int) ([]string, error) {
+	planned, err := eventPlannedIntervals(db, eventID)
+	if err != nil {
+		return nil, err
+	}
+	return findScheduleConflicts(db, studentID, planned)
+}
+
+// GetMySchedule — личное расписание ученика с отметками о пересечениях.
+func (sc *EventSessionController) GetMySchedule(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := utils.VerifyToken(r)
+		if err != nil {
+			utils.RespondWithError(w, http.StatusUnauthorized, models.Error{Message: "Unauthorized"})
+			return
+		}
+
+		intervals, err := studentScheduleIntervals(db, userID)
+		if err != nil {
+			log.Printf("Error building schedule for student %d: %v", userID, err)
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Failed to fetch schedule"})
+			return
+		}
+
+		from := time.Now().Add(-24 * time.Hour)
+		if fromStr := r.URL.Query().Get("from"); fromStr != "" {
+			if from, err = parseWindowTime(fromStr); err != nil {
+				utils.RespondWithError(w, http.StatusBadRequest, models.Error{Message: "Invalid from date, use YYYY-MM-DD"})
+				return
+			}
+		}
+
+		items := []models.ScheduleItem{}
+		for i, si := range intervals {
+			if si.End.Before(from) {
+				continue
+			}
+			item := models.ScheduleItem{
+				Kind:      si.Kind,
+				EventID:   si.EventID,
+				EventName: si.EventName,
+				SessionID: si.SessionID,
+				Title:     si.Title,
+				StartTime: si.Start.Format("2006-01-02 15:04:05"),
+				EndTime:   si.End.Format("2006-01-02 15:04:05"),
+				Location:  si.Location,
+				Room:      si.Room,
+			}
+			for j, other := range intervals {
+				if i != j && si.overlaps(other) {
+					item.Conflicts = append(item.Conflicts, other.describe())
+				}
+			}
+			items = append(items, item)
+		}
+
+		utils.ResponseJSON(w, items)
+	}
+}
@@ -0,0 +1,79 @@
+package controllers
+
+import (
+	"testing"
+	"time"
+)
+
+func TestEventSpan(t *testing.T) {
+	at := func(s string) time.Time {
+		tm, err := parseWindowTime(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return tm
+	}
+
+	tests := []struct {
+		name               string
+		start, end         string
+		wantOK             bool
+		wantStart, wantEnd string
+	}{
+		{"date and time", "2026-03-20 09:00:00", "2026-03-20 17:30:00", true, "2026-03-20 09:00:00", "2026-03-20 17:30:00"},
+		{"end date covers the whole day", "2026-03-20 09:00:00", "2026-03-22", true, "2026-03-20 09:00:00", "2026-03-23 00:00:00"},
+		{"no end means the start day", "2026-03-20", "", true, "2026-03-20 00:00:00", "2026-03-21 00:00:00"},
+		{"invalid end falls back to start", "2026-03-20", "когда-нибудь", true, "2026-03-20 00:00:00", "2026-03-21 00:00:00"},
+		{"invalid start", "", "2026-03-20", false, "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start, end, ok := eventSpan(tt.start, tt.end)
+			if ok != tt.wantOK {
+				t.Fatalf("eventSpan() ok = %v, want %v", ok, tt.wantOK)
+			}
+			if !ok {
+				return
+			}
+			if !start.Equal(at(tt.wantStart)) || !end.Equal(at(tt.wantEnd)) {
+				t.Errorf("eventSpan() = %s – %s, want %s – %s", start, end, tt.wantStart, tt.wantEnd)
+			}
+		})
+	}
+}
+
+func TestScheduleIntervalOverlaps(t *testing.T) {
+	interval := func(start, end string) scheduleInterval {
+		s, _ := parseWindowTime(start)
+		e, _ := parseWindowTime(end)
+		return scheduleInterval{Start: s, End: e}
+	}
+	base := interval("2026-03-20 10:00", "2026-03-20 12:00")
+
+	tests := []struct {
+		name  string
+		other scheduleInterval
+		want  bool
+	}{
+		{"same interval", base, true},
+		{"starts inside", interval("2026-03-20 11:00", "2026-03-20 13:00"), true},
+		{"ends inside", interval("2026-03-20 09:00", "2026-03-20 10:30"), true},
+		{"contains", interval("2026-03-20 08:00", "2026-03-20 18:00"), true},
+		{"contained", interval("2026-03-20 10:30", "2026-03-20 11:00"), true},
+		{"ends when base starts", interval("2026-03-20 09:00", "2026-03-20 10:00"), false},
+		{"starts when base ends", interval("2026-03-20 12:00", "2026-03-20 13:00"), false},
+		{"another day", interval("2026-03-21 10:00", "2026-03-21 12:00"), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := base.overlaps(tt.other); got != tt.want {
+				t.Errorf("overlaps() = %v, want %v", got, tt.want)
+			}
+			if got := tt.other.overlaps(base); got != tt.want {
+				t.Errorf("overlaps() is not symmetric: %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -52,19 +52,34 @@ func (c *EventsParticipantController) AddEventsParticipant(db *sql.DB) http.Hand
 		// Step 5: Parse required fields
 		schoolIDStr := r.FormValue("school_id")
 		studentIDStr := r.FormValue("student_id")
-		eventsName := r.FormValue("events_name")
+		eventIDStr := r.FormValue("event_id")
+		eventsName := strings.TrimSpace(r.FormValue("events_name"))
 		category := r.FormValue("category")
 		role := r.FormValue("role")
 		dateStr := r.FormValue("date")
 
 		// Validate required fields
-		if schoolIDStr == "" || studentIDStr == "" || eventsName == "" || category == "" || role == "" || dateStr == "" {
+		if schoolIDStr == "" || studentIDStr == "" || (eventsName == "" && eventIDStr == "") || category == "" || role == "" || dateStr == "" {
 			utils.RespondWithError(w, http.StatusBadRequest, models.Error{
-				Message: "All fields (school_id, student_id, events_name, category, role, date) are required",
+				Message: "All fields (school_id, student_id, event_id or events_name, category, role, date) are required",
 			})
 			return
 		}
 
+		// Мероприятие определяется по event_id; название — только для
+		// внешних мероприятий или однозначного совпадения
+		event, badRequest, err := resolveParticipantEvent(db, eventIDStr, eventsName)
+		if err != nil {
+			log.Println("Error resolving event:", err)
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Database error while resolving event"})
+			return
+		}
+		if badRequest != "" {
+			utils.RespondWithError(w, http.StatusBadRequest, models.Error{Message: badRequest})
+			return
+		}
+		eventsName = event.Name
+
 		// Parse optional fields, allowing empty values (NULL in DB)
 		grade := sql.NullString{String: r.FormValue("grade"), Valid: r.FormValue("grade") != ""}
 		letter := sql.NullString{String: r.FormValue("letter"), Valid: r.FormValue("letter") != ""}
@@ -128,14 +143,15 @@ func (c *EventsParticipantController) AddEventsParticipant(db *sql.DB) http.Hand
 
 		// Step 8: Insert the event participant into the database
 		query := `INSERT INTO events_participants 
-            (school_id, grade, letter, student_id, events_name, document, category, role, date, creator_id) 
-            VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+            (school_id, grade, letter, student_id, event_id, events_name, document, category, role, date, creator_id) 
+            VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
 
 		result, err := db.Exec(query,
 			schoolID,
 			grade,
 			letter,
 			studentID,
+			event.ID,
 			eventsName,
 			documentURL,
 			category,
@@ -157,10 +173,17 @@ func (c *EventsParticipantController) AddEventsParticipant(db *sql.DB) http.Hand
 			return
 		}
 
+		// Участие засчитывается как посещение мероприятия
+		if event.ID.Valid {
+			if err := markParticipantAttended(db, int(event.ID.Int64), studentID, userID); err != nil {
+				log.Println("Error marking registration as attended:", err)
+			}
+		}
+
 		// Step 9: Retrieve complete participant data for response
 		var participant models.EventsParticipant
 
-		query = `SELECT ep.id, ep.school_id, ep.grade, ep.letter, ep.student_id, ep.events_name, 
+		query = `SELECT ep.id, ep.school_id, ep.grade, ep.letter, ep.student_id, COALESCE(ep.event_id, 0), ep.events_name, 
                 ep.document, ep.category, ep.role, ep.date, 
                 s.student_id, s.first_name as student_name, s.last_name as student_lastname,
                 sch.school_name as school_name,
@@ -177,6 +200,7 @@ func (c *EventsParticipantController) AddEventsParticipant(db *sql.DB) http.Hand
 			&participant.Grade,
 			&participant.Letter,
 			&participant.StudentID,
+			&participant.EventID,
 			&participant.EventsName,
 			&participant.Document,
 			&participant.Category,
@@ -200,6 +224,7 @@ func (c *EventsParticipantController) AddEventsParticipant(db *sql.DB) http.Hand
 				Grade:      grade.String,  // Fixed: use grade.String instead of Grade
 				Letter:     letter.String, // Fixed: use letter.String instead of Letter
 				StudentID:  studentID,
+				EventID:    int(event.ID.Int64),
 				EventsName: eventsName,
 				Document:   documentURL,
 				Category:   category,
@@ -303,14 +328,13 @@ func (c *EventsParticipantController) DeleteEventsParticipant(db *sql.DB) http.H
 			return
 		}
 
-		// Шаг 4: Получение student_id и event_name ДО удаления
+		// Шаг 4: Получение student_id и event_id ДО удаления
 		var studentID int
-		var eventName string
+		var eventID sql.NullInt64
 		err = db.QueryRow(`
-			SELECT ep.student_id, e.event_name
+			SELECT ep.student_id, ep.event_id
 			FROM events_participants ep
-			JOIN Events e ON ep.id = e.id
-			WHERE ep.id = ?`, eventsID).Scan(&studentID, &eventName)
+			WHERE ep.id = ?`, eventsID).Scan(&studentID, &eventID)
 		if err == sql.ErrNoRows {
 			utils.RespondWithError(w, http.StatusNotFound, models.Error{Message: "Event participant not found"})
 			return
@@ -340,12 +364,14 @@ func (c *EventsParticipantController) DeleteEventsParticipant(db *sql.DB) http.H
 			return
 		}
 
-		// Шаг 6: Обновление статуса на "registered" в EventRegistrations
-		_, err = db.Exec(`
-			UPDATE EventRegistrations r
-			JOIN Events e ON r.event_id = e.id
-			SET r.status = 'registered'
-			WHERE r.student_id = ? AND e.event_name = ?`, studentID, eventName)
+		// Шаг 6: Обновление статуса на "registered" в EventRegistrations,
+		// отметка о посещении снимается вместе с участием
+		if eventID.Valid {
+			_, err = db.Exec(`
+				UPDATE EventRegistrations
+				SET status = 'registered', attendance = NULL, checked_in_at = NULL, checked_in_by = NULL
+				WHERE student_id = ? AND event_id = ? AND status IN (`+eventQueue.seatStatuses+`)`, studentID, eventID.Int64)
+		}
 		if err != nil {
 			log.Println("Error updating registration status:", err)
 			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Failed to update registration status"})
@@ -405,6 +431,7 @@ func (c *EventsParticipantController) UpdateEventsParticipant(db *sql.DB) http.H
 		// Step 6: Parse fields
 		schoolIDStr := r.FormValue("school_id")
 		studentIDStr := r.FormValue("student_id")
+		eventIDStr := r.FormValue("event_id")
 		eventsName := strings.TrimSpace(r.FormValue("events_name"))
 		category := strings.TrimSpace(r.FormValue("category"))
 		role := strings.TrimSpace(r.FormValue("role"))
@@ -448,13 +475,25 @@ func (c *EventsParticipantController) UpdateEventsParticipant(db *sql.DB) http.H
 			params = append(params, studentID)
 		}
 
-		if eventsName != "" {
+		var linkedEvent participantEvent
+		if eventIDStr != "" || eventsName != "" {
 			if len(eventsName) > 255 {
 				utils.RespondWithError(w, http.StatusBadRequest, models.Error{Message: "events_name is too long"})
 				return
 			}
-			updateFields = append(updateFields, "events_name = ?")
-			params = append(params, eventsName)
+			event, badRequest, err := resolveParticipantEvent(db, eventIDStr, eventsName)
+			if err != nil {
+				log.Println("Error resolving event:", err)
+				utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Database error while resolving event"})
+				return
+			}
+			if badRequest != "" {
+				utils.RespondWithError(w, http.StatusBadRequest, models.Error{Message: badRequest})
+				return
+			}
+			linkedEvent = event
+			updateFields = append(updateFields, "event_id = ?", "events_name = ?")
+			params = append(params, event.ID, event.Name)
 		}
 
 		if category != "" {
@@ -584,9 +623,18 @@ func (c *EventsParticipantController) UpdateEventsParticipant(db *sql.DB) http.H
 			return
 		}
 
+		if linkedEvent.ID.Valid {
+			var studentID int
+			if err := db.QueryRow("SELECT student_id FROM events_participants WHERE id = ?", eventsIDInt).Scan(&studentID); err == nil {
+				if err := markParticipantAttended(db, int(linkedEvent.ID.Int64), studentID, userID); err != nil {
+					log.Println("Error marking registration as attended:", err)
+				}
+			}
+		}
+
 		// Step 13: Retrieve updated participant data
 		var participant models.EventsParticipant
-		querySelect := `SELECT ep.id, ep.school_id, ep.grade, ep.letter, ep.student_id, ep.events_name, 
+		querySelect := `SELECT ep.id, ep.school_id, ep.grade, ep.letter, ep.student_id, COALESCE(ep.event_id, 0), ep.events_name, 
                               ep.document, ep.category, ep.role, ep.date, 
                               s.student_id, s.first_name as student_name, s.last_name as student_lastname,
                               sch.school_name as school_name,
@@ -603,6 +651,7 @@ func (c *EventsParticipantController) UpdateEventsParticipant(db *sql.DB) http.H
 			&participant.Grade,
 			&participant.Letter,
 			&participant.StudentID,
+			&participant.EventID,
 			&participant.EventsName,
 			&participant.Document,
 			&participant.Category,
@@ -655,7 +704,7 @@ func (c *EventsParticipantController) GetEventsParticipant(db *sql.DB) http.Hand
 		}
 
 		// Step 4: Build query with optional filters
-		query := `SELECT ep.id, ep.school_id, ep.grade, ep.letter, ep.student_id, ep.events_name, 
+		query := `SELECT ep.id, ep.school_id, ep.grade, ep.letter, ep.student_id, COALESCE(ep.event_id, 0), ep.events_name, 
                         ep.document, ep.category, ep.role, ep.date, 
                         s.student_id, s.first_name as student_name, s.last_name as student_lastname,
                         sch.school_name as school_name,
@@ -718,6 +767,7 @@ func (c *EventsParticipantController) GetEventsParticipant(db *sql.DB) http.Hand
 				&p.Grade,
 				&p.Letter,
 				&p.StudentID,
+				&p.EventID,
 				&p.EventsName,
 				&p.Document,
 				&p.Category,
@@ -800,7 +850,7 @@ func (c *EventsParticipantController) GetSingleEventsParticipant(db *sql.DB) htt
 
 		// Step 5: Retrieve participant data
 		var participant models.EventsParticipant
-		query := `SELECT ep.id, ep.school_id, ep.grade, ep.letter, ep.student_id, ep.events_name, 
+		query := `SELECT ep.id, ep.school_id, ep.grade, ep.letter, ep.student_id, COALESCE(ep.event_id, 0), ep.events_name, 
                         ep.document, ep.category, ep.role, ep.date, 
                         s.student_id, s.first_name as student_name, s.last_name as student_lastname,
                         sch.school_name as school_name,
@@ -817,6 +867,7 @@ func (c *EventsParticipantController) GetSingleEventsParticipant(db *sql.DB) htt
 			&participant.Grade,
 			&participant.Letter,
 			&participant.StudentID,
+			&participant.EventID,
 			&participant.EventsName,
 			&participant.Document,
 			&participant.Category,
@@ -889,7 +940,7 @@ func (c *EventsParticipantController) GetEventsParticipantBySchool(db *sql.DB) h
 		}
 
 		// Step 5: Build query for participants by school_id
-		query := `SELECT ep.id, ep.school_id, ep.grade, ep.letter, ep.student_id, ep.events_name, 
+		query := `SELECT ep.id, ep.school_id, ep.grade, ep.letter, ep.student_id, COALESCE(ep.event_id, 0), ep.events_name, 
 				ep.document, ep.category, ep.role, ep.date, 
 				s.student_id, s.first_name as student_name, s.last_name as student_lastname,
 				sch.school_name as school_name,
@@ -919,6 +970,7 @@ func (c *EventsParticipantController) GetEventsParticipantBySchool(db *sql.DB) h
 				&p.Grade,
 				&p.Letter,
 				&p.StudentID,
+				&p.EventID,
 				&p.EventsName,
 				&p.Document,
 				&p.Category,
@@ -1035,6 +1087,17 @@ func (c *EventsParticipantController) CountOlympiadParticipants(db *sql.DB) http
 			log.Printf("Filtering by events_name: %s", eventsName)
 		}
 
+		// Optional filter by event_id
+		if eventIDStr := r.URL.Query().Get("event_id"); eventIDStr != "" {
+			eventID, err := strconv.Atoi(eventIDStr)
+			if err != nil || eventID <= 0 {
+				utils.RespondWithError(w, http.StatusBadRequest, models.Error{Message: "Invalid event_id format"})
+				return
+			}
+			conditions = append(conditions, "ep.event_id = ?")
+			args = append(args, eventID)
+		}
+
 		// Optional filter by date range
 		if startDate := r.URL.Query().Get("start_date"); startDate != "" {
 			conditions = append(conditions, "ep.date >= ?")
@@ -1058,7 +1121,7 @@ func (c *EventsParticipantController) CountOlympiadParticipants(db *sql.DB) http
 		}
 
 		// Complete the group by for the second query
-		countByEventQuery += " GROUP BY ep.events_name ORDER BY participant_count DESC"
+		countByEventQuery += " GROUP BY COALESCE(ep.event_id, 0), ep.events_name ORDER BY participant_count DESC"
 
 		// Response structure
 		type OlympiadStats struct {
@@ -1132,10 +1195,9 @@ func (c *EventsParticipantController) CountOlympiadParticipantsBySchool(db *sql.
 			return
 		}
 
-		// Step 2: Get user role and school_id (if schooladmin)
+		// Step 2: Get user role
 		var userRole string
-		var userSchoolID sql.NullInt64 // Use NullInt64 to handle NULL school_id
-		err = db.QueryRow("SELECT role, school_id FROM users WHERE id = ?", userID).Scan(&userRole, &userSchoolID)
+		err = db.QueryRow("SELECT role FROM users WHERE id = ?", userID).Scan(&userRole)
 		if err != nil {
 			log.Printf("Error fetching user details for user ID %d: %v", userID, err)
 			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Error fetching user details"})
@@ -1161,13 +1223,14 @@ func (c *EventsParticipantController) CountOlympiadParticipantsBySchool(db *sql.
 
 		// Step 5: Restrict schooladmin to their school
 		if userRole == "schooladmin" {
-			if !userSchoolID.Valid {
-				log.Printf("No school_id associated with schooladmin user ID %d", userID)
-				utils.RespondWithError(w, http.StatusForbidden, models.Error{Message: "No school assigned to this admin"})
+			isMember, err := hasSchoolPermission(db, userID, schoolID, "")
+			if err != nil {
+				log.Printf("Error checking membership for user ID %d: %v", userID, err)
+				utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Error fetching user details"})
 				return
 			}
-			if int(userSchoolID.Int64) != schoolID {
-				log.Printf("Schooladmin user ID %d attempted to access school ID %d, but is assigned to school ID %d", userID, schoolID, userSchoolID.Int64)
+			if !isMember {
+				log.Printf("Schooladmin user ID %d attempted to access school ID %d without membership", userID, schoolID)
 				utils.RespondWithError(w, http.StatusForbidden, models.Error{Message: "You do not have permission to view this school's data"})
 				return
 			}
@@ -1175,7 +1238,7 @@ func (c *EventsParticipantController) CountOlympiadParticipantsBySchool(db *sql.
 
 		// Step 6: Build queries with mandatory school_id filter
 		query := `SELECT COUNT(ep.student_id) AS total_participants`
-		eventCountQuery := `SELECT COUNT(DISTINCT ep.events_name) AS event_count`
+		eventCountQuery := `SELECT COUNT(DISTINCT COALESCE(CONCAT('e', ep.event_id), CONCAT('n', ep.events_name))) AS event_count`
 		countByEventQuery := `SELECT 
             ep.events_name, 
             COUNT(ep.student_id) AS participant_count 
@@ -1199,6 +1262,17 @@ func (c *EventsParticipantController) CountOlympiadParticipantsBySchool(db *sql.
 			log.Printf("Filtering by events_name: %s", eventsName)
 		}
 
+		// Optional filter by event_id
+		if eventIDStr := r.URL.Query().Get("event_id"); eventIDStr != "" {
+			eventID, err := strconv.Atoi(eventIDStr)
+			if err != nil || eventID <= 0 {
+				utils.RespondWithError(w, http.StatusBadRequest, models.Error{Message: "Invalid event_id format"})
+				return
+			}
+			conditions = append(conditions, "ep.event_id = ?")
+			args = append(args, eventID)
+		}
+
 		// Optional filter by date range
 		if startDate := r.URL.Query().Get("start_date"); startDate != "" {
 			conditions = append(conditions, "ep.date >= ?")
@@ -1226,7 +1300,7 @@ func (c *EventsParticipantController) CountOlympiadParticipantsBySchool(db *sql.
 		}
 
 		// Complete the group by for the event breakdown query
-		countByEventQuery += " GROUP BY ep.events_name ORDER BY participant_count DESC"
+		countByEventQuery += " GROUP BY COALESCE(ep.event_id, 0), ep.events_name ORDER BY participant_count DESC"
 
 		// Response structure
 		type OlympiadStats struct {
@@ -1296,6 +1370,9 @@ func (c *EventsParticipantController) CountOlympiadParticipantsBySchool(db *sql.
 	}
 }
 
+// GetParticipantByEventNameAndStudentID ищет участие по названию
+// мероприятия. Название сопоставляется с Events; если под ним несколько
+// мероприятий, нужно использовать поиск по event_id.
 func (c *EventsParticipantController) GetParticipantByEventNameAndStudentID(db *sql.DB) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		_, err := utils.VerifyToken(r)
@@ -1312,48 +1389,201 @@ func (c *EventsParticipantController) GetParticipantByEventNameAndStudentID(db *
 			return
 		}
 
-		query := `
-			SELECT ep.id, ep.school_id, ep.grade, ep.letter, ep.student_id, ep.events_name,
-			       ep.document, ep.category, ep.role, ep.date,
-			       s.first_name as student_name, s.last_name as student_lastname,
-			       sch.school_name,
-			       c.id as creator_id, c.first_name as creator_first_name, c.last_name as creator_last_name
-			FROM events_participants ep
-			JOIN student s ON ep.student_id = s.student_id
-			JOIN Schools sch ON ep.school_id = sch.school_id
-			JOIN users c ON ep.creator_id = c.id
-			WHERE ep.events_name = ? AND ep.student_id = ?
-		`
+		event, badRequest, err := resolveParticipantEvent(db, "", eventsName)
+		if err != nil {
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Database error"})
+			return
+		}
+		if badRequest != "" {
+			utils.RespondWithError(w, http.StatusConflict, models.Error{Message: badRequest})
+			return
+		}
 
-		var participant models.EventsParticipant
-		err = db.QueryRow(query, eventsName, studentID).Scan(
-			&participant.ID,
-			&participant.SchoolID,
-			&participant.Grade,
-			&participant.Letter,
-			&participant.StudentID,
-			&participant.EventsName,
-			&participant.Document,
-			&participant.Category,
-			&participant.Role,
-			&participant.Date,
-			&participant.StudentName,
-			&participant.StudentLastName,
-			&participant.SchoolName,
-			&participant.CreatorID,
-			&participant.CreatorFirstName,
-			&participant.CreatorLastName,
-		)
+		if event.ID.Valid {
+			respondParticipantByEvent(w, db, "ep.event_id = ?", event.ID.Int64, studentID)
+			return
+		}
+		// Внешнее мероприятие — его нет в Events
+		respondParticipantByEvent(w, db, "ep.event_id IS NULL AND ep.events_name = ?", eventsName, studentID)
+	}
+}
 
+// GetParticipantByEventIDAndStudentID — участие ученика в мероприятии платформы.
+func (c *EventsParticipantController) GetParticipantByEventIDAndStudentID(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		_, err := utils.VerifyToken(r)
 		if err != nil {
-			if err == sql.ErrNoRows {
-				utils.RespondWithError(w, http.StatusNotFound, models.Error{Message: "Participant not found"})
-			} else {
-				utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Database error"})
-			}
+			utils.RespondWithError(w, http.StatusUnauthorized, models.Error{Message: "Unauthorized"})
 			return
 		}
 
-		utils.ResponseJSON(w, participant)
+		vars := mux.Vars(r)
+		eventID, err1 := strconv.Atoi(vars["event_id"])
+		studentID, err2 := strconv.Atoi(vars["student_id"])
+		if err1 != nil || err2 != nil || eventID <= 0 {
+			utils.RespondWithError(w, http.StatusBadRequest, models.Error{Message: "Invalid parameters"})
+			return
+		}
+
+		respondParticipantByEvent(w, db, "ep.event_id = ?", eventID, studentID)
+	}
+}
+
+func respondParticipantByEvent(w http.ResponseWriter, db *sql.DB, eventCondition string, eventArg interface{}, studentID int) {
+	query := `
+		SELECT ep.id, ep.school_id, ep.grade, ep.letter, ep.student_id, COALESCE(ep.event_id, 0), ep.events_name,
+		       ep.document, ep.category, ep.role, ep.date,
+		       s.first_name as student_name, s.last_name as student_lastname,
+		       sch.school_name,
+		       c.id as creator_id, c.first_name as creator_first_name, c.last_name as creator_last_name
+		FROM events_participants ep
+		JOIN student s ON ep.student_id = s.student_id
+		JOIN Schools sch ON ep.school_id = sch.school_id
+		JOIN users c ON ep.creator_id = c.id
+		WHERE ` + eventCondition + ` AND ep.student_id = ?
+		ORDER BY ep.id DESC
+		LIMIT 1`
+
+	var participant models.EventsParticipant
+	err := db.QueryRow(query, eventArg, studentID).Scan(
+		&participant.ID,
+		&participant.SchoolID,
+		&participant.Grade,
+		&participant.Letter,
+		&participant.StudentID,
+		&participant.EventID,
+		&participant.EventsName,
+		&participant.Document,
+		&participant.Category,
+		&participant.Role,
+		&participant.Date,
+		&participant.StudentName,
+		&participant.StudentLastName,
+		&participant.SchoolName,
+		&participant.CreatorID,
+		&participant.CreatorFirstName,
+		&participant.CreatorLastName,
+	)
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			utils.RespondWithError(w, http.StatusNotFound, models.Error{Message: "Participant not found"})
+		} else {
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Database error"})
+		}
+		return
+	}
+
+	utils.ResponseJSON(w, participant)
+}
+
+// GetUnlinkedEventsParticipants — участия без event_id, название которых
+// совпадает с несколькими мероприятиями (остались после миграции 017).
+// Суперадмин проставляет event_id вручную через обновление участника.
+func (c *EventsParticipantController) GetUnlinkedEventsParticipants(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := utils.VerifyToken(r)
+		if err != nil {
+			utils.RespondWithError(w, http.StatusUnauthorized, models.Error{Message: "Unauthorized"})
+			return
+		}
+		var role string
+		if err := db.QueryRow("SELECT role FROM users WHERE id = ?", userID).Scan(&role); err != nil || role != "superadmin" {
+			utils.RespondWithError(w, http.StatusForbidden, models.Error{Message: "Only superadmin can resolve event links"})
+			return
+		}
+
+		rows, err := db.Query(`
+			SELECT ep.id, ep.school_id, ep.student_id, ep.events_name, COALESCE(CAST(ep.date AS CHAR), ''),
+			       GROUP_CONCAT(e.id ORDER BY e.id)
+			FROM events_participants ep
+			JOIN Events e ON e.event_name = ep.events_name
+			WHERE ep.event_id IS NULL
+			GROUP BY ep.id, ep.school_id, ep.student_id, ep.events_name, ep.date
+			ORDER BY ep.events_name, ep.id`)
+		if err != nil {
+			log.Printf("Error fetching unlinked participants: %v", err)
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Failed to fetch unlinked participants"})
+			return
+		}
+		defer rows.Close()
+
+		type unlinkedParticipant struct {
+			ID                int    `json:"id"`
+			SchoolID          int    `json:"school_id"`
+			StudentID         int    `json:"student_id"`
+			EventsName        string `json:"events_name"`
+			Date              string `json:"date"`
+			CandidateEventIDs []int  `json:"candidate_event_ids"`
+		}
+		result := []unlinkedParticipant{}
+		for rows.Next() {
+			var p unlinkedParticipant
+			var candidates string
+			if err := rows.Scan(&p.ID, &p.SchoolID, &p.StudentID, &p.EventsName, &p.Date, &candidates); err != nil {
+				log.Printf("Error scanning unlinked participant: %v", err)
+				utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Failed to fetch unlinked participants"})
+				return
+			}
+			for _, idStr := range strings.Split(candidates, ",") {
+				if id, err := strconv.Atoi(idStr); err == nil {
+					p.CandidateEventIDs = append(p.CandidateEventIDs, id)
+				}
+			}
+			result = append(result, p)
+		}
+
+		utils.ResponseJSON(w, result)
 	}
 }
+
+// participantEvent — мероприятие, к которому относится участие. ID не
+// задан для внешних мероприятий, которых нет в Events.
+type participantEvent struct {
+	ID   sql.NullInt64
+	Name string
+}
+
+// resolveParticipantEvent определяет мероприятие по event_id или, если он не
+// передан, по названию. Возвращает текст ошибки для клиента (badRequest),
+// если event_id неверный или название совпадает с несколькими мероприятиями.
+func resolveParticipantEvent(db rowQuerier, eventIDStr, eventsName string) (participantEvent, string, error) {
+	var event participantEvent
+	if eventIDStr != "" {
+		eventID, err := strconv.Atoi(eventIDStr)
+		if err != nil || eventID <= 0 {
+			return event, "Invalid event_id format", nil
+		}
+		err = db.QueryRow("SELECT id, event_name FROM Events WHERE id = ?", eventID).Scan(&event.ID, &event.Name)
+		if err == sql.ErrNoRows {
+			return event, "Event not found", nil
+		}
+		return event, "", err
+	}
+
+	var matches int
+	var eventID sql.NullInt64
+	err := db.QueryRow("SELECT COUNT(*), MIN(id) FROM Events WHERE event_name = ?", eventsName).Scan(&matches, &eventID)
+	if err != nil {
+		return event, "", err
+	}
+	if matches > 1 {
+		return event, fmt.Sprintf("Several events are named %q, specify event_id", eventsName), nil
+	}
+	event.Name = eventsName
+	if matches == 1 {
+		event.ID = eventID
+	}
+	return event, "", nil
+}
+
+// markParticipantAttended отмечает заявку ученика на мероприятие как
+// посещённую — внесённое школой участие подтверждает приход.
+func markParticipantAttended(db execQuerier, eventID, studentID, markedBy int) error {
+	_, err := db.Exec(`
+		UPDATE EventRegistrations
+		SET attendance = 'attended', checked_in_at = COALESCE(checked_in_at, NOW()), checked_in_by = COALESCE(checked_in_by, ?)
+		WHERE event_id = ? AND student_id = ? AND status IN (`+eventQueue.seatStatuses+`)
+		  AND (attendance IS NULL OR attendance = 'no_show')`, markedBy, eventID, studentID)
+	return err
+}
@@ -0,0 +1,524 @@
+package controllers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"ranking-school/models"
+	"ranking-school/utils"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+type GrantController struct{}
+
+type grantCheck struct {
+	Component string `json:"component"`
+	Score     int    `json:"score"`
+	Minimum   int    `json:"minimum"`
+	Passed    bool   `json:"passed"`
+}
+
+type grantGroupResult struct {
+	GroupCode    string       `json:"group_code"`
+	GroupName    string       `json:"group_name"`
+	PassingScore *int         `json:"passing_score,omitempty"`
+	Eligible     bool         `json:"eligible"`
+	Checks       []grantCheck `json:"checks"`
+}
+
+const grantThresholdColumns = `id, year, group_code, group_name, exam_type,
+	COALESCE(first_subject_code, ''), COALESCE(second_subject_code, ''),
+	min_total, min_history_of_kazakhstan, min_reading_literacy, min_mathematical_literacy,
+	min_first_subject, min_second_subject, passing_score`
+
+func scanGrantThreshold(row interface{ Scan(...interface{}) error }) (models.GrantThreshold, error) {
+	var t models.GrantThreshold
+	var passing sql.NullInt64
+	err := row.Scan(&t.ID, &t.Year, &t.GroupCode, &t.GroupName, &t.ExamType,
+		&t.FirstSubjectCode, &t.SecondSubjectCode,
+		&t.MinTotal, &t.MinHistoryOfKazakhstan, &t.MinReadingLiteracy, &t.MinMathLiteracy,
+		&t.MinFirstSubject, &t.MinSecondSubject, &passing)
+	if passing.Valid {
+		score := int(passing.Int64)
+		t.PassingScore = &score
+	}
+	return t, err
+}
+
+// loadGrantThresholds returns thresholds for the year. If the year has none
+// yet, the latest configured year is used.
+func loadGrantThresholds(db *sql.DB, year int) ([]models.GrantThreshold, int, error) {
+	var effectiveYear sql.NullInt64
+	err := db.QueryRow("SELECT MAX(year) FROM grant_thresholds WHERE year <= ?", year).Scan(&effectiveYear)
+	if err != nil {
+		return nil, 0, err
+	}
+	if !effectiveYear.Valid {
+		return []models.GrantThreshold{}, year, nil
+	}
+
+	rows, err := db.Query("SELECT "+grantThresholdColumns+" FROM grant_thresholds WHERE year = ? ORDER BY group_code", effectiveYear.Int64)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	thresholds := []models.GrantThreshold{}
+	for rows.Next() {
+		t, err := scanGrantThreshold(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		thresholds = append(thresholds, t)
+	}
+	return thresholds, int(effectiveYear.Int64), rows.Err()
+}
+
+// evaluateGrant checks one exam against one specialty group. Groups of another
+// exam type or subject pair are skipped (ok == false).
+func evaluateGrant(catalog *untCatalog, exam models.UNTExam, t models.GrantThreshold) (grantGroupResult, bool) {
+	if t.ExamType != exam.ExamType {
+		return grantGroupResult{}, false
+	}
+	if t.FirstSubjectCode != "" || t.SecondSubjectCode != "" {
+		first, _ := catalog.subject(exam.FirstSubject)
+		second, _ := catalog.subject(exam.SecondSubject)
+		samePair := (first.Code == t.FirstSubjectCode && second.Code == t.SecondSubjectCode) ||
+			(first.Code == t.SecondSubjectCode && second.Code == t.FirstSubjectCode)
+		if !samePair {
+			return grantGroupResult{}, false
+		}
+	}
+
+	minTotal := t.MinTotal
+	if t.PassingScore != nil && *t.PassingScore > minTotal {
+		minTotal = *t.PassingScore
+	}
+	checks := []grantCheck{
+		{"total_score", exam.TotalScore, minTotal, false},
+		{"history_of_kazakhstan", exam.HistoryOfKazakhstan, t.MinHistoryOfKazakhstan, false},
+		{"reading_literacy", exam.ReadingLiteracy, t.MinReadingLiteracy, false},
+		{"first_subject_score", exam.FirstSubjectScore, t.MinFirstSubject, false},
+		{"second_subject_score", exam.SecondSubjectScore, t.MinSecondSubject, false},
+	}
+	if exam.ExamType == "regular" {
+		checks = append(checks, grantCheck{"mathematical_literacy", exam.MathematicalLiteracy, t.MinMathLiteracy, false})
+	}
+
+	result := grantGroupResult{GroupCode: t.GroupCode, GroupName: t.GroupName, PassingScore: t.PassingScore, Eligible: true}
+	for i := range checks {
+		checks[i].Passed = checks[i].Score >= checks[i].Minimum
+		if !checks[i].Passed {
+			result.Eligible = false
+		}
+	}
+	result.Checks = checks
+	return result, true
+}
+
+func examYear(exam models.UNTExam) int {
+	year, _ := strconv.Atoi(firstN(exam.Date, 4))
+	return year
+}
+
+// GetGrantThresholds — пороговые баллы за год (?year=, по умолчанию текущий).
+func (gc *GrantController) GetGrantThresholds(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		year := time.Now().Year()
+		if raw := r.URL.Query().Get("year"); raw != "" {
+			parsed, err := strconv.Atoi(raw)
+			if err != nil {
+				utils.RespondWithError(w, http.StatusBadRequest, models.Error{Message: "Некорректный год"})
+				return
+			}
+			year = parsed
+		}
+
+		thresholds, effectiveYear, err := loadGrantThresholds(db, year)
+		if err != nil {
+			log.Printf("Ошибка при получении пороговых баллов: %v", err)
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Не удалось получить пороговые баллы"})
+			return
+		}
+		utils.ResponseJSON(w, map[string]interface{}{
+			"year":       effectiveYear,
+			"thresholds": thresholds,
+		})
+	}
+}
+
+// decodeGrantThreshold reads and validates the request body. It writes the
+// HTTP error itself and returns false if the handler should stop.
+func decodeGrantThreshold(w http.ResponseWriter, r *http.Request, db *sql.DB) (models.GrantThreshold, bool) {
+	t := models.GrantThreshold{
+		ExamType:               "regular",
+		MinTotal:               50,
+		MinHistoryOfKazakhstan: 5,
+		MinReadingLiteracy:     3,
+		MinMathLiteracy:        3,
+		MinFirstSubject:        5,
+		MinSecondSubject:       5,
+	}
+	if err := json.NewDecoder(r.Body).Decode(&t); err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, models.Error{Message: "Некорректный запрос"})
+		return t, false
+	}
+
+	t.GroupCode = strings.ToUpper(strings.TrimSpace(t.GroupCode))
+	t.GroupName = strings.TrimSpace(t.GroupName)
+	t.ExamType = strings.ToLower(t.ExamType)
+	if t.Year < 2000 || t.GroupCode == "" || t.GroupName == "" {
+		utils.RespondWithError(w, http.StatusBadRequest, models.Error{Message: "Обязательные поля: year, group_code, group_name"})
+		return t, false
+	}
+	if t.ExamType != "regular" && t.ExamType != "creative" {
+		utils.RespondWithError(w, http.StatusBadRequest, models.Error{Message: "Некорректный тип экзамена. Допустимые значения: regular, creative"})
+		return t, false
+	}
+	if (t.FirstSubjectCode == "") != (t.SecondSubjectCode == "") {
+		utils.RespondWithError(w, http.StatusBadRequest, models.Error{Message: "Пара профильных предметов указывается целиком или не указывается"})
+		return t, false
+	}
+
+	if t.FirstSubjectCode != "" {
+		catalog, err := loadUNTCatalog(db)
+		if err != nil {
+			log.Printf("Ошибка при загрузке справочника предметов ЕНТ: %v", err)
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Не удалось загрузить справочник предметов ЕНТ"})
+			return t, false
+		}
+		for _, code := range []*string{&t.FirstSubjectCode, &t.SecondSubjectCode} {
+			s, ok := catalog.subject(*code)
+			if !ok || s.Kind == "mandatory" {
+				utils.RespondWithError(w, http.StatusBadRequest, models.Error{Message: fmt.Sprintf("Предмет %q не найден в справочнике", *code)})
+				return t, false
+			}
+			*code = s.Code
+		}
+	}
+
+	for _, v := range []int{t.MinTotal, t.MinHistoryOfKazakhstan, t.MinReadingLiteracy, t.MinMathLiteracy, t.MinFirstSubject, t.MinSecondSubject} {
+		if v < 0 {
+			utils.RespondWithError(w, http.StatusBadRequest, models.Error{Message: "Минимальные баллы не могут быть отрицательными"})
+			return t, false
+		}
+	}
+	return t, true
+}
+
+func requireSuperadmin(w http.ResponseWriter, db *sql.DB, userID int) bool {
+	var role string
+	err := db.QueryRow("SELECT role FROM users WHERE id = ?", userID).Scan(&role)
+	if err != nil && err != sql.ErrNoRows {
+		log.Printf("Ошибка при получении роли пользователя: %v", err)
+		utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Не удалось получить роль пользователя"})
+		return false
+	}
+	if role != "superadmin" {
+		utils.RespondWithError(w, http.StatusForbidden, models.Error{Message: "Только суперадмин может изменять пороговые баллы"})
+		return false
+	}
+	return true
+}
+
+// CreateGrantThreshold добавляет порог для группы образовательных программ.
+func (gc *GrantController) CreateGrantThreshold(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := utils.VerifyToken(r)
+		if err != nil {
+			utils.RespondWithError(w, http.StatusUnauthorized, models.Error{Message: err.Error()})
+			return
+		}
+		if !requireSuperadmin(w, db, userID) {
+			return
+		}
+
+		t, ok := decodeGrantThreshold(w, r, db)
+		if !ok {
+			return
+		}
+
+		result, err := db.Exec(`INSERT INTO grant_thresholds (year, group_code, group_name, exam_type, first_subject_code, second_subject_code,
+				min_total, min_history_of_kazakhstan, min_reading_literacy, min_mathematical_literacy, min_first_subject, min_second_subject, passing_score)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			t.Year, t.GroupCode, t.GroupName, t.ExamType, toNullString(t.FirstSubjectCode), toNullString(t.SecondSubjectCode),
+			t.MinTotal, t.MinHistoryOfKazakhstan, t.MinReadingLiteracy, t.MinMathLiteracy, t.MinFirstSubject, t.MinSecondSubject, t.PassingScore)
+		if err != nil {
+			if strings.Contains(err.Error(), "Duplicate entry") {
+				utils.RespondWithError(w, http.StatusConflict, models.Error{Message: "Порог для этой группы и года уже существует"})
+				return
+			}
+			log.Printf("Ошибка при создании порогового балла: %v", err)
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Не удалось создать пороговый балл"})
+			return
+		}
+		id, _ := result.LastInsertId()
+		t.ID = int(id)
+		utils.ResponseJSON(w, t)
+	}
+}
+
+// UpdateGrantThreshold полностью заменяет порог.
+func (gc *GrantController) UpdateGrantThreshold(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := utils.VerifyToken(r)
+		if err != nil {
+			utils.RespondWithError(w, http.StatusUnauthorized, models.Error{Message: err.Error()})
+			return
+		}
+		if !requireSuperadmin(w, db, userID) {
+			return
+		}
+
+		id, err := strconv.Atoi(mux.Vars(r)["id"])
+		if err != nil {
+			utils.RespondWithError(w, http.StatusBadRequest, models.Error{Message: "Некорректный ID"})
+			return
+		}
+
+		t, ok := decodeGrantThreshold(w, r, db)
+		if !ok {
+			return
+		}
+		t.ID = id
+
+		result, err := db.Exec(`UPDATE grant_thresholds SET year = ?, group_code = ?, group_name = ?, exam_type = ?,
+				first_subject_code = ?, second_subject_code = ?, min_total = ?, min_history_of_kazakhstan = ?,
+				min_reading_literacy = ?, min_mathematical_literacy = ?, min_first_subject = ?, min_second_subject = ?, passing_score = ?
+			WHERE id = ?`,
+			t.Year, t.GroupCode, t.GroupName, t.ExamType, toNullString(t.FirstSubjectCode), toNullString(t.SecondSubjectCode),
+			t.MinTotal, t.MinHistoryOfKazakhstan, t.MinReadingLiteracy, t.MinMathLiteracy, t.MinFirstSubject, t.MinSecondSubject, t.PassingScore, id)
+		if err != nil {
+			if strings.Contains(err.Error(), "Duplicate entry") {
+				utils.RespondWithError(w, http.StatusConflict, models.Error{Message: "Порог для этой группы и года уже существует"})
+				return
+			}
+			log.Printf("Ошибка при обновлении порогового балла: %v", err)
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Не удалось обновить пороговый балл"})
+			return
+		}
+		if affected, _ := result.RowsAffected(); affected == 0 {
+			var exists bool
+			db.QueryRow("SELECT EXISTS(SELECT 1 FROM grant_thresholds WHERE id = ?)", id).Scan(&exists)
+			if !exists {
+				utils.RespondWithError(w, http.StatusNotFound, models.Error{Message: "Пороговый балл не найден"})
+				return
+			}
+		}
+		utils.ResponseJSON(w, t)
+	}
+}
+
+// DeleteGrantThreshold удаляет порог.
+func (gc *GrantController) DeleteGrantThreshold(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := utils.VerifyToken(r)
+		if err != nil {
+			utils.RespondWithError(w, http.StatusUnauthorized, models.Error{Message: err.Error()})
+			return
+		}
+		if !requireSuperadmin(w, db, userID) {
+			return
+		}
+
+		id, err := strconv.Atoi(mux.Vars(r)["id"])
+		if err != nil {
+			utils.RespondWithError(w, http.StatusBadRequest, models.Error{Message: "Некорректный ID"})
+			return
+		}
+
+		result, err := db.Exec("DELETE FROM grant_thresholds WHERE id = ?", id)
+		if err != nil {
+			log.Printf("Ошибка при удалении порогового балла: %v", err)
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Не удалось удалить пороговый балл"})
+			return
+		}
+		if affected, _ := result.RowsAffected(); affected == 0 {
+			utils.RespondWithError(w, http.StatusNotFound, models.Error{Message: "Пороговый балл не найден"})
+			return
+		}
+		utils.ResponseJSON(w, map[string]string{"message": "Пороговый балл удалён"})
+	}
+}
+
+// GetStudentGrantEligibility сравнивает последний итоговый ЕНТ ученика
+// с порогами года сдачи и возвращает подходящие группы программ.
+func (gc *GrantController) GetStudentGrantEligibility(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if _, err := utils.VerifyToken(r); err != nil {
+			utils.RespondWithError(w, http.StatusUnauthorized, models.Error{Message: "Unauthorized"})
+			return
+		}
+
+		studentID, err := strconv.Atoi(mux.Vars(r)["student_id"])
+		if err != nil || studentID <= 0 {
+			utils.RespondWithError(w, http.StatusBadRequest, models.Error{Message: "Invalid student_id"})
+			return
+		}
+
+		exam, err := latestOfficialUNTExam(db, studentID)
+		if err == sql.ErrNoRows {
+			utils.RespondWithError(w, http.StatusNotFound, models.Error{Message: "UNT exam not found for this student"})
+			return
+		} else if err != nil {
+			log.Printf("Ошибка при получении результата ЕНТ: %v", err)
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Database error"})
+			return
+		}
+
+		catalog, err := loadUNTCatalog(db)
+		if err != nil {
+			log.Printf("Ошибка при загрузке справочника предметов ЕНТ: %v", err)
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Не удалось загрузить справочник предметов ЕНТ"})
+			return
+		}
+		thresholds, year, err := loadGrantThresholds(db, examYear(exam))
+		if err != nil {
+			log.Printf("Ошибка при получении пороговых баллов: %v", err)
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Не удалось получить пороговые баллы"})
+			return
+		}
+
+		eligible := []grantGroupResult{}
+		notEligible := []grantGroupResult{}
+		for _, t := range thresholds {
+			result, ok := evaluateGrant(catalog, exam, t)
+			if !ok {
+				continue
+			}
+			if result.Eligible {
+				eligible = append(eligible, result)
+			} else {
+				notEligible = append(notEligible, result)
+			}
+		}
+
+		utils.ResponseJSON(w, map[string]interface{}{
+			"student_id":      studentID,
+			"thresholds_year": year,
+			"exam":            exam,
+			"eligible":        eligible,
+			"not_eligible":    notEligible,
+		})
+	}
+}
+
+// GetSchoolGrantSummary считает, сколько выпускников года (?year=) прошли
+// пороги хотя бы одной группы и каждой группы в отдельности.
+func (gc *GrantController) GetSchoolGrantSummary(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := utils.VerifyToken(r)
+		if err != nil {
+			utils.RespondWithError(w, http.StatusUnauthorized, models.Error{Message: err.Error()})
+			return
+		}
+
+		schoolID, err := strconv.Atoi(mux.Vars(r)["school_id"])
+		if err != nil {
+			utils.RespondWithError(w, http.StatusBadRequest, models.Error{Message: "Некорректный ID школы"})
+			return
+		}
+
+		allowed, err := canViewSchool(db, userID, schoolID)
+		if err != nil {
+			log.Printf("Ошибка при проверке прав: %v", err)
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Не удалось проверить права пользователя"})
+			return
+		}
+		if !allowed {
+			utils.RespondWithError(w, http.StatusForbidden, models.Error{Message: "У вас нет прав для просмотра данных этой школы"})
+			return
+		}
+
+		year := time.Now().Year()
+		if raw := r.URL.Query().Get("year"); raw != "" {
+			year, err = strconv.Atoi(raw)
+			if err != nil {
+				utils.RespondWithError(w, http.StatusBadRequest, models.Error{Message: "Некорректный год"})
+				return
+			}
+		}
+
+		// Итоговые ЕНТ, сданные в школе в этом году; у ученика берётся последний
+		rows, err := db.Query(`
+			SELECT e.student_id, e.exam_type, COALESCE(e.first_subject, ''), COALESCE(e.first_subject_score, 0),
+			       COALESCE(e.second_subject, ''), COALESCE(e.second_subject_score, 0), COALESCE(e.history_of_kazakhstan, 0),
+			       COALESCE(e.mathematical_literacy, 0), COALESCE(e.reading_literacy, 0), COALESCE(e.total_score, 0)
+			FROM UNT_Exams e
+			WHERE e.school_id = ? AND e.attempt_kind = 'official' AND YEAR(e.date) = ?
+			  AND e.id = (SELECT e2.id FROM UNT_Exams e2
+			              WHERE e2.student_id = e.student_id AND e2.attempt_kind = 'official' AND YEAR(e2.date) = ?
+			              ORDER BY e2.date DESC, e2.id DESC LIMIT 1)`, schoolID, year, year)
+		if err != nil {
+			log.Printf("Ошибка при получении результатов ЕНТ: %v", err)
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Не удалось получить результаты ЕНТ"})
+			return
+		}
+		defer rows.Close()
+
+		var exams []models.UNTExam
+		for rows.Next() {
+			var exam models.UNTExam
+			if err := rows.Scan(&exam.StudentID, &exam.ExamType, &exam.FirstSubject, &exam.FirstSubjectScore,
+				&exam.SecondSubject, &exam.SecondSubjectScore, &exam.HistoryOfKazakhstan,
+				&exam.MathematicalLiteracy, &exam.ReadingLiteracy, &exam.TotalScore); err != nil {
+				log.Printf("Ошибка при сканировании результата ЕНТ: %v", err)
+				utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Не удалось получить результаты ЕНТ"})
+				return
+			}
+			exams = append(exams, exam)
+		}
+
+		catalog, err := loadUNTCatalog(db)
+		if err != nil {
+			log.Printf("Ошибка при загрузке справочника предметов ЕНТ: %v", err)
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Не удалось загрузить справочник предметов ЕНТ"})
+			return
+		}
+		thresholds, thresholdsYear, err := loadGrantThresholds(db, year)
+		if err != nil {
+			log.Printf("Ошибка при получении пороговых баллов: %v", err)
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Не удалось получить пороговые баллы"})
+			return
+		}
+
+		type groupSummary struct {
+			GroupCode string `json:"group_code"`
+			GroupName string `json:"group_name"`
+			Eligible  int    `json:"eligible"`
+		}
+		groups := make([]groupSummary, len(thresholds))
+		for i, t := range thresholds {
+			groups[i] = groupSummary{GroupCode: t.GroupCode, GroupName: t.GroupName}
+		}
+
+		clearedAny := 0
+		for _, exam := range exams {
+			cleared := false
+			for i, t := range thresholds {
+				if result, ok := evaluateGrant(catalog, exam, t); ok && result.Eligible {
+					groups[i].Eligible++
+					cleared = true
+				}
+			}
+			if cleared {
+				clearedAny++
+			}
+		}
+
+		utils.ResponseJSON(w, map[string]interface{}{
+			"school_id":         schoolID,
+			"year":              year,
+			"thresholds_year":   thresholdsYear,
+			"graduates_tested":  len(exams),
+			"cleared_any_group": clearedAny,
+			"groups":            groups,
+		})
+	}
+}
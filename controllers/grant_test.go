@@ -0,0 +1,96 @@
+package controllers
+
+import (
+	"ranking-school/models"
+	"testing"
+)
+
+func TestEvaluateGrant(t *testing.T) {
+	passing := 110
+	mathPhysics := models.GrantThreshold{
+		GroupCode: "B057", GroupName: "Информационные технологии", ExamType: "regular",
+		FirstSubjectCode: "mathematics", SecondSubjectCode: "physics",
+		MinTotal: 75, MinHistoryOfKazakhstan: 5, MinReadingLiteracy: 3, MinMathLiteracy: 3,
+		MinFirstSubject: 5, MinSecondSubject: 5,
+	}
+	withPassing := mathPhysics
+	withPassing.PassingScore = &passing
+	anyPair := mathPhysics
+	anyPair.FirstSubjectCode, anyPair.SecondSubjectCode = "", ""
+	creativeGroup := models.GrantThreshold{GroupCode: "B031", ExamType: "creative", MinTotal: 65}
+
+	exam := func(first, second string, history, mathLiteracy, total int) models.UNTExam {
+		return models.UNTExam{
+			ExamType: "regular", FirstSubject: first, FirstSubjectScore: 40,
+			SecondSubject: second, SecondSubjectScore: 35, HistoryOfKazakhstan: history,
+			ReadingLiteracy: 8, MathematicalLiteracy: mathLiteracy, TotalScore: total,
+		}
+	}
+
+	tests := []struct {
+		name         string
+		exam         models.UNTExam
+		threshold    models.GrantThreshold
+		wantOK       bool
+		wantEligible bool
+		wantFailed   []string
+	}{
+		{"eligible", exam("Математика", "Физика", 15, 7, 105), mathPhysics, true, true, nil},
+		{"pair in reverse order", exam("Физика", "Математика", 15, 7, 105), mathPhysics, true, true, nil},
+		{"group without pair accepts any pair", exam("Математика", "Биология", 15, 7, 105), anyPair, true, true, nil},
+		{"other pair is skipped", exam("Математика", "Биология", 15, 7, 105), mathPhysics, false, false, nil},
+		{"other exam type is skipped", exam("Математика", "Физика", 15, 7, 105), creativeGroup, false, false, nil},
+		{"component below minimum", exam("Математика", "Физика", 4, 7, 94), mathPhysics, true, false, []string{"history_of_kazakhstan"}},
+		{"math literacy below minimum", exam("Математика", "Физика", 15, 2, 100), mathPhysics, true, false, []string{"mathematical_literacy"}},
+		{"passing score raises the total minimum", exam("Математика", "Физика", 15, 7, 105), withPassing, true, false, []string{"total_score"}},
+	}
+
+	catalog := newTestUNTCatalog()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, ok := evaluateGrant(catalog, tt.exam, tt.threshold)
+			if ok != tt.wantOK {
+				t.Fatalf("evaluateGrant() ok = %v, want %v", ok, tt.wantOK)
+			}
+			if !ok {
+				return
+			}
+			if result.Eligible != tt.wantEligible {
+				t.Errorf("Eligible = %v, want %v", result.Eligible, tt.wantEligible)
+			}
+			var failed []string
+			for _, c := range result.Checks {
+				if !c.Passed {
+					failed = append(failed, c.Component)
+				}
+			}
+			if len(failed) != len(tt.wantFailed) {
+				t.Fatalf("failed checks = %v, want %v", failed, tt.wantFailed)
+			}
+			for i := range failed {
+				if failed[i] != tt.wantFailed[i] {
+					t.Errorf("failed checks = %v, want %v", failed, tt.wantFailed)
+				}
+			}
+		})
+	}
+}
+
+func TestEvaluateGrantSkipsMathLiteracyOnCreative(t *testing.T) {
+	exam := models.UNTExam{
+		ExamType: "creative", FirstSubject: "creative_exam_1", FirstSubjectScore: 40,
+		SecondSubject: "creative_exam_2", SecondSubjectScore: 40, HistoryOfKazakhstan: 15,
+		ReadingLiteracy: 8, TotalScore: 103,
+	}
+	threshold := models.GrantThreshold{ExamType: "creative", MinTotal: 65, MinMathLiteracy: 3}
+
+	result, ok := evaluateGrant(newTestUNTCatalog(), exam, threshold)
+	if !ok || !result.Eligible {
+		t.Fatalf("evaluateGrant() = %+v, %v; want eligible", result, ok)
+	}
+	for _, c := range result.Checks {
+		if c.Component == "mathematical_literacy" {
+			t.Errorf("creative exam must not be checked for mathematical_literacy")
+		}
+	}
+}
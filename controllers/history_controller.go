@@ -26,21 +26,23 @@ func (hc *HistoryController) GetMyHistory(db *sql.DB) http.HandlerFunc {
 			return
 		}
 
+		// Участия во внутренних олимпиадах и внешние результаты — из Olympiads
 		olympRows, err := db.Query(`
 			SELECT 
-				o.subject_name, 
-				o.level, 
-				o.date, 
-				o.end_date, 
-				r.status, 
-				r.score, 
-				r.olympiad_place,
+				COALESCE(so.subject_name, o.olympiad_name, ''),
+				COALESCE(o.level, ''),
+				COALESCE(CAST(COALESCE(so.date, o.date) AS CHAR), ''),
+				COALESCE(CAST(COALESCE(so.end_date, o.date) AS CHAR), ''),
+				COALESCE(r.status, 'completed'),
+				o.score, 
+				o.olympiad_place,
 				sc.school_name
-			FROM olympiad_registrations r
-			JOIN subject_olympiads o ON r.subject_olympiad_id = o.subject_olympiad_id
-			JOIN Schools sc ON o.school_id = sc.school_id
-			WHERE r.student_id = ?
-			ORDER BY r.registration_date DESC
+			FROM Olympiads o
+			LEFT JOIN subject_olympiads so ON so.subject_olympiad_id = o.subject_olympiad_id
+			LEFT JOIN olympiad_registrations r ON r.olympiads_registrations_id = o.registration_id
+			LEFT JOIN Schools sc ON sc.school_id = COALESCE(so.school_id, o.school_id)
+			WHERE o.student_id = ?
+			ORDER BY COALESCE(r.registration_date, o.date) DESC
 		`, userID)
 		if err != nil {
 			log.Printf("ERROR: olympiad query error: %v", err)
@@ -152,7 +154,7 @@ func (hc *HistoryController) GetMyAchievements(db *sql.DB) http.HandlerFunc {
 			SELECT 
 				olympiad_name,
 				level,
-				date,
+				COALESCE(CAST(date AS CHAR), ''),
 				score,
 				olympiad_place,
 				s.school_name,
@@ -0,0 +1,97 @@
+package controllers
+
+import (
+	"database/sql"
+	"log"
+	"net/http"
+	"ranking-school/models"
+	"ranking-school/utils"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+type NotificationController struct{}
+
+// createNotification сохраняет уведомление ученику. Вызывается внутри
+// транзакции, которая его породила.
+func createNotification(db execQuerier, studentID int, kind, message string) error {
+	_, err := db.Exec("INSERT INTO notifications (student_id, kind, message) VALUES (?, ?, ?)", studentID, kind, message)
+	return err
+}
+
+// GetMyNotifications — уведомления текущего ученика, новые сверху.
+// ?unread=true — только непрочитанные.
+func (nc *NotificationController) GetMyNotifications(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := utils.VerifyToken(r)
+		if err != nil {
+			utils.RespondWithError(w, http.StatusUnauthorized, models.Error{Message: "Unauthorized"})
+			return
+		}
+
+		query := `
+			SELECT id, student_id, kind, message, is_read, CAST(created_at AS CHAR)
+			FROM notifications
+			WHERE student_id = ?`
+		if r.URL.Query().Get("unread") == "true" {
+			query += " AND is_read = 0"
+		}
+		query += " ORDER BY created_at DESC, id DESC LIMIT 100"
+
+		rows, err := db.Query(query, userID)
+		if err != nil {
+			log.Printf("Error fetching notifications for student %d: %v", userID, err)
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Failed to fetch notifications"})
+			return
+		}
+		defer rows.Close()
+
+		notifications := []models.Notification{}
+		for rows.Next() {
+			var n models.Notification
+			if err := rows.Scan(&n.ID, &n.StudentID, &n.Kind, &n.Message, &n.IsRead, &n.CreatedAt); err != nil {
+				log.Printf("Error scanning notification: %v", err)
+				utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Failed to fetch notifications"})
+				return
+			}
+			notifications = append(notifications, n)
+		}
+
+		utils.ResponseJSON(w, notifications)
+	}
+}
+
+// MarkNotificationRead отмечает уведомление прочитанным.
+func (nc *NotificationController) MarkNotificationRead(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := utils.VerifyToken(r)
+		if err != nil {
+			utils.RespondWithError(w, http.StatusUnauthorized, models.Error{Message: "Unauthorized"})
+			return
+		}
+
+		id, err := strconv.Atoi(mux.Vars(r)["id"])
+		if err != nil || id <= 0 {
+			utils.RespondWithError(w, http.StatusBadRequest, models.Error{Message: "Invalid notification ID"})
+			return
+		}
+
+		res, err := db.Exec("UPDATE notifications SET is_read = 1 WHERE id = ? AND student_id = ?", id, userID)
+		if err != nil {
+			log.Printf("Error marking notification %d as read: %v", id, err)
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Failed to update notification"})
+			return
+		}
+		if affected, _ := res.RowsAffected(); affected == 0 {
+			var exists bool
+			db.QueryRow("SELECT EXISTS(SELECT 1 FROM notifications WHERE id = ? AND student_id = ?)", id, userID).Scan(&exists)
+			if !exists {
+				utils.RespondWithError(w, http.StatusNotFound, models.Error{Message: "Notification not found"})
+				return
+			}
+		}
+
+		utils.ResponseJSON(w, map[string]string{"message": "Notification marked as read"})
+	}
+}
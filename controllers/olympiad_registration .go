@@ -9,6 +9,7 @@ import (
 	"net/http"
 	"path/filepath"
 	"strconv"
+	"strings"
 	"time"
 
 	"ranking-school/models"
@@ -68,18 +69,15 @@ func (c *OlympiadRegistrationController) RegisterStudent(db *sql.DB) http.Handle
 		var olympiad models.SubjectOlympiad
 		var endDateStr sql.NullString
 		var limit sql.NullInt64
-		var creatorSchoolID int
-		var currentParticipants int
-		var olympiadGrade int
 
 		err = db.QueryRow(`
 			SELECT so.subject_olympiad_id, so.subject_name, so.date, so.end_date,
-			so.limit_participants, so.school_id, so.current_participants, so.grade
+			so.limit_participants, so.status
 			FROM subject_olympiads so
 			WHERE so.subject_olympiad_id = ?`, request.SubjectOlympiadID).Scan(
 			&olympiad.ID, &olympiad.SubjectName, &olympiad.StartDate, &endDateStr,
-			&limit, &creatorSchoolID, &currentParticipants, &olympiadGrade)
-		if err != nil {
+			&limit, &olympiad.Status)
+		if err != nil || olympiad.Status != PublicationPublished {
 			utils.RespondWithError(w, http.StatusNotFound, models.Error{Message: "Subject olympiad not found"})
 			return
 		}
@@ -97,20 +95,33 @@ func (c *OlympiadRegistrationController) RegisterStudent(db *sql.DB) http.Handle
 			olympiad.Limit = 100
 		}
 
-		parsedStartDate, _ := time.Parse("2006-01-02", olympiad.StartDate)
-		if time.Now().After(parsedStartDate) {
-			utils.RespondWithError(w, http.StatusBadRequest, models.Error{Message: "Registration period has ended"})
+		window, err := loadOlympiadWindow(db, request.SubjectOlympiadID)
+		if err != nil {
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Database error"})
+			return
+		}
+		isLate, closedMessage := window.check(time.Now())
+		if closedMessage != "" {
+			utils.RespondWithError(w, http.StatusBadRequest, models.Error{Message: closedMessage})
+			return
+		}
+
+		tx, err := db.Begin()
+		if err != nil {
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Database error"})
 			return
 		}
+		defer tx.Rollback()
 
-		// Проверка на соответствие класса ученика классу олимпиады
-		if student.Grade != olympiadGrade {
-			utils.RespondWithError(w, http.StatusBadRequest, models.Error{Message: "You do not meet the required grade for this olympiad"})
+		// Блокировка олимпиады: лимит проверяется атомарно
+		limitCount, taken, err := olympiadQueue.lock(tx, request.SubjectOlympiadID)
+		if err != nil {
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Database error"})
 			return
 		}
 
 		var exists int
-		err = db.QueryRow(`
+		err = tx.QueryRow(`
 			SELECT COUNT(*) FROM olympiad_registrations
 			WHERE student_id = ? AND subject_olympiad_id = ?`,
 			student.ID, request.SubjectOlympiadID).Scan(&exists)
@@ -119,37 +130,31 @@ func (c *OlympiadRegistrationController) RegisterStudent(db *sql.DB) http.Handle
 			return
 		}
 
-		if olympiad.Limit > 0 && currentParticipants >= olympiad.Limit {
-			utils.RespondWithError(w, http.StatusConflict, models.Error{Message: "Registration limit reached"})
+		// Правила допуска (класс, школы, квоты и т.д.)
+		eligibility, err := evaluateEligibility(tx, olympiadEligibility, request.SubjectOlympiadID, student.ID)
+		if err != nil {
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Database error"})
+			return
+		}
+		if !eligibility.Eligible {
+			utils.RespondWithError(w, http.StatusForbidden, models.Error{Message: strings.Join(eligibility.Reasons, "; ")})
 			return
 		}
 
-		const maxStudentsFromCreatorSchool = 2
-		if student.SchoolID == creatorSchoolID {
-			var schoolStudentsCount int
-			err = db.QueryRow(`
-				SELECT COUNT(*) FROM olympiad_registrations r
-				JOIN student s ON r.student_id = s.student_id
-				WHERE r.subject_olympiad_id = ? AND s.school_id = ? AND r.status = 'registered'`,
-				request.SubjectOlympiadID, creatorSchoolID).Scan(&schoolStudentsCount)
-			if err != nil {
-				utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Database error"})
-				return
-			}
-
-			if schoolStudentsCount >= maxStudentsFromCreatorSchool {
-				utils.RespondWithError(w, http.StatusConflict,
-					models.Error{Message: "Maximum limit for your school has been reached"})
-				return
-			}
+		// Мест нет — заявка попадает в лист ожидания
+		newStatus := "registered"
+		if !hasFreeSeat(limitCount, taken) {
+			newStatus = "waitlisted"
+		} else {
+			taken++
 		}
 
 		now := time.Now()
-		result, err := db.Exec(`
+		result, err := tx.Exec(`
 			INSERT INTO olympiad_registrations
-			(student_id, subject_olympiad_id, registration_date, status, school_id)
-			VALUES (?, ?, ?, 'registered', ?)`,
-			student.ID, request.SubjectOlympiadID, now.Format("2006-01-02 15:04:05"), student.SchoolID)
+			(student_id, subject_olympiad_id, registration_date, status, school_id, is_late)
+			VALUES (?, ?, ?, ?, ?, ?)`,
+			student.ID, request.SubjectOlympiadID, now.Format("2006-01-02 15:04:05"), newStatus, student.SchoolID, isLate)
 		if err != nil {
 			log.Printf("Failed to insert registration: %v", err)
 			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Registration failed"})
@@ -158,6 +163,26 @@ func (c *OlympiadRegistrationController) RegisterStudent(db *sql.DB) http.Handle
 
 		regID, _ := result.LastInsertId()
 
+		_, err = tx.Exec("UPDATE subject_olympiads SET current_participants = ? WHERE subject_olympiad_id = ?", taken, request.SubjectOlympiadID)
+		if err != nil {
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Registration failed"})
+			return
+		}
+
+		var waitlistPosition int
+		if newStatus == "waitlisted" {
+			waitlistPosition, err = olympiadQueue.waitlistPosition(tx, request.SubjectOlympiadID, int(regID))
+			if err != nil {
+				utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Registration failed"})
+				return
+			}
+		}
+
+		if err := tx.Commit(); err != nil {
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Registration failed"})
+			return
+		}
+
 		var registration models.OlympiadRegistration
 		var regDateStr string
 		var olympiadEnd sql.NullString
@@ -192,8 +217,10 @@ func (c *OlympiadRegistrationController) RegisterStudent(db *sql.DB) http.Handle
 				OlympiadsRegistrationsID: int(regID),
 				StudentID:                student.ID,
 				SubjectOlympiadID:        request.SubjectOlympiadID,
-				Status:                   "registered",
+				Status:                   newStatus,
 				SchoolID:                 student.SchoolID,
+				WaitlistPosition:         waitlistPosition,
+				IsLate:                   isLate,
 			})
 			return
 		}
@@ -208,6 +235,8 @@ func (c *OlympiadRegistrationController) RegisterStudent(db *sql.DB) http.Handle
 		} else {
 			registration.Level = ""
 		}
+		registration.WaitlistPosition = waitlistPosition
+		registration.IsLate = isLate
 
 		utils.ResponseJSON(w, registration)
 	}
@@ -222,9 +251,8 @@ func (c *OlympiadRegistrationController) GetOlympiadRegistrations(db *sql.DB) ht
 		}
 
 		var role string
-		var schoolID sql.NullInt64
 
-		err = db.QueryRow("SELECT role, school_id FROM users WHERE id = ?", userID).Scan(&role, &schoolID)
+		err = db.QueryRow("SELECT role FROM users WHERE id = ?", userID).Scan(&role)
 		if err != nil {
 			log.Printf("User with ID %d not found", userID)
 			utils.RespondWithError(w, http.StatusForbidden, models.Error{Message: "User not found"})
@@ -238,15 +266,16 @@ func (c *OlympiadRegistrationController) GetOlympiadRegistrations(db *sql.DB) ht
 
 		query := `
             SELECT r.olympiads_registrations_id, r.student_id, r.subject_olympiad_id, r.registration_date, r.status,
-                   r.school_id, r.document_url,
+                   r.school_id, res.document_url,
                    s.first_name, s.last_name, s.patronymic, s.grade, s.letter,
                    sch.school_name,
                    o.subject_name, o.date, o.end_date, o.level,
-                   r.score, r.olympiad_place
+                   res.score, res.olympiad_place
             FROM olympiad_registrations r
             JOIN student s ON r.student_id = s.student_id
             JOIN subject_olympiads o ON r.subject_olympiad_id = o.subject_olympiad_id
             JOIN Schools sch ON r.school_id = sch.school_id
+            LEFT JOIN Olympiads res ON res.registration_id = r.olympiads_registrations_id
             WHERE 1=1`
 
 		var params []interface{}
@@ -258,12 +287,13 @@ func (c *OlympiadRegistrationController) GetOlympiadRegistrations(db *sql.DB) ht
 				params = append(params, studentID)
 			}
 		case "schooladmin":
-			if !schoolID.Valid {
+			schoolID, err := getMemberSchoolID(db, userID, "")
+			if err != nil || !schoolID.Valid {
 				utils.RespondWithError(w, http.StatusForbidden, models.Error{Message: "Missing school ID for schooladmin"})
 				return
 			}
-			query += " AND (r.school_id = ? OR o.created_by = ?)"
-			params = append(params, schoolID.Int64, userID)
+			query += " AND (r.school_id IN (SELECT school_id FROM school_members WHERE user_id = ?) OR o.created_by = ?)"
+			params = append(params, userID, userID)
 			if studentID != "" {
 				query += " AND r.student_id = ?"
 				params = append(params, studentID)
@@ -363,8 +393,7 @@ func (c *OlympiadRegistrationController) UpdateRegistrationStatus(db *sql.DB) ht
 		}
 
 		var role string
-		var userSchoolID sql.NullInt64
-		err = db.QueryRow("SELECT role, school_id FROM users WHERE id = ?", userID).Scan(&role, &userSchoolID)
+		err = db.QueryRow("SELECT role FROM users WHERE id = ?", userID).Scan(&role)
 		if err != nil || (role != "schooladmin" && role != "superadmin") {
 			utils.RespondWithError(w, http.StatusForbidden, models.Error{Message: "Access denied"})
 			return
@@ -412,12 +441,81 @@ func (c *OlympiadRegistrationController) UpdateRegistrationStatus(db *sql.DB) ht
 		// 	}
 		// }
 
-		// Обновление
-		_, err = db.Exec("UPDATE olympiad_registrations SET status = ? WHERE olympiads_registrations_id = ?", body.Status, regID)
+		// Участие с присвоенным местом нельзя снять сменой статуса
+		if !isParticipatingStatus(body.Status) {
+			var placed bool
+			err = db.QueryRow("SELECT EXISTS(SELECT 1 FROM Olympiads WHERE registration_id = ? AND olympiad_place IS NOT NULL)", regID).Scan(&placed)
+			if err != nil {
+				utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Failed to check olympiad result"})
+				return
+			}
+			if placed {
+				utils.RespondWithError(w, http.StatusConflict, models.Error{Message: "Place already assigned for this registration"})
+				return
+			}
+		}
+
+		// Обновление статуса и участия в одной транзакции
+		tx, err := db.Begin()
+		if err != nil {
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Failed to update status"})
+			return
+		}
+		defer tx.Rollback()
+
+		var subjectOlympiadID int
+		err = tx.QueryRow("SELECT subject_olympiad_id FROM olympiad_registrations WHERE olympiads_registrations_id = ?", regID).Scan(&subjectOlympiadID)
+		if err == sql.ErrNoRows {
+			utils.RespondWithError(w, http.StatusNotFound, models.Error{Message: "Registration not found"})
+			return
+		}
+		if err != nil {
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Failed to update status"})
+			return
+		}
+
+		// Строку олимпиады блокируем раньше заявки — в том же порядке,
+		// что и olympiadQueue.promote
+		limitCount, taken, err := olympiadQueue.lock(tx, subjectOlympiadID)
+		if err != nil {
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Failed to update status"})
+			return
+		}
+		var currentStatus string
+		err = tx.QueryRow("SELECT status FROM olympiad_registrations WHERE olympiads_registrations_id = ? FOR UPDATE", regID).Scan(&currentStatus)
+		if err != nil {
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Failed to update status"})
+			return
+		}
+
+		// Заявку из листа ожидания можно принять только на свободное место
+		if currentStatus == "waitlisted" && body.Status != "rejected" && body.Status != "canceled" && !hasFreeSeat(limitCount, taken) {
+			utils.RespondWithError(w, http.StatusConflict, models.Error{Message: "Olympiad is full, the registration stays on the waitlist"})
+			return
+		}
+
+		_, err = tx.Exec("UPDATE olympiad_registrations SET status = ? WHERE olympiads_registrations_id = ?", body.Status, regID)
 		if err != nil {
 			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Failed to update status"})
 			return
 		}
+		if err := syncRegistrationResult(tx, regID, body.Status); err != nil {
+			log.Printf("Failed to sync olympiad result for registration %d: %v", regID, err)
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Failed to update status"})
+			return
+		}
+		// Отклонение или отмена освобождает место для листа ожидания
+		promotions, err := olympiadQueue.promote(tx, subjectOlympiadID)
+		if err != nil {
+			log.Printf("Failed to promote waitlist for olympiad %d: %v", subjectOlympiadID, err)
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Failed to update status"})
+			return
+		}
+		if err := tx.Commit(); err != nil {
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Failed to update status"})
+			return
+		}
+		emailWaitlistPromotions(db, promotions)
 
 		// Получаем обновлённую запись
 		var registration models.OlympiadRegistration
@@ -481,8 +579,7 @@ func (c *OlympiadRegistrationController) AssignPlaceToRegistration(db *sql.DB) h
 		}
 
 		var role string
-		var userSchoolID sql.NullInt64
-		err = db.QueryRow("SELECT role, school_id FROM users WHERE id = ?", userID).Scan(&role, &userSchoolID)
+		err = db.QueryRow("SELECT role FROM users WHERE id = ?", userID).Scan(&role)
 		if err != nil || (role != "schooladmin" && role != "superadmin") {
 			utils.RespondWithError(w, http.StatusForbidden, models.Error{Message: "Access denied"})
 			return
@@ -566,10 +663,12 @@ func (c *OlympiadRegistrationController) AssignPlaceToRegistration(db *sql.DB) h
 
 		// Check access for schooladmin
 		if role == "schooladmin" {
-			hasAccess := false
-			if userSchoolID.Valid && schoolID == int(userSchoolID.Int64) {
-				hasAccess = true
-			} else if createdBy.Valid && int(createdBy.Int64) == userID {
+			hasAccess, err := hasSchoolPermission(db, userID, schoolID, PermissionManageEvents)
+			if err != nil {
+				utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Failed to check permissions"})
+				return
+			}
+			if createdBy.Valid && int(createdBy.Int64) == userID {
 				hasAccess = true
 			}
 			if !hasAccess {
@@ -578,11 +677,15 @@ func (c *OlympiadRegistrationController) AssignPlaceToRegistration(db *sql.DB) h
 			}
 		}
 
-		// Check if place is already assigned
+		// Place is stored on the participation row in Olympiads
+		if err := syncRegistrationResult(db, regID, status); err != nil {
+			log.Printf("Failed to sync olympiad result for registration %d: %v", regID, err)
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Failed to assign place"})
+			return
+		}
+
 		var existingPlace sql.NullInt64
-		err = db.QueryRow(`
-            SELECT olympiad_place FROM olympiad_registrations WHERE olympiads_registrations_id = ?
-        `, regID).Scan(&existingPlace)
+		err = db.QueryRow("SELECT olympiad_place FROM Olympiads WHERE registration_id = ?", regID).Scan(&existingPlace)
 		if err != nil {
 			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Failed to check existing place"})
 			return
@@ -592,37 +695,12 @@ func (c *OlympiadRegistrationController) AssignPlaceToRegistration(db *sql.DB) h
 			return
 		}
 
-		// Calculate score based on place
-		score := 0
-		switch place {
-		case 1:
-			score = 50
-		case 2:
-			score = 30
-		case 3:
-			score = 20
-		}
-
-		// Update database
-		var query string
-		var args []interface{}
-		if documentURL != "" {
-			query = `
-                UPDATE olympiad_registrations 
-                SET olympiad_place = ?, score = ?, document_url = ? 
-                WHERE olympiads_registrations_id = ?
-            `
-			args = []interface{}{place, score, documentURL, regID}
-		} else {
-			query = `
-                UPDATE olympiad_registrations 
-                SET olympiad_place = ?, score = ? 
-                WHERE olympiads_registrations_id = ?
-            `
-			args = []interface{}{place, score, regID}
-		}
-
-		_, err = db.Exec(query, args...)
+		score := olympiadPlaceScore(place)
+		_, err = db.Exec(`
+            UPDATE Olympiads
+            SET olympiad_place = ?, score = ?, document_url = COALESCE(NULLIF(?, ''), document_url)
+            WHERE registration_id = ?
+        `, place, score, documentURL, regID)
 		if err != nil {
 			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Failed to assign place"})
 			return
@@ -666,22 +744,149 @@ func (c *OlympiadRegistrationController) DeleteRegistration(db *sql.DB) http.Han
 			return
 		}
 
-		// Удаление
-		result, err := db.Exec("DELETE FROM olympiad_registrations WHERE olympiads_registrations_id = ?", regID)
+		tx, err := db.Begin()
 		if err != nil {
 			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Failed to delete registration"})
 			return
 		}
+		defer tx.Rollback()
 
-		affected, _ := result.RowsAffected()
-		if affected == 0 {
+		var subjectOlympiadID int
+		err = tx.QueryRow("SELECT subject_olympiad_id FROM olympiad_registrations WHERE olympiads_registrations_id = ?", regID).Scan(&subjectOlympiadID)
+		if err == sql.ErrNoRows {
 			utils.RespondWithError(w, http.StatusNotFound, models.Error{Message: "Registration not found"})
 			return
 		}
+		if err != nil {
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Failed to delete registration"})
+			return
+		}
+
+		// Строку олимпиады блокируем раньше заявки — в том же порядке,
+		// что и olympiadQueue.promote
+		if _, _, err := olympiadQueue.lock(tx, subjectOlympiadID); err != nil {
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Failed to delete registration"})
+			return
+		}
+
+		// Удаление участия и заявки
+		if _, err := tx.Exec("DELETE FROM Olympiads WHERE registration_id = ?", regID); err != nil {
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Failed to delete registration"})
+			return
+		}
+		if _, err := tx.Exec("DELETE FROM olympiad_registrations WHERE olympiads_registrations_id = ?", regID); err != nil {
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Failed to delete registration"})
+			return
+		}
+
+		promotions, err := olympiadQueue.promote(tx, subjectOlympiadID)
+		if err != nil {
+			log.Printf("Failed to promote waitlist for olympiad %d: %v", subjectOlympiadID, err)
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Failed to delete registration"})
+			return
+		}
+		if err := tx.Commit(); err != nil {
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Failed to delete registration"})
+			return
+		}
+		emailWaitlistPromotions(db, promotions)
 
 		utils.ResponseJSON(w, map[string]string{"message": "Registration deleted successfully"})
 	}
 }
+
+// CancelMyOlympiadRegistration — ученик сам отменяет свою заявку до
+// cancellation_deadline олимпиады.
+func (c *OlympiadRegistrationController) CancelMyOlympiadRegistration(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := utils.VerifyToken(r)
+		if err != nil {
+			utils.RespondWithError(w, http.StatusUnauthorized, models.Error{Message: "Unauthorized"})
+			return
+		}
+
+		regID, err := strconv.Atoi(mux.Vars(r)["id"])
+		if err != nil || regID <= 0 {
+			utils.RespondWithError(w, http.StatusBadRequest, models.Error{Message: "Invalid registration ID"})
+			return
+		}
+
+		tx, err := db.Begin()
+		if err != nil {
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Failed to cancel registration"})
+			return
+		}
+		defer tx.Rollback()
+
+		var regStudentID, subjectOlympiadID int
+		err = tx.QueryRow("SELECT student_id, subject_olympiad_id FROM olympiad_registrations WHERE olympiads_registrations_id = ?", regID).
+			Scan(&regStudentID, &subjectOlympiadID)
+		if err == sql.ErrNoRows {
+			utils.RespondWithError(w, http.StatusNotFound, models.Error{Message: "Registration not found"})
+			return
+		}
+		if err != nil {
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Failed to cancel registration"})
+			return
+		}
+		// Строку олимпиады блокируем раньше заявки — в том же порядке,
+		// что и olympiadQueue.promote
+		if _, _, err := olympiadQueue.lock(tx, subjectOlympiadID); err != nil {
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Failed to cancel registration"})
+			return
+		}
+
+		if regStudentID != userID {
+			utils.RespondWithError(w, http.StatusForbidden, models.Error{Message: "You can only cancel your own registration"})
+			return
+		}
+
+		window, err := loadOlympiadWindow(tx, subjectOlympiadID)
+		if err != nil {
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Failed to cancel registration"})
+			return
+		}
+		if !window.canCancel(time.Now()) {
+			utils.RespondWithError(w, http.StatusForbidden, models.Error{Message: "Cancellation deadline has passed"})
+			return
+		}
+
+		var placed bool
+		err = tx.QueryRow("SELECT EXISTS(SELECT 1 FROM Olympiads WHERE registration_id = ? AND olympiad_place IS NOT NULL)", regID).Scan(&placed)
+		if err != nil {
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Failed to cancel registration"})
+			return
+		}
+		if placed {
+			utils.RespondWithError(w, http.StatusConflict, models.Error{Message: "Place already assigned for this registration"})
+			return
+		}
+
+		_, err = tx.Exec("UPDATE olympiad_registrations SET status = 'canceled' WHERE olympiads_registrations_id = ?", regID)
+		if err != nil {
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Failed to cancel registration"})
+			return
+		}
+		if err := syncRegistrationResult(tx, regID, "canceled"); err != nil {
+			log.Printf("Failed to sync olympiad result for registration %d: %v", regID, err)
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Failed to cancel registration"})
+			return
+		}
+		promotions, err := olympiadQueue.promote(tx, subjectOlympiadID)
+		if err != nil {
+			log.Printf("Failed to promote waitlist for olympiad %d: %v", subjectOlympiadID, err)
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Failed to cancel registration"})
+			return
+		}
+		if err := tx.Commit(); err != nil {
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Failed to cancel registration"})
+			return
+		}
+		emailWaitlistPromotions(db, promotions)
+
+		utils.ResponseJSON(w, map[string]string{"message": "Registration canceled successfully"})
+	}
+}
 func (c *OlympiadRegistrationController) GetTotalOlympiadRating(db *sql.DB) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodGet {
@@ -698,15 +903,14 @@ func (c *OlympiadRegistrationController) GetTotalOlympiadRating(db *sql.DB) http
 
 		// Get user role and school ID
 		var role string
-		var userSchoolID sql.NullInt64
-		err = db.QueryRow("SELECT role, school_id FROM users WHERE id = ?", userID).Scan(&role, &userSchoolID)
+		err = db.QueryRow("SELECT role FROM users WHERE id = ?", userID).Scan(&role)
 		if err != nil {
 			log.Printf("User with ID %d not found in users table", userID)
 			utils.RespondWithError(w, http.StatusForbidden, models.Error{Message: "User not found"})
 			return
 		}
 
-		log.Printf("Authenticated user: ID=%d, role=%s, school_id=%v", userID, role, userSchoolID)
+		log.Printf("Authenticated user: ID=%d, role=%s", userID, role)
 
 		// Handle school ID based on role
 		var schoolID int
@@ -729,12 +933,22 @@ func (c *OlympiadRegistrationController) GetTotalOlympiadRating(db *sql.DB) http
 				schoolID = parsedID
 			}
 		} else if role == "schooladmin" {
-			// For schooladmin, use their associated school
-			if !userSchoolID.Valid || userSchoolID.Int64 <= 0 {
-				utils.RespondWithError(w, http.StatusForbidden, models.Error{Message: "School not assigned to this administrator"})
-				return
+			// For schooladmin, use the requested school if they are a member, otherwise their first school
+			if parsedID, err := strconv.Atoi(mux.Vars(r)["school_id"]); err == nil && parsedID > 0 {
+				isMember, err := hasSchoolPermission(db, userID, parsedID, "")
+				if err != nil || !isMember {
+					utils.RespondWithError(w, http.StatusForbidden, models.Error{Message: "School not assigned to this administrator"})
+					return
+				}
+				schoolID = parsedID
+			} else {
+				userSchoolID, err := getMemberSchoolID(db, userID, "")
+				if err != nil || !userSchoolID.Valid {
+					utils.RespondWithError(w, http.StatusForbidden, models.Error{Message: "School not assigned to this administrator"})
+					return
+				}
+				schoolID = int(userSchoolID.Int64)
 			}
-			schoolID = int(userSchoolID.Int64)
 		} else {
 			utils.RespondWithError(w, http.StatusForbidden, models.Error{Message: "Access denied for role: " + role})
 			return
@@ -788,15 +1002,14 @@ func (c *OlympiadRegistrationController) GetTotalOlympiadRankBySchoolID(db *sql.
 
 		// Get user role and school ID
 		var role string
-		var userSchoolID sql.NullInt64
-		err = db.QueryRow("SELECT role, school_id FROM users WHERE id = ?", userID).Scan(&role, &userSchoolID)
+		err = db.QueryRow("SELECT role FROM users WHERE id = ?", userID).Scan(&role)
 		if err != nil {
 			log.Printf("User with ID %d not found in users table", userID)
 			utils.RespondWithError(w, http.StatusForbidden, models.Error{Message: "User not found"})
 			return
 		}
 
-		log.Printf("Authenticated user: ID=%d, role=%s, school_id=%v", userID, role, userSchoolID)
+		log.Printf("Authenticated user: ID=%d, role=%s", userID, role)
 
 		// Handle school ID based on role
 		var schoolID int
@@ -819,12 +1032,22 @@ func (c *OlympiadRegistrationController) GetTotalOlympiadRankBySchoolID(db *sql.
 				schoolID = parsedID
 			}
 		} else if role == "schooladmin" {
-			// For schooladmin, use their associated school
-			if !userSchoolID.Valid || userSchoolID.Int64 <= 0 {
-				utils.RespondWithError(w, http.StatusForbidden, models.Error{Message: "School not assigned to this administrator"})
-				return
+			// For schooladmin, use the requested school if they are a member, otherwise their first school
+			if parsedID, err := strconv.Atoi(mux.Vars(r)["school_id"]); err == nil && parsedID > 0 {
+				isMember, err := hasSchoolPermission(db, userID, parsedID, "")
+				if err != nil || !isMember {
+					utils.RespondWithError(w, http.StatusForbidden, models.Error{Message: "School not assigned to this administrator"})
+					return
+				}
+				schoolID = parsedID
+			} else {
+				userSchoolID, err := getMemberSchoolID(db, userID, "")
+				if err != nil || !userSchoolID.Valid {
+					utils.RespondWithError(w, http.StatusForbidden, models.Error{Message: "School not assigned to this administrator"})
+					return
+				}
+				schoolID = int(userSchoolID.Int64)
 			}
-			schoolID = int(userSchoolID.Int64)
 		} else {
 			utils.RespondWithError(w, http.StatusForbidden, models.Error{Message: "Access denied for role: " + role})
 			return
@@ -897,19 +1120,11 @@ func calculateOlympiadRatingByLevel(db *sql.DB, schoolID int, level string, weig
 			continue
 		}
 
-		// Use the calculated score if available
+		// Use the stored score if available, otherwise derive it from the place
 		if score.Valid && score.Int64 > 0 {
 			totalScore += float64(score.Int64)
 		} else {
-			// Otherwise calculate based on place
-			switch place.Int64 {
-			case 1:
-				totalScore += 50
-			case 2:
-				totalScore += 30
-			case 3:
-				totalScore += 20
-			}
+			totalScore += float64(olympiadPlaceScore(int(place.Int64)))
 		}
 		count++
 	}
@@ -936,11 +1151,11 @@ func (c *OlympiadRegistrationController) GetOverallOlympiadParticipationCount(db
 			return
 		}
 
-		// Подсчёт всех регистраций
+		// Подсчёт всех участий: внешних результатов и принятых заявок
 		var total int
-		err = db.QueryRow("SELECT COUNT(*) FROM olympiad_registrations").Scan(&total)
+		err = db.QueryRow("SELECT COUNT(*) FROM Olympiads").Scan(&total)
 		if err != nil {
-			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Failed to count registrations"})
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Failed to count participations"})
 			return
 		}
 
@@ -1121,15 +1336,16 @@ func (c *OlympiadRegistrationController) GetOlympiadRegistrationByID(db *sql.DB)
 
 		err = db.QueryRow(`
 			SELECT r.olympiads_registrations_id, r.student_id, r.subject_olympiad_id, r.registration_date, r.status,
-				   r.school_id, r.document_url,
+				   r.school_id, res.document_url,
 				   s.first_name, s.last_name, s.patronymic, s.grade, s.letter,
 				   sch.school_name,
 				   o.subject_name, o.date, o.end_date, o.level,
-				   r.score, r.olympiad_place
+				   res.score, res.olympiad_place
 			FROM olympiad_registrations r
 			JOIN student s ON r.student_id = s.student_id
 			JOIN subject_olympiads o ON r.subject_olympiad_id = o.subject_olympiad_id
 			JOIN Schools sch ON r.school_id = sch.school_id
+			LEFT JOIN Olympiads res ON res.registration_id = r.olympiads_registrations_id
 			WHERE r.olympiads_registrations_id = ?`, id).Scan(
 			&reg.OlympiadsRegistrationsID,
 			&reg.StudentID,
@@ -1197,15 +1413,16 @@ func (c *OlympiadRegistrationController) GetOlympiadRegistrationsBySchoolID(db *
 		// Query registrations for the given school_id
 		rows, err := db.Query(`
 			SELECT r.olympiads_registrations_id, r.student_id, r.subject_olympiad_id, r.registration_date, r.status,
-				   r.school_id, r.document_url,
+				   r.school_id, res.document_url,
 				   s.first_name, s.last_name, s.patronymic, s.grade, s.letter,
 				   sch.school_name,
 				   o.subject_name, o.date, o.end_date, o.level,
-				   r.score, r.olympiad_place
+				   res.score, res.olympiad_place
 			FROM olympiad_registrations r
 			JOIN student s ON r.student_id = s.student_id
 			JOIN subject_olympiads o ON r.subject_olympiad_id = o.subject_olympiad_id
 			JOIN Schools sch ON r.school_id = sch.school_id
+			LEFT JOIN Olympiads res ON res.registration_id = r.olympiads_registrations_id
 			WHERE r.school_id = ?`, schoolID)
 		if err != nil {
 			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Error querying registrations"})
@@ -1356,6 +1573,7 @@ func (c *OlympiadRegistrationController) GetOlympiadRegistrationsByOlympID(db *s
 			JOIN student s ON r.student_id = s.student_id
 			JOIN subject_olympiads o ON r.subject_olympiad_id = o.subject_olympiad_id
 			JOIN Schools sch ON r.school_id = sch.school_id
+			LEFT JOIN Olympiads res ON res.registration_id = r.olympiads_registrations_id
 			WHERE r.subject_olympiad_id = ?
 		`, subjectOlympiadID)
 		if err != nil {
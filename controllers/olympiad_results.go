@@ -0,0 +1,43 @@
+package controllers
+
+// Результаты олимпиад хранятся в одной таблице Olympiads: внешние результаты
+// (source = 'external') вносит школа, участия во внутренних олимпиадах
+// (source = 'internal') создаются из заявок olympiad_registrations.
+// Рейтинги, история и статистика читают только Olympiads.
+
+// olympiadPlaceScore — баллы за призовое место.
+func olympiadPlaceScore(place int) int {
+	switch place {
+	case 1:
+		return 50
+	case 2:
+		return 30
+	case 3:
+		return 20
+	}
+	return 0
+}
+
+// isParticipatingStatus — статусы заявки, при которых ученик считается
+// участником внутренней олимпиады.
+func isParticipatingStatus(status string) bool {
+	return status == "accepted" || status == "completed"
+}
+
+// syncRegistrationResult приводит участие в Olympiads в соответствие со
+// статусом заявки: принятая заявка получает строку участия, отклонённая
+// или отменённая — теряет её.
+func syncRegistrationResult(db execQuerier, regID int, status string) error {
+	if !isParticipatingStatus(status) {
+		_, err := db.Exec("DELETE FROM Olympiads WHERE registration_id = ?", regID)
+		return err
+	}
+	_, err := db.Exec(`
+		INSERT INTO Olympiads (student_id, school_id, level, olympiad_name, date, source, subject_olympiad_id, registration_id)
+		SELECT r.student_id, r.school_id, so.level, so.subject_name, so.date, 'internal', so.subject_olympiad_id, r.olympiads_registrations_id
+		FROM olympiad_registrations r
+		JOIN subject_olympiads so ON so.subject_olympiad_id = r.subject_olympiad_id
+		WHERE r.olympiads_registrations_id = ?
+		ON DUPLICATE KEY UPDATE registration_id = registration_id`, regID)
+	return err
+}
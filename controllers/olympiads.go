@@ -7,9 +7,7 @@ import (
 	"fmt"
 	"io"
 	"log"
-	"mime/multipart"
 	"net/http"
-	"os"
 	"path/filepath"
 	"ranking-school/models"
 	"ranking-school/utils"
@@ -17,10 +15,6 @@ import (
 	"strings"
 	"time"
 
-	"github.com/aws/aws-sdk-go/aws"
-	"github.com/aws/aws-sdk-go/aws/credentials"
-	"github.com/aws/aws-sdk-go/aws/session"
-	"github.com/aws/aws-sdk-go/service/s3"
 	"github.com/golang-jwt/jwt"
 	"github.com/gorilla/mux"
 )
@@ -156,95 +150,6 @@ func (oc *OlympiadController) CreateOlympiad(db *sql.DB) http.HandlerFunc {
 	}
 }
 
-func UploadFileToS3(file multipart.File, fileName string, fileType string) (string, error) {
-	var accessKey, secretKey, region, bucketName string
-
-	// Выбираем набор ключей и бакет в зависимости от типа файла
-	switch fileType {
-	case "avatar":
-		accessKey = os.Getenv("AWS_ACCESS_KEY2_ID")
-		secretKey = os.Getenv("AWS_SECRET_ACCESS2_KEY")
-		region = os.Getenv("AWS_REGION2")
-		bucketName = "avatarschoolrank" // Бакет для аватаров
-	case "schoolphoto":
-		accessKey = os.Getenv("AWS_ACCESS_KEY_ID")
-		secretKey = os.Getenv("AWS_SECRET_ACCESS_KEY")
-		region = os.Getenv("AWS_REGION")
-		bucketName = "schoolrank-schoolphotos" // Бакет для школьных фото
-	case "olympiaddoc":
-		accessKey = os.Getenv("AWS_ACCESS_KEY3_ID")
-		secretKey = os.Getenv("AWS_SECRET_ACCESS3_KEY")
-		region = os.Getenv("AWS_REGION3")
-		bucketName = "olympiaddocument" // Бакет для документов олимпиад
-	default:
-		return "", fmt.Errorf("unknown file type: %s", fileType)
-	}
-
-	// Проверяем, что ключи и регион заданы
-	if accessKey == "" || secretKey == "" || region == "" || bucketName == "" {
-		return "", fmt.Errorf("AWS credentials, region or bucket name not set in environment for %s", fileType)
-	}
-
-	// Создаем сессию с AWS
-	sess, err := session.NewSession(&aws.Config{
-		Region:      aws.String(region),
-		Credentials: credentials.NewStaticCredentials(accessKey, secretKey, ""),
-	})
-	if err != nil {
-		return "", fmt.Errorf("failed to create AWS session: %v", err)
-	}
-
-	// Создаем клиент для S3
-	svc := s3.New(sess)
-
-	// Считываем файл в буфер
-	buf := new(bytes.Buffer)
-	_, err = io.Copy(buf, file)
-	if err != nil {
-		return "", fmt.Errorf("failed to read file buffer: %v", err)
-	}
-
-	// Сброс указателя файла в начало (на случай, если файл читался ранее)
-	if seeker, ok := file.(io.Seeker); ok {
-		seeker.Seek(0, io.SeekStart)
-	}
-
-	// Определяем Content-Type на основе расширения файла
-	contentType := "application/octet-stream" // По умолчанию
-	if ext := filepath.Ext(fileName); ext != "" {
-		switch strings.ToLower(ext) {
-		case ".pdf":
-			contentType = "application/pdf"
-		case ".doc":
-			contentType = "application/msword"
-		case ".docx":
-			contentType = "application/vnd.openxmlformats-officedocument.wordprocessingml.document"
-		case ".jpg", ".jpeg":
-			contentType = "image/jpeg"
-		case ".png":
-			contentType = "image/png"
-		}
-	}
-
-	// Задаем параметры для загрузки
-	input := &s3.PutObjectInput{
-		Bucket:      aws.String(bucketName),
-		Key:         aws.String(fileName),
-		Body:        bytes.NewReader(buf.Bytes()),
-		ContentType: aws.String(contentType),
-		ACL:         aws.String("public-read"), // Делаем файл публично доступным
-	}
-
-	// Загружаем файл в S3
-	_, err = svc.PutObject(input)
-	if err != nil {
-		return "", fmt.Errorf("failed to upload file to S3: %v", err)
-	}
-
-	// Формируем URL для доступа к файлу
-	url := fmt.Sprintf("https://%s.s3.%s.amazonaws.com/%s", bucketName, region, fileName)
-	return url, nil
-}
 func (oc *OlympiadController) GetOlympiad(db *sql.DB) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		// Получаем userID из токена для проверки прав доступа
@@ -256,8 +161,7 @@ func (oc *OlympiadController) GetOlympiad(db *sql.DB) http.HandlerFunc {
 
 		// Получаем роль и школу пользователя
 		var userRole string
-		var userSchoolID sql.NullInt64
-		err = db.QueryRow("SELECT role, school_id FROM users WHERE id = ?", userID).Scan(&userRole, &userSchoolID)
+		err = db.QueryRow("SELECT role FROM users WHERE id = ?", userID).Scan(&userRole)
 		if err != nil {
 			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Failed to fetch user information"})
 			return
@@ -271,7 +175,7 @@ func (oc *OlympiadController) GetOlympiad(db *sql.DB) http.HandlerFunc {
 
 		// Базовая часть запроса с выборкой всех полей включая grade, letter и school_name
 		baseQuery := `SELECT 
-						Olympiads.olympiad_id, Olympiads.student_id, Olympiads.olympiad_place, Olympiads.score, 
+						Olympiads.olympiad_id, Olympiads.student_id, COALESCE(Olympiads.olympiad_place, 0), COALESCE(Olympiads.score, 0),
 						Olympiads.school_id, Olympiads.level, Olympiads.olympiad_name, Olympiads.document_url,
 						Olympiads.date, 
 						student.first_name, student.last_name, student.patronymic, 
@@ -284,10 +188,17 @@ func (oc *OlympiadController) GetOlympiad(db *sql.DB) http.HandlerFunc {
 		// Создаем условия запроса на основе параметров и роли пользователя
 		whereConditions := []string{}
 
-		// Для schooladmin показываем только данные из его школы
-		if userRole == "schooladmin" && userSchoolID.Valid {
-			whereConditions = append(whereConditions, "Olympiads.school_id = ?")
-			queryArgs = append(queryArgs, userSchoolID.Int64)
+		// Для schooladmin показываем только данные из его школ
+		if userRole == "schooladmin" {
+			userSchoolID, err := getMemberSchoolID(db, userID, "")
+			if err != nil {
+				utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Failed to fetch user information"})
+				return
+			}
+			if userSchoolID.Valid {
+				whereConditions = append(whereConditions, "Olympiads.school_id IN (SELECT school_id FROM school_members WHERE user_id = ?)")
+				queryArgs = append(queryArgs, userID)
+			}
 		}
 
 		// Добавляем фильтр по studentID, если указан
@@ -410,7 +321,7 @@ func (oc *OlympiadController) GetParticipantByOlympNameAndStudentID(db *sql.DB)
 
 		query := `
 			SELECT 
-				Olympiads.olympiad_id, Olympiads.student_id, Olympiads.olympiad_place, Olympiads.document_url,
+				Olympiads.olympiad_id, Olympiads.student_id, COALESCE(Olympiads.olympiad_place, 0), Olympiads.document_url,
 				Olympiads.olympiad_name, Olympiads.level, Olympiads.grade, Olympiads.letter,
 				Schools.school_name
 			FROM Olympiads
@@ -556,22 +467,13 @@ func (oc *OlympiadController) DeleteOlympiad(db *sql.DB) http.HandlerFunc {
 			return
 		}
 
-		var directorSchoolID int
-		if userRole == "schooladmin" {
-			err = db.QueryRow("SELECT school_id FROM users WHERE id = ?", userID).Scan(&directorSchoolID)
-			if err != nil {
-				utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Failed to fetch user school"})
-				return
-			}
-		}
-
 		// Шаг 4: Получаем данные участника ДО удаления
-		var studentID, olympiadSchoolID int
-		var olympiadName string
+		var olympiadSchoolID int
+		var registrationID sql.NullInt64
 		err = db.QueryRow(`
-			SELECT student_id, olympiad_name, school_id 
+			SELECT school_id, registration_id
 			FROM Olympiads 
-			WHERE olympiad_id = ?`, olympiadID).Scan(&studentID, &olympiadName, &olympiadSchoolID)
+			WHERE olympiad_id = ?`, olympiadID).Scan(&olympiadSchoolID, &registrationID)
 
 		if err == sql.ErrNoRows {
 			utils.RespondWithError(w, http.StatusNotFound, models.Error{Message: "Participant not found"})
@@ -584,9 +486,16 @@ func (oc *OlympiadController) DeleteOlympiad(db *sql.DB) http.HandlerFunc {
 		}
 
 		// Проверка прав доступа
-		if userRole == "schooladmin" && olympiadSchoolID != directorSchoolID {
-			utils.RespondWithError(w, http.StatusForbidden, models.Error{Message: "You do not have permission to delete this participant"})
-			return
+		if userRole == "schooladmin" {
+			allowed, err := hasSchoolPermission(db, userID, olympiadSchoolID, PermissionManageEvents)
+			if err != nil {
+				utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Failed to fetch user school"})
+				return
+			}
+			if !allowed {
+				utils.RespondWithError(w, http.StatusForbidden, models.Error{Message: "You do not have permission to delete this participant"})
+				return
+			}
 		}
 
 		// Шаг 5: Удаление участника из таблицы Olympiads
@@ -597,17 +506,14 @@ func (oc *OlympiadController) DeleteOlympiad(db *sql.DB) http.HandlerFunc {
 			return
 		}
 
-		// Шаг 6: Обновление статуса регистрации
-		_, err = db.Exec(`
-			UPDATE olympiad_registrations r
-			JOIN subject_olympiads s ON r.subject_olympiad_id = s.subject_olympiad_id
-			SET r.status = 'registered'
-			WHERE r.student_id = ? AND s.subject_name = ?`, studentID, olympiadName)
-
-		if err != nil {
-			log.Println("Error updating registration status:", err)
-			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Failed to update registration status"})
-			return
+		// Шаг 6: Участие во внутренней олимпиаде возвращает заявку в статус registered
+		if registrationID.Valid {
+			_, err = db.Exec("UPDATE olympiad_registrations SET status = 'registered' WHERE olympiads_registrations_id = ?", registrationID.Int64)
+			if err != nil {
+				log.Println("Error updating registration status:", err)
+				utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Failed to update registration status"})
+				return
+			}
 		}
 
 		utils.ResponseJSON(w, map[string]string{
@@ -812,8 +718,7 @@ func (oc *OlympiadController) UpdateOlympiad(db *sql.DB) http.HandlerFunc {
 
 		// Проверка роли пользователя и школы
 		var userRole string
-		var userSchoolID sql.NullInt64
-		err = db.QueryRow("SELECT role, school_id FROM users WHERE id = ?", userID).Scan(&userRole, &userSchoolID)
+		err = db.QueryRow("SELECT role FROM users WHERE id = ?", userID).Scan(&userRole)
 		if err != nil {
 			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Failed to fetch user information"})
 			return
@@ -829,9 +734,16 @@ func (oc *OlympiadController) UpdateOlympiad(db *sql.DB) http.HandlerFunc {
 		}
 
 		// Проверка доступа: schooladmin может редактировать только своей школы
-		if userRole == "schooladmin" && (!userSchoolID.Valid || int(userSchoolID.Int64) != currentSchoolID) {
-			utils.RespondWithError(w, http.StatusForbidden, models.Error{Message: "You can only edit olympiads for your school"})
-			return
+		if userRole == "schooladmin" {
+			allowed, err := hasSchoolPermission(db, userID, currentSchoolID, PermissionManageEvents)
+			if err != nil {
+				utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Failed to fetch user information"})
+				return
+			}
+			if !allowed {
+				utils.RespondWithError(w, http.StatusForbidden, models.Error{Message: "You can only edit olympiads for your school"})
+				return
+			}
 		}
 
 		// Если меняется ученик, проверяем принадлежность к школе
@@ -844,9 +756,12 @@ func (oc *OlympiadController) UpdateOlympiad(db *sql.DB) http.HandlerFunc {
 			}
 
 			// Школьный админ может назначать только учеников своей школы
-			if userRole == "schooladmin" && (!userSchoolID.Valid || int(userSchoolID.Int64) != studentSchoolID) {
-				utils.RespondWithError(w, http.StatusForbidden, models.Error{Message: "Student does not belong to your school"})
-				return
+			if userRole == "schooladmin" {
+				allowed, err := hasSchoolPermission(db, userID, studentSchoolID, PermissionManageEvents)
+				if err != nil || !allowed {
+					utils.RespondWithError(w, http.StatusForbidden, models.Error{Message: "Student does not belong to your school"})
+					return
+				}
 			}
 		}
 
@@ -960,8 +875,7 @@ func (oc *OlympiadController) GetOlympiadById(db *sql.DB) http.HandlerFunc {
 
 		// Получаем роль и школу пользователя
 		var userRole string
-		var userSchoolID sql.NullInt64
-		err = db.QueryRow("SELECT role, school_id FROM users WHERE id = ?", userID).Scan(&userRole, &userSchoolID)
+		err = db.QueryRow("SELECT role FROM users WHERE id = ?", userID).Scan(&userRole)
 		if err != nil {
 			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Failed to fetch user information"})
 			return
@@ -979,7 +893,7 @@ func (oc *OlympiadController) GetOlympiadById(db *sql.DB) http.HandlerFunc {
 
 		// Базовая часть запроса с выборкой всех полей включая grade, letter и school_name
 		baseQuery := `SELECT 
-						Olympiads.olympiad_id, Olympiads.student_id, Olympiads.olympiad_place, Olympiads.score, 
+						Olympiads.olympiad_id, Olympiads.student_id, COALESCE(Olympiads.olympiad_place, 0), COALESCE(Olympiads.score, 0),
 						Olympiads.school_id, Olympiads.level, Olympiads.olympiad_name, Olympiads.document_url,
 						Olympiads.date, 
 						student.first_name, student.last_name, student.patronymic, 
@@ -992,10 +906,17 @@ func (oc *OlympiadController) GetOlympiadById(db *sql.DB) http.HandlerFunc {
 		// Создаем условия запроса на основе параметров и роли пользователя
 		whereConditions := []string{}
 
-		// Для schooladmin показываем только данные из его школы
-		if userRole == "schooladmin" && userSchoolID.Valid {
-			whereConditions = append(whereConditions, "Olympiads.school_id = ?")
-			queryArgs = append(queryArgs, userSchoolID.Int64)
+		// Для schooladmin показываем только данные из его школ
+		if userRole == "schooladmin" {
+			userSchoolID, err := getMemberSchoolID(db, userID, "")
+			if err != nil {
+				utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Failed to fetch user information"})
+				return
+			}
+			if userSchoolID.Valid {
+				whereConditions = append(whereConditions, "Olympiads.school_id IN (SELECT school_id FROM school_members WHERE user_id = ?)")
+				queryArgs = append(queryArgs, userID)
+			}
 		}
 
 		// Добавляем фильтр по studentID, если указан
@@ -1015,9 +936,16 @@ func (oc *OlympiadController) GetOlympiadById(db *sql.DB) http.HandlerFunc {
 			schoolID, err := strconv.Atoi(schoolIDParam)
 			if err == nil {
 				// Проверяем права пользователя - schooladmin может просматривать только свою школу
-				if userRole == "schooladmin" && userSchoolID.Valid && int(userSchoolID.Int64) != schoolID {
-					utils.RespondWithError(w, http.StatusForbidden, models.Error{Message: "You can only view olympiads for your school"})
-					return
+				if userRole == "schooladmin" {
+					isMember, err := hasSchoolPermission(db, userID, schoolID, "")
+					if err != nil {
+						utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Failed to fetch user information"})
+						return
+					}
+					if !isMember {
+						utils.RespondWithError(w, http.StatusForbidden, models.Error{Message: "You can only view olympiads for your school"})
+						return
+					}
 				}
 				whereConditions = append(whereConditions, "Olympiads.school_id = ?")
 				queryArgs = append(queryArgs, schoolID)
@@ -1129,8 +1057,8 @@ func (oc *OlympiadController) GetOlympiadBySchoolId(db *sql.DB) http.HandlerFunc
 
 		// 3. Получаем список олимпиад для указанной школы с добавлением grade и letter
 		query := `
-			SELECT o.olympiad_id, o.student_id, s.first_name, s.last_name, o.olympiad_place, 
-			       o.score, o.level, o.school_id, o.olympiad_name, o.document_url,
+			SELECT o.olympiad_id, o.student_id, s.first_name, s.last_name, COALESCE(o.olympiad_place, 0),
+			       COALESCE(o.score, 0), o.level, o.school_id, o.olympiad_name, o.document_url,
 			       s.grade, s.letter
 			FROM Olympiads o
 			JOIN student s ON o.student_id = s.student_id
@@ -1239,7 +1167,7 @@ func (oc *OlympiadController) CalculateCityOlympiadRating(db *sql.DB) http.Handl
 
 		// Запрос для получения всех записей о городской олимпиаде для этой школы, только для level = 'city'
 		query := `
-            SELECT o.olympiad_place, o.student_id, o.score, o.school_id, o.level, s.first_name, s.last_name, s.patronymic
+            SELECT COALESCE(o.olympiad_place, 0), o.student_id, COALESCE(o.score, 0), o.school_id, o.level, s.first_name, s.last_name, s.patronymic
             FROM Olympiads o
             JOIN student s ON o.student_id = s.student_id
             WHERE o.school_id = ? AND o.level = 'city'
@@ -1315,7 +1243,7 @@ func (oc *OlympiadController) CalculateRegionalOlympiadRating(db *sql.DB) http.H
 
 		// Запрос для получения всех записей о региональной олимпиаде для этой школы, только для level = 'region'
 		query := `
-            SELECT o.olympiad_place, o.student_id, o.score, o.school_id, o.level, s.first_name, s.last_name, s.patronymic
+            SELECT COALESCE(o.olympiad_place, 0), o.student_id, COALESCE(o.score, 0), o.school_id, o.level, s.first_name, s.last_name, s.patronymic
             FROM Olympiads o
             JOIN student s ON o.student_id = s.student_id
             WHERE o.school_id = ? AND o.level = 'region'
@@ -1391,7 +1319,7 @@ func (oc *OlympiadController) CalculateRepublicanOlympiadRating(db *sql.DB) http
 
 		// Запрос для получения всех записей о республиканской олимпиаде для этой школы, только для level = 'republican'
 		query := `
-            SELECT o.olympiad_place, o.student_id, o.score, o.school_id, o.level, s.first_name, s.last_name, s.patronymic
+            SELECT COALESCE(o.olympiad_place, 0), o.student_id, COALESCE(o.score, 0), o.school_id, o.level, s.first_name, s.last_name, s.patronymic
             FROM Olympiads o
             JOIN student s ON o.student_id = s.student_id
             WHERE o.school_id = ? AND o.level = 'republican'
@@ -1467,7 +1395,7 @@ func (oc *OlympiadController) CalculateTotalOlympiadRating(db *sql.DB) http.Hand
 
 		// Запрос для получения всех записей об олимпиадах для этой школы, разделенных по уровням
 		query := `
-            SELECT o.olympiad_place, o.student_id, o.score, o.school_id, o.level, s.first_name, s.last_name, s.patronymic
+            SELECT COALESCE(o.olympiad_place, 0), o.student_id, COALESCE(o.score, 0), o.school_id, o.level, s.first_name, s.last_name, s.patronymic
             FROM Olympiads o
             JOIN student s ON o.student_id = s.student_id
             WHERE o.school_id = ?
@@ -1851,8 +1779,7 @@ func (oc *OlympiadController) GetCountParticipantsOlympiadBySchoolID(db *sql.DB)
 
 		// Step 2: Get user role and school_id
 		var userRole string
-		var userSchoolID sql.NullInt64
-		err = db.QueryRow("SELECT role, school_id FROM users WHERE id = ?", userID).Scan(&userRole, &userSchoolID)
+		err = db.QueryRow("SELECT role FROM users WHERE id = ?", userID).Scan(&userRole)
 		if err != nil {
 			log.Printf("Error fetching user details for user ID %d: %v", userID, err)
 			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Error fetching user details"})
@@ -1878,13 +1805,14 @@ func (oc *OlympiadController) GetCountParticipantsOlympiadBySchoolID(db *sql.DB)
 
 		// Step 5: Restrict schooladmin to their school
 		if userRole == "schooladmin" {
-			if !userSchoolID.Valid {
-				log.Printf("No school_id associated with schooladmin user ID %d", userID)
-				utils.RespondWithError(w, http.StatusForbidden, models.Error{Message: "No school assigned to this admin"})
+			isMember, err := hasSchoolPermission(db, userID, schoolID, "")
+			if err != nil {
+				log.Printf("Error checking membership for user ID %d: %v", userID, err)
+				utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Error fetching user details"})
 				return
 			}
-			if int(userSchoolID.Int64) != schoolID {
-				log.Printf("Schooladmin user ID %d attempted to access school ID %d, but is assigned to school ID %d", userID, schoolID, userSchoolID.Int64)
+			if !isMember {
+				log.Printf("Schooladmin user ID %d attempted to access school ID %d without membership", userID, schoolID)
 				utils.RespondWithError(w, http.StatusForbidden, models.Error{Message: "You do not have permission to view this school's data"})
 				return
 			}
@@ -1976,8 +1904,7 @@ func (oc *OlympiadController) GetOlympiadPrizeStatsBySchoolID(db *sql.DB) http.H
 
 		// Step 2: Get user role and school_id
 		var userRole string
-		var userSchoolID sql.NullInt64
-		err = db.QueryRow("SELECT role, school_id FROM users WHERE id = ?", userID).Scan(&userRole, &userSchoolID)
+		err = db.QueryRow("SELECT role FROM users WHERE id = ?", userID).Scan(&userRole)
 		if err != nil {
 			log.Printf("Error fetching user details for user ID %d: %v", userID, err)
 			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Error fetching user details"})
@@ -2003,13 +1930,14 @@ func (oc *OlympiadController) GetOlympiadPrizeStatsBySchoolID(db *sql.DB) http.H
 
 		// Step 5: Restrict schooladmin to their school
 		if userRole == "schooladmin" {
-			if !userSchoolID.Valid {
-				log.Printf("No school_id associated with schooladmin user ID %d", userID)
-				utils.RespondWithError(w, http.StatusForbidden, models.Error{Message: "No school assigned to this admin"})
+			isMember, err := hasSchoolPermission(db, userID, schoolID, "")
+			if err != nil {
+				log.Printf("Error checking membership for user ID %d: %v", userID, err)
+				utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Error fetching user details"})
 				return
 			}
-			if int(userSchoolID.Int64) != schoolID {
-				log.Printf("Schooladmin user ID %d attempted to access school ID %d, but is assigned to school ID %d", userID, schoolID, userSchoolID.Int64)
+			if !isMember {
+				log.Printf("Schooladmin user ID %d attempted to access school ID %d without membership", userID, schoolID)
 				utils.RespondWithError(w, http.StatusForbidden, models.Error{Message: "You do not have permission to view this school's data"})
 				return
 			}
@@ -2105,14 +2033,20 @@ func (oc *OlympiadController) GetOlympiadMonthlyStatsBySchoolID(db *sql.DB) http
 
 		// Step 3: Restrict schooladmin to their school
 		if role == "schooladmin" {
-			userSchoolID, ok := claims["school_id"].(float64) // JWT claims often store numbers as float64
-			if !ok || int(userSchoolID) <= 0 {
-				log.Printf("No valid school_id associated with schooladmin")
+			userID, ok := claims["user_id"].(float64) // JWT claims often store numbers as float64
+			if !ok || int(userID) <= 0 {
+				log.Printf("No valid user_id in schooladmin token")
 				utils.RespondWithError(w, http.StatusForbidden, models.Error{Message: "No school assigned to this admin"})
 				return
 			}
-			if int(userSchoolID) != schoolID {
-				log.Printf("Schooladmin attempted to access school ID %d, but is assigned to school ID %d", schoolID, int(userSchoolID))
+			isMember, err := hasSchoolPermission(db, int(userID), schoolID, "")
+			if err != nil {
+				log.Printf("Error checking membership for user ID %d: %v", int(userID), err)
+				utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Error fetching user details"})
+				return
+			}
+			if !isMember {
+				log.Printf("Schooladmin user ID %d attempted to access school ID %d without membership", int(userID), schoolID)
 				utils.RespondWithError(w, http.StatusForbidden, models.Error{Message: "You do not have permission to view this school's data"})
 				return
 			}
@@ -0,0 +1,422 @@
+package controllers
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"ranking-school/models"
+	"ranking-school/utils"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-pdf/fpdf"
+	"github.com/gorilla/mux"
+)
+
+type PortfolioController struct{}
+
+// Шрифт с кириллицей. Каталог можно переопределить через PORTFOLIO_FONT_DIR.
+const (
+	defaultPortfolioFontDir = "/usr/share/fonts/truetype/dejavu"
+	portfolioFontRegular    = "DejaVuSans.ttf"
+	portfolioFontBold       = "DejaVuSans-Bold.ttf"
+)
+
+type portfolioUNT struct {
+	ExamType   string `json:"exam_type"`
+	Date       string `json:"date"`
+	TotalScore int    `json:"total_score"`
+	Subjects   string `json:"subjects"`
+	Document   string `json:"document_url"`
+}
+
+type portfolioOlympiad struct {
+	Name     string `json:"name"`
+	Level    string `json:"level"`
+	Date     string `json:"date"`
+	Place    int    `json:"place"`
+	Score    int    `json:"score"`
+	School   string `json:"school_name"`
+	Document string `json:"document_url"`
+}
+
+type portfolioEvent struct {
+	Name     string `json:"name"`
+	Category string `json:"category"`
+	Date     string `json:"date"`
+	Role     string `json:"role"`
+	School   string `json:"school_name"`
+	Document string `json:"document_url"`
+}
+
+type portfolioData struct {
+	StudentID   int                 `json:"student_id"`
+	FirstName   string              `json:"first_name"`
+	LastName    string              `json:"last_name"`
+	Patronymic  string              `json:"patronymic"`
+	DateOfBirth string              `json:"date_of_birth"`
+	SchoolName  string              `json:"school_name"`
+	City        string              `json:"city"`
+	Grade       int                 `json:"grade"`
+	Letter      string              `json:"letter"`
+	Status      string              `json:"status"`
+	UNT         []portfolioUNT      `json:"unt"`
+	Olympiads   []portfolioOlympiad `json:"olympiads"`
+	Events      []portfolioEvent    `json:"events"`
+}
+
+// loadPortfolioData собирает профиль и все достижения ученика.
+func loadPortfolioData(db *sql.DB, studentID int) (portfolioData, error) {
+	p := portfolioData{StudentID: studentID}
+	err := db.QueryRow(`
+		SELECT COALESCE(s.first_name, ''), COALESCE(s.last_name, ''), COALESCE(s.patronymic, ''),
+		       COALESCE(CAST(s.date_of_birth AS CHAR), ''), COALESCE(sc.school_name, ''), COALESCE(sc.city, ''),
+		       COALESCE(s.grade, 0), COALESCE(s.letter, ''), COALESCE(s.status, 'active')
+		FROM student s
+		LEFT JOIN Schools sc ON sc.school_id = s.school_id
+		WHERE s.student_id = ?`, studentID).
+		Scan(&p.FirstName, &p.LastName, &p.Patronymic, &p.DateOfBirth, &p.SchoolName, &p.City, &p.Grade, &p.Letter, &p.Status)
+	if err != nil {
+		return p, err
+	}
+	p.DateOfBirth = firstN(p.DateOfBirth, 10)
+
+	untRows, err := db.Query(`
+		SELECT COALESCE(exam_type, ''), COALESCE(CAST(date AS CHAR), ''), COALESCE(total_score, 0),
+		       COALESCE(first_subject, ''), COALESCE(first_subject_score, 0),
+		       COALESCE(second_subject, ''), COALESCE(second_subject_score, 0),
+		       COALESCE(document_url, '')
+		FROM UNT_Exams WHERE student_id = ? AND attempt_kind = 'official'
+		ORDER BY date DESC`, studentID)
+	if err != nil {
+		return p, err
+	}
+	defer untRows.Close()
+	for untRows.Next() {
+		var u portfolioUNT
+		var first, second string
+		var firstScore, secondScore int
+		if err := untRows.Scan(&u.ExamType, &u.Date, &u.TotalScore, &first, &firstScore, &second, &secondScore, &u.Document); err != nil {
+			return p, err
+		}
+		u.Date = firstN(u.Date, 10)
+		var subjects []string
+		if first != "" {
+			subjects = append(subjects, fmt.Sprintf("%s: %d", first, firstScore))
+		}
+		if second != "" {
+			subjects = append(subjects, fmt.Sprintf("%s: %d", second, secondScore))
+		}
+		u.Subjects = strings.Join(subjects, ", ")
+		p.UNT = append(p.UNT, u)
+	}
+
+	olympRows, err := db.Query(`
+		SELECT COALESCE(o.olympiad_name, ''), COALESCE(o.level, ''), COALESCE(CAST(o.date AS CHAR), ''),
+		       COALESCE(o.olympiad_place, 0), COALESCE(o.score, 0), COALESCE(s.school_name, ''), COALESCE(o.document_url, '')
+		FROM Olympiads o
+		LEFT JOIN Schools s ON o.school_id = s.school_id
+		WHERE o.student_id = ?
+		ORDER BY o.date DESC`, studentID)
+	if err != nil {
+		return p, err
+	}
+	defer olympRows.Close()
+	for olympRows.Next() {
+		var o portfolioOlympiad
+		if err := olympRows.Scan(&o.Name, &o.Level, &o.Date, &o.Place, &o.Score, &o.School, &o.Document); err != nil {
+			return p, err
+		}
+		o.Date = firstN(o.Date, 10)
+		p.Olympiads = append(p.Olympiads, o)
+	}
+
+	eventRows, err := db.Query(`
+		SELECT COALESCE(e.events_name, ''), COALESCE(e.category, ''), COALESCE(CAST(e.date AS CHAR), ''),
+		       COALESCE(e.role, ''), COALESCE(s.school_name, ''), COALESCE(e.document, '')
+		FROM events_participants e
+		LEFT JOIN Schools s ON e.school_id = s.school_id
+		WHERE e.student_id = ?
+		ORDER BY e.date DESC`, studentID)
+	if err != nil {
+		return p, err
+	}
+	defer eventRows.Close()
+	for eventRows.Next() {
+		var e portfolioEvent
+		if err := eventRows.Scan(&e.Name, &e.Category, &e.Date, &e.Role, &e.School, &e.Document); err != nil {
+			return p, err
+		}
+		e.Date = firstN(e.Date, 10)
+		p.Events = append(p.Events, e)
+	}
+
+	return p, nil
+}
+
+// newVerificationCode returns a random code like "4F2A-9C1B-77D0-E3A5".
+func newVerificationCode() (string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	code := strings.ToUpper(hex.EncodeToString(b))
+	return code[0:4] + "-" + code[4:8] + "-" + code[8:12] + "-" + code[12:16], nil
+}
+
+// renderPortfolioPDF рисует портфолио. Ссылки на документы кликабельны.
+func renderPortfolioPDF(p portfolioData, code string, issuedAt time.Time) ([]byte, error) {
+	fontDir := os.Getenv("PORTFOLIO_FONT_DIR")
+	if fontDir == "" {
+		fontDir = defaultPortfolioFontDir
+	}
+
+	pdf := fpdf.New("P", "mm", "A4", fontDir)
+	pdf.AddUTF8Font("DejaVu", "", portfolioFontRegular)
+	pdf.AddUTF8Font("DejaVu", "B", portfolioFontBold)
+	pdf.SetFooterFunc(func() {
+		pdf.SetY(-15)
+		pdf.SetFont("DejaVu", "", 8)
+		pdf.CellFormat(0, 10, fmt.Sprintf("Код проверки: %s    Стр. %d", code, pdf.PageNo()), "", 0, "C", false, 0, "")
+	})
+	pdf.AddPage()
+
+	heading := func(text string) {
+		pdf.Ln(4)
+		pdf.SetFont("DejaVu", "B", 13)
+		pdf.CellFormat(0, 8, text, "B", 1, "", false, 0, "")
+		pdf.Ln(2)
+	}
+	line := func(text string) {
+		pdf.SetFont("DejaVu", "", 10)
+		pdf.MultiCell(0, 5, text, "", "", false)
+	}
+	link := func(url string) {
+		if url == "" {
+			return
+		}
+		pdf.SetFont("DejaVu", "", 9)
+		pdf.SetTextColor(0, 0, 200)
+		pdf.WriteLinkString(5, "Документ: "+url, url)
+		pdf.SetTextColor(0, 0, 0)
+		pdf.Ln(5)
+	}
+
+	pdf.SetFont("DejaVu", "B", 18)
+	pdf.CellFormat(0, 10, "Портфолио достижений", "", 1, "C", false, 0, "")
+	pdf.SetFont("DejaVu", "", 9)
+	pdf.CellFormat(0, 5, "Сформировано "+issuedAt.Format("02.01.2006 15:04"), "", 1, "C", false, 0, "")
+
+	heading("Профиль")
+	line(strings.TrimSpace(p.LastName + " " + p.FirstName + " " + p.Patronymic))
+	if p.DateOfBirth != "" {
+		line("Дата рождения: " + p.DateOfBirth)
+	}
+	school := p.SchoolName
+	if p.City != "" {
+		school += ", " + p.City
+	}
+	line("Школа: " + school)
+	if p.Status == "alumni" {
+		line("Выпускник")
+	} else if p.Grade > 0 {
+		line(fmt.Sprintf("Класс: %d%s", p.Grade, p.Letter))
+	}
+
+	heading("Результаты ЕНТ")
+	if len(p.UNT) == 0 {
+		line("Нет результатов")
+	}
+	for _, u := range p.UNT {
+		line(fmt.Sprintf("%s  %s — %d баллов", u.Date, u.ExamType, u.TotalScore))
+		if u.Subjects != "" {
+			line("Профильные предметы: " + u.Subjects)
+		}
+		link(u.Document)
+	}
+
+	heading("Олимпиады")
+	if len(p.Olympiads) == 0 {
+		line("Нет результатов")
+	}
+	for _, o := range p.Olympiads {
+		text := fmt.Sprintf("%s  %s (%s)", o.Date, o.Name, o.Level)
+		if o.Place > 0 {
+			text += fmt.Sprintf(" — %d место", o.Place)
+		}
+		line(text)
+		link(o.Document)
+	}
+
+	heading("Мероприятия")
+	if len(p.Events) == 0 {
+		line("Нет участий")
+	}
+	for _, e := range p.Events {
+		text := fmt.Sprintf("%s  %s", e.Date, e.Name)
+		if e.Category != "" {
+			text += " (" + e.Category + ")"
+		}
+		if e.Role != "" {
+			text += " — " + e.Role
+		}
+		line(text)
+		link(e.Document)
+	}
+
+	heading("Проверка подлинности")
+	line("Код проверки: " + code)
+	if base := os.Getenv("PORTFOLIO_VERIFY_URL"); base != "" {
+		link(strings.TrimRight(base, "/") + "/" + code)
+	}
+
+	var buf bytes.Buffer
+	if err := pdf.Output(&buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// issuePortfolio формирует PDF, регистрирует код проверки и отдаёт файл.
+func issuePortfolio(w http.ResponseWriter, db *sql.DB, studentID int, generatedBy sql.NullInt64) {
+	data, err := loadPortfolioData(db, studentID)
+	if err == sql.ErrNoRows {
+		utils.RespondWithError(w, http.StatusNotFound, models.Error{Message: "Student not found"})
+		return
+	} else if err != nil {
+		log.Println("Error loading portfolio data:", err)
+		utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Failed to load achievements"})
+		return
+	}
+
+	code, err := newVerificationCode()
+	if err != nil {
+		log.Println("Error generating verification code:", err)
+		utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Failed to generate portfolio"})
+		return
+	}
+
+	issuedAt := time.Now()
+	pdfBytes, err := renderPortfolioPDF(data, code, issuedAt)
+	if err != nil {
+		log.Println("Error rendering portfolio PDF:", err)
+		utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Failed to generate portfolio"})
+		return
+	}
+
+	dataJSON, _ := json.Marshal(data)
+	hash := sha256.Sum256(dataJSON)
+	summary, _ := json.Marshal(map[string]interface{}{
+		"full_name":   strings.TrimSpace(data.LastName + " " + data.FirstName + " " + data.Patronymic),
+		"school_name": data.SchoolName,
+		"unt":         len(data.UNT),
+		"olympiads":   len(data.Olympiads),
+		"events":      len(data.Events),
+	})
+	_, err = db.Exec(`INSERT INTO student_portfolios (student_id, verification_code, content_hash, summary, generated_by, created_at)
+		VALUES (?, ?, ?, ?, ?, ?)`, studentID, code, hex.EncodeToString(hash[:]), string(summary), generatedBy, issuedAt)
+	if err != nil {
+		log.Println("Error saving portfolio record:", err)
+		utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Failed to generate portfolio"})
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/pdf")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=portfolio_%d.pdf", studentID))
+	w.Header().Set("X-Verification-Code", code)
+	w.Write(pdfBytes)
+}
+
+// GetMyPortfolio — PDF-портфолио текущего ученика.
+func (pc *PortfolioController) GetMyPortfolio(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		studentID, err := utils.VerifyToken(r)
+		if err != nil {
+			utils.RespondWithError(w, http.StatusUnauthorized, models.Error{Message: "Unauthorized"})
+			return
+		}
+		issuePortfolio(w, db, studentID, sql.NullInt64{})
+	}
+}
+
+// GetStudentPortfolio — PDF-портфолио ученика для администратора школы.
+func (pc *PortfolioController) GetStudentPortfolio(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := utils.VerifyToken(r)
+		if err != nil {
+			utils.RespondWithError(w, http.StatusUnauthorized, models.Error{Message: err.Error()})
+			return
+		}
+
+		studentID, err := strconv.Atoi(mux.Vars(r)["student_id"])
+		if err != nil {
+			utils.RespondWithError(w, http.StatusBadRequest, models.Error{Message: "Invalid student ID"})
+			return
+		}
+
+		var schoolID int
+		err = db.QueryRow("SELECT COALESCE(school_id, 0) FROM student WHERE student_id = ?", studentID).Scan(&schoolID)
+		if err == sql.ErrNoRows {
+			utils.RespondWithError(w, http.StatusNotFound, models.Error{Message: "Student not found"})
+			return
+		} else if err != nil {
+			log.Println("Error fetching student:", err)
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Error fetching student"})
+			return
+		}
+
+		allowed, err := canViewSchool(db, userID, schoolID)
+		if err != nil {
+			log.Println("Error checking permissions:", err)
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Error checking permissions"})
+			return
+		}
+		if !allowed {
+			utils.RespondWithError(w, http.StatusForbidden, models.Error{Message: "You do not have permission to view this student"})
+			return
+		}
+
+		issuePortfolio(w, db, studentID, sql.NullInt64{Int64: int64(userID), Valid: true})
+	}
+}
+
+// VerifyPortfolio — публичная проверка кода с PDF. Возвращает только
+// сводку, сохранённую при выдаче, без контактных данных ученика.
+func (pc *PortfolioController) VerifyPortfolio(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		code := strings.ToUpper(strings.TrimSpace(mux.Vars(r)["code"]))
+
+		var summary sql.NullString
+		var issuedAt, contentHash string
+		err := db.QueryRow("SELECT summary, content_hash, CAST(created_at AS CHAR) FROM student_portfolios WHERE verification_code = ?", code).
+			Scan(&summary, &contentHash, &issuedAt)
+		if err == sql.ErrNoRows {
+			utils.RespondWithError(w, http.StatusNotFound, models.Error{Message: "Portfolio with this verification code was not issued"})
+			return
+		} else if err != nil {
+			log.Println("Error verifying portfolio:", err)
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Failed to verify portfolio"})
+			return
+		}
+
+		var details map[string]interface{}
+		if summary.Valid {
+			json.Unmarshal([]byte(summary.String), &details)
+		}
+		utils.ResponseJSON(w, map[string]interface{}{
+			"valid":             true,
+			"verification_code": code,
+			"issued_at":         issuedAt,
+			"content_hash":      contentHash,
+			"summary":           details,
+		})
+	}
+}
@@ -0,0 +1,680 @@
+package controllers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"ranking-school/models"
+	"ranking-school/utils"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// Модерация публикаций школ. Мероприятие или олимпиада, созданные
+// школьным админом, остаются черновиком (draft), пока их не отправят на
+// проверку (pending_review); суперадмин публикует (published) или
+// отклоняет (rejected) с указанием причины. Отклонённую публикацию можно
+// исправить и отправить снова. Каждый переход и комментарий пишется в
+// publication_reviews.
+type PublicationReviewController struct{}
+
+const (
+	PublicationDraft         = "draft"
+	PublicationPendingReview = "pending_review"
+	PublicationPublished     = "published"
+	PublicationRejected      = "rejected"
+)
+
+// publicationKind описывает таблицу, публикации которой проходят модерацию.
+type publicationKind struct {
+	itemType      string
+	table         string
+	key           string
+	titleColumn   string
+	startColumn   string
+	endColumn     string
+	creatorColumn string
+	label         string
+	// reviewedColumns — поля, которые проверяет суперадмин: их изменение
+	// возвращает опубликованную запись на проверку
+	reviewedColumns []string
+}
+
+var publicationKinds = map[string]publicationKind{
+	"event": {
+		itemType:      "event",
+		table:         "Events",
+		key:           "id",
+		titleColumn:   "event_name",
+		startColumn:   "start_date",
+		endColumn:     "end_date",
+		creatorColumn: "user_id",
+		label:         "Мероприятие",
+		reviewedColumns: []string{
+			"event_name", "description", "start_date", "end_date", "location", "category", "grade", "photo",
+		},
+	},
+	"olympiad": {
+		itemType:      "olympiad",
+		table:         "subject_olympiads",
+		key:           "subject_olympiad_id",
+		titleColumn:   "subject_name",
+		startColumn:   "date",
+		endColumn:     "end_date",
+		creatorColumn: "creator_id",
+		label:         "Олимпиада",
+		reviewedColumns: []string{
+			"subject_name", "description", "date", "end_date", "level", "grade", "school_id", "photo_url",
+		},
+	},
+}
+
+// publicationTransition — допустимый переход статуса. reviewer = true —
+// переход выполняет только суперадмин, иначе — организатор школы.
+type publicationTransition struct {
+	from     []string
+	to       string
+	reviewer bool
+}
+
+var publicationTransitions = map[string]publicationTransition{
+	"submitted": {from: []string{PublicationDraft, PublicationRejected}, to: PublicationPendingReview},
+	"withdrawn": {from: []string{PublicationPendingReview}, to: PublicationDraft},
+	"approved":  {from: []string{PublicationPendingReview}, to: PublicationPublished, reviewer: true},
+	"rejected":  {from: []string{PublicationPendingReview, PublicationPublished}, to: PublicationRejected, reviewer: true},
+}
+
+func isSuperadmin(db rowQuerier, userID int) (bool, error) {
+	var role string
+	err := db.QueryRow("SELECT role FROM users WHERE id = ?", userID).Scan(&role)
+	if err != nil && err != sql.ErrNoRows {
+		return false, err
+	}
+	return role == "superadmin", nil
+}
+
+// initialPublicationStatus — статус новой публикации: суперадмин публикует
+// сразу, школа создаёт черновик или (submit) сразу отправляет на проверку.
+func initialPublicationStatus(db rowQuerier, userID int, submit bool) (string, error) {
+	superadmin, err := isSuperadmin(db, userID)
+	if err != nil {
+		return "", err
+	}
+	if superadmin {
+		return PublicationPublished, nil
+	}
+	if submit {
+		return PublicationPendingReview, nil
+	}
+	return PublicationDraft, nil
+}
+
+// recordPublicationCreated пишет первую запись журнала для новой публикации.
+func recordPublicationCreated(db execQuerier, itemType string, itemID int64, status string, actorID int) error {
+	_, err := db.Exec(`
+		INSERT INTO publication_reviews (item_type, item_id, action, to_status, actor_id)
+		VALUES (?, ?, 'created', ?, ?)`, itemType, itemID, status, actorID)
+	return err
+}
+
+// submittedAtFor — submitted_at для новой записи со статусом status.
+func submittedAtFor(status string) interface{} {
+	if status == PublicationPendingReview {
+		return sql.NullString{String: time.Now().Format("2006-01-02 15:04:05"), Valid: true}
+	}
+	return nil
+}
+
+// changedReviewedColumns — проверяемые поля из updates, значения которых
+// отличаются от сохранённых. Даты сравниваются как время, чтобы
+// "2025-05-01" и "2025-05-01 00:00:00" не считались изменением.
+func changedReviewedColumns(db rowQuerier, kind publicationKind, itemID int, updates map[string]interface{}) ([]string, error) {
+	var columns []string
+	for _, column := range kind.reviewedColumns {
+		if _, ok := updates[column]; ok {
+			columns = append(columns, column)
+		}
+	}
+	if len(columns) == 0 {
+		return nil, nil
+	}
+
+	selects := make([]string, len(columns))
+	current := make([]string, len(columns))
+	dest := make([]interface{}, len(columns))
+	for i, column := range columns {
+		selects[i] = fmt.Sprintf("COALESCE(CAST(%s AS CHAR), '')", column)
+		dest[i] = &current[i]
+	}
+	query := fmt.Sprintf("SELECT %s FROM %s WHERE %s = ?", strings.Join(selects, ", "), kind.table, kind.key)
+	if err := db.QueryRow(query, itemID).Scan(dest...); err != nil {
+		return nil, err
+	}
+
+	var changed []string
+	for i, column := range columns {
+		value := fmt.Sprint(updates[column])
+		if value == current[i] {
+			continue
+		}
+		if a, err := parseWindowTime(value); err == nil {
+			if b, err := parseWindowTime(current[i]); err == nil && a.Equal(b) {
+				continue
+			}
+		}
+		changed = append(changed, column)
+	}
+	return changed, nil
+}
+
+// returnToReview возвращает опубликованную запись на проверку после
+// изменения проверяемых полей и пишет переход edited в журнал. Правки
+// суперадмина проверки не требуют. Возвращает true, если статус изменён.
+func returnToReview(db execQuerier, kind publicationKind, itemID, actorID int, changed []string) (bool, error) {
+	if len(changed) == 0 {
+		return false, nil
+	}
+	superadmin, err := isSuperadmin(db, actorID)
+	if err != nil || superadmin {
+		return false, err
+	}
+
+	res, err := db.Exec(fmt.Sprintf(`
+		UPDATE %s SET status = ?, submitted_at = NOW(), reviewed_by = NULL, reviewed_at = NULL
+		WHERE %s = ? AND status = ?`, kind.table, kind.key), PublicationPendingReview, itemID, PublicationPublished)
+	if err != nil {
+		return false, err
+	}
+	if affected, _ := res.RowsAffected(); affected == 0 {
+		return false, nil
+	}
+	_, err = db.Exec(`
+		INSERT INTO publication_reviews (item_type, item_id, action, from_status, to_status, comment, actor_id)
+		VALUES (?, ?, 'edited', ?, ?, ?, ?)`,
+		kind.itemType, itemID, PublicationPublished, PublicationPendingReview, "Изменено: "+strings.Join(changed, ", "), actorID)
+	return err == nil, err
+}
+
+func publicationKindFromPath(w http.ResponseWriter, r *http.Request) (publicationKind, int, bool) {
+	vars := mux.Vars(r)
+	kind, ok := publicationKinds[vars["kind"]]
+	if !ok {
+		utils.RespondWithError(w, http.StatusBadRequest, models.Error{Message: "Invalid kind, use event or olympiad"})
+		return kind, 0, false
+	}
+	itemID, err := strconv.Atoi(vars["id"])
+	if err != nil || itemID <= 0 {
+		utils.RespondWithError(w, http.StatusBadRequest, models.Error{Message: "Invalid ID"})
+		return kind, 0, false
+	}
+	return kind, itemID, true
+}
+
+type publicationState struct {
+	SchoolID  int
+	Status    string
+	Title     string
+	CreatorID int
+}
+
+func loadPublicationState(db rowQuerier, kind publicationKind, itemID int, forUpdate bool) (publicationState, error) {
+	var st publicationState
+	query := fmt.Sprintf("SELECT COALESCE(school_id, 0), status, COALESCE(%s, ''), COALESCE(%s, 0) FROM %s WHERE %s = ?",
+		kind.titleColumn, kind.creatorColumn, kind.table, kind.key)
+	if forUpdate {
+		query += " FOR UPDATE"
+	}
+	err := db.QueryRow(query, itemID).Scan(&st.SchoolID, &st.Status, &st.Title, &st.CreatorID)
+	return st, err
+}
+
+// canSeePublication — суперадмин и организаторы школы видят публикацию в
+// любом статусе, остальные — только опубликованную.
+func canSeePublication(db rowQuerier, userID, schoolID int, status string) (bool, error) {
+	if status == PublicationPublished {
+		return true, nil
+	}
+	if userID <= 0 {
+		return false, nil
+	}
+	return canManageEvents(db, userID, schoolID)
+}
+
+// optionalUserID — ID пользователя, если запрос пришёл с токеном.
+func optionalUserID(r *http.Request) int {
+	userID, err := utils.VerifyToken(r)
+	if err != nil {
+		return 0
+	}
+	return userID
+}
+
+func (pc *PublicationReviewController) SubmitForReview(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		applyPublicationTransition(w, r, db, "submitted")
+	}
+}
+
+func (pc *PublicationReviewController) WithdrawFromReview(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		applyPublicationTransition(w, r, db, "withdrawn")
+	}
+}
+
+func (pc *PublicationReviewController) ApprovePublication(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		applyPublicationTransition(w, r, db, "approved")
+	}
+}
+
+// RejectPublication отклоняет публикацию; reason обязателен. Уже
+// опубликованную запись тоже можно снять с публикации.
+func (pc *PublicationReviewController) RejectPublication(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		applyPublicationTransition(w, r, db, "rejected")
+	}
+}
+
+func applyPublicationTransition(w http.ResponseWriter, r *http.Request, db *sql.DB, action string) {
+	userID, err := utils.VerifyToken(r)
+	if err != nil {
+		utils.RespondWithError(w, http.StatusUnauthorized, models.Error{Message: "Unauthorized"})
+		return
+	}
+	kind, itemID, ok := publicationKindFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	var body struct {
+		Reason  string `json:"reason"`
+		Comment string `json:"comment"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil && err != io.EOF {
+		utils.RespondWithError(w, http.StatusBadRequest, models.Error{Message: "Invalid body"})
+		return
+	}
+	body.Reason = strings.TrimSpace(body.Reason)
+	body.Comment = strings.TrimSpace(body.Comment)
+	transition := publicationTransitions[action]
+	if action == "rejected" && body.Reason == "" {
+		utils.RespondWithError(w, http.StatusBadRequest, models.Error{Message: "reason is required to reject"})
+		return
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Database error"})
+		return
+	}
+	defer tx.Rollback()
+
+	st, err := loadPublicationState(tx, kind, itemID, true)
+	if err == sql.ErrNoRows {
+		utils.RespondWithError(w, http.StatusNotFound, models.Error{Message: kind.label + " not found"})
+		return
+	} else if err != nil {
+		log.Printf("Error loading %s %d: %v", kind.itemType, itemID, err)
+		utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Database error"})
+		return
+	}
+
+	var allowed bool
+	if transition.reviewer {
+		allowed, err = isSuperadmin(tx, userID)
+	} else {
+		allowed, err = canManageEvents(tx, userID, st.SchoolID)
+	}
+	if err != nil {
+		utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Error checking permissions"})
+		return
+	}
+	if !allowed {
+		message := "Only school organisers can do this"
+		if transition.reviewer {
+			message = "Only superadmins can review publications"
+		}
+		utils.RespondWithError(w, http.StatusForbidden, models.Error{Message: message})
+		return
+	}
+
+	valid := false
+	for _, from := range transition.from {
+		if st.Status == from {
+			valid = true
+			break
+		}
+	}
+	if !valid {
+		utils.RespondWithError(w, http.StatusConflict, models.Error{
+			Message: fmt.Sprintf("Cannot apply %s to a publication in status %s", action, st.Status),
+		})
+		return
+	}
+
+	var update string
+	switch action {
+	case "submitted":
+		update = "status = ?, submitted_at = NOW(), rejection_reason = NULL"
+	case "withdrawn":
+		update = "status = ?, submitted_at = NULL"
+	case "approved":
+		update = "status = ?, reviewed_by = ?, reviewed_at = NOW(), rejection_reason = NULL"
+	case "rejected":
+		update = "status = ?, reviewed_by = ?, reviewed_at = NOW(), rejection_reason = ?"
+	}
+	args := []interface{}{transition.to}
+	if transition.reviewer {
+		args = append(args, userID)
+	}
+	if action == "rejected" {
+		args = append(args, body.Reason)
+	}
+	args = append(args, itemID)
+	if _, err := tx.Exec(fmt.Sprintf("UPDATE %s SET %s WHERE %s = ?", kind.table, update, kind.key), args...); err != nil {
+		log.Printf("Error updating %s %d status: %v", kind.itemType, itemID, err)
+		utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Failed to update status"})
+		return
+	}
+	_, err = tx.Exec(`
+		INSERT INTO publication_reviews (item_type, item_id, action, from_status, to_status, reason, comment, actor_id)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		kind.itemType, itemID, action, st.Status, transition.to, toNullString(body.Reason), toNullString(body.Comment), userID)
+	if err != nil {
+		log.Printf("Error writing review log for %s %d: %v", kind.itemType, itemID, err)
+		utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Failed to update status"})
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Failed to update status"})
+		return
+	}
+
+	if transition.reviewer {
+		emailPublicationDecision(db, kind, st, transition.to, body.Reason, body.Comment)
+	}
+
+	utils.ResponseJSON(w, map[string]interface{}{
+		"item_type":   kind.itemType,
+		"item_id":     itemID,
+		"from_status": st.Status,
+		"status":      transition.to,
+	})
+}
+
+// emailPublicationDecision сообщает автору о решении модератора.
+func emailPublicationDecision(db *sql.DB, kind publicationKind, st publicationState, status, reason, comment string) {
+	if st.CreatorID <= 0 {
+		return
+	}
+	go func() {
+		var email sql.NullString
+		if err := db.QueryRow("SELECT email FROM users WHERE id = ?", st.CreatorID).Scan(&email); err != nil {
+			log.Printf("Failed to load email for user %d: %v", st.CreatorID, err)
+			return
+		}
+		if !email.Valid || email.String == "" {
+			return
+		}
+		subject := kind.label + " опубликовано"
+		message := fmt.Sprintf("%s «%s» прошло проверку и опубликовано.", kind.label, st.Title)
+		if kind.itemType == "olympiad" {
+			subject = kind.label + " опубликована"
+			message = fmt.Sprintf("%s «%s» прошла проверку и опубликована.", kind.label, st.Title)
+		}
+		if status == PublicationRejected {
+			subject = "Публикация отклонена"
+			message = fmt.Sprintf("%s «%s» отклонено модератором. Причина: %s", kind.label, st.Title, reason)
+			if kind.itemType == "olympiad" {
+				message = fmt.Sprintf("%s «%s» отклонена модератором. Причина: %s", kind.label, st.Title, reason)
+			}
+		}
+		if comment != "" {
+			message += "\nКомментарий: " + comment
+		}
+		utils.SendEmail(email.String, subject, message)
+	}()
+}
+
+// AddPublicationComment добавляет комментарий к публикации без смены
+// статуса. Писать могут суперадмин и организаторы школы.
+func (pc *PublicationReviewController) AddPublicationComment(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := utils.VerifyToken(r)
+		if err != nil {
+			utils.RespondWithError(w, http.StatusUnauthorized, models.Error{Message: "Unauthorized"})
+			return
+		}
+		kind, itemID, ok := publicationKindFromPath(w, r)
+		if !ok {
+			return
+		}
+
+		var body struct {
+			Comment string `json:"comment"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			utils.RespondWithError(w, http.StatusBadRequest, models.Error{Message: "Invalid body"})
+			return
+		}
+		body.Comment = strings.TrimSpace(body.Comment)
+		if body.Comment == "" {
+			utils.RespondWithError(w, http.StatusBadRequest, models.Error{Message: "comment is required"})
+			return
+		}
+
+		st, err := loadPublicationState(db, kind, itemID, false)
+		if err == sql.ErrNoRows {
+			utils.RespondWithError(w, http.StatusNotFound, models.Error{Message: kind.label + " not found"})
+			return
+		} else if err != nil {
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Database error"})
+			return
+		}
+		allowed, err := canManageEvents(db, userID, st.SchoolID)
+		if err != nil {
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Error checking permissions"})
+			return
+		}
+		if !allowed {
+			utils.RespondWithError(w, http.StatusForbidden, models.Error{Message: "You cannot comment on this publication"})
+			return
+		}
+
+		res, err := db.Exec(`
+			INSERT INTO publication_reviews (item_type, item_id, action, from_status, to_status, comment, actor_id)
+			VALUES (?, ?, 'comment', ?, ?, ?, ?)`, kind.itemType, itemID, st.Status, st.Status, body.Comment, userID)
+		if err != nil {
+			log.Printf("Error adding comment to %s %d: %v", kind.itemType, itemID, err)
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Failed to add comment"})
+			return
+		}
+		id, _ := res.LastInsertId()
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		utils.ResponseJSON(w, models.PublicationReview{
+			ID:         int(id),
+			ItemType:   kind.itemType,
+			ItemID:     itemID,
+			Action:     "comment",
+			FromStatus: st.Status,
+			ToStatus:   st.Status,
+			Comment:    body.Comment,
+			ActorID:    userID,
+			CreatedAt:  time.Now().Format("2006-01-02 15:04:05"),
+		})
+	}
+}
+
+// GetPublicationHistory — журнал переходов и комментариев публикации.
+func (pc *PublicationReviewController) GetPublicationHistory(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := utils.VerifyToken(r)
+		if err != nil {
+			utils.RespondWithError(w, http.StatusUnauthorized, models.Error{Message: "Unauthorized"})
+			return
+		}
+		kind, itemID, ok := publicationKindFromPath(w, r)
+		if !ok {
+			return
+		}
+
+		st, err := loadPublicationState(db, kind, itemID, false)
+		if err == sql.ErrNoRows {
+			utils.RespondWithError(w, http.StatusNotFound, models.Error{Message: kind.label + " not found"})
+			return
+		} else if err != nil {
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Database error"})
+			return
+		}
+		allowed, err := canManageEvents(db, userID, st.SchoolID)
+		if err != nil {
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Error checking permissions"})
+			return
+		}
+		if !allowed {
+			utils.RespondWithError(w, http.StatusForbidden, models.Error{Message: "You cannot view this publication's history"})
+			return
+		}
+
+		rows, err := db.Query(`
+			SELECT pr.id, pr.item_type, pr.item_id, pr.action, COALESCE(pr.from_status, ''), pr.to_status,
+			       COALESCE(pr.reason, ''), COALESCE(pr.comment, ''), pr.actor_id,
+			       TRIM(CONCAT(COALESCE(u.first_name, ''), ' ', COALESCE(u.last_name, ''))),
+			       CAST(pr.created_at AS CHAR)
+			FROM publication_reviews pr
+			LEFT JOIN users u ON u.id = pr.actor_id
+			WHERE pr.item_type = ? AND pr.item_id = ?
+			ORDER BY pr.created_at, pr.id`, kind.itemType, itemID)
+		if err != nil {
+			log.Printf("Error fetching review history for %s %d: %v", kind.itemType, itemID, err)
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Failed to fetch history"})
+			return
+		}
+		defer rows.Close()
+
+		history := []models.PublicationReview{}
+		for rows.Next() {
+			var h models.PublicationReview
+			if err := rows.Scan(&h.ID, &h.ItemType, &h.ItemID, &h.Action, &h.FromStatus, &h.ToStatus,
+				&h.Reason, &h.Comment, &h.ActorID, &h.ActorName, &h.CreatedAt); err != nil {
+				utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Failed to fetch history"})
+				return
+			}
+			history = append(history, h)
+		}
+
+		utils.ResponseJSON(w, map[string]interface{}{
+			"item_type": kind.itemType,
+			"item_id":   itemID,
+			"status":    st.Status,
+			"history":   history,
+		})
+	}
+}
+
+// GetReviewQueue — очередь модерации суперадмина: публикации в статусе
+// pending_review (или ?status=...), старые заявки первыми. Фильтры: kind,
+// school_id.
+func (pc *PublicationReviewController) GetReviewQueue(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := utils.VerifyToken(r)
+		if err != nil {
+			utils.RespondWithError(w, http.StatusUnauthorized, models.Error{Message: "Unauthorized"})
+			return
+		}
+		superadmin, err := isSuperadmin(db, userID)
+		if err != nil {
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Error checking permissions"})
+			return
+		}
+		if !superadmin {
+			utils.RespondWithError(w, http.StatusForbidden, models.Error{Message: "Only superadmins can review publications"})
+			return
+		}
+
+		query := r.URL.Query()
+		status := query.Get("status")
+		if status == "" {
+			status = PublicationPendingReview
+		}
+		switch status {
+		case PublicationDraft, PublicationPendingReview, PublicationPublished, PublicationRejected:
+		default:
+			utils.RespondWithError(w, http.StatusBadRequest, models.Error{Message: "Invalid status"})
+			return
+		}
+		var schoolID int
+		if s := query.Get("school_id"); s != "" {
+			if schoolID, err = strconv.Atoi(s); err != nil || schoolID <= 0 {
+				utils.RespondWithError(w, http.StatusBadRequest, models.Error{Message: "Invalid school_id"})
+				return
+			}
+		}
+
+		var parts []string
+		var args []interface{}
+		for _, name := range []string{"event", "olympiad"} {
+			if k := query.Get("kind"); k != "" && k != name {
+				continue
+			}
+			kind := publicationKinds[name]
+			part := fmt.Sprintf(`
+				SELECT '%[1]s' AS item_type, p.%[2]s AS item_id, COALESCE(p.%[3]s, '') AS title,
+				       COALESCE(p.school_id, 0) AS school_id, COALESCE(s.school_name, '') AS school_name,
+				       COALESCE(CAST(p.%[4]s AS CHAR), '') AS start_date, COALESCE(CAST(p.%[5]s AS CHAR), '') AS end_date,
+				       p.status, COALESCE(CAST(p.submitted_at AS CHAR), '') AS submitted_at,
+				       COALESCE(u.email, '') AS submitted_by,
+				       (SELECT COUNT(*) FROM publication_reviews pr
+				        WHERE pr.item_type = '%[1]s' AND pr.item_id = p.%[2]s AND pr.action = 'comment') AS comment_count
+				FROM %[6]s p
+				LEFT JOIN Schools s ON s.school_id = p.school_id
+				LEFT JOIN users u ON u.id = p.%[7]s
+				WHERE p.status = ?`,
+				kind.itemType, kind.key, kind.titleColumn, kind.startColumn, kind.endColumn, kind.table, kind.creatorColumn)
+			args = append(args, status)
+			if schoolID > 0 {
+				part += " AND p.school_id = ?"
+				args = append(args, schoolID)
+			}
+			parts = append(parts, part)
+		}
+		if len(parts) == 0 {
+			utils.RespondWithError(w, http.StatusBadRequest, models.Error{Message: "Invalid kind, use event or olympiad"})
+			return
+		}
+
+		rows, err := db.Query(strings.Join(parts, " UNION ALL ")+" ORDER BY submitted_at = '', submitted_at, item_type, item_id", args...)
+		if err != nil {
+			log.Printf("Error fetching review queue: %v", err)
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Failed to fetch review queue"})
+			return
+		}
+		defer rows.Close()
+
+		items := []models.ReviewQueueItem{}
+		for rows.Next() {
+			var item models.ReviewQueueItem
+			if err := rows.Scan(&item.ItemType, &item.ItemID, &item.Title, &item.SchoolID, &item.SchoolName,
+				&item.StartDate, &item.EndDate, &item.Status, &item.SubmittedAt, &item.SubmittedBy, &item.CommentCount); err != nil {
+				log.Printf("Error scanning review queue: %v", err)
+				utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Failed to fetch review queue"})
+				return
+			}
+			items = append(items, item)
+		}
+
+		utils.ResponseJSON(w, map[string]interface{}{
+			"status": status,
+			"count":  len(items),
+			"items":  items,
+		})
+	}
+}
@@ -0,0 +1,947 @@
+package controllers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"ranking-school/models"
+	"ranking-school/utils"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// Шаблоны мероприятий и олимпиад. Школа один раз описывает мероприятие,
+// а экземпляры на конкретные даты создаются по правилу повторения
+// (фоновой задачей StartTemplateScheduler или вручную). Экземпляры —
+// обычные записи Events / subject_olympiads и проходят модерацию так же,
+// как созданные вручную.
+type PublicationTemplateController struct{}
+
+// termMonths — месяцы начала четвертей учебного года.
+var termMonths = []int{9, 11, 1, 4}
+
+const templateSelect = `
+	SELECT id, item_type, school_id, name, COALESCE(description, ''), COALESCE(location, ''),
+	       COALESCE(category, ''), COALESCE(level, ''), grade, limit_count, COALESCE(photo, ''),
+	       COALESCE(CAST(start_time AS CHAR), ''), duration_days, recurrence, recurrence_months, recurrence_day,
+	       CAST(first_date AS CHAR), COALESCE(CAST(until_date AS CHAR), ''), months_ahead, auto_submit,
+	       created_by, CAST(created_at AS CHAR), CAST(updated_at AS CHAR)
+	FROM publication_templates`
+
+func scanPublicationTemplate(scan func(dest ...interface{}) error) (models.PublicationTemplate, error) {
+	var t models.PublicationTemplate
+	var months sql.NullString
+	err := scan(&t.ID, &t.ItemType, &t.SchoolID, &t.Name, &t.Description, &t.Location,
+		&t.Category, &t.Level, &t.Grade, &t.Limit, &t.Photo,
+		&t.StartTime, &t.DurationDays, &t.Recurrence, &months, &t.RecurrenceDay,
+		&t.FirstDate, &t.UntilDate, &t.MonthsAhead, &t.AutoSubmit,
+		&t.CreatedBy, &t.CreatedAt, &t.UpdatedAt)
+	if err != nil {
+		return t, err
+	}
+	if months.Valid && months.String != "" {
+		if err := json.Unmarshal([]byte(months.String), &t.RecurrenceMonths); err != nil {
+			return t, err
+		}
+	}
+	return t, nil
+}
+
+func loadPublicationTemplate(db rowQuerier, templateID int) (models.PublicationTemplate, error) {
+	return scanPublicationTemplate(db.QueryRow(templateSelect+" WHERE id = ?", templateID).Scan)
+}
+
+// validatePublicationTemplate проверяет поля шаблона и приводит их к
+// виду, в котором они хранятся в БД.
+func validatePublicationTemplate(t *models.PublicationTemplate) error {
+	t.Name = strings.TrimSpace(t.Name)
+	t.Description = strings.TrimSpace(t.Description)
+	t.Location = strings.TrimSpace(t.Location)
+	if t.Name == "" || len(t.Name) > 255 {
+		return fmt.Errorf("name is required and must be at most 255 characters")
+	}
+
+	switch t.ItemType {
+	case "event":
+		valid := false
+		for _, c := range []string{"Science", "Humanities", "Sport", "Creative"} {
+			if t.Category == c {
+				valid = true
+				break
+			}
+		}
+		if !valid {
+			return fmt.Errorf("invalid or missing category. Allowed values are: Science, Humanities, Sport, Creative")
+		}
+		if t.Location == "" {
+			return fmt.Errorf("location is required for events")
+		}
+		t.Level = ""
+	case "olympiad":
+		if t.Description == "" || t.Level == "" {
+			return fmt.Errorf("description and level are required for olympiads")
+		}
+		if t.Grade <= 0 {
+			return fmt.Errorf("grade is required for olympiads")
+		}
+		if t.Limit <= 0 {
+			return fmt.Errorf("limit must be positive for olympiads")
+		}
+		t.Category = ""
+	default:
+		return fmt.Errorf("item_type must be event or olympiad")
+	}
+	if t.Grade < 0 || t.Limit < 0 {
+		return fmt.Errorf("grade and limit must not be negative")
+	}
+
+	if t.StartTime != "" {
+		parsed, err := time.Parse("15:04", t.StartTime)
+		if err != nil {
+			if parsed, err = time.Parse("15:04:05", t.StartTime); err != nil {
+				return fmt.Errorf("invalid start_time, use HH:MM")
+			}
+		}
+		t.StartTime = parsed.Format("15:04:05")
+	}
+	if t.DurationDays == 0 {
+		t.DurationDays = 1
+	}
+	if t.DurationDays < 1 || t.DurationDays > 60 {
+		return fmt.Errorf("duration_days must be between 1 and 60")
+	}
+
+	first, err := time.ParseInLocation("2006-01-02", t.FirstDate, time.Local)
+	if err != nil {
+		return fmt.Errorf("invalid first_date, use YYYY-MM-DD")
+	}
+	if t.UntilDate != "" {
+		until, err := time.ParseInLocation("2006-01-02", t.UntilDate, time.Local)
+		if err != nil {
+			return fmt.Errorf("invalid until_date, use YYYY-MM-DD")
+		}
+		if until.Before(first) {
+			return fmt.Errorf("until_date must not be before first_date")
+		}
+	}
+
+	if t.Recurrence == "" {
+		t.Recurrence = "none"
+	}
+	switch t.Recurrence {
+	case "none", "yearly", "termly":
+		t.RecurrenceMonths = nil
+	case "custom":
+		if len(t.RecurrenceMonths) == 0 {
+			return fmt.Errorf("recurrence_months is required for custom recurrence")
+		}
+		for _, m := range t.RecurrenceMonths {
+			if m < 1 || m > 12 {
+				return fmt.Errorf("recurrence_months must contain months 1..12")
+			}
+		}
+	default:
+		return fmt.Errorf("recurrence must be none, yearly, termly or custom")
+	}
+	if t.RecurrenceDay == 0 {
+		t.RecurrenceDay = first.Day()
+	}
+	if t.RecurrenceDay < 1 || t.RecurrenceDay > 31 {
+		return fmt.Errorf("recurrence_day must be between 1 and 31")
+	}
+	if t.MonthsAhead == 0 {
+		t.MonthsAhead = 12
+	}
+	if t.MonthsAhead < 1 || t.MonthsAhead > 36 {
+		return fmt.Errorf("months_ahead must be between 1 and 36")
+	}
+	return nil
+}
+
+// templateMonths — месяцы, в которые шаблон повторяется.
+func templateMonths(t models.PublicationTemplate) []int {
+	switch t.Recurrence {
+	case "yearly":
+		first, err := time.ParseInLocation("2006-01-02", t.FirstDate, time.Local)
+		if err != nil {
+			return nil
+		}
+		return []int{int(first.Month())}
+	case "termly":
+		return termMonths
+	case "custom":
+		return t.RecurrenceMonths
+	}
+	return nil
+}
+
+// templateOccurrences — даты экземпляров шаблона в промежутке [from, to]
+// с учётом first_date и until_date. День, которого нет в месяце (31-е в
+// ноябре), переносится на последний день месяца.
+func templateOccurrences(t models.PublicationTemplate, from, to time.Time) []time.Time {
+	first, err := time.ParseInLocation("2006-01-02", t.FirstDate, time.Local)
+	if err != nil {
+		return nil
+	}
+	if from.Before(first) {
+		from = first
+	}
+	if t.UntilDate != "" {
+		if until, err := time.ParseInLocation("2006-01-02", t.UntilDate, time.Local); err == nil && until.Before(to) {
+			to = until
+		}
+	}
+
+	months := make(map[int]bool)
+	for _, m := range templateMonths(t) {
+		months[m] = true
+	}
+
+	var occurrences []time.Time
+	for m := time.Date(from.Year(), from.Month(), 1, 0, 0, 0, 0, time.Local); !m.After(to); m = m.AddDate(0, 1, 0) {
+		if !months[int(m.Month())] {
+			continue
+		}
+		day := t.RecurrenceDay
+		if last := m.AddDate(0, 1, -1).Day(); day > last {
+			day = last
+		}
+		d := time.Date(m.Year(), m.Month(), day, 0, 0, 0, 0, time.Local)
+		if d.Before(from) || d.After(to) {
+			continue
+		}
+		occurrences = append(occurrences, d)
+	}
+	return occurrences
+}
+
+// createTemplateInstance создаёт экземпляр шаблона на дату occurrence.
+// Если экземпляр на эту дату уже есть, возвращает created = false.
+func createTemplateInstance(db *sql.DB, t models.PublicationTemplate, occurrence time.Time) (int64, bool, error) {
+	kind := publicationKinds[t.ItemType]
+	day := occurrence.Format("2006-01-02")
+
+	var exists bool
+	err := db.QueryRow(fmt.Sprintf("SELECT EXISTS(SELECT 1 FROM %s WHERE template_id = ? AND template_occurrence = ?)", kind.table), t.ID, day).Scan(&exists)
+	if err != nil || exists {
+		return 0, false, err
+	}
+
+	status, err := initialPublicationStatus(db, t.CreatedBy, t.AutoSubmit)
+	if err != nil {
+		return 0, false, err
+	}
+	startDate := day
+	if t.StartTime != "" {
+		startDate = day + " " + t.StartTime
+	}
+	endDate := occurrence.AddDate(0, 0, t.DurationDays-1).Format("2006-01-02")
+
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, false, err
+	}
+	defer tx.Rollback()
+
+	var res sql.Result
+	if t.ItemType == "event" {
+		var createdBy string
+		tx.QueryRow("SELECT COALESCE(username, '') FROM users WHERE id = ?", t.CreatedBy).Scan(&createdBy)
+		now := time.Now().Format("2006-01-02 15:04:05")
+		res, err = tx.Exec(`
+			INSERT INTO Events (
+				school_id, user_id, event_name, description, photo, start_date, end_date, location,
+				grade, limit_count, participants, limit_participants, created_at, updated_at, created_by, category,
+				status, submitted_at, template_id, template_occurrence
+			) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, 0, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			t.SchoolID, t.CreatedBy, t.Name, t.Description, t.Photo, startDate, endDate, t.Location,
+			t.Grade, t.Limit, t.Limit, now, now, createdBy, t.Category,
+			status, submittedAtFor(status), t.ID, day)
+	} else {
+		res, err = tx.Exec(`
+			INSERT INTO subject_olympiads (
+				subject_name, date, end_date, description, school_id, level, grade, limit_participants, creator_id,
+				photo_url, status, submitted_at, template_id, template_occurrence
+			) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			t.Name, day, endDate, t.Description, t.SchoolID, t.Level, t.Grade, t.Limit, t.CreatedBy,
+			toNullString(t.Photo), status, submittedAtFor(status), t.ID, day)
+	}
+	if err != nil {
+		return 0, false, err
+	}
+	itemID, err := res.LastInsertId()
+	if err != nil {
+		return 0, false, err
+	}
+	if err := recordPublicationCreated(tx, t.ItemType, itemID, status, t.CreatedBy); err != nil {
+		return 0, false, err
+	}
+	return itemID, true, tx.Commit()
+}
+
+// generateTemplateInstances создаёт недостающие экземпляры от сегодняшнего
+// дня на months_ahead месяцев вперёд.
+func generateTemplateInstances(db *sql.DB, t models.PublicationTemplate, now time.Time) (int, error) {
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local)
+	created := 0
+	for _, occurrence := range templateOccurrences(t, today, today.AddDate(0, t.MonthsAhead, 0)) {
+		_, ok, err := createTemplateInstance(db, t, occurrence)
+		if err != nil {
+			return created, err
+		}
+		if ok {
+			created++
+		}
+	}
+	return created, nil
+}
+
+// StartTemplateScheduler раз в interval создаёт экземпляры по всем
+// шаблонам с повторением. Запускается один раз при старте сервера.
+func StartTemplateScheduler(db *sql.DB, interval time.Duration) {
+	go func() {
+		for {
+			if err := generateAllTemplateInstances(db, time.Now()); err != nil {
+				log.Printf("Template generation failed: %v", err)
+			}
+			time.Sleep(interval)
+		}
+	}()
+}
+
+func generateAllTemplateInstances(db *sql.DB, now time.Time) error {
+	rows, err := db.Query(templateSelect+" WHERE recurrence <> 'none' AND (until_date IS NULL OR until_date >= ?)", now.Format("2006-01-02"))
+	if err != nil {
+		return err
+	}
+	var templates []models.PublicationTemplate
+	for rows.Next() {
+		t, err := scanPublicationTemplate(rows.Scan)
+		if err != nil {
+			rows.Close()
+			return err
+		}
+		templates = append(templates, t)
+	}
+	rows.Close()
+
+	for _, t := range templates {
+		created, err := generateTemplateInstances(db, t, now)
+		if err != nil {
+			log.Printf("Failed to generate instances for template %d: %v", t.ID, err)
+			continue
+		}
+		if created > 0 {
+			log.Printf("Template %d: created %d instance(s)", t.ID, created)
+		}
+	}
+	return nil
+}
+
+// templatePropagation — итог переноса изменений шаблона на экземпляры.
+type templatePropagation struct {
+	Updated          int   `json:"updated_instances"`
+	ReturnedToReview []int `json:"returned_to_review,omitempty"`
+	// LimitKept — экземпляры, где занято больше мест, чем новый лимит:
+	// зарегистрированных не снимаем, лимит остаётся равным числу занятых мест
+	LimitKept []int `json:"limit_kept_instances,omitempty"`
+}
+
+// propagateTemplate переносит поля шаблона на будущие экземпляры:
+// название, описание, место, класс, лимит, категорию/уровень, время
+// начала и длительность. Даты экземпляров (template_occurrence) не
+// меняются. При увеличении лимита места отдаются листу ожидания, при
+// уменьшении лимит не опускается ниже числа занятых мест. Опубликованные
+// экземпляры с изменёнными проверяемыми полями возвращаются на модерацию.
+func propagateTemplate(db *sql.DB, t models.PublicationTemplate, actorID int, now time.Time) (templatePropagation, error) {
+	var result templatePropagation
+	kind := publicationKinds[t.ItemType]
+	queue := eventQueue
+	if t.ItemType == "olympiad" {
+		queue = olympiadQueue
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return result, err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.Query(fmt.Sprintf("SELECT %s, CAST(template_occurrence AS CHAR) FROM %s WHERE template_id = ? AND template_occurrence > ?", kind.key, kind.table),
+		t.ID, now.Format("2006-01-02"))
+	if err != nil {
+		return result, err
+	}
+	type instance struct {
+		id         int
+		occurrence string
+	}
+	var instances []instance
+	for rows.Next() {
+		var inst instance
+		if err := rows.Scan(&inst.id, &inst.occurrence); err != nil {
+			rows.Close()
+			return result, err
+		}
+		instances = append(instances, inst)
+	}
+	rows.Close()
+
+	startTime := t.StartTime
+	if startTime == "" {
+		startTime = "00:00:00"
+	}
+	var promotions []waitlistPromotion
+	for _, inst := range instances {
+		occurrence, err := time.ParseInLocation("2006-01-02", inst.occurrence, time.Local)
+		if err != nil {
+			return result, err
+		}
+		endDate := occurrence.AddDate(0, 0, t.DurationDays-1).Format("2006-01-02")
+
+		_, taken, err := queue.lock(tx, inst.id)
+		if err != nil {
+			return result, err
+		}
+		limit := t.Limit
+		if limit > 0 && taken > limit {
+			limit = taken
+			result.LimitKept = append(result.LimitKept, inst.id)
+		}
+
+		fields := map[string]interface{}{"description": t.Description, "grade": t.Grade, "end_date": endDate}
+		if t.ItemType == "event" {
+			fields["event_name"] = t.Name
+			fields["location"] = t.Location
+			fields["category"] = t.Category
+			fields["start_date"] = inst.occurrence + " " + startTime
+			if t.Photo != "" {
+				fields["photo"] = t.Photo
+			}
+		} else {
+			fields["subject_name"] = t.Name
+			fields["level"] = t.Level
+			if t.Photo != "" {
+				fields["photo_url"] = t.Photo
+			}
+		}
+		changed, err := changedReviewedColumns(tx, kind, inst.id, fields)
+		if err != nil {
+			return result, err
+		}
+
+		if t.ItemType == "event" {
+			_, err = tx.Exec(`
+				UPDATE Events
+				SET event_name = ?, description = ?, location = ?, grade = ?, limit_count = ?, limit_participants = ?,
+				    category = ?, photo = COALESCE(NULLIF(?, ''), photo),
+				    start_date = TIMESTAMP(template_occurrence, ?),
+				    end_date = DATE_ADD(template_occurrence, INTERVAL ? DAY),
+				    updated_at = NOW()
+				WHERE id = ?`,
+				t.Name, t.Description, t.Location, t.Grade, limit, limit, t.Category, t.Photo,
+				startTime, t.DurationDays-1, inst.id)
+		} else {
+			_, err = tx.Exec(`
+				UPDATE subject_olympiads
+				SET subject_name = ?, description = ?, level = ?, grade = ?, limit_participants = ?,
+				    photo_url = COALESCE(NULLIF(?, ''), photo_url),
+				    end_date = DATE_ADD(template_occurrence, INTERVAL ? DAY)
+				WHERE subject_olympiad_id = ?`,
+				t.Name, t.Description, t.Level, t.Grade, limit, t.Photo, t.DurationDays-1, inst.id)
+		}
+		if err != nil {
+			return result, err
+		}
+		returned, err := returnToReview(tx, kind, inst.id, actorID, changed)
+		if err != nil {
+			return result, err
+		}
+		if returned {
+			result.ReturnedToReview = append(result.ReturnedToReview, inst.id)
+		}
+		p, err := queue.promote(tx, inst.id)
+		if err != nil {
+			return result, err
+		}
+		promotions = append(promotions, p...)
+	}
+
+	if t.ItemType == "event" && len(instances) > 0 {
+		// Название мероприятия хранится и в events_participants
+		if _, err := tx.Exec(`
+			UPDATE events_participants ep
+			JOIN Events e ON e.id = ep.event_id
+			SET ep.events_name = e.event_name
+			WHERE e.template_id = ? AND e.template_occurrence > ?`, t.ID, now.Format("2006-01-02")); err != nil {
+			return result, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return result, err
+	}
+	emailWaitlistPromotions(db, promotions)
+	result.Updated = len(instances)
+	return result, nil
+}
+
+// occursOn — попадает ли day в правило повторения шаблона.
+func occursOn(t models.PublicationTemplate, day time.Time) bool {
+	return len(templateOccurrences(t, day, day)) > 0
+}
+
+// removeStaleTemplateInstances убирает будущие экземпляры, созданные по
+// прежнему правилу повторения, если в новое правило их дата не попадает.
+// Экземпляры, созданные вручную на произвольную дату, не трогаются.
+// Экземпляр без заявок удаляется; с заявками — отвязывается от шаблона и
+// остаётся обычным мероприятием/олимпиадой, чтобы не потерять регистрации.
+func removeStaleTemplateInstances(db *sql.DB, old, updated models.PublicationTemplate, now time.Time) (removed, detached []int, err error) {
+	kind := publicationKinds[updated.ItemType]
+	queue := eventQueue
+	if updated.ItemType == "olympiad" {
+		queue = olympiadQueue
+	}
+
+	rows, err := db.Query(fmt.Sprintf("SELECT %s, CAST(template_occurrence AS CHAR) FROM %s WHERE template_id = ? AND template_occurrence > ?", kind.key, kind.table),
+		updated.ID, now.Format("2006-01-02"))
+	if err != nil {
+		return nil, nil, err
+	}
+	var stale []int
+	for rows.Next() {
+		var id int
+		var occurrence string
+		if err := rows.Scan(&id, &occurrence); err != nil {
+			rows.Close()
+			return nil, nil, err
+		}
+		day, err := time.ParseInLocation("2006-01-02", occurrence, time.Local)
+		if err != nil {
+			continue
+		}
+		if occursOn(old, day) && !occursOn(updated, day) {
+			stale = append(stale, id)
+		}
+	}
+	rows.Close()
+
+	for _, id := range stale {
+		var registrations int
+		err := db.QueryRow(fmt.Sprintf("SELECT COUNT(*) FROM %s WHERE %s = ? AND status IN (%s, 'waitlisted')", queue.regTable, queue.regParentKey, queue.seatStatuses), id).Scan(&registrations)
+		if err != nil {
+			return removed, detached, err
+		}
+		if registrations == 0 {
+			if _, err := db.Exec(fmt.Sprintf("DELETE FROM %s WHERE %s = ?", kind.table, kind.key), id); err != nil {
+				return removed, detached, err
+			}
+			removed = append(removed, id)
+			continue
+		}
+		if _, err := db.Exec(fmt.Sprintf("UPDATE %s SET template_id = NULL, template_occurrence = NULL WHERE %s = ?", kind.table, kind.key), id); err != nil {
+			return removed, detached, err
+		}
+		detached = append(detached, id)
+	}
+	return removed, detached, nil
+}
+
+func loadTemplateInstances(db *sql.DB, t models.PublicationTemplate) ([]models.TemplateInstance, error) {
+	kind := publicationKinds[t.ItemType]
+	rows, err := db.Query(fmt.Sprintf(`
+		SELECT %s, COALESCE(%s, ''), CAST(template_occurrence AS CHAR),
+		       COALESCE(CAST(%s AS CHAR), ''), COALESCE(CAST(%s AS CHAR), ''), status
+		FROM %s
+		WHERE template_id = ?
+		ORDER BY template_occurrence`,
+		kind.key, kind.titleColumn, kind.startColumn, kind.endColumn, kind.table), t.ID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	instances := []models.TemplateInstance{}
+	for rows.Next() {
+		inst := models.TemplateInstance{ItemType: t.ItemType}
+		if err := rows.Scan(&inst.ItemID, &inst.Title, &inst.Occurrence, &inst.StartDate, &inst.EndDate, &inst.Status); err != nil {
+			return nil, err
+		}
+		instances = append(instances, inst)
+	}
+	return instances, rows.Err()
+}
+
+// requireTemplateOrganizer загружает шаблон и проверяет права на его школу.
+func requireTemplateOrganizer(w http.ResponseWriter, r *http.Request, db *sql.DB, userID int) (models.PublicationTemplate, bool) {
+	templateID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil || templateID <= 0 {
+		utils.RespondWithError(w, http.StatusBadRequest, models.Error{Message: "Invalid template ID"})
+		return models.PublicationTemplate{}, false
+	}
+	t, err := loadPublicationTemplate(db, templateID)
+	if err == sql.ErrNoRows {
+		utils.RespondWithError(w, http.StatusNotFound, models.Error{Message: "Template not found"})
+		return t, false
+	} else if err != nil {
+		log.Printf("Error fetching template %d: %v", templateID, err)
+		utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Error fetching template"})
+		return t, false
+	}
+	allowed, err := canManageEvents(db, userID, t.SchoolID)
+	if err != nil {
+		utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Error checking permissions"})
+		return t, false
+	}
+	if !allowed {
+		utils.RespondWithError(w, http.StatusForbidden, models.Error{Message: "Only event organisers can do this"})
+		return t, false
+	}
+	return t, true
+}
+
+func monthsJSON(t models.PublicationTemplate) interface{} {
+	if len(t.RecurrenceMonths) == 0 {
+		return nil
+	}
+	data, _ := json.Marshal(t.RecurrenceMonths)
+	return string(data)
+}
+
+// CreatePublicationTemplate создаёт шаблон школы и сразу генерирует
+// экземпляры на ближайшие months_ahead месяцев.
+func (tc *PublicationTemplateController) CreatePublicationTemplate(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := utils.VerifyToken(r)
+		if err != nil {
+			utils.RespondWithError(w, http.StatusUnauthorized, models.Error{Message: "Unauthorized"})
+			return
+		}
+		schoolID, err := strconv.Atoi(mux.Vars(r)["school_id"])
+		if err != nil || schoolID <= 0 {
+			utils.RespondWithError(w, http.StatusBadRequest, models.Error{Message: "Invalid school ID"})
+			return
+		}
+		allowed, err := canManageEvents(db, userID, schoolID)
+		if err != nil {
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Error checking permissions"})
+			return
+		}
+		if !allowed {
+			utils.RespondWithError(w, http.StatusForbidden, models.Error{Message: "Only event organisers can do this"})
+			return
+		}
+
+		var t models.PublicationTemplate
+		if err := json.NewDecoder(r.Body).Decode(&t); err != nil {
+			utils.RespondWithError(w, http.StatusBadRequest, models.Error{Message: "Invalid request body"})
+			return
+		}
+		if err := validatePublicationTemplate(&t); err != nil {
+			utils.RespondWithError(w, http.StatusBadRequest, models.Error{Message: err.Error()})
+			return
+		}
+
+		res, err := db.Exec(`
+			INSERT INTO publication_templates (
+				item_type, school_id, name, description, location, category, level, grade, limit_count, photo,
+				start_time, duration_days, recurrence, recurrence_months, recurrence_day, first_date, until_date,
+				months_ahead, auto_submit, created_by
+			) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			t.ItemType, schoolID, t.Name, toNullString(t.Description), toNullString(t.Location),
+			toNullString(t.Category), toNullString(t.Level), t.Grade, t.Limit, toNullString(t.Photo),
+			toNullString(t.StartTime), t.DurationDays, t.Recurrence, monthsJSON(t), t.RecurrenceDay,
+			t.FirstDate, toNullString(t.UntilDate), t.MonthsAhead, t.AutoSubmit, userID)
+		if err != nil {
+			log.Printf("Error creating template: %v", err)
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Failed to create template"})
+			return
+		}
+		templateID, _ := res.LastInsertId()
+
+		t, err = loadPublicationTemplate(db, int(templateID))
+		if err != nil {
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Template created, but failed to load it"})
+			return
+		}
+		if _, err := generateTemplateInstances(db, t, time.Now()); err != nil {
+			log.Printf("Error generating instances for template %d: %v", t.ID, err)
+		}
+		if t.Instances, err = loadTemplateInstances(db, t); err != nil {
+			log.Printf("Error fetching instances for template %d: %v", t.ID, err)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		utils.ResponseJSON(w, t)
+	}
+}
+
+// GetSchoolTemplates — шаблоны школы (?item_type=event|olympiad).
+func (tc *PublicationTemplateController) GetSchoolTemplates(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := utils.VerifyToken(r)
+		if err != nil {
+			utils.RespondWithError(w, http.StatusUnauthorized, models.Error{Message: "Unauthorized"})
+			return
+		}
+		schoolID, err := strconv.Atoi(mux.Vars(r)["school_id"])
+		if err != nil || schoolID <= 0 {
+			utils.RespondWithError(w, http.StatusBadRequest, models.Error{Message: "Invalid school ID"})
+			return
+		}
+		allowed, err := canManageEvents(db, userID, schoolID)
+		if err != nil {
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Error checking permissions"})
+			return
+		}
+		if !allowed {
+			utils.RespondWithError(w, http.StatusForbidden, models.Error{Message: "Only event organisers can do this"})
+			return
+		}
+
+		query := templateSelect + " WHERE school_id = ?"
+		args := []interface{}{schoolID}
+		if itemType := r.URL.Query().Get("item_type"); itemType != "" {
+			if _, ok := publicationKinds[itemType]; !ok {
+				utils.RespondWithError(w, http.StatusBadRequest, models.Error{Message: "item_type must be event or olympiad"})
+				return
+			}
+			query += " AND item_type = ?"
+			args = append(args, itemType)
+		}
+		rows, err := db.Query(query+" ORDER BY name, id", args...)
+		if err != nil {
+			log.Printf("Error fetching templates for school %d: %v", schoolID, err)
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Failed to fetch templates"})
+			return
+		}
+		defer rows.Close()
+
+		templates := []models.PublicationTemplate{}
+		for rows.Next() {
+			t, err := scanPublicationTemplate(rows.Scan)
+			if err != nil {
+				log.Printf("Error scanning template: %v", err)
+				utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Failed to fetch templates"})
+				return
+			}
+			templates = append(templates, t)
+		}
+		utils.ResponseJSON(w, templates)
+	}
+}
+
+// GetPublicationTemplate — шаблон со списком созданных экземпляров.
+func (tc *PublicationTemplateController) GetPublicationTemplate(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := utils.VerifyToken(r)
+		if err != nil {
+			utils.RespondWithError(w, http.StatusUnauthorized, models.Error{Message: "Unauthorized"})
+			return
+		}
+		t, ok := requireTemplateOrganizer(w, r, db, userID)
+		if !ok {
+			return
+		}
+		if t.Instances, err = loadTemplateInstances(db, t); err != nil {
+			log.Printf("Error fetching instances for template %d: %v", t.ID, err)
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Failed to fetch template instances"})
+			return
+		}
+		utils.ResponseJSON(w, t)
+	}
+}
+
+// UpdatePublicationTemplate заменяет шаблон. С ?propagate=true изменения
+// переносятся на будущие экземпляры; новые даты по изменённому правилу
+// повторения создаются, а даты, выпавшие из правила, убираются в любом
+// случае. Тип шаблона менять нельзя.
+func (tc *PublicationTemplateController) UpdatePublicationTemplate(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := utils.VerifyToken(r)
+		if err != nil {
+			utils.RespondWithError(w, http.StatusUnauthorized, models.Error{Message: "Unauthorized"})
+			return
+		}
+		current, ok := requireTemplateOrganizer(w, r, db, userID)
+		if !ok {
+			return
+		}
+
+		var t models.PublicationTemplate
+		if err := json.NewDecoder(r.Body).Decode(&t); err != nil {
+			utils.RespondWithError(w, http.StatusBadRequest, models.Error{Message: "Invalid request body"})
+			return
+		}
+		if t.ItemType == "" {
+			t.ItemType = current.ItemType
+		}
+		if t.ItemType != current.ItemType {
+			utils.RespondWithError(w, http.StatusBadRequest, models.Error{Message: "item_type cannot be changed"})
+			return
+		}
+		if err := validatePublicationTemplate(&t); err != nil {
+			utils.RespondWithError(w, http.StatusBadRequest, models.Error{Message: err.Error()})
+			return
+		}
+
+		_, err = db.Exec(`
+			UPDATE publication_templates
+			SET name = ?, description = ?, location = ?, category = ?, level = ?, grade = ?, limit_count = ?, photo = ?,
+			    start_time = ?, duration_days = ?, recurrence = ?, recurrence_months = ?, recurrence_day = ?,
+			    first_date = ?, until_date = ?, months_ahead = ?, auto_submit = ?
+			WHERE id = ?`,
+			t.Name, toNullString(t.Description), toNullString(t.Location), toNullString(t.Category), toNullString(t.Level),
+			t.Grade, t.Limit, toNullString(t.Photo), toNullString(t.StartTime), t.DurationDays, t.Recurrence,
+			monthsJSON(t), t.RecurrenceDay, t.FirstDate, toNullString(t.UntilDate), t.MonthsAhead, t.AutoSubmit, current.ID)
+		if err != nil {
+			log.Printf("Error updating template %d: %v", current.ID, err)
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Failed to update template"})
+			return
+		}
+
+		updated, err := loadPublicationTemplate(db, current.ID)
+		if err != nil {
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Template updated, but failed to load it"})
+			return
+		}
+
+		now := time.Now()
+		removed, detached, err := removeStaleTemplateInstances(db, current, updated, now)
+		if err != nil {
+			log.Printf("Error removing stale instances of template %d: %v", updated.ID, err)
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Template updated, but failed to remove instances on dates outside the new rule"})
+			return
+		}
+		var propagated templatePropagation
+		if r.URL.Query().Get("propagate") == "true" {
+			if propagated, err = propagateTemplate(db, updated, userID, now); err != nil {
+				log.Printf("Error propagating template %d: %v", updated.ID, err)
+				utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Template updated, but failed to update future instances"})
+				return
+			}
+		}
+		created, err := generateTemplateInstances(db, updated, now)
+		if err != nil {
+			log.Printf("Error generating instances for template %d: %v", updated.ID, err)
+		}
+		if updated.Instances, err = loadTemplateInstances(db, updated); err != nil {
+			log.Printf("Error fetching instances for template %d: %v", updated.ID, err)
+		}
+
+		utils.ResponseJSON(w, map[string]interface{}{
+			"template":            updated,
+			"propagation":         propagated,
+			"generated_instances": created,
+			"removed_instances":   removed,
+			"detached_instances":  detached,
+		})
+	}
+}
+
+// DeletePublicationTemplate удаляет шаблон. Созданные экземпляры остаются
+// обычными мероприятиями/олимпиадами.
+func (tc *PublicationTemplateController) DeletePublicationTemplate(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := utils.VerifyToken(r)
+		if err != nil {
+			utils.RespondWithError(w, http.StatusUnauthorized, models.Error{Message: "Unauthorized"})
+			return
+		}
+		t, ok := requireTemplateOrganizer(w, r, db, userID)
+		if !ok {
+			return
+		}
+		if _, err := db.Exec("DELETE FROM publication_templates WHERE id = ?", t.ID); err != nil {
+			log.Printf("Error deleting template %d: %v", t.ID, err)
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Failed to delete template"})
+			return
+		}
+		utils.ResponseJSON(w, map[string]string{"message": "Template deleted"})
+	}
+}
+
+// GenerateTemplateInstances создаёт недостающие экземпляры сейчас, не
+// дожидаясь фоновой задачи.
+func (tc *PublicationTemplateController) GenerateTemplateInstances(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := utils.VerifyToken(r)
+		if err != nil {
+			utils.RespondWithError(w, http.StatusUnauthorized, models.Error{Message: "Unauthorized"})
+			return
+		}
+		t, ok := requireTemplateOrganizer(w, r, db, userID)
+		if !ok {
+			return
+		}
+		created, err := generateTemplateInstances(db, t, time.Now())
+		if err != nil {
+			log.Printf("Error generating instances for template %d: %v", t.ID, err)
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Failed to generate instances"})
+			return
+		}
+		utils.ResponseJSON(w, map[string]interface{}{"template_id": t.ID, "generated_instances": created})
+	}
+}
+
+// CreateInstanceFromTemplate создаёт один экземпляр на произвольную дату
+// (в том числе для шаблонов без повторения).
+func (tc *PublicationTemplateController) CreateInstanceFromTemplate(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := utils.VerifyToken(r)
+		if err != nil {
+			utils.RespondWithError(w, http.StatusUnauthorized, models.Error{Message: "Unauthorized"})
+			return
+		}
+		t, ok := requireTemplateOrganizer(w, r, db, userID)
+		if !ok {
+			return
+		}
+
+		var body struct {
+			Date string `json:"date"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			utils.RespondWithError(w, http.StatusBadRequest, models.Error{Message: "Invalid request body"})
+			return
+		}
+		date, err := time.ParseInLocation("2006-01-02", body.Date, time.Local)
+		if err != nil {
+			utils.RespondWithError(w, http.StatusBadRequest, models.Error{Message: "Invalid date, use YYYY-MM-DD"})
+			return
+		}
+		today := time.Now()
+		if date.Before(time.Date(today.Year(), today.Month(), today.Day(), 0, 0, 0, 0, time.Local)) {
+			utils.RespondWithError(w, http.StatusBadRequest, models.Error{Message: "date must not be in the past"})
+			return
+		}
+
+		// Экземпляр создаёт текущий пользователь: статус зависит от его роли
+		t.CreatedBy = userID
+		itemID, created, err := createTemplateInstance(db, t, date)
+		if err != nil {
+			log.Printf("Error creating instance of template %d: %v", t.ID, err)
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Failed to create instance"})
+			return
+		}
+		if !created {
+			utils.RespondWithError(w, http.StatusConflict, models.Error{Message: "An instance for this date already exists"})
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		utils.ResponseJSON(w, map[string]interface{}{
+			"template_id": t.ID,
+			"item_type":   t.ItemType,
+			"item_id":     itemID,
+			"occurrence":  body.Date,
+		})
+	}
+}
@@ -0,0 +1,68 @@
+package controllers
+
+import (
+	"ranking-school/models"
+	"testing"
+	"time"
+)
+
+func TestTemplateOccurrences(t *testing.T) {
+	date := func(s string) time.Time {
+		d, err := time.ParseInLocation("2006-01-02", s, time.Local)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return d
+	}
+	template := func(recurrence, first string, day int, months ...int) models.PublicationTemplate {
+		return models.PublicationTemplate{Recurrence: recurrence, FirstDate: first, RecurrenceDay: day, RecurrenceMonths: months}
+	}
+	withUntil := func(t models.PublicationTemplate, until string) models.PublicationTemplate {
+		t.UntilDate = until
+		return t
+	}
+
+	tests := []struct {
+		name     string
+		template models.PublicationTemplate
+		from, to string
+		want     []string
+	}{
+		{"termly", template("termly", "2026-09-01", 1), "2026-09-01", "2027-08-31",
+			[]string{"2026-09-01", "2026-11-01", "2027-01-01", "2027-04-01"}},
+		{"yearly uses the month of first_date", template("yearly", "2025-09-15", 15), "2026-01-01", "2027-12-31",
+			[]string{"2026-09-15", "2027-09-15"}},
+		{"custom months clamp the day to the month end", template("custom", "2026-01-01", 31, 2, 11), "2026-06-01", "2027-06-01",
+			[]string{"2026-11-30", "2027-02-28"}},
+		{"leap year", template("custom", "2027-01-01", 29, 2), "2027-01-01", "2028-12-31",
+			[]string{"2027-02-28", "2028-02-29"}},
+		{"until_date limits the range", withUntil(template("termly", "2026-09-01", 1), "2027-01-15"), "2026-09-01", "2027-08-31",
+			[]string{"2026-09-01", "2026-11-01", "2027-01-01"}},
+		{"nothing before first_date", template("yearly", "2026-10-05", 5), "2026-01-01", "2026-12-31",
+			[]string{"2026-10-05"}},
+		{"occurrence before from is skipped", template("yearly", "2025-10-05", 5), "2026-10-10", "2027-12-31",
+			[]string{"2027-10-05"}},
+		{"single day range", template("termly", "2026-09-01", 1), "2026-11-01", "2026-11-01",
+			[]string{"2026-11-01"}},
+		{"no recurrence", template("none", "2026-09-01", 1), "2026-01-01", "2027-12-31", nil},
+		{"invalid first_date", template("termly", "01.09.2026", 1), "2026-01-01", "2027-12-31", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := templateOccurrences(tt.template, date(tt.from), date(tt.to))
+			var gotDays []string
+			for _, d := range got {
+				gotDays = append(gotDays, d.Format("2006-01-02"))
+			}
+			if len(gotDays) != len(tt.want) {
+				t.Fatalf("templateOccurrences() = %v, want %v", gotDays, tt.want)
+			}
+			for i := range gotDays {
+				if gotDays[i] != tt.want[i] {
+					t.Fatalf("templateOccurrences() = %v, want %v", gotDays, tt.want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,167 @@
+package controllers
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"ranking-school/utils"
+)
+
+// registrationQueue описывает таблицы заявок с ограниченным числом мест.
+// Все операции выполняются в транзакции, которая блокирует строку
+// мероприятия/олимпиады (SELECT ... FOR UPDATE), поэтому параллельные
+// заявки на одно и то же событие проверяют лимит по очереди.
+type registrationQueue struct {
+	parentTable   string // Events / subject_olympiads
+	parentKey     string
+	limitExpr     string // лимит мест, 0 — без ограничения
+	counterColumn string // денормализованный счётчик занятых мест
+	titleColumn   string
+	regTable      string
+	regKey        string
+	regParentKey  string
+	seatStatuses  string // статусы заявок, занимающие место
+	kind          string // тип уведомления о переводе из листа ожидания
+	label         string
+}
+
+var eventQueue = registrationQueue{
+	parentTable:   "Events",
+	parentKey:     "id",
+	limitExpr:     "COALESCE(limit_count, 0)",
+	counterColumn: "participants",
+	titleColumn:   "event_name",
+	regTable:      "EventRegistrations",
+	regKey:        "event_registration_id",
+	regParentKey:  "event_id",
+	seatStatuses:  "'registered', 'accepted', 'completed'",
+	kind:          "event_waitlist_promoted",
+	label:         "мероприятие",
+}
+
+var olympiadQueue = registrationQueue{
+	parentTable:   "subject_olympiads",
+	parentKey:     "subject_olympiad_id",
+	limitExpr:     "COALESCE(limit_participants, 100)",
+	counterColumn: "current_participants",
+	titleColumn:   "subject_name",
+	regTable:      "olympiad_registrations",
+	regKey:        "olympiads_registrations_id",
+	regParentKey:  "subject_olympiad_id",
+	seatStatuses:  "'registered', 'pending', 'accepted', 'completed'",
+	kind:          "olympiad_waitlist_promoted",
+	label:         "олимпиаду",
+}
+
+var sessionQueue = registrationQueue{
+	parentTable:   "event_sessions",
+	parentKey:     "id",
+	limitExpr:     "COALESCE(capacity, 0)",
+	counterColumn: "participants",
+	titleColumn:   "title",
+	regTable:      "event_session_registrations",
+	regKey:        "id",
+	regParentKey:  "session_id",
+	seatStatuses:  "'registered'",
+	kind:          "session_waitlist_promoted",
+	label:         "сессию",
+}
+
+// waitlistPromotion — ученик, получивший место из листа ожидания.
+type waitlistPromotion struct {
+	RegistrationID int
+	StudentID      int
+	Message        string
+}
+
+// lock блокирует строку события до конца транзакции и возвращает лимит
+// и число занятых мест.
+func (q registrationQueue) lock(tx *sql.Tx, parentID int) (limit, taken int, err error) {
+	err = tx.QueryRow(fmt.Sprintf("SELECT %s FROM %s WHERE %s = ? FOR UPDATE", q.limitExpr, q.parentTable, q.parentKey), parentID).Scan(&limit)
+	if err != nil {
+		return 0, 0, err
+	}
+	err = tx.QueryRow(fmt.Sprintf("SELECT COUNT(*) FROM %s WHERE %s = ? AND status IN (%s)", q.regTable, q.regParentKey, q.seatStatuses), parentID).Scan(&taken)
+	return limit, taken, err
+}
+
+func hasFreeSeat(limit, taken int) bool {
+	return limit <= 0 || taken < limit
+}
+
+// waitlistPosition — место заявки в листе ожидания (с единицы).
+func (q registrationQueue) waitlistPosition(db rowQuerier, parentID, regID int) (int, error) {
+	var position int
+	err := db.QueryRow(fmt.Sprintf(`
+		SELECT COUNT(*) FROM %[1]s w
+		JOIN %[1]s me ON me.%[2]s = ?
+		WHERE w.%[3]s = ? AND w.status = 'waitlisted'
+		  AND (w.registration_date < me.registration_date
+		       OR (w.registration_date = me.registration_date AND w.%[2]s <= me.%[2]s))`,
+		q.regTable, q.regKey, q.regParentKey), regID, parentID).Scan(&position)
+	return position, err
+}
+
+// promote переводит заявки из листа ожидания на свободные места, создаёт
+// уведомления и обновляет счётчик мест. Вызывается после любой отмены.
+func (q registrationQueue) promote(tx *sql.Tx, parentID int) ([]waitlistPromotion, error) {
+	limit, taken, err := q.lock(tx, parentID)
+	if err != nil {
+		return nil, err
+	}
+
+	var title string
+	err = tx.QueryRow(fmt.Sprintf("SELECT COALESCE(%s, '') FROM %s WHERE %s = ?", q.titleColumn, q.parentTable, q.parentKey), parentID).Scan(&title)
+	if err != nil {
+		return nil, err
+	}
+
+	var promotions []waitlistPromotion
+	for hasFreeSeat(limit, taken) {
+		var p waitlistPromotion
+		err := tx.QueryRow(fmt.Sprintf(`
+			SELECT %s, student_id FROM %s
+			WHERE %s = ? AND status = 'waitlisted'
+			ORDER BY registration_date, %s
+			LIMIT 1 FOR UPDATE`, q.regKey, q.regTable, q.regParentKey, q.regKey), parentID).Scan(&p.RegistrationID, &p.StudentID)
+		if err == sql.ErrNoRows {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+
+		_, err = tx.Exec(fmt.Sprintf("UPDATE %s SET status = 'registered' WHERE %s = ?", q.regTable, q.regKey), p.RegistrationID)
+		if err != nil {
+			return nil, err
+		}
+		p.Message = fmt.Sprintf("Освободилось место: вы зарегистрированы на %s «%s»", q.label, title)
+		if err := createNotification(tx, p.StudentID, q.kind, p.Message); err != nil {
+			return nil, err
+		}
+		promotions = append(promotions, p)
+		taken++
+	}
+
+	_, err = tx.Exec(fmt.Sprintf("UPDATE %s SET %s = ? WHERE %s = ?", q.parentTable, q.counterColumn, q.parentKey), taken, parentID)
+	return promotions, err
+}
+
+// emailWaitlistPromotions дублирует уведомления на почту. Вызывается после
+// коммита, письма отправляются в фоне.
+func emailWaitlistPromotions(db *sql.DB, promotions []waitlistPromotion) {
+	if len(promotions) == 0 {
+		return
+	}
+	go func() {
+		for _, p := range promotions {
+			var email sql.NullString
+			if err := db.QueryRow("SELECT email FROM student WHERE student_id = ?", p.StudentID).Scan(&email); err != nil {
+				log.Printf("Failed to load email for student %d: %v", p.StudentID, err)
+				continue
+			}
+			if email.Valid && email.String != "" {
+				utils.SendEmail(email.String, "Место освободилось", p.Message)
+			}
+		}
+	}()
+}
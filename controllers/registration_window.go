@@ -0,0 +1,149 @@
+package controllers
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Окно регистрации задаётся организатором отдельно от дат проведения:
+//   - registration_opens_at — до этого момента заявки не принимаются;
+//   - registration_closes_at — обычная регистрация закрывается
+//     (если не задано — в момент начала мероприятия, как раньше);
+//   - late_registration_until — после закрытия ещё можно подать
+//     позднюю заявку (is_late = 1);
+//   - cancellation_deadline — после него ученик не может сам отменить заявку.
+var registrationWindowFields = []string{
+	"registration_opens_at",
+	"registration_closes_at",
+	"late_registration_until",
+	"cancellation_deadline",
+}
+
+var windowTimeLayouts = []string{"2006-01-02 15:04:05", "2006-01-02T15:04:05", "2006-01-02T15:04", "2006-01-02 15:04", "2006-01-02"}
+
+type registrationWindow struct {
+	StartDate            string
+	OpensAt              sql.NullString
+	ClosesAt             sql.NullString
+	LateUntil            sql.NullString
+	CancellationDeadline sql.NullString
+}
+
+func parseWindowTime(s string) (time.Time, error) {
+	for _, layout := range windowTimeLayouts {
+		if t, err := time.ParseInLocation(layout, s, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid datetime %q", s)
+}
+
+func windowTime(ns sql.NullString) (time.Time, bool) {
+	if !ns.Valid || ns.String == "" {
+		return time.Time{}, false
+	}
+	t, err := parseWindowTime(ns.String)
+	return t, err == nil
+}
+
+// check возвращает текст ошибки, если регистрация сейчас закрыта, и
+// признак поздней регистрации.
+func (rw registrationWindow) check(now time.Time) (late bool, closedMessage string) {
+	if opens, ok := windowTime(rw.OpensAt); ok && now.Before(opens) {
+		return false, "Registration has not opened yet"
+	}
+
+	closes, ok := windowTime(rw.ClosesAt)
+	if !ok {
+		closes, ok = windowTime(sql.NullString{String: rw.StartDate, Valid: true})
+	}
+	if !ok || !now.After(closes) {
+		return false, ""
+	}
+
+	if lateUntil, ok := windowTime(rw.LateUntil); ok && !now.After(lateUntil) {
+		return true, ""
+	}
+	return false, "Registration has already closed"
+}
+
+// canCancel — может ли ученик сам отменить заявку.
+func (rw registrationWindow) canCancel(now time.Time) bool {
+	deadline, ok := windowTime(rw.CancellationDeadline)
+	return !ok || !now.After(deadline)
+}
+
+func loadRegistrationWindow(db rowQuerier, table, key, startColumn string, id int) (registrationWindow, error) {
+	var rw registrationWindow
+	err := db.QueryRow(fmt.Sprintf(`
+		SELECT CAST(%s AS CHAR), CAST(registration_opens_at AS CHAR), CAST(registration_closes_at AS CHAR),
+		       CAST(late_registration_until AS CHAR), CAST(cancellation_deadline AS CHAR)
+		FROM %s WHERE %s = ?`, startColumn, table, key), id).
+		Scan(&rw.StartDate, &rw.OpensAt, &rw.ClosesAt, &rw.LateUntil, &rw.CancellationDeadline)
+	return rw, err
+}
+
+func loadEventWindow(db rowQuerier, eventID int) (registrationWindow, error) {
+	return loadRegistrationWindow(db, "Events", "id", "start_date", eventID)
+}
+
+func loadOlympiadWindow(db rowQuerier, olympiadID int) (registrationWindow, error) {
+	return loadRegistrationWindow(db, "subject_olympiads", "subject_olympiad_id", "date", olympiadID)
+}
+
+// parseRegistrationWindowForm читает поля окна регистрации из формы.
+// Возвращает только переданные поля (колонка → значение); пустая строка
+// или "null" сбрасывает поле. current — текущие значения для проверки порядка.
+func parseRegistrationWindowForm(r *http.Request, current registrationWindow) (map[string]interface{}, error) {
+	fields := make(map[string]interface{})
+	values := map[string]*sql.NullString{
+		"registration_opens_at":   &current.OpensAt,
+		"registration_closes_at":  &current.ClosesAt,
+		"late_registration_until": &current.LateUntil,
+		"cancellation_deadline":   &current.CancellationDeadline,
+	}
+
+	for _, field := range registrationWindowFields {
+		if _, ok := r.Form[field]; !ok {
+			continue
+		}
+		raw := strings.TrimSpace(r.FormValue(field))
+		if raw == "" || raw == "null" {
+			fields[field] = nil
+			*values[field] = sql.NullString{}
+			continue
+		}
+		t, err := parseWindowTime(raw)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s format, use YYYY-MM-DD HH:MM:SS", field)
+		}
+		formatted := t.Format("2006-01-02 15:04:05")
+		fields[field] = formatted
+		*values[field] = sql.NullString{String: formatted, Valid: true}
+	}
+
+	opens, hasOpens := windowTime(current.OpensAt)
+	closes, hasCloses := windowTime(current.ClosesAt)
+	lateUntil, hasLate := windowTime(current.LateUntil)
+	if hasOpens && hasCloses && !opens.Before(closes) {
+		return nil, fmt.Errorf("registration_opens_at must be before registration_closes_at")
+	}
+	if hasLate && !hasCloses {
+		return nil, fmt.Errorf("late_registration_until requires registration_closes_at")
+	}
+	if hasLate && !lateUntil.After(closes) {
+		return nil, fmt.Errorf("late_registration_until must be after registration_closes_at")
+	}
+	return fields, nil
+}
+
+// windowValue — значение поля окна для ответа API.
+func windowValue(ns sql.NullString) string {
+	if !ns.Valid {
+		return ""
+	}
+	return ns.String
+}
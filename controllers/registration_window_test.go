@@ -0,0 +1,71 @@
+package controllers
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+)
+
+func testWindowTime(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
+
+func TestRegistrationWindowCheck(t *testing.T) {
+	window := registrationWindow{
+		StartDate: "2026-03-20 09:00:00",
+		OpensAt:   testWindowTime("2026-03-01 00:00:00"),
+		ClosesAt:  testWindowTime("2026-03-15 18:00:00"),
+		LateUntil: testWindowTime("2026-03-18"),
+	}
+	legacy := registrationWindow{StartDate: "2026-03-20"}
+
+	tests := []struct {
+		name       string
+		window     registrationWindow
+		now        string
+		wantLate   bool
+		wantClosed string
+	}{
+		{"before opening", window, "2026-02-28 23:59:59", false, "Registration has not opened yet"},
+		{"at opening", window, "2026-03-01 00:00:00", false, ""},
+		{"at closing", window, "2026-03-15 18:00:00", false, ""},
+		{"late registration", window, "2026-03-16 10:00:00", true, ""},
+		{"after late deadline", window, "2026-03-18 00:00:01", false, "Registration has already closed"},
+		{"closes at start by default", legacy, "2026-03-19 23:00:00", false, ""},
+		{"closed after start", legacy, "2026-03-20 00:00:01", false, "Registration has already closed"},
+		{"unparseable start never closes", registrationWindow{StartDate: "скоро"}, "2030-01-01", false, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			now, err := parseWindowTime(tt.now)
+			if err != nil {
+				t.Fatal(err)
+			}
+			late, closed := tt.window.check(now)
+			if late != tt.wantLate || closed != tt.wantClosed {
+				t.Errorf("check(%s) = (%v, %q), want (%v, %q)", tt.now, late, closed, tt.wantLate, tt.wantClosed)
+			}
+		})
+	}
+}
+
+func TestRegistrationWindowCanCancel(t *testing.T) {
+	deadline := registrationWindow{CancellationDeadline: testWindowTime("2026-03-10 12:00")}
+	tests := []struct {
+		name   string
+		window registrationWindow
+		now    time.Time
+		want   bool
+	}{
+		{"no deadline", registrationWindow{}, time.Date(2030, 1, 1, 0, 0, 0, 0, time.Local), true},
+		{"before deadline", deadline, time.Date(2026, 3, 10, 11, 59, 0, 0, time.Local), true},
+		{"at deadline", deadline, time.Date(2026, 3, 10, 12, 0, 0, 0, time.Local), true},
+		{"after deadline", deadline, time.Date(2026, 3, 10, 12, 1, 0, 0, time.Local), false},
+	}
+	for _, tt := range tests {
+		if got := tt.window.canCancel(tt.now); got != tt.want {
+			t.Errorf("%s: canCancel() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -440,14 +440,6 @@ func (rc *ReviewController) DeleteReview(db *sql.DB) http.HandlerFunc {
 
 		// For schooladmin, we need to check if the review belongs to their school
 		if userRole == "schooladmin" {
-			var schoolID int
-			err = db.QueryRow("SELECT school_id FROM users WHERE id = ?", tokenUserID).Scan(&schoolID)
-			if err != nil {
-				log.Println("Error fetching school ID for admin:", err)
-				utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Error checking admin permissions"})
-				return
-			}
-
 			var reviewSchoolID int
 			err = db.QueryRow("SELECT school_id FROM Reviews WHERE id = ?", reviewID).Scan(&reviewSchoolID)
 			if err != nil {
@@ -456,7 +448,14 @@ func (rc *ReviewController) DeleteReview(db *sql.DB) http.HandlerFunc {
 				return
 			}
 
-			if schoolID != reviewSchoolID {
+			isMember, err := hasSchoolPermission(db, tokenUserID, reviewSchoolID, "")
+			if err != nil {
+				log.Println("Error checking school membership for admin:", err)
+				utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Error checking admin permissions"})
+				return
+			}
+
+			if !isMember {
 				utils.RespondWithError(w, http.StatusForbidden, models.Error{Message: "You do not have permission to delete reviews for this school"})
 				return
 			}
@@ -216,6 +216,14 @@ func (sc *SchoolController) CreateSchool(db *sql.DB) http.HandlerFunc {
 			return
 		}
 
+		// Шаг 8: Владелец школы становится участником со всеми правами
+		ownerPermissions := strings.Join([]string{PermissionManageStudents, PermissionManageUNT, PermissionManageEvents}, ",")
+		if err := addSchoolMember(db, school.SchoolID, schoolAdminID, ownerPermissions); err != nil {
+			log.Println("Error adding school membership for admin:", err)
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Failed to add admin to school members"})
+			return
+		}
+
 		// Подготовка ответа с учетом nullable полей
 		responseSchool := struct {
 			SchoolID         int      `json:"school_id"`
@@ -286,8 +294,7 @@ func (sc *SchoolController) UpdateSchool(db *sql.DB) http.HandlerFunc {
 
 		// Step 2: Get user role
 		var requesterRole string
-		var requesterSchoolID sql.NullInt64
-		err = db.QueryRow("SELECT role, school_id FROM users WHERE id = ?", requesterID).Scan(&requesterRole, &requesterSchoolID)
+		err = db.QueryRow("SELECT role FROM users WHERE id = ?", requesterID).Scan(&requesterRole)
 		if err != nil {
 			log.Println("Error fetching user role:", err)
 			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Failed to fetch user role"})
@@ -304,9 +311,20 @@ func (sc *SchoolController) UpdateSchool(db *sql.DB) http.HandlerFunc {
 		log.Println("DEBUG: Updating school ID:", schoolID)
 
 		// Access control: ensure the user has permission to update this school
-		if requesterRole != "superadmin" && (requesterRole != "schooladmin" || int(requesterSchoolID.Int64) != schoolID) {
-			utils.RespondWithError(w, http.StatusForbidden, models.Error{Message: "You can only update your own school"})
-			return
+		if requesterRole != "superadmin" {
+			allowed := false
+			if requesterRole == "schooladmin" {
+				allowed, err = canManageSchoolMembers(db, requesterID, schoolID)
+				if err != nil && err != sql.ErrNoRows {
+					log.Println("Error checking school ownership:", err)
+					utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Failed to check permissions"})
+					return
+				}
+			}
+			if !allowed {
+				utils.RespondWithError(w, http.StatusForbidden, models.Error{Message: "You can only update your own school"})
+				return
+			}
 		}
 
 		// Step 4: Fetch the existing school data
@@ -473,10 +491,35 @@ func (sc *SchoolController) UpdateSchool(db *sql.DB) http.HandlerFunc {
 			return
 		}
 
-		// Step 10: Update school data in the database
+		// Step 10: A new admin login makes that admin the school's owner
+		adminChanged := updatedSchool.SchoolAdminLogin.Valid && updatedSchool.SchoolAdminLogin.String != existingSchool.SchoolAdminLogin.String
+		if adminChanged {
+			var newAdminID int
+			err = db.QueryRow("SELECT id FROM users WHERE email = ? AND role = 'schooladmin'", updatedSchool.SchoolAdminLogin.String).Scan(&newAdminID)
+			if err == sql.ErrNoRows {
+				utils.RespondWithError(w, http.StatusBadRequest, models.Error{Message: "School admin not found by provided email"})
+				return
+			} else if err != nil {
+				log.Println("Error fetching new school admin:", err)
+				utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Error fetching new school admin"})
+				return
+			}
+			updatedSchool.UserID = newAdminID
+		}
+
+		tx, err := db.Begin()
+		if err != nil {
+			log.Println("Error starting transaction:", err)
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Failed to update school"})
+			return
+		}
+		defer tx.Rollback()
+
+		// Step 10.1: Update school data in the database
 		query := `
             UPDATE Schools
             SET
+                user_id = ?,
                 school_name = ?,
                 school_address = ?,
                 city = ?,
@@ -489,7 +532,8 @@ func (sc *SchoolController) UpdateSchool(db *sql.DB) http.HandlerFunc {
                 updated_at = NOW()
             WHERE school_id = ?
         `
-		result, err := db.Exec(query,
+		result, err := tx.Exec(query,
+			updatedSchool.UserID,
 			updatedSchool.SchoolName,
 			updatedSchool.SchoolAddress,
 			updatedSchool.City,
@@ -510,6 +554,41 @@ func (sc *SchoolController) UpdateSchool(db *sql.DB) http.HandlerFunc {
 		rowsAffected, _ := result.RowsAffected()
 		log.Println("DEBUG: Rows affected:", rowsAffected)
 
+		// Step 10.2: The new owner gets a full membership, the previous owner loses theirs
+		if adminChanged && updatedSchool.UserID != existingSchool.UserID {
+			ownerPermissions := strings.Join([]string{PermissionManageStudents, PermissionManageUNT, PermissionManageEvents}, ",")
+			if err := addSchoolMember(tx, schoolID, updatedSchool.UserID, ownerPermissions); err != nil {
+				log.Println("Error adding school membership for new admin:", err)
+				utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Failed to add admin to school members"})
+				return
+			}
+
+			if existingSchool.UserID != 0 {
+				if _, err := tx.Exec("DELETE FROM school_members WHERE school_id = ? AND user_id = ?", schoolID, existingSchool.UserID); err != nil {
+					log.Println("Error removing previous admin membership:", err)
+					utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Failed to update school members"})
+					return
+				}
+				nextSchoolID, err := getMemberSchoolID(tx, existingSchool.UserID, "")
+				if err != nil {
+					log.Println("Error fetching remaining membership:", err)
+					utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Failed to update school members"})
+					return
+				}
+				if _, err := tx.Exec("UPDATE users SET school_id = ? WHERE id = ? AND school_id = ?", nextSchoolID, existingSchool.UserID, schoolID); err != nil {
+					log.Println("Error updating previous admin school_id:", err)
+					utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Failed to update school members"})
+					return
+				}
+			}
+		}
+
+		if err := tx.Commit(); err != nil {
+			log.Println("Error committing school update:", err)
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Failed to update school"})
+			return
+		}
+
 		// Step 11: Return the updated school data as a response
 		responseSchool := struct {
 			SchoolID         int      `json:"school_id"`
@@ -650,10 +729,10 @@ func (sc SchoolController) DeleteSchool(db *sql.DB) http.HandlerFunc {
 
 		// 4. Удаление зависимых данных
 		relatedTables := []string{
+			"school_members",
 			"events_participants",
 			"subject_olympiads",
 			"UNT_Exams",
-			"Second_Type",
 			"student",
 			"Events",
 		}
@@ -1241,7 +1320,7 @@ func (sc *SchoolController) GetSchoolByID(db *sql.DB) http.HandlerFunc {
 				UNT_Exams 
 			WHERE 
 				school_id = ? 
-				AND exam_type = 'regular'`
+				AND exam_type = 'regular' AND attempt_kind = 'official'`
 
 		err = db.QueryRow(examQuery, schoolID).Scan(&regularAverage, &regularCount, &highAchieversCount)
 		if err != nil && err != sql.ErrNoRows {
@@ -0,0 +1,416 @@
+package controllers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"log"
+	"net/http"
+	"ranking-school/models"
+	"ranking-school/utils"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+type SchoolMemberController struct{}
+
+// Права участника школы (колонка school_members.permissions)
+const (
+	PermissionManageStudents = "manage_students"
+	PermissionManageUNT      = "manage_unt"
+	PermissionManageEvents   = "manage_events"
+	PermissionReadOnly       = "read_only"
+)
+
+var validSchoolPermissions = map[string]bool{
+	PermissionManageStudents: true,
+	PermissionManageUNT:      true,
+	PermissionManageEvents:   true,
+	PermissionReadOnly:       true,
+}
+
+// rowQuerier is satisfied by both *sql.DB and *sql.Tx.
+type rowQuerier interface {
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+// getMemberSchoolID returns the school where the user is a member with the given
+// permission. An empty permission matches any membership, including read-only.
+// The result is invalid (not an error) when the user has no such membership.
+func getMemberSchoolID(db rowQuerier, userID int, permission string) (sql.NullInt64, error) {
+	var schoolID sql.NullInt64
+	query := "SELECT school_id FROM school_members WHERE user_id = ?"
+	args := []interface{}{userID}
+	if permission != "" {
+		query += " AND FIND_IN_SET(?, permissions) > 0"
+		args = append(args, permission)
+	}
+	query += " ORDER BY school_id LIMIT 1"
+
+	err := db.QueryRow(query, args...).Scan(&schoolID)
+	if err == sql.ErrNoRows {
+		return sql.NullInt64{}, nil
+	}
+	return schoolID, err
+}
+
+// hasSchoolPermission reports whether the user is a member of the school with the
+// given permission. An empty permission checks plain membership.
+func hasSchoolPermission(db rowQuerier, userID, schoolID int, permission string) (bool, error) {
+	query := "SELECT EXISTS(SELECT 1 FROM school_members WHERE user_id = ? AND school_id = ?"
+	args := []interface{}{userID, schoolID}
+	if permission != "" {
+		query += " AND FIND_IN_SET(?, permissions) > 0"
+		args = append(args, permission)
+	}
+	query += ")"
+
+	var ok bool
+	err := db.QueryRow(query, args...).Scan(&ok)
+	return ok, err
+}
+
+// normalizeSchoolPermissions validates the permission list and collapses it to the
+// SET value stored in the database. read_only excludes every manage_* permission.
+func normalizeSchoolPermissions(permissions []string) (string, bool) {
+	seen := map[string]bool{}
+	var result []string
+	for _, p := range permissions {
+		p = strings.TrimSpace(p)
+		if !validSchoolPermissions[p] {
+			return "", false
+		}
+		if !seen[p] {
+			seen[p] = true
+			result = append(result, p)
+		}
+	}
+	if len(result) == 0 || seen[PermissionReadOnly] {
+		return PermissionReadOnly, true
+	}
+	return strings.Join(result, ","), true
+}
+
+// canManageSchoolMembers allows superadmins and the school's primary admin (Schools.user_id).
+func canManageSchoolMembers(db *sql.DB, userID, schoolID int) (bool, error) {
+	var role string
+	if err := db.QueryRow("SELECT role FROM users WHERE id = ?", userID).Scan(&role); err != nil {
+		return false, err
+	}
+	if role == "superadmin" {
+		return true, nil
+	}
+
+	var ownerID sql.NullInt64
+	err := db.QueryRow("SELECT user_id FROM Schools WHERE school_id = ?", schoolID).Scan(&ownerID)
+	if err != nil {
+		return false, err
+	}
+	return ownerID.Valid && int(ownerID.Int64) == userID, nil
+}
+
+// addSchoolMember inserts or updates a membership and fills users.school_id for
+// admins that don't have one yet, so older profile endpoints keep working.
+func addSchoolMember(db execQuerier, schoolID, userID int, permissions string) error {
+	_, err := db.Exec(`
+		INSERT INTO school_members (school_id, user_id, permissions)
+		VALUES (?, ?, ?)
+		ON DUPLICATE KEY UPDATE permissions = VALUES(permissions)`,
+		schoolID, userID, permissions)
+	if err != nil {
+		return err
+	}
+	_, err = db.Exec("UPDATE users SET school_id = ? WHERE id = ? AND school_id IS NULL", schoolID, userID)
+	return err
+}
+
+func (smc *SchoolMemberController) GetSchoolMembers(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := utils.VerifyToken(r)
+		if err != nil {
+			utils.RespondWithError(w, http.StatusUnauthorized, models.Error{Message: err.Error()})
+			return
+		}
+
+		schoolID, err := strconv.Atoi(mux.Vars(r)["school_id"])
+		if err != nil {
+			utils.RespondWithError(w, http.StatusBadRequest, models.Error{Message: "Invalid school ID"})
+			return
+		}
+
+		var role string
+		if err := db.QueryRow("SELECT role FROM users WHERE id = ?", userID).Scan(&role); err != nil {
+			log.Println("Error fetching user role:", err)
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Error fetching user details"})
+			return
+		}
+		if role != "superadmin" {
+			isMember, err := hasSchoolPermission(db, userID, schoolID, "")
+			if err != nil {
+				log.Println("Error checking school membership:", err)
+				utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Error checking permissions"})
+				return
+			}
+			if !isMember {
+				utils.RespondWithError(w, http.StatusForbidden, models.Error{Message: "You are not a member of this school"})
+				return
+			}
+		}
+
+		rows, err := db.Query(`
+			SELECT sm.id, sm.school_id, sm.user_id, u.email, COALESCE(u.first_name, ''), COALESCE(u.last_name, ''),
+			       sm.permissions, (s.user_id = sm.user_id) AS is_owner, sm.created_at
+			FROM school_members sm
+			JOIN users u ON u.id = sm.user_id
+			JOIN Schools s ON s.school_id = sm.school_id
+			WHERE sm.school_id = ?
+			ORDER BY is_owner DESC, sm.id`, schoolID)
+		if err != nil {
+			log.Println("Error fetching school members:", err)
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Failed to fetch school members"})
+			return
+		}
+		defer rows.Close()
+
+		members := []models.SchoolMember{}
+		for rows.Next() {
+			var m models.SchoolMember
+			var permissions string
+			var isOwner sql.NullBool
+			var createdAt sql.NullString
+			if err := rows.Scan(&m.ID, &m.SchoolID, &m.UserID, &m.Email, &m.FirstName, &m.LastName,
+				&permissions, &isOwner, &createdAt); err != nil {
+				log.Println("Error scanning school member:", err)
+				utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Failed to parse school members"})
+				return
+			}
+			m.Permissions = strings.Split(permissions, ",")
+			m.IsOwner = isOwner.Valid && isOwner.Bool
+			m.CreatedAt = createdAt.String
+			members = append(members, m)
+		}
+
+		utils.ResponseJSON(w, members)
+	}
+}
+
+func (smc *SchoolMemberController) AddSchoolMember(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := utils.VerifyToken(r)
+		if err != nil {
+			utils.RespondWithError(w, http.StatusUnauthorized, models.Error{Message: err.Error()})
+			return
+		}
+
+		schoolID, err := strconv.Atoi(mux.Vars(r)["school_id"])
+		if err != nil {
+			utils.RespondWithError(w, http.StatusBadRequest, models.Error{Message: "Invalid school ID"})
+			return
+		}
+
+		allowed, err := canManageSchoolMembers(db, userID, schoolID)
+		if err == sql.ErrNoRows {
+			utils.RespondWithError(w, http.StatusNotFound, models.Error{Message: "School not found"})
+			return
+		} else if err != nil {
+			log.Println("Error checking member management rights:", err)
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Error checking permissions"})
+			return
+		}
+		if !allowed {
+			utils.RespondWithError(w, http.StatusForbidden, models.Error{Message: "Only superadmin or the school's primary admin can manage members"})
+			return
+		}
+
+		var body struct {
+			UserID      int      `json:"user_id"`
+			Email       string   `json:"email"`
+			Permissions []string `json:"permissions"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			utils.RespondWithError(w, http.StatusBadRequest, models.Error{Message: "Invalid request body"})
+			return
+		}
+
+		permissions, ok := normalizeSchoolPermissions(body.Permissions)
+		if !ok {
+			utils.RespondWithError(w, http.StatusBadRequest, models.Error{Message: "Unknown permission. Allowed: manage_students, manage_unt, manage_events, read_only"})
+			return
+		}
+
+		var memberID int
+		var memberRole string
+		if body.UserID > 0 {
+			err = db.QueryRow("SELECT id, role FROM users WHERE id = ?", body.UserID).Scan(&memberID, &memberRole)
+		} else if body.Email != "" {
+			err = db.QueryRow("SELECT id, role FROM users WHERE email = ?", body.Email).Scan(&memberID, &memberRole)
+		} else {
+			utils.RespondWithError(w, http.StatusBadRequest, models.Error{Message: "user_id or email is required"})
+			return
+		}
+		if err == sql.ErrNoRows {
+			utils.RespondWithError(w, http.StatusNotFound, models.Error{Message: "User not found"})
+			return
+		} else if err != nil {
+			log.Println("Error fetching user:", err)
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Error fetching user"})
+			return
+		}
+		if memberRole != "schooladmin" {
+			utils.RespondWithError(w, http.StatusBadRequest, models.Error{Message: "Only users with the schooladmin role can be school members"})
+			return
+		}
+
+		if err := addSchoolMember(db, schoolID, memberID, permissions); err != nil {
+			log.Println("Error adding school member:", err)
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Failed to add school member"})
+			return
+		}
+
+		utils.ResponseJSON(w, map[string]interface{}{
+			"message":     "School member saved successfully",
+			"school_id":   schoolID,
+			"user_id":     memberID,
+			"permissions": strings.Split(permissions, ","),
+		})
+	}
+}
+
+func (smc *SchoolMemberController) UpdateSchoolMember(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := utils.VerifyToken(r)
+		if err != nil {
+			utils.RespondWithError(w, http.StatusUnauthorized, models.Error{Message: err.Error()})
+			return
+		}
+
+		vars := mux.Vars(r)
+		schoolID, err := strconv.Atoi(vars["school_id"])
+		if err != nil {
+			utils.RespondWithError(w, http.StatusBadRequest, models.Error{Message: "Invalid school ID"})
+			return
+		}
+		memberID, err := strconv.Atoi(vars["user_id"])
+		if err != nil {
+			utils.RespondWithError(w, http.StatusBadRequest, models.Error{Message: "Invalid user ID"})
+			return
+		}
+
+		allowed, err := canManageSchoolMembers(db, userID, schoolID)
+		if err == sql.ErrNoRows {
+			utils.RespondWithError(w, http.StatusNotFound, models.Error{Message: "School not found"})
+			return
+		} else if err != nil {
+			log.Println("Error checking member management rights:", err)
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Error checking permissions"})
+			return
+		}
+		if !allowed {
+			utils.RespondWithError(w, http.StatusForbidden, models.Error{Message: "Only superadmin or the school's primary admin can manage members"})
+			return
+		}
+
+		var body struct {
+			Permissions []string `json:"permissions"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			utils.RespondWithError(w, http.StatusBadRequest, models.Error{Message: "Invalid request body"})
+			return
+		}
+
+		permissions, ok := normalizeSchoolPermissions(body.Permissions)
+		if !ok {
+			utils.RespondWithError(w, http.StatusBadRequest, models.Error{Message: "Unknown permission. Allowed: manage_students, manage_unt, manage_events, read_only"})
+			return
+		}
+
+		result, err := db.Exec("UPDATE school_members SET permissions = ? WHERE school_id = ? AND user_id = ?", permissions, schoolID, memberID)
+		if err != nil {
+			log.Println("Error updating school member:", err)
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Failed to update school member"})
+			return
+		}
+		if rows, _ := result.RowsAffected(); rows == 0 {
+			var exists bool
+			db.QueryRow("SELECT EXISTS(SELECT 1 FROM school_members WHERE school_id = ? AND user_id = ?)", schoolID, memberID).Scan(&exists)
+			if !exists {
+				utils.RespondWithError(w, http.StatusNotFound, models.Error{Message: "School member not found"})
+				return
+			}
+		}
+
+		utils.ResponseJSON(w, map[string]interface{}{
+			"message":     "School member updated successfully",
+			"school_id":   schoolID,
+			"user_id":     memberID,
+			"permissions": strings.Split(permissions, ","),
+		})
+	}
+}
+
+func (smc *SchoolMemberController) DeleteSchoolMember(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := utils.VerifyToken(r)
+		if err != nil {
+			utils.RespondWithError(w, http.StatusUnauthorized, models.Error{Message: err.Error()})
+			return
+		}
+
+		vars := mux.Vars(r)
+		schoolID, err := strconv.Atoi(vars["school_id"])
+		if err != nil {
+			utils.RespondWithError(w, http.StatusBadRequest, models.Error{Message: "Invalid school ID"})
+			return
+		}
+		memberID, err := strconv.Atoi(vars["user_id"])
+		if err != nil {
+			utils.RespondWithError(w, http.StatusBadRequest, models.Error{Message: "Invalid user ID"})
+			return
+		}
+
+		allowed, err := canManageSchoolMembers(db, userID, schoolID)
+		if err == sql.ErrNoRows {
+			utils.RespondWithError(w, http.StatusNotFound, models.Error{Message: "School not found"})
+			return
+		} else if err != nil {
+			log.Println("Error checking member management rights:", err)
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Error checking permissions"})
+			return
+		}
+		if !allowed {
+			utils.RespondWithError(w, http.StatusForbidden, models.Error{Message: "Only superadmin or the school's primary admin can manage members"})
+			return
+		}
+
+		// Главного администратора школы убрать нельзя, его меняют через UpdateSchool
+		var ownerID sql.NullInt64
+		db.QueryRow("SELECT user_id FROM Schools WHERE school_id = ?", schoolID).Scan(&ownerID)
+		if ownerID.Valid && int(ownerID.Int64) == memberID {
+			utils.RespondWithError(w, http.StatusBadRequest, models.Error{Message: "The school's primary admin cannot be removed"})
+			return
+		}
+
+		result, err := db.Exec("DELETE FROM school_members WHERE school_id = ? AND user_id = ?", schoolID, memberID)
+		if err != nil {
+			log.Println("Error deleting school member:", err)
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Failed to delete school member"})
+			return
+		}
+		if rows, _ := result.RowsAffected(); rows == 0 {
+			utils.RespondWithError(w, http.StatusNotFound, models.Error{Message: "School member not found"})
+			return
+		}
+
+		// Переносим users.school_id на другое членство, если оно есть
+		nextSchoolID, err := getMemberSchoolID(db, memberID, "")
+		if err != nil {
+			log.Println("Error fetching remaining membership:", err)
+		}
+		if _, err := db.Exec("UPDATE users SET school_id = ? WHERE id = ? AND school_id = ?", nextSchoolID, memberID, schoolID); err != nil {
+			log.Println("Error updating user school_id:", err)
+		}
+
+		utils.ResponseJSON(w, map[string]string{"message": "School member removed successfully"})
+	}
+}
@@ -15,6 +15,7 @@ func GetEventParticipantRank(db *sql.DB, schoolID int) (float64, string, error)
 		SELECT s.school_name, COUNT(r.event_registration_id)
 		FROM Schools s
 		LEFT JOIN EventRegistrations r ON s.school_id = r.school_id
+		JOIN Events e ON e.id = r.event_id AND e.status = 'published'
 		WHERE s.school_id = ? AND r.status IN ('registered', 'accepted', 'completed')
 		GROUP BY s.school_name
 	`, schoolID).Scan(&schoolName, &participantCount)
@@ -28,6 +29,7 @@ func GetEventParticipantRank(db *sql.DB, schoolID int) (float64, string, error)
 		SELECT COUNT(r.event_registration_id)
 		FROM Schools s
 		LEFT JOIN EventRegistrations r ON s.school_id = r.school_id
+		JOIN Events e ON e.id = r.event_id AND e.status = 'published'
 		WHERE r.status IN ('registered', 'accepted', 'completed')
 	`).Scan(&maxParticipants)
 
@@ -43,7 +45,7 @@ func GetEventParticipantRank(db *sql.DB, schoolID int) (float64, string, error)
 func GetEventScoreRank(db *sql.DB, schoolID int) (float64, error) {
 	var eventCount int
 	err := db.QueryRow(`
-		SELECT COUNT(id) FROM Events WHERE school_id = ?
+		SELECT COUNT(id) FROM Events WHERE school_id = ? AND status = 'published'
 	`, schoolID).Scan(&eventCount)
 	if err != nil {
 		return 0, err
@@ -52,7 +54,7 @@ func GetEventScoreRank(db *sql.DB, schoolID int) (float64, error) {
 	var maxEventCount int
 	err = db.QueryRow(`
 		SELECT MAX(event_count) FROM (
-			SELECT COUNT(id) as event_count FROM Events GROUP BY school_id
+			SELECT COUNT(id) as event_count FROM Events WHERE status = 'published' GROUP BY school_id
 		) as counts
 	`).Scan(&maxEventCount)
 	if err != nil || maxEventCount == 0 {
@@ -66,7 +68,7 @@ func GetUNTRank(db *sql.DB, schoolID int) (float64, error) {
 	query := `
 		SELECT exam_type, AVG(total_score), COUNT(*)
 		FROM UNT_Exams
-		WHERE school_id = ? AND exam_type IN ('regular', 'creative')
+		WHERE school_id = ? AND exam_type IN ('regular', 'creative') AND attempt_kind = 'official'
 		GROUP BY exam_type`
 
 	rows, err := db.Query(query, schoolID)
@@ -117,7 +119,7 @@ func GetReviewRank(db *sql.DB, schoolID int) (float64, error) {
 func GetOlympiadRank(db *sql.DB, schoolID int) (float64, string, error) {
 	getLevelRating := func(level string, weight float64) float64 {
 		var count int
-		db.QueryRow(`SELECT COUNT(*) FROM Olympiad_Results WHERE school_id = ? AND level = ?`, schoolID, level).Scan(&count)
+		db.QueryRow(`SELECT COUNT(*) FROM Olympiads WHERE school_id = ? AND level = ? AND olympiad_place IS NOT NULL`, schoolID, level).Scan(&count)
 		return float64(count) * weight
 	}
 
@@ -28,8 +28,7 @@ func (src *SchoolRatingController) GetSchoolCompleteRating(db *sql.DB) http.Hand
 		}
 
 		var userRole string
-		var userSchoolID sql.NullInt64
-		err = db.QueryRow("SELECT role, school_id FROM users WHERE id = ?", userID).Scan(&userRole, &userSchoolID)
+		err = db.QueryRow("SELECT role FROM users WHERE id = ?", userID).Scan(&userRole)
 		if err != nil {
 			log.Println("Ошибка при получении роли пользователя или school_id:", err)
 			utils.RespondWithError(w, http.StatusForbidden, models.Error{Message: "Не удалось получить роль или school_id пользователя"})
@@ -45,7 +44,13 @@ func (src *SchoolRatingController) GetSchoolCompleteRating(db *sql.DB) http.Hand
 		}
 
 		if userRole == "schooladmin" {
-			if !userSchoolID.Valid || userSchoolID.Int64 != schoolID {
+			isMember, err := hasSchoolPermission(db, userID, int(schoolID), "")
+			if err != nil {
+				log.Println("Ошибка при проверке членства в школе:", err)
+				utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Не удалось проверить доступ к школе"})
+				return
+			}
+			if !isMember {
 				utils.RespondWithError(w, http.StatusForbidden, models.Error{Message: "Вы не можете просматривать данные других школ"})
 				return
 			}
@@ -125,6 +130,7 @@ func (src *SchoolRatingController) getUNTRank(db *sql.DB, schoolID int64) (float
         WHERE 
             school_id = ? 
             AND exam_type IN ('regular', 'creative')
+            AND attempt_kind = 'official'
         GROUP BY 
             exam_type`
 
@@ -179,6 +185,14 @@ func (src *SchoolRatingController) getUNTRank(db *sql.DB, schoolID int64) (float
 	return untRank, nil
 }
 
+// Мероприятие засчитывается, если оно опубликовано и на него по QR-билетам
+// пришло не меньше 5% зарегистрированных (и хотя бы один человек).
+const validEventCondition = `e.status = 'published' AND (
+			SELECT COUNT(*) FROM EventRegistrations r WHERE r.event_id = e.id AND r.attendance = 'attended'
+		) >= GREATEST(1, 0.05 * (
+			SELECT COUNT(*) FROM EventRegistrations r WHERE r.event_id = e.id AND r.status IN ('registered', 'accepted', 'completed')
+		))`
+
 func (src *SchoolRatingController) getEventScore(db *sql.DB, schoolID int64) (float64, error) {
 	var schoolValidEventCount int
 	querySchool := `
@@ -186,28 +200,19 @@ func (src *SchoolRatingController) getEventScore(db *sql.DB, schoolID int64) (fl
 		FROM Events e
 		WHERE e.school_id = ?
 		AND e.end_date < CURRENT_DATE
-		AND (
-			SELECT COUNT(*) FROM events_participants ep WHERE ep.events_name = e.event_name
-		) >= 0.05 * (
-			SELECT COUNT(*) FROM EventRegistrations r WHERE r.event_id = e.id
-		)
-	`
+		AND ` + validEventCondition
 	err := db.QueryRow(querySchool, schoolID).Scan(&schoolValidEventCount)
 	if err != nil {
 		return 0.0, err
 	}
 
-	var maxValidEventCount int
+	var maxValidEventCount sql.NullInt64
 	queryMax := `
 		SELECT MAX(valid_event_count) FROM (
 			SELECT e.school_id, COUNT(e.id) AS valid_event_count
 			FROM Events e
 			WHERE e.end_date < CURRENT_DATE
-			AND (
-				SELECT COUNT(*) FROM events_participants ep WHERE ep.events_name = e.event_name
-			) >= 0.05 * (
-				SELECT COUNT(*) FROM EventRegistrations r WHERE r.event_id = e.id
-			)
+			AND ` + validEventCondition + `
 			GROUP BY e.school_id
 		) AS sub
 	`
@@ -217,8 +222,8 @@ func (src *SchoolRatingController) getEventScore(db *sql.DB, schoolID int64) (fl
 	}
 
 	var score float64
-	if maxValidEventCount > 0 {
-		score = (float64(schoolValidEventCount) / float64(maxValidEventCount)) * 20
+	if maxValidEventCount.Valid && maxValidEventCount.Int64 > 0 {
+		score = (float64(schoolValidEventCount) / float64(maxValidEventCount.Int64)) * 20
 	}
 
 	return score, nil
@@ -230,6 +235,7 @@ func (src *SchoolRatingController) getOlympiadActivityScore(db *sql.DB, schoolID
 		SELECT COUNT(o.subject_olympiad_id)
 		FROM subject_olympiads o
 		WHERE o.school_id = ?
+		AND o.status = 'published'
 		AND o.end_date < CURRENT_DATE
 		AND (
 			SELECT COUNT(*) 
@@ -253,6 +259,7 @@ func (src *SchoolRatingController) getOlympiadActivityScore(db *sql.DB, schoolID
 			SELECT o.school_id, COUNT(o.subject_olympiad_id) AS valid_count
 			FROM subject_olympiads o
 			WHERE o.end_date < CURRENT_DATE
+			AND o.status = 'published'
 			AND (
 				SELECT COUNT(*) 
 				FROM olympiad_registrations r 
@@ -282,10 +289,10 @@ func (src *SchoolRatingController) getOlympiadActivityScore(db *sql.DB, schoolID
 func (src *SchoolRatingController) getParticipantPoints(db *sql.DB, schoolID int64) (float64, error) {
 	var maxParticipants int
 	countQuery := `
-		SELECT COUNT(ep.id)
-		FROM Schools s
-		LEFT JOIN Events e ON s.school_id = e.school_id
-		LEFT JOIN events_participants ep ON ep.events_name = e.event_name`
+		SELECT COUNT(r.event_registration_id)
+		FROM Events e
+		JOIN EventRegistrations r ON r.event_id = e.id AND r.attendance = 'attended'
+		WHERE e.status = 'published'`
 	err := db.QueryRow(countQuery).Scan(&maxParticipants)
 	if err != nil {
 		log.Println("Ошибка при подсчете всех участников:", err)
@@ -294,11 +301,10 @@ func (src *SchoolRatingController) getParticipantPoints(db *sql.DB, schoolID int
 
 	var participantCount int
 	query := `
-		SELECT COUNT(ep.id) AS participant_count
-		FROM Schools s
-		LEFT JOIN Events e ON s.school_id = e.school_id
-		LEFT JOIN events_participants ep ON ep.events_name = e.event_name
-		WHERE s.school_id = ?`
+		SELECT COUNT(r.event_registration_id) AS participant_count
+		FROM Events e
+		JOIN EventRegistrations r ON r.event_id = e.id AND r.attendance = 'attended'
+		WHERE e.school_id = ? AND e.status = 'published'`
 	err = db.QueryRow(query, schoolID).Scan(&participantCount)
 	if err != nil {
 		log.Println("Ошибка при подсчете участников школы:", err)
@@ -54,25 +54,34 @@ func (sc *StudentController) CreateStudent(db *sql.DB) http.HandlerFunc {
 
 		// Step 5: Handle school ID based on user role
 		if userRole == "schooladmin" {
-			// For schooladmin, get the school ID associated with them
-			var userEmail string
-			err = db.QueryRow("SELECT email FROM users WHERE id = ?", userID).Scan(&userEmail)
-			if err != nil {
-				log.Println("Error fetching user email:", err)
-				utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Error fetching user email"})
-				return
-			}
+			// For schooladmin, use the school they may manage students in.
+			// An explicit school_id in the body picks one of several memberships.
+			if student.SchoolID > 0 {
+				allowed, err := hasSchoolPermission(db, userID, student.SchoolID, PermissionManageStudents)
+				if err != nil {
+					log.Println("Error checking school membership:", err)
+					utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Error checking permissions"})
+					return
+				}
+				if !allowed {
+					utils.RespondWithError(w, http.StatusForbidden, models.Error{Message: "You do not have permission to manage students of this school"})
+					return
+				}
+			} else {
+				schoolID, err := getMemberSchoolID(db, userID, PermissionManageStudents)
+				if err != nil {
+					log.Println("Error fetching school ID:", err)
+					utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Error fetching school details"})
+					return
+				}
+				if !schoolID.Valid {
+					utils.RespondWithError(w, http.StatusForbidden, models.Error{Message: "Director does not have an assigned school. Please create a school first."})
+					return
+				}
 
-			var schoolID int
-			err = db.QueryRow("SELECT school_id FROM Schools WHERE school_admin_login = ?", userEmail).Scan(&schoolID)
-			if err != nil {
-				log.Println("Error fetching school ID:", err)
-				utils.RespondWithError(w, http.StatusForbidden, models.Error{Message: "Director does not have an assigned school. Please create a school first."})
-				return
+				// Set the school ID for the student
+				student.SchoolID = int(schoolID.Int64)
 			}
-
-			// Set the school ID for the student
-			student.SchoolID = schoolID
 		} else if userRole == "superadmin" {
 			// For superadmin, use the school_id from the request body
 			// Make sure it's provided
@@ -88,15 +97,13 @@ func (sc *StudentController) CreateStudent(db *sql.DB) http.HandlerFunc {
 			return
 		}
 
-		// Step 6: Generate login and email for the student
-		randomString := generateRandomString(4)
-		student.Login = student.FirstName + student.LastName + randomString
-		student.Email = student.Login + "@school.com"
+		// Validate IIN and derive date of birth and gender from it
+		if !validateStudentIIN(w, db, &student, 0) {
+			return
+		}
 
-		// Step 7: Generate the student's password
-		// student.Password = student.FirstName + student.LastName
-		randomString = generateRandomString(4) // например: "Xy7z"
-		student.Password = student.FirstName + "@" + randomString
+		// Step 6-7: Generate login, email and password for the student
+		student.Login, student.Email, student.Password = generateStudentCredentials(student.FirstName, student.LastName)
 
 		// Step 8: Set role to student
 		student.Role = "student"
@@ -118,7 +125,7 @@ func (sc *StudentController) CreateStudent(db *sql.DB) http.HandlerFunc {
 		query := `INSERT INTO student (first_name, last_name, patronymic, iin, school_id, date_of_birth, grade, letter, gender, phone, email, password, role, login, avatar_url)
           VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
 
-		result, err := db.Exec(query, student.FirstName, student.LastName, student.Patronymic, student.IIN, student.SchoolID, student.DateOfBirth, student.Grade, student.Letter, student.Gender, student.Phone, student.Email, student.Password, student.Role, student.Login, avatarURL)
+		result, err := db.Exec(query, student.FirstName, student.LastName, student.Patronymic, toNullString(student.IIN), student.SchoolID, student.DateOfBirth, student.Grade, student.Letter, student.Gender, student.Phone, student.Email, student.Password, student.Role, student.Login, avatarURL)
 		if err != nil {
 			log.Println("Error inserting student:", err)
 			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Failed to create student"})
@@ -134,6 +141,10 @@ func (sc *StudentController) CreateStudent(db *sql.DB) http.HandlerFunc {
 
 		student.ID = int(studentID)
 
+		if err := syncStudentClass(db, student.ID); err != nil {
+			log.Println("Error assigning student to class:", err)
+		}
+
 		// Send response with the student data
 		utils.ResponseJSON(w, student)
 	}
@@ -209,24 +220,7 @@ func (sc *StudentController) EditStudent(db *sql.DB) http.HandlerFunc {
 
 		// Step 7: Handle permissions based on user role
 		if userRole == "schooladmin" {
-			// Fetch school ID for schooladmin
-			var userEmail string
-			err = db.QueryRow("SELECT email FROM users WHERE id = ?", userID).Scan(&userEmail)
-			if err != nil {
-				log.Println("Error fetching user email:", err)
-				utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Error fetching user email"})
-				return
-			}
-
-			var adminSchoolID int
-			err = db.QueryRow("SELECT school_id FROM Schools WHERE school_admin_login = ?", userEmail).Scan(&adminSchoolID)
-			if err != nil {
-				log.Println("Error fetching school ID:", err)
-				utils.RespondWithError(w, http.StatusForbidden, models.Error{Message: "Director does not have an assigned school"})
-				return
-			}
-
-			// Verify that student belongs to admin's school
+			// Verify that student belongs to a school the admin manages
 			var studentSchoolID int
 			err = db.QueryRow("SELECT school_id FROM student WHERE id = ?", studentID).Scan(&studentSchoolID)
 			if err != nil {
@@ -235,13 +229,19 @@ func (sc *StudentController) EditStudent(db *sql.DB) http.HandlerFunc {
 				return
 			}
 
-			if studentSchoolID != adminSchoolID {
+			allowed, err := hasSchoolPermission(db, userID, studentSchoolID, PermissionManageStudents)
+			if err != nil {
+				log.Println("Error checking school membership:", err)
+				utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Error checking permissions"})
+				return
+			}
+			if !allowed {
 				utils.RespondWithError(w, http.StatusForbidden, models.Error{Message: "You do not have permission to edit this student"})
 				return
 			}
 
 			// Ensure school ID remains the same for schooladmin
-			updatedStudent.SchoolID = adminSchoolID
+			updatedStudent.SchoolID = studentSchoolID
 		} else if userRole == "superadmin" {
 			// Superadmin can edit any student and can change school ID
 			if updatedStudent.SchoolID <= 0 {
@@ -259,6 +259,9 @@ func (sc *StudentController) EditStudent(db *sql.DB) http.HandlerFunc {
 			utils.RespondWithError(w, http.StatusBadRequest, models.Error{Message: "First name and last name are required"})
 			return
 		}
+		if !validateStudentIIN(w, db, &updatedStudent, studentID) {
+			return
+		}
 
 		// Step 9: Update student data in database
 		query := `UPDATE student 
@@ -272,7 +275,7 @@ func (sc *StudentController) EditStudent(db *sql.DB) http.HandlerFunc {
 			updatedStudent.FirstName,
 			updatedStudent.LastName,
 			updatedStudent.Patronymic,
-			updatedStudent.IIN,
+			toNullString(updatedStudent.IIN),
 			updatedStudent.SchoolID,
 			updatedStudent.DateOfBirth,
 			updatedStudent.Grade,
@@ -290,10 +293,13 @@ func (sc *StudentController) EditStudent(db *sql.DB) http.HandlerFunc {
 			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Failed to update student"})
 			return
 		}
+		if err := syncStudentClass(db, studentID); err != nil {
+			log.Println("Error assigning student to class:", err)
+		}
 
 		// Step 10: Get updated student data to return in response
 		var student models.Student
-		err = db.QueryRow("SELECT id, first_name, last_name, patronymic, iin, school_id, date_of_birth, grade, letter, gender, phone, email, role FROM student WHERE id = ?", studentID).
+		err = db.QueryRow("SELECT id, first_name, last_name, patronymic, COALESCE(iin, ''), school_id, date_of_birth, grade, letter, gender, phone, email, role FROM student WHERE id = ?", studentID).
 			Scan(&student.ID, &student.FirstName, &student.LastName, &student.Patronymic, &student.IIN, &student.SchoolID, &student.DateOfBirth, &student.Grade, &student.Letter, &student.Gender, &student.Phone, &student.Email, &student.Role)
 
 		if err != nil {
@@ -303,6 +309,7 @@ func (sc *StudentController) EditStudent(db *sql.DB) http.HandlerFunc {
 		}
 
 		// Send response with updated student data
+		student.Warnings = updatedStudent.Warnings
 		utils.ResponseJSON(w, student)
 	}
 }
@@ -313,7 +320,7 @@ func (sc *StudentController) GetAllStudents(db *sql.DB) http.HandlerFunc {
 
 		// Step 1: Get all students and their associated school names from the database
 		rows, err := db.Query(`
-            SELECT s.student_id, s.first_name, s.last_name, s.patronymic, s.date_of_birth, s.iin, s.school_id, 
+            SELECT s.student_id, s.first_name, s.last_name, s.patronymic, s.date_of_birth, COALESCE(s.iin, ''), s.school_id, 
                    s.grade, s.letter, s.gender, s.phone, s.email, s.role, s.login, s.avatar_url, s.password,
                    Sc.school_name 
             FROM student s
@@ -488,7 +495,7 @@ func (sc *StudentController) GetStudentByID(db *sql.DB) http.HandlerFunc {
 		var schoolName sql.NullString
 
 		err = db.QueryRow(`
-			SELECT s.student_id, s.first_name, s.last_name, s.patronymic, s.date_of_birth, s.iin, s.school_id, 
+			SELECT s.student_id, s.first_name, s.last_name, s.patronymic, s.date_of_birth, COALESCE(s.iin, ''), s.school_id, 
 				   s.grade, s.letter, s.gender, s.phone, s.email, s.role, s.login, s.avatar_url, s.password,
 				   Sc.school_name 
 			FROM student s
@@ -606,7 +613,7 @@ func (c *StudentController) GetStudentsBySchool(db *sql.DB) http.HandlerFunc {
 		}
 
 		// Шаг 1: Запрос к базе данных для получения студентов по school_id
-		rows, err := db.Query("SELECT student_id, first_name, last_name, patronymic, iin, school_id, date_of_birth, grade, letter, gender, phone, email, login, password FROM student WHERE school_id = ?", schoolID)
+		rows, err := db.Query("SELECT student_id, first_name, last_name, patronymic, iin, school_id, date_of_birth, grade, letter, gender, phone, email, login, password FROM student WHERE school_id = ? AND status = 'active'", schoolID)
 		if err != nil {
 			log.Println("SQL Error:", err) // Логируем ошибку SQL запроса
 			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Failed to get students"})
@@ -708,9 +715,10 @@ func (sc StudentController) GetAvailableLettersByGrade(db *sql.DB) http.HandlerF
 			return
 		}
 
-		// Check if any student exists with this grade and school_id
+		// Check if the school has classes in this grade for the current academic year
+		academicYear := currentAcademicYear()
 		var exists bool
-		err = db.QueryRow("SELECT EXISTS(SELECT 1 FROM student WHERE grade = ? AND school_id = ? LIMIT 1)", grade, schoolID).Scan(&exists)
+		err = db.QueryRow("SELECT EXISTS(SELECT 1 FROM classes WHERE grade = ? AND school_id = ? AND academic_year = ?)", grade, schoolID, academicYear).Scan(&exists)
 		if err != nil {
 			log.Println("SQL Error:", err)
 			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Database error"})
@@ -718,12 +726,12 @@ func (sc StudentController) GetAvailableLettersByGrade(db *sql.DB) http.HandlerF
 		}
 
 		if !exists {
-			utils.RespondWithError(w, http.StatusNotFound, models.Error{Message: "No students found in this grade and school"})
+			utils.RespondWithError(w, http.StatusNotFound, models.Error{Message: "No classes found in this grade and school"})
 			return
 		}
 
-		// Get distinct letters for this grade and school_id
-		rows, err := db.Query("SELECT DISTINCT letter FROM student WHERE grade = ? AND school_id = ? ORDER BY letter", grade, schoolID)
+		// Get class letters for this grade and school_id
+		rows, err := db.Query("SELECT letter FROM classes WHERE grade = ? AND school_id = ? AND academic_year = ? ORDER BY letter", grade, schoolID, academicYear)
 		if err != nil {
 			log.Println("SQL Error:", err)
 			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Failed to get letters"})
@@ -779,7 +787,7 @@ func (c *StudentController) GetTotalStudentsBySchool(db *sql.DB) http.HandlerFun
 
 		// Запрос к базе данных для получения количества студентов по school_id
 		var totalStudents int
-		err = db.QueryRow("SELECT COUNT(*) FROM student WHERE school_id = ?", schoolID).Scan(&totalStudents)
+		err = db.QueryRow("SELECT COUNT(*) FROM student WHERE school_id = ? AND status = 'active'", schoolID).Scan(&totalStudents)
 		if err != nil {
 			log.Println("SQL Error:", err) // Логируем ошибку SQL запроса
 			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Failed to get total students"})
@@ -795,6 +803,16 @@ func (c *StudentController) GetTotalStudentsBySchool(db *sql.DB) http.HandlerFun
 		utils.ResponseJSON(w, response)
 	}
 }
+
+// generateStudentCredentials builds the login, email and initial password
+// the same way for single and bulk student creation.
+func generateStudentCredentials(firstName, lastName string) (login, email, password string) {
+	login = firstName + lastName + generateRandomString(4)
+	email = login + "@school.com"
+	password = firstName + "@" + generateRandomString(4) // например: "Xy7z"
+	return login, email, password
+}
+
 func generateRandomString(n int) string {
 	const letters = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
 	// generateRandomString generates a random string of length n
@@ -907,7 +925,7 @@ func (sc StudentController) GetAvailableGradesBySchool(db *sql.DB) http.HandlerF
 		// No need to check if school exists - we'll just return available grades
 
 		// Query to get distinct grades that exist for this school
-		rows, err := db.Query("SELECT DISTINCT grade FROM student WHERE school_id = ? ORDER BY grade", schoolID)
+		rows, err := db.Query("SELECT DISTINCT grade FROM classes WHERE school_id = ? AND academic_year = ? ORDER BY grade", schoolID, currentAcademicYear())
 		if err != nil {
 			log.Println("SQL Error:", err)
 			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Failed to get grades"})
@@ -1375,15 +1393,14 @@ func (sc *StudentController) UpdateStudent(db *sql.DB) http.HandlerFunc {
 				utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Failed to fetch student details"})
 				return
 			}
-			var userSchoolID int
-			err = db.QueryRow("SELECT school_id FROM users WHERE id = ?", userID).Scan(&userSchoolID)
+			allowed, err := hasSchoolPermission(db, userID, studentSchoolID, PermissionManageStudents)
 			if err != nil {
-				log.Println("Error fetching user school ID:", err)
+				log.Println("Error checking school membership:", err)
 				utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Error fetching user details"})
 				return
 			}
 
-			if userSchoolID != studentSchoolID {
+			if !allowed {
 				utils.RespondWithError(w, http.StatusForbidden, models.Error{Message: "You can only update students from your own school"})
 				return
 			}
@@ -1414,6 +1431,9 @@ func (sc *StudentController) UpdateStudent(db *sql.DB) http.HandlerFunc {
 		}
 
 		updatedStudent := requestData.Student
+		if !validateStudentIIN(w, db, &updatedStudent, studentID) {
+			return
+		}
 
 		// Step 6: Prepare the update query
 		query := `UPDATE student 
@@ -1422,7 +1442,7 @@ func (sc *StudentController) UpdateStudent(db *sql.DB) http.HandlerFunc {
 
 		args := []interface{}{
 			updatedStudent.FirstName, updatedStudent.LastName, updatedStudent.Patronymic,
-			updatedStudent.IIN, updatedStudent.DateOfBirth, updatedStudent.Grade,
+			toNullString(updatedStudent.IIN), updatedStudent.DateOfBirth, updatedStudent.Grade,
 			updatedStudent.SchoolID, updatedStudent.Letter, updatedStudent.Gender,
 			updatedStudent.Phone, updatedStudent.Email,
 		}
@@ -1449,9 +1469,12 @@ func (sc *StudentController) UpdateStudent(db *sql.DB) http.HandlerFunc {
 			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Failed to update student"})
 			return
 		}
+		if err := syncStudentClass(db, studentID); err != nil {
+			log.Println("Error assigning student to class:", err)
+		}
 
 		// Step 9: Fetch the updated student to return in the response
-		query = `SELECT student_id, first_name, last_name, patronymic, iin, school_id, date_of_birth, 
+		query = `SELECT student_id, first_name, last_name, patronymic, COALESCE(iin, ''), school_id, date_of_birth, 
                 grade, letter, gender, phone, email, role, login 
                 FROM student WHERE student_id = ?`
 
@@ -1552,10 +1575,6 @@ func (sc *StudentController) DeleteStudent(db *sql.DB) http.HandlerFunc {
 			"events_participants",
 			"olympiad_registrations",
 			"Olympiads",
-			"student_ratings",
-			"First_Type",
-			"Second_Type",
-			"UNT_Score",
 			"student_types",
 			"subject_olympiad_registrations",
 		}
@@ -1606,8 +1625,7 @@ func (sc StudentController) SuperadminUpdateStudent(db *sql.DB) http.HandlerFunc
 
 		// Step 2: Get user role
 		var userRole string
-		var userSchoolID int // To store school ID for schooladmin
-		err = db.QueryRow("SELECT role, school_id FROM users WHERE id = ?", userID).Scan(&userRole, &userSchoolID)
+		err = db.QueryRow("SELECT role FROM users WHERE id = ?", userID).Scan(&userRole)
 		if err != nil {
 			log.Println("Error fetching user role:", err)
 			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Error fetching user details"})
@@ -1622,8 +1640,15 @@ func (sc StudentController) SuperadminUpdateStudent(db *sql.DB) http.HandlerFunc
 			return
 		}
 
+		allowed, err := hasSchoolPermission(db, userID, studentSchoolID, PermissionManageStudents)
+		if err != nil {
+			log.Println("Error checking school membership:", err)
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Error fetching user details"})
+			return
+		}
+
 		// If user is superadmin, allow updating any student
-		if userRole != "superadmin" && userSchoolID != studentSchoolID {
+		if userRole != "superadmin" && !allowed {
 			utils.RespondWithError(w, http.StatusForbidden, models.Error{Message: "You do not have permission to update this student"})
 			return
 		}
@@ -1634,6 +1659,9 @@ func (sc StudentController) SuperadminUpdateStudent(db *sql.DB) http.HandlerFunc
 			utils.RespondWithError(w, http.StatusBadRequest, models.Error{Message: "Invalid request payload"})
 			return
 		}
+		if !validateStudentIIN(w, db, &updatedStudent, studentID) {
+			return
+		}
 
 		// Step 5: Prepare and execute the update query
 		query := `UPDATE Student 
@@ -1643,7 +1671,7 @@ func (sc StudentController) SuperadminUpdateStudent(db *sql.DB) http.HandlerFunc
 
 		_, err = db.Exec(query,
 			updatedStudent.FirstName, updatedStudent.LastName, updatedStudent.Patronymic,
-			updatedStudent.IIN, updatedStudent.DateOfBirth, updatedStudent.Grade,
+			toNullString(updatedStudent.IIN), updatedStudent.DateOfBirth, updatedStudent.Grade,
 			updatedStudent.SchoolID, updatedStudent.Letter, updatedStudent.Gender,
 			updatedStudent.Phone, updatedStudent.Email, studentID)
 
@@ -1652,9 +1680,12 @@ func (sc StudentController) SuperadminUpdateStudent(db *sql.DB) http.HandlerFunc
 			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Failed to update student"})
 			return
 		}
+		if err := syncStudentClass(db, studentID); err != nil {
+			log.Println("Error assigning student to class:", err)
+		}
 
 		// Step 6: Fetch the updated student to return in the response
-		query = `SELECT student_id, first_name, last_name, patronymic, iin, school_id, date_of_birth, 
+		query = `SELECT student_id, first_name, last_name, patronymic, COALESCE(iin, ''), school_id, date_of_birth, 
                 grade, letter, gender, phone, email, role, login 
                 FROM Student WHERE student_id = ?`
 
@@ -1687,38 +1718,48 @@ func (sc StudentController) GetStudentData(db *sql.DB) http.HandlerFunc {
 
 		// Step 2: Get user role and school ID
 		var userRole string
-		var userSchoolID sql.NullInt64
-		err = db.QueryRow("SELECT role, school_id FROM users WHERE id = ?", userID).Scan(&userRole, &userSchoolID)
+		err = db.QueryRow("SELECT role FROM users WHERE id = ?", userID).Scan(&userRole)
 		if err != nil {
-			log.Println("Error fetching user role and school ID:", err)
+			log.Println("Error fetching user role:", err)
 			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Error fetching user details"})
 			return
 		}
 
-		// Step 3: Ensure the user is a director and has a school assigned
+		// Step 3: Ensure the user is a director and is a member of the school
 		if userRole != "schooladmin" {
 			utils.RespondWithError(w, http.StatusForbidden, models.Error{Message: "You do not have permission to view student data"})
 			return
 		}
 
-		if !userSchoolID.Valid {
-			utils.RespondWithError(w, http.StatusForbidden, models.Error{Message: "Director does not have an assigned school"})
+		// Step 4: Extract school_id and student_id from the URL
+		vars := mux.Vars(r)
+		schoolID, err := strconv.Atoi(vars["school_id"])
+		if err != nil {
+			utils.RespondWithError(w, http.StatusBadRequest, models.Error{Message: "Invalid school ID"})
 			return
 		}
-
-		// Step 4: Extract student_id from the URL
-		vars := mux.Vars(r)
 		studentID, err := strconv.Atoi(vars["student_id"])
 		if err != nil {
 			utils.RespondWithError(w, http.StatusBadRequest, models.Error{Message: "Invalid student ID"})
 			return
 		}
 
+		isMember, err := hasSchoolPermission(db, userID, schoolID, "")
+		if err != nil {
+			log.Println("Error checking school membership:", err)
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Error fetching user details"})
+			return
+		}
+		if !isMember {
+			utils.RespondWithError(w, http.StatusForbidden, models.Error{Message: "Director does not have an assigned school"})
+			return
+		}
+
 		// Step 5: Retrieve student data from the database
 		var student models.Student
-		query := `SELECT id, first_name, last_name, patronymic, iin, school_id, date_of_birth, grade, letter, gender, phone, email
+		query := `SELECT id, first_name, last_name, patronymic, COALESCE(iin, ''), school_id, date_of_birth, grade, letter, gender, phone, email
                   FROM student WHERE id = ? AND school_id = ?`
-		err = db.QueryRow(query, studentID, userSchoolID.Int64).Scan(&student.ID, &student.FirstName, &student.LastName, &student.Patronymic, &student.IIN, &student.SchoolID, &student.DateOfBirth, &student.Grade, &student.Letter, &student.Gender, &student.Phone, &student.Email)
+		err = db.QueryRow(query, studentID, schoolID).Scan(&student.ID, &student.FirstName, &student.LastName, &student.Patronymic, &student.IIN, &student.SchoolID, &student.DateOfBirth, &student.Grade, &student.Letter, &student.Gender, &student.Phone, &student.Email)
 
 		if err != nil {
 			if err == sql.ErrNoRows {
@@ -1754,8 +1795,7 @@ func (sc StudentController) SuperadminDeleteStudent(db *sql.DB) http.HandlerFunc
 
 		// Step 2: Get user role and school ID
 		var userRole string
-		var userSchoolID int
-		err = db.QueryRow("SELECT role, school_id FROM users WHERE id = ?", userID).Scan(&userRole, &userSchoolID)
+		err = db.QueryRow("SELECT role FROM users WHERE id = ?", userID).Scan(&userRole)
 		if err != nil {
 			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Error fetching user details"})
 			return
@@ -1769,8 +1809,14 @@ func (sc StudentController) SuperadminDeleteStudent(db *sql.DB) http.HandlerFunc
 			return
 		}
 
+		allowed, err := hasSchoolPermission(db, userID, studentSchoolID, PermissionManageStudents)
+		if err != nil {
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Error fetching user details"})
+			return
+		}
+
 		// Step 4: Check permission to delete the student
-		if userRole != "superadmin" && userSchoolID != studentSchoolID {
+		if userRole != "superadmin" && !allowed {
 			utils.RespondWithError(w, http.StatusForbidden, models.Error{Message: "You do not have permission to delete this student"})
 			return
 		}
@@ -1834,28 +1880,40 @@ func (sfc *StudentController) GetStudentFilters(db *sql.DB) http.HandlerFunc {
 			}
 			response["schools"] = schools
 		} else if userRole == "schooladmin" {
-			// For schooladmin, get their associated school
-			var userEmail string
-			err = db.QueryRow("SELECT email FROM users WHERE id = ?", userID).Scan(&userEmail)
+			// For schooladmin, get the schools they are a member of
+			rows, err := db.Query(`
+				SELECT s.school_id, s.school_name
+				FROM school_members sm
+				JOIN Schools s ON s.school_id = sm.school_id
+				WHERE sm.user_id = ?
+				ORDER BY s.school_id`, userID)
 			if err != nil {
-				log.Println("Error fetching user email:", err)
-				utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Error fetching user email"})
+				log.Println("Error fetching school:", err)
+				utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Error fetching school details"})
 				return
 			}
+			defer rows.Close()
 
-			var schoolID int
-			var schoolName string
-			err = db.QueryRow("SELECT school_id, school_name FROM Schools WHERE school_admin_login = ?", userEmail).Scan(&schoolID, &schoolName)
-			if err != nil {
-				log.Println("Error fetching school:", err)
+			var schools []map[string]interface{}
+			for rows.Next() {
+				var schoolID int
+				var schoolName string
+				if err := rows.Scan(&schoolID, &schoolName); err != nil {
+					log.Println("Error scanning school data:", err)
+					continue
+				}
+				schools = append(schools, map[string]interface{}{
+					"id":   schoolID,
+					"name": schoolName,
+				})
+			}
+			if len(schools) == 0 {
 				utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Error fetching school details"})
 				return
 			}
 
-			response["school"] = map[string]interface{}{
-				"id":   schoolID,
-				"name": schoolName,
-			}
+			response["school"] = schools[0]
+			response["schools"] = schools
 		}
 
 		utils.ResponseJSON(w, response)
@@ -1899,20 +1957,31 @@ func (sfc *StudentController) GetAvailableGrades(db *sql.DB) http.HandlerFunc {
 				return
 			}
 		} else if userRole == "schooladmin" {
-			// Get school ID associated with this schooladmin
-			var userEmail string
-			err = db.QueryRow("SELECT email FROM users WHERE id = ?", userID).Scan(&userEmail)
-			if err != nil {
-				log.Println("Error fetching user email:", err)
-				utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Error fetching user email"})
-				return
-			}
-
-			err = db.QueryRow("SELECT school_id FROM Schools WHERE school_admin_login = ?", userEmail).Scan(&schoolID)
-			if err != nil {
-				log.Println("Error fetching school ID:", err)
-				utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Error fetching school details"})
-				return
+			// Get school ID from the admin's memberships; schoolId selects one of them
+			if schoolIDParam := r.URL.Query().Get("schoolId"); schoolIDParam != "" {
+				schoolID, err = strconv.Atoi(schoolIDParam)
+				if err != nil {
+					utils.RespondWithError(w, http.StatusBadRequest, models.Error{Message: "Invalid school ID"})
+					return
+				}
+				isMember, err := hasSchoolPermission(db, userID, schoolID, "")
+				if err != nil {
+					log.Println("Error checking school membership:", err)
+					utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Error fetching school details"})
+					return
+				}
+				if !isMember {
+					utils.RespondWithError(w, http.StatusForbidden, models.Error{Message: "Unauthorized access to this school"})
+					return
+				}
+			} else {
+				memberSchoolID, err := getMemberSchoolID(db, userID, "")
+				if err != nil || !memberSchoolID.Valid {
+					log.Println("Error fetching school ID:", err)
+					utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Error fetching school details"})
+					return
+				}
+				schoolID = int(memberSchoolID.Int64)
 			}
 		} else {
 			utils.RespondWithError(w, http.StatusForbidden, models.Error{Message: "Unauthorized access"})
@@ -1920,7 +1989,7 @@ func (sfc *StudentController) GetAvailableGrades(db *sql.DB) http.HandlerFunc {
 		}
 
 		// Step 4: Get distinct grades for the school
-		rows, err := db.Query("SELECT DISTINCT grade FROM student WHERE school_id = ? AND grade IS NOT NULL ORDER BY grade", schoolID)
+		rows, err := db.Query("SELECT DISTINCT grade FROM student WHERE school_id = ? AND status = 'active' AND grade IS NOT NULL ORDER BY grade", schoolID)
 		if err != nil {
 			log.Println("Error fetching grades:", err)
 			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Failed to fetch grade data"})
@@ -1982,20 +2051,31 @@ func (sfc *StudentController) GetAvailableLetters(db *sql.DB) http.HandlerFunc {
 				return
 			}
 		} else if userRole == "schooladmin" {
-			// Get school ID associated with this schooladmin
-			var userEmail string
-			err = db.QueryRow("SELECT email FROM users WHERE id = ?", userID).Scan(&userEmail)
-			if err != nil {
-				log.Println("Error fetching user email:", err)
-				utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Error fetching user email"})
-				return
-			}
-
-			err = db.QueryRow("SELECT school_id FROM Schools WHERE school_admin_login = ?", userEmail).Scan(&schoolID)
-			if err != nil {
-				log.Println("Error fetching school ID:", err)
-				utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Error fetching school details"})
-				return
+			// Get school ID from the admin's memberships; schoolId selects one of them
+			if schoolIDParam := r.URL.Query().Get("schoolId"); schoolIDParam != "" {
+				schoolID, err = strconv.Atoi(schoolIDParam)
+				if err != nil {
+					utils.RespondWithError(w, http.StatusBadRequest, models.Error{Message: "Invalid school ID"})
+					return
+				}
+				isMember, err := hasSchoolPermission(db, userID, schoolID, "")
+				if err != nil {
+					log.Println("Error checking school membership:", err)
+					utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Error fetching school details"})
+					return
+				}
+				if !isMember {
+					utils.RespondWithError(w, http.StatusForbidden, models.Error{Message: "Unauthorized access to this school"})
+					return
+				}
+			} else {
+				memberSchoolID, err := getMemberSchoolID(db, userID, "")
+				if err != nil || !memberSchoolID.Valid {
+					log.Println("Error fetching school ID:", err)
+					utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Error fetching school details"})
+					return
+				}
+				schoolID = int(memberSchoolID.Int64)
 			}
 		} else {
 			utils.RespondWithError(w, http.StatusForbidden, models.Error{Message: "Unauthorized access"})
@@ -2016,7 +2096,7 @@ func (sfc *StudentController) GetAvailableLetters(db *sql.DB) http.HandlerFunc {
 		}
 
 		// Step 5: Get distinct letters for the school and grade
-		rows, err := db.Query("SELECT DISTINCT letter FROM student WHERE school_id = ? AND grade = ? AND letter IS NOT NULL AND letter != '' ORDER BY letter", schoolID, grade)
+		rows, err := db.Query("SELECT DISTINCT letter FROM student WHERE school_id = ? AND grade = ? AND status = 'active' AND letter IS NOT NULL AND letter != '' ORDER BY letter", schoolID, grade)
 		if err != nil {
 			log.Println("Error fetching letters:", err)
 			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Failed to fetch letter data"})
@@ -2075,23 +2155,19 @@ func (sfc *StudentController) GetFilteredStudents(db *sql.DB) http.HandlerFunc {
 				return
 			}
 		} else if userRole == "schooladmin" {
-			var userEmail string
-			err = db.QueryRow("SELECT email FROM users WHERE id = ?", userID).Scan(&userEmail)
+			// For school admin, verify they're a member of the requested school
+			schoolID, err = strconv.Atoi(vars["schoolId"])
 			if err != nil {
-				log.Println("Error fetching user email:", err)
-				utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Error fetching user email"})
+				utils.RespondWithError(w, http.StatusForbidden, models.Error{Message: "Unauthorized access to this school"})
 				return
 			}
-			err = db.QueryRow("SELECT school_id FROM Schools WHERE school_admin_login = ?", userEmail).Scan(&schoolID)
+			isMember, err := hasSchoolPermission(db, userID, schoolID, "")
 			if err != nil {
-				log.Println("Error fetching school ID:", err)
+				log.Println("Error checking school membership:", err)
 				utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Error fetching school details"})
 				return
 			}
-
-			// For school admin, verify they're accessing their own school
-			pathSchoolID, err := strconv.Atoi(vars["schoolId"])
-			if err != nil || pathSchoolID != schoolID {
+			if !isMember {
 				utils.RespondWithError(w, http.StatusForbidden, models.Error{Message: "Unauthorized access to this school"})
 				return
 			}
@@ -2127,7 +2203,7 @@ func (sfc *StudentController) GetFilteredStudents(db *sql.DB) http.HandlerFunc {
 		rows, err := db.Query(`
 			SELECT student_id, first_name, last_name, patronymic, letter
 			FROM student
-			WHERE school_id = ? AND grade = ?
+			WHERE school_id = ? AND grade = ? AND status = 'active'
 		`, schoolID, grade)
 		if err != nil {
 			log.Println("Error querying students:", err)
@@ -2147,7 +2223,7 @@ func (sfc *StudentController) GetFilteredStudents(db *sql.DB) http.HandlerFunc {
 				continue
 			}
 
-			log.Printf("Found student: %s %s (%s), letter in DB: %q", student.FirstName, student.LastName, student.ID, dbLetter)
+			log.Printf("Found student: %s %s (%d), letter in DB: %q", student.FirstName, student.LastName, student.ID, dbLetter)
 
 			if patronymic.Valid {
 				student.Patronymic = patronymic.String
@@ -2204,20 +2280,13 @@ func (sfc *StudentController) GetStudentsWithoutENT(db *sql.DB) http.HandlerFunc
 				return
 			}
 		} else if userRole == "schooladmin" {
-			var email string
-			err = db.QueryRow("SELECT email FROM users WHERE id = ?", userID).Scan(&email)
-			if err != nil {
-				utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Failed to get email"})
-				return
-			}
-			err = db.QueryRow("SELECT school_id FROM Schools WHERE school_admin_login = ?", email).Scan(&schoolID)
+			schoolID, _ = strconv.Atoi(vars["schoolId"])
+			isMember, err := hasSchoolPermission(db, userID, schoolID, "")
 			if err != nil {
 				utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Failed to get school ID"})
 				return
 			}
-
-			pathSchoolID, _ := strconv.Atoi(vars["schoolId"])
-			if pathSchoolID != schoolID {
+			if !isMember {
 				utils.RespondWithError(w, http.StatusForbidden, models.Error{Message: "Unauthorized access"})
 				return
 			}
@@ -2235,8 +2304,8 @@ func (sfc *StudentController) GetStudentsWithoutENT(db *sql.DB) http.HandlerFunc
 		rows, err := db.Query(`
 			SELECT s.student_id, s.first_name, s.last_name, s.patronymic, s.letter
 			FROM student s
-			LEFT JOIN UNT_Exams e ON s.student_id = e.student_id
-			WHERE s.school_id = ? AND s.grade = ? AND UPPER(TRIM(s.letter)) = ? AND e.student_id IS NULL
+			LEFT JOIN UNT_Exams e ON s.student_id = e.student_id AND e.attempt_kind = 'official'
+			WHERE s.school_id = ? AND s.grade = ? AND s.status = 'active' AND UPPER(TRIM(s.letter)) = ? AND e.student_id IS NULL
 		`, schoolID, grade, letter)
 
 		if err != nil {
@@ -2286,7 +2355,7 @@ func (c *StudentController) GetStudentsCountBySchool(db *sql.DB) http.HandlerFun
 
 		// Query to count students by school_id
 		var studentCount int
-		err = db.QueryRow("SELECT COUNT(*) FROM student WHERE school_id = ?", schoolID).Scan(&studentCount)
+		err = db.QueryRow("SELECT COUNT(*) FROM student WHERE school_id = ? AND status = 'active'", schoolID).Scan(&studentCount)
 		if err != nil {
 			log.Printf("SQL Error: %v", err)
 			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Failed to count students"})
@@ -0,0 +1,480 @@
+package controllers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"ranking-school/models"
+	"ranking-school/utils"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+// Порог похожести ФИО для нечёткого поиска (0..1)
+const duplicateNameSimilarity = 0.85
+
+type duplicateCandidate struct {
+	StudentID   int    `json:"student_id"`
+	FirstName   string `json:"first_name"`
+	LastName    string `json:"last_name"`
+	Patronymic  string `json:"patronymic"`
+	IIN         string `json:"iin"`
+	DateOfBirth string `json:"date_of_birth"`
+	Grade       int    `json:"grade"`
+	Letter      string `json:"letter"`
+	Login       string `json:"login"`
+}
+
+type duplicatePair struct {
+	Students   [2]duplicateCandidate `json:"students"`
+	Reasons    []string              `json:"reasons"`
+	Similarity float64               `json:"similarity"`
+}
+
+// normalizePersonName lowercases and folds ё into е so that spelling
+// variants of the same name compare equal.
+func normalizePersonName(parts ...string) string {
+	var fields []string
+	for _, p := range parts {
+		fields = append(fields, strings.Fields(strings.ToLower(p))...)
+	}
+	return strings.ReplaceAll(strings.Join(fields, " "), "ё", "е")
+}
+
+// nameSimilarity returns 1 - levenshtein(a, b) / max(len(a), len(b)).
+func nameSimilarity(a, b string) float64 {
+	ra, rb := []rune(a), []rune(b)
+	if len(ra) == 0 && len(rb) == 0 {
+		return 1
+	}
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	maxLen := max(len(ra), len(rb))
+	return 1 - float64(prev[len(rb)])/float64(maxLen)
+}
+
+// findDuplicatePairs compares students of one school pairwise. IIN and
+// name+DOB matches are exact; fuzzy name matches are limited to one grade.
+func findDuplicatePairs(students []duplicateCandidate) []duplicatePair {
+	pairs := []duplicatePair{}
+	names := make([]string, len(students))
+	for i, s := range students {
+		names[i] = normalizePersonName(s.LastName, s.FirstName, s.Patronymic)
+	}
+
+	for i := 0; i < len(students); i++ {
+		for j := i + 1; j < len(students); j++ {
+			a, b := students[i], students[j]
+			var reasons []string
+
+			if a.IIN != "" && a.IIN == b.IIN {
+				reasons = append(reasons, "same_iin")
+			}
+			shortA := normalizePersonName(a.LastName, a.FirstName)
+			shortB := normalizePersonName(b.LastName, b.FirstName)
+			if a.DateOfBirth != "" && a.DateOfBirth == b.DateOfBirth && shortA == shortB {
+				reasons = append(reasons, "same_name_and_date_of_birth")
+			}
+
+			similarity := nameSimilarity(names[i], names[j])
+			if a.Grade == b.Grade && similarity >= duplicateNameSimilarity {
+				reasons = append(reasons, "similar_name")
+			}
+
+			if len(reasons) > 0 {
+				pairs = append(pairs, duplicatePair{
+					Students:   [2]duplicateCandidate{a, b},
+					Reasons:    reasons,
+					Similarity: float64(int(similarity*1000)) / 1000,
+				})
+			}
+		}
+	}
+
+	// Сначала самые надёжные совпадения
+	sort.SliceStable(pairs, func(i, j int) bool {
+		if len(pairs[i].Reasons) != len(pairs[j].Reasons) {
+			return len(pairs[i].Reasons) > len(pairs[j].Reasons)
+		}
+		return pairs[i].Similarity > pairs[j].Similarity
+	})
+	return pairs
+}
+
+// GetDuplicateStudents ищет вероятные дубликаты учеников в школе.
+func (sc *StudentController) GetDuplicateStudents(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := utils.VerifyToken(r)
+		if err != nil {
+			utils.RespondWithError(w, http.StatusUnauthorized, models.Error{Message: err.Error()})
+			return
+		}
+
+		schoolID, err := strconv.Atoi(mux.Vars(r)["school_id"])
+		if err != nil {
+			utils.RespondWithError(w, http.StatusBadRequest, models.Error{Message: "Invalid school ID"})
+			return
+		}
+
+		allowed, err := canManageStudentsOf(db, userID, schoolID)
+		if err != nil {
+			log.Println("Error checking permissions:", err)
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Error checking permissions"})
+			return
+		}
+		if !allowed {
+			utils.RespondWithError(w, http.StatusForbidden, models.Error{Message: "You do not have permission to manage students of this school"})
+			return
+		}
+
+		rows, err := db.Query(`
+			SELECT student_id, COALESCE(first_name, ''), COALESCE(last_name, ''), COALESCE(patronymic, ''),
+			       COALESCE(iin, ''), COALESCE(CAST(date_of_birth AS CHAR), ''), COALESCE(grade, 0),
+			       COALESCE(letter, ''), COALESCE(login, '')
+			FROM student
+			WHERE school_id = ? AND status = 'active'
+			ORDER BY student_id`, schoolID)
+		if err != nil {
+			log.Println("Error fetching students:", err)
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Failed to fetch students"})
+			return
+		}
+		defer rows.Close()
+
+		var students []duplicateCandidate
+		for rows.Next() {
+			var s duplicateCandidate
+			if err := rows.Scan(&s.StudentID, &s.FirstName, &s.LastName, &s.Patronymic, &s.IIN, &s.DateOfBirth, &s.Grade, &s.Letter, &s.Login); err != nil {
+				log.Println("Error scanning student:", err)
+				utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Failed to fetch students"})
+				return
+			}
+			s.DateOfBirth = firstN(s.DateOfBirth, 10)
+			students = append(students, s)
+		}
+
+		utils.ResponseJSON(w, findDuplicatePairs(students))
+	}
+}
+
+// Таблицы, где строки ученика переносятся на оставшуюся запись.
+// uniqueBy — колонка, по которой у ученика не может быть двух строк.
+var studentMergeTables = []struct {
+	table    string
+	uniqueBy string
+}{
+	{"UNT_Exams", ""},
+	{"Olympiads", "subject_olympiad_id"},
+	{"events_participants", ""},
+	{"EventRegistrations", "event_id"},
+	{"olympiad_registrations", "subject_olympiad_id"},
+	{"student_grade_history", "academic_year"},
+	{"student_transfers", ""},
+	{"notifications", ""},
+	{"survey_invitations", "event_id"},
+	{"survey_responses", "event_id"},
+	{"event_session_registrations", "session_id"},
+	{"student_portfolios", ""},
+}
+
+// MergeStudents переносит результаты дубликата на ученика из URL и
+// удаляет дубликат. Пустые поля оставшейся записи заполняются из дубликата.
+func (sc *StudentController) MergeStudents(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := utils.VerifyToken(r)
+		if err != nil {
+			utils.RespondWithError(w, http.StatusUnauthorized, models.Error{Message: err.Error()})
+			return
+		}
+
+		survivorID, err := strconv.Atoi(mux.Vars(r)["student_id"])
+		if err != nil {
+			utils.RespondWithError(w, http.StatusBadRequest, models.Error{Message: "Invalid student ID"})
+			return
+		}
+
+		var req struct {
+			DuplicateID int `json:"duplicate_id"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.DuplicateID <= 0 {
+			utils.RespondWithError(w, http.StatusBadRequest, models.Error{Message: "duplicate_id is required"})
+			return
+		}
+		if req.DuplicateID == survivorID {
+			utils.RespondWithError(w, http.StatusBadRequest, models.Error{Message: "Cannot merge a student with itself"})
+			return
+		}
+
+		tx, err := db.Begin()
+		if err != nil {
+			log.Println("Error starting transaction:", err)
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Failed to start transaction"})
+			return
+		}
+		defer tx.Rollback()
+
+		survivor, err := loadMergeRecord(tx, survivorID)
+		var duplicate mergeRecord
+		if err == nil {
+			duplicate, err = loadMergeRecord(tx, req.DuplicateID)
+		}
+		if err == sql.ErrNoRows {
+			utils.RespondWithError(w, http.StatusNotFound, models.Error{Message: "Student not found"})
+			return
+		}
+		if err != nil {
+			log.Println("Error fetching students for merge:", err)
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Error fetching students"})
+			return
+		}
+
+		// Права нужны в обеих школах
+		for _, schoolID := range []int{survivor.SchoolID, duplicate.SchoolID} {
+			allowed, err := canManageStudentsOf(tx, userID, schoolID)
+			if err != nil {
+				log.Println("Error checking permissions:", err)
+				utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Error checking permissions"})
+				return
+			}
+			if !allowed {
+				utils.RespondWithError(w, http.StatusForbidden, models.Error{Message: "You do not have permission to merge these students"})
+				return
+			}
+		}
+
+		if survivor.IIN != "" && duplicate.IIN != "" && survivor.IIN != duplicate.IIN {
+			utils.RespondWithError(w, http.StatusConflict, models.Error{Message: "Students have different IINs and cannot be merged"})
+			return
+		}
+
+		seats, err := lockMergedSeats(tx, survivor.StudentID, duplicate.StudentID)
+		var moved map[string]int64
+		if err == nil {
+			moved, err = mergeStudentRecords(tx, survivor, duplicate, userID)
+		}
+		var promotions []waitlistPromotion
+		if err == nil {
+			promotions, err = seats.promote(tx)
+		}
+		if err == nil {
+			err = tx.Commit()
+		}
+		if err != nil {
+			log.Printf("Error merging student %d into %d: %v", req.DuplicateID, survivorID, err)
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Failed to merge students"})
+			return
+		}
+		emailWaitlistPromotions(db, promotions)
+
+		log.Printf("Student %d merged into %d by user %d", req.DuplicateID, survivorID, userID)
+		utils.ResponseJSON(w, map[string]interface{}{
+			"message":    "Students merged successfully",
+			"student_id": survivorID,
+			"merged_id":  req.DuplicateID,
+			"moved_rows": moved,
+		})
+	}
+}
+
+// mergeRecord — снимок ученика, который сохраняется в student_merges.
+type mergeRecord struct {
+	StudentID   int    `json:"student_id"`
+	SchoolID    int    `json:"school_id"`
+	FirstName   string `json:"first_name"`
+	LastName    string `json:"last_name"`
+	Patronymic  string `json:"patronymic"`
+	IIN         string `json:"iin"`
+	DateOfBirth string `json:"date_of_birth"`
+	Gender      string `json:"gender"`
+	Phone       string `json:"phone"`
+	Email       string `json:"email"`
+	Login       string `json:"login"`
+	Grade       int    `json:"grade"`
+	Letter      string `json:"letter"`
+}
+
+func loadMergeRecord(tx *sql.Tx, studentID int) (mergeRecord, error) {
+	m := mergeRecord{StudentID: studentID}
+	err := tx.QueryRow(`
+		SELECT COALESCE(school_id, 0), COALESCE(first_name, ''), COALESCE(last_name, ''), COALESCE(patronymic, ''),
+		       COALESCE(iin, ''), COALESCE(CAST(date_of_birth AS CHAR), ''), COALESCE(gender, ''),
+		       COALESCE(phone, ''), COALESCE(email, ''), COALESCE(login, ''), COALESCE(grade, 0), COALESCE(letter, '')
+		FROM student WHERE student_id = ? FOR UPDATE`, studentID).
+		Scan(&m.SchoolID, &m.FirstName, &m.LastName, &m.Patronymic, &m.IIN, &m.DateOfBirth,
+			&m.Gender, &m.Phone, &m.Email, &m.Login, &m.Grade, &m.Letter)
+	m.DateOfBirth = firstN(m.DateOfBirth, 10)
+	return m, err
+}
+
+// mergedSeats — мероприятия и олимпиады, на которые записаны оба ученика.
+// Заявка дубликата там удаляется при слиянии и освобождает место.
+type mergedSeats struct {
+	eventIDs    []int
+	olympiadIDs []int
+}
+
+// lockMergedSeats находит такие мероприятия и олимпиады и блокирует их
+// строки (и сессии мероприятий) до удаления заявок, сохраняя общий
+// порядок блокировок: сначала событие, потом заявки.
+func lockMergedSeats(tx *sql.Tx, survivorID, duplicateID int) (mergedSeats, error) {
+	var seats mergedSeats
+	var err error
+	seats.eventIDs, err = sharedRegistrationParents(tx, eventQueue, survivorID, duplicateID)
+	if err != nil {
+		return seats, err
+	}
+	seats.olympiadIDs, err = sharedRegistrationParents(tx, olympiadQueue, survivorID, duplicateID)
+	if err != nil {
+		return seats, err
+	}
+
+	for _, eventID := range seats.eventIDs {
+		if _, _, err := eventQueue.lock(tx, eventID); err != nil {
+			return seats, err
+		}
+		rows, err := tx.Query("SELECT id FROM event_sessions WHERE event_id = ? ORDER BY id FOR UPDATE", eventID)
+		if err != nil {
+			return seats, err
+		}
+		rows.Close()
+	}
+	for _, olympiadID := range seats.olympiadIDs {
+		if _, _, err := olympiadQueue.lock(tx, olympiadID); err != nil {
+			return seats, err
+		}
+	}
+	return seats, nil
+}
+
+func sharedRegistrationParents(tx *sql.Tx, q registrationQueue, survivorID, duplicateID int) ([]int, error) {
+	rows, err := tx.Query(fmt.Sprintf(`
+		SELECT DISTINCT d.%[2]s FROM %[1]s d
+		JOIN %[1]s s ON s.%[2]s = d.%[2]s AND s.student_id = ?
+		WHERE d.student_id = ?
+		ORDER BY d.%[2]s`, q.regTable, q.regParentKey), survivorID, duplicateID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// promote отдаёт освободившиеся места листам ожидания мероприятий, их
+// сессий и олимпиад. Вызывается после mergeStudentRecords в той же транзакции.
+func (s mergedSeats) promote(tx *sql.Tx) ([]waitlistPromotion, error) {
+	var promotions []waitlistPromotion
+	for _, eventID := range s.eventIDs {
+		p, err := eventQueue.promote(tx, eventID)
+		if err != nil {
+			return nil, err
+		}
+		promotions = append(promotions, p...)
+		p, err = releaseSessionSeats(tx, eventID)
+		if err != nil {
+			return nil, err
+		}
+		promotions = append(promotions, p...)
+	}
+	for _, olympiadID := range s.olympiadIDs {
+		p, err := olympiadQueue.promote(tx, olympiadID)
+		if err != nil {
+			return nil, err
+		}
+		promotions = append(promotions, p...)
+	}
+	return promotions, nil
+}
+
+// mergeStudentRecords moves every result row of the duplicate onto the
+// survivor, fills the survivor's empty fields, deletes the duplicate and
+// records the merge. It returns the number of moved rows per table.
+func mergeStudentRecords(tx *sql.Tx, survivor, duplicate mergeRecord, mergedBy int) (map[string]int64, error) {
+	moved := map[string]int64{}
+	for _, t := range studentMergeTables {
+		if t.uniqueBy != "" {
+			// Строки, которые уже есть у оставшейся записи, не переносятся
+			_, err := tx.Exec("DELETE d FROM "+t.table+" d JOIN "+t.table+" s ON s."+t.uniqueBy+" = d."+t.uniqueBy+
+				" AND s.student_id = ? WHERE d.student_id = ?", survivor.StudentID, duplicate.StudentID)
+			if err != nil {
+				return nil, err
+			}
+		}
+		res, err := tx.Exec("UPDATE "+t.table+" SET student_id = ? WHERE student_id = ?", survivor.StudentID, duplicate.StudentID)
+		if err != nil {
+			return nil, err
+		}
+		moved[t.table], _ = res.RowsAffected()
+	}
+
+	// Токены календаря ссылаются на владельца через owner_type/owner_id.
+	// Действует один токен на владельца: если он есть у оставшейся записи,
+	// токен дубликата отзывается
+	_, err := tx.Exec(`
+		UPDATE calendar_feed_tokens d
+		JOIN calendar_feed_tokens s ON s.owner_type = 'student' AND s.owner_id = ? AND s.revoked_at IS NULL
+		SET d.revoked_at = NOW()
+		WHERE d.owner_type = 'student' AND d.owner_id = ? AND d.revoked_at IS NULL`, survivor.StudentID, duplicate.StudentID)
+	if err != nil {
+		return nil, err
+	}
+	res, err := tx.Exec("UPDATE calendar_feed_tokens SET owner_id = ? WHERE owner_type = 'student' AND owner_id = ?", survivor.StudentID, duplicate.StudentID)
+	if err != nil {
+		return nil, err
+	}
+	moved["calendar_feed_tokens"], _ = res.RowsAffected()
+
+	// ИИН уникален, поэтому сначала удаляем дубликат, потом заполняем поля
+	if _, err := tx.Exec("DELETE FROM student WHERE student_id = ?", duplicate.StudentID); err != nil {
+		return nil, err
+	}
+	_, err = tx.Exec(`
+		UPDATE student
+		SET iin = COALESCE(NULLIF(iin, ''), ?),
+		    patronymic = COALESCE(NULLIF(patronymic, ''), ?),
+		    date_of_birth = COALESCE(date_of_birth, ?),
+		    gender = COALESCE(NULLIF(gender, ''), ?),
+		    phone = COALESCE(NULLIF(phone, ''), ?)
+		WHERE student_id = ?`,
+		toNullString(duplicate.IIN), toNullString(duplicate.Patronymic), toNullString(duplicate.DateOfBirth),
+		toNullString(duplicate.Gender), toNullString(duplicate.Phone), survivor.StudentID)
+	if err != nil {
+		return nil, err
+	}
+
+	snapshot, _ := json.Marshal(duplicate)
+	movedJSON, _ := json.Marshal(moved)
+	_, err = tx.Exec(`INSERT INTO student_merges (survivor_id, merged_id, school_id, merged_data, moved_rows, merged_by)
+		VALUES (?, ?, ?, ?, ?, ?)`, survivor.StudentID, duplicate.StudentID, survivor.SchoolID, string(snapshot), string(movedJSON), mergedBy)
+	if err != nil {
+		return nil, err
+	}
+	return moved, nil
+}
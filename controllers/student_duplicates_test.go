@@ -0,0 +1,41 @@
+package controllers
+
+import (
+	"math"
+	"testing"
+)
+
+func TestNameSimilarity(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b string
+		want float64
+	}{
+		{"both empty", "", "", 1},
+		{"one empty", "иванов", "", 0},
+		{"identical", "иванов иван", "иванов иван", 1},
+		{"one substitution", "петров", "петрав", 1 - 1.0/6},
+		{"one insertion", "ким", "кимм", 1 - 1.0/4},
+		{"counts runes not bytes", "ё", "е", 0},
+		{"completely different", "abc", "xyz", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := nameSimilarity(tt.a, tt.b)
+			if math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("nameSimilarity(%q, %q) = %v, want %v", tt.a, tt.b, got, tt.want)
+			}
+			if back := nameSimilarity(tt.b, tt.a); math.Abs(back-got) > 1e-9 {
+				t.Errorf("nameSimilarity is not symmetric: %v vs %v", got, back)
+			}
+		})
+	}
+}
+
+func TestNormalizePersonName(t *testing.T) {
+	got := normalizePersonName("  Семёнов ", "Пётр", "")
+	if want := "семенов петр"; got != want {
+		t.Errorf("normalizePersonName = %q, want %q", got, want)
+	}
+}
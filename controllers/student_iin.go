@@ -0,0 +1,179 @@
+package controllers
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"net/http"
+	"ranking-school/models"
+	"ranking-school/utils"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+// applyIINDerivedFields validates the student's IIN and overwrites date of
+// birth and gender with the values encoded in it. Contradicting values that
+// were sent by the client are returned as warnings.
+func applyIINDerivedFields(student *models.Student) ([]string, error) {
+	student.IIN = strings.TrimSpace(student.IIN)
+	if student.IIN == "" {
+		return nil, nil
+	}
+
+	info, err := utils.ParseIIN(student.IIN)
+	if err != nil {
+		return nil, err
+	}
+
+	var warnings []string
+	derivedDOB := info.BirthDate.Format("2006-01-02")
+	if dob := strings.TrimSpace(student.DateOfBirth); dob != "" {
+		if parsed, ok := parseImportDate(firstN(dob, 10)); !ok || parsed.Format("2006-01-02") != derivedDOB {
+			warnings = append(warnings, fmt.Sprintf("date_of_birth %s does not match IIN, replaced with %s", dob, derivedDOB))
+		}
+	}
+	if student.Gender != "" {
+		if gender, ok := normalizeGender(student.Gender); !ok || gender != info.Gender {
+			warnings = append(warnings, fmt.Sprintf("gender %s does not match IIN, replaced with %s", student.Gender, info.Gender))
+		}
+	}
+
+	student.DateOfBirth = derivedDOB
+	student.Gender = info.Gender
+	return warnings, nil
+}
+
+// iinTaken reports whether another student already uses this IIN.
+func iinTaken(db rowQuerier, iin string, exceptStudentID int) (bool, error) {
+	if iin == "" {
+		return false, nil
+	}
+	var exists bool
+	err := db.QueryRow("SELECT EXISTS(SELECT 1 FROM student WHERE iin = ? AND student_id <> ?)", iin, exceptStudentID).Scan(&exists)
+	return exists, err
+}
+
+// validateStudentIIN runs both checks and writes the HTTP error itself.
+// It returns false if the handler should stop.
+func validateStudentIIN(w http.ResponseWriter, db rowQuerier, student *models.Student, studentID int) bool {
+	warnings, err := applyIINDerivedFields(student)
+	if err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, models.Error{Message: err.Error()})
+		return false
+	}
+	taken, err := iinTaken(db, student.IIN, studentID)
+	if err != nil {
+		log.Println("Error checking IIN uniqueness:", err)
+		utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Error checking IIN"})
+		return false
+	}
+	if taken {
+		utils.RespondWithError(w, http.StatusConflict, models.Error{Message: "Student with this IIN already exists"})
+		return false
+	}
+	student.Warnings = warnings
+	return true
+}
+
+func firstN(s string, n int) string {
+	if len(s) > n {
+		return s[:n]
+	}
+	return s
+}
+
+// GetStudentIINIssues lists students of a school whose IIN is missing,
+// invalid, duplicated or contradicts the stored date of birth or gender.
+func (sc *StudentController) GetStudentIINIssues(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := utils.VerifyToken(r)
+		if err != nil {
+			utils.RespondWithError(w, http.StatusUnauthorized, models.Error{Message: err.Error()})
+			return
+		}
+
+		schoolID, err := strconv.Atoi(mux.Vars(r)["school_id"])
+		if err != nil {
+			utils.RespondWithError(w, http.StatusBadRequest, models.Error{Message: "Invalid school ID"})
+			return
+		}
+
+		var userRole string
+		err = db.QueryRow("SELECT role FROM users WHERE id = ?", userID).Scan(&userRole)
+		if err != nil {
+			log.Println("Error fetching user role:", err)
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Error fetching user details"})
+			return
+		}
+		if userRole == "schooladmin" {
+			isMember, err := hasSchoolPermission(db, userID, schoolID, "")
+			if err != nil {
+				log.Println("Error checking school membership:", err)
+				utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Error checking permissions"})
+				return
+			}
+			if !isMember {
+				utils.RespondWithError(w, http.StatusForbidden, models.Error{Message: "You do not have permission to view this school's data"})
+				return
+			}
+		} else if userRole != "superadmin" {
+			utils.RespondWithError(w, http.StatusForbidden, models.Error{Message: "You do not have permission to view students"})
+			return
+		}
+
+		rows, err := db.Query(`
+			SELECT s.student_id, s.first_name, s.last_name, COALESCE(s.iin, ''),
+			       COALESCE(CAST(s.date_of_birth AS CHAR), ''), COALESCE(s.gender, ''),
+			       (SELECT COUNT(*) FROM student d WHERE d.iin = s.iin AND d.student_id <> s.student_id)
+			FROM student s
+			WHERE s.school_id = ? AND s.status = 'active'
+			ORDER BY s.last_name, s.first_name`, schoolID)
+		if err != nil {
+			log.Println("Error fetching students:", err)
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Failed to fetch students"})
+			return
+		}
+		defer rows.Close()
+
+		type iinIssue struct {
+			StudentID int      `json:"student_id"`
+			FirstName string   `json:"first_name"`
+			LastName  string   `json:"last_name"`
+			IIN       string   `json:"iin"`
+			Issues    []string `json:"issues"`
+		}
+		issues := []iinIssue{}
+		for rows.Next() {
+			var item iinIssue
+			var dob, gender string
+			var duplicates int
+			if err := rows.Scan(&item.StudentID, &item.FirstName, &item.LastName, &item.IIN, &dob, &gender, &duplicates); err != nil {
+				log.Println("Error scanning student:", err)
+				utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Failed to fetch students"})
+				return
+			}
+
+			if item.IIN == "" {
+				item.Issues = append(item.Issues, "IIN is missing")
+			} else {
+				check := models.Student{IIN: item.IIN, DateOfBirth: dob, Gender: gender}
+				warnings, err := applyIINDerivedFields(&check)
+				if err != nil {
+					item.Issues = append(item.Issues, err.Error())
+				}
+				item.Issues = append(item.Issues, warnings...)
+				if duplicates > 0 {
+					item.Issues = append(item.Issues, "IIN is used by another student")
+				}
+			}
+
+			if len(item.Issues) > 0 {
+				issues = append(issues, item)
+			}
+		}
+
+		utils.ResponseJSON(w, issues)
+	}
+}
@@ -0,0 +1,578 @@
+package controllers
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"path/filepath"
+	"ranking-school/models"
+	"ranking-school/utils"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/gorilla/mux"
+	"github.com/xuri/excelize/v2"
+)
+
+// Поля, которые можно сопоставить с колонками файла импорта
+var studentImportFields = []string{
+	"first_name", "last_name", "patronymic", "iin", "grade", "letter",
+	"gender", "date_of_birth", "phone", "email",
+}
+
+const maxImportFileSize = 10 << 20 // 10 MB
+
+type importRowError struct {
+	Row     int    `json:"row"`
+	Field   string `json:"field,omitempty"`
+	Message string `json:"message"`
+}
+
+type studentImportCredential struct {
+	Row       int    `json:"row"`
+	StudentID int    `json:"student_id"`
+	LastName  string `json:"last_name"`
+	FirstName string `json:"first_name"`
+	Grade     int    `json:"grade"`
+	Letter    string `json:"letter"`
+	Login     string `json:"login"`
+	Password  string `json:"password"`
+}
+
+// ImportStudents загружает учеников из CSV/XLSX.
+// По умолчанию работает как dry-run и возвращает отчёт по строкам;
+// с ?dry_run=false сохраняет всех учеников в одной транзакции и
+// отдаёт файл с логинами и одноразовыми паролями: до первого входа
+// ученик меняет пароль через SetInitialStudentPassword.
+func (sc *StudentController) ImportStudents(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// Step 1: Verify token and role
+		userID, err := utils.VerifyToken(r)
+		if err != nil {
+			utils.RespondWithError(w, http.StatusUnauthorized, models.Error{Message: err.Error()})
+			return
+		}
+
+		var userRole string
+		err = db.QueryRow("SELECT role FROM users WHERE id = ?", userID).Scan(&userRole)
+		if err != nil {
+			log.Println("Error fetching user role:", err)
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Error fetching user details"})
+			return
+		}
+
+		schoolID, err := strconv.Atoi(mux.Vars(r)["school_id"])
+		if err != nil {
+			utils.RespondWithError(w, http.StatusBadRequest, models.Error{Message: "Invalid school ID"})
+			return
+		}
+
+		switch userRole {
+		case "superadmin":
+		case "schooladmin":
+			allowed, err := hasSchoolPermission(db, userID, schoolID, PermissionManageStudents)
+			if err != nil {
+				log.Println("Error checking school membership:", err)
+				utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Error checking permissions"})
+				return
+			}
+			if !allowed {
+				utils.RespondWithError(w, http.StatusForbidden, models.Error{Message: "You do not have permission to manage students of this school"})
+				return
+			}
+		default:
+			utils.RespondWithError(w, http.StatusForbidden, models.Error{Message: "You do not have permission to import students"})
+			return
+		}
+
+		var schoolExists bool
+		err = db.QueryRow("SELECT EXISTS(SELECT 1 FROM Schools WHERE school_id = ?)", schoolID).Scan(&schoolExists)
+		if err != nil {
+			log.Println("Error checking school:", err)
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Error checking school"})
+			return
+		}
+		if !schoolExists {
+			utils.RespondWithError(w, http.StatusNotFound, models.Error{Message: "School not found"})
+			return
+		}
+
+		// Step 2: Read the uploaded file and column mapping
+		if err := r.ParseMultipartForm(maxImportFileSize); err != nil {
+			utils.RespondWithError(w, http.StatusBadRequest, models.Error{Message: "Invalid form data"})
+			return
+		}
+		file, header, err := r.FormFile("file")
+		if err != nil {
+			utils.RespondWithError(w, http.StatusBadRequest, models.Error{Message: "File is required"})
+			return
+		}
+		defer file.Close()
+
+		// mapping: {"first_name": "Имя", "iin": "ИИН", ...}; по умолчанию
+		// заголовки колонок должны совпадать с названиями полей
+		mapping := map[string]string{}
+		if raw := r.FormValue("mapping"); raw != "" {
+			if err := json.Unmarshal([]byte(raw), &mapping); err != nil {
+				utils.RespondWithError(w, http.StatusBadRequest, models.Error{Message: "Invalid column mapping"})
+				return
+			}
+		}
+
+		dryRun := r.URL.Query().Get("dry_run") != "false"
+
+		var records [][]string
+		switch strings.ToLower(filepath.Ext(header.Filename)) {
+		case ".csv":
+			records, err = readImportCSV(file)
+		case ".xlsx":
+			records, err = readImportXLSX(file)
+		default:
+			utils.RespondWithError(w, http.StatusBadRequest, models.Error{Message: "Only .csv and .xlsx files are supported"})
+			return
+		}
+		if err != nil {
+			log.Println("Error reading import file:", err)
+			utils.RespondWithError(w, http.StatusBadRequest, models.Error{Message: "Failed to read file: " + err.Error()})
+			return
+		}
+		if len(records) < 2 {
+			utils.RespondWithError(w, http.StatusBadRequest, models.Error{Message: "File must contain a header row and at least one student"})
+			return
+		}
+
+		columns, err := resolveImportColumns(records[0], mapping, studentImportFields,
+			[]string{"first_name", "last_name", "iin", "grade", "letter"})
+		if err != nil {
+			utils.RespondWithError(w, http.StatusBadRequest, models.Error{Message: err.Error()})
+			return
+		}
+
+		// Step 3: Validate every row
+		students, rowNumbers, rowErrors, rowWarnings := parseStudentImportRows(records[1:], columns, schoolID)
+
+		dupErrors, err := findExistingStudentIINs(db, students, rowNumbers)
+		if err != nil {
+			log.Println("Error checking existing IINs:", err)
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Error checking existing students"})
+			return
+		}
+		rowErrors = append(rowErrors, dupErrors...)
+
+		invalidRows := map[int]bool{}
+		for _, e := range rowErrors {
+			invalidRows[e.Row] = true
+		}
+		validRows := 0
+		for _, row := range rowNumbers {
+			if !invalidRows[row] {
+				validRows++
+			}
+		}
+
+		report := map[string]interface{}{
+			"dry_run":      dryRun,
+			"total_rows":   validRows + len(invalidRows),
+			"valid_rows":   validRows,
+			"invalid_rows": len(invalidRows),
+			"errors":       rowErrors,
+			"warnings":     rowWarnings,
+		}
+
+		if dryRun {
+			utils.ResponseJSON(w, report)
+			return
+		}
+		if len(rowErrors) > 0 {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusUnprocessableEntity)
+			json.NewEncoder(w).Encode(report)
+			return
+		}
+
+		// Step 4: Insert all students in one transaction
+		tx, err := db.Begin()
+		if err != nil {
+			log.Println("Error starting transaction:", err)
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Failed to start transaction"})
+			return
+		}
+		defer tx.Rollback()
+
+		stmt, err := tx.Prepare(`INSERT INTO student (first_name, last_name, patronymic, iin, school_id, date_of_birth, grade, letter, gender, phone, email, password, must_change_password, role, login)
+          VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, TRUE, ?, ?)`)
+		if err != nil {
+			log.Println("Error preparing insert:", err)
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Failed to import students"})
+			return
+		}
+		defer stmt.Close()
+
+		credentials := make([]studentImportCredential, 0, len(students))
+		for i, student := range students {
+			var generatedEmail string
+			student.Login, generatedEmail, student.Password = generateStudentCredentials(student.FirstName, student.LastName)
+			if student.Email == "" {
+				student.Email = generatedEmail
+			}
+			student.Role = "student"
+
+			result, err := stmt.Exec(student.FirstName, student.LastName, student.Patronymic, student.IIN, student.SchoolID,
+				toNullString(student.DateOfBirth), student.Grade, student.Letter, toNullString(student.Gender),
+				toNullString(student.Phone), student.Email, student.Password, student.Role, student.Login)
+			if err != nil {
+				log.Printf("Error inserting imported student on row %d: %v", rowNumbers[i], err)
+				utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: fmt.Sprintf("Failed to import student on row %d", rowNumbers[i])})
+				return
+			}
+			studentID, _ := result.LastInsertId()
+			if err := syncStudentClass(tx, int(studentID)); err != nil {
+				log.Printf("Error assigning imported student on row %d to class: %v", rowNumbers[i], err)
+				utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: fmt.Sprintf("Failed to import student on row %d", rowNumbers[i])})
+				return
+			}
+
+			credentials = append(credentials, studentImportCredential{
+				Row:       rowNumbers[i],
+				StudentID: int(studentID),
+				LastName:  student.LastName,
+				FirstName: student.FirstName,
+				Grade:     student.Grade,
+				Letter:    student.Letter,
+				Login:     student.Login,
+				Password:  student.Password,
+			})
+		}
+
+		if err := tx.Commit(); err != nil {
+			log.Println("Error committing import:", err)
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Failed to import students"})
+			return
+		}
+
+		log.Printf("Imported %d students into school %d by user %d", len(credentials), schoolID, userID)
+
+		// Step 5: Return credentials as a downloadable CSV
+		var buf bytes.Buffer
+		buf.WriteString("\ufeff") // BOM, чтобы Excel корректно открыл кириллицу
+		cw := csv.NewWriter(&buf)
+		cw.Write([]string{"row", "student_id", "last_name", "first_name", "grade", "letter", "login", "password"})
+		for _, c := range credentials {
+			cw.Write([]string{
+				strconv.Itoa(c.Row), strconv.Itoa(c.StudentID), c.LastName, c.FirstName,
+				strconv.Itoa(c.Grade), c.Letter, c.Login, c.Password,
+			})
+		}
+		cw.Flush()
+
+		fileName := fmt.Sprintf("students-school-%d-%s.csv", schoolID, time.Now().Format("20060102-150405"))
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", fileName))
+		w.WriteHeader(http.StatusCreated)
+		w.Write(buf.Bytes())
+	}
+}
+
+// SetInitialStudentPassword меняет одноразовый пароль из импорта на
+// пароль ученика. Пока это не сделано, Login отвечает 403.
+func (sc *StudentController) SetInitialStudentPassword(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Login       string `json:"login"`
+			Password    string `json:"password"`
+			NewPassword string `json:"new_password"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			utils.RespondWithError(w, http.StatusBadRequest, models.Error{Message: "Invalid request body"})
+			return
+		}
+		req.Login = strings.TrimSpace(req.Login)
+		if req.Login == "" || req.Password == "" {
+			utils.RespondWithError(w, http.StatusBadRequest, models.Error{Message: "Login and password are required"})
+			return
+		}
+		if len([]rune(req.NewPassword)) < 6 {
+			utils.RespondWithError(w, http.StatusBadRequest, models.Error{Message: "New password must be at least 6 characters long"})
+			return
+		}
+		if req.NewPassword == req.Password {
+			utils.RespondWithError(w, http.StatusBadRequest, models.Error{Message: "New password cannot be the same as the one-time password"})
+			return
+		}
+
+		var studentID int
+		var password string
+		var mustChange bool
+		err := db.QueryRow("SELECT student_id, password, must_change_password FROM student WHERE login = ?", req.Login).
+			Scan(&studentID, &password, &mustChange)
+		if err == sql.ErrNoRows || (err == nil && password != req.Password) {
+			utils.RespondWithError(w, http.StatusUnauthorized, models.Error{Message: "Invalid login or password"})
+			return
+		} else if err != nil {
+			log.Println("Error fetching student credentials:", err)
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Failed to update password"})
+			return
+		}
+		if !mustChange {
+			utils.RespondWithError(w, http.StatusConflict, models.Error{Message: "Password has already been changed"})
+			return
+		}
+
+		// Пароли учеников хранятся так же, как их проверяет Login
+		_, err = db.Exec("UPDATE student SET password = ?, must_change_password = FALSE WHERE student_id = ? AND must_change_password",
+			req.NewPassword, studentID)
+		if err != nil {
+			log.Println("Error updating student password:", err)
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Failed to update password"})
+			return
+		}
+
+		utils.ResponseJSON(w, map[string]string{"message": "Password updated successfully"})
+	}
+}
+
+func readImportCSV(r io.Reader) ([][]string, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	data = bytes.TrimPrefix(data, []byte("\ufeff"))
+
+	// Excel в русской локали сохраняет CSV через ";"
+	firstLine := data
+	if i := bytes.IndexByte(data, '\n'); i >= 0 {
+		firstLine = data[:i]
+	}
+	reader := csv.NewReader(bytes.NewReader(data))
+	if bytes.Count(firstLine, []byte(";")) > bytes.Count(firstLine, []byte(",")) {
+		reader.Comma = ';'
+	}
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	return reader.ReadAll()
+}
+
+func readImportXLSX(r io.Reader) ([][]string, error) {
+	f, err := excelize.OpenReader(r)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	sheets := f.GetSheetList()
+	if len(sheets) == 0 {
+		return nil, fmt.Errorf("workbook has no sheets")
+	}
+	return f.GetRows(sheets[0])
+}
+
+// resolveImportColumns maps each import field to its column index.
+func resolveImportColumns(headers []string, mapping map[string]string, fields, required []string) (map[string]int, error) {
+	index := make(map[string]int, len(headers))
+	for i, h := range headers {
+		index[strings.ToLower(strings.TrimSpace(h))] = i
+	}
+
+	columns := map[string]int{}
+	for _, field := range fields {
+		name := field
+		if mapped, ok := mapping[field]; ok && mapped != "" {
+			name = mapped
+		}
+		if i, ok := index[strings.ToLower(strings.TrimSpace(name))]; ok {
+			columns[field] = i
+		} else if _, ok := mapping[field]; ok {
+			return nil, fmt.Errorf("column %q mapped to %s not found in file", name, field)
+		}
+	}
+
+	for _, field := range required {
+		if _, ok := columns[field]; !ok {
+			return nil, fmt.Errorf("required column %s is missing", field)
+		}
+	}
+	return columns, nil
+}
+
+func parseStudentImportRows(records [][]string, columns map[string]int, schoolID int) ([]models.Student, []int, []importRowError, []importRowError) {
+	var students []models.Student
+	var rowNumbers []int
+	var rowErrors, rowWarnings []importRowError
+	seenIIN := map[string]int{}
+
+	for i, record := range records {
+		rowNum := i + 2 // строка 1 — заголовок
+		cell := func(field string) string {
+			idx, ok := columns[field]
+			if !ok || idx >= len(record) {
+				return ""
+			}
+			return strings.TrimSpace(record[idx])
+		}
+
+		// Пустые строки в конце файла не считаются ошибкой
+		empty := true
+		for _, v := range record {
+			if strings.TrimSpace(v) != "" {
+				empty = false
+				break
+			}
+		}
+		if empty {
+			continue
+		}
+
+		var errs []importRowError
+		addErr := func(field, msg string) {
+			errs = append(errs, importRowError{Row: rowNum, Field: field, Message: msg})
+		}
+
+		student := models.Student{
+			FirstName:  cell("first_name"),
+			LastName:   cell("last_name"),
+			Patronymic: cell("patronymic"),
+			IIN:        cell("iin"),
+			Letter:     strings.ToUpper(cell("letter")),
+			Phone:      cell("phone"),
+			Email:      cell("email"),
+			SchoolID:   schoolID,
+		}
+
+		if !isValidPersonName(student.FirstName) {
+			addErr("first_name", "First name is required and may contain only letters, spaces and hyphens")
+		}
+		if !isValidPersonName(student.LastName) {
+			addErr("last_name", "Last name is required and may contain only letters, spaces and hyphens")
+		}
+		if student.Patronymic != "" && !isValidPersonName(student.Patronymic) {
+			addErr("patronymic", "Patronymic may contain only letters, spaces and hyphens")
+		}
+
+		iinValid := false
+		if _, err := utils.ParseIIN(student.IIN); err != nil {
+			addErr("iin", err.Error())
+		} else if prev, ok := seenIIN[student.IIN]; ok {
+			addErr("iin", fmt.Sprintf("Duplicate IIN, already used on row %d", prev))
+		} else {
+			seenIIN[student.IIN] = rowNum
+			iinValid = true
+		}
+
+		grade, err := strconv.Atoi(cell("grade"))
+		if err != nil || grade < 1 || grade > 11 {
+			addErr("grade", "Grade must be a number from 1 to 11")
+		}
+		student.Grade = grade
+
+		if runes := []rune(student.Letter); len(runes) != 1 || !unicode.IsLetter(runes[0]) {
+			addErr("letter", "Letter must be a single letter")
+		}
+
+		if raw := cell("gender"); raw != "" {
+			gender, ok := normalizeGender(raw)
+			if !ok {
+				addErr("gender", "Gender must be male or female")
+			}
+			student.Gender = gender
+		}
+
+		if raw := cell("date_of_birth"); raw != "" {
+			dob, ok := parseImportDate(raw)
+			if !ok {
+				addErr("date_of_birth", "Date of birth must be in YYYY-MM-DD or DD.MM.YYYY format")
+			} else if dob.After(time.Now()) {
+				addErr("date_of_birth", "Date of birth cannot be in the future")
+			} else {
+				student.DateOfBirth = dob.Format("2006-01-02")
+			}
+		}
+
+		if len(errs) > 0 {
+			rowErrors = append(rowErrors, errs...)
+			continue
+		}
+
+		// Дата рождения и пол берутся из ИИН, расхождения попадают в предупреждения
+		if iinValid {
+			mismatches, _ := applyIINDerivedFields(&student)
+			for _, m := range mismatches {
+				rowWarnings = append(rowWarnings, importRowError{Row: rowNum, Field: "iin", Message: m})
+			}
+		}
+		students = append(students, student)
+		rowNumbers = append(rowNumbers, rowNum)
+	}
+
+	return students, rowNumbers, rowErrors, rowWarnings
+}
+
+// findExistingStudentIINs reports rows whose IIN already belongs to a student.
+func findExistingStudentIINs(db *sql.DB, students []models.Student, rowNumbers []int) ([]importRowError, error) {
+	if len(students) == 0 {
+		return nil, nil
+	}
+
+	rowByIIN := make(map[string]int, len(students))
+	placeholders := make([]string, 0, len(students))
+	args := make([]interface{}, 0, len(students))
+	for i, s := range students {
+		rowByIIN[s.IIN] = rowNumbers[i]
+		placeholders = append(placeholders, "?")
+		args = append(args, s.IIN)
+	}
+
+	rows, err := db.Query("SELECT iin FROM student WHERE iin IN ("+strings.Join(placeholders, ",")+")", args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var errs []importRowError
+	for rows.Next() {
+		var iin string
+		if err := rows.Scan(&iin); err != nil {
+			return nil, err
+		}
+		errs = append(errs, importRowError{Row: rowByIIN[iin], Field: "iin", Message: "Student with this IIN already exists"})
+	}
+	return errs, rows.Err()
+}
+
+func isValidPersonName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for _, r := range name {
+		if !unicode.IsLetter(r) && r != ' ' && r != '-' && r != '\'' {
+			return false
+		}
+	}
+	return true
+}
+
+func normalizeGender(raw string) (string, bool) {
+	switch strings.ToLower(strings.TrimSpace(raw)) {
+	case "male", "m", "м", "муж", "мужской":
+		return "male", true
+	case "female", "f", "ж", "жен", "женский":
+		return "female", true
+	}
+	return "", false
+}
+
+func parseImportDate(raw string) (time.Time, bool) {
+	// "01-02-06" — так excelize отдаёт ячейки с форматом даты по умолчанию
+	for _, layout := range []string{"2006-01-02", "02.01.2006", "02/01/2006", "01-02-06"} {
+		if t, err := time.Parse(layout, raw); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
@@ -0,0 +1,500 @@
+package controllers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"log"
+	"net/http"
+	"ranking-school/models"
+	"ranking-school/utils"
+	"strconv"
+	"unicode"
+
+	"github.com/gorilla/mux"
+)
+
+type StudentTransferController struct{}
+
+type StudentTransfer struct {
+	ID             int     `json:"id"`
+	StudentID      int     `json:"student_id"`
+	FirstName      string  `json:"first_name"`
+	LastName       string  `json:"last_name"`
+	FromSchoolID   int     `json:"from_school_id"`
+	FromSchoolName string  `json:"from_school_name"`
+	ToSchoolID     int     `json:"to_school_id"`
+	ToSchoolName   string  `json:"to_school_name"`
+	Status         string  `json:"status"`
+	Reason         *string `json:"reason"`
+	DecisionNote   *string `json:"decision_note"`
+	InitiatedBy    int     `json:"initiated_by"`
+	DecidedBy      *int    `json:"decided_by"`
+	CreatedAt      string  `json:"created_at"`
+	DecidedAt      *string `json:"decided_at"`
+}
+
+const studentTransferSelect = `
+	SELECT t.id, t.student_id, COALESCE(st.first_name, ''), COALESCE(st.last_name, ''),
+	       t.from_school_id, COALESCE(fs.school_name, ''), t.to_school_id, COALESCE(ts.school_name, ''),
+	       t.status, t.reason, t.decision_note, t.initiated_by, t.decided_by,
+	       CAST(t.created_at AS CHAR), CAST(t.decided_at AS CHAR)
+	FROM student_transfers t
+	LEFT JOIN student st ON st.student_id = t.student_id
+	LEFT JOIN Schools fs ON fs.school_id = t.from_school_id
+	LEFT JOIN Schools ts ON ts.school_id = t.to_school_id`
+
+func scanStudentTransfers(rows *sql.Rows) ([]StudentTransfer, error) {
+	transfers := []StudentTransfer{}
+	for rows.Next() {
+		var t StudentTransfer
+		var reason, note, decidedAt sql.NullString
+		var decidedBy sql.NullInt64
+		if err := rows.Scan(&t.ID, &t.StudentID, &t.FirstName, &t.LastName,
+			&t.FromSchoolID, &t.FromSchoolName, &t.ToSchoolID, &t.ToSchoolName,
+			&t.Status, &reason, &note, &t.InitiatedBy, &decidedBy, &t.CreatedAt, &decidedAt); err != nil {
+			return nil, err
+		}
+		if reason.Valid {
+			t.Reason = &reason.String
+		}
+		if note.Valid {
+			t.DecisionNote = &note.String
+		}
+		if decidedBy.Valid {
+			id := int(decidedBy.Int64)
+			t.DecidedBy = &id
+		}
+		if decidedAt.Valid {
+			t.DecidedAt = &decidedAt.String
+		}
+		transfers = append(transfers, t)
+	}
+	return transfers, rows.Err()
+}
+
+// canManageStudentsOf reports whether the user is a superadmin or may manage
+// students of the given school.
+func canManageStudentsOf(db rowQuerier, userID, schoolID int) (bool, error) {
+	var role string
+	err := db.QueryRow("SELECT role FROM users WHERE id = ?", userID).Scan(&role)
+	if err == sql.ErrNoRows {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+	if role == "superadmin" {
+		return true, nil
+	}
+	if role != "schooladmin" {
+		return false, nil
+	}
+	return hasSchoolPermission(db, userID, schoolID, PermissionManageStudents)
+}
+
+// CreateTransfer — школа-отправитель создаёт заявку на перевод ученика.
+func (tc *StudentTransferController) CreateTransfer(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := utils.VerifyToken(r)
+		if err != nil {
+			utils.RespondWithError(w, http.StatusUnauthorized, models.Error{Message: err.Error()})
+			return
+		}
+
+		studentID, err := strconv.Atoi(mux.Vars(r)["student_id"])
+		if err != nil {
+			utils.RespondWithError(w, http.StatusBadRequest, models.Error{Message: "Invalid student ID"})
+			return
+		}
+
+		var req struct {
+			ToSchoolID int    `json:"to_school_id"`
+			Reason     string `json:"reason"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.ToSchoolID <= 0 {
+			utils.RespondWithError(w, http.StatusBadRequest, models.Error{Message: "to_school_id is required"})
+			return
+		}
+
+		tx, err := db.Begin()
+		if err != nil {
+			log.Println("Error starting transaction:", err)
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Failed to start transaction"})
+			return
+		}
+		defer tx.Rollback()
+
+		// Блокировка строки ученика не даёт двум параллельным запросам
+		// одновременно пройти проверку на заявку в ожидании
+		var fromSchoolID int
+		var status string
+		err = tx.QueryRow("SELECT school_id, status FROM student WHERE student_id = ? FOR UPDATE", studentID).Scan(&fromSchoolID, &status)
+		if err == sql.ErrNoRows {
+			utils.RespondWithError(w, http.StatusNotFound, models.Error{Message: "Student not found"})
+			return
+		} else if err != nil {
+			log.Println("Error fetching student:", err)
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Error fetching student"})
+			return
+		}
+		if status != "active" {
+			utils.RespondWithError(w, http.StatusBadRequest, models.Error{Message: "Only active students can be transferred"})
+			return
+		}
+		if fromSchoolID == req.ToSchoolID {
+			utils.RespondWithError(w, http.StatusBadRequest, models.Error{Message: "Student already belongs to this school"})
+			return
+		}
+
+		allowed, err := canManageStudentsOf(tx, userID, fromSchoolID)
+		if err != nil {
+			log.Println("Error checking permissions:", err)
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Error checking permissions"})
+			return
+		}
+		if !allowed {
+			utils.RespondWithError(w, http.StatusForbidden, models.Error{Message: "Only the student's current school can initiate a transfer"})
+			return
+		}
+
+		var schoolExists bool
+		err = tx.QueryRow("SELECT EXISTS(SELECT 1 FROM Schools WHERE school_id = ?)", req.ToSchoolID).Scan(&schoolExists)
+		if err != nil {
+			log.Println("Error checking school:", err)
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Error checking school"})
+			return
+		}
+		if !schoolExists {
+			utils.RespondWithError(w, http.StatusNotFound, models.Error{Message: "Destination school not found"})
+			return
+		}
+
+		var pending bool
+		err = tx.QueryRow("SELECT EXISTS(SELECT 1 FROM student_transfers WHERE student_id = ? AND status = 'pending')", studentID).Scan(&pending)
+		if err != nil {
+			log.Println("Error checking pending transfers:", err)
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Error checking transfers"})
+			return
+		}
+		if pending {
+			utils.RespondWithError(w, http.StatusConflict, models.Error{Message: "Student already has a pending transfer"})
+			return
+		}
+
+		result, err := tx.Exec(`INSERT INTO student_transfers (student_id, from_school_id, to_school_id, reason, initiated_by)
+			VALUES (?, ?, ?, ?, ?)`, studentID, fromSchoolID, req.ToSchoolID, toNullString(req.Reason), userID)
+		if err != nil {
+			log.Println("Error creating transfer:", err)
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Failed to create transfer"})
+			return
+		}
+		transferID, _ := result.LastInsertId()
+
+		if err := tx.Commit(); err != nil {
+			log.Println("Error committing transfer:", err)
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Failed to create transfer"})
+			return
+		}
+
+		utils.ResponseJSON(w, map[string]interface{}{
+			"message":     "Transfer request created",
+			"transfer_id": transferID,
+		})
+	}
+}
+
+// decideTransfer handles accept, reject and cancel. Accept and reject are
+// done by the destination school, cancel by the origin school.
+func (tc *StudentTransferController) decideTransfer(db *sql.DB, action string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := utils.VerifyToken(r)
+		if err != nil {
+			utils.RespondWithError(w, http.StatusUnauthorized, models.Error{Message: err.Error()})
+			return
+		}
+
+		transferID, err := strconv.Atoi(mux.Vars(r)["transfer_id"])
+		if err != nil {
+			utils.RespondWithError(w, http.StatusBadRequest, models.Error{Message: "Invalid transfer ID"})
+			return
+		}
+
+		var req struct {
+			Note   string `json:"note"`
+			Grade  int    `json:"grade"`
+			Letter string `json:"letter"`
+		}
+		if r.ContentLength != 0 {
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				utils.RespondWithError(w, http.StatusBadRequest, models.Error{Message: "Invalid request body"})
+				return
+			}
+		}
+		// Класс в новой школе задают теми же правилами, что и при импорте
+		if req.Grade != 0 && (req.Grade < 1 || req.Grade > 11) {
+			utils.RespondWithError(w, http.StatusBadRequest, models.Error{Message: "Grade must be a number from 1 to 11"})
+			return
+		}
+		req.Letter = normalizeClassLetter(req.Letter)
+		if runes := []rune(req.Letter); req.Letter != "" && (len(runes) != 1 || !unicode.IsLetter(runes[0])) {
+			utils.RespondWithError(w, http.StatusBadRequest, models.Error{Message: "Letter must be a single letter"})
+			return
+		}
+
+		tx, err := db.Begin()
+		if err != nil {
+			log.Println("Error starting transaction:", err)
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Failed to start transaction"})
+			return
+		}
+		defer tx.Rollback()
+
+		var studentID, fromSchoolID, toSchoolID int
+		var status string
+		err = tx.QueryRow("SELECT student_id, from_school_id, to_school_id, status FROM student_transfers WHERE id = ? FOR UPDATE", transferID).
+			Scan(&studentID, &fromSchoolID, &toSchoolID, &status)
+		if err == sql.ErrNoRows {
+			utils.RespondWithError(w, http.StatusNotFound, models.Error{Message: "Transfer not found"})
+			return
+		} else if err != nil {
+			log.Println("Error fetching transfer:", err)
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Error fetching transfer"})
+			return
+		}
+		if status != "pending" {
+			utils.RespondWithError(w, http.StatusConflict, models.Error{Message: "Transfer is already " + status})
+			return
+		}
+
+		deciderSchoolID := toSchoolID
+		if action == "cancelled" {
+			deciderSchoolID = fromSchoolID
+		}
+		allowed, err := canManageStudentsOf(tx, userID, deciderSchoolID)
+		if err != nil {
+			log.Println("Error checking permissions:", err)
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Error checking permissions"})
+			return
+		}
+		if !allowed {
+			utils.RespondWithError(w, http.StatusForbidden, models.Error{Message: "You do not have permission to change this transfer"})
+			return
+		}
+
+		if action == "accepted" {
+			var currentSchoolID int
+			err = tx.QueryRow("SELECT school_id FROM student WHERE student_id = ? FOR UPDATE", studentID).Scan(&currentSchoolID)
+			if err != nil {
+				log.Println("Error fetching student:", err)
+				utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Error fetching student"})
+				return
+			}
+			if currentSchoolID != fromSchoolID {
+				utils.RespondWithError(w, http.StatusConflict, models.Error{Message: "Student no longer belongs to the origin school"})
+				return
+			}
+
+			// Переносится только сам ученик: прошлые результаты сохраняют
+			// school_id школы, где они были получены
+			query := "UPDATE student SET school_id = ?"
+			args := []interface{}{toSchoolID}
+			if req.Grade > 0 {
+				query += ", grade = ?"
+				args = append(args, req.Grade)
+			}
+			if req.Letter != "" {
+				query += ", letter = ?"
+				args = append(args, req.Letter)
+			}
+			query += " WHERE student_id = ?"
+			args = append(args, studentID)
+			if _, err := tx.Exec(query, args...); err != nil {
+				log.Println("Error moving student:", err)
+				utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Failed to move student"})
+				return
+			}
+			if err := syncStudentClass(tx, studentID); err != nil {
+				log.Println("Error assigning transferred student to class:", err)
+				utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Failed to move student"})
+				return
+			}
+		}
+
+		_, err = tx.Exec(`UPDATE student_transfers SET status = ?, decision_note = ?, decided_by = ?, decided_at = NOW() WHERE id = ?`,
+			action, toNullString(req.Note), userID, transferID)
+		if err != nil {
+			log.Println("Error updating transfer:", err)
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Failed to update transfer"})
+			return
+		}
+
+		if err := tx.Commit(); err != nil {
+			log.Println("Error committing transfer:", err)
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Failed to update transfer"})
+			return
+		}
+
+		log.Printf("Transfer %d of student %d %s by user %d", transferID, studentID, action, userID)
+		utils.ResponseJSON(w, map[string]interface{}{
+			"message":     "Transfer " + action,
+			"transfer_id": transferID,
+			"status":      action,
+		})
+	}
+}
+
+// AcceptTransfer — школа-получатель принимает ученика.
+func (tc *StudentTransferController) AcceptTransfer(db *sql.DB) http.HandlerFunc {
+	return tc.decideTransfer(db, "accepted")
+}
+
+// RejectTransfer — школа-получатель отклоняет заявку.
+func (tc *StudentTransferController) RejectTransfer(db *sql.DB) http.HandlerFunc {
+	return tc.decideTransfer(db, "rejected")
+}
+
+// CancelTransfer — школа-отправитель отзывает заявку.
+func (tc *StudentTransferController) CancelTransfer(db *sql.DB) http.HandlerFunc {
+	return tc.decideTransfer(db, "cancelled")
+}
+
+// GetSchoolTransfers возвращает входящие и исходящие переводы школы.
+// ?direction=incoming|outgoing, ?status=pending|accepted|rejected|cancelled
+func (tc *StudentTransferController) GetSchoolTransfers(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := utils.VerifyToken(r)
+		if err != nil {
+			utils.RespondWithError(w, http.StatusUnauthorized, models.Error{Message: err.Error()})
+			return
+		}
+
+		schoolID, err := strconv.Atoi(mux.Vars(r)["school_id"])
+		if err != nil {
+			utils.RespondWithError(w, http.StatusBadRequest, models.Error{Message: "Invalid school ID"})
+			return
+		}
+
+		var userRole string
+		err = db.QueryRow("SELECT role FROM users WHERE id = ?", userID).Scan(&userRole)
+		if err != nil {
+			log.Println("Error fetching user role:", err)
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Error fetching user details"})
+			return
+		}
+		if userRole == "schooladmin" {
+			isMember, err := hasSchoolPermission(db, userID, schoolID, "")
+			if err != nil {
+				log.Println("Error checking school membership:", err)
+				utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Error checking permissions"})
+				return
+			}
+			if !isMember {
+				utils.RespondWithError(w, http.StatusForbidden, models.Error{Message: "You do not have permission to view this school's transfers"})
+				return
+			}
+		} else if userRole != "superadmin" {
+			utils.RespondWithError(w, http.StatusForbidden, models.Error{Message: "You do not have permission to view transfers"})
+			return
+		}
+
+		query := studentTransferSelect
+		var args []interface{}
+		switch r.URL.Query().Get("direction") {
+		case "incoming":
+			query += " WHERE t.to_school_id = ?"
+			args = append(args, schoolID)
+		case "outgoing":
+			query += " WHERE t.from_school_id = ?"
+			args = append(args, schoolID)
+		case "":
+			query += " WHERE (t.to_school_id = ? OR t.from_school_id = ?)"
+			args = append(args, schoolID, schoolID)
+		default:
+			utils.RespondWithError(w, http.StatusBadRequest, models.Error{Message: "direction must be incoming or outgoing"})
+			return
+		}
+		if status := r.URL.Query().Get("status"); status != "" {
+			query += " AND t.status = ?"
+			args = append(args, status)
+		}
+		query += " ORDER BY t.created_at DESC"
+
+		rows, err := db.Query(query, args...)
+		if err != nil {
+			log.Println("Error fetching transfers:", err)
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Failed to fetch transfers"})
+			return
+		}
+		defer rows.Close()
+
+		transfers, err := scanStudentTransfers(rows)
+		if err != nil {
+			log.Println("Error scanning transfers:", err)
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Failed to fetch transfers"})
+			return
+		}
+		utils.ResponseJSON(w, transfers)
+	}
+}
+
+// GetStudentTransfers возвращает историю переводов ученика.
+func (tc *StudentTransferController) GetStudentTransfers(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := utils.VerifyToken(r)
+		if err != nil {
+			utils.RespondWithError(w, http.StatusUnauthorized, models.Error{Message: err.Error()})
+			return
+		}
+
+		studentID, err := strconv.Atoi(mux.Vars(r)["student_id"])
+		if err != nil {
+			utils.RespondWithError(w, http.StatusBadRequest, models.Error{Message: "Invalid student ID"})
+			return
+		}
+
+		var userRole string
+		err = db.QueryRow("SELECT role FROM users WHERE id = ?", userID).Scan(&userRole)
+		if err != nil && err != sql.ErrNoRows {
+			log.Println("Error fetching user role:", err)
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Error fetching user details"})
+			return
+		}
+
+		// Историю видят ученик, суперадмин и админы школ, участвовавших в переводах
+		allowed := userRole == "superadmin" || (err == sql.ErrNoRows && userID == studentID)
+		if userRole == "schooladmin" {
+			err = db.QueryRow(`
+				SELECT EXISTS(
+					SELECT 1 FROM school_members m
+					WHERE m.user_id = ? AND m.school_id IN (
+						SELECT school_id FROM student WHERE student_id = ?
+						UNION SELECT from_school_id FROM student_transfers WHERE student_id = ?
+						UNION SELECT to_school_id FROM student_transfers WHERE student_id = ?))`,
+				userID, studentID, studentID, studentID).Scan(&allowed)
+			if err != nil {
+				log.Println("Error checking school membership:", err)
+				utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Error checking permissions"})
+				return
+			}
+		}
+		if !allowed {
+			utils.RespondWithError(w, http.StatusForbidden, models.Error{Message: "You do not have permission to view this student's transfers"})
+			return
+		}
+
+		rows, err := db.Query(studentTransferSelect+" WHERE t.student_id = ? ORDER BY t.created_at DESC", studentID)
+		if err != nil {
+			log.Println("Error fetching transfers:", err)
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Failed to fetch transfers"})
+			return
+		}
+		defer rows.Close()
+
+		transfers, err := scanStudentTransfers(rows)
+		if err != nil {
+			log.Println("Error scanning transfers:", err)
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Failed to fetch transfers"})
+			return
+		}
+		utils.ResponseJSON(w, transfers)
+	}
+}
@@ -359,10 +359,26 @@ func (c *SubjectOlympiadController) CreateSubjectOlympiad(db *sql.DB) http.Handl
 			return
 		}
 
+		windowFields, err := parseRegistrationWindowForm(r, registrationWindow{})
+		if err != nil {
+			utils.RespondWithError(w, http.StatusBadRequest, models.Error{Message: err.Error()})
+			return
+		}
+
+		// Школа создаёт черновик; submit_for_review=true — сразу на модерацию
+		status, err := initialPublicationStatus(db, userID, r.FormValue("submit_for_review") == "true")
+		if err != nil {
+			log.Println("Error fetching user role:", err)
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Error fetching user details"})
+			return
+		}
+
 		// Шаг 7: Вставка в БД
 		query := `INSERT INTO subject_olympiads 
-			(subject_name, date, end_date, description, school_id, level, grade, limit_participants, creator_id) 
-			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`
+			(subject_name, date, end_date, description, school_id, level, grade, limit_participants, creator_id,
+			registration_opens_at, registration_closes_at, late_registration_until, cancellation_deadline,
+			status, submitted_at) 
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
 
 		result, err := db.Exec(query,
 			subjectName,
@@ -373,7 +389,13 @@ func (c *SubjectOlympiadController) CreateSubjectOlympiad(db *sql.DB) http.Handl
 			level,
 			grade,
 			limit,
-			userID)
+			userID,
+			windowFields["registration_opens_at"],
+			windowFields["registration_closes_at"],
+			windowFields["late_registration_until"],
+			windowFields["cancellation_deadline"],
+			status,
+			submittedAtFor(status))
 		if err != nil {
 			log.Println("Error inserting olympiad:", err)
 			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Failed to create olympiad"})
@@ -386,12 +408,18 @@ func (c *SubjectOlympiadController) CreateSubjectOlympiad(db *sql.DB) http.Handl
 			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Failed to retrieve olympiad ID"})
 			return
 		}
+		if err := recordPublicationCreated(db, "olympiad", olympiadID, status, userID); err != nil {
+			log.Println("Error writing publication log:", err)
+		}
 
 		// Шаг 8: Получение полной информации
 		var olympiad models.SubjectOlympiad
 		query = `SELECT so.subject_olympiad_id, so.subject_name, so.date, so.end_date, so.description, 
 			so.school_id, so.level, so.grade, so.limit_participants, 
-			u.id as creator_id, u.first_name, u.last_name, s.school_name as school_name
+			u.id as creator_id, u.first_name, u.last_name, s.school_name as school_name,
+			COALESCE(CAST(so.registration_opens_at AS CHAR), ''), COALESCE(CAST(so.registration_closes_at AS CHAR), ''),
+			COALESCE(CAST(so.late_registration_until AS CHAR), ''), COALESCE(CAST(so.cancellation_deadline AS CHAR), ''),
+			so.status
 			FROM subject_olympiads so
 			LEFT JOIN users u ON so.creator_id = u.id
 			LEFT JOIN Schools s ON so.school_id = s.school_id
@@ -411,6 +439,11 @@ func (c *SubjectOlympiadController) CreateSubjectOlympiad(db *sql.DB) http.Handl
 			&olympiad.CreatorFirstName,
 			&olympiad.CreatorLastName,
 			&olympiad.SchoolName,
+			&olympiad.RegistrationOpensAt,
+			&olympiad.RegistrationClosesAt,
+			&olympiad.LateRegistrationUntil,
+			&olympiad.CancellationDeadline,
+			&olympiad.Status,
 		)
 		if err != nil {
 			log.Println("Error fetching created olympiad:", err)
@@ -425,6 +458,7 @@ func (c *SubjectOlympiadController) CreateSubjectOlympiad(db *sql.DB) http.Handl
 				Grade:       grade,
 				Limit:       limit,
 				CreatorID:   userID,
+				Status:      status,
 			})
 			return
 		}
@@ -474,7 +508,12 @@ func (c *SubjectOlympiadController) GetSubjectOlympiad(db *sql.DB) http.HandlerF
 				SELECT COUNT(reg.olympiads_registrations_id) 
 				FROM olympiad_registrations reg
 				WHERE reg.subject_olympiad_id = so.subject_olympiad_id
-			) AS participants
+			) AS participants,
+			COALESCE(CAST(so.registration_opens_at AS CHAR), ''),
+			COALESCE(CAST(so.registration_closes_at AS CHAR), ''),
+			COALESCE(CAST(so.late_registration_until AS CHAR), ''),
+			COALESCE(CAST(so.cancellation_deadline AS CHAR), ''),
+			so.status
 		FROM subject_olympiads so
 		LEFT JOIN users u ON so.creator_id = u.id
 		LEFT JOIN Schools s ON so.school_id = s.school_id
@@ -497,6 +536,11 @@ func (c *SubjectOlympiadController) GetSubjectOlympiad(db *sql.DB) http.HandlerF
 			&schoolName,
 			&olympiad.Expired,
 			&olympiad.CurrentParticipants,
+			&olympiad.RegistrationOpensAt,
+			&olympiad.RegistrationClosesAt,
+			&olympiad.LateRegistrationUntil,
+			&olympiad.CancellationDeadline,
+			&olympiad.Status,
 		)
 
 		if err == sql.ErrNoRows {
@@ -508,6 +552,17 @@ func (c *SubjectOlympiadController) GetSubjectOlympiad(db *sql.DB) http.HandlerF
 			return
 		}
 
+		// Неопубликованную олимпиаду видят только организаторы школы
+		visible, err := canSeePublication(db, userID, olympiad.SchoolID, olympiad.Status)
+		if err != nil {
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Ошибка запроса к базе данных"})
+			return
+		}
+		if !visible {
+			utils.RespondWithError(w, http.StatusNotFound, models.Error{Message: "Олимпиада не найдена"})
+			return
+		}
+
 		olympiad.CreatorFirstName = utils.NullStringToString(firstName)
 		olympiad.CreatorLastName = utils.NullStringToString(lastName)
 		olympiad.SchoolName = utils.NullStringToString(schoolName)
@@ -954,9 +1009,33 @@ func (c *SubjectOlympiadController) EditOlympiadsCreated(db *sql.DB) http.Handle
 			schoolID = newSchoolID
 		}
 
+		currentWindow, err := loadOlympiadWindow(db, olympiadID)
+		if err != nil {
+			log.Printf("Error fetching registration window for olympiadID %d: %v", olympiadID, err)
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Failed to fetch current olympiad data"})
+			return
+		}
+		windowFields, err := parseRegistrationWindowForm(r, currentWindow)
+		if err != nil {
+			utils.RespondWithError(w, http.StatusBadRequest, models.Error{Message: err.Error()})
+			return
+		}
+
 		log.Printf("Processed form data - olympiadID: %d, subject_name: %s, start_date: %s, end_date: %s, school_id: %d, level: %s, grade: %d, limit: %d",
 			olympiadID, subjectName, startDate, endDate, schoolID, level, grade, limit)
 
+		// Изменение проверенных полей опубликованной олимпиады возвращает
+		// её на модерацию
+		changedReviewed, err := changedReviewedColumns(db, publicationKinds["olympiad"], olympiadID, map[string]interface{}{
+			"subject_name": subjectName, "date": startDate, "end_date": endDate, "description": description,
+			"school_id": schoolID, "level": level, "grade": grade,
+		})
+		if err != nil {
+			log.Printf("Error comparing olympiad fields for olympiadID %d: %v", olympiadID, err)
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Failed to update olympiad"})
+			return
+		}
+
 		_, err = db.Exec(`
 			UPDATE subject_olympiads
 			SET subject_name = ?, date = ?, end_date = ?, description = ?, 
@@ -970,6 +1049,21 @@ func (c *SubjectOlympiadController) EditOlympiadsCreated(db *sql.DB) http.Handle
 			return
 		}
 
+		// Поля окна регистрации обновляются, только если переданы в форме
+		for field, value := range windowFields {
+			if _, err := db.Exec("UPDATE subject_olympiads SET "+field+" = ? WHERE subject_olympiad_id = ?", value, olympiadID); err != nil {
+				log.Printf("Error updating %s for olympiadID %d: %v", field, olympiadID, err)
+				utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Failed to update olympiad"})
+				return
+			}
+		}
+
+		if _, err := returnToReview(db, publicationKinds["olympiad"], olympiadID, userID, changedReviewed); err != nil {
+			log.Printf("Error returning olympiad %d to review: %v", olympiadID, err)
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Olympiad updated but failed to return it to review"})
+			return
+		}
+
 		var updatedOlympiad models.SubjectOlympiad
 		err = db.QueryRow(`
 			SELECT 
@@ -985,7 +1079,12 @@ func (c *SubjectOlympiadController) EditOlympiadsCreated(db *sql.DB) http.Handle
 				COALESCE(so.creator_id, 0) as creator_id,
 				COALESCE(u.first_name, '') as creator_first_name,
 				COALESCE(u.last_name, '') as creator_last_name,
-				COALESCE(s.school_name, '') as school_name
+				COALESCE(s.school_name, '') as school_name,
+				COALESCE(CAST(so.registration_opens_at AS CHAR), ''),
+				COALESCE(CAST(so.registration_closes_at AS CHAR), ''),
+				COALESCE(CAST(so.late_registration_until AS CHAR), ''),
+				COALESCE(CAST(so.cancellation_deadline AS CHAR), ''),
+				so.status
 			FROM 
 				subject_olympiads so
 			LEFT JOIN 
@@ -1008,6 +1107,11 @@ func (c *SubjectOlympiadController) EditOlympiadsCreated(db *sql.DB) http.Handle
 			&updatedOlympiad.CreatorFirstName,
 			&updatedOlympiad.CreatorLastName,
 			&updatedOlympiad.SchoolName,
+			&updatedOlympiad.RegistrationOpensAt,
+			&updatedOlympiad.RegistrationClosesAt,
+			&updatedOlympiad.LateRegistrationUntil,
+			&updatedOlympiad.CancellationDeadline,
+			&updatedOlympiad.Status,
 		)
 
 		if err != nil {
@@ -1069,6 +1173,10 @@ func (c *SubjectOlympiadController) DeleteSubjectOlympiad(db *sql.DB) http.Handl
 			return
 		}
 
+		if _, err := db.Exec("DELETE FROM eligibility_rules WHERE target_type = 'olympiad' AND target_id = ?", olympiadID); err != nil {
+			log.Println("Error deleting olympiad eligibility rules:", err)
+		}
+
 		// Шаг 7: Ответ на успешное удаление
 		utils.ResponseJSON(w, map[string]string{"message": "Olympiad successfully deleted"})
 	}
@@ -1556,7 +1664,8 @@ func (c *SubjectOlympiadController) GetAllSubjectOlympiads(db *sql.DB) http.Hand
 				COALESCE(u.first_name, '') AS creator_first_name,
 				COALESCE(u.last_name, '') AS creator_last_name,
 				COALESCE(s.school_name, '') AS school_name,
-				so.photo_url
+				so.photo_url,
+				so.status
 			FROM subject_olympiads so
 			LEFT JOIN users u ON so.creator_id = u.id
 			LEFT JOIN Schools s ON so.school_id = s.school_id
@@ -1565,6 +1674,9 @@ func (c *SubjectOlympiadController) GetAllSubjectOlympiads(db *sql.DB) http.Hand
 		var rows *sql.Rows
 		if userRole == "superadmin" {
 			rows, err = db.Query(query)
+		} else if userRole == "schooladmin" {
+			query += " WHERE so.school_id IN (SELECT school_id FROM school_members WHERE user_id = ?)"
+			rows, err = db.Query(query, userID)
 		} else {
 			var schoolID int
 			err = db.QueryRow("SELECT school_id FROM users WHERE id = ?", userID).Scan(&schoolID)
@@ -1589,6 +1701,7 @@ func (c *SubjectOlympiadController) GetAllSubjectOlympiads(db *sql.DB) http.Hand
 			var (
 				id, creatorID, currentParticipants                                    int
 				subjectName, startDate, creatorFirstName, creatorLastName, schoolName string
+				status                                                                string
 				endDate, description, level, grade, photo                             sql.NullString
 				schoolID                                                              sql.NullInt64
 				limitParticipants                                                     sql.NullInt64
@@ -1610,6 +1723,7 @@ func (c *SubjectOlympiadController) GetAllSubjectOlympiads(db *sql.DB) http.Hand
 				&creatorLastName,
 				&schoolName,
 				&photo,
+				&status,
 			)
 			if err != nil {
 				log.Println("Error scanning olympiad row:", err)
@@ -1643,6 +1757,7 @@ func (c *SubjectOlympiadController) GetAllSubjectOlympiads(db *sql.DB) http.Hand
 				"creator_last_name":    creatorLastName,
 				"school_name":          schoolName,
 				"expired":              isExpired,
+				"status":               status,
 			}
 
 			olympiads = append(olympiads, olympiad)
@@ -1687,7 +1802,7 @@ func (c *SubjectOlympiadController) GetOlympiadsBySubjectID(db *sql.DB) http.Han
 		query := `
             SELECT subject_olympiad_id, subject_name, date, end_date, description
             FROM subject_olympiads
-            WHERE subject_olympiad_id = ?
+            WHERE subject_olympiad_id = ? AND status = 'published'
         `
 		rows, err := db.Query(query, subjectOlympiadID)
 		if err != nil {
@@ -1731,11 +1846,12 @@ func (c *SubjectOlympiadController) GetSubjectOlympiadsByNamePhoto(db *sql.DB) h
 		query := `
 			SELECT subject_olympiad_id, subject_name, photo_url
 			FROM subject_olympiads
+			WHERE status = 'published'
 		`
 
 		var rows *sql.Rows
 		if subject != "" {
-			query += " WHERE subject_name = ?"
+			query += " AND subject_name = ?"
 			rows, err = db.Query(query, subject)
 		} else {
 			rows, err = db.Query(query)
@@ -1829,7 +1945,7 @@ func (c *SubjectOlympiadController) GetOlympiadsBySubjectName(db *sql.DB) http.H
             LEFT JOIN 
                 olympiad_registrations reg ON so.subject_olympiad_id = reg.subject_olympiad_id 
             WHERE 
-                so.subject_name = ?
+                so.subject_name = ? AND so.status = 'published'
             GROUP BY 
                 so.subject_olympiad_id, so.date, so.end_date, so.description, so.level, so.limit_participants, so.grade, s.school_id, s.school_name
             ORDER BY 
@@ -1937,7 +2053,7 @@ func (c *SubjectOlympiadController) GetOlympiadsBySubjectName(db *sql.DB) http.H
 
 func (c *SubjectOlympiadController) GetAllSubjectOlympiadsSchool(db *sql.DB) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		_, err := utils.VerifyToken(r)
+		userID, err := utils.VerifyToken(r)
 		if err != nil {
 			utils.RespondWithError(w, http.StatusUnauthorized, models.Error{Message: "Invalid token"})
 			return
@@ -1961,7 +2077,8 @@ func (c *SubjectOlympiadController) GetAllSubjectOlympiadsSchool(db *sql.DB) htt
 				COALESCE(so.creator_id, 0) as creator_id,
 				COALESCE(u.first_name, '') as creator_first_name,
 				COALESCE(u.last_name, '') as creator_last_name,
-				COALESCE(s.school_name, '') as school_name
+				COALESCE(s.school_name, '') as school_name,
+				so.status
 			FROM 
 				subject_olympiads so
 			LEFT JOIN 
@@ -1982,6 +2099,17 @@ func (c *SubjectOlympiadController) GetAllSubjectOlympiadsSchool(db *sql.DB) htt
 			conditions = append(conditions, "so.school_id = ?")
 			params = append(params, schoolID)
 		}
+		// Черновики и олимпиады на модерации видят только организаторы школы
+		schoolIDInt, _ := strconv.Atoi(schoolID)
+		canManage, err := canManageEvents(db, userID, schoolIDInt)
+		if err != nil {
+			log.Println("Permission check error:", err)
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Query failed"})
+			return
+		}
+		if !canManage {
+			conditions = append(conditions, "so.status = 'published'")
+		}
 		if len(conditions) > 0 {
 			query += " WHERE " + strings.Join(conditions, " AND ")
 			rows, err = db.Query(query, params...)
@@ -2013,6 +2141,7 @@ func (c *SubjectOlympiadController) GetAllSubjectOlympiadsSchool(db *sql.DB) htt
 				&o.CreatorFirstName,
 				&o.CreatorLastName,
 				&o.SchoolName,
+				&o.Status,
 			); err != nil {
 				log.Println("Scan error:", err)
 				continue
@@ -2143,8 +2272,8 @@ func (c *SubjectOlympiadController) GetOlympiadParticipants(db *sql.DB) http.Han
 				so.subject_name,
 				(
 					SELECT COUNT(*) 
-					FROM olympiad_registrations r 
-					WHERE r.subject_olympiad_id = so.subject_olympiad_id AND r.status = 'accepted'
+					FROM Olympiads res 
+					WHERE res.subject_olympiad_id = so.subject_olympiad_id
 				) AS participants
 			FROM subject_olympiads so
 			LEFT JOIN Schools s ON s.school_id = so.school_id
@@ -2207,7 +2336,7 @@ func (c *SubjectOlympiadController) GetSchoolOlympiadStats(db *sql.DB) http.Hand
 		query := `
 			SELECT COUNT(*) as olympiad_count
 			FROM subject_olympiads
-			WHERE school_id = ?
+			WHERE school_id = ? AND status = 'published'
 		`
 
 		var olympiadCount int
@@ -2265,7 +2394,7 @@ func (c *SubjectOlympiadController) CountOlympiadsBySchool(db *sql.DB) http.Hand
 		}
 
 		// Step 5: Query to count olympiads
-		query := "SELECT COUNT(*) FROM subject_olympiads WHERE school_id = ?"
+		query := "SELECT COUNT(*) FROM subject_olympiads WHERE school_id = ? AND status = 'published'"
 		var count int
 		err = db.QueryRow(query, schoolID).Scan(&count)
 		if err != nil {
@@ -0,0 +1,1200 @@
+package controllers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"ranking-school/models"
+	"ranking-school/utils"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// Опросы после мероприятий:
+//   - организатор создаёт шаблон (survey_templates + survey_questions) и
+//     прикрепляет его к мероприятию (event_surveys);
+//   - после end_date диспетчер рассылает опрос принятым участникам
+//     (survey_invitations + уведомление + письмо);
+//   - ученик отвечает один раз, итоги доступны по мероприятию и по школе.
+type SurveyController struct{}
+
+const (
+	maxSurveyQuestions = 50
+	defaultMaxRating   = 5
+)
+
+// Кому рассылается опрос: принятые заявки и все, кто отмечен как пришедший.
+const surveyRecipientsCondition = "(status IN ('accepted', 'completed') OR attendance = 'attended')"
+
+// canManageEvents — суперадмин или сотрудник школы с правом manage_events.
+func canManageEvents(db rowQuerier, userID, schoolID int) (bool, error) {
+	superadmin, err := isSuperadmin(db, userID)
+	if err != nil || superadmin {
+		return superadmin, err
+	}
+	if schoolID <= 0 {
+		return false, nil
+	}
+	return hasSchoolPermission(db, userID, schoolID, PermissionManageEvents)
+}
+
+func validateSurveyTemplate(t *models.SurveyTemplate) error {
+	t.Title = strings.TrimSpace(t.Title)
+	if t.Title == "" || len(t.Title) > 255 {
+		return fmt.Errorf("title is required and must be at most 255 characters")
+	}
+	if len(t.Questions) == 0 || len(t.Questions) > maxSurveyQuestions {
+		return fmt.Errorf("survey must have between 1 and %d questions", maxSurveyQuestions)
+	}
+
+	for i := range t.Questions {
+		q := &t.Questions[i]
+		q.Position = i + 1
+		q.Text = strings.TrimSpace(q.Text)
+		if q.Text == "" || len(q.Text) > 500 {
+			return fmt.Errorf("question %d: text is required and must be at most 500 characters", q.Position)
+		}
+		switch q.Kind {
+		case "rating":
+			if q.MaxRating == 0 {
+				q.MaxRating = defaultMaxRating
+			}
+			if q.MaxRating < 2 || q.MaxRating > 10 {
+				return fmt.Errorf("question %d: max_rating must be between 2 and 10", q.Position)
+			}
+			q.Options = nil
+			q.AllowMultiple = false
+		case "choice":
+			seen := make(map[string]bool)
+			options := make([]string, 0, len(q.Options))
+			for _, option := range q.Options {
+				option = strings.TrimSpace(option)
+				if option == "" || len(option) > 255 {
+					return fmt.Errorf("question %d: options must be non-empty and at most 255 characters", q.Position)
+				}
+				if seen[option] {
+					return fmt.Errorf("question %d: duplicate option %q", q.Position, option)
+				}
+				seen[option] = true
+				options = append(options, option)
+			}
+			if len(options) < 2 {
+				return fmt.Errorf("question %d: choice question needs at least 2 options", q.Position)
+			}
+			q.Options = options
+			q.MaxRating = 0
+		case "text":
+			q.Options = nil
+			q.AllowMultiple = false
+			q.MaxRating = 0
+		default:
+			return fmt.Errorf("question %d: kind must be rating, choice or text", q.Position)
+		}
+	}
+	return nil
+}
+
+func loadSurveyTemplate(db *sql.DB, templateID int) (models.SurveyTemplate, error) {
+	var t models.SurveyTemplate
+	var schoolID sql.NullInt64
+	err := db.QueryRow(`
+		SELECT id, school_id, title, created_by, CAST(created_at AS CHAR)
+		FROM survey_templates WHERE id = ?`, templateID).
+		Scan(&t.ID, &schoolID, &t.Title, &t.CreatedBy, &t.CreatedAt)
+	if err != nil {
+		return t, err
+	}
+	if schoolID.Valid {
+		id := int(schoolID.Int64)
+		t.SchoolID = &id
+	}
+	t.Questions, err = loadSurveyQuestions(db, templateID)
+	return t, err
+}
+
+func loadSurveyQuestions(db *sql.DB, templateID int) ([]models.SurveyQuestion, error) {
+	rows, err := db.Query(`
+		SELECT id, position, kind, text, options, allow_multiple, max_rating, required
+		FROM survey_questions WHERE template_id = ?
+		ORDER BY position, id`, templateID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	questions := []models.SurveyQuestion{}
+	for rows.Next() {
+		var q models.SurveyQuestion
+		var options sql.NullString
+		if err := rows.Scan(&q.ID, &q.Position, &q.Kind, &q.Text, &options, &q.AllowMultiple, &q.MaxRating, &q.Required); err != nil {
+			return nil, err
+		}
+		if options.Valid && options.String != "" {
+			if err := json.Unmarshal([]byte(options.String), &q.Options); err != nil {
+				return nil, err
+			}
+		}
+		if q.Kind != "rating" {
+			q.MaxRating = 0
+		}
+		questions = append(questions, q)
+	}
+	return questions, rows.Err()
+}
+
+func insertSurveyQuestions(tx *sql.Tx, templateID int, questions []models.SurveyQuestion) error {
+	for _, q := range questions {
+		var options interface{}
+		if q.Kind == "choice" {
+			data, err := json.Marshal(q.Options)
+			if err != nil {
+				return err
+			}
+			options = string(data)
+		}
+		maxRating := q.MaxRating
+		if maxRating == 0 {
+			maxRating = defaultMaxRating
+		}
+		_, err := tx.Exec(`
+			INSERT INTO survey_questions (template_id, position, kind, text, options, allow_multiple, max_rating, required)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+			templateID, q.Position, q.Kind, q.Text, options, q.AllowMultiple, maxRating, q.Required)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// requireTemplateManager проверяет право менять шаблон: общий шаблон —
+// только суперадмин, шаблон школы — её организаторы.
+func requireTemplateManager(w http.ResponseWriter, db *sql.DB, userID int, schoolID *int) bool {
+	id := 0
+	if schoolID != nil {
+		id = *schoolID
+	}
+	allowed, err := canManageEvents(db, userID, id)
+	if err != nil {
+		log.Printf("Error checking survey permissions for user %d: %v", userID, err)
+		utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Error checking permissions"})
+		return false
+	}
+	if !allowed {
+		utils.RespondWithError(w, http.StatusForbidden, models.Error{Message: "You do not have permission to manage surveys of this school"})
+		return false
+	}
+	return true
+}
+
+func surveyTemplateIDFromPath(w http.ResponseWriter, r *http.Request) (int, bool) {
+	templateID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil || templateID <= 0 {
+		utils.RespondWithError(w, http.StatusBadRequest, models.Error{Message: "Invalid template ID"})
+		return 0, false
+	}
+	return templateID, true
+}
+
+// CreateSurveyTemplate создаёт шаблон опроса. Без school_id — общий шаблон.
+func (sc *SurveyController) CreateSurveyTemplate(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := utils.VerifyToken(r)
+		if err != nil {
+			utils.RespondWithError(w, http.StatusUnauthorized, models.Error{Message: "Unauthorized"})
+			return
+		}
+
+		var template models.SurveyTemplate
+		if err := json.NewDecoder(r.Body).Decode(&template); err != nil {
+			utils.RespondWithError(w, http.StatusBadRequest, models.Error{Message: "Invalid request body"})
+			return
+		}
+		if err := validateSurveyTemplate(&template); err != nil {
+			utils.RespondWithError(w, http.StatusBadRequest, models.Error{Message: err.Error()})
+			return
+		}
+		if !requireTemplateManager(w, db, userID, template.SchoolID) {
+			return
+		}
+
+		tx, err := db.Begin()
+		if err != nil {
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Database error"})
+			return
+		}
+		defer tx.Rollback()
+
+		res, err := tx.Exec("INSERT INTO survey_templates (school_id, title, created_by) VALUES (?, ?, ?)", template.SchoolID, template.Title, userID)
+		if err != nil {
+			log.Printf("Error creating survey template: %v", err)
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Failed to create survey template"})
+			return
+		}
+		templateID, _ := res.LastInsertId()
+		if err := insertSurveyQuestions(tx, int(templateID), template.Questions); err != nil {
+			log.Printf("Error creating survey questions: %v", err)
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Failed to create survey template"})
+			return
+		}
+		if err := tx.Commit(); err != nil {
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Failed to create survey template"})
+			return
+		}
+
+		created, err := loadSurveyTemplate(db, int(templateID))
+		if err != nil {
+			log.Printf("Error loading survey template %d: %v", templateID, err)
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Survey template created, but failed to load it"})
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		utils.ResponseJSON(w, created)
+	}
+}
+
+// GetSurveyTemplates — шаблоны школы (?school_id=) вместе с общими.
+func (sc *SurveyController) GetSurveyTemplates(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := utils.VerifyToken(r)
+		if err != nil {
+			utils.RespondWithError(w, http.StatusUnauthorized, models.Error{Message: "Unauthorized"})
+			return
+		}
+
+		query := "SELECT id FROM survey_templates WHERE school_id IS NULL"
+		var args []interface{}
+		if schoolIDStr := r.URL.Query().Get("school_id"); schoolIDStr != "" {
+			schoolID, err := strconv.Atoi(schoolIDStr)
+			if err != nil || schoolID <= 0 {
+				utils.RespondWithError(w, http.StatusBadRequest, models.Error{Message: "Invalid school_id"})
+				return
+			}
+			if !requireTemplateManager(w, db, userID, &schoolID) {
+				return
+			}
+			query += " OR school_id = ?"
+			args = append(args, schoolID)
+		}
+		query += " ORDER BY school_id IS NULL, title"
+
+		rows, err := db.Query(query, args...)
+		if err != nil {
+			log.Printf("Error fetching survey templates: %v", err)
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Failed to fetch survey templates"})
+			return
+		}
+		var ids []int
+		for rows.Next() {
+			var id int
+			if err := rows.Scan(&id); err != nil {
+				rows.Close()
+				utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Failed to fetch survey templates"})
+				return
+			}
+			ids = append(ids, id)
+		}
+		rows.Close()
+
+		templates := []models.SurveyTemplate{}
+		for _, id := range ids {
+			t, err := loadSurveyTemplate(db, id)
+			if err != nil {
+				log.Printf("Error loading survey template %d: %v", id, err)
+				utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Failed to fetch survey templates"})
+				return
+			}
+			templates = append(templates, t)
+		}
+
+		utils.ResponseJSON(w, templates)
+	}
+}
+
+// GetSurveyTemplate — шаблон с вопросами.
+func (sc *SurveyController) GetSurveyTemplate(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if _, err := utils.VerifyToken(r); err != nil {
+			utils.RespondWithError(w, http.StatusUnauthorized, models.Error{Message: "Unauthorized"})
+			return
+		}
+		templateID, ok := surveyTemplateIDFromPath(w, r)
+		if !ok {
+			return
+		}
+
+		template, err := loadSurveyTemplate(db, templateID)
+		if err == sql.ErrNoRows {
+			utils.RespondWithError(w, http.StatusNotFound, models.Error{Message: "Survey template not found"})
+			return
+		} else if err != nil {
+			log.Printf("Error loading survey template %d: %v", templateID, err)
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Failed to fetch survey template"})
+			return
+		}
+
+		utils.ResponseJSON(w, template)
+	}
+}
+
+// UpdateSurveyTemplate заменяет название и вопросы шаблона. Шаблон, на
+// который уже есть ответы, менять нельзя — ответы ссылаются на вопросы.
+func (sc *SurveyController) UpdateSurveyTemplate(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := utils.VerifyToken(r)
+		if err != nil {
+			utils.RespondWithError(w, http.StatusUnauthorized, models.Error{Message: "Unauthorized"})
+			return
+		}
+		templateID, ok := surveyTemplateIDFromPath(w, r)
+		if !ok {
+			return
+		}
+
+		current, err := loadSurveyTemplate(db, templateID)
+		if err == sql.ErrNoRows {
+			utils.RespondWithError(w, http.StatusNotFound, models.Error{Message: "Survey template not found"})
+			return
+		} else if err != nil {
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Failed to fetch survey template"})
+			return
+		}
+		if !requireTemplateManager(w, db, userID, current.SchoolID) {
+			return
+		}
+
+		var template models.SurveyTemplate
+		if err := json.NewDecoder(r.Body).Decode(&template); err != nil {
+			utils.RespondWithError(w, http.StatusBadRequest, models.Error{Message: "Invalid request body"})
+			return
+		}
+		if err := validateSurveyTemplate(&template); err != nil {
+			utils.RespondWithError(w, http.StatusBadRequest, models.Error{Message: err.Error()})
+			return
+		}
+
+		tx, err := db.Begin()
+		if err != nil {
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Database error"})
+			return
+		}
+		defer tx.Rollback()
+
+		var responses int
+		if err := tx.QueryRow("SELECT COUNT(*) FROM survey_responses WHERE template_id = ? FOR UPDATE", templateID).Scan(&responses); err != nil {
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Database error"})
+			return
+		}
+		if responses > 0 {
+			utils.RespondWithError(w, http.StatusConflict, models.Error{Message: "Survey template already has responses, create a new template instead"})
+			return
+		}
+
+		if _, err := tx.Exec("UPDATE survey_templates SET title = ? WHERE id = ?", template.Title, templateID); err != nil {
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Failed to update survey template"})
+			return
+		}
+		if _, err := tx.Exec("DELETE FROM survey_questions WHERE template_id = ?", templateID); err != nil {
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Failed to update survey template"})
+			return
+		}
+		if err := insertSurveyQuestions(tx, templateID, template.Questions); err != nil {
+			log.Printf("Error updating survey questions: %v", err)
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Failed to update survey template"})
+			return
+		}
+		if err := tx.Commit(); err != nil {
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Failed to update survey template"})
+			return
+		}
+
+		updated, err := loadSurveyTemplate(db, templateID)
+		if err != nil {
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Survey template updated, but failed to load it"})
+			return
+		}
+		utils.ResponseJSON(w, updated)
+	}
+}
+
+// DeleteSurveyTemplate удаляет шаблон, если он не прикреплён к мероприятиям.
+func (sc *SurveyController) DeleteSurveyTemplate(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := utils.VerifyToken(r)
+		if err != nil {
+			utils.RespondWithError(w, http.StatusUnauthorized, models.Error{Message: "Unauthorized"})
+			return
+		}
+		templateID, ok := surveyTemplateIDFromPath(w, r)
+		if !ok {
+			return
+		}
+
+		current, err := loadSurveyTemplate(db, templateID)
+		if err == sql.ErrNoRows {
+			utils.RespondWithError(w, http.StatusNotFound, models.Error{Message: "Survey template not found"})
+			return
+		} else if err != nil {
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Failed to fetch survey template"})
+			return
+		}
+		if !requireTemplateManager(w, db, userID, current.SchoolID) {
+			return
+		}
+
+		var attached int
+		if err := db.QueryRow("SELECT COUNT(*) FROM event_surveys WHERE template_id = ?", templateID).Scan(&attached); err != nil {
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Database error"})
+			return
+		}
+		if attached > 0 {
+			utils.RespondWithError(w, http.StatusConflict, models.Error{Message: "Survey template is attached to events"})
+			return
+		}
+
+		if _, err := db.Exec("DELETE FROM survey_templates WHERE id = ?", templateID); err != nil {
+			log.Printf("Error deleting survey template %d: %v", templateID, err)
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Failed to delete survey template"})
+			return
+		}
+		utils.ResponseJSON(w, map[string]string{"message": "Survey template deleted"})
+	}
+}
+
+// AttachEventSurvey прикрепляет шаблон к мероприятию (или заменяет его,
+// пока опрос не разослан).
+func (sc *SurveyController) AttachEventSurvey(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := utils.VerifyToken(r)
+		if err != nil {
+			utils.RespondWithError(w, http.StatusUnauthorized, models.Error{Message: "Unauthorized"})
+			return
+		}
+		eventID, ok := eventIDFromPath(w, r)
+		if !ok {
+			return
+		}
+		event, ok := requireEventOrganizer(w, db, eventID, userID)
+		if !ok {
+			return
+		}
+
+		var req struct {
+			TemplateID int `json:"template_id"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.TemplateID <= 0 {
+			utils.RespondWithError(w, http.StatusBadRequest, models.Error{Message: "template_id is required"})
+			return
+		}
+
+		var templateSchool sql.NullInt64
+		err = db.QueryRow("SELECT school_id FROM survey_templates WHERE id = ?", req.TemplateID).Scan(&templateSchool)
+		if err == sql.ErrNoRows {
+			utils.RespondWithError(w, http.StatusNotFound, models.Error{Message: "Survey template not found"})
+			return
+		} else if err != nil {
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Database error"})
+			return
+		}
+		if templateSchool.Valid && int(templateSchool.Int64) != event.SchoolID {
+			utils.RespondWithError(w, http.StatusForbidden, models.Error{Message: "Survey template belongs to another school"})
+			return
+		}
+
+		var sentAt sql.NullString
+		err = db.QueryRow("SELECT CAST(sent_at AS CHAR) FROM event_surveys WHERE event_id = ?", eventID).Scan(&sentAt)
+		if err != nil && err != sql.ErrNoRows {
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Database error"})
+			return
+		}
+		if sentAt.Valid {
+			utils.RespondWithError(w, http.StatusConflict, models.Error{Message: "Survey has already been sent for this event"})
+			return
+		}
+
+		_, err = db.Exec(`
+			INSERT INTO event_surveys (event_id, template_id, attached_by) VALUES (?, ?, ?)
+			ON DUPLICATE KEY UPDATE template_id = VALUES(template_id), attached_by = VALUES(attached_by)`,
+			eventID, req.TemplateID, userID)
+		if err != nil {
+			log.Printf("Error attaching survey to event %d: %v", eventID, err)
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Failed to attach survey"})
+			return
+		}
+
+		utils.ResponseJSON(w, map[string]interface{}{
+			"event_id":    eventID,
+			"template_id": req.TemplateID,
+			"message":     "Survey will be sent to accepted participants after the event ends",
+		})
+	}
+}
+
+// DetachEventSurvey открепляет ещё не разосланный опрос.
+func (sc *SurveyController) DetachEventSurvey(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := utils.VerifyToken(r)
+		if err != nil {
+			utils.RespondWithError(w, http.StatusUnauthorized, models.Error{Message: "Unauthorized"})
+			return
+		}
+		eventID, ok := eventIDFromPath(w, r)
+		if !ok {
+			return
+		}
+		if _, ok := requireEventOrganizer(w, db, eventID, userID); !ok {
+			return
+		}
+
+		res, err := db.Exec("DELETE FROM event_surveys WHERE event_id = ? AND sent_at IS NULL", eventID)
+		if err != nil {
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Failed to detach survey"})
+			return
+		}
+		if affected, _ := res.RowsAffected(); affected == 0 {
+			var exists bool
+			db.QueryRow("SELECT EXISTS(SELECT 1 FROM event_surveys WHERE event_id = ?)", eventID).Scan(&exists)
+			if exists {
+				utils.RespondWithError(w, http.StatusConflict, models.Error{Message: "Survey has already been sent for this event"})
+			} else {
+				utils.RespondWithError(w, http.StatusNotFound, models.Error{Message: "Event has no survey"})
+			}
+			return
+		}
+		utils.ResponseJSON(w, map[string]string{"message": "Survey detached"})
+	}
+}
+
+func loadEventSurvey(db *sql.DB, eventID int) (models.EventSurvey, error) {
+	var s models.EventSurvey
+	var templateID int
+	var sentAt sql.NullString
+	err := db.QueryRow(`
+		SELECT es.event_id, es.template_id, CAST(es.sent_at AS CHAR),
+		       COALESCE(e.event_name, ''), COALESCE(CAST(e.end_date AS CHAR), '')
+		FROM event_surveys es
+		JOIN Events e ON e.id = es.event_id
+		WHERE es.event_id = ?`, eventID).Scan(&s.EventID, &templateID, &sentAt, &s.EventName, &s.EventEnd)
+	if err != nil {
+		return s, err
+	}
+	s.SentAt = sentAt.String
+	s.Template, err = loadSurveyTemplate(db, templateID)
+	return s, err
+}
+
+// GetEventSurvey — опрос мероприятия. Доступен организаторам и ученикам,
+// которым он был разослан.
+func (sc *SurveyController) GetEventSurvey(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := utils.VerifyToken(r)
+		if err != nil {
+			utils.RespondWithError(w, http.StatusUnauthorized, models.Error{Message: "Unauthorized"})
+			return
+		}
+		eventID, ok := eventIDFromPath(w, r)
+		if !ok {
+			return
+		}
+
+		survey, err := loadEventSurvey(db, eventID)
+		if err == sql.ErrNoRows {
+			utils.RespondWithError(w, http.StatusNotFound, models.Error{Message: "Event has no survey"})
+			return
+		} else if err != nil {
+			log.Printf("Error loading survey for event %d: %v", eventID, err)
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Failed to fetch survey"})
+			return
+		}
+
+		var invited bool
+		db.QueryRow("SELECT EXISTS(SELECT 1 FROM survey_invitations WHERE event_id = ? AND student_id = ?)", eventID, userID).Scan(&invited)
+		if invited {
+			db.QueryRow("SELECT EXISTS(SELECT 1 FROM survey_responses WHERE event_id = ? AND student_id = ?)", eventID, userID).Scan(&survey.Responded)
+			utils.ResponseJSON(w, survey)
+			return
+		}
+
+		var schoolID int
+		db.QueryRow("SELECT COALESCE(school_id, 0) FROM Events WHERE id = ?", eventID).Scan(&schoolID)
+		allowed, err := canManageEvents(db, userID, schoolID)
+		if err != nil {
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Error checking permissions"})
+			return
+		}
+		if !allowed {
+			utils.RespondWithError(w, http.StatusForbidden, models.Error{Message: "Survey is available only to invited participants"})
+			return
+		}
+		db.QueryRow("SELECT COUNT(*) FROM survey_invitations WHERE event_id = ?", eventID).Scan(&survey.Invitations)
+		db.QueryRow("SELECT COUNT(*) FROM survey_responses WHERE event_id = ?", eventID).Scan(&survey.Responses)
+		utils.ResponseJSON(w, survey)
+	}
+}
+
+// GetMySurveys — опросы, разосланные ученику, на которые он ещё не ответил.
+func (sc *SurveyController) GetMySurveys(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := utils.VerifyToken(r)
+		if err != nil {
+			utils.RespondWithError(w, http.StatusUnauthorized, models.Error{Message: "Unauthorized"})
+			return
+		}
+
+		rows, err := db.Query(`
+			SELECT si.event_id
+			FROM survey_invitations si
+			LEFT JOIN survey_responses sr ON sr.event_id = si.event_id AND sr.student_id = si.student_id
+			WHERE si.student_id = ? AND sr.id IS NULL
+			ORDER BY si.sent_at DESC`, userID)
+		if err != nil {
+			log.Printf("Error fetching surveys for student %d: %v", userID, err)
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Failed to fetch surveys"})
+			return
+		}
+		var eventIDs []int
+		for rows.Next() {
+			var id int
+			if err := rows.Scan(&id); err == nil {
+				eventIDs = append(eventIDs, id)
+			}
+		}
+		rows.Close()
+
+		surveys := []models.EventSurvey{}
+		for _, eventID := range eventIDs {
+			survey, err := loadEventSurvey(db, eventID)
+			if err != nil {
+				log.Printf("Error loading survey for event %d: %v", eventID, err)
+				continue
+			}
+			surveys = append(surveys, survey)
+		}
+		utils.ResponseJSON(w, surveys)
+	}
+}
+
+// SubmitEventSurvey сохраняет ответы ученика. Ответить можно один раз.
+func (sc *SurveyController) SubmitEventSurvey(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := utils.VerifyToken(r)
+		if err != nil {
+			utils.RespondWithError(w, http.StatusUnauthorized, models.Error{Message: "Unauthorized"})
+			return
+		}
+		eventID, ok := eventIDFromPath(w, r)
+		if !ok {
+			return
+		}
+
+		var req struct {
+			Answers []models.SurveyAnswer `json:"answers"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			utils.RespondWithError(w, http.StatusBadRequest, models.Error{Message: "Invalid request body"})
+			return
+		}
+
+		var invited bool
+		if err := db.QueryRow("SELECT EXISTS(SELECT 1 FROM survey_invitations WHERE event_id = ? AND student_id = ?)", eventID, userID).Scan(&invited); err != nil {
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Database error"})
+			return
+		}
+		if !invited {
+			utils.RespondWithError(w, http.StatusForbidden, models.Error{Message: "You were not invited to this survey"})
+			return
+		}
+
+		survey, err := loadEventSurvey(db, eventID)
+		if err != nil {
+			log.Printf("Error loading survey for event %d: %v", eventID, err)
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Failed to fetch survey"})
+			return
+		}
+		if err := validateSurveyAnswers(survey.Template.Questions, req.Answers); err != nil {
+			utils.RespondWithError(w, http.StatusBadRequest, models.Error{Message: err.Error()})
+			return
+		}
+
+		tx, err := db.Begin()
+		if err != nil {
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Database error"})
+			return
+		}
+		defer tx.Rollback()
+
+		res, err := tx.Exec("INSERT INTO survey_responses (event_id, template_id, student_id) VALUES (?, ?, ?)", eventID, survey.Template.ID, userID)
+		if err != nil {
+			if strings.Contains(err.Error(), "Duplicate entry") {
+				utils.RespondWithError(w, http.StatusConflict, models.Error{Message: "You have already answered this survey"})
+				return
+			}
+			log.Printf("Error saving survey response: %v", err)
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Failed to save survey response"})
+			return
+		}
+		responseID, _ := res.LastInsertId()
+
+		for _, a := range req.Answers {
+			switch {
+			case a.Rating != nil:
+				_, err = tx.Exec("INSERT INTO survey_answers (response_id, question_id, rating) VALUES (?, ?, ?)", responseID, a.QuestionID, *a.Rating)
+			case len(a.Choices) > 0:
+				for _, choice := range a.Choices {
+					if _, err = tx.Exec("INSERT INTO survey_answers (response_id, question_id, choice) VALUES (?, ?, ?)", responseID, a.QuestionID, choice); err != nil {
+						break
+					}
+				}
+			case a.Text != "":
+				_, err = tx.Exec("INSERT INTO survey_answers (response_id, question_id, text) VALUES (?, ?, ?)", responseID, a.QuestionID, a.Text)
+			}
+			if err != nil {
+				log.Printf("Error saving survey answer: %v", err)
+				utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Failed to save survey response"})
+				return
+			}
+		}
+
+		if err := tx.Commit(); err != nil {
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Failed to save survey response"})
+			return
+		}
+		utils.ResponseJSON(w, map[string]interface{}{"message": "Thank you for your feedback", "response_id": responseID})
+	}
+}
+
+// validateSurveyAnswers проверяет ответы по вопросам шаблона и нормализует
+// их (лишние поля ответа очищаются).
+func validateSurveyAnswers(questions []models.SurveyQuestion, answers []models.SurveyAnswer) error {
+	byID := make(map[int]models.SurveyQuestion, len(questions))
+	for _, q := range questions {
+		byID[q.ID] = q
+	}
+
+	answered := make(map[int]bool)
+	for i := range answers {
+		a := &answers[i]
+		q, ok := byID[a.QuestionID]
+		if !ok {
+			return fmt.Errorf("unknown question_id %d", a.QuestionID)
+		}
+		if answered[a.QuestionID] {
+			return fmt.Errorf("question %d is answered twice", q.Position)
+		}
+
+		switch q.Kind {
+		case "rating":
+			if a.Rating == nil {
+				continue
+			}
+			if *a.Rating < 1 || *a.Rating > q.MaxRating {
+				return fmt.Errorf("question %d: rating must be between 1 and %d", q.Position, q.MaxRating)
+			}
+			a.Choices, a.Text = nil, ""
+		case "choice":
+			if len(a.Choices) == 0 {
+				a.Rating, a.Text = nil, ""
+				continue
+			}
+			if len(a.Choices) > 1 && !q.AllowMultiple {
+				return fmt.Errorf("question %d: only one option can be chosen", q.Position)
+			}
+			seen := make(map[string]bool)
+			for _, choice := range a.Choices {
+				if seen[choice] || !containsString(q.Options, choice) {
+					return fmt.Errorf("question %d: invalid option %q", q.Position, choice)
+				}
+				seen[choice] = true
+			}
+			a.Rating, a.Text = nil, ""
+		case "text":
+			a.Text = strings.TrimSpace(a.Text)
+			if len(a.Text) > 5000 {
+				return fmt.Errorf("question %d: answer is too long", q.Position)
+			}
+			if a.Text == "" {
+				continue
+			}
+			a.Rating, a.Choices = nil, nil
+		}
+		answered[a.QuestionID] = true
+	}
+
+	for _, q := range questions {
+		if q.Required && !answered[q.ID] {
+			return fmt.Errorf("question %d is required", q.Position)
+		}
+	}
+	return nil
+}
+
+func containsString(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
+
+// buildEventSurveyResults агрегирует ответы по мероприятию. Средняя оценка
+// мероприятия приводится к пятибалльной шкале, чтобы вопросы с разным
+// max_rating можно было сравнивать.
+func buildEventSurveyResults(db *sql.DB, eventID int) (models.EventSurveyResults, error) {
+	var res models.EventSurveyResults
+	survey, err := loadEventSurvey(db, eventID)
+	if err != nil {
+		return res, err
+	}
+	res.EventID = eventID
+	res.EventName = survey.EventName
+	res.TemplateID = survey.Template.ID
+	res.SentAt = survey.SentAt
+
+	if err := db.QueryRow("SELECT COUNT(*) FROM survey_invitations WHERE event_id = ?", eventID).Scan(&res.Invitations); err != nil {
+		return res, err
+	}
+	if err := db.QueryRow("SELECT COUNT(*) FROM survey_responses WHERE event_id = ?", eventID).Scan(&res.Responses); err != nil {
+		return res, err
+	}
+	if res.Invitations > 0 {
+		res.ResponseRate = math.Round(float64(res.Responses)/float64(res.Invitations)*1000) / 10
+	}
+
+	var normalizedSum float64
+	var normalizedCount int
+	res.Questions = []models.SurveyQuestionResult{}
+	for _, q := range survey.Template.Questions {
+		qr := models.SurveyQuestionResult{QuestionID: q.ID, Kind: q.Kind, Text: q.Text}
+		switch q.Kind {
+		case "rating":
+			qr.Distribution = make(map[string]int)
+			rows, err := db.Query(`
+				SELECT a.rating, COUNT(*)
+				FROM survey_answers a
+				JOIN survey_responses r ON r.id = a.response_id
+				WHERE r.event_id = ? AND a.question_id = ? AND a.rating IS NOT NULL
+				GROUP BY a.rating`, eventID, q.ID)
+			if err != nil {
+				return res, err
+			}
+			var sum int
+			for rows.Next() {
+				var rating, count int
+				if err := rows.Scan(&rating, &count); err != nil {
+					rows.Close()
+					return res, err
+				}
+				qr.Distribution[strconv.Itoa(rating)] = count
+				qr.Answers += count
+				sum += rating * count
+			}
+			rows.Close()
+			if qr.Answers > 0 {
+				avg := math.Round(float64(sum)/float64(qr.Answers)*100) / 100
+				qr.AverageRating = &avg
+				normalizedSum += float64(sum) / float64(q.MaxRating) * 5
+				normalizedCount += qr.Answers
+			}
+		case "choice":
+			qr.Distribution = make(map[string]int)
+			for _, option := range q.Options {
+				qr.Distribution[option] = 0
+			}
+			rows, err := db.Query(`
+				SELECT a.choice, COUNT(*)
+				FROM survey_answers a
+				JOIN survey_responses r ON r.id = a.response_id
+				WHERE r.event_id = ? AND a.question_id = ? AND a.choice IS NOT NULL
+				GROUP BY a.choice`, eventID, q.ID)
+			if err != nil {
+				return res, err
+			}
+			for rows.Next() {
+				var choice string
+				var count int
+				if err := rows.Scan(&choice, &count); err != nil {
+					rows.Close()
+					return res, err
+				}
+				qr.Distribution[choice] = count
+			}
+			rows.Close()
+			if err := db.QueryRow(`
+				SELECT COUNT(DISTINCT a.response_id)
+				FROM survey_answers a
+				JOIN survey_responses r ON r.id = a.response_id
+				WHERE r.event_id = ? AND a.question_id = ?`, eventID, q.ID).Scan(&qr.Answers); err != nil {
+				return res, err
+			}
+		case "text":
+			// Свободные ответы отдаются без указания автора
+			rows, err := db.Query(`
+				SELECT a.text
+				FROM survey_answers a
+				JOIN survey_responses r ON r.id = a.response_id
+				WHERE r.event_id = ? AND a.question_id = ? AND a.text IS NOT NULL AND a.text <> ''
+				ORDER BY r.submitted_at`, eventID, q.ID)
+			if err != nil {
+				return res, err
+			}
+			for rows.Next() {
+				var text string
+				if err := rows.Scan(&text); err != nil {
+					rows.Close()
+					return res, err
+				}
+				qr.TextAnswers = append(qr.TextAnswers, text)
+			}
+			rows.Close()
+			qr.Answers = len(qr.TextAnswers)
+		}
+		res.Questions = append(res.Questions, qr)
+	}
+
+	if normalizedCount > 0 {
+		avg := math.Round(normalizedSum/float64(normalizedCount)*100) / 100
+		res.AverageRating = &avg
+	}
+	return res, nil
+}
+
+// GetEventSurveyResults — итоги опроса по мероприятию для организаторов.
+func (sc *SurveyController) GetEventSurveyResults(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := utils.VerifyToken(r)
+		if err != nil {
+			utils.RespondWithError(w, http.StatusUnauthorized, models.Error{Message: "Unauthorized"})
+			return
+		}
+		eventID, ok := eventIDFromPath(w, r)
+		if !ok {
+			return
+		}
+		if _, ok := requireEventOrganizer(w, db, eventID, userID); !ok {
+			return
+		}
+
+		results, err := buildEventSurveyResults(db, eventID)
+		if err == sql.ErrNoRows {
+			utils.RespondWithError(w, http.StatusNotFound, models.Error{Message: "Event has no survey"})
+			return
+		} else if err != nil {
+			log.Printf("Error building survey results for event %d: %v", eventID, err)
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Failed to fetch survey results"})
+			return
+		}
+		utils.ResponseJSON(w, results)
+	}
+}
+
+// GetSchoolSurveyResults — итоги опросов по всем мероприятиям школы-организатора.
+func (sc *SurveyController) GetSchoolSurveyResults(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := utils.VerifyToken(r)
+		if err != nil {
+			utils.RespondWithError(w, http.StatusUnauthorized, models.Error{Message: "Unauthorized"})
+			return
+		}
+		schoolID, err := strconv.Atoi(mux.Vars(r)["school_id"])
+		if err != nil || schoolID <= 0 {
+			utils.RespondWithError(w, http.StatusBadRequest, models.Error{Message: "Invalid school ID"})
+			return
+		}
+		allowed, err := canViewSchool(db, userID, schoolID)
+		if err != nil {
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Error checking permissions"})
+			return
+		}
+		if !allowed {
+			utils.RespondWithError(w, http.StatusForbidden, models.Error{Message: "You do not have permission to view this school's data"})
+			return
+		}
+
+		rows, err := db.Query(`
+			SELECT es.event_id
+			FROM event_surveys es
+			JOIN Events e ON e.id = es.event_id
+			WHERE e.school_id = ? AND es.sent_at IS NOT NULL
+			ORDER BY e.end_date DESC`, schoolID)
+		if err != nil {
+			log.Printf("Error fetching surveys for school %d: %v", schoolID, err)
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Failed to fetch survey results"})
+			return
+		}
+		var eventIDs []int
+		for rows.Next() {
+			var id int
+			if err := rows.Scan(&id); err == nil {
+				eventIDs = append(eventIDs, id)
+			}
+		}
+		rows.Close()
+
+		summary := models.SchoolSurveySummary{SchoolID: schoolID, EventResults: []models.EventSurveyResults{}}
+		var ratingSum float64
+		var ratedEvents int
+		for _, eventID := range eventIDs {
+			results, err := buildEventSurveyResults(db, eventID)
+			if err != nil {
+				log.Printf("Error building survey results for event %d: %v", eventID, err)
+				utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Failed to fetch survey results"})
+				return
+			}
+			summary.Events++
+			summary.Invitations += results.Invitations
+			summary.Responses += results.Responses
+			if results.AverageRating != nil {
+				ratingSum += *results.AverageRating
+				ratedEvents++
+			}
+			summary.EventResults = append(summary.EventResults, results)
+		}
+		if summary.Invitations > 0 {
+			summary.ResponseRate = math.Round(float64(summary.Responses)/float64(summary.Invitations)*1000) / 10
+		}
+		if ratedEvents > 0 {
+			avg := math.Round(ratingSum/float64(ratedEvents)*100) / 100
+			summary.AverageRating = &avg
+		}
+
+		utils.ResponseJSON(w, summary)
+	}
+}
+
+// StartSurveyDispatcher раз в interval рассылает опросы по завершившимся
+// мероприятиям. Запускается один раз при старте сервера.
+func StartSurveyDispatcher(db *sql.DB, interval time.Duration) {
+	go func() {
+		for {
+			if err := dispatchEventSurveys(db, time.Now()); err != nil {
+				log.Printf("Survey dispatch failed: %v", err)
+			}
+			time.Sleep(interval)
+		}
+	}()
+}
+
+type surveyInvitation struct {
+	StudentID int
+	Message   string
+}
+
+func dispatchEventSurveys(db *sql.DB, now time.Time) error {
+	rows, err := db.Query(`
+		SELECT es.event_id
+		FROM event_surveys es
+		JOIN Events e ON e.id = es.event_id
+		WHERE es.sent_at IS NULL AND e.end_date < ?`, now.Format("2006-01-02 15:04:05"))
+	if err != nil {
+		return err
+	}
+	var eventIDs []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return err
+		}
+		eventIDs = append(eventIDs, id)
+	}
+	rows.Close()
+
+	for _, eventID := range eventIDs {
+		invitations, err := sendEventSurvey(db, eventID, now)
+		if err != nil {
+			log.Printf("Failed to send survey for event %d: %v", eventID, err)
+			continue
+		}
+		emailSurveyInvitations(db, invitations)
+	}
+	return nil
+}
+
+// sendEventSurvey приглашает участников и отмечает опрос разосланным.
+// Строка event_surveys блокируется, поэтому опрос не уйдёт дважды даже при
+// нескольких экземплярах сервера.
+func sendEventSurvey(db *sql.DB, eventID int, now time.Time) ([]surveyInvitation, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var sentAt sql.NullString
+	if err := tx.QueryRow("SELECT CAST(sent_at AS CHAR) FROM event_surveys WHERE event_id = ? FOR UPDATE", eventID).Scan(&sentAt); err != nil {
+		return nil, err
+	}
+	if sentAt.Valid {
+		return nil, nil
+	}
+
+	var eventName string
+	if err := tx.QueryRow("SELECT COALESCE(event_name, '') FROM Events WHERE id = ?", eventID).Scan(&eventName); err != nil {
+		return nil, err
+	}
+
+	rows, err := tx.Query(`
+		SELECT DISTINCT student_id FROM EventRegistrations
+		WHERE event_id = ? AND `+surveyRecipientsCondition, eventID)
+	if err != nil {
+		return nil, err
+	}
+	var studentIDs []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		studentIDs = append(studentIDs, id)
+	}
+	rows.Close()
+
+	sentAtStr := now.Format("2006-01-02 15:04:05")
+	message := fmt.Sprintf("Поделитесь впечатлениями о мероприятии «%s» — пройдите короткий опрос", eventName)
+	var invitations []surveyInvitation
+	for _, studentID := range studentIDs {
+		res, err := tx.Exec("INSERT IGNORE INTO survey_invitations (event_id, student_id, sent_at) VALUES (?, ?, ?)", eventID, studentID, sentAtStr)
+		if err != nil {
+			return nil, err
+		}
+		if affected, _ := res.RowsAffected(); affected == 0 {
+			continue
+		}
+		if err := createNotification(tx, studentID, "event_survey", message); err != nil {
+			return nil, err
+		}
+		invitations = append(invitations, surveyInvitation{StudentID: studentID, Message: message})
+	}
+
+	if _, err := tx.Exec("UPDATE event_surveys SET sent_at = ? WHERE event_id = ?", sentAtStr, eventID); err != nil {
+		return nil, err
+	}
+	return invitations, tx.Commit()
+}
+
+func emailSurveyInvitations(db *sql.DB, invitations []surveyInvitation) {
+	if len(invitations) == 0 {
+		return
+	}
+	go func() {
+		for _, inv := range invitations {
+			var email sql.NullString
+			if err := db.QueryRow("SELECT email FROM student WHERE student_id = ?", inv.StudentID).Scan(&email); err != nil {
+				log.Printf("Failed to load email for student %d: %v", inv.StudentID, err)
+				continue
+			}
+			if email.Valid && email.String != "" {
+				utils.SendEmail(email.String, "Опрос после мероприятия", inv.Message)
+			}
+		}
+	}()
+}
@@ -22,6 +22,27 @@ import (
 type TypeController struct{}
 type UNTExam struct{}
 
+// insertUNTExam сохраняет проверенную попытку ЕНТ и проставляет ей ID.
+func insertUNTExam(db execQuerier, exam *models.UNTExam) (int64, error) {
+	result, err := db.Exec(`INSERT INTO UNT_Exams (
+		exam_type, attempt_kind, first_subject, first_subject_score, second_subject, second_subject_score,
+		history_of_kazakhstan, mathematical_literacy, reading_literacy,
+		total_score, student_id, school_id, document_url, date
+	) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		exam.ExamType, exam.AttemptKind, exam.FirstSubject, exam.FirstSubjectScore, exam.SecondSubject, exam.SecondSubjectScore,
+		exam.HistoryOfKazakhstan, exam.MathematicalLiteracy, exam.ReadingLiteracy,
+		exam.TotalScore, exam.StudentID, exam.SchoolID, exam.DocumentURL, exam.Date)
+	if err != nil {
+		return 0, err
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+	exam.ID = int(id)
+	return id, nil
+}
+
 func (c *UNTScoreController) CreateUNT(db *sql.DB) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		// Step 1: Get userID from token
@@ -33,8 +54,7 @@ func (c *UNTScoreController) CreateUNT(db *sql.DB) http.HandlerFunc {
 
 		// Step 2: Check user role and school_id
 		var userRole string
-		var userSchoolID sql.NullInt64
-		err = db.QueryRow("SELECT role, school_id FROM users WHERE id = ?", userID).Scan(&userRole, &userSchoolID)
+		err = db.QueryRow("SELECT role FROM users WHERE id = ?", userID).Scan(&userRole)
 		if err != nil {
 			log.Println("Ошибка при получении роли пользователя или school_id:", err)
 			utils.RespondWithError(w, http.StatusForbidden, models.Error{Message: "Не удалось получить роль или school_id пользователя"})
@@ -56,7 +76,13 @@ func (c *UNTScoreController) CreateUNT(db *sql.DB) http.HandlerFunc {
 			log.Printf("Пользователь %d с ролью superadmin создаёт UNT для школы %d", userID, urlSchoolID)
 		} else if userRole == "schooladmin" {
 			// Schooladmin can only create UNT exams for their school
-			if !userSchoolID.Valid || int(userSchoolID.Int64) != urlSchoolID {
+			allowed, err := hasSchoolPermission(db, userID, urlSchoolID, PermissionManageUNT)
+			if err != nil {
+				log.Printf("Ошибка при проверке прав участника школы: %v", err)
+				utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Не удалось проверить права пользователя"})
+				return
+			}
+			if !allowed {
 				log.Printf("Пользователь %d с ролью schooladmin пытается создать UNT для не своей школы %d", userID, urlSchoolID)
 				utils.RespondWithError(w, http.StatusForbidden, models.Error{Message: "У вас нет прав на создание UNT экзамена для этой школы"})
 				return
@@ -89,6 +115,7 @@ func (c *UNTScoreController) CreateUNT(db *sql.DB) http.HandlerFunc {
 
 		var untExam models.UNTExam
 		untExam.SchoolID = urlSchoolID // Use the school_id from URL
+		clientTotal := 0               // total_score от клиента, сверяется с пересчитанным
 
 		if strings.Contains(contentType, "multipart/form-data") {
 			// Process multipart/form-data request
@@ -129,6 +156,7 @@ func (c *UNTScoreController) CreateUNT(db *sql.DB) http.HandlerFunc {
 			}
 
 			// Get common fields
+			untExam.AttemptKind = r.FormValue("attempt_kind")
 			untExam.Date = r.FormValue("date")
 			untExam.StudentID, _ = strconv.Atoi(r.FormValue("student_id"))
 
@@ -154,6 +182,7 @@ func (c *UNTScoreController) CreateUNT(db *sql.DB) http.HandlerFunc {
 			untExam.SecondSubjectScore, _ = strconv.Atoi(r.FormValue("second_subject_score"))
 			untExam.HistoryOfKazakhstan, _ = strconv.Atoi(r.FormValue("history_of_kazakhstan"))
 			untExam.ReadingLiteracy, _ = strconv.Atoi(r.FormValue("reading_literacy"))
+			clientTotal, _ = strconv.Atoi(r.FormValue("total_score"))
 
 			// Get fields based on exam type
 			if untExam.ExamType == "regular" {
@@ -169,10 +198,6 @@ func (c *UNTScoreController) CreateUNT(db *sql.DB) http.HandlerFunc {
 					return
 				}
 
-				// Calculate total score for regular exam
-				untExam.TotalScore = untExam.FirstSubjectScore + untExam.SecondSubjectScore +
-					untExam.HistoryOfKazakhstan + untExam.MathematicalLiteracy +
-					untExam.ReadingLiteracy
 			} else {
 				// Validate required fields for creative exam
 				if untExam.FirstSubject == "" || untExam.SecondSubject == "" ||
@@ -183,9 +208,6 @@ func (c *UNTScoreController) CreateUNT(db *sql.DB) http.HandlerFunc {
 					return
 				}
 
-				// Calculate total score for creative exam
-				untExam.TotalScore = untExam.FirstSubjectScore + untExam.SecondSubjectScore +
-					untExam.HistoryOfKazakhstan + untExam.ReadingLiteracy
 			}
 
 			// Process file upload
@@ -308,15 +330,22 @@ func (c *UNTScoreController) CreateUNT(db *sql.DB) http.HandlerFunc {
 			// Override school_id from URL parameter
 			untExam.SchoolID = urlSchoolID
 
-			// Calculate total score based on exam type
-			if untExam.ExamType == "regular" {
-				untExam.TotalScore = untExam.FirstSubjectScore + untExam.SecondSubjectScore +
-					untExam.HistoryOfKazakhstan + untExam.MathematicalLiteracy +
-					untExam.ReadingLiteracy
-			} else {
-				untExam.TotalScore = untExam.FirstSubjectScore + untExam.SecondSubjectScore +
-					untExam.HistoryOfKazakhstan + untExam.ReadingLiteracy
-			}
+			clientTotal = untExam.TotalScore
+		}
+
+		// Пробные ЕНТ не участвуют в рейтинге школы
+		untExam.AttemptKind = strings.ToLower(untExam.AttemptKind)
+		if untExam.AttemptKind == "" {
+			untExam.AttemptKind = "official"
+		}
+		if untExam.AttemptKind != "official" && untExam.AttemptKind != "trial" {
+			utils.RespondWithError(w, http.StatusBadRequest, models.Error{Message: "Некорректный вид попытки. Допустимые значения: official, trial"})
+			return
+		}
+
+		// Проверяем предметы и баллы по справочнику, итог считаем на сервере
+		if !validateUNTExamWithCatalog(w, db, &untExam, clientTotal) {
+			return
 		}
 
 		// Step 7: Check if student exists
@@ -341,28 +370,7 @@ func (c *UNTScoreController) CreateUNT(db *sql.DB) http.HandlerFunc {
 		}
 
 		// Step 8: Insert record into database
-		query := `INSERT INTO UNT_Exams (
-			exam_type, first_subject, first_subject_score, second_subject, second_subject_score,
-			history_of_kazakhstan, mathematical_literacy, reading_literacy, 
-			total_score, student_id, school_id, document_url, date
-		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
-
-		result, err := db.Exec(query,
-			untExam.ExamType,
-			untExam.FirstSubject,
-			untExam.FirstSubjectScore,
-			untExam.SecondSubject,
-			untExam.SecondSubjectScore,
-			untExam.HistoryOfKazakhstan,
-			untExam.MathematicalLiteracy,
-			untExam.ReadingLiteracy,
-			untExam.TotalScore,
-			untExam.StudentID,
-			untExam.SchoolID,
-			untExam.DocumentURL,
-			untExam.Date,
-		)
-
+		newID, err := insertUNTExam(db, &untExam)
 		if err != nil {
 			log.Printf("Ошибка SQL: %v", err)
 
@@ -381,11 +389,7 @@ func (c *UNTScoreController) CreateUNT(db *sql.DB) http.HandlerFunc {
 			return
 		}
 
-		// Step 9: Get ID of the inserted record
-		newID, _ := result.LastInsertId()
-		untExam.ID = int(newID)
-
-		// Step 10: Return success message
+		// Step 9: Return success message
 		utils.ResponseJSON(w, map[string]interface{}{
 			"message":      fmt.Sprintf("UNT экзамен типа %s создан успешно", untExam.ExamType),
 			"id":           newID,
@@ -515,8 +519,7 @@ func (c *UNTScoreController) GetUNTExams(db *sql.DB) http.HandlerFunc {
 
 		// Step 2: Get role and school_id
 		var userRole string
-		var userSchoolID sql.NullInt64
-		err = db.QueryRow("SELECT role, school_id FROM users WHERE id = ?", userID).Scan(&userRole, &userSchoolID)
+		err = db.QueryRow("SELECT role FROM users WHERE id = ?", userID).Scan(&userRole)
 		if err != nil {
 			log.Println("Ошибка при получении роли или school_id пользователя:", err)
 			utils.RespondWithError(w, http.StatusForbidden, models.Error{Message: "Ошибка при получении пользователя"})
@@ -526,6 +529,7 @@ func (c *UNTScoreController) GetUNTExams(db *sql.DB) http.HandlerFunc {
 		// Step 3: Get query parameters
 		studentID := r.URL.Query().Get("student_id")
 		examType := r.URL.Query().Get("exam_type")
+		attemptKind := r.URL.Query().Get("attempt_kind")
 		schoolID := r.URL.Query().Get("school_id")
 		dateFrom := r.URL.Query().Get("date_from")
 		dateTo := r.URL.Query().Get("date_to")
@@ -535,7 +539,7 @@ func (c *UNTScoreController) GetUNTExams(db *sql.DB) http.HandlerFunc {
 					e.id, e.exam_type, e.first_subject, e.first_subject_score, e.second_subject, 
 					e.second_subject_score, e.history_of_kazakhstan, e.mathematical_literacy, 
 					e.reading_literacy, e.total_score, e.student_id, e.school_id, 
-					e.document_url, e.date, s.school_name
+					COALESCE(e.document_url, ''), COALESCE(CAST(e.date AS CHAR), ''), e.attempt_kind, s.school_name
 				  FROM UNT_Exams e
 				  LEFT JOIN Schools s ON e.school_id = s.school_id
 				  WHERE 1=1`
@@ -544,12 +548,18 @@ func (c *UNTScoreController) GetUNTExams(db *sql.DB) http.HandlerFunc {
 
 		// Step 5: Apply role filter
 		if userRole == "schooladmin" {
+			userSchoolID, err := getMemberSchoolID(db, userID, "")
+			if err != nil {
+				log.Printf("Ошибка при получении школ пользователя: %v", err)
+				utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Не удалось получить школу пользователя"})
+				return
+			}
 			if !userSchoolID.Valid {
 				utils.RespondWithError(w, http.StatusForbidden, models.Error{Message: "Нет доступа к экзаменам"})
 				return
 			}
-			query += " AND e.school_id = ?"
-			args = append(args, userSchoolID.Int64)
+			query += " AND e.school_id IN (SELECT school_id FROM school_members WHERE user_id = ?)"
+			args = append(args, userID)
 		}
 
 		// Step 6: Apply query parameters
@@ -561,6 +571,10 @@ func (c *UNTScoreController) GetUNTExams(db *sql.DB) http.HandlerFunc {
 			query += " AND e.exam_type = ?"
 			args = append(args, strings.ToLower(examType))
 		}
+		if attemptKind != "" {
+			query += " AND e.attempt_kind = ?"
+			args = append(args, strings.ToLower(attemptKind))
+		}
 		if schoolID != "" && (userRole == "superadmin" || userRole == "admin" || userRole == "moderator") {
 			query += " AND e.school_id = ?"
 			args = append(args, schoolID)
@@ -594,7 +608,7 @@ func (c *UNTScoreController) GetUNTExams(db *sql.DB) http.HandlerFunc {
 				&exam.SecondSubject, &exam.SecondSubjectScore, &exam.HistoryOfKazakhstan,
 				&exam.MathematicalLiteracy, &exam.ReadingLiteracy, &exam.TotalScore,
 				&exam.StudentID, &exam.SchoolID, &exam.DocumentURL, &exam.Date,
-				&exam.SchoolName, // получаем school_name
+				&exam.AttemptKind, &exam.SchoolName, // получаем school_name
 			)
 			if err != nil {
 				log.Printf("Ошибка при сканировании строки: %v", err)
@@ -629,8 +643,7 @@ func (c *UNTScoreController) UpdateUNTExam(db *sql.DB) http.HandlerFunc {
 
 		// Step 3: Check user role and school_id
 		var userRole string
-		var userSchoolID sql.NullInt64
-		err = db.QueryRow("SELECT role, school_id FROM users WHERE id = ?", userID).Scan(&userRole, &userSchoolID)
+		err = db.QueryRow("SELECT role FROM users WHERE id = ?", userID).Scan(&userRole)
 		if err != nil {
 			log.Println("Ошибка при получении роли пользователя или school_id:", err)
 			utils.RespondWithError(w, http.StatusForbidden, models.Error{Message: "Не удалось получить роль или school_id пользователя"})
@@ -639,11 +652,6 @@ func (c *UNTScoreController) UpdateUNTExam(db *sql.DB) http.HandlerFunc {
 
 		// Step 4: Check if the user has permission to update this exam
 		if userRole == "schooladmin" {
-			if !userSchoolID.Valid {
-				utils.RespondWithError(w, http.StatusForbidden, models.Error{Message: "У вас нет прав для изменения этого экзамена"})
-				return
-			}
-
 			// Check if exam belongs to this school
 			var examSchoolID int
 			err = db.QueryRow("SELECT school_id FROM UNT_Exams WHERE id = ?", examID).Scan(&examSchoolID)
@@ -657,7 +665,13 @@ func (c *UNTScoreController) UpdateUNTExam(db *sql.DB) http.HandlerFunc {
 				return
 			}
 
-			if examSchoolID != int(userSchoolID.Int64) {
+			allowed, err := hasSchoolPermission(db, userID, examSchoolID, PermissionManageUNT)
+			if err != nil {
+				log.Printf("Ошибка при проверке прав участника школы: %v", err)
+				utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Не удалось проверить права пользователя"})
+				return
+			}
+			if !allowed {
 				utils.RespondWithError(w, http.StatusForbidden, models.Error{Message: "У вас нет прав для изменения этого экзамена"})
 				return
 			}
@@ -677,11 +691,11 @@ func (c *UNTScoreController) UpdateUNTExam(db *sql.DB) http.HandlerFunc {
 		// Step 6: Fetch existing exam for comparison and calculated fields
 		var existingExam models.UNTExam
 		err = db.QueryRow(`
-			SELECT exam_type, first_subject, first_subject_score, second_subject, second_subject_score,
+			SELECT exam_type, attempt_kind, first_subject, first_subject_score, second_subject, second_subject_score,
 			history_of_kazakhstan, mathematical_literacy, reading_literacy, total_score,
-			school_id, student_id, document_url, date
+			school_id, student_id, COALESCE(document_url, ''), COALESCE(CAST(date AS CHAR), '')
 			FROM UNT_Exams WHERE id = ?`, examID).Scan(
-			&existingExam.ExamType, &existingExam.FirstSubject, &existingExam.FirstSubjectScore,
+			&existingExam.ExamType, &existingExam.AttemptKind, &existingExam.FirstSubject, &existingExam.FirstSubjectScore,
 			&existingExam.SecondSubject, &existingExam.SecondSubjectScore, &existingExam.HistoryOfKazakhstan,
 			&existingExam.MathematicalLiteracy, &existingExam.ReadingLiteracy, &existingExam.TotalScore,
 			&existingExam.SchoolID, &existingExam.StudentID, &existingExam.DocumentURL, &existingExam.Date,
@@ -703,6 +717,9 @@ func (c *UNTScoreController) UpdateUNTExam(db *sql.DB) http.HandlerFunc {
 
 		// Track if any fields were changed
 		fieldsChanged := false
+		// Предметы и баллы сверяются со справочником, только если они менялись:
+		// перенесённые старые записи без предметов можно править по дате и документу
+		scoresChanged := false
 
 		// Handle exam_type update
 		if examType := r.FormValue("exam_type"); examType != "" {
@@ -713,12 +730,25 @@ func (c *UNTScoreController) UpdateUNTExam(db *sql.DB) http.HandlerFunc {
 			}
 			existingExam.ExamType = examType
 			fieldsChanged = true
+			scoresChanged = true
+		}
+
+		// Handle attempt_kind update
+		if attemptKind := r.FormValue("attempt_kind"); attemptKind != "" {
+			attemptKind = strings.ToLower(attemptKind)
+			if attemptKind != "official" && attemptKind != "trial" {
+				utils.RespondWithError(w, http.StatusBadRequest, models.Error{Message: "Некорректный вид попытки. Допустимые значения: official, trial"})
+				return
+			}
+			existingExam.AttemptKind = attemptKind
+			fieldsChanged = true
 		}
 
 		// Handle first_subject update
 		if firstSubject := r.FormValue("first_subject"); firstSubject != "" {
 			existingExam.FirstSubject = firstSubject
 			fieldsChanged = true
+			scoresChanged = true
 		}
 
 		// Handle first_subject_score update
@@ -730,12 +760,14 @@ func (c *UNTScoreController) UpdateUNTExam(db *sql.DB) http.HandlerFunc {
 			}
 			existingExam.FirstSubjectScore = score
 			fieldsChanged = true
+			scoresChanged = true
 		}
 
 		// Handle second_subject update
 		if secondSubject := r.FormValue("second_subject"); secondSubject != "" {
 			existingExam.SecondSubject = secondSubject
 			fieldsChanged = true
+			scoresChanged = true
 		}
 
 		// Handle second_subject_score update
@@ -747,6 +779,7 @@ func (c *UNTScoreController) UpdateUNTExam(db *sql.DB) http.HandlerFunc {
 			}
 			existingExam.SecondSubjectScore = score
 			fieldsChanged = true
+			scoresChanged = true
 		}
 
 		// Handle history_of_kazakhstan update
@@ -758,6 +791,7 @@ func (c *UNTScoreController) UpdateUNTExam(db *sql.DB) http.HandlerFunc {
 			}
 			existingExam.HistoryOfKazakhstan = score
 			fieldsChanged = true
+			scoresChanged = true
 		}
 
 		// Handle mathematical_literacy update
@@ -769,6 +803,7 @@ func (c *UNTScoreController) UpdateUNTExam(db *sql.DB) http.HandlerFunc {
 			}
 			existingExam.MathematicalLiteracy = score
 			fieldsChanged = true
+			scoresChanged = true
 		}
 
 		// Handle reading_literacy update
@@ -780,6 +815,7 @@ func (c *UNTScoreController) UpdateUNTExam(db *sql.DB) http.HandlerFunc {
 			}
 			existingExam.ReadingLiteracy = score
 			fieldsChanged = true
+			scoresChanged = true
 		}
 
 		// Handle document_url update (may come from file upload)
@@ -834,19 +870,20 @@ func (c *UNTScoreController) UpdateUNTExam(db *sql.DB) http.HandlerFunc {
 			return
 		}
 
-		// Calculate total score based on exam type
-		if existingExam.ExamType == "regular" {
-			existingExam.TotalScore = existingExam.FirstSubjectScore + existingExam.SecondSubjectScore +
-				existingExam.HistoryOfKazakhstan + existingExam.MathematicalLiteracy +
-				existingExam.ReadingLiteracy
-		} else { // creative
-			existingExam.TotalScore = existingExam.FirstSubjectScore + existingExam.SecondSubjectScore +
-				existingExam.HistoryOfKazakhstan + existingExam.ReadingLiteracy
+		// Проверяем предметы и баллы по справочнику, итог считаем на сервере
+		if scoresChanged {
+			if existingExam.ExamType == "creative" {
+				existingExam.MathematicalLiteracy = 0
+			}
+			clientTotal, _ := strconv.Atoi(r.FormValue("total_score"))
+			if !validateUNTExamWithCatalog(w, db, &existingExam, clientTotal) {
+				return
+			}
 		}
 
 		// Step 8: Update record in database
 		query := `UPDATE UNT_Exams SET 
-			exam_type = ?, first_subject = ?, first_subject_score = ?, 
+			exam_type = ?, attempt_kind = ?, first_subject = ?, first_subject_score = ?, 
 			second_subject = ?, second_subject_score = ?, history_of_kazakhstan = ?, 
 			mathematical_literacy = ?, reading_literacy = ?, total_score = ?, 
 			document_url = ?, date = ?
@@ -854,6 +891,7 @@ func (c *UNTScoreController) UpdateUNTExam(db *sql.DB) http.HandlerFunc {
 
 		_, err = db.Exec(query,
 			existingExam.ExamType,
+			existingExam.AttemptKind,
 			existingExam.FirstSubject,
 			existingExam.FirstSubjectScore,
 			existingExam.SecondSubject,
@@ -863,7 +901,7 @@ func (c *UNTScoreController) UpdateUNTExam(db *sql.DB) http.HandlerFunc {
 			existingExam.ReadingLiteracy,
 			existingExam.TotalScore,
 			existingExam.DocumentURL,
-			existingExam.Date,
+			toNullString(existingExam.Date),
 			existingExam.ID,
 		)
 
@@ -900,8 +938,7 @@ func (c *UNTScoreController) DeleteUNTExam(db *sql.DB) http.HandlerFunc {
 
 		// Step 3: Check user role and school_id
 		var userRole string
-		var userSchoolID sql.NullInt64
-		err = db.QueryRow("SELECT role, school_id FROM users WHERE id = ?", userID).Scan(&userRole, &userSchoolID)
+		err = db.QueryRow("SELECT role FROM users WHERE id = ?", userID).Scan(&userRole)
 		if err != nil {
 			log.Println("Ошибка при получении роли пользователя или school_id:", err)
 			utils.RespondWithError(w, http.StatusForbidden, models.Error{Message: "Не удалось получить роль или school_id пользователя"})
@@ -910,11 +947,6 @@ func (c *UNTScoreController) DeleteUNTExam(db *sql.DB) http.HandlerFunc {
 
 		// Step 4: Check if the user has permission to delete this exam
 		if userRole == "schooladmin" {
-			if !userSchoolID.Valid {
-				utils.RespondWithError(w, http.StatusForbidden, models.Error{Message: "У вас нет прав для удаления этого экзамена"})
-				return
-			}
-
 			// Check if exam belongs to this school
 			var examSchoolID int
 			err = db.QueryRow("SELECT school_id FROM UNT_Exams WHERE id = ?", examID).Scan(&examSchoolID)
@@ -928,7 +960,13 @@ func (c *UNTScoreController) DeleteUNTExam(db *sql.DB) http.HandlerFunc {
 				return
 			}
 
-			if examSchoolID != int(userSchoolID.Int64) {
+			allowed, err := hasSchoolPermission(db, userID, examSchoolID, PermissionManageUNT)
+			if err != nil {
+				log.Printf("Ошибка при проверке прав участника школы: %v", err)
+				utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Не удалось проверить права пользователя"})
+				return
+			}
+			if !allowed {
 				utils.RespondWithError(w, http.StatusForbidden, models.Error{Message: "У вас нет прав для удаления этого экзамена"})
 				return
 			}
@@ -975,8 +1013,7 @@ func (c *UNTScoreController) GetUNTBySchoolID(db *sql.DB) http.HandlerFunc {
 		}
 
 		var userRole string
-		var userSchoolID sql.NullInt64
-		err = db.QueryRow("SELECT role, school_id FROM users WHERE id = ?", userID).Scan(&userRole, &userSchoolID)
+		err = db.QueryRow("SELECT role FROM users WHERE id = ?", userID).Scan(&userRole)
 		if err != nil {
 			log.Println("Ошибка при получении роли пользователя или school_id:", err)
 			utils.RespondWithError(w, http.StatusForbidden, models.Error{Message: "Не удалось получить роль или school_id пользователя"})
@@ -997,11 +1034,13 @@ func (c *UNTScoreController) GetUNTBySchoolID(db *sql.DB) http.HandlerFunc {
 
 		switch userRole {
 		case "schooladmin":
-			if !userSchoolID.Valid {
-				utils.RespondWithError(w, http.StatusForbidden, models.Error{Message: "У вас нет привязки к школе"})
+			isMember, err := hasSchoolPermission(db, userID, int(schoolID), "")
+			if err != nil {
+				log.Printf("Ошибка при проверке членства в школе: %v", err)
+				utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Не удалось проверить доступ к школе"})
 				return
 			}
-			if userSchoolID.Int64 != schoolID {
+			if !isMember {
 				utils.RespondWithError(w, http.StatusForbidden, models.Error{Message: "Вы не можете просматривать данные других школ"})
 				return
 			}
@@ -1026,8 +1065,9 @@ func (c *UNTScoreController) GetUNTBySchoolID(db *sql.DB) http.HandlerFunc {
 				e.student_id,
 				e.school_id,
 				s.letter,
-				e.date,
-				e.document_url
+				COALESCE(CAST(e.date AS CHAR), ''),
+				COALESCE(e.document_url, ''),
+				e.attempt_kind
 			FROM 
 				UNT_Exams e
 			JOIN 
@@ -1050,6 +1090,10 @@ func (c *UNTScoreController) GetUNTBySchoolID(db *sql.DB) http.HandlerFunc {
 			query += " AND e.exam_type = ?"
 			args = append(args, strings.ToLower(examType))
 		}
+		if attemptKind := r.URL.Query().Get("attempt_kind"); attemptKind != "" {
+			query += " AND e.attempt_kind = ?"
+			args = append(args, strings.ToLower(attemptKind))
+		}
 
 		query += " ORDER BY e.total_score DESC, s.letter, e.student_id"
 
@@ -1077,6 +1121,7 @@ func (c *UNTScoreController) GetUNTBySchoolID(db *sql.DB) http.HandlerFunc {
 			Letter               string `json:"letter"`
 			Date                 string `json:"date"`
 			DocumentURL          string `json:"document_url"`
+			AttemptKind          string `json:"attempt_kind"`
 		}
 
 		var results []UNTSchoolResult
@@ -1098,6 +1143,7 @@ func (c *UNTScoreController) GetUNTBySchoolID(db *sql.DB) http.HandlerFunc {
 				&result.Letter,
 				&result.Date,
 				&result.DocumentURL,
+				&result.AttemptKind,
 			)
 			if err != nil {
 				log.Printf("Ошибка при сканировании строки: %v", err)
@@ -1114,118 +1160,45 @@ func (c *UNTScoreController) GetUNTBySchoolID(db *sql.DB) http.HandlerFunc {
 }
 func (c *TypeController) GetAverageRatingBySchool(db *sql.DB) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		// Extract school_id from URL parameters
-		vars := mux.Vars(r)
-		schoolID, err := strconv.Atoi(vars["school_id"])
+		schoolID, err := schoolIDFromRequest(r)
 		if err != nil {
-			utils.RespondWithError(w, http.StatusBadRequest, models.Error{Message: "Invalid school ID"})
+			utils.RespondWithError(w, http.StatusBadRequest, models.Error{Message: err.Error()})
 			return
 		}
 
-		// Query to get average score by school
-		query := `
-        SELECT 
-            AVG(CASE WHEN ft.first_subject_score IS NOT NULL THEN ft.first_subject_score ELSE 0 END) AS avg_first_subject_score,
-            AVG(CASE WHEN ft.second_subject_score IS NOT NULL THEN ft.second_subject_score ELSE 0 END) AS avg_second_subject_score,
-            AVG(CASE WHEN ft.history_of_kazakhstan IS NOT NULL THEN ft.history_of_kazakhstan ELSE 0 END) AS avg_history_of_kazakhstan,
-            AVG(CASE WHEN ft.mathematical_literacy IS NOT NULL THEN ft.mathematical_literacy ELSE 0 END) AS avg_mathematical_literacy,
-            AVG(CASE WHEN ft.reading_literacy IS NOT NULL THEN ft.reading_literacy ELSE 0 END) AS avg_reading_literacy,
-            AVG(CASE WHEN ft.first_subject_score IS NOT NULL AND ft.second_subject_score IS NOT NULL AND 
-                     ft.history_of_kazakhstan IS NOT NULL AND ft.mathematical_literacy IS NOT NULL AND 
-                     ft.reading_literacy IS NOT NULL 
-                     THEN (ft.first_subject_score + ft.second_subject_score + ft.history_of_kazakhstan + 
-                           ft.mathematical_literacy + ft.reading_literacy) ELSE 0 END) AS avg_total_score
-        FROM First_Type ft
-        WHERE ft.school_id = ?`
-
-		row := db.QueryRow(query, schoolID)
-
-		// Use sql.NullFloat64 to handle NULL values
-		var avgFirstSubjectScore, avgSecondSubjectScore, avgHistoryOfKazakhstan, avgMathematicalLiteracy, avgReadingLiteracy, avgTotalScore sql.NullFloat64
-
-		err = row.Scan(&avgFirstSubjectScore, &avgSecondSubjectScore, &avgHistoryOfKazakhstan, &avgMathematicalLiteracy, &avgReadingLiteracy, &avgTotalScore)
+		result, count, err := averageRegularUNT(db, schoolID)
 		if err != nil {
 			log.Println("SQL Error:", err)
 			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Failed to calculate average rating"})
 			return
 		}
-
-		// Check if all averages are NULL (indicating no data)
-		if !avgFirstSubjectScore.Valid && !avgSecondSubjectScore.Valid && !avgHistoryOfKazakhstan.Valid &&
-			!avgMathematicalLiteracy.Valid && !avgReadingLiteracy.Valid && !avgTotalScore.Valid {
+		if count == 0 {
 			utils.RespondWithError(w, http.StatusNotFound, models.Error{Message: "No scores found for this school"})
 			return
 		}
 
-		// Convert sql.NullFloat64 to float64 for response, using 0 for NULL values
-		result := map[string]float64{
-			"avg_first_subject_score":   avgFirstSubjectScore.Float64,
-			"avg_second_subject_score":  avgSecondSubjectScore.Float64,
-			"avg_history_of_kazakhstan": avgHistoryOfKazakhstan.Float64,
-			"avg_mathematical_literacy": avgMathematicalLiteracy.Float64,
-			"avg_reading_literacy":      avgReadingLiteracy.Float64,
-			"avg_total_score":           avgTotalScore.Float64,
-		}
-
-		// Return result in JSON format
 		utils.ResponseJSON(w, result)
 	}
 }
 func (c *TypeController) GetAverageRatingSecondBySchool(db *sql.DB) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		// Извлекаем school_id из параметров URL
-		vars := mux.Vars(r)
-		schoolID, err := strconv.Atoi(vars["school_id"])
+		schoolID, err := schoolIDFromRequest(r)
 		if err != nil {
-			utils.RespondWithError(w, http.StatusBadRequest, models.Error{Message: "Invalid school ID"})
+			utils.RespondWithError(w, http.StatusBadRequest, models.Error{Message: err.Error()})
 			return
 		}
 
-		// Запрос для получения всех оценок по конкретной школе
-		query := `
-        SELECT 
-            history_of_kazakhstan_creative,
-            reading_literacy_creative,
-            creative_exam1,
-            creative_exam2
-        FROM Second_Type
-        WHERE school_id = ?`
-
-		rows, err := db.Query(query, schoolID)
+		averageRating, count, err := averageCreativeUNT(db, schoolID)
 		if err != nil {
 			log.Println("SQL Error:", err)
-			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Failed to get Second Types by School"})
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Failed to get creative UNT results by school"})
 			return
 		}
-		defer rows.Close()
-
-		var totalScore float64
-		var studentCount int
-
-		for rows.Next() {
-			var historyOfKazakhstanCreative, readingLiteracyCreative, creativeExam1, creativeExam2 sql.NullInt64
-
-			// Считываем данные для каждого экзамена
-			if err := rows.Scan(&historyOfKazakhstanCreative, &readingLiteracyCreative, &creativeExam1, &creativeExam2); err != nil {
-				log.Println("Scan Error:", err)
-				utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Failed to parse Second Types"})
-				return
-			}
-
-			// Вычисляем сумму оценок для каждого студента
-			totalScore += float64(historyOfKazakhstanCreative.Int64 + readingLiteracyCreative.Int64 + creativeExam1.Int64 + creativeExam2.Int64)
-			studentCount++
-		}
-
-		if studentCount == 0 {
+		if count == 0 {
 			utils.RespondWithError(w, http.StatusNotFound, models.Error{Message: "No students found for this school"})
 			return
 		}
 
-		// Рассчитываем средний балл
-		averageRating := totalScore / float64(studentCount)
-
-		// Возвращаем результат в формате JSON
 		utils.ResponseJSON(w, map[string]interface{}{
 			"average_rating": averageRating,
 		})
@@ -1237,12 +1210,13 @@ func (c *UNTScoreController) GetTop3UNTStudents(db *sql.DB) http.HandlerFunc {
 		query := `
 			SELECT 
 				CONCAT(s.first_name, ' ', s.last_name, ' ', COALESCE(s.patronymic, '')) AS full_name,
-				s.iin,
+				COALESCE(s.iin, '') AS iin,
 				s.grade,
 				s.letter,
 				ue.total_score
 			FROM student s
 			JOIN UNT_Exams ue ON s.student_id = ue.student_id
+			WHERE ue.attempt_kind = 'official'
 			ORDER BY ue.total_score DESC
 			LIMIT 3
 		`
@@ -1305,12 +1279,13 @@ func (c *UNTScoreController) GetTop10UNTStudents(db *sql.DB) http.HandlerFunc {
 		query := `
 			SELECT 
 				CONCAT(s.first_name, ' ', s.last_name, ' ', COALESCE(s.patronymic, '')) AS full_name,
-				s.iin,
+				COALESCE(s.iin, '') AS iin,
 				s.grade,
 				s.letter,
 				ue.total_score
 			FROM student s
 			JOIN UNT_Exams ue ON s.student_id = ue.student_id
+			WHERE ue.attempt_kind = 'official'
 			ORDER BY ue.total_score DESC
 			LIMIT 10
 		`
@@ -1376,8 +1351,7 @@ func (c *UNTScoreController) GetTop3UNTStudentsBySchoolID(db *sql.DB) http.Handl
 
 		// Step 2: Get user role and school_id
 		var userRole string
-		var userSchoolID sql.NullInt64
-		err = db.QueryRow("SELECT role, school_id FROM users WHERE id = ?", userID).Scan(&userRole, &userSchoolID)
+		err = db.QueryRow("SELECT role FROM users WHERE id = ?", userID).Scan(&userRole)
 		if err != nil {
 			log.Printf("Error fetching user details for user ID %d: %v", userID, err)
 			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Error fetching user details"})
@@ -1399,13 +1373,14 @@ func (c *UNTScoreController) GetTop3UNTStudentsBySchoolID(db *sql.DB) http.Handl
 
 		// Step 5: Restrict schooladmin to their school (остальные роли могут смотреть любые школы)
 		if userRole == "schooladmin" {
-			if !userSchoolID.Valid {
-				log.Printf("No school_id associated with schooladmin user ID %d", userID)
-				utils.RespondWithError(w, http.StatusForbidden, models.Error{Message: "No school assigned to this admin"})
+			isMember, err := hasSchoolPermission(db, userID, schoolID, "")
+			if err != nil {
+				log.Printf("Error checking membership for user ID %d: %v", userID, err)
+				utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Error fetching user details"})
 				return
 			}
-			if int(userSchoolID.Int64) != schoolID {
-				log.Printf("Schooladmin user ID %d attempted to access school ID %d, but is assigned to school ID %d", userID, schoolID, userSchoolID.Int64)
+			if !isMember {
+				log.Printf("Schooladmin user ID %d attempted to access school ID %d without membership", userID, schoolID)
 				utils.RespondWithError(w, http.StatusForbidden, models.Error{Message: "You do not have permission to view this school's data"})
 				return
 			}
@@ -1439,13 +1414,13 @@ func (c *UNTScoreController) GetTop3UNTStudentsBySchoolID(db *sql.DB) http.Handl
 		query := `
             SELECT 
                 CONCAT(s.first_name, ' ', s.last_name, ' ', COALESCE(s.patronymic, '')) AS full_name,
-                s.iin,
+                COALESCE(s.iin, '') AS iin,
                 s.grade,
                 s.letter,
                 ue.total_score
             FROM student s
             JOIN UNT_Exams ue ON s.student_id = ue.student_id
-            WHERE s.school_id = ?
+            WHERE ue.school_id = ? AND ue.attempt_kind = 'official'
             ORDER BY ue.total_score DESC
             LIMIT 3
         `
@@ -1499,6 +1474,36 @@ func (c *UNTScoreController) GetTop3UNTStudentsBySchoolID(db *sql.DB) http.Handl
 		utils.ResponseJSON(w, topStudents)
 	}
 }
+
+// latestOfficialUNTExam returns the student's most recent official UNT result.
+func latestOfficialUNTExam(db rowQuerier, studentID int) (models.UNTExam, error) {
+	query := `
+		SELECT id, exam_type, first_subject, first_subject_score, second_subject, second_subject_score,
+		       history_of_kazakhstan, mathematical_literacy, reading_literacy, total_score, school_id, COALESCE(CAST(date AS CHAR), '')
+		FROM UNT_Exams
+		WHERE student_id = ? AND attempt_kind = 'official'
+		ORDER BY date DESC, id DESC
+		LIMIT 1
+	`
+
+	exam := models.UNTExam{StudentID: studentID, AttemptKind: "official"}
+	err := db.QueryRow(query, studentID).Scan(
+		&exam.ID,
+		&exam.ExamType,
+		&exam.FirstSubject,
+		&exam.FirstSubjectScore,
+		&exam.SecondSubject,
+		&exam.SecondSubjectScore,
+		&exam.HistoryOfKazakhstan,
+		&exam.MathematicalLiteracy,
+		&exam.ReadingLiteracy,
+		&exam.TotalScore,
+		&exam.SchoolID,
+		&exam.Date,
+	)
+	return exam, err
+}
+
 func (c *UNTScoreController) GetUNTScoreByStudentID(db *sql.DB) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		// Проверка токена
@@ -1517,28 +1522,7 @@ func (c *UNTScoreController) GetUNTScoreByStudentID(db *sql.DB) http.HandlerFunc
 		}
 
 		// Получение последнего экзамена
-		query := `
-			SELECT exam_type, first_subject, first_subject_score, second_subject, second_subject_score,
-			       history_of_kazakhstan, mathematical_literacy, reading_literacy, total_score
-			FROM UNT_Exams
-			WHERE student_id = ?
-			ORDER BY date DESC
-			LIMIT 1
-		`
-
-		var exam models.UNTExam
-		err = db.QueryRow(query, studentID).Scan(
-			&exam.ExamType,
-			&exam.FirstSubject,
-			&exam.FirstSubjectScore,
-			&exam.SecondSubject,
-			&exam.SecondSubjectScore,
-			&exam.HistoryOfKazakhstan,
-			&exam.MathematicalLiteracy,
-			&exam.ReadingLiteracy,
-			&exam.TotalScore,
-		)
-
+		exam, err := latestOfficialUNTExam(db, studentID)
 		if err != nil {
 			if err == sql.ErrNoRows {
 				utils.RespondWithError(w, http.StatusNotFound, models.Error{Message: "UNT exam not found for this student"})
@@ -1578,8 +1562,7 @@ func (c *UNTScoreController) GetAverageUNTScoreBySchoolID(db *sql.DB) http.Handl
 
 		// Step 2: Check user role and school_id
 		var userRole string
-		var userSchoolID sql.NullInt64
-		err = db.QueryRow("SELECT role, school_id FROM users WHERE id = ?", userID).Scan(&userRole, &userSchoolID)
+		err = db.QueryRow("SELECT role FROM users WHERE id = ?", userID).Scan(&userRole)
 		if err != nil {
 			log.Println("Ошибка при получении роли пользователя или school_id:", err)
 			utils.RespondWithError(w, http.StatusForbidden, models.Error{Message: "Не удалось получить роль или school_id пользователя"})
@@ -1597,7 +1580,13 @@ func (c *UNTScoreController) GetAverageUNTScoreBySchoolID(db *sql.DB) http.Handl
 
 		// Step 4: Check permissions based on role
 		if userRole == "schooladmin" {
-			if !userSchoolID.Valid || userSchoolID.Int64 != schoolID {
+			isMember, err := hasSchoolPermission(db, userID, int(schoolID), "")
+			if err != nil {
+				log.Printf("Ошибка при проверке членства в школе: %v", err)
+				utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Не удалось проверить доступ к школе"})
+				return
+			}
+			if !isMember {
 				utils.RespondWithError(w, http.StatusForbidden, models.Error{Message: "Вы не можете просматривать данные других школ"})
 				return
 			}
@@ -1614,7 +1603,7 @@ func (c *UNTScoreController) GetAverageUNTScoreBySchoolID(db *sql.DB) http.Handl
 			FROM 
 				UNT_Exams 
 			WHERE 
-				school_id = ? AND exam_type = 'regular'`
+				school_id = ? AND exam_type = 'regular' AND attempt_kind = 'official'`
 
 		var averageScore float64
 		var studentCount int
@@ -1656,8 +1645,7 @@ func (c *UNTScoreController) GetAverageCreativeUNTScoreBySchoolID(db *sql.DB) ht
 
 		// Step 2: Check user role and school_id
 		var userRole string
-		var userSchoolID sql.NullInt64
-		err = db.QueryRow("SELECT role, school_id FROM users WHERE id = ?", userID).Scan(&userRole, &userSchoolID)
+		err = db.QueryRow("SELECT role FROM users WHERE id = ?", userID).Scan(&userRole)
 		if err != nil {
 			log.Println("Ошибка при получении роли пользователя или school_id:", err)
 			utils.RespondWithError(w, http.StatusForbidden, models.Error{Message: "Не удалось получить роль или school_id пользователя"})
@@ -1675,7 +1663,13 @@ func (c *UNTScoreController) GetAverageCreativeUNTScoreBySchoolID(db *sql.DB) ht
 
 		// Step 4: Check permissions based on role
 		if userRole == "schooladmin" {
-			if !userSchoolID.Valid || userSchoolID.Int64 != schoolID {
+			isMember, err := hasSchoolPermission(db, userID, int(schoolID), "")
+			if err != nil {
+				log.Printf("Ошибка при проверке членства в школе: %v", err)
+				utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Не удалось проверить доступ к школе"})
+				return
+			}
+			if !isMember {
 				utils.RespondWithError(w, http.StatusForbidden, models.Error{Message: "Вы не можете просматривать данные других школ"})
 				return
 			}
@@ -1692,7 +1686,7 @@ func (c *UNTScoreController) GetAverageCreativeUNTScoreBySchoolID(db *sql.DB) ht
 			FROM 
 				UNT_Exams 
 			WHERE 
-				school_id = ? AND exam_type = 'creative'`
+				school_id = ? AND exam_type = 'creative' AND attempt_kind = 'official'`
 
 		var averageScore float64
 		var studentCount int
@@ -1734,8 +1728,7 @@ func (c *UNTScoreController) GetCombinedAverageUNTScoreBySchoolID(db *sql.DB) ht
 
 		// Step 2: Check user role and school_id
 		var userRole string
-		var userSchoolID sql.NullInt64
-		err = db.QueryRow("SELECT role, school_id FROM users WHERE id = ?", userID).Scan(&userRole, &userSchoolID)
+		err = db.QueryRow("SELECT role FROM users WHERE id = ?", userID).Scan(&userRole)
 		if err != nil {
 			log.Println("Ошибка при получении роли пользователя или school_id:", err)
 			utils.RespondWithError(w, http.StatusForbidden, models.Error{Message: "Не удалось получить роль или school_id пользователя"})
@@ -1753,7 +1746,13 @@ func (c *UNTScoreController) GetCombinedAverageUNTScoreBySchoolID(db *sql.DB) ht
 
 		// Step 4: Check permissions based on role
 		if userRole == "schooladmin" {
-			if !userSchoolID.Valid || userSchoolID.Int64 != schoolID {
+			isMember, err := hasSchoolPermission(db, userID, int(schoolID), "")
+			if err != nil {
+				log.Printf("Ошибка при проверке членства в школе: %v", err)
+				utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Не удалось проверить доступ к школе"})
+				return
+			}
+			if !isMember {
 				utils.RespondWithError(w, http.StatusForbidden, models.Error{Message: "Вы не можете просматривать данные других школ"})
 				return
 			}
@@ -1773,6 +1772,7 @@ func (c *UNTScoreController) GetCombinedAverageUNTScoreBySchoolID(db *sql.DB) ht
 			WHERE 
 				school_id = ? 
 				AND exam_type IN ('regular', 'creative')
+				AND attempt_kind = 'official'
 			GROUP BY 
 				exam_type`
 
@@ -1858,8 +1858,7 @@ func (c *UNTScoreController) GetCombinedAverageUNTRankBySchoolID(db *sql.DB) htt
 
 		// Step 2: Check user role and school_id
 		var userRole string
-		var userSchoolID sql.NullInt64
-		err = db.QueryRow("SELECT role, school_id FROM users WHERE id = ?", userID).Scan(&userRole, &userSchoolID)
+		err = db.QueryRow("SELECT role FROM users WHERE id = ?", userID).Scan(&userRole)
 		if err != nil {
 			log.Println("Ошибка при получении роли пользователя или school_id:", err)
 			utils.RespondWithError(w, http.StatusForbidden, models.Error{Message: "Не удалось получить роль или school_id пользователя"})
@@ -1877,7 +1876,13 @@ func (c *UNTScoreController) GetCombinedAverageUNTRankBySchoolID(db *sql.DB) htt
 
 		// Step 4: Check permissions based on role
 		if userRole == "schooladmin" {
-			if !userSchoolID.Valid || userSchoolID.Int64 != schoolID {
+			isMember, err := hasSchoolPermission(db, userID, int(schoolID), "")
+			if err != nil {
+				log.Printf("Ошибка при проверке членства в школе: %v", err)
+				utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Не удалось проверить доступ к школе"})
+				return
+			}
+			if !isMember {
 				utils.RespondWithError(w, http.StatusForbidden, models.Error{Message: "Вы не можете просматривать данные других школ"})
 				return
 			}
@@ -1897,6 +1902,7 @@ func (c *UNTScoreController) GetCombinedAverageUNTRankBySchoolID(db *sql.DB) htt
             WHERE 
                 school_id = ? 
                 AND exam_type IN ('regular', 'creative')
+                AND attempt_kind = 'official'
             GROUP BY 
                 exam_type`
 
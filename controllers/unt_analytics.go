@@ -0,0 +1,401 @@
+package controllers
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"ranking-school/models"
+	"ranking-school/utils"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Компоненты, по которым строится распределение, и ширина корзины гистограммы
+var untDistributionComponents = []struct {
+	field    string
+	binWidth int
+}{
+	{"total_score", 10},
+	{"history_of_kazakhstan", 2},
+	{"reading_literacy", 1},
+	{"mathematical_literacy", 1},
+	{"first_subject_score", 5},
+	{"second_subject_score", 5},
+}
+
+type untAnalyticsRow struct {
+	SchoolID   int
+	SchoolName string
+	City       string
+	Year       int
+	ExamType   string
+	Pair       string
+	Scores     map[string]int
+}
+
+type histogramBin struct {
+	From  int `json:"from"`
+	To    int `json:"to"`
+	Count int `json:"count"`
+}
+
+type scoreDistribution struct {
+	Count       int                `json:"count"`
+	Mean        float64            `json:"mean"`
+	Median      float64            `json:"median"`
+	StdDev      float64            `json:"std_dev"`
+	Min         int                `json:"min"`
+	Max         int                `json:"max"`
+	Percentiles map[string]float64 `json:"percentiles"`
+	Histogram   []histogramBin     `json:"histogram"`
+}
+
+type untDistributionGroup struct {
+	Key        string                       `json:"key"`
+	Label      string                       `json:"label,omitempty"`
+	Components map[string]scoreDistribution `json:"components"`
+	// Процентиль медианы группы в национальном распределении total_score
+	// того же типа экзамена (ключ — exam_type): максимум у обычного ЕНТ
+	// 140 баллов, у творческого 120, поэтому вместе их не сравнивают
+	NationalPercentile map[string]float64 `json:"national_percentile"`
+}
+
+func round2(v float64) float64 {
+	return math.Round(v*100) / 100
+}
+
+// percentile uses linear interpolation between closest ranks; values must be sorted.
+func percentile(sorted []int, p float64) float64 {
+	if len(sorted) == 0 {
+		return 0
+	}
+	pos := p / 100 * float64(len(sorted)-1)
+	lower := int(math.Floor(pos))
+	upper := int(math.Ceil(pos))
+	frac := pos - float64(lower)
+	return float64(sorted[lower]) + (float64(sorted[upper])-float64(sorted[lower]))*frac
+}
+
+// percentileRank returns the share of values strictly below v plus half of equal ones.
+func percentileRank(sorted []int, v float64) float64 {
+	if len(sorted) == 0 {
+		return 0
+	}
+	below, equal := 0, 0
+	for _, s := range sorted {
+		if float64(s) < v {
+			below++
+		} else if float64(s) == v {
+			equal++
+		}
+	}
+	return round2((float64(below) + float64(equal)/2) / float64(len(sorted)) * 100)
+}
+
+func describeScores(values []int, binWidth int) scoreDistribution {
+	d := scoreDistribution{Count: len(values), Percentiles: map[string]float64{}, Histogram: []histogramBin{}}
+	if len(values) == 0 {
+		return d
+	}
+	sorted := append([]int(nil), values...)
+	sort.Ints(sorted)
+
+	sum := 0
+	for _, v := range sorted {
+		sum += v
+	}
+	mean := float64(sum) / float64(len(sorted))
+	variance := 0.0
+	for _, v := range sorted {
+		variance += (float64(v) - mean) * (float64(v) - mean)
+	}
+
+	d.Mean = round2(mean)
+	d.StdDev = round2(math.Sqrt(variance / float64(len(sorted))))
+	d.Median = percentile(sorted, 50)
+	d.Min = sorted[0]
+	d.Max = sorted[len(sorted)-1]
+	for _, p := range []float64{10, 25, 75, 90} {
+		d.Percentiles[fmt.Sprintf("p%d", int(p))] = round2(percentile(sorted, p))
+	}
+
+	for _, v := range sorted {
+		from := v / binWidth * binWidth
+		if n := len(d.Histogram); n > 0 && d.Histogram[n-1].From == from {
+			d.Histogram[n-1].Count++
+			continue
+		}
+		d.Histogram = append(d.Histogram, histogramBin{From: from, To: from + binWidth - 1, Count: 1})
+	}
+	return d
+}
+
+func describeUNTRows(rows []untAnalyticsRow) map[string]scoreDistribution {
+	components := map[string]scoreDistribution{}
+	for _, c := range untDistributionComponents {
+		var values []int
+		for _, r := range rows {
+			if v, ok := r.Scores[c.field]; ok {
+				values = append(values, v)
+			}
+		}
+		components[c.field] = describeScores(values, c.binWidth)
+	}
+	return components
+}
+
+// loadUNTAnalyticsRows выбирает результаты с учётом фильтров запроса.
+func loadUNTAnalyticsRows(db *sql.DB, filters map[string]string) ([]untAnalyticsRow, error) {
+	query := `
+		SELECT e.school_id, COALESCE(s.school_name, ''), COALESCE(s.city, ''), YEAR(e.date), e.exam_type,
+		       COALESCE(e.first_subject, ''), COALESCE(e.second_subject, ''),
+		       COALESCE(e.total_score, 0), COALESCE(e.history_of_kazakhstan, 0), COALESCE(e.reading_literacy, 0),
+		       COALESCE(e.mathematical_literacy, 0), COALESCE(e.first_subject_score, 0), COALESCE(e.second_subject_score, 0)
+		FROM UNT_Exams e
+		LEFT JOIN Schools s ON s.school_id = e.school_id
+		WHERE e.attempt_kind = ?`
+	args := []interface{}{filters["attempt_kind"]}
+
+	if v := filters["exam_type"]; v != "" {
+		query += " AND e.exam_type = ?"
+		args = append(args, v)
+	}
+	if v := filters["year"]; v != "" {
+		query += " AND YEAR(e.date) = ?"
+		args = append(args, v)
+	}
+	if v := filters["school_id"]; v != "" {
+		query += " AND e.school_id = ?"
+		args = append(args, v)
+	}
+	if v := filters["city"]; v != "" {
+		query += " AND s.city = ?"
+		args = append(args, v)
+	}
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []untAnalyticsRow
+	for rows.Next() {
+		var r untAnalyticsRow
+		var year sql.NullInt64
+		var first, second string
+		var total, history, reading, mathLiteracy, firstScore, secondScore int
+		if err := rows.Scan(&r.SchoolID, &r.SchoolName, &r.City, &year, &r.ExamType, &first, &second,
+			&total, &history, &reading, &mathLiteracy, &firstScore, &secondScore); err != nil {
+			return nil, err
+		}
+		r.Year = int(year.Int64)
+		r.Pair = first + " / " + second
+		if pair := filters["subject_pair"]; pair != "" && !strings.EqualFold(pair, r.Pair) &&
+			!strings.EqualFold(pair, second+" / "+first) {
+			continue
+		}
+		r.Scores = map[string]int{
+			"total_score":           total,
+			"history_of_kazakhstan": history,
+			"reading_literacy":      reading,
+			"first_subject_score":   firstScore,
+			"second_subject_score":  secondScore,
+		}
+		if r.ExamType == "regular" {
+			r.Scores["mathematical_literacy"] = mathLiteracy
+		}
+		result = append(result, r)
+	}
+	return result, rows.Err()
+}
+
+// GetUNTDistribution возвращает гистограммы, процентили, медиану и
+// стандартное отклонение баллов ЕНТ. Фильтры: school_id, city, year,
+// exam_type, subject_pair ("Математика / Физика"), attempt_kind.
+// group_by (school, city, year, subject_pair, exam_type) разбивает
+// выборку на группы. Для сравнения всегда возвращается национальное
+// распределение по каждому типу экзамена за тот же год; при
+// group_by=year каждая группа сравнивается со своим годом.
+func (c *UNTScoreController) GetUNTDistribution(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := utils.VerifyToken(r)
+		if err != nil {
+			utils.RespondWithError(w, http.StatusUnauthorized, models.Error{Message: err.Error()})
+			return
+		}
+
+		q := r.URL.Query()
+		filters := map[string]string{
+			"school_id":    q.Get("school_id"),
+			"city":         strings.TrimSpace(q.Get("city")),
+			"year":         q.Get("year"),
+			"exam_type":    strings.ToLower(q.Get("exam_type")),
+			"subject_pair": strings.TrimSpace(q.Get("subject_pair")),
+			"attempt_kind": strings.ToLower(q.Get("attempt_kind")),
+		}
+		if filters["attempt_kind"] == "" {
+			filters["attempt_kind"] = "official"
+		}
+		if filters["attempt_kind"] != "official" && filters["attempt_kind"] != "trial" {
+			utils.RespondWithError(w, http.StatusBadRequest, models.Error{Message: "Некорректный вид попытки. Допустимые значения: official, trial"})
+			return
+		}
+		if et := filters["exam_type"]; et != "" && et != "regular" && et != "creative" {
+			utils.RespondWithError(w, http.StatusBadRequest, models.Error{Message: "Некорректный тип экзамена. Допустимые значения: regular, creative"})
+			return
+		}
+		if y := filters["year"]; y != "" {
+			if _, err := strconv.Atoi(y); err != nil {
+				utils.RespondWithError(w, http.StatusBadRequest, models.Error{Message: "Некорректный год"})
+				return
+			}
+		}
+
+		groupBy := q.Get("group_by")
+		switch groupBy {
+		case "", "city", "year", "subject_pair", "exam_type", "school":
+		default:
+			utils.RespondWithError(w, http.StatusBadRequest, models.Error{Message: "group_by: school, city, year, subject_pair или exam_type"})
+			return
+		}
+
+		// Данные одной школы видят её участники; разбивку по всем школам — суперадмин.
+		// Пробные попытки — внутренние данные школы, без school_id доступны только суперадмину.
+		var role string
+		db.QueryRow("SELECT role FROM users WHERE id = ?", userID).Scan(&role)
+		if schoolID := filters["school_id"]; schoolID != "" {
+			id, err := strconv.Atoi(schoolID)
+			if err != nil {
+				utils.RespondWithError(w, http.StatusBadRequest, models.Error{Message: "Некорректный ID школы"})
+				return
+			}
+			allowed, err := canViewSchool(db, userID, id)
+			if err != nil {
+				log.Printf("Ошибка при проверке прав: %v", err)
+				utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Не удалось проверить права пользователя"})
+				return
+			}
+			if !allowed {
+				utils.RespondWithError(w, http.StatusForbidden, models.Error{Message: "У вас нет прав для просмотра данных этой школы"})
+				return
+			}
+		} else if (groupBy == "school" || filters["attempt_kind"] == "trial") && role != "superadmin" {
+			utils.RespondWithError(w, http.StatusForbidden, models.Error{Message: "Эта выборка доступна только суперадмину"})
+			return
+		}
+
+		rows, err := loadUNTAnalyticsRows(db, filters)
+		if err != nil {
+			log.Printf("Ошибка при получении результатов ЕНТ: %v", err)
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Не удалось получить результаты ЕНТ"})
+			return
+		}
+
+		national, err := loadUNTAnalyticsRows(db, map[string]string{
+			"attempt_kind": "official",
+			"exam_type":    filters["exam_type"],
+			"year":         filters["year"],
+		})
+		if err != nil {
+			log.Printf("Ошибка при получении национальных результатов ЕНТ: %v", err)
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Не удалось получить результаты ЕНТ"})
+			return
+		}
+		nationalByType := map[string][]untAnalyticsRow{}
+		for _, r := range national {
+			nationalByType[r.ExamType] = append(nationalByType[r.ExamType], r)
+		}
+
+		// Отсортированные национальные total_score по типу экзамена;
+		// year = 0 — за все годы выборки
+		nationalTotals := map[string][]int{}
+		totalsFor := func(examType string, year int) []int {
+			key := examType + "/" + strconv.Itoa(year)
+			if totals, ok := nationalTotals[key]; ok {
+				return totals
+			}
+			totals := []int{}
+			for _, r := range nationalByType[examType] {
+				if year == 0 || r.Year == year {
+					totals = append(totals, r.Scores["total_score"])
+				}
+			}
+			sort.Ints(totals)
+			nationalTotals[key] = totals
+			return totals
+		}
+
+		makeGroup := func(key, label string, rows []untAnalyticsRow, year int) untDistributionGroup {
+			byType := map[string][]int{}
+			for _, r := range rows {
+				byType[r.ExamType] = append(byType[r.ExamType], r.Scores["total_score"])
+			}
+			percentiles := map[string]float64{}
+			for examType, totals := range byType {
+				sort.Ints(totals)
+				percentiles[examType] = percentileRank(totalsFor(examType, year), percentile(totals, 50))
+			}
+			return untDistributionGroup{
+				Key:                key,
+				Label:              label,
+				Components:         describeUNTRows(rows),
+				NationalPercentile: percentiles,
+			}
+		}
+
+		nationalDistribution := map[string]map[string]scoreDistribution{}
+		for examType, rows := range nationalByType {
+			nationalDistribution[examType] = describeUNTRows(rows)
+		}
+
+		response := map[string]interface{}{
+			"filters":  filters,
+			"overall":  makeGroup("overall", "", rows, 0),
+			"national": nationalDistribution,
+		}
+
+		if groupBy != "" {
+			var keys []string
+			buckets := map[string][]untAnalyticsRow{}
+			labels := map[string]string{}
+			for _, row := range rows {
+				var key, label string
+				switch groupBy {
+				case "school":
+					key, label = strconv.Itoa(row.SchoolID), row.SchoolName
+				case "city":
+					key = row.City
+				case "year":
+					key = strconv.Itoa(row.Year)
+				case "subject_pair":
+					key = row.Pair
+				case "exam_type":
+					key = row.ExamType
+				}
+				if _, ok := buckets[key]; !ok {
+					keys = append(keys, key)
+					labels[key] = label
+				}
+				buckets[key] = append(buckets[key], row)
+			}
+			sort.Strings(keys)
+
+			groups := make([]untDistributionGroup, 0, len(keys))
+			for _, key := range keys {
+				year := 0
+				if groupBy == "year" {
+					year, _ = strconv.Atoi(key)
+				}
+				groups = append(groups, makeGroup(key, labels[key], buckets[key], year))
+			}
+			response["group_by"] = groupBy
+			response["groups"] = groups
+		}
+
+		utils.ResponseJSON(w, response)
+	}
+}
@@ -0,0 +1,101 @@
+package controllers
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestPercentile(t *testing.T) {
+	sorted := []int{10, 20, 30, 40}
+	tests := []struct {
+		name   string
+		sorted []int
+		p      float64
+		want   float64
+	}{
+		{"empty", nil, 50, 0},
+		{"single value", []int{7}, 90, 7},
+		{"minimum", sorted, 0, 10},
+		{"maximum", sorted, 100, 40},
+		{"median interpolates", sorted, 50, 25},
+		{"quartile", sorted, 25, 17.5},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := percentile(tt.sorted, tt.p); got != tt.want {
+				t.Errorf("percentile(%v, %v) = %v, want %v", tt.sorted, tt.p, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPercentileRank(t *testing.T) {
+	sorted := []int{10, 20, 20, 40}
+	tests := []struct {
+		v    float64
+		want float64
+	}{
+		{5, 0},
+		{20, 50},
+		{30, 75},
+		{50, 100},
+	}
+	for _, tt := range tests {
+		if got := percentileRank(sorted, tt.v); got != tt.want {
+			t.Errorf("percentileRank(%v, %v) = %v, want %v", sorted, tt.v, got, tt.want)
+		}
+	}
+	if got := percentileRank(nil, 10); got != 0 {
+		t.Errorf("percentileRank(nil) = %v, want 0", got)
+	}
+}
+
+func TestDescribeScores(t *testing.T) {
+	tests := []struct {
+		name     string
+		values   []int
+		binWidth int
+		want     scoreDistribution
+	}{
+		{
+			name:     "empty",
+			values:   nil,
+			binWidth: 10,
+			want:     scoreDistribution{Percentiles: map[string]float64{}, Histogram: []histogramBin{}},
+		},
+		{
+			name:     "unsorted input",
+			values:   []int{95, 101, 88, 120, 101},
+			binWidth: 10,
+			want: scoreDistribution{
+				Count:  5,
+				Mean:   101,
+				Median: 101,
+				StdDev: 10.64,
+				Min:    88,
+				Max:    120,
+				Percentiles: map[string]float64{
+					"p10": 90.8, "p25": 95, "p75": 101, "p90": 112.4,
+				},
+				Histogram: []histogramBin{
+					{From: 80, To: 89, Count: 1},
+					{From: 90, To: 99, Count: 1},
+					{From: 100, To: 109, Count: 2},
+					{From: 120, To: 129, Count: 1},
+				},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			values := append([]int(nil), tt.values...)
+			got := describeScores(tt.values, tt.binWidth)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("describeScores() = %+v, want %+v", got, tt.want)
+			}
+			if !reflect.DeepEqual(values, tt.values) {
+				t.Errorf("describeScores modified its input: %v", tt.values)
+			}
+		})
+	}
+}
@@ -0,0 +1,362 @@
+package controllers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"path/filepath"
+	"ranking-school/models"
+	"ranking-school/utils"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+// Поля файла импорта результатов ЕНТ
+var untImportFields = []string{
+	"iin", "exam_type", "attempt_kind", "date", "first_subject", "first_subject_score",
+	"second_subject", "second_subject_score", "history_of_kazakhstan",
+	"mathematical_literacy", "reading_literacy", "total_score", "document_url",
+}
+
+var untImportRequired = []string{
+	"iin", "first_subject", "first_subject_score", "second_subject",
+	"second_subject_score", "history_of_kazakhstan", "reading_literacy",
+}
+
+// ImportUNTResults загружает результаты ЕНТ всего выпуска из CSV/XLSX.
+// Строки сопоставляются с учениками школы по ИИН. По умолчанию dry-run;
+// с ?dry_run=false все результаты сохраняются в одной транзакции.
+// Поля формы exam_type, attempt_kind и date задают значения для строк, где они пустые.
+func (c *UNTScoreController) ImportUNTResults(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := utils.VerifyToken(r)
+		if err != nil {
+			utils.RespondWithError(w, http.StatusUnauthorized, models.Error{Message: err.Error()})
+			return
+		}
+
+		schoolID, err := strconv.Atoi(mux.Vars(r)["school_id"])
+		if err != nil {
+			utils.RespondWithError(w, http.StatusBadRequest, models.Error{Message: "Некорректный school_id в URL"})
+			return
+		}
+
+		var userRole string
+		err = db.QueryRow("SELECT role FROM users WHERE id = ?", userID).Scan(&userRole)
+		if err != nil {
+			log.Println("Ошибка при получении роли пользователя:", err)
+			utils.RespondWithError(w, http.StatusForbidden, models.Error{Message: "Не удалось получить роль пользователя"})
+			return
+		}
+		switch userRole {
+		case "superadmin":
+		case "schooladmin":
+			allowed, err := hasSchoolPermission(db, userID, schoolID, PermissionManageUNT)
+			if err != nil {
+				log.Printf("Ошибка при проверке прав участника школы: %v", err)
+				utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Не удалось проверить права пользователя"})
+				return
+			}
+			if !allowed {
+				utils.RespondWithError(w, http.StatusForbidden, models.Error{Message: "У вас нет прав на загрузку результатов ЕНТ для этой школы"})
+				return
+			}
+		default:
+			utils.RespondWithError(w, http.StatusForbidden, models.Error{Message: "У вас нет прав на загрузку результатов ЕНТ"})
+			return
+		}
+
+		if err := r.ParseMultipartForm(maxImportFileSize); err != nil {
+			utils.RespondWithError(w, http.StatusBadRequest, models.Error{Message: "Ошибка при обработке данных формы"})
+			return
+		}
+		file, header, err := r.FormFile("file")
+		if err != nil {
+			utils.RespondWithError(w, http.StatusBadRequest, models.Error{Message: "Необходимо загрузить файл"})
+			return
+		}
+		defer file.Close()
+
+		mapping := map[string]string{}
+		if raw := r.FormValue("mapping"); raw != "" {
+			if err := json.Unmarshal([]byte(raw), &mapping); err != nil {
+				utils.RespondWithError(w, http.StatusBadRequest, models.Error{Message: "Некорректное сопоставление колонок"})
+				return
+			}
+		}
+		defaultExamType := strings.ToLower(r.FormValue("exam_type"))
+		defaultAttemptKind := strings.ToLower(r.FormValue("attempt_kind"))
+		defaultDate := r.FormValue("date")
+		dryRun := r.URL.Query().Get("dry_run") != "false"
+
+		var records [][]string
+		switch strings.ToLower(filepath.Ext(header.Filename)) {
+		case ".csv":
+			records, err = readImportCSV(file)
+		case ".xlsx":
+			records, err = readImportXLSX(file)
+		default:
+			utils.RespondWithError(w, http.StatusBadRequest, models.Error{Message: "Поддерживаются только файлы .csv и .xlsx"})
+			return
+		}
+		if err != nil {
+			log.Println("Ошибка чтения файла импорта ЕНТ:", err)
+			utils.RespondWithError(w, http.StatusBadRequest, models.Error{Message: "Не удалось прочитать файл: " + err.Error()})
+			return
+		}
+		if len(records) < 2 {
+			utils.RespondWithError(w, http.StatusBadRequest, models.Error{Message: "Файл должен содержать заголовок и хотя бы одну строку"})
+			return
+		}
+
+		columns, err := resolveImportColumns(records[0], mapping, untImportFields, untImportRequired)
+		if err != nil {
+			utils.RespondWithError(w, http.StatusBadRequest, models.Error{Message: err.Error()})
+			return
+		}
+
+		catalog, err := loadUNTCatalog(db)
+		if err != nil {
+			log.Printf("Ошибка при загрузке справочника предметов ЕНТ: %v", err)
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Не удалось загрузить справочник предметов ЕНТ"})
+			return
+		}
+
+		exams, iins, rowNumbers, rowErrors := parseUNTImportRows(records[1:], columns, catalog, defaultExamType, defaultAttemptKind, defaultDate)
+
+		matchErrors, err := matchUNTImportStudents(db, schoolID, exams, iins, rowNumbers)
+		if err != nil {
+			log.Println("Ошибка при сопоставлении учеников по ИИН:", err)
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Не удалось сопоставить учеников"})
+			return
+		}
+		rowErrors = append(rowErrors, matchErrors...)
+
+		invalidRows := map[int]bool{}
+		for _, e := range rowErrors {
+			invalidRows[e.Row] = true
+		}
+		validRows := 0
+		for _, row := range rowNumbers {
+			if !invalidRows[row] {
+				validRows++
+			}
+		}
+
+		report := map[string]interface{}{
+			"dry_run":      dryRun,
+			"total_rows":   validRows + len(invalidRows),
+			"valid_rows":   validRows,
+			"invalid_rows": len(invalidRows),
+			"errors":       rowErrors,
+		}
+
+		if dryRun {
+			utils.ResponseJSON(w, report)
+			return
+		}
+		if len(rowErrors) > 0 {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusUnprocessableEntity)
+			json.NewEncoder(w).Encode(report)
+			return
+		}
+
+		tx, err := db.Begin()
+		if err != nil {
+			log.Println("Ошибка при начале транзакции:", err)
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Не удалось начать транзакцию"})
+			return
+		}
+		defer tx.Rollback()
+
+		stmt, err := tx.Prepare(`INSERT INTO UNT_Exams (
+			exam_type, attempt_kind, first_subject, first_subject_score, second_subject, second_subject_score,
+			history_of_kazakhstan, mathematical_literacy, reading_literacy,
+			total_score, student_id, school_id, document_url, date
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`)
+		if err != nil {
+			log.Println("Ошибка при подготовке запроса:", err)
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Не удалось импортировать результаты"})
+			return
+		}
+		defer stmt.Close()
+
+		for i, exam := range exams {
+			_, err := stmt.Exec(exam.ExamType, exam.AttemptKind, exam.FirstSubject, exam.FirstSubjectScore, exam.SecondSubject, exam.SecondSubjectScore,
+				exam.HistoryOfKazakhstan, exam.MathematicalLiteracy, exam.ReadingLiteracy,
+				exam.TotalScore, exam.StudentID, schoolID, exam.DocumentURL, exam.Date)
+			if err != nil {
+				log.Printf("Ошибка при сохранении результата ЕНТ в строке %d: %v", rowNumbers[i], err)
+				utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: fmt.Sprintf("Не удалось сохранить результат в строке %d", rowNumbers[i])})
+				return
+			}
+		}
+
+		if err := tx.Commit(); err != nil {
+			log.Println("Ошибка при фиксации импорта ЕНТ:", err)
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Не удалось импортировать результаты"})
+			return
+		}
+
+		log.Printf("Импортировано %d результатов ЕНТ для школы %d пользователем %d", len(exams), schoolID, userID)
+		report["imported"] = len(exams)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(report)
+	}
+}
+
+// parseUNTImportRows validates each row against the subject catalog. The
+// student is identified only by IIN here; matchUNTImportStudents resolves it.
+func parseUNTImportRows(records [][]string, columns map[string]int, catalog *untCatalog, defaultExamType, defaultAttemptKind, defaultDate string) ([]models.UNTExam, []string, []int, []importRowError) {
+	var exams []models.UNTExam
+	var iins []string
+	var rowNumbers []int
+	var rowErrors []importRowError
+	seenIIN := map[string]int{}
+
+	for i, record := range records {
+		rowNum := i + 2
+		cell := func(field string) string {
+			idx, ok := columns[field]
+			if !ok || idx >= len(record) {
+				return ""
+			}
+			return strings.TrimSpace(record[idx])
+		}
+
+		empty := true
+		for _, v := range record {
+			if strings.TrimSpace(v) != "" {
+				empty = false
+				break
+			}
+		}
+		if empty {
+			continue
+		}
+
+		var errs []importRowError
+		addErr := func(field, msg string) {
+			errs = append(errs, importRowError{Row: rowNum, Field: field, Message: msg})
+		}
+		score := func(field string) int {
+			raw := cell(field)
+			if raw == "" {
+				return 0
+			}
+			v, err := strconv.Atoi(raw)
+			if err != nil {
+				addErr(field, "Балл должен быть целым числом")
+			}
+			return v
+		}
+
+		exam := models.UNTExam{
+			ExamType:             strings.ToLower(cell("exam_type")),
+			AttemptKind:          strings.ToLower(cell("attempt_kind")),
+			FirstSubject:         cell("first_subject"),
+			SecondSubject:        cell("second_subject"),
+			FirstSubjectScore:    score("first_subject_score"),
+			SecondSubjectScore:   score("second_subject_score"),
+			HistoryOfKazakhstan:  score("history_of_kazakhstan"),
+			MathematicalLiteracy: score("mathematical_literacy"),
+			ReadingLiteracy:      score("reading_literacy"),
+			DocumentURL:          cell("document_url"),
+		}
+		iin := cell("iin")
+		clientTotal := score("total_score")
+
+		if exam.ExamType == "" {
+			exam.ExamType = defaultExamType
+		}
+		if exam.ExamType == "" {
+			exam.ExamType = "regular"
+		}
+		if exam.ExamType != "regular" && exam.ExamType != "creative" {
+			addErr("exam_type", "Допустимые значения: regular, creative")
+		}
+
+		if exam.AttemptKind == "" {
+			exam.AttemptKind = defaultAttemptKind
+		}
+		if exam.AttemptKind == "" {
+			exam.AttemptKind = "official"
+		}
+		if exam.AttemptKind != "official" && exam.AttemptKind != "trial" {
+			addErr("attempt_kind", "Допустимые значения: official, trial")
+		}
+
+		rawDate := cell("date")
+		if rawDate == "" {
+			rawDate = defaultDate
+		}
+		if date, ok := parseImportDate(rawDate); ok {
+			exam.Date = date.Format("2006-01-02")
+		} else {
+			addErr("date", "Дата должна быть в формате ГГГГ-ММ-ДД или ДД.ММ.ГГГГ")
+		}
+
+		if _, err := utils.ParseIIN(iin); err != nil {
+			addErr("iin", err.Error())
+		} else if prev, ok := seenIIN[iin]; ok {
+			addErr("iin", fmt.Sprintf("ИИН повторяется, уже указан в строке %d", prev))
+		} else {
+			seenIIN[iin] = rowNum
+		}
+
+		if len(errs) == 0 {
+			if msg := catalog.validate(&exam, clientTotal); msg != "" {
+				addErr("", msg)
+			}
+		}
+
+		if len(errs) > 0 {
+			rowErrors = append(rowErrors, errs...)
+			continue
+		}
+		exams = append(exams, exam)
+		iins = append(iins, iin)
+		rowNumbers = append(rowNumbers, rowNum)
+	}
+
+	return exams, iins, rowNumbers, rowErrors
+}
+
+// matchUNTImportStudents fills StudentID by IIN and reports rows whose IIN is
+// unknown, belongs to another school, or already has a result on that date.
+func matchUNTImportStudents(db *sql.DB, schoolID int, exams []models.UNTExam, iins []string, rowNumbers []int) ([]importRowError, error) {
+	var errs []importRowError
+	for i := range exams {
+		exam := &exams[i]
+
+		var studentID, studentSchoolID int
+		err := db.QueryRow("SELECT student_id, COALESCE(school_id, 0) FROM student WHERE iin = ?", iins[i]).Scan(&studentID, &studentSchoolID)
+		if err == sql.ErrNoRows {
+			errs = append(errs, importRowError{Row: rowNumbers[i], Field: "iin", Message: "Ученик с таким ИИН не найден"})
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		if studentSchoolID != schoolID {
+			errs = append(errs, importRowError{Row: rowNumbers[i], Field: "iin", Message: "Ученик с таким ИИН учится в другой школе"})
+			continue
+		}
+		exam.StudentID = studentID
+
+		var exists bool
+		err = db.QueryRow("SELECT EXISTS(SELECT 1 FROM UNT_Exams WHERE student_id = ? AND date = ?)", studentID, exam.Date).Scan(&exists)
+		if err != nil {
+			return nil, err
+		}
+		if exists {
+			errs = append(errs, importRowError{Row: rowNumbers[i], Field: "date", Message: "У ученика уже есть результат ЕНТ за эту дату"})
+		}
+	}
+	return errs, nil
+}
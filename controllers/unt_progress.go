@@ -0,0 +1,196 @@
+package controllers
+
+import (
+	"database/sql"
+	"log"
+	"net/http"
+	"ranking-school/models"
+	"ranking-school/utils"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+type untProgressPoint struct {
+	ExamID      int    `json:"exam_id"`
+	Date        string `json:"date"`
+	AttemptKind string `json:"attempt_kind"`
+	Score       int    `json:"score"`
+}
+
+type untComponentTrend struct {
+	Component string             `json:"component"`
+	MaxScore  int                `json:"max_score"`
+	First     int                `json:"first"`
+	Last      int                `json:"last"`
+	Best      int                `json:"best"`
+	Change    int                `json:"change"`
+	Points    []untProgressPoint `json:"points"`
+}
+
+// untProgressComponents returns the components of an exam with their
+// maximum scores taken from the subject catalog.
+func untProgressComponents(catalog *untCatalog, exam models.UNTExam) []untComponent {
+	totalMax := 140
+	if exam.ExamType == "creative" {
+		totalMax = 120
+	}
+	first, _ := catalog.subject(exam.FirstSubject)
+	second, _ := catalog.subject(exam.SecondSubject)
+
+	components := []untComponent{
+		{"total_score", exam.TotalScore, totalMax},
+		{"history_of_kazakhstan", exam.HistoryOfKazakhstan, catalog.maxScore("history_of_kazakhstan")},
+		{"reading_literacy", exam.ReadingLiteracy, catalog.maxScore("reading_literacy")},
+		{"first_subject_score", exam.FirstSubjectScore, first.MaxScore},
+		{"second_subject_score", exam.SecondSubjectScore, second.MaxScore},
+	}
+	if exam.ExamType == "regular" {
+		components = append(components, untComponent{"mathematical_literacy", exam.MathematicalLiteracy, catalog.maxScore("mathematical_literacy")})
+	}
+	return components
+}
+
+// buildUNTProgress собирает попытки ученика одного типа экзамена
+// (по умолчанию — типа последней попытки) и считает динамику по компонентам.
+func buildUNTProgress(db *sql.DB, studentID int, examType string) (map[string]interface{}, error) {
+	if examType == "" {
+		err := db.QueryRow("SELECT exam_type FROM UNT_Exams WHERE student_id = ? ORDER BY date DESC, id DESC LIMIT 1", studentID).Scan(&examType)
+		if err == sql.ErrNoRows {
+			return map[string]interface{}{
+				"student_id": studentID,
+				"attempts":   []models.UNTExam{},
+				"trends":     []untComponentTrend{},
+			}, nil
+		} else if err != nil {
+			return nil, err
+		}
+	}
+
+	rows, err := db.Query(`
+		SELECT id, exam_type, attempt_kind, COALESCE(first_subject, ''), COALESCE(first_subject_score, 0),
+		       COALESCE(second_subject, ''), COALESCE(second_subject_score, 0), COALESCE(history_of_kazakhstan, 0),
+		       COALESCE(mathematical_literacy, 0), COALESCE(reading_literacy, 0), COALESCE(total_score, 0),
+		       COALESCE(CAST(date AS CHAR), '')
+		FROM UNT_Exams
+		WHERE student_id = ? AND exam_type = ?
+		ORDER BY date, id`, studentID, examType)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	attempts := []models.UNTExam{}
+	for rows.Next() {
+		exam := models.UNTExam{StudentID: studentID}
+		if err := rows.Scan(&exam.ID, &exam.ExamType, &exam.AttemptKind, &exam.FirstSubject, &exam.FirstSubjectScore,
+			&exam.SecondSubject, &exam.SecondSubjectScore, &exam.HistoryOfKazakhstan,
+			&exam.MathematicalLiteracy, &exam.ReadingLiteracy, &exam.TotalScore, &exam.Date); err != nil {
+			return nil, err
+		}
+		exam.Date = firstN(exam.Date, 10)
+		attempts = append(attempts, exam)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	catalog, err := loadUNTCatalog(db)
+	if err != nil {
+		return nil, err
+	}
+
+	trends := []untComponentTrend{}
+	index := map[string]int{}
+	for _, exam := range attempts {
+		for _, comp := range untProgressComponents(catalog, exam) {
+			i, ok := index[comp.field]
+			if !ok {
+				trends = append(trends, untComponentTrend{Component: comp.field, MaxScore: comp.max, First: comp.value, Best: comp.value})
+				i = len(trends) - 1
+				index[comp.field] = i
+			}
+			t := &trends[i]
+			t.Points = append(t.Points, untProgressPoint{ExamID: exam.ID, Date: exam.Date, AttemptKind: exam.AttemptKind, Score: comp.value})
+			t.Last = comp.value
+			t.Change = t.Last - t.First
+			if comp.value > t.Best {
+				t.Best = comp.value
+			}
+		}
+	}
+
+	return map[string]interface{}{
+		"student_id": studentID,
+		"exam_type":  examType,
+		"attempts":   attempts,
+		"trends":     trends,
+	}, nil
+}
+
+// GetMyUNTProgress — динамика пробных и итоговых ЕНТ текущего ученика.
+func (c *UNTScoreController) GetMyUNTProgress(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		studentID, err := utils.VerifyToken(r)
+		if err != nil {
+			utils.RespondWithError(w, http.StatusUnauthorized, models.Error{Message: "Unauthorized"})
+			return
+		}
+
+		progress, err := buildUNTProgress(db, studentID, strings.ToLower(r.URL.Query().Get("exam_type")))
+		if err != nil {
+			log.Printf("Ошибка при расчёте динамики ЕНТ: %v", err)
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Не удалось получить динамику ЕНТ"})
+			return
+		}
+		utils.ResponseJSON(w, progress)
+	}
+}
+
+// GetStudentUNTProgress — динамика ЕНТ ученика для администратора школы.
+func (c *UNTScoreController) GetStudentUNTProgress(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := utils.VerifyToken(r)
+		if err != nil {
+			utils.RespondWithError(w, http.StatusUnauthorized, models.Error{Message: err.Error()})
+			return
+		}
+
+		studentID, err := strconv.Atoi(mux.Vars(r)["student_id"])
+		if err != nil {
+			utils.RespondWithError(w, http.StatusBadRequest, models.Error{Message: "Некорректный ID ученика"})
+			return
+		}
+
+		var schoolID int
+		err = db.QueryRow("SELECT COALESCE(school_id, 0) FROM student WHERE student_id = ?", studentID).Scan(&schoolID)
+		if err == sql.ErrNoRows {
+			utils.RespondWithError(w, http.StatusNotFound, models.Error{Message: "Ученик не найден"})
+			return
+		} else if err != nil {
+			log.Printf("Ошибка при получении ученика: %v", err)
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Не удалось получить данные ученика"})
+			return
+		}
+
+		allowed, err := canViewSchool(db, userID, schoolID)
+		if err != nil {
+			log.Printf("Ошибка при проверке прав: %v", err)
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Не удалось проверить права пользователя"})
+			return
+		}
+		if !allowed {
+			utils.RespondWithError(w, http.StatusForbidden, models.Error{Message: "У вас нет прав для просмотра результатов этого ученика"})
+			return
+		}
+
+		progress, err := buildUNTProgress(db, studentID, strings.ToLower(r.URL.Query().Get("exam_type")))
+		if err != nil {
+			log.Printf("Ошибка при расчёте динамики ЕНТ: %v", err)
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Не удалось получить динамику ЕНТ"})
+			return
+		}
+		utils.ResponseJSON(w, progress)
+	}
+}
@@ -2,6 +2,7 @@ package controllers
 
 import (
 	"database/sql"
+	"errors"
 	"log"
 	"math"
 	"net/http"
@@ -14,193 +15,121 @@ import (
 
 type UNTScoreController struct{}
 
-func (usc *UNTScoreController) GetTotalScoreForSchool(db *sql.DB) http.HandlerFunc {
-	return func(w http.ResponseWriter, r *http.Request) {
-		schoolID := r.URL.Query().Get("school_id")
-		if schoolID == "" {
-			utils.RespondWithError(w, http.StatusBadRequest, models.Error{Message: "school_id is required"})
-			return
-		}
+// schoolIDFromRequest берёт school_id из пути, а если его там нет — из query.
+// Старые маршруты передают школу и так, и так.
+func schoolIDFromRequest(r *http.Request) (int, error) {
+	raw := mux.Vars(r)["school_id"]
+	if raw == "" {
+		raw = r.URL.Query().Get("school_id")
+	}
+	if raw == "" {
+		return 0, errors.New("school_id is required")
+	}
+	schoolID, err := strconv.Atoi(raw)
+	if err != nil || schoolID <= 0 {
+		return 0, errors.New("Invalid school ID")
+	}
+	return schoolID, nil
+}
+
+// averageRegularUNT — средние баллы итоговых обычных ЕНТ школы по компонентам.
+func averageRegularUNT(db *sql.DB, schoolID int) (map[string]float64, int, error) {
+	var count int
+	var first, second, history, mathLiteracy, reading, total sql.NullFloat64
+	err := db.QueryRow(`
+		SELECT COUNT(*), AVG(first_subject_score), AVG(second_subject_score), AVG(history_of_kazakhstan),
+		       AVG(mathematical_literacy), AVG(reading_literacy), AVG(total_score)
+		FROM UNT_Exams
+		WHERE school_id = ? AND exam_type = 'regular' AND attempt_kind = 'official'`, schoolID).
+		Scan(&count, &first, &second, &history, &mathLiteracy, &reading, &total)
+	if err != nil {
+		return nil, 0, err
+	}
+	return map[string]float64{
+		"avg_first_subject_score":   first.Float64,
+		"avg_second_subject_score":  second.Float64,
+		"avg_history_of_kazakhstan": history.Float64,
+		"avg_mathematical_literacy": mathLiteracy.Float64,
+		"avg_reading_literacy":      reading.Float64,
+		"avg_total_score":           total.Float64,
+	}, count, nil
+}
 
-		// Query for total score for first type
-		queryFirstType := `
-            SELECT 
-                SUM(COALESCE(fs.score, 0) + COALESCE(ss.score, 0) + COALESCE(ft.history_of_kazakhstan, 0) + 
-                    COALESCE(ft.mathematical_literacy, 0) + COALESCE(ft.reading_literacy, 0)) AS total_score
-            FROM UNT_Score us
-            JOIN Student s ON us.student_id = s.student_id
-            LEFT JOIN UNT_Type ut ON us.unt_type_id = ut.unt_type_id
-            LEFT JOIN First_Type ft ON ut.first_type_id = ft.first_type_id
-            LEFT JOIN First_Subject fs ON ft.first_subject_id = fs.first_subject_id
-            LEFT JOIN Second_Subject ss ON ft.second_subject_id = ss.second_subject_id
-            WHERE s.school_id = ? AND us.unt_type_id = 1
-        `
+// averageCreativeUNT — средний итоговый балл творческих ЕНТ школы.
+func averageCreativeUNT(db *sql.DB, schoolID int) (float64, int, error) {
+	var count int
+	var avg sql.NullFloat64
+	err := db.QueryRow(`
+		SELECT COUNT(*), AVG(total_score)
+		FROM UNT_Exams
+		WHERE school_id = ? AND exam_type = 'creative' AND attempt_kind = 'official'`, schoolID).Scan(&count, &avg)
+	return avg.Float64, count, err
+}
 
-		var totalScoreFirstType sql.NullFloat64
-		err := db.QueryRow(queryFirstType, schoolID).Scan(&totalScoreFirstType)
+func (usc *UNTScoreController) GetTotalScoreForSchool(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		schoolID, err := schoolIDFromRequest(r)
 		if err != nil {
-			log.Println("SQL Error:", err)
-			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Failed to get total score for first type"})
+			utils.RespondWithError(w, http.StatusBadRequest, models.Error{Message: err.Error()})
 			return
 		}
 
-		// Query for total score for second type
-		querySecondType := `
-            SELECT 
-                SUM(COALESCE(st.history_of_kazakhstan_creative, 0) + COALESCE(st.reading_literacy_creative, 0) + 
-                    COALESCE(st.creative_exam1, 0) + COALESCE(st.creative_exam2, 0)) AS total_score_creative
-            FROM UNT_Score us
-            JOIN Student s ON us.student_id = s.student_id
-            LEFT JOIN UNT_Type ut ON us.unt_type_id = ut.unt_type_id
-            LEFT JOIN Second_Type st ON ut.second_type_id = st.second_type_id
-            WHERE s.school_id = ? AND us.unt_type_id = 2
-        `
-
-		var totalScoreSecondType sql.NullFloat64
-		err = db.QueryRow(querySecondType, schoolID).Scan(&totalScoreSecondType)
+		var totalRegular, totalCreative sql.NullFloat64
+		err = db.QueryRow(`
+			SELECT SUM(CASE WHEN exam_type = 'regular' THEN total_score END),
+			       SUM(CASE WHEN exam_type = 'creative' THEN total_score END)
+			FROM UNT_Exams
+			WHERE school_id = ? AND attempt_kind = 'official'`, schoolID).Scan(&totalRegular, &totalCreative)
 		if err != nil {
 			log.Println("SQL Error:", err)
-			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Failed to get total score for second type"})
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Failed to get total score"})
 			return
 		}
 
-		// Combine total score results and send the response
-		var totalScore float64
-		if totalScoreFirstType.Valid {
-			totalScore += totalScoreFirstType.Float64
-		}
-		if totalScoreSecondType.Valid {
-			totalScore += totalScoreSecondType.Float64
-		}
-
 		utils.ResponseJSON(w, map[string]interface{}{
-			"total_score_first_type":  totalScoreFirstType.Float64,
-			"total_score_second_type": totalScoreSecondType.Float64,
-			"total_score":             totalScore,
+			"total_score_first_type":  totalRegular.Float64,
+			"total_score_second_type": totalCreative.Float64,
+			"total_score":             totalRegular.Float64 + totalCreative.Float64,
 		})
 	}
 }
 func (usc *UNTScoreController) GetAverageRatingBySchool(db *sql.DB) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		// Получение school_id из query параметров
-		schoolIDStr := r.URL.Query().Get("school_id")
-		if schoolIDStr == "" {
-			utils.RespondWithError(w, http.StatusBadRequest, models.Error{Message: "school_id is required"})
-			return
-		}
-
-		log.Printf("Получен school_id из query: %s", schoolIDStr)
-
-		schoolID, err := strconv.Atoi(schoolIDStr)
-		if err != nil || schoolID <= 0 {
-			utils.RespondWithError(w, http.StatusBadRequest, models.Error{Message: "Invalid school ID"})
+		schoolID, err := schoolIDFromRequest(r)
+		if err != nil {
+			utils.RespondWithError(w, http.StatusBadRequest, models.Error{Message: err.Error()})
 			return
 		}
 
-		// SQL-запрос на получение средних значений
-		query := `
-			SELECT 
-				AVG(CASE WHEN ft.first_subject_score IS NOT NULL THEN ft.first_subject_score ELSE 0 END) AS avg_first_subject_score,
-				AVG(CASE WHEN ft.second_subject_score IS NOT NULL THEN ft.second_subject_score ELSE 0 END) AS avg_second_subject_score,
-				AVG(CASE WHEN ft.history_of_kazakhstan IS NOT NULL THEN ft.history_of_kazakhstan ELSE 0 END) AS avg_history_of_kazakhstan,
-				AVG(CASE WHEN ft.mathematical_literacy IS NOT NULL THEN ft.mathematical_literacy ELSE 0 END) AS avg_mathematical_literacy,
-				AVG(CASE WHEN ft.reading_literacy IS NOT NULL THEN ft.reading_literacy ELSE 0 END) AS avg_reading_literacy,
-				AVG(CASE WHEN ft.first_subject_score IS NOT NULL AND ft.second_subject_score IS NOT NULL AND 
-						 ft.history_of_kazakhstan IS NOT NULL AND ft.mathematical_literacy IS NOT NULL AND 
-						 ft.reading_literacy IS NOT NULL 
-						 THEN (ft.first_subject_score + ft.second_subject_score + ft.history_of_kazakhstan + 
-							   ft.mathematical_literacy + ft.reading_literacy) ELSE 0 END) AS avg_total_score
-			FROM First_Type ft
-			WHERE ft.school_id = ?;
-		`
-
-		row := db.QueryRow(query, schoolID)
-
-		// Переменные с null-защитой
-		var avgFirstSubjectScore, avgSecondSubjectScore, avgHistoryOfKazakhstan, avgMathematicalLiteracy, avgReadingLiteracy, avgTotalScore sql.NullFloat64
-
-		err = row.Scan(
-			&avgFirstSubjectScore,
-			&avgSecondSubjectScore,
-			&avgHistoryOfKazakhstan,
-			&avgMathematicalLiteracy,
-			&avgReadingLiteracy,
-			&avgTotalScore,
-		)
+		result, _, err := averageRegularUNT(db, schoolID)
 		if err != nil {
-			log.Println("SQL Error while scanning average rating result:", err)
+			log.Println("SQL Error while calculating average rating:", err)
 			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Failed to calculate average rating"})
 			return
 		}
 
-		// Подготовка ответа
-		result := map[string]float64{
-			"avg_first_subject_score":   avgFirstSubjectScore.Float64,
-			"avg_second_subject_score":  avgSecondSubjectScore.Float64,
-			"avg_history_of_kazakhstan": avgHistoryOfKazakhstan.Float64,
-			"avg_mathematical_literacy": avgMathematicalLiteracy.Float64,
-			"avg_reading_literacy":      avgReadingLiteracy.Float64,
-			"avg_total_score":           avgTotalScore.Float64,
-		}
-
 		utils.ResponseJSON(w, result)
 	}
 }
 func (c *UNTScoreController) GetAverageRatingSecondBySchool(db *sql.DB) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		// Извлекаем school_id из параметров URL
-		vars := mux.Vars(r)
-		schoolID, err := strconv.Atoi(vars["school_id"])
+		schoolID, err := schoolIDFromRequest(r)
 		if err != nil {
-			utils.RespondWithError(w, http.StatusBadRequest, models.Error{Message: "Invalid school ID"})
+			utils.RespondWithError(w, http.StatusBadRequest, models.Error{Message: err.Error()})
 			return
 		}
 
-		// Запрос для получения всех оценок по конкретной школе
-		query := `
-        SELECT 
-            history_of_kazakhstan_creative,
-            reading_literacy_creative,
-            creative_exam1,
-            creative_exam2
-        FROM Second_Type
-        WHERE school_id = ?`
-
-		rows, err := db.Query(query, schoolID)
+		averageRating, count, err := averageCreativeUNT(db, schoolID)
 		if err != nil {
 			log.Println("SQL Error:", err)
-			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Failed to get Second Types by School"})
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Failed to get creative UNT results by school"})
 			return
 		}
-		defer rows.Close()
-
-		var totalScore float64
-		var studentCount int
-
-		for rows.Next() {
-			var historyOfKazakhstanCreative, readingLiteracyCreative, creativeExam1, creativeExam2 sql.NullInt64
-
-			// Считываем данные для каждого экзамена
-			if err := rows.Scan(&historyOfKazakhstanCreative, &readingLiteracyCreative, &creativeExam1, &creativeExam2); err != nil {
-				log.Println("Scan Error:", err)
-				utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Failed to parse Second Types"})
-				return
-			}
-
-			// Вычисляем сумму оценок для каждого студента
-			totalScore += float64(historyOfKazakhstanCreative.Int64 + readingLiteracyCreative.Int64 + creativeExam1.Int64 + creativeExam2.Int64)
-			studentCount++
-		}
-
-		if studentCount == 0 {
+		if count == 0 {
 			utils.RespondWithError(w, http.StatusNotFound, models.Error{Message: "No students found for this school"})
 			return
 		}
 
-		// Рассчитываем средний балл
-		averageRating := totalScore / float64(studentCount)
-
-		// Возвращаем результат в формате JSON
 		utils.ResponseJSON(w, map[string]interface{}{
 			"average_rating": averageRating,
 		})
@@ -208,90 +137,47 @@ func (c *UNTScoreController) GetAverageRatingSecondBySchool(db *sql.DB) http.Han
 }
 func (usc *UNTScoreController) GetCombinedAverageRating(db *sql.DB) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-
-		// Извлекаем school_id из query параметра URL
-		schoolID := r.URL.Query().Get("school_id")
-		if schoolID == "" {
-			utils.RespondWithError(w, http.StatusBadRequest, models.Error{Message: "school_id is required"})
+		schoolID, err := schoolIDFromRequest(r)
+		if err != nil {
+			utils.RespondWithError(w, http.StatusBadRequest, models.Error{Message: err.Error()})
 			return
 		}
 
-		// 1. Получаем avg_total_score для первого типа
-		queryFirstType := `
-        SELECT 
-            AVG(CASE WHEN ft.first_subject_score IS NOT NULL THEN ft.first_subject_score ELSE 0 END) AS avg_first_subject_score,
-            AVG(CASE WHEN ft.second_subject_score IS NOT NULL THEN ft.second_subject_score ELSE 0 END) AS avg_second_subject_score,
-            AVG(CASE WHEN ft.history_of_kazakhstan IS NOT NULL THEN ft.history_of_kazakhstan ELSE 0 END) AS avg_history_of_kazakhstan,
-            AVG(CASE WHEN ft.mathematical_literacy IS NOT NULL THEN ft.mathematical_literacy ELSE 0 END) AS avg_mathematical_literacy,
-            AVG(CASE WHEN ft.reading_literacy IS NOT NULL THEN ft.reading_literacy ELSE 0 END) AS avg_reading_literacy,
-            AVG(CASE WHEN ft.first_subject_score IS NOT NULL AND ft.second_subject_score IS NOT NULL AND 
-                     ft.history_of_kazakhstan IS NOT NULL AND ft.mathematical_literacy IS NOT NULL AND 
-                     ft.reading_literacy IS NOT NULL 
-                     THEN (ft.first_subject_score + ft.second_subject_score + ft.history_of_kazakhstan + 
-                           ft.mathematical_literacy + ft.reading_literacy) ELSE 0 END) AS avg_total_score
-        FROM First_Type ft
-        WHERE ft.school_id = ?`
-
-		rowFirstType := db.QueryRow(queryFirstType, schoolID)
-
-		var avgFirstSubjectScore, avgSecondSubjectScore, avgHistoryOfKazakhstan, avgMathematicalLiteracy, avgReadingLiteracy, avgTotalScore float64
-		err := rowFirstType.Scan(&avgFirstSubjectScore, &avgSecondSubjectScore, &avgHistoryOfKazakhstan, &avgMathematicalLiteracy, &avgReadingLiteracy, &avgTotalScore)
+		regular, regularCount, err := averageRegularUNT(db, schoolID)
 		if err != nil {
 			log.Println("SQL Error:", err)
-			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Failed to get total score for first type"})
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Failed to get average score for regular UNT"})
 			return
 		}
-
-		// 2. Получаем average_rating для второго типа
-		querySecondType := `
-        SELECT 
-            history_of_kazakhstan_creative,
-            reading_literacy_creative,
-            creative_exam1,
-            creative_exam2
-        FROM Second_Type
-        WHERE school_id = ?`
-
-		rowsSecondType, err := db.Query(querySecondType, schoolID)
+		creative, creativeCount, err := averageCreativeUNT(db, schoolID)
 		if err != nil {
 			log.Println("SQL Error:", err)
-			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Failed to get Second Types by School"})
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Failed to get average score for creative UNT"})
 			return
 		}
-		defer rowsSecondType.Close()
-
-		var totalScoreSecondType float64
-		var studentCountSecondType int
-
-		for rowsSecondType.Next() {
-			var historyOfKazakhstanCreative, readingLiteracyCreative, creativeExam1, creativeExam2 sql.NullInt64
 
-			if err := rowsSecondType.Scan(&historyOfKazakhstanCreative, &readingLiteracyCreative, &creativeExam1, &creativeExam2); err != nil {
-				log.Println("Scan Error:", err)
-				utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Failed to parse Second Types"})
-				return
-			}
-
-			totalScoreSecondType += float64(historyOfKazakhstanCreative.Int64 + readingLiteracyCreative.Int64 + creativeExam1.Int64 + creativeExam2.Int64)
-			studentCountSecondType++
+		// Средние переводятся в проценты от максимума (140 и 120 баллов);
+		// если в школе сдавали только один вид ЕНТ, берётся он один.
+		var percents []float64
+		if regularCount > 0 {
+			percents = append(percents, regular["avg_total_score"]*100/140)
 		}
-
-		if studentCountSecondType == 0 {
+		if creativeCount > 0 {
+			percents = append(percents, creative*100/120)
+		}
+		if len(percents) == 0 {
 			utils.RespondWithError(w, http.StatusNotFound, models.Error{Message: "No students found for this school"})
 			return
 		}
+		var sum float64
+		for _, p := range percents {
+			sum += p
+		}
 
-		averageRatingSecondType := totalScoreSecondType / float64(studentCountSecondType)
-
-		// 3. Рассчитываем комбинированный рейтинг
-		combinedAverageRating := (((avgTotalScore * 100) / 140) + ((averageRatingSecondType * 100) / 120)) / 2
-		roundedCombinedRating := customRound(combinedAverageRating) // Применяем округление
-
-		// Возвращаем результат в формате JSON
 		utils.ResponseJSON(w, map[string]interface{}{
-			"avg_total_score_first_type":  avgTotalScore,
-			"avg_total_score_second_type": averageRatingSecondType,
-			"combined_average_rating":     roundedCombinedRating,
+			"avg_total_score_first_type":  regular["avg_total_score"],
+			"avg_total_score_second_type": creative,
+			"combined_average_rating":     customRound(sum / float64(len(percents))),
 		})
 	}
 }
@@ -0,0 +1,169 @@
+package controllers
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"net/http"
+	"ranking-school/models"
+	"ranking-school/utils"
+	"strings"
+)
+
+// untCatalog — справочник предметов ЕНТ, загруженный из БД.
+type untCatalog struct {
+	subjects []models.UNTSubject
+	pairs    []models.UNTSubjectPair
+	byKey    map[string]models.UNTSubject // по коду и по названию в нижнем регистре
+	allowed  map[string]bool              // exam_type|first|second
+}
+
+func loadUNTCatalog(db *sql.DB) (*untCatalog, error) {
+	c := &untCatalog{
+		byKey:   map[string]models.UNTSubject{},
+		allowed: map[string]bool{},
+	}
+
+	rows, err := db.Query("SELECT id, code, name, kind, max_score FROM unt_subjects ORDER BY kind, id")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var s models.UNTSubject
+		if err := rows.Scan(&s.ID, &s.Code, &s.Name, &s.Kind, &s.MaxScore); err != nil {
+			return nil, err
+		}
+		c.subjects = append(c.subjects, s)
+		c.byKey[strings.ToLower(s.Code)] = s
+		c.byKey[strings.ToLower(s.Name)] = s
+	}
+
+	pairRows, err := db.Query(`
+		SELECT p.id, p.exam_type, f.code, s.code
+		FROM unt_subject_pairs p
+		JOIN unt_subjects f ON f.id = p.first_subject_id
+		JOIN unt_subjects s ON s.id = p.second_subject_id
+		ORDER BY p.exam_type, p.id`)
+	if err != nil {
+		return nil, err
+	}
+	defer pairRows.Close()
+	for pairRows.Next() {
+		var p models.UNTSubjectPair
+		if err := pairRows.Scan(&p.ID, &p.ExamType, &p.FirstSubject, &p.SecondSubject); err != nil {
+			return nil, err
+		}
+		c.pairs = append(c.pairs, p)
+		c.allowed[p.ExamType+"|"+p.FirstSubject+"|"+p.SecondSubject] = true
+	}
+	return c, nil
+}
+
+type untComponent struct {
+	field string
+	value int
+	max   int
+}
+
+func (c *untCatalog) subject(nameOrCode string) (models.UNTSubject, bool) {
+	s, ok := c.byKey[strings.ToLower(strings.TrimSpace(nameOrCode))]
+	return s, ok
+}
+
+func (c *untCatalog) maxScore(code string) int {
+	return c.byKey[code].MaxScore
+}
+
+// validate checks the exam against the catalog, replaces subject names with
+// their catalog names and recomputes TotalScore. clientTotal is the total sent
+// by the client (0 if none); a mismatch is rejected. It returns a message for
+// the client or "" if the exam is valid.
+func (c *untCatalog) validate(exam *models.UNTExam, clientTotal int) string {
+	wantKind := "profile"
+	if exam.ExamType == "creative" {
+		wantKind = "creative"
+	}
+
+	first, ok := c.subject(exam.FirstSubject)
+	if !ok || first.Kind != wantKind {
+		return fmt.Sprintf("Предмет %q не найден в справочнике для экзамена типа %s", exam.FirstSubject, exam.ExamType)
+	}
+	second, ok := c.subject(exam.SecondSubject)
+	if !ok || second.Kind != wantKind {
+		return fmt.Sprintf("Предмет %q не найден в справочнике для экзамена типа %s", exam.SecondSubject, exam.ExamType)
+	}
+	if !c.allowed[exam.ExamType+"|"+first.Code+"|"+second.Code] && !c.allowed[exam.ExamType+"|"+second.Code+"|"+first.Code] {
+		return fmt.Sprintf("Недопустимое сочетание профильных предметов: %s и %s", first.Name, second.Name)
+	}
+	exam.FirstSubject = first.Name
+	exam.SecondSubject = second.Name
+
+	components := []untComponent{
+		{"first_subject_score", exam.FirstSubjectScore, first.MaxScore},
+		{"second_subject_score", exam.SecondSubjectScore, second.MaxScore},
+		{"history_of_kazakhstan", exam.HistoryOfKazakhstan, c.maxScore("history_of_kazakhstan")},
+		{"reading_literacy", exam.ReadingLiteracy, c.maxScore("reading_literacy")},
+	}
+	if exam.ExamType == "regular" {
+		components = append(components, untComponent{"mathematical_literacy", exam.MathematicalLiteracy, c.maxScore("mathematical_literacy")})
+	} else if exam.MathematicalLiteracy != 0 {
+		return "mathematical_literacy не сдаётся на творческом ЕНТ"
+	}
+
+	total := 0
+	for _, comp := range components {
+		if comp.value < 0 || comp.value > comp.max {
+			return fmt.Sprintf("Значение %s должно быть от 0 до %d", comp.field, comp.max)
+		}
+		total += comp.value
+	}
+
+	if clientTotal > 0 && clientTotal != total {
+		return fmt.Sprintf("total_score %d не совпадает с суммой баллов по компонентам (%d)", clientTotal, total)
+	}
+	exam.TotalScore = total
+	return ""
+}
+
+// validateUNTExamWithCatalog loads the catalog and validates the exam,
+// writing the HTTP error itself. It returns false if the handler should stop.
+func validateUNTExamWithCatalog(w http.ResponseWriter, db *sql.DB, exam *models.UNTExam, clientTotal int) bool {
+	catalog, err := loadUNTCatalog(db)
+	if err != nil {
+		log.Printf("Ошибка при загрузке справочника предметов ЕНТ: %v", err)
+		utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Не удалось загрузить справочник предметов ЕНТ"})
+		return false
+	}
+	if msg := catalog.validate(exam, clientTotal); msg != "" {
+		utils.RespondWithError(w, http.StatusBadRequest, models.Error{Message: msg})
+		return false
+	}
+	return true
+}
+
+// GetUNTSubjectCatalog возвращает предметы ЕНТ, их максимальные баллы
+// и допустимые сочетания профильных предметов.
+func (c *UNTScoreController) GetUNTSubjectCatalog(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		catalog, err := loadUNTCatalog(db)
+		if err != nil {
+			log.Printf("Ошибка при загрузке справочника предметов ЕНТ: %v", err)
+			utils.RespondWithError(w, http.StatusInternalServerError, models.Error{Message: "Не удалось загрузить справочник предметов ЕНТ"})
+			return
+		}
+
+		subjects := catalog.subjects
+		if subjects == nil {
+			subjects = []models.UNTSubject{}
+		}
+		pairs := catalog.pairs
+		if pairs == nil {
+			pairs = []models.UNTSubjectPair{}
+		}
+		utils.ResponseJSON(w, map[string]interface{}{
+			"subjects": subjects,
+			"pairs":    pairs,
+		})
+	}
+}
@@ -0,0 +1,105 @@
+package controllers
+
+import (
+	"ranking-school/models"
+	"strings"
+	"testing"
+)
+
+// newTestUNTCatalog собирает справочник как loadUNTCatalog, но из
+// части данных миграции 008 без обращения к БД.
+func newTestUNTCatalog() *untCatalog {
+	c := &untCatalog{
+		byKey:   map[string]models.UNTSubject{},
+		allowed: map[string]bool{},
+	}
+	c.subjects = []models.UNTSubject{
+		{ID: 1, Code: "history_of_kazakhstan", Name: "История Казахстана", Kind: "mandatory", MaxScore: 20},
+		{ID: 2, Code: "mathematical_literacy", Name: "Математическая грамотность", Kind: "mandatory", MaxScore: 10},
+		{ID: 3, Code: "reading_literacy", Name: "Грамотность чтения", Kind: "mandatory", MaxScore: 10},
+		{ID: 4, Code: "mathematics", Name: "Математика", Kind: "profile", MaxScore: 50},
+		{ID: 5, Code: "physics", Name: "Физика", Kind: "profile", MaxScore: 50},
+		{ID: 6, Code: "biology", Name: "Биология", Kind: "profile", MaxScore: 50},
+		{ID: 7, Code: "creative_exam_1", Name: "Творческий экзамен 1", Kind: "creative", MaxScore: 45},
+		{ID: 8, Code: "creative_exam_2", Name: "Творческий экзамен 2", Kind: "creative", MaxScore: 45},
+	}
+	for _, s := range c.subjects {
+		c.byKey[strings.ToLower(s.Code)] = s
+		c.byKey[strings.ToLower(s.Name)] = s
+	}
+	c.pairs = []models.UNTSubjectPair{
+		{ID: 1, ExamType: "regular", FirstSubject: "mathematics", SecondSubject: "physics"},
+		{ID: 2, ExamType: "creative", FirstSubject: "creative_exam_1", SecondSubject: "creative_exam_2"},
+	}
+	for _, p := range c.pairs {
+		c.allowed[p.ExamType+"|"+p.FirstSubject+"|"+p.SecondSubject] = true
+	}
+	return c
+}
+
+func regularExam(first, second string, firstScore, secondScore int) models.UNTExam {
+	return models.UNTExam{
+		ExamType:             "regular",
+		FirstSubject:         first,
+		FirstSubjectScore:    firstScore,
+		SecondSubject:        second,
+		SecondSubjectScore:   secondScore,
+		HistoryOfKazakhstan:  15,
+		ReadingLiteracy:      8,
+		MathematicalLiteracy: 7,
+	}
+}
+
+func TestUNTCatalogValidate(t *testing.T) {
+	creative := models.UNTExam{
+		ExamType: "creative", FirstSubject: "creative_exam_1", FirstSubjectScore: 45,
+		SecondSubject: "creative_exam_2", SecondSubjectScore: 45, HistoryOfKazakhstan: 20, ReadingLiteracy: 10,
+	}
+	creativeWithMath := creative
+	creativeWithMath.MathematicalLiteracy = 5
+
+	tests := []struct {
+		name        string
+		exam        models.UNTExam
+		clientTotal int
+		wantMsg     string // подстрока сообщения, "" — экзамен корректен
+		wantTotal   int
+		wantFirst   string
+	}{
+		{"names", regularExam("Математика", "Физика", 40, 35), 0, "", 105, "Математика"},
+		{"codes are replaced with names", regularExam("mathematics", "physics", 40, 35), 0, "", 105, "Математика"},
+		{"reversed pair", regularExam("физика", "математика", 35, 40), 0, "", 105, "Физика"},
+		{"matching client total", regularExam("Математика", "Физика", 40, 35), 105, "", 105, "Математика"},
+		{"creative maximum", creative, 120, "", 120, "Творческий экзамен 1"},
+		{"unknown subject", regularExam("Астрономия", "Физика", 40, 35), 0, "не найден в справочнике", 0, ""},
+		{"creative subject on regular exam", regularExam("creative_exam_1", "Физика", 40, 35), 0, "не найден в справочнике", 0, ""},
+		{"pair not allowed", regularExam("Математика", "Биология", 40, 35), 0, "Недопустимое сочетание", 0, ""},
+		{"score above maximum", regularExam("Математика", "Физика", 51, 35), 0, "first_subject_score должно быть от 0 до 50", 0, ""},
+		{"negative score", regularExam("Математика", "Физика", 40, -1), 0, "second_subject_score должно быть от 0 до 50", 0, ""},
+		{"math literacy on creative exam", creativeWithMath, 0, "mathematical_literacy не сдаётся", 0, ""},
+		{"client total mismatch", regularExam("Математика", "Физика", 40, 35), 110, "не совпадает", 0, ""},
+	}
+
+	catalog := newTestUNTCatalog()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			exam := tt.exam
+			msg := catalog.validate(&exam, tt.clientTotal)
+			if tt.wantMsg != "" {
+				if !strings.Contains(msg, tt.wantMsg) {
+					t.Fatalf("validate() = %q, want message containing %q", msg, tt.wantMsg)
+				}
+				return
+			}
+			if msg != "" {
+				t.Fatalf("validate() = %q, want no error", msg)
+			}
+			if exam.TotalScore != tt.wantTotal {
+				t.Errorf("TotalScore = %d, want %d", exam.TotalScore, tt.wantTotal)
+			}
+			if exam.FirstSubject != tt.wantFirst {
+				t.Errorf("FirstSubject = %q, want %q", exam.FirstSubject, tt.wantFirst)
+			}
+		})
+	}
+}
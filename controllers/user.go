@@ -666,6 +666,7 @@ func (c *Controller) Login(db *sql.DB) http.HandlerFunc {
 		var verified bool
 		var userFound bool = false
 		var login sql.NullString
+		var mustChangePassword bool
 
 		// For debugging
 		log.Println("Login attempt with:", user.Email, user.Phone, user.Login, user.Password)
@@ -697,11 +698,11 @@ func (c *Controller) Login(db *sql.DB) http.HandlerFunc {
 			// Check if student
 			var studentQuery string
 			if user.Email != "" {
-				studentQuery = "SELECT student_id, email, phone, password, first_name, last_name, grade, role, login FROM student WHERE email = ?"
+				studentQuery = "SELECT student_id, email, phone, password, first_name, last_name, grade, role, login, must_change_password FROM student WHERE email = ?"
 			} else if user.Phone != "" {
-				studentQuery = "SELECT student_id, email, phone, password, first_name, last_name, grade, role, login FROM student WHERE phone = ?"
+				studentQuery = "SELECT student_id, email, phone, password, first_name, last_name, grade, role, login, must_change_password FROM student WHERE phone = ?"
 			} else if user.Login != "" {
-				studentQuery = "SELECT student_id, email, phone, password, first_name, last_name, grade, role, login FROM student WHERE login = ?"
+				studentQuery = "SELECT student_id, email, phone, password, first_name, last_name, grade, role, login, must_change_password FROM student WHERE login = ?"
 			}
 
 			var studentGrade int
@@ -710,7 +711,7 @@ func (c *Controller) Login(db *sql.DB) http.HandlerFunc {
 
 			log.Println("Executing student query:", studentQuery, "with identifier:", identifier)
 			studentRow := db.QueryRow(studentQuery, identifier)
-			err = studentRow.Scan(&studentID, &studentEmail, &studentPhone, &studentPassword, &studentFirstName, &studentLastName, &studentGrade, &studentRole, &studentLogin)
+			err = studentRow.Scan(&studentID, &studentEmail, &studentPhone, &studentPassword, &studentFirstName, &studentLastName, &studentGrade, &studentRole, &studentLogin, &mustChangePassword)
 
 			if err == nil {
 				log.Println("Student found:", studentID, studentEmail, studentRole)
@@ -775,6 +776,17 @@ func (c *Controller) Login(db *sql.DB) http.HandlerFunc {
 			return
 		}
 
+		// Одноразовый пароль из импорта нужно сменить до первого входа
+		if mustChangePassword {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusForbidden)
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"message":              "Password change required. Set a new password via /api/auth/student/initial-password.",
+				"must_change_password": true,
+			})
+			return
+		}
+
 		// Set user properties before token generation
 		if email.Valid {
 			user.Email = email.String
@@ -2888,8 +2900,8 @@ func (c *Controller) GetSchoolAdminsWithoutSchools(db *sql.DB) http.HandlerFunc
 		query := `
             SELECT u.id, u.email, u.first_name, u.last_name, u.role 
             FROM users u 
-            LEFT JOIN Schools s ON u.email = s.school_admin_login
-            WHERE u.role = 'schooladmin' AND s.school_id IS NULL
+            LEFT JOIN school_members sm ON sm.user_id = u.id
+            WHERE u.role = 'schooladmin' AND sm.id IS NULL
         `
 		rows, err := db.Query(query)
 		if err != nil {
@@ -8,6 +8,7 @@ import (
 	"os"
 	"ranking-school/controllers"
 	"ranking-school/driver"
+	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/credentials"
@@ -76,6 +77,19 @@ func main() {
 	EventsRegistrationController := controllers.EventsRegistrationController{}
 	olympiadController := &controllers.OlympiadController{}
 	historyController := &controllers.HistoryController{}
+	schoolMemberController := controllers.SchoolMemberController{}
+	academicYearController := controllers.AcademicYearController{}
+	studentTransferController := controllers.StudentTransferController{}
+	classController := controllers.ClassController{}
+	grantController := controllers.GrantController{}
+	portfolioController := controllers.PortfolioController{}
+	notificationController := controllers.NotificationController{}
+	eligibilityController := controllers.EligibilityController{}
+	surveyController := controllers.SurveyController{}
+	eventSessionController := controllers.EventSessionController{}
+	publicationReviewController := controllers.PublicationReviewController{}
+	publicationTemplateController := controllers.PublicationTemplateController{}
+	calendarFeedController := controllers.CalendarFeedController{}
 
 	router := mux.NewRouter()
 
@@ -96,6 +110,19 @@ func main() {
 	// mobile
 	router.HandleFunc("/api/my-history", historyController.GetMyHistory(db)).Methods("GET")
 	router.HandleFunc("/api/my-achievements", historyController.GetMyAchievements(db)).Methods("GET")
+	router.HandleFunc("/api/my-achievements/portfolio", portfolioController.GetMyPortfolio(db)).Methods("GET")
+	router.HandleFunc("/api/students/{student_id}/portfolio", portfolioController.GetStudentPortfolio(db)).Methods("GET")
+	router.HandleFunc("/api/students/{student_id}/unt-progress", untScoreController.GetStudentUNTProgress(db)).Methods("GET")
+	router.HandleFunc("/api/students/{student_id}/grant-eligibility", grantController.GetStudentGrantEligibility(db)).Methods("GET")
+	router.HandleFunc("/api/schools/{school_id}/grant-summary", grantController.GetSchoolGrantSummary(db)).Methods("GET")
+	router.HandleFunc("/api/grant-thresholds", grantController.GetGrantThresholds(db)).Methods("GET")
+	router.HandleFunc("/api/grant-thresholds", grantController.CreateGrantThreshold(db)).Methods("POST")
+	router.HandleFunc("/api/grant-thresholds/{id}", grantController.UpdateGrantThreshold(db)).Methods("PUT")
+	router.HandleFunc("/api/grant-thresholds/{id}", grantController.DeleteGrantThreshold(db)).Methods("DELETE")
+	router.HandleFunc("/api/my-unt-progress", untScoreController.GetMyUNTProgress(db)).Methods("GET")
+	router.HandleFunc("/api/my-notifications", notificationController.GetMyNotifications(db)).Methods("GET")
+	router.HandleFunc("/api/my-notifications/{id}/read", notificationController.MarkNotificationRead(db)).Methods("PATCH")
+	router.HandleFunc("/api/portfolio/verify/{code}", portfolioController.VerifyPortfolio(db)).Methods("GET")
 
 	// =======================
 	// Профиль пользователя и аватар
@@ -145,6 +172,12 @@ func main() {
 	router.HandleFunc("/api/schools/ranking", schoolController.GetTopSchoolsByRating(db)).Methods("GET")
 	router.HandleFunc("/api/statistics/schools/top", schoolController.GetTopSchoolsByRating(db)).Methods("GET")
 
+	// School members
+	router.HandleFunc("/api/schools/{school_id}/members", schoolMemberController.GetSchoolMembers(db)).Methods("GET")
+	router.HandleFunc("/api/schools/{school_id}/members", schoolMemberController.AddSchoolMember(db)).Methods("POST")
+	router.HandleFunc("/api/schools/{school_id}/members/{user_id}", schoolMemberController.UpdateSchoolMember(db)).Methods("PUT")
+	router.HandleFunc("/api/schools/{school_id}/members/{user_id}", schoolMemberController.DeleteSchoolMember(db)).Methods("DELETE")
+
 	// =======================
 	// Работа с отзывами (Reviews)
 	// =======================
@@ -167,7 +200,10 @@ func main() {
 	// Работа с UNT Scores (оценками)
 	// =======================
 	router.HandleFunc("/api/unt/{school_id}", untScoreController.CreateUNT(db)).Methods("POST")
+	router.HandleFunc("/api/unt/{school_id}/import", untScoreController.ImportUNTResults(db)).Methods("POST")
 	router.HandleFunc("/api/unt", untScoreController.GetUNTExams(db)).Methods("GET")
+	router.HandleFunc("/api/unt/subjects", untScoreController.GetUNTSubjectCatalog(db)).Methods("GET")
+	router.HandleFunc("/api/unt/analytics/distribution", untScoreController.GetUNTDistribution(db)).Methods("GET")
 	router.HandleFunc("/api/unt/school/{school_id}", untScoreController.GetUNTBySchoolID(db)).Methods("GET")
 	router.HandleFunc("/api/unt/{id}", untScoreController.UpdateUNTExam(db)).Methods("PUT")
 	router.HandleFunc("/api/unt/{id}", untScoreController.DeleteUNTExam(db)).Methods("DELETE")
@@ -200,6 +236,34 @@ func main() {
 	router.HandleFunc("/api/schools/{school_id}/total-students", studentController.GetTotalStudentsBySchool(db)).Methods("GET")
 	router.HandleFunc("/api/students/{student_id}", studentController.DeleteStudent(db)).Methods("DELETE")
 	router.HandleFunc("/api/schools/{school_id}/students", studentController.GetStudentsBySchool(db)).Methods("GET")
+	router.HandleFunc("/api/schools/{school_id}/students/import", studentController.ImportStudents(db)).Methods("POST")
+	router.HandleFunc("/api/auth/student/initial-password", studentController.SetInitialStudentPassword(db)).Methods("POST")
+	router.HandleFunc("/api/schools/{school_id}/students/iin-issues", studentController.GetStudentIINIssues(db)).Methods("GET")
+	router.HandleFunc("/api/schools/{school_id}/students/duplicates", studentController.GetDuplicateStudents(db)).Methods("GET")
+	router.HandleFunc("/api/students/{student_id}/merge", studentController.MergeStudents(db)).Methods("POST")
+
+	// Перевод учеников на следующий учебный год
+	router.HandleFunc("/api/schools/{school_id}/rollover", academicYearController.RolloverSchool(db)).Methods("POST")
+	router.HandleFunc("/api/rollover", academicYearController.RolloverAllSchools(db)).Methods("POST")
+	router.HandleFunc("/api/schools/{school_id}/alumni", academicYearController.GetAlumniBySchool(db)).Methods("GET")
+	router.HandleFunc("/api/students/{student_id}/grade-history", academicYearController.GetStudentGradeHistory(db)).Methods("GET")
+
+	// Переводы учеников между школами
+	router.HandleFunc("/api/students/{student_id}/transfers", studentTransferController.CreateTransfer(db)).Methods("POST")
+	router.HandleFunc("/api/students/{student_id}/transfers", studentTransferController.GetStudentTransfers(db)).Methods("GET")
+	router.HandleFunc("/api/schools/{school_id}/transfers", studentTransferController.GetSchoolTransfers(db)).Methods("GET")
+	router.HandleFunc("/api/transfers/{transfer_id}/accept", studentTransferController.AcceptTransfer(db)).Methods("POST")
+	router.HandleFunc("/api/transfers/{transfer_id}/reject", studentTransferController.RejectTransfer(db)).Methods("POST")
+	router.HandleFunc("/api/transfers/{transfer_id}/cancel", studentTransferController.CancelTransfer(db)).Methods("POST")
+
+	// Классы
+	router.HandleFunc("/api/schools/{school_id}/classes", classController.GetClassesBySchool(db)).Methods("GET")
+	router.HandleFunc("/api/schools/{school_id}/classes", classController.CreateClass(db)).Methods("POST")
+	router.HandleFunc("/api/schools/{school_id}/classes/analytics", classController.GetClassAnalytics(db)).Methods("GET")
+	router.HandleFunc("/api/classes/{class_id}", classController.UpdateClass(db)).Methods("PUT")
+	router.HandleFunc("/api/classes/{class_id}", classController.DeleteClass(db)).Methods("DELETE")
+	router.HandleFunc("/api/classes/{class_id}/students", classController.GetClassRoster(db)).Methods("GET")
+	router.HandleFunc("/api/classes/{class_id}/students", classController.AssignStudentsToClass(db)).Methods("POST")
 	router.HandleFunc("/api/schools/count/{school_id}/students", studentController.GetStudentsCountBySchool(db)).Methods("GET")
 	router.HandleFunc("/api/schools/{school_id}/students/grades", studentController.GetAvailableGradesBySchool(db)).Methods("GET")
 	router.HandleFunc("/students/letters/{school_id}/{grade}", studentController.GetAvailableLettersByGrade(db)).Methods("GET")
@@ -224,6 +288,8 @@ func main() {
 	router.HandleFunc("/events/participants", EventsParticipantController.GetEventsParticipant(db)).Methods("GET")
 	router.HandleFunc("/events/participants/school/{school_id}", EventsParticipantController.GetEventsParticipantBySchool(db)).Methods("GET")
 	router.HandleFunc("/api/events-participant/by-name/{events_name}/{student_id}", EventsParticipantController.GetParticipantByEventNameAndStudentID(db)).Methods("GET")
+	router.HandleFunc("/api/events-participant/by-event/{event_id}/{student_id}", EventsParticipantController.GetParticipantByEventIDAndStudentID(db)).Methods("GET")
+	router.HandleFunc("/api/events-participant/unlinked", EventsParticipantController.GetUnlinkedEventsParticipants(db)).Methods("GET")
 
 	// =======================
 	// Работа с Second Types
@@ -243,6 +309,13 @@ func main() {
 	router.HandleFunc("/api/event-registrations", EventsRegistrationController.GetEventRegistrations(db)).Methods("GET")
 	router.HandleFunc("/api/event-registrations/{id}", EventsRegistrationController.DeleteEventRegistrationByID(db)).Methods("DELETE")
 	router.HandleFunc("/api/my-registrations/{id}", EventsRegistrationController.DeleteMyEventRegistration(db)).Methods("DELETE")
+	router.HandleFunc("/api/my-registrations/{id}/ticket", EventsRegistrationController.GetMyEventTicket(db)).Methods("GET")
+	router.HandleFunc("/api/events/{id}/check-in", EventsRegistrationController.CheckInEventTicket(db)).Methods("POST")
+	router.HandleFunc("/api/events/{id}/check-in/batch", EventsRegistrationController.BatchCheckInEvent(db)).Methods("POST")
+	router.HandleFunc("/api/events/{id}/attendance", EventsRegistrationController.GetEventAttendance(db)).Methods("GET")
+	router.HandleFunc("/api/events/{id}/attendance/finalize", EventsRegistrationController.FinalizeEventAttendance(db)).Methods("POST")
+	router.HandleFunc("/api/event-registrations/{id}/attendance", EventsRegistrationController.SetEventRegistrationAttendance(db)).Methods("PATCH")
+	router.HandleFunc("/api/my-olympiad-registrations/{id}", OlympiadRegistrationController.CancelMyOlympiadRegistration(db)).Methods("DELETE")
 	router.HandleFunc("/api/event-registrations/{id}/approve-or-cancel", EventsRegistrationController.ApproveOrCancelEventRegistration(db)).Methods("PATCH")
 	router.HandleFunc("/api/school-ranking", EventsRegistrationController.GetSchoolRanking(db)).Methods("GET")
 	router.HandleFunc("/api/school/{school_id}/participants", EventsRegistrationController.GetParticipantsBySchoolID(db)).Methods("GET")
@@ -269,6 +342,10 @@ func main() {
 	router.HandleFunc("/api/subject-olympiads/{id}", SubjectOlympiadController.EditOlympiadsCreated(db)).Methods("PUT")
 	router.HandleFunc("/api/subject-olympiads/{id}", SubjectOlympiadController.DeleteSubjectOlympiad(db)).Methods("DELETE")
 	router.HandleFunc("/api/subject-olympiads/participants/{subject_olympiad_id}", SubjectOlympiadController.GetOlympiadParticipants(db)).Methods("GET")
+	router.HandleFunc("/api/subject-olympiads/{id}/eligibility-rules", eligibilityController.GetOlympiadEligibilityRules(db)).Methods("GET")
+	router.HandleFunc("/api/subject-olympiads/{id}/eligibility-rules", eligibilityController.UpdateOlympiadEligibilityRules(db)).Methods("PUT")
+	router.HandleFunc("/api/subject-olympiads/{id}/eligibility-rules", eligibilityController.DeleteOlympiadEligibilityRules(db)).Methods("DELETE")
+	router.HandleFunc("/api/subject-olympiads/{id}/eligibility", eligibilityController.CheckOlympiadEligibility(db)).Methods("GET")
 	router.HandleFunc("/api/schools/{school_id}/olympiad-stats", SubjectOlympiadController.GetSchoolOlympiadStats(db)).Methods("GET")
 
 	// =======================
@@ -279,6 +356,60 @@ func main() {
 	router.HandleFunc("/api/events/school/{school_id}", eventController.GetEventsBySchoolAndType(db)).Methods("GET")
 	router.HandleFunc("/api/events/{event_id}", eventController.UpdateEvent(db)).Methods("PUT")
 	router.HandleFunc("/api/events/{event_id}", eventController.DeleteEvent(db)).Methods("DELETE")
+	router.HandleFunc("/api/events/{id}/eligibility-rules", eligibilityController.GetEventEligibilityRules(db)).Methods("GET")
+	router.HandleFunc("/api/events/{id}/eligibility-rules", eligibilityController.UpdateEventEligibilityRules(db)).Methods("PUT")
+	router.HandleFunc("/api/events/{id}/eligibility-rules", eligibilityController.DeleteEventEligibilityRules(db)).Methods("DELETE")
+	router.HandleFunc("/api/events/{id}/eligibility", eligibilityController.CheckEventEligibility(db)).Methods("GET")
+
+	// Опросы после мероприятий
+	router.HandleFunc("/api/survey-templates", surveyController.CreateSurveyTemplate(db)).Methods("POST")
+	router.HandleFunc("/api/survey-templates", surveyController.GetSurveyTemplates(db)).Methods("GET")
+	router.HandleFunc("/api/survey-templates/{id}", surveyController.GetSurveyTemplate(db)).Methods("GET")
+	router.HandleFunc("/api/survey-templates/{id}", surveyController.UpdateSurveyTemplate(db)).Methods("PUT")
+	router.HandleFunc("/api/survey-templates/{id}", surveyController.DeleteSurveyTemplate(db)).Methods("DELETE")
+	router.HandleFunc("/api/events/{id}/survey", surveyController.AttachEventSurvey(db)).Methods("PUT")
+	router.HandleFunc("/api/events/{id}/survey", surveyController.DetachEventSurvey(db)).Methods("DELETE")
+	router.HandleFunc("/api/events/{id}/survey", surveyController.GetEventSurvey(db)).Methods("GET")
+	router.HandleFunc("/api/events/{id}/survey/responses", surveyController.SubmitEventSurvey(db)).Methods("POST")
+	router.HandleFunc("/api/events/{id}/survey/results", surveyController.GetEventSurveyResults(db)).Methods("GET")
+	router.HandleFunc("/api/schools/{school_id}/survey-results", surveyController.GetSchoolSurveyResults(db)).Methods("GET")
+	router.HandleFunc("/api/my-surveys", surveyController.GetMySurveys(db)).Methods("GET")
+
+	// Расписание мероприятий: сессии и запись на них
+	router.HandleFunc("/api/events/{id}/sessions", eventSessionController.CreateEventSession(db)).Methods("POST")
+	router.HandleFunc("/api/events/{id}/schedule", eventSessionController.GetEventSchedule(db)).Methods("GET")
+	router.HandleFunc("/api/event-sessions/{id}", eventSessionController.UpdateEventSession(db)).Methods("PUT")
+	router.HandleFunc("/api/event-sessions/{id}", eventSessionController.DeleteEventSession(db)).Methods("DELETE")
+	router.HandleFunc("/api/event-sessions/{id}/register", eventSessionController.RegisterForSession(db)).Methods("POST")
+	router.HandleFunc("/api/event-sessions/{id}/register", eventSessionController.CancelSessionRegistration(db)).Methods("DELETE")
+	router.HandleFunc("/api/event-sessions/{id}/registrations", eventSessionController.GetSessionRegistrations(db)).Methods("GET")
+	router.HandleFunc("/api/my-schedule", eventSessionController.GetMySchedule(db)).Methods("GET")
+
+	// Модерация мероприятий и олимпиад ({kind}: event / olympiad)
+	router.HandleFunc("/api/review-queue", publicationReviewController.GetReviewQueue(db)).Methods("GET")
+	router.HandleFunc("/api/publications/{kind}/{id}/submit", publicationReviewController.SubmitForReview(db)).Methods("POST")
+	router.HandleFunc("/api/publications/{kind}/{id}/withdraw", publicationReviewController.WithdrawFromReview(db)).Methods("POST")
+	router.HandleFunc("/api/publications/{kind}/{id}/approve", publicationReviewController.ApprovePublication(db)).Methods("POST")
+	router.HandleFunc("/api/publications/{kind}/{id}/reject", publicationReviewController.RejectPublication(db)).Methods("POST")
+	router.HandleFunc("/api/publications/{kind}/{id}/comments", publicationReviewController.AddPublicationComment(db)).Methods("POST")
+	router.HandleFunc("/api/publications/{kind}/{id}/history", publicationReviewController.GetPublicationHistory(db)).Methods("GET")
+
+	// Шаблоны и повторяющиеся мероприятия/олимпиады
+	router.HandleFunc("/api/schools/{school_id}/templates", publicationTemplateController.CreatePublicationTemplate(db)).Methods("POST")
+	router.HandleFunc("/api/schools/{school_id}/templates", publicationTemplateController.GetSchoolTemplates(db)).Methods("GET")
+	router.HandleFunc("/api/templates/{id}", publicationTemplateController.GetPublicationTemplate(db)).Methods("GET")
+	router.HandleFunc("/api/templates/{id}", publicationTemplateController.UpdatePublicationTemplate(db)).Methods("PUT")
+	router.HandleFunc("/api/templates/{id}", publicationTemplateController.DeletePublicationTemplate(db)).Methods("DELETE")
+	router.HandleFunc("/api/templates/{id}/generate", publicationTemplateController.GenerateTemplateInstances(db)).Methods("POST")
+	router.HandleFunc("/api/templates/{id}/instances", publicationTemplateController.CreateInstanceFromTemplate(db)).Methods("POST")
+
+	// Календарные ICS-ленты: токен выдаётся по JWT, ленты открываются по ?token=
+	router.HandleFunc("/api/calendar/token", calendarFeedController.CreateCalendarFeedToken(db)).Methods("POST")
+	router.HandleFunc("/api/calendar/token", calendarFeedController.GetCalendarFeedToken(db)).Methods("GET")
+	router.HandleFunc("/api/calendar/token", calendarFeedController.RevokeCalendarFeedToken(db)).Methods("DELETE")
+	router.HandleFunc("/api/calendar/my.ics", calendarFeedController.GetMyCalendarFeed(db)).Methods("GET")
+	router.HandleFunc("/api/calendar/public.ics", calendarFeedController.GetPublicCalendarFeed(db)).Methods("GET")
+	router.HandleFunc("/api/calendar/schools/{school_id:[0-9]+}.ics", calendarFeedController.GetSchoolCalendarFeed(db)).Methods("GET")
 	router.HandleFunc("/api/events/school/data/{school_id}", eventController.GetEventsBySchoolID(db)).Methods("GET")
 	router.HandleFunc("/api/events/category/{category}", eventController.GetEventsByCategory(db)).Methods("GET")
 	router.HandleFunc("/api/event/{id}", eventController.GetEventByID(db)).Methods("GET")
@@ -337,6 +468,10 @@ func main() {
 	router.HandleFunc("/api/olympiads/{olympiad_id}", olympiadController.UpdateOlympiad(db)).Methods("PUT")
 	router.HandleFunc("/api/olympiads-participant/by-name/{olympiad_name}/{student_id}", olympiadController.GetParticipantByOlympNameAndStudentID(db)).Methods("GET")
 
+	// Рассылка опросов по завершившимся мероприятиям
+	controllers.StartSurveyDispatcher(db, 15*time.Minute)
+	controllers.StartTemplateScheduler(db, 6*time.Hour)
+
 	// Включаем CORS
 	handler := corsMiddleware(router)
 
@@ -0,0 +1,27 @@
+-- Членство администраторов в школах: несколько админов на одну школу,
+-- у каждого свой набор прав.
+CREATE TABLE IF NOT EXISTS `school_members` (
+  `id` int NOT NULL AUTO_INCREMENT,
+  `school_id` int NOT NULL,
+  `user_id` int NOT NULL,
+  `permissions` set('manage_students','manage_unt','manage_events','read_only') NOT NULL DEFAULT 'read_only',
+  `created_at` timestamp NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `uq_school_members_school_user` (`school_id`,`user_id`),
+  KEY `idx_school_members_user` (`user_id`),
+  CONSTRAINT `fk_school_members_school` FOREIGN KEY (`school_id`) REFERENCES `Schools` (`school_id`) ON DELETE CASCADE,
+  CONSTRAINT `fk_school_members_user` FOREIGN KEY (`user_id`) REFERENCES `users` (`id`) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+-- Переносим существующие привязки (school_admin_login и users.school_id)
+INSERT IGNORE INTO `school_members` (`school_id`, `user_id`, `permissions`)
+SELECT s.school_id, u.id, 'manage_students,manage_unt,manage_events'
+FROM `Schools` s
+JOIN `users` u ON u.email = s.school_admin_login
+WHERE u.role = 'schooladmin';
+
+INSERT IGNORE INTO `school_members` (`school_id`, `user_id`, `permissions`)
+SELECT u.school_id, u.id, 'manage_students,manage_unt,manage_events'
+FROM `users` u
+JOIN `Schools` s ON s.school_id = u.school_id
+WHERE u.role = 'schooladmin';
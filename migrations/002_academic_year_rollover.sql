@@ -0,0 +1,36 @@
+-- Перевод учеников в следующий класс в конце учебного года.
+-- Выпускники не удаляются, а помечаются как alumni.
+ALTER TABLE `student`
+  ADD COLUMN `status` enum('active','alumni') NOT NULL DEFAULT 'active',
+  ADD COLUMN `graduation_year` smallint NULL,
+  ADD KEY `idx_student_school_status` (`school_id`,`status`);
+
+-- Класс и литера ученика на каждый учебный год (например, 2024-2025)
+CREATE TABLE IF NOT EXISTS `student_grade_history` (
+  `id` int NOT NULL AUTO_INCREMENT,
+  `student_id` int NOT NULL,
+  `school_id` int NOT NULL,
+  `academic_year` varchar(9) NOT NULL,
+  `grade` int NOT NULL,
+  `letter` varchar(5) DEFAULT NULL,
+  `outcome` enum('promoted','repeated','graduated') NOT NULL,
+  `created_at` timestamp NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `uq_student_grade_history_year` (`student_id`,`academic_year`),
+  KEY `idx_student_grade_history_school` (`school_id`,`academic_year`),
+  CONSTRAINT `fk_student_grade_history_student` FOREIGN KEY (`student_id`) REFERENCES `student` (`student_id`) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+-- Журнал выполненных переводов
+CREATE TABLE IF NOT EXISTS `academic_year_rollovers` (
+  `id` int NOT NULL AUTO_INCREMENT,
+  `school_id` int NOT NULL,
+  `academic_year` varchar(9) NOT NULL,
+  `promoted` int NOT NULL DEFAULT 0,
+  `repeated` int NOT NULL DEFAULT 0,
+  `graduated` int NOT NULL DEFAULT 0,
+  `performed_by` int NOT NULL,
+  `created_at` timestamp NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `uq_academic_year_rollovers_school_year` (`school_id`,`academic_year`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
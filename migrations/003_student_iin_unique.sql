@@ -0,0 +1,9 @@
+-- ИИН ученика уникален. Пустые значения храним как NULL,
+-- чтобы ученики без ИИН не конфликтовали между собой.
+ALTER TABLE `student` MODIFY `iin` varchar(12) NULL;
+
+UPDATE `student` SET `iin` = NULL WHERE TRIM(`iin`) = '';
+
+-- Уникальность обеспечивает уже существующий индекс `iin` (UNIQUE KEY),
+-- NULL-значения он не сравнивает. Список учеников с проблемным ИИН:
+-- GET /api/schools/{school_id}/students/iin-issues
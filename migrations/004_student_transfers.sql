@@ -0,0 +1,22 @@
+-- Переводы учеников между школами. Школа-отправитель создаёт заявку,
+-- школа-получатель принимает или отклоняет её. Результаты (UNT_Exams,
+-- olympiad_registrations, EventRegistrations) хранят свой school_id и
+-- остаются за школой, где были получены.
+CREATE TABLE IF NOT EXISTS `student_transfers` (
+  `id` int NOT NULL AUTO_INCREMENT,
+  `student_id` int NOT NULL,
+  `from_school_id` int NOT NULL,
+  `to_school_id` int NOT NULL,
+  `status` enum('pending','accepted','rejected','cancelled') NOT NULL DEFAULT 'pending',
+  `reason` text,
+  `decision_note` text,
+  `initiated_by` int NOT NULL,
+  `decided_by` int DEFAULT NULL,
+  `created_at` timestamp NULL DEFAULT CURRENT_TIMESTAMP,
+  `decided_at` timestamp NULL DEFAULT NULL,
+  PRIMARY KEY (`id`),
+  KEY `idx_student_transfers_student` (`student_id`),
+  KEY `idx_student_transfers_from` (`from_school_id`,`status`),
+  KEY `idx_student_transfers_to` (`to_school_id`,`status`),
+  CONSTRAINT `fk_student_transfers_student` FOREIGN KEY (`student_id`) REFERENCES `student` (`student_id`) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
@@ -0,0 +1,46 @@
+-- Классы (параллель + литера) школы на учебный год.
+-- student.grade и student.letter остаются и синхронизируются с классом,
+-- чтобы старые запросы продолжали работать.
+CREATE TABLE IF NOT EXISTS `classes` (
+  `id` int NOT NULL AUTO_INCREMENT,
+  `school_id` int NOT NULL,
+  `academic_year` varchar(9) NOT NULL,
+  `grade` int NOT NULL,
+  `letter` varchar(5) NOT NULL DEFAULT '',
+  `homeroom_teacher` varchar(255) DEFAULT NULL,
+  `capacity` int DEFAULT NULL,
+  `created_at` timestamp NULL DEFAULT CURRENT_TIMESTAMP,
+  `updated_at` timestamp NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `uq_classes_school_year_grade_letter` (`school_id`,`academic_year`,`grade`,`letter`),
+  CONSTRAINT `fk_classes_school` FOREIGN KEY (`school_id`) REFERENCES `Schools` (`school_id`) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+ALTER TABLE `student`
+  ADD COLUMN `class_id` int NULL,
+  ADD KEY `idx_student_class` (`class_id`),
+  ADD CONSTRAINT `fk_student_class` FOREIGN KEY (`class_id`) REFERENCES `classes` (`id`) ON DELETE SET NULL;
+
+-- Класс, в котором ученик учился в каждом учебном году
+ALTER TABLE `student_grade_history`
+  ADD COLUMN `class_id` int NULL,
+  ADD KEY `idx_student_grade_history_class` (`class_id`);
+
+-- Создаём классы текущего учебного года из существующих учеников
+SET @academic_year = IF(MONTH(CURDATE()) >= 7,
+  CONCAT(YEAR(CURDATE()), '-', YEAR(CURDATE()) + 1),
+  CONCAT(YEAR(CURDATE()) - 1, '-', YEAR(CURDATE())));
+
+INSERT IGNORE INTO `classes` (`school_id`, `academic_year`, `grade`, `letter`)
+SELECT DISTINCT s.school_id, @academic_year, s.grade, UPPER(TRIM(COALESCE(s.letter, '')))
+FROM `student` s
+JOIN `Schools` sc ON sc.school_id = s.school_id
+WHERE s.status = 'active' AND s.grade IS NOT NULL;
+
+UPDATE `student` s
+JOIN `classes` c ON c.school_id = s.school_id
+  AND c.academic_year = @academic_year
+  AND c.grade = s.grade
+  AND c.letter = UPPER(TRIM(COALESCE(s.letter, '')))
+SET s.class_id = c.id
+WHERE s.status = 'active';
@@ -0,0 +1,14 @@
+-- Журнал слияния дубликатов учеников. merged_data хранит снимок
+-- удалённой записи, чтобы слияние можно было проверить позже.
+CREATE TABLE IF NOT EXISTS `student_merges` (
+  `id` int NOT NULL AUTO_INCREMENT,
+  `survivor_id` int NOT NULL,
+  `merged_id` int NOT NULL,
+  `school_id` int DEFAULT NULL,
+  `merged_data` json NOT NULL,
+  `moved_rows` json DEFAULT NULL,
+  `merged_by` int NOT NULL,
+  `created_at` timestamp NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (`id`),
+  KEY `idx_student_merges_survivor` (`survivor_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
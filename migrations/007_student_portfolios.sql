@@ -0,0 +1,14 @@
+-- Выданные PDF-портфолио учеников. По verification_code публичный
+-- эндпоинт подтверждает, что документ был сформирован системой.
+CREATE TABLE IF NOT EXISTS `student_portfolios` (
+  `id` int NOT NULL AUTO_INCREMENT,
+  `student_id` int NOT NULL,
+  `verification_code` varchar(32) NOT NULL,
+  `content_hash` char(64) NOT NULL,
+  `summary` json DEFAULT NULL,
+  `generated_by` int DEFAULT NULL,
+  `created_at` timestamp NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `uq_student_portfolios_code` (`verification_code`),
+  KEY `idx_student_portfolios_student` (`student_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
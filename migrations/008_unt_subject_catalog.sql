@@ -0,0 +1,66 @@
+-- Справочник предметов ЕНТ с максимальными баллами.
+-- kind: mandatory — обязательные компоненты, profile — профильные предметы,
+-- creative — творческие экзамены. Сумма максимумов даёт 140 (regular)
+-- и 120 (creative), как в расчёте рейтинга.
+CREATE TABLE IF NOT EXISTS `unt_subjects` (
+  `id` int NOT NULL AUTO_INCREMENT,
+  `code` varchar(50) NOT NULL,
+  `name` varchar(100) NOT NULL,
+  `kind` enum('mandatory','profile','creative') NOT NULL,
+  `max_score` int NOT NULL,
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `uq_unt_subjects_code` (`code`),
+  UNIQUE KEY `uq_unt_subjects_name` (`name`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+-- Допустимые сочетания профильных предметов
+CREATE TABLE IF NOT EXISTS `unt_subject_pairs` (
+  `id` int NOT NULL AUTO_INCREMENT,
+  `exam_type` enum('regular','creative') NOT NULL,
+  `first_subject_id` int NOT NULL,
+  `second_subject_id` int NOT NULL,
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `uq_unt_subject_pairs` (`exam_type`, `first_subject_id`, `second_subject_id`),
+  CONSTRAINT `fk_unt_pairs_first` FOREIGN KEY (`first_subject_id`) REFERENCES `unt_subjects` (`id`),
+  CONSTRAINT `fk_unt_pairs_second` FOREIGN KEY (`second_subject_id`) REFERENCES `unt_subjects` (`id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+INSERT IGNORE INTO `unt_subjects` (`code`, `name`, `kind`, `max_score`) VALUES
+  ('history_of_kazakhstan', 'История Казахстана', 'mandatory', 20),
+  ('mathematical_literacy', 'Математическая грамотность', 'mandatory', 10),
+  ('reading_literacy', 'Грамотность чтения', 'mandatory', 10),
+  ('mathematics', 'Математика', 'profile', 50),
+  ('physics', 'Физика', 'profile', 50),
+  ('informatics', 'Информатика', 'profile', 50),
+  ('chemistry', 'Химия', 'profile', 50),
+  ('biology', 'Биология', 'profile', 50),
+  ('geography', 'География', 'profile', 50),
+  ('world_history', 'Всемирная история', 'profile', 50),
+  ('law', 'Основы права', 'profile', 50),
+  ('foreign_language', 'Иностранный язык', 'profile', 50),
+  ('kazakh_language', 'Казахский язык', 'profile', 50),
+  ('kazakh_literature', 'Казахская литература', 'profile', 50),
+  ('russian_language', 'Русский язык', 'profile', 50),
+  ('russian_literature', 'Русская литература', 'profile', 50),
+  ('creative_exam_1', 'Творческий экзамен 1', 'creative', 45),
+  ('creative_exam_2', 'Творческий экзамен 2', 'creative', 45);
+
+INSERT IGNORE INTO `unt_subject_pairs` (`exam_type`, `first_subject_id`, `second_subject_id`)
+SELECT p.exam_type, f.id, s.id
+FROM (
+  SELECT 'regular' AS exam_type, 'mathematics' AS first_code, 'physics' AS second_code
+  UNION ALL SELECT 'regular', 'mathematics', 'informatics'
+  UNION ALL SELECT 'regular', 'mathematics', 'geography'
+  UNION ALL SELECT 'regular', 'biology', 'chemistry'
+  UNION ALL SELECT 'regular', 'biology', 'geography'
+  UNION ALL SELECT 'regular', 'chemistry', 'physics'
+  UNION ALL SELECT 'regular', 'world_history', 'geography'
+  UNION ALL SELECT 'regular', 'world_history', 'law'
+  UNION ALL SELECT 'regular', 'foreign_language', 'world_history'
+  UNION ALL SELECT 'regular', 'geography', 'foreign_language'
+  UNION ALL SELECT 'regular', 'kazakh_language', 'kazakh_literature'
+  UNION ALL SELECT 'regular', 'russian_language', 'russian_literature'
+  UNION ALL SELECT 'creative', 'creative_exam_1', 'creative_exam_2'
+) p
+JOIN `unt_subjects` f ON f.code = p.first_code
+JOIN `unt_subjects` s ON s.code = p.second_code;
@@ -0,0 +1,5 @@
+-- Вид попытки ЕНТ: official — итоговый результат, trial — пробное
+-- тестирование школы. Пробные попытки не входят в рейтинг.
+ALTER TABLE `UNT_Exams`
+  ADD COLUMN `attempt_kind` enum('official','trial') NOT NULL DEFAULT 'official' AFTER `exam_type`,
+  ADD KEY `idx_unt_exams_student_date` (`student_id`, `date`);
@@ -0,0 +1,24 @@
+-- Пороговые баллы на государственный грант по группам образовательных
+-- программ. year — год поступления. Если пара профильных предметов не
+-- указана, группа доступна при любой паре. Минимумы по компонентам
+-- задаются отдельно, passing_score — проходной балл конкурса на грант.
+CREATE TABLE IF NOT EXISTS `grant_thresholds` (
+  `id` int NOT NULL AUTO_INCREMENT,
+  `year` int NOT NULL,
+  `group_code` varchar(20) NOT NULL,
+  `group_name` varchar(255) NOT NULL,
+  `exam_type` enum('regular','creative') NOT NULL DEFAULT 'regular',
+  `first_subject_code` varchar(50) DEFAULT NULL,
+  `second_subject_code` varchar(50) DEFAULT NULL,
+  `min_total` int NOT NULL DEFAULT 50,
+  `min_history_of_kazakhstan` int NOT NULL DEFAULT 5,
+  `min_reading_literacy` int NOT NULL DEFAULT 3,
+  `min_mathematical_literacy` int NOT NULL DEFAULT 3,
+  `min_first_subject` int NOT NULL DEFAULT 5,
+  `min_second_subject` int NOT NULL DEFAULT 5,
+  `passing_score` int DEFAULT NULL,
+  `created_at` timestamp NULL DEFAULT CURRENT_TIMESTAMP,
+  `updated_at` timestamp NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `uq_grant_thresholds_year_group` (`year`, `group_code`, `exam_type`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
@@ -0,0 +1,240 @@
+-- Перенос результатов ЕНТ из старых таблиц (First_Type, Second_Type,
+-- UNT_Type, UNT_Score) в единую таблицу UNT_Exams и удаление старых таблиц.
+--
+-- Ученик строки First_Type/Second_Type определяется по её student_id, а если
+-- его нет — через UNT_Type (first_type_id/second_type_id) и
+-- student.student_type_id, как это делал GetTop3StudentsByUNT. Баллы,
+-- которые CreateUNTType записывал в саму UNT_Type, переносятся отдельной
+-- попыткой, если у ученика ещё нет попытки того же типа с тем же итогом.
+-- В старых таблицах нет даты сдачи: для First_Type/Second_Type/UNT_Type
+-- дата остаётся NULL (код читает её через COALESCE), для UNT_Score берётся
+-- 1 июня указанного года. Документов в старых таблицах нет, document_url = ''.
+-- Все перенесённые результаты считаются итоговыми. Попытка не дублируется,
+-- если в UNT_Exams уже есть попытка ученика того же типа с теми же
+-- предметами и баллами.
+
+-- Обычный ЕНТ (type-1)
+INSERT INTO `UNT_Exams` (
+  exam_type, attempt_kind, first_subject, first_subject_score, second_subject, second_subject_score,
+  history_of_kazakhstan, mathematical_literacy, reading_literacy, total_score, student_id, school_id, document_url, date
+)
+SELECT 'regular', 'official', COALESCE(ft.first_subject, ''), COALESCE(ft.first_subject_score, 0),
+       COALESCE(ft.second_subject, ''), COALESCE(ft.second_subject_score, 0),
+       COALESCE(ft.history_of_kazakhstan, 0), ft.mathematical_literacy, ft.reading_literacy,
+       COALESCE(ft.total_score, COALESCE(ft.first_subject_score, 0) + COALESCE(ft.second_subject_score, 0)
+         + COALESCE(ft.history_of_kazakhstan, 0) + ft.mathematical_literacy + ft.reading_literacy),
+       ft.student_id, COALESCE(ft.school_id, s.school_id), '', NULL
+FROM `First_Type` ft
+JOIN `student` s ON s.student_id = ft.student_id
+WHERE NOT EXISTS (
+  SELECT 1 FROM `UNT_Exams` ue
+  WHERE ue.student_id = ft.student_id AND ue.exam_type = 'regular'
+    AND ue.first_subject = COALESCE(ft.first_subject, '') AND ue.second_subject = COALESCE(ft.second_subject, '')
+    AND ue.first_subject_score = COALESCE(ft.first_subject_score, 0) AND ue.second_subject_score = COALESCE(ft.second_subject_score, 0)
+    AND ue.total_score = COALESCE(ft.total_score, COALESCE(ft.first_subject_score, 0) + COALESCE(ft.second_subject_score, 0)
+      + COALESCE(ft.history_of_kazakhstan, 0) + ft.mathematical_literacy + ft.reading_literacy)
+);
+
+-- Обычный ЕНТ без student_id: ученик через UNT_Type.first_type_id
+INSERT INTO `UNT_Exams` (
+  exam_type, attempt_kind, first_subject, first_subject_score, second_subject, second_subject_score,
+  history_of_kazakhstan, mathematical_literacy, reading_literacy, total_score, student_id, school_id, document_url, date
+)
+SELECT 'regular', 'official', src.first_subject, src.first_score, src.second_subject, src.second_score,
+       src.history, src.math, src.reading, src.total, src.student_id, src.school_id, '', NULL
+FROM (
+  SELECT s.student_id, COALESCE(ft.school_id, s.school_id) AS school_id,
+         COALESCE(ft.first_subject, '') AS first_subject, COALESCE(ft.first_subject_score, 0) AS first_score,
+         COALESCE(ft.second_subject, '') AS second_subject, COALESCE(ft.second_subject_score, 0) AS second_score,
+         COALESCE(ft.history_of_kazakhstan, 0) AS history, ft.mathematical_literacy AS math, ft.reading_literacy AS reading,
+         COALESCE(ft.total_score, COALESCE(ft.first_subject_score, 0) + COALESCE(ft.second_subject_score, 0)
+           + COALESCE(ft.history_of_kazakhstan, 0) + ft.mathematical_literacy + ft.reading_literacy) AS total
+  FROM `First_Type` ft
+  JOIN `UNT_Type` ut ON ut.first_type_id = ft.first_type_id
+  JOIN `student` s ON s.student_type_id = ut.unt_type_id
+  WHERE NOT EXISTS (SELECT 1 FROM `student` own WHERE own.student_id = ft.student_id)
+) src
+WHERE NOT EXISTS (
+  SELECT 1 FROM `UNT_Exams` ue
+  WHERE ue.student_id = src.student_id AND ue.exam_type = 'regular'
+    AND ue.first_subject = src.first_subject AND ue.second_subject = src.second_subject
+    AND ue.first_subject_score = src.first_score AND ue.second_subject_score = src.second_score
+    AND ue.total_score = src.total
+);
+
+-- Творческий ЕНТ (type-2): творческие экзамены становятся профильными баллами
+INSERT INTO `UNT_Exams` (
+  exam_type, attempt_kind, first_subject, first_subject_score, second_subject, second_subject_score,
+  history_of_kazakhstan, mathematical_literacy, reading_literacy, total_score, student_id, school_id, document_url, date
+)
+SELECT 'creative', 'official', '', COALESCE(st.creative_exam1, 0), '', COALESCE(st.creative_exam2, 0),
+       COALESCE(st.history_of_kazakhstan_creative, 0), 0, COALESCE(st.reading_literacy_creative, 0),
+       COALESCE(st.total_score_creative, COALESCE(st.history_of_kazakhstan_creative, 0) + COALESCE(st.reading_literacy_creative, 0)
+         + COALESCE(st.creative_exam1, 0) + COALESCE(st.creative_exam2, 0)),
+       st.student_id, COALESCE(st.school_id, s.school_id), '', NULL
+FROM `Second_Type` st
+JOIN `student` s ON s.student_id = st.student_id
+WHERE NOT EXISTS (
+  SELECT 1 FROM `UNT_Exams` ue
+  WHERE ue.student_id = st.student_id AND ue.exam_type = 'creative'
+    AND ue.first_subject_score = COALESCE(st.creative_exam1, 0) AND ue.second_subject_score = COALESCE(st.creative_exam2, 0)
+    AND ue.total_score = COALESCE(st.total_score_creative, COALESCE(st.history_of_kazakhstan_creative, 0) + COALESCE(st.reading_literacy_creative, 0)
+      + COALESCE(st.creative_exam1, 0) + COALESCE(st.creative_exam2, 0))
+);
+
+-- Творческий ЕНТ без student_id: ученик через UNT_Type.second_type_id
+INSERT INTO `UNT_Exams` (
+  exam_type, attempt_kind, first_subject, first_subject_score, second_subject, second_subject_score,
+  history_of_kazakhstan, mathematical_literacy, reading_literacy, total_score, student_id, school_id, document_url, date
+)
+SELECT 'creative', 'official', '', src.first_score, '', src.second_score,
+       src.history, 0, src.reading, src.total, src.student_id, src.school_id, '', NULL
+FROM (
+  SELECT s.student_id, COALESCE(st.school_id, s.school_id) AS school_id,
+         COALESCE(st.creative_exam1, 0) AS first_score, COALESCE(st.creative_exam2, 0) AS second_score,
+         COALESCE(st.history_of_kazakhstan_creative, 0) AS history, COALESCE(st.reading_literacy_creative, 0) AS reading,
+         COALESCE(st.total_score_creative, COALESCE(st.history_of_kazakhstan_creative, 0) + COALESCE(st.reading_literacy_creative, 0)
+           + COALESCE(st.creative_exam1, 0) + COALESCE(st.creative_exam2, 0)) AS total
+  FROM `Second_Type` st
+  JOIN `UNT_Type` ut ON ut.second_type_id = st.second_type_id
+  JOIN `student` s ON s.student_type_id = ut.unt_type_id
+  WHERE NOT EXISTS (SELECT 1 FROM `student` own WHERE own.student_id = st.student_id)
+) src
+WHERE NOT EXISTS (
+  SELECT 1 FROM `UNT_Exams` ue
+  WHERE ue.student_id = src.student_id AND ue.exam_type = 'creative'
+    AND ue.first_subject_score = src.first_score AND ue.second_subject_score = src.second_score
+    AND ue.total_score = src.total
+);
+
+-- Собственные баллы UNT_Type. Для type-1 CreateUNTType сохранял только
+-- total_score, для type-2 — творческие экзамены и total_score_creative.
+-- Если у ученика уже есть попытка того же типа с тем же итогом (перенесённая
+-- из связанной строки First_Type/Second_Type), вторая не создаётся.
+INSERT INTO `UNT_Exams` (
+  exam_type, attempt_kind, first_subject, first_subject_score, second_subject, second_subject_score,
+  history_of_kazakhstan, mathematical_literacy, reading_literacy, total_score, student_id, school_id, document_url, date
+)
+SELECT 'regular', 'official', '', src.first_score, src.second_subject, src.second_score,
+       src.history, src.math, src.reading, src.total, src.student_id, src.school_id, '', NULL
+FROM (
+  SELECT s.student_id, COALESCE(ut.school_id, s.school_id) AS school_id,
+         COALESCE(ut.first_subject_score, 0) AS first_score,
+         COALESCE(ut.second_subject_name, '') AS second_subject, COALESCE(ut.second_subject_score, 0) AS second_score,
+         COALESCE(ut.history_of_kazakhstan, 0) AS history, COALESCE(ut.mathematical_literacy, 0) AS math,
+         COALESCE(ut.reading_literacy, 0) AS reading,
+         COALESCE(NULLIF(ut.total_score, 0), ut.TotalScore, 0) AS total
+  FROM `UNT_Type` ut
+  JOIN `student` s ON s.student_type_id = ut.unt_type_id
+) src
+WHERE src.total > 0
+  AND NOT EXISTS (
+    SELECT 1 FROM `UNT_Exams` ue
+    WHERE ue.student_id = src.student_id AND ue.exam_type = 'regular' AND ue.total_score = src.total
+  );
+
+INSERT INTO `UNT_Exams` (
+  exam_type, attempt_kind, first_subject, first_subject_score, second_subject, second_subject_score,
+  history_of_kazakhstan, mathematical_literacy, reading_literacy, total_score, student_id, school_id, document_url, date
+)
+SELECT 'creative', 'official', '', src.first_score, '', src.second_score,
+       src.history, 0, src.reading, src.total, src.student_id, src.school_id, '', NULL
+FROM (
+  SELECT s.student_id, COALESCE(ut.school_id, s.school_id) AS school_id,
+         COALESCE(ut.creative_exam1, 0) AS first_score, COALESCE(ut.creative_exam2, 0) AS second_score,
+         COALESCE(NULLIF(ut.second_type_history_kazakhstan, 0), ut.history_of_kazakhstan_creative, 0) AS history,
+         COALESCE(NULLIF(ut.second_type_reading_literacy, 0), ut.reading_literacy_creative, 0) AS reading,
+         COALESCE(NULLIF(ut.total_score_creative, 0),
+           COALESCE(NULLIF(ut.second_type_history_kazakhstan, 0), ut.history_of_kazakhstan_creative, 0)
+           + COALESCE(NULLIF(ut.second_type_reading_literacy, 0), ut.reading_literacy_creative, 0)
+           + COALESCE(ut.creative_exam1, 0) + COALESCE(ut.creative_exam2, 0)) AS total
+  FROM `UNT_Type` ut
+  JOIN `student` s ON s.student_type_id = ut.unt_type_id
+) src
+WHERE src.total > 0
+  AND NOT EXISTS (
+    SELECT 1 FROM `UNT_Exams` ue
+    WHERE ue.student_id = src.student_id AND ue.exam_type = 'creative' AND ue.total_score = src.total
+  );
+
+-- UNT_Score: строка творческая, если заполнены творческие экзамены
+INSERT INTO `UNT_Exams` (
+  exam_type, attempt_kind, first_subject, first_subject_score, second_subject, second_subject_score,
+  history_of_kazakhstan, mathematical_literacy, reading_literacy, total_score, student_id, school_id, document_url, date
+)
+SELECT src.exam_type, 'official', src.first_subject, src.first_score, src.second_subject, src.second_score,
+       src.history, src.math, src.reading, src.total, src.student_id, src.school_id, '', src.exam_date
+FROM (
+  SELECT us.student_id, s.school_id,
+         CASE WHEN COALESCE(us.creative_exam1, 0) + COALESCE(us.creative_exam2, 0) > 0 THEN 'creative' ELSE 'regular' END AS exam_type,
+         COALESCE(us.first_subject_name, '') AS first_subject,
+         COALESCE(us.second_subject_name, '') AS second_subject,
+         CASE WHEN COALESCE(us.creative_exam1, 0) + COALESCE(us.creative_exam2, 0) > 0
+              THEN COALESCE(us.creative_exam1, 0) ELSE COALESCE(us.first_subject_score, 0) END AS first_score,
+         CASE WHEN COALESCE(us.creative_exam1, 0) + COALESCE(us.creative_exam2, 0) > 0
+              THEN COALESCE(us.creative_exam2, 0) ELSE COALESCE(us.second_subject_score, 0) END AS second_score,
+         COALESCE(us.history_of_kazakhstan, 0) AS history,
+         CASE WHEN COALESCE(us.creative_exam1, 0) + COALESCE(us.creative_exam2, 0) > 0
+              THEN 0 ELSE COALESCE(us.math_literacy, 0) END AS math,
+         COALESCE(us.reading_literacy, 0) AS reading,
+         CASE WHEN COALESCE(us.creative_exam1, 0) + COALESCE(us.creative_exam2, 0) > 0
+              THEN COALESCE(NULLIF(us.total_score_creative, 0), COALESCE(us.history_of_kazakhstan, 0) + COALESCE(us.reading_literacy, 0)
+                + COALESCE(us.creative_exam1, 0) + COALESCE(us.creative_exam2, 0))
+              ELSE COALESCE(us.total_score, COALESCE(us.first_subject_score, 0) + COALESCE(us.second_subject_score, 0)
+                + COALESCE(us.history_of_kazakhstan, 0) + COALESCE(us.math_literacy, 0) + COALESCE(us.reading_literacy, 0))
+         END AS total,
+         STR_TO_DATE(CONCAT(us.year, '-06-01'), '%Y-%m-%d') AS exam_date
+  FROM `UNT_Score` us
+  JOIN `student` s ON s.student_id = us.student_id
+) src
+WHERE NOT EXISTS (
+  SELECT 1 FROM `UNT_Exams` ue
+  WHERE ue.student_id = src.student_id AND ue.exam_type = src.exam_type
+    AND ue.first_subject = src.first_subject AND ue.second_subject = src.second_subject
+    AND ue.first_subject_score = src.first_score AND ue.second_subject_score = src.second_score
+    AND ue.total_score = src.total
+);
+
+-- student_ratings — кэш рейтинга по старым таблицам, рейтинг теперь
+-- считается по UNT_Exams, поэтому таблица удаляется без переноса.
+DROP TABLE IF EXISTS `UNT_Score`;
+DROP TABLE IF EXISTS `student_ratings`;
+
+-- First_Type, Second_Type и UNT_Type удаляются, только если в них не
+-- осталось баллов, которые не удалось привязать ни к одному ученику.
+-- Иначе таблицы остаются для ручного разбора.
+SET @unt_legacy_unlinked = (
+  SELECT COUNT(*) FROM `First_Type` ft
+  WHERE NOT EXISTS (SELECT 1 FROM `student` s WHERE s.student_id = ft.student_id)
+    AND NOT EXISTS (
+      SELECT 1 FROM `UNT_Type` ut JOIN `student` s ON s.student_type_id = ut.unt_type_id
+      WHERE ut.first_type_id = ft.first_type_id
+    )
+) + (
+  SELECT COUNT(*) FROM `Second_Type` st
+  WHERE NOT EXISTS (SELECT 1 FROM `student` s WHERE s.student_id = st.student_id)
+    AND NOT EXISTS (
+      SELECT 1 FROM `UNT_Type` ut JOIN `student` s ON s.student_type_id = ut.unt_type_id
+      WHERE ut.second_type_id = st.second_type_id
+    )
+) + (
+  SELECT COUNT(*) FROM `UNT_Type` ut
+  WHERE NOT EXISTS (SELECT 1 FROM `student` s WHERE s.student_type_id = ut.unt_type_id)
+    AND (COALESCE(NULLIF(ut.total_score, 0), ut.TotalScore, 0) > 0
+      OR COALESCE(ut.total_score_creative, 0) + COALESCE(ut.creative_exam1, 0) + COALESCE(ut.creative_exam2, 0) > 0)
+);
+
+SET @unt_legacy_sql = IF(@unt_legacy_unlinked = 0, 'DROP TABLE IF EXISTS `UNT_Type`', 'DO 0');
+PREPARE unt_legacy_stmt FROM @unt_legacy_sql;
+EXECUTE unt_legacy_stmt;
+DEALLOCATE PREPARE unt_legacy_stmt;
+
+SET @unt_legacy_sql = IF(@unt_legacy_unlinked = 0, 'DROP TABLE IF EXISTS `First_Type`', 'DO 0');
+PREPARE unt_legacy_stmt FROM @unt_legacy_sql;
+EXECUTE unt_legacy_stmt;
+DEALLOCATE PREPARE unt_legacy_stmt;
+
+SET @unt_legacy_sql = IF(@unt_legacy_unlinked = 0, 'DROP TABLE IF EXISTS `Second_Type`', 'DO 0');
+PREPARE unt_legacy_stmt FROM @unt_legacy_sql;
+EXECUTE unt_legacy_stmt;
+DEALLOCATE PREPARE unt_legacy_stmt;
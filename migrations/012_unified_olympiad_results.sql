@@ -0,0 +1,31 @@
+-- Единая модель результатов олимпиад.
+--
+-- subject_olympiads — олимпиады, которые проводят школы на платформе,
+-- olympiad_registrations — заявки учеников на них (только статус заявки).
+-- Olympiads — все участия учеников: внешние результаты (source = 'external'),
+-- которые вносит школа, и участия во внутренних олимпиадах
+-- (source = 'internal'), которые создаются при принятии заявки.
+-- Место, баллы и документ хранятся только в Olympiads.
+ALTER TABLE `Olympiads`
+  ADD COLUMN `source` enum('external','internal') NOT NULL DEFAULT 'external',
+  ADD COLUMN `subject_olympiad_id` int DEFAULT NULL,
+  ADD COLUMN `registration_id` int DEFAULT NULL,
+  ADD UNIQUE KEY `uq_olympiads_registration` (`registration_id`),
+  ADD KEY `idx_olympiads_subject_olympiad` (`subject_olympiad_id`),
+  ADD KEY `idx_olympiads_school_level` (`school_id`, `level`);
+
+-- Перенос принятых заявок и уже присвоенных мест
+INSERT INTO `Olympiads` (
+  student_id, olympiad_place, score, school_id, level, olympiad_name, document_url, date,
+  source, subject_olympiad_id, registration_id
+)
+SELECT r.student_id, r.olympiad_place, r.score, r.school_id, so.level, so.subject_name, r.document_url, so.date,
+       'internal', so.subject_olympiad_id, r.olympiads_registrations_id
+FROM `olympiad_registrations` r
+JOIN `subject_olympiads` so ON so.subject_olympiad_id = r.subject_olympiad_id
+WHERE r.status IN ('accepted', 'completed') OR r.olympiad_place IS NOT NULL;
+
+ALTER TABLE `olympiad_registrations`
+  DROP COLUMN `olympiad_place`,
+  DROP COLUMN `score`,
+  DROP COLUMN `document_url`;
@@ -0,0 +1,36 @@
+-- Лист ожидания для мероприятий и олимпиад. Заявка сверх лимита получает
+-- статус waitlisted; при освобождении места первая по времени заявка
+-- переводится в registered, а ученик получает уведомление.
+ALTER TABLE `EventRegistrations`
+  MODIFY COLUMN `status` varchar(20) NOT NULL DEFAULT 'registered',
+  ADD KEY `idx_event_registrations_queue` (`event_id`, `status`, `registration_date`);
+
+ALTER TABLE `olympiad_registrations`
+  MODIFY COLUMN `status` varchar(20) NOT NULL DEFAULT 'registered',
+  ADD KEY `idx_olympiad_registrations_queue` (`subject_olympiad_id`, `status`, `registration_date`);
+
+-- Счётчики мест раньше не уменьшались при отмене — пересчитываем
+UPDATE `Events` e
+SET e.participants = (
+  SELECT COUNT(*) FROM `EventRegistrations` r
+  WHERE r.event_id = e.id AND r.status IN ('registered', 'accepted', 'completed')
+);
+
+UPDATE `subject_olympiads` so
+SET so.current_participants = (
+  SELECT COUNT(*) FROM `olympiad_registrations` r
+  WHERE r.subject_olympiad_id = so.subject_olympiad_id AND r.status IN ('registered', 'pending', 'accepted', 'completed')
+);
+
+-- Уведомления ученикам
+CREATE TABLE IF NOT EXISTS `notifications` (
+  `id` int NOT NULL AUTO_INCREMENT,
+  `student_id` int NOT NULL,
+  `kind` varchar(50) NOT NULL,
+  `message` varchar(500) NOT NULL,
+  `is_read` tinyint(1) NOT NULL DEFAULT 0,
+  `created_at` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (`id`),
+  KEY `idx_notifications_student` (`student_id`, `is_read`, `created_at`),
+  CONSTRAINT `fk_notifications_student` FOREIGN KEY (`student_id`) REFERENCES `student` (`student_id`) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;
@@ -0,0 +1,20 @@
+-- Окно регистрации, отдельное от дат проведения мероприятия/олимпиады.
+-- Пустое registration_closes_at означает прежнее поведение: регистрация
+-- закрывается в момент начала. Поздние заявки помечаются is_late.
+ALTER TABLE `Events`
+  ADD COLUMN `registration_opens_at` datetime DEFAULT NULL,
+  ADD COLUMN `registration_closes_at` datetime DEFAULT NULL,
+  ADD COLUMN `late_registration_until` datetime DEFAULT NULL,
+  ADD COLUMN `cancellation_deadline` datetime DEFAULT NULL;
+
+ALTER TABLE `subject_olympiads`
+  ADD COLUMN `registration_opens_at` datetime DEFAULT NULL,
+  ADD COLUMN `registration_closes_at` datetime DEFAULT NULL,
+  ADD COLUMN `late_registration_until` datetime DEFAULT NULL,
+  ADD COLUMN `cancellation_deadline` datetime DEFAULT NULL;
+
+ALTER TABLE `EventRegistrations`
+  ADD COLUMN `is_late` tinyint(1) NOT NULL DEFAULT 0;
+
+ALTER TABLE `olympiad_registrations`
+  ADD COLUMN `is_late` tinyint(1) NOT NULL DEFAULT 0;
@@ -0,0 +1,28 @@
+-- Правила допуска к мероприятиям и олимпиадам. Одна строка на
+-- мероприятие/олимпиаду. Пустое поле — ограничения нет. Если правила для
+-- события не заданы, действует прежнее поведение: класс ученика равен
+-- классу события и не более 2 участников от школы-организатора.
+-- Списки школ и городов хранятся как JSON-массивы.
+CREATE TABLE IF NOT EXISTS `eligibility_rules` (
+  `id` int NOT NULL AUTO_INCREMENT,
+  `target_type` enum('event','olympiad') NOT NULL,
+  `target_id` int NOT NULL,
+  `min_grade` int DEFAULT NULL,
+  `max_grade` int DEFAULT NULL,
+  `allowed_school_ids` json DEFAULT NULL,
+  `excluded_school_ids` json DEFAULT NULL,
+  `allowed_cities` json DEFAULT NULL,
+  `excluded_cities` json DEFAULT NULL,
+  `per_school_quota` int DEFAULT NULL,
+  `creator_school_quota` int DEFAULT NULL,
+  `gender` enum('male','female') DEFAULT NULL,
+  `min_age` int DEFAULT NULL,
+  `max_age` int DEFAULT NULL,
+  `prerequisite_olympiad_id` int DEFAULT NULL,
+  `prerequisite_level` varchar(50) DEFAULT NULL,
+  `prerequisite_max_place` int DEFAULT NULL,
+  `updated_by` int DEFAULT NULL,
+  `updated_at` timestamp NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `uq_eligibility_rules_target` (`target_type`, `target_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
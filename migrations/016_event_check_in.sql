@@ -0,0 +1,30 @@
+-- Посещаемость мероприятий по QR-билетам. У каждой заявки свой код билета
+-- (его содержимое кодируется в QR), организатор отмечает пришедших
+-- (attended), после мероприятия остальные отмечаются как no_show.
+ALTER TABLE `EventRegistrations`
+  ADD COLUMN `ticket_code` varchar(32) DEFAULT NULL,
+  ADD COLUMN `attendance` enum('attended','no_show') DEFAULT NULL,
+  ADD COLUMN `checked_in_at` datetime DEFAULT NULL,
+  ADD COLUMN `checked_in_by` int DEFAULT NULL,
+  ADD UNIQUE KEY `uq_event_registrations_ticket` (`ticket_code`),
+  ADD KEY `idx_event_registrations_attendance` (`event_id`, `attendance`);
+
+-- Билеты для уже существующих заявок
+UPDATE `EventRegistrations`
+SET ticket_code = UPPER(LEFT(SHA2(CONCAT(event_registration_id, '-', UUID(), '-', RAND()), 256), 16))
+WHERE ticket_code IS NULL;
+
+-- Раньше посещение определялось по events_participants (совпадение
+-- названия мероприятия) — переносим это как отметку attended. Повторяющиеся
+-- названия не переносим: по ним нельзя понять, какое мероприятие посещено.
+UPDATE `EventRegistrations` r
+JOIN `Events` e ON e.id = r.event_id
+JOIN (
+  SELECT event_name FROM `Events` GROUP BY event_name HAVING COUNT(*) = 1
+) uniq ON uniq.event_name = e.event_name
+SET r.attendance = 'attended'
+WHERE r.attendance IS NULL
+  AND EXISTS (
+    SELECT 1 FROM `events_participants` ep
+    WHERE ep.student_id = r.student_id AND ep.events_name = e.event_name
+  );
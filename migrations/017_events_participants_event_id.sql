@@ -0,0 +1,57 @@
+-- events_participants ссылалась на мероприятие по названию (events_name),
+-- из-за чего переименование или повтор названия портили рейтинг.
+-- Добавляем внешний ключ event_id; events_name остаётся для внешних
+-- мероприятий, которых нет в Events, и для отображения.
+ALTER TABLE `events_participants`
+  ADD COLUMN `event_id` int DEFAULT NULL,
+  ADD KEY `idx_events_participants_event` (`event_id`, `student_id`),
+  ADD CONSTRAINT `fk_events_participants_event` FOREIGN KEY (`event_id`) REFERENCES `Events` (`id`) ON DELETE SET NULL;
+
+-- 1. Название встречается в Events ровно один раз
+UPDATE `events_participants` ep
+JOIN (
+  SELECT event_name, MIN(id) AS event_id
+  FROM `Events`
+  GROUP BY event_name
+  HAVING COUNT(*) = 1
+) e ON e.event_name = ep.events_name
+SET ep.event_id = e.event_id
+WHERE ep.event_id IS NULL;
+
+-- 2. Повторяющееся название — берём мероприятие школы участника,
+--    если у этой школы оно одно
+UPDATE `events_participants` ep
+JOIN (
+  SELECT event_name, school_id, MIN(id) AS event_id
+  FROM `Events`
+  GROUP BY event_name, school_id
+  HAVING COUNT(*) = 1
+) e ON e.event_name = ep.events_name AND e.school_id = ep.school_id
+SET ep.event_id = e.event_id
+WHERE ep.event_id IS NULL;
+
+-- 3. Остальное — по дате участия, если в неё попадает ровно одно
+--    мероприятие с таким названием
+UPDATE `events_participants` ep
+JOIN (
+  SELECT p.id AS participant_id, MIN(e.id) AS event_id
+  FROM `events_participants` p
+  JOIN `Events` e ON e.event_name = p.events_name
+   AND p.date BETWEEN DATE(e.start_date) AND DATE(COALESCE(e.end_date, e.start_date))
+  WHERE p.event_id IS NULL
+  GROUP BY p.id
+  HAVING COUNT(*) = 1
+) m ON m.participant_id = ep.id
+SET ep.event_id = m.event_id;
+
+-- Отчёт: неоднозначные названия, которые не удалось сопоставить.
+-- Их нужно разобрать вручную (GET /api/events-participant/unlinked,
+-- затем PUT /events/participants/{id} с event_id).
+SELECT ep.id AS participant_id, ep.events_name, ep.school_id, ep.date,
+       COUNT(e.id) AS candidate_events,
+       GROUP_CONCAT(e.id ORDER BY e.id) AS candidate_event_ids
+FROM `events_participants` ep
+JOIN `Events` e ON e.event_name = ep.events_name
+WHERE ep.event_id IS NULL
+GROUP BY ep.id, ep.events_name, ep.school_id, ep.date
+ORDER BY ep.events_name, ep.id;
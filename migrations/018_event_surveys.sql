@@ -0,0 +1,78 @@
+-- Опросы после мероприятий. Организатор собирает шаблон из вопросов
+-- (оценка, выбор варианта, свободный ответ) и прикрепляет его к
+-- мероприятию. После end_date опрос автоматически рассылается принятым
+-- участникам (survey_invitations), ответы хранятся в survey_responses.
+CREATE TABLE IF NOT EXISTS `survey_templates` (
+  `id` int NOT NULL AUTO_INCREMENT,
+  `school_id` int DEFAULT NULL, -- NULL — общий шаблон, создаёт суперадмин
+  `title` varchar(255) NOT NULL,
+  `created_by` int NOT NULL,
+  `created_at` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  `updated_at` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+  PRIMARY KEY (`id`),
+  KEY `idx_survey_templates_school` (`school_id`),
+  CONSTRAINT `fk_survey_templates_school` FOREIGN KEY (`school_id`) REFERENCES `Schools` (`school_id`) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;
+
+CREATE TABLE IF NOT EXISTS `survey_questions` (
+  `id` int NOT NULL AUTO_INCREMENT,
+  `template_id` int NOT NULL,
+  `position` int NOT NULL DEFAULT 0,
+  `kind` enum('rating','choice','text') NOT NULL,
+  `text` varchar(500) NOT NULL,
+  `options` json DEFAULT NULL,          -- варианты для choice
+  `allow_multiple` tinyint(1) NOT NULL DEFAULT 0,
+  `max_rating` tinyint NOT NULL DEFAULT 5,
+  `required` tinyint(1) NOT NULL DEFAULT 1,
+  PRIMARY KEY (`id`),
+  KEY `idx_survey_questions_template` (`template_id`, `position`),
+  CONSTRAINT `fk_survey_questions_template` FOREIGN KEY (`template_id`) REFERENCES `survey_templates` (`id`) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;
+
+-- Опрос мероприятия: какой шаблон и когда разослан
+CREATE TABLE IF NOT EXISTS `event_surveys` (
+  `event_id` int NOT NULL,
+  `template_id` int NOT NULL,
+  `attached_by` int NOT NULL,
+  `sent_at` datetime DEFAULT NULL,
+  PRIMARY KEY (`event_id`),
+  KEY `idx_event_surveys_pending` (`sent_at`),
+  CONSTRAINT `fk_event_surveys_event` FOREIGN KEY (`event_id`) REFERENCES `Events` (`id`) ON DELETE CASCADE,
+  CONSTRAINT `fk_event_surveys_template` FOREIGN KEY (`template_id`) REFERENCES `survey_templates` (`id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;
+
+CREATE TABLE IF NOT EXISTS `survey_invitations` (
+  `event_id` int NOT NULL,
+  `student_id` int NOT NULL,
+  `sent_at` datetime NOT NULL,
+  PRIMARY KEY (`event_id`, `student_id`),
+  KEY `idx_survey_invitations_student` (`student_id`),
+  CONSTRAINT `fk_survey_invitations_event` FOREIGN KEY (`event_id`) REFERENCES `Events` (`id`) ON DELETE CASCADE,
+  CONSTRAINT `fk_survey_invitations_student` FOREIGN KEY (`student_id`) REFERENCES `student` (`student_id`) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;
+
+CREATE TABLE IF NOT EXISTS `survey_responses` (
+  `id` int NOT NULL AUTO_INCREMENT,
+  `event_id` int NOT NULL,
+  `template_id` int NOT NULL,
+  `student_id` int NOT NULL,
+  `submitted_at` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `uq_survey_responses_student` (`event_id`, `student_id`),
+  CONSTRAINT `fk_survey_responses_event` FOREIGN KEY (`event_id`) REFERENCES `Events` (`id`) ON DELETE CASCADE,
+  CONSTRAINT `fk_survey_responses_student` FOREIGN KEY (`student_id`) REFERENCES `student` (`student_id`) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;
+
+-- Для choice с несколькими вариантами — по строке на выбранный вариант
+CREATE TABLE IF NOT EXISTS `survey_answers` (
+  `id` int NOT NULL AUTO_INCREMENT,
+  `response_id` int NOT NULL,
+  `question_id` int NOT NULL,
+  `rating` tinyint DEFAULT NULL,
+  `choice` varchar(255) DEFAULT NULL,
+  `text` text,
+  PRIMARY KEY (`id`),
+  KEY `idx_survey_answers_question` (`question_id`),
+  CONSTRAINT `fk_survey_answers_response` FOREIGN KEY (`response_id`) REFERENCES `survey_responses` (`id`) ON DELETE CASCADE,
+  CONSTRAINT `fk_survey_answers_question` FOREIGN KEY (`question_id`) REFERENCES `survey_questions` (`id`) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;
@@ -0,0 +1,37 @@
+-- Многодневные мероприятия (сборы, фестивали): у мероприятия может быть
+-- несколько сессий со своим временем, аудиторией, спикерами и лимитом мест.
+-- Сессии без requires_registration посещают все участники мероприятия,
+-- на остальные ученик записывается отдельно (с листом ожидания).
+CREATE TABLE IF NOT EXISTS `event_sessions` (
+  `id` int NOT NULL AUTO_INCREMENT,
+  `event_id` int NOT NULL,
+  `title` varchar(255) NOT NULL,
+  `description` text,
+  `start_time` datetime NOT NULL,
+  `end_time` datetime NOT NULL,
+  `room` varchar(255) DEFAULT NULL,
+  `location` varchar(255) DEFAULT NULL, -- NULL — место проведения мероприятия
+  `speakers` json DEFAULT NULL,
+  `capacity` int DEFAULT NULL,          -- NULL — без ограничения
+  `requires_registration` tinyint(1) NOT NULL DEFAULT 0,
+  `participants` int NOT NULL DEFAULT 0,
+  `created_at` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (`id`),
+  KEY `idx_event_sessions_event` (`event_id`, `start_time`),
+  CONSTRAINT `fk_event_sessions_event` FOREIGN KEY (`event_id`) REFERENCES `Events` (`id`) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;
+
+CREATE TABLE IF NOT EXISTS `event_session_registrations` (
+  `id` int NOT NULL AUTO_INCREMENT,
+  `session_id` int NOT NULL,
+  `event_registration_id` int NOT NULL,
+  `student_id` int NOT NULL,
+  `status` varchar(20) NOT NULL DEFAULT 'registered', -- registered / waitlisted / canceled
+  `registration_date` datetime NOT NULL,
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `uq_event_session_registrations_student` (`session_id`, `student_id`),
+  KEY `idx_event_session_registrations_queue` (`session_id`, `status`, `registration_date`),
+  KEY `idx_event_session_registrations_student` (`student_id`, `status`),
+  CONSTRAINT `fk_event_session_registrations_session` FOREIGN KEY (`session_id`) REFERENCES `event_sessions` (`id`) ON DELETE CASCADE,
+  CONSTRAINT `fk_event_session_registrations_registration` FOREIGN KEY (`event_registration_id`) REFERENCES `EventRegistrations` (`event_registration_id`) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;
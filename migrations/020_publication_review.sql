@@ -0,0 +1,43 @@
+-- Модерация публикаций. Мероприятия и олимпиады школ проходят путь
+-- draft → pending_review → published / rejected; в публичных списках и
+-- рейтингах учитываются только published. Суперадмин публикует сразу.
+-- Уже существующие записи считаются опубликованными.
+ALTER TABLE `Events`
+  ADD COLUMN `status` enum('draft','pending_review','published','rejected') NOT NULL DEFAULT 'published',
+  ADD COLUMN `submitted_at` datetime DEFAULT NULL,
+  ADD COLUMN `reviewed_by` int DEFAULT NULL,
+  ADD COLUMN `reviewed_at` datetime DEFAULT NULL,
+  ADD COLUMN `rejection_reason` text DEFAULT NULL,
+  ADD KEY `idx_events_status` (`status`);
+
+ALTER TABLE `Events`
+  ALTER COLUMN `status` SET DEFAULT 'draft';
+
+ALTER TABLE `subject_olympiads`
+  ADD COLUMN `status` enum('draft','pending_review','published','rejected') NOT NULL DEFAULT 'published',
+  ADD COLUMN `submitted_at` datetime DEFAULT NULL,
+  ADD COLUMN `reviewed_by` int DEFAULT NULL,
+  ADD COLUMN `reviewed_at` datetime DEFAULT NULL,
+  ADD COLUMN `rejection_reason` text DEFAULT NULL,
+  ADD KEY `idx_subject_olympiads_status` (`status`);
+
+ALTER TABLE `subject_olympiads`
+  ALTER COLUMN `status` SET DEFAULT 'draft';
+
+-- Журнал переходов и комментариев модерации. action = comment — запись
+-- без смены статуса (from_status = to_status), edited — опубликованная
+-- запись изменена школой и вернулась на проверку.
+CREATE TABLE IF NOT EXISTS `publication_reviews` (
+  `id` int NOT NULL AUTO_INCREMENT,
+  `item_type` enum('event','olympiad') NOT NULL,
+  `item_id` int NOT NULL,
+  `action` enum('created','submitted','withdrawn','approved','rejected','comment','edited') NOT NULL,
+  `from_status` varchar(20) DEFAULT NULL,
+  `to_status` varchar(20) NOT NULL,
+  `reason` text DEFAULT NULL,
+  `comment` text DEFAULT NULL,
+  `actor_id` int NOT NULL,
+  `created_at` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (`id`),
+  KEY `idx_publication_reviews_item` (`item_type`, `item_id`, `created_at`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;
@@ -0,0 +1,46 @@
+-- Шаблоны мероприятий и олимпиад с правилом повторения. Из шаблона по
+-- расписанию создаются будущие экземпляры (Events / subject_olympiads)
+-- с template_id и template_occurrence — датой, на которую они созданы.
+-- recurrence: none — только ручное создание экземпляров, yearly — раз в
+-- год в месяц first_date, termly — в начале каждой четверти (сентябрь,
+-- ноябрь, январь, апрель), custom — месяцы из recurrence_months.
+CREATE TABLE IF NOT EXISTS `publication_templates` (
+  `id` int NOT NULL AUTO_INCREMENT,
+  `item_type` enum('event','olympiad') NOT NULL,
+  `school_id` int NOT NULL,
+  `name` varchar(255) NOT NULL,
+  `description` text DEFAULT NULL,
+  `location` varchar(255) DEFAULT NULL,
+  `category` varchar(50) DEFAULT NULL, -- мероприятия: Science / Humanities / Sport / Creative
+  `level` varchar(100) DEFAULT NULL,   -- олимпиады
+  `grade` int NOT NULL DEFAULT 0,
+  `limit_count` int NOT NULL DEFAULT 0,
+  `photo` varchar(512) DEFAULT NULL,
+  `start_time` time DEFAULT NULL,
+  `duration_days` int NOT NULL DEFAULT 1,
+  `recurrence` enum('none','yearly','termly','custom') NOT NULL DEFAULT 'none',
+  `recurrence_months` json DEFAULT NULL,
+  `recurrence_day` int NOT NULL DEFAULT 1,
+  `first_date` date NOT NULL,
+  `until_date` date DEFAULT NULL,
+  `months_ahead` int NOT NULL DEFAULT 12,
+  `auto_submit` tinyint(1) NOT NULL DEFAULT 0, -- экземпляры сразу уходят на модерацию
+  `created_by` int NOT NULL,
+  `created_at` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  `updated_at` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+  PRIMARY KEY (`id`),
+  KEY `idx_publication_templates_school` (`school_id`, `item_type`),
+  CONSTRAINT `fk_publication_templates_school` FOREIGN KEY (`school_id`) REFERENCES `Schools` (`school_id`) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;
+
+ALTER TABLE `Events`
+  ADD COLUMN `template_id` int DEFAULT NULL,
+  ADD COLUMN `template_occurrence` date DEFAULT NULL,
+  ADD UNIQUE KEY `uq_events_template_occurrence` (`template_id`, `template_occurrence`),
+  ADD CONSTRAINT `fk_events_template` FOREIGN KEY (`template_id`) REFERENCES `publication_templates` (`id`) ON DELETE SET NULL;
+
+ALTER TABLE `subject_olympiads`
+  ADD COLUMN `template_id` int DEFAULT NULL,
+  ADD COLUMN `template_occurrence` date DEFAULT NULL,
+  ADD UNIQUE KEY `uq_subject_olympiads_template_occurrence` (`template_id`, `template_occurrence`),
+  ADD CONSTRAINT `fk_subject_olympiads_template` FOREIGN KEY (`template_id`) REFERENCES `publication_templates` (`id`) ON DELETE SET NULL;
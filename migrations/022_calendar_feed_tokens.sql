@@ -0,0 +1,17 @@
+-- Токены календарных ICS-лент. Календарные приложения не умеют передавать
+-- заголовок Authorization, поэтому лента открывается по ссылке с токеном
+-- (?token=...). Хранится только SHA-256 токена; у владельца одновременно
+-- действует один токен, выпуск нового отзывает прежний.
+-- owner_type: student — ID из student, user — ID из users.
+CREATE TABLE IF NOT EXISTS `calendar_feed_tokens` (
+  `id` int NOT NULL AUTO_INCREMENT,
+  `owner_type` enum('user','student') NOT NULL,
+  `owner_id` int NOT NULL,
+  `token_hash` char(64) NOT NULL,
+  `created_at` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  `last_used_at` datetime DEFAULT NULL,
+  `revoked_at` datetime DEFAULT NULL,
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `uq_calendar_feed_tokens_hash` (`token_hash`),
+  KEY `idx_calendar_feed_tokens_owner` (`owner_type`, `owner_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;
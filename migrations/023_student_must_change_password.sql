@@ -0,0 +1,5 @@
+-- Пароли, выданные при массовом импорте учеников, одноразовые: пока флаг
+-- установлен, вход не выдаёт токены, и ученик должен задать свой пароль
+-- через /api/auth/student/initial-password.
+ALTER TABLE `student`
+  ADD COLUMN `must_change_password` tinyint(1) NOT NULL DEFAULT 0 AFTER `password`;
@@ -38,5 +38,10 @@ type SubjectOlympiad struct {
 	Participants        int     `json:"participants"`
 	Location            string  `json:"location"`
 	CurrentParticipants int     `json:"current_participants"` // ← вот это добавь
+	Status              string  `json:"status,omitempty"`
 
+	RegistrationOpensAt   string `json:"registration_opens_at,omitempty"`
+	RegistrationClosesAt  string `json:"registration_closes_at,omitempty"`
+	LateRegistrationUntil string `json:"late_registration_until,omitempty"`
+	CancellationDeadline  string `json:"cancellation_deadline,omitempty"`
 }
@@ -19,4 +19,10 @@ type Event struct {
 	UpdatedAt         string `json:"updated_at"`
 	CreatedBy         string `json:"created_by"`
 	Category          string `json:"category"`
+	Status            string `json:"status,omitempty"` // draft / pending_review / published / rejected
+
+	RegistrationOpensAt   string `json:"registration_opens_at,omitempty"`
+	RegistrationClosesAt  string `json:"registration_closes_at,omitempty"`
+	LateRegistrationUntil string `json:"late_registration_until,omitempty"`
+	CancellationDeadline  string `json:"cancellation_deadline,omitempty"`
 }
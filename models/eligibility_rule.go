@@ -0,0 +1,40 @@
+package models
+
+// EligibilityRule — правила допуска к мероприятию или олимпиаде.
+// nil/пустое значение означает отсутствие ограничения.
+type EligibilityRule struct {
+	ID         int    `json:"id,omitempty"`
+	TargetType string `json:"target_type"`
+	TargetID   int    `json:"target_id"`
+	IsDefault  bool   `json:"is_default"`
+
+	MinGrade          *int     `json:"min_grade"`
+	MaxGrade          *int     `json:"max_grade"`
+	AllowedSchoolIDs  []int    `json:"allowed_school_ids"`
+	ExcludedSchoolIDs []int    `json:"excluded_school_ids"`
+	AllowedCities     []string `json:"allowed_cities"`
+	ExcludedCities    []string `json:"excluded_cities"`
+
+	PerSchoolQuota     *int `json:"per_school_quota"`
+	CreatorSchoolQuota *int `json:"creator_school_quota"`
+
+	Gender string `json:"gender,omitempty"`
+	MinAge *int   `json:"min_age"`
+	MaxAge *int   `json:"max_age"`
+
+	PrerequisiteOlympiadID *int   `json:"prerequisite_olympiad_id"`
+	PrerequisiteLevel      string `json:"prerequisite_level,omitempty"`
+	PrerequisiteMaxPlace   *int   `json:"prerequisite_max_place"`
+}
+
+type EligibilityCheck struct {
+	Rule    string `json:"rule"`
+	Passed  bool   `json:"passed"`
+	Message string `json:"message"`
+}
+
+type EligibilityResult struct {
+	Eligible bool               `json:"eligible"`
+	Reasons  []string           `json:"reasons,omitempty"`
+	Checks   []EligibilityCheck `json:"checks"`
+}
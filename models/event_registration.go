@@ -10,6 +10,11 @@ type EventRegistration struct {
 	Status              string    `json:"status"`
 	SchoolID            int       `json:"school_id"`
 	Message             string    `json:"message"`
+	WaitlistPosition    int       `json:"waitlist_position,omitempty"`
+	IsLate              bool      `json:"is_late,omitempty"`
+	TicketCode          string    `json:"ticket_code,omitempty"`
+	Attendance          string    `json:"attendance,omitempty"`
+	CheckedInAt         string    `json:"checked_in_at,omitempty"`
 
 	StudentName       string `json:"student_name"`
 	StudentFirstName  string `json:"student_first_name"`
@@ -0,0 +1,66 @@
+package models
+
+// EventSession — сессия многодневного мероприятия.
+type EventSession struct {
+	ID                   int      `json:"id"`
+	EventID              int      `json:"event_id"`
+	Title                string   `json:"title"`
+	Description          string   `json:"description,omitempty"`
+	StartTime            string   `json:"start_time"`
+	EndTime              string   `json:"end_time"`
+	Room                 string   `json:"room,omitempty"`
+	Location             string   `json:"location,omitempty"`
+	Speakers             []string `json:"speakers"`
+	Capacity             *int     `json:"capacity"`
+	RequiresRegistration bool     `json:"requires_registration"`
+	Participants         int      `json:"participants"`
+	SeatsLeft            *int     `json:"seats_left,omitempty"`
+	RegistrationStatus   string   `json:"registration_status,omitempty"`
+	WaitlistPosition     int      `json:"waitlist_position,omitempty"`
+}
+
+// EventSessionRegistration — запись ученика на сессию.
+type EventSessionRegistration struct {
+	ID                  int    `json:"id"`
+	SessionID           int    `json:"session_id"`
+	EventRegistrationID int    `json:"event_registration_id"`
+	StudentID           int    `json:"student_id"`
+	StudentFirstName    string `json:"student_first_name,omitempty"`
+	StudentLastName     string `json:"student_last_name,omitempty"`
+	StudentGrade        int    `json:"student_grade,omitempty"`
+	StudentLetter       string `json:"student_letter,omitempty"`
+	Status              string `json:"status"`
+	RegistrationDate    string `json:"registration_date"`
+	WaitlistPosition    int    `json:"waitlist_position,omitempty"`
+}
+
+// ScheduleDay — сессии одного дня в расписании мероприятия.
+type ScheduleDay struct {
+	Date     string         `json:"date"`
+	Sessions []EventSession `json:"sessions"`
+}
+
+// EventSchedule — расписание мероприятия по дням.
+type EventSchedule struct {
+	EventID   int           `json:"event_id"`
+	EventName string        `json:"event_name"`
+	StartDate string        `json:"start_date"`
+	EndDate   string        `json:"end_date"`
+	Location  string        `json:"location,omitempty"`
+	Days      []ScheduleDay `json:"days"`
+}
+
+// ScheduleItem — пункт личного расписания ученика: мероприятие целиком
+// (если у него нет сессий) или сессия.
+type ScheduleItem struct {
+	Kind      string   `json:"kind"` // event / session
+	EventID   int      `json:"event_id"`
+	EventName string   `json:"event_name"`
+	SessionID int      `json:"session_id,omitempty"`
+	Title     string   `json:"title"`
+	StartTime string   `json:"start_time"`
+	EndTime   string   `json:"end_time"`
+	Location  string   `json:"location,omitempty"`
+	Room      string   `json:"room,omitempty"`
+	Conflicts []string `json:"conflicts,omitempty"`
+}
@@ -7,6 +7,7 @@ type EventsParticipant struct {
 	Grade      string `json:"grade"`
 	Letter     string `json:"letter"`
 	StudentID  int    `json:"student_id"`
+	EventID    int    `json:"event_id,omitempty"`
 	EventsName string `json:"events_name"`
 	Document   string `json:"document"`
 	Category   string `json:"category"`
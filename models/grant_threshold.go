@@ -0,0 +1,18 @@
+package models
+
+type GrantThreshold struct {
+	ID                     int    `json:"id"`
+	Year                   int    `json:"year"`
+	GroupCode              string `json:"group_code"`
+	GroupName              string `json:"group_name"`
+	ExamType               string `json:"exam_type"`
+	FirstSubjectCode       string `json:"first_subject_code,omitempty"`
+	SecondSubjectCode      string `json:"second_subject_code,omitempty"`
+	MinTotal               int    `json:"min_total"`
+	MinHistoryOfKazakhstan int    `json:"min_history_of_kazakhstan"`
+	MinReadingLiteracy     int    `json:"min_reading_literacy"`
+	MinMathLiteracy        int    `json:"min_mathematical_literacy"`
+	MinFirstSubject        int    `json:"min_first_subject"`
+	MinSecondSubject       int    `json:"min_second_subject"`
+	PassingScore           *int   `json:"passing_score,omitempty"`
+}
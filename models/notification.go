@@ -0,0 +1,10 @@
+package models
+
+type Notification struct {
+	ID        int    `json:"id"`
+	StudentID int    `json:"student_id"`
+	Kind      string `json:"kind"`
+	Message   string `json:"message"`
+	IsRead    bool   `json:"is_read"`
+	CreatedAt string `json:"created_at"`
+}
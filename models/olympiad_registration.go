@@ -9,6 +9,8 @@ type OlympiadRegistration struct {
 	RegistrationDate         time.Time `json:"registration_date"`
 	Status                   string    `json:"status"`
 	SchoolID                 int       `json:"school_id"`
+	WaitlistPosition         int       `json:"waitlist_position,omitempty"`
+	IsLate                   bool      `json:"is_late,omitempty"`
 
 	StudentName       string `json:"student_name"`
 	StudentFirstName  string `json:"student_first_name"`
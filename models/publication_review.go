@@ -0,0 +1,31 @@
+package models
+
+// PublicationReview — запись журнала модерации мероприятия или олимпиады.
+type PublicationReview struct {
+	ID         int    `json:"id"`
+	ItemType   string `json:"item_type"`
+	ItemID     int    `json:"item_id"`
+	Action     string `json:"action"`
+	FromStatus string `json:"from_status,omitempty"`
+	ToStatus   string `json:"to_status"`
+	Reason     string `json:"reason,omitempty"`
+	Comment    string `json:"comment,omitempty"`
+	ActorID    int    `json:"actor_id"`
+	ActorName  string `json:"actor_name,omitempty"`
+	CreatedAt  string `json:"created_at"`
+}
+
+// ReviewQueueItem — публикация, ожидающая решения суперадмина.
+type ReviewQueueItem struct {
+	ItemType     string `json:"item_type"`
+	ItemID       int    `json:"item_id"`
+	Title        string `json:"title"`
+	SchoolID     int    `json:"school_id"`
+	SchoolName   string `json:"school_name"`
+	StartDate    string `json:"start_date"`
+	EndDate      string `json:"end_date"`
+	Status       string `json:"status"`
+	SubmittedAt  string `json:"submitted_at,omitempty"`
+	SubmittedBy  string `json:"submitted_by,omitempty"`
+	CommentCount int    `json:"comment_count"`
+}
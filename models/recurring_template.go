@@ -0,0 +1,42 @@
+package models
+
+// PublicationTemplate — шаблон мероприятия или олимпиады с правилом
+// повторения. Recurrence: none / yearly / termly / custom.
+type PublicationTemplate struct {
+	ID               int    `json:"id"`
+	ItemType         string `json:"item_type"`
+	SchoolID         int    `json:"school_id"`
+	Name             string `json:"name"`
+	Description      string `json:"description"`
+	Location         string `json:"location,omitempty"`
+	Category         string `json:"category,omitempty"`
+	Level            string `json:"level,omitempty"`
+	Grade            int    `json:"grade"`
+	Limit            int    `json:"limit"`
+	Photo            string `json:"photo,omitempty"`
+	StartTime        string `json:"start_time,omitempty"` // HH:MM
+	DurationDays     int    `json:"duration_days"`
+	Recurrence       string `json:"recurrence"`
+	RecurrenceMonths []int  `json:"recurrence_months,omitempty"`
+	RecurrenceDay    int    `json:"recurrence_day"`
+	FirstDate        string `json:"first_date"`
+	UntilDate        string `json:"until_date,omitempty"`
+	MonthsAhead      int    `json:"months_ahead"`
+	AutoSubmit       bool   `json:"auto_submit"`
+	CreatedBy        int    `json:"created_by,omitempty"`
+	CreatedAt        string `json:"created_at,omitempty"`
+	UpdatedAt        string `json:"updated_at,omitempty"`
+
+	Instances []TemplateInstance `json:"instances,omitempty"`
+}
+
+// TemplateInstance — мероприятие или олимпиада, созданные из шаблона.
+type TemplateInstance struct {
+	ItemType   string `json:"item_type"`
+	ItemID     int    `json:"item_id"`
+	Title      string `json:"title"`
+	Occurrence string `json:"occurrence"`
+	StartDate  string `json:"start_date"`
+	EndDate    string `json:"end_date"`
+	Status     string `json:"status"`
+}
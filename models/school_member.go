@@ -0,0 +1,13 @@
+package models
+
+type SchoolMember struct {
+	ID          int      `json:"id"`
+	SchoolID    int      `json:"school_id"`
+	UserID      int      `json:"user_id"`
+	Email       string   `json:"email,omitempty"`
+	FirstName   string   `json:"first_name,omitempty"`
+	LastName    string   `json:"last_name,omitempty"`
+	Permissions []string `json:"permissions"`
+	IsOwner     bool     `json:"is_owner"`
+	CreatedAt   string   `json:"created_at"`
+}
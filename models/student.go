@@ -20,4 +20,6 @@ type Student struct {
 	AvatarURL     string `json:"avatar_url,omitempty"`
 	StudentTypeID int    `json:"student_type_id"`
 	Age           int    `json:"age"`
+	// Расхождения даты рождения и пола с ИИН, исправленные при сохранении
+	Warnings []string `json:"warnings,omitempty"`
 }
@@ -0,0 +1,80 @@
+package models
+
+// SurveyTemplate — шаблон опроса после мероприятия. SchoolID = nil —
+// общий шаблон платформы.
+type SurveyTemplate struct {
+	ID        int              `json:"id"`
+	SchoolID  *int             `json:"school_id"`
+	Title     string           `json:"title"`
+	CreatedBy int              `json:"created_by,omitempty"`
+	CreatedAt string           `json:"created_at,omitempty"`
+	Questions []SurveyQuestion `json:"questions"`
+}
+
+// SurveyQuestion — вопрос опроса: rating (оценка 1..MaxRating),
+// choice (выбор из Options) или text (свободный ответ).
+type SurveyQuestion struct {
+	ID            int      `json:"id,omitempty"`
+	Position      int      `json:"position"`
+	Kind          string   `json:"kind"`
+	Text          string   `json:"text"`
+	Options       []string `json:"options,omitempty"`
+	AllowMultiple bool     `json:"allow_multiple,omitempty"`
+	MaxRating     int      `json:"max_rating,omitempty"`
+	Required      bool     `json:"required"`
+}
+
+// SurveyAnswer — ответ ученика на один вопрос.
+type SurveyAnswer struct {
+	QuestionID int      `json:"question_id"`
+	Rating     *int     `json:"rating,omitempty"`
+	Choices    []string `json:"choices,omitempty"`
+	Text       string   `json:"text,omitempty"`
+}
+
+// EventSurvey — опрос, прикреплённый к мероприятию.
+type EventSurvey struct {
+	EventID     int            `json:"event_id"`
+	EventName   string         `json:"event_name,omitempty"`
+	EventEnd    string         `json:"event_end_date,omitempty"`
+	SentAt      string         `json:"sent_at,omitempty"`
+	Responded   bool           `json:"responded,omitempty"`
+	Template    SurveyTemplate `json:"template"`
+	Invitations int            `json:"invitations,omitempty"`
+	Responses   int            `json:"responses,omitempty"`
+}
+
+// SurveyQuestionResult — агрегированные ответы на вопрос.
+type SurveyQuestionResult struct {
+	QuestionID    int            `json:"question_id"`
+	Kind          string         `json:"kind"`
+	Text          string         `json:"text"`
+	Answers       int            `json:"answers"`
+	AverageRating *float64       `json:"average_rating,omitempty"`
+	Distribution  map[string]int `json:"distribution,omitempty"`
+	TextAnswers   []string       `json:"text_answers,omitempty"`
+}
+
+// EventSurveyResults — итоги опроса по мероприятию.
+type EventSurveyResults struct {
+	EventID       int                    `json:"event_id"`
+	EventName     string                 `json:"event_name"`
+	TemplateID    int                    `json:"template_id"`
+	SentAt        string                 `json:"sent_at,omitempty"`
+	Invitations   int                    `json:"invitations"`
+	Responses     int                    `json:"responses"`
+	ResponseRate  float64                `json:"response_rate"`
+	AverageRating *float64               `json:"average_rating,omitempty"`
+	Questions     []SurveyQuestionResult `json:"questions"`
+}
+
+// SchoolSurveySummary — итоги опросов по всем мероприятиям школы.
+type SchoolSurveySummary struct {
+	SchoolID      int                  `json:"school_id"`
+	Events        int                  `json:"events"`
+	Invitations   int                  `json:"invitations"`
+	Responses     int                  `json:"responses"`
+	ResponseRate  float64              `json:"response_rate"`
+	AverageRating *float64             `json:"average_rating,omitempty"`
+	EventResults  []EventSurveyResults `json:"event_results"`
+}
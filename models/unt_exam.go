@@ -2,7 +2,8 @@ package models
 
 type UNTExam struct {
 	ID                   int    `json:"id"`
-	ExamType             string `json:"exam_type"` // "regular" or "creative"
+	ExamType             string `json:"exam_type"`    // "regular" or "creative"
+	AttemptKind          string `json:"attempt_kind"` // "official" or "trial"
 	FirstSubject         string `json:"first_subject,omitempty"`
 	FirstSubjectScore    int    `json:"first_subject_score,omitempty"`
 	SecondSubject        string `json:"second_subject,omitempty"`
@@ -0,0 +1,16 @@
+package models
+
+type UNTSubject struct {
+	ID       int    `json:"id"`
+	Code     string `json:"code"`
+	Name     string `json:"name"`
+	Kind     string `json:"kind"` // mandatory, profile или creative
+	MaxScore int    `json:"max_score"`
+}
+
+type UNTSubjectPair struct {
+	ID            int    `json:"id"`
+	ExamType      string `json:"exam_type"`
+	FirstSubject  string `json:"first_subject"`
+	SecondSubject string `json:"second_subject"`
+}
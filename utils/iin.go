@@ -0,0 +1,90 @@
+package utils
+
+import (
+	"errors"
+	"time"
+)
+
+// IINInfo holds the data encoded in a Kazakhstan IIN.
+type IINInfo struct {
+	BirthDate time.Time
+	Gender    string // "male" или "female"
+}
+
+var (
+	ErrIINFormat   = errors.New("IIN must be 12 digits")
+	ErrIINDate     = errors.New("IIN contains an invalid date of birth")
+	ErrIINCentury  = errors.New("IIN contains an invalid century/gender digit")
+	ErrIINChecksum = errors.New("IIN checksum is invalid")
+)
+
+// ParseIIN validates an IIN (ИИН) and extracts date of birth and gender.
+//
+// Structure: YYMMDD, then one digit for century and gender
+// (1/2 — 1800s, 3/4 — 1900s, 5/6 — 2000s; odd — male, even — female),
+// four serial digits and a control digit.
+func ParseIIN(iin string) (IINInfo, error) {
+	var info IINInfo
+	if len(iin) != 12 {
+		return info, ErrIINFormat
+	}
+	var d [12]int
+	for i := 0; i < 12; i++ {
+		if iin[i] < '0' || iin[i] > '9' {
+			return info, ErrIINFormat
+		}
+		d[i] = int(iin[i] - '0')
+	}
+
+	if iinControlDigit(d) != d[11] {
+		return info, ErrIINChecksum
+	}
+
+	var century int
+	switch d[6] {
+	case 1, 2:
+		century = 1800
+	case 3, 4:
+		century = 1900
+	case 5, 6:
+		century = 2000
+	default:
+		return info, ErrIINCentury
+	}
+
+	year := century + d[0]*10 + d[1]
+	month := d[2]*10 + d[3]
+	day := d[4]*10 + d[5]
+	birth := time.Date(year, time.Month(month), day, 0, 0, 0, 0, time.UTC)
+	if month < 1 || month > 12 || birth.Day() != day || birth.After(time.Now()) {
+		return info, ErrIINDate
+	}
+
+	info.BirthDate = birth
+	if d[6]%2 == 1 {
+		info.Gender = "male"
+	} else {
+		info.Gender = "female"
+	}
+	return info, nil
+}
+
+// iinControlDigit computes the 12th digit using the national algorithm:
+// weights 1..11, and if the remainder is 10 — weights 3..11,1,2.
+// A second remainder of 10 means no valid IIN exists for these digits.
+func iinControlDigit(d [12]int) int {
+	weights := [][11]int{
+		{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11},
+		{3, 4, 5, 6, 7, 8, 9, 10, 11, 1, 2},
+	}
+	for _, w := range weights {
+		sum := 0
+		for i := 0; i < 11; i++ {
+			sum += d[i] * w[i]
+		}
+		if r := sum % 11; r != 10 {
+			return r
+		}
+	}
+	return -1
+}
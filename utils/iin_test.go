@@ -0,0 +1,45 @@
+package utils
+
+import "testing"
+
+func TestParseIIN(t *testing.T) {
+	tests := []struct {
+		name      string
+		iin       string
+		wantErr   error
+		wantBirth string
+		wantSex   string
+	}{
+		{"male 2000s", "050315500123", nil, "2005-03-15", "male"},
+		{"female 2000s", "071220600457", nil, "2007-12-20", "female"},
+		{"male 1900s", "950101300670", nil, "1995-01-01", "male"},
+		{"second weight set", "050315500503", nil, "2005-03-15", "male"},
+		{"leap day", "000229500028", nil, "2000-02-29", "male"},
+		{"too short", "05031550012", ErrIINFormat, "", ""},
+		{"not digits", "05031550012a", ErrIINFormat, "", ""},
+		{"wrong control digit", "050315500124", ErrIINChecksum, "", ""},
+		{"no valid control digit", "050315500590", ErrIINChecksum, "", ""},
+		{"bad century digit", "050315700126", ErrIINCentury, "", ""},
+		{"month out of range", "051315500017", ErrIINDate, "", ""},
+		{"day out of range", "100231500037", ErrIINDate, "", ""},
+		{"born in the future", "990101600012", ErrIINDate, "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			info, err := ParseIIN(tt.iin)
+			if err != tt.wantErr {
+				t.Fatalf("ParseIIN(%q) error = %v, want %v", tt.iin, err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				return
+			}
+			if got := info.BirthDate.Format("2006-01-02"); got != tt.wantBirth {
+				t.Errorf("BirthDate = %s, want %s", got, tt.wantBirth)
+			}
+			if info.Gender != tt.wantSex {
+				t.Errorf("Gender = %q, want %q", info.Gender, tt.wantSex)
+			}
+		})
+	}
+}